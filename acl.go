@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, The BioTeam, Inc.                     ***
  *** For more information please refer to the LICENSE.md file  ***/
 
@@ -8,6 +11,7 @@ import "C"
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -23,9 +27,139 @@ type AccessObject interface {
 	Con() *Connection
 }
 
-// ACL is used to describe the access level that a particular AccessObject (User/Group) has on a DataObj or Collection
+// Principal identifies a user or group in ACL APIs by name, zone and type, so users and groups sharing
+// a name (or users with the same name in different zones) can't be confused.
+// Type is UserType, AdminType, GroupAdminType or GroupType. An empty Zone means the local zone.
+type Principal struct {
+	Name string
+	Zone string
+	Type int
+}
+
+// UserPrincipal returns a Principal for the user name in zone
+func UserPrincipal(name string, zone string) Principal {
+	return Principal{Name: name, Zone: zone, Type: UserType}
+}
+
+// GroupPrincipal returns a Principal for the group name in zone
+func GroupPrincipal(name string, zone string) Principal {
+	return Principal{Name: name, Zone: zone, Type: GroupType}
+}
+
+// PrincipalOf returns the Principal of an existing *User or *Group
+func PrincipalOf(ao AccessObject) Principal {
+	p := Principal{Name: ao.Name(), Type: ao.Type()}
+
+	if z := ao.Zone(); z != nil {
+		p.Zone = z.Name()
+	}
+
+	return p
+}
+
+// ParsePrincipal parses a "name" or "name#zone" string. The type of the returned Principal is UnknownType.
+func ParsePrincipal(s string) Principal {
+	p := Principal{Name: s, Type: UnknownType}
+
+	if i := strings.LastIndex(s, "#"); i > -1 {
+		p.Name = s[:i]
+		p.Zone = s[i+1:]
+	}
+
+	return p
+}
+
+// IsGroup returns true if the principal is a group
+func (p Principal) IsGroup() bool {
+	return p.Type == GroupType
+}
+
+// IsUser returns true if the principal is a user of any type
+func (p Principal) IsUser() bool {
+	return p.Type == UserType || p.Type == AdminType || p.Type == GroupAdminType
+}
+
+// Equal returns true if both principals have the same name and zone, and their types are compatible (user vs group).
+// Principals with an UnknownType match either.
+func (p Principal) Equal(o Principal) bool {
+	if p.Name != o.Name || p.Zone != o.Zone {
+		return false
+	}
+
+	if p.Type == UnknownType || o.Type == UnknownType {
+		return true
+	}
+
+	return p.IsGroup() == o.IsGroup()
+}
+
+// String returns the principal in name#zone format
+func (p Principal) String() string {
+	if p.Zone == "" {
+		return p.Name
+	}
+
+	return p.Name + "#" + p.Zone
+}
+
+// chmod sets the access level of the principal on obj, using the local zone if p.Zone is empty. The catalog
+// resolves the name to a user or group itself, so the type of a typed principal is checked first: granting access to
+// a group that turns out to be a user of the same name (or the reverse) fails instead.
+func (p Principal) chmod(obj IRodsObj, accessLevel int, recursive bool) error {
+	con := obj.Con()
+	zone := p.Zone
+
+	if zone == "" {
+		z, err := con.LocalZone()
+		if err != nil {
+			return err
+		}
+
+		zone = z.Name()
+	}
+
+	if p.Type != UnknownType {
+		typ, err := con.principalType(p.Name, zone)
+		if err != nil {
+			return err
+		}
+
+		if (Principal{Type: typ}).IsGroup() != p.IsGroup() {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Chmod Failed: %v#%v is a %v, not a %v", p.Name, zone, UserTypeName(typ), UserTypeName(p.Type)))
+		}
+	}
+
+	return chmodZone(obj, p.Name, zone, accessLevel, recursive)
+}
+
+// principalType returns the type of the user or group name in zone
+func (con *Connection) principalType(name string, zone string) (int, error) {
+	nameLit, err := queryLiteral(name)
+	if err != nil {
+		return UnknownType, err
+	}
+
+	zoneLit, err := queryLiteral(zone)
+	if err != nil {
+		return UnknownType, err
+	}
+
+	result, err := con.IQuest(fmt.Sprintf("select USER_TYPE where USER_NAME = %v and USER_ZONE = %v", nameLit, zoneLit), false)
+	if err != nil {
+		return UnknownType, err
+	}
+
+	if len(result) == 0 {
+		return UnknownType, newError(Fatal, -1, fmt.Sprintf("iRODS Chmod Failed: unknown user or group %v#%v", name, zone))
+	}
+
+	return ParseUserType(result[0]["USER_TYPE"]), nil
+}
+
+// Principal always holds the name, zone and type reported by the server, and should be preferred for comparisons.
 type ACL struct {
 	AccessObject AccessObject
+	Principal    Principal
 	AccessLevel  int
 	Type         int
 }
@@ -33,6 +167,43 @@ type ACL struct {
 // ACLs is a slice of ACL pointers
 type ACLs []*ACL
 
+// Users returns the ACLs granted to users
+func (acls ACLs) Users() ACLs {
+	response := make(ACLs, 0)
+
+	for _, acl := range acls {
+		if acl.Principal.IsUser() {
+			response = append(response, acl)
+		}
+	}
+
+	return response
+}
+
+// Groups returns the ACLs granted to groups
+func (acls ACLs) Groups() ACLs {
+	response := make(ACLs, 0)
+
+	for _, acl := range acls {
+		if acl.Principal.IsGroup() {
+			response = append(response, acl)
+		}
+	}
+
+	return response
+}
+
+// Find returns the ACL granted to the principal, or nil if there isn't one
+func (acls ACLs) Find(p Principal) *ACL {
+	for _, acl := range acls {
+		if acl.Principal.Equal(p) {
+			return acl
+		}
+	}
+
+	return nil
+}
+
 // User is a shortcut to cast the AccessObject as it's underlying data structure type (*User)
 func (acl *ACL) User() *User {
 	if acl.Type == UserType || acl.Type == AdminType || acl.Type == GroupAdminType {
@@ -61,5 +232,5 @@ func (acl *ACL) AccessLevelString() string {
 func (acl *ACL) String() string {
 	typeString := getTypeString(acl.Type)
 
-	return fmt.Sprintf("%v:%v#%v:%v", typeString, acl.AccessObject.Name(), acl.AccessObject.Zone().Name(), getTypeString(acl.AccessLevel))
+	return fmt.Sprintf("%v:%v:%v", typeString, acl.Principal, getTypeString(acl.AccessLevel))
 }
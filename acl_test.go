@@ -0,0 +1,45 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestPrincipals(t *testing.T) {
+	p := ParsePrincipal("designers#otherZone")
+
+	if p.Name != "designers" || p.Zone != "otherZone" {
+		t.Fatalf("ParsePrincipal: got %v/%v", p.Name, p.Zone)
+	}
+
+	acls := ACLs{
+		{Principal: UserPrincipal("designers", "tempZone"), AccessLevel: Read},
+		{Principal: GroupPrincipal("designers", "tempZone"), AccessLevel: Write},
+		{Principal: UserPrincipal("designers", "otherZone"), AccessLevel: Own},
+	}
+
+	if acl := acls.Find(GroupPrincipal("designers", "tempZone")); acl == nil || acl.AccessLevel != Write {
+		t.Error("Find didn't return the group ACL")
+	}
+
+	if acl := acls.Find(p); acl == nil || acl.AccessLevel != Own {
+		t.Error("Find didn't return the remote zone ACL")
+	}
+
+	if len(acls.Groups()) != 1 || len(acls.Users()) != 2 {
+		t.Errorf("Groups/Users: got %v/%v", len(acls.Groups()), len(acls.Users()))
+	}
+}
+
+func TestDataObjSetAccessRecursive(t *testing.T) {
+	obj := &DataObj{path: "/tempZone/home/rods/a.txt"}
+
+	if err := obj.SetAccess(UserPrincipal("alice", "tempZone"), Read, true); err == nil {
+		t.Error("Expected an error setting the access of a data object recursively")
+	}
+}
@@ -0,0 +1,189 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"path"
+	"strings"
+
+	"github.com/jjacquay712/GoRODS/api"
+)
+
+// ConnectionAPI is the path based subset of the connection API that applications can program against, so they
+// can be unit tested with the in-memory implementation in gorods/mock. API returns the iRODS backed implementation.
+type ConnectionAPI = api.ConnectionAPI
+
+// CollectionAPI is the subset of *Collection operations that is part of ConnectionAPI, *Collection implements it
+type CollectionAPI = api.CollectionAPI
+
+// DataObjAPI is the subset of *DataObj operations that is part of ConnectionAPI, *DataObj implements it
+type DataObjAPI = api.DataObjAPI
+
+// AVU is an attribute, value and units triple, as passed to and returned by the metadata methods of ConnectionAPI
+type AVU = api.AVU
+
+// API returns the connection as a ConnectionAPI
+func API(con *Connection) ConnectionAPI {
+	return &conAPI{con}
+}
+
+// ConnectionOf returns the *Connection behind a ConnectionAPI returned by API or Client.Connect,
+// or nil if api is another implementation (or a wrapper of one)
+func ConnectionOf(api ConnectionAPI) *Connection {
+	if c, ok := api.(*conAPI); ok {
+		return c.con
+	}
+
+	return nil
+}
+
+// CollectionOf returns the *Collection behind a CollectionAPI of the iRODS backed implementation, or nil
+func CollectionOf(api CollectionAPI) *Collection {
+	col, _ := api.(*Collection)
+
+	return col
+}
+
+// DataObjOf returns the *DataObj behind a DataObjAPI of the iRODS backed implementation, or nil
+func DataObjOf(api DataObjAPI) *DataObj {
+	obj, _ := api.(*DataObj)
+
+	return obj
+}
+
+var (
+	_ CollectionAPI = (*Collection)(nil)
+	_ DataObjAPI    = (*DataObj)(nil)
+)
+
+type conAPI struct {
+	con *Connection
+}
+
+func (c *conAPI) Collection(p string) (CollectionAPI, error) {
+	return c.con.Collection(CollectionOptions{Path: p})
+}
+
+func (c *conAPI) DataObject(p string) (DataObjAPI, error) {
+	return c.con.DataObject(p)
+}
+
+func (c *conAPI) CreateCollection(p string) (CollectionAPI, error) {
+	p = strings.TrimRight(p, "/")
+
+	parent, err := c.con.Collection(CollectionOptions{Path: path.Dir(p)})
+	if err != nil {
+		return nil, err
+	}
+	defer parent.Close()
+
+	return parent.CreateSubCollection(path.Base(p))
+}
+
+func (c *conAPI) CreateDataObj(colPath string, opts DataObjOptions) (DataObjAPI, error) {
+	col, err := c.con.Collection(CollectionOptions{Path: colPath})
+	if err != nil {
+		return nil, err
+	}
+	defer col.Close()
+
+	return col.CreateDataObj(opts)
+}
+
+func (c *conAPI) Put(localPath string, colPath string, opts DataObjOptions) (DataObjAPI, error) {
+	col, err := c.con.Collection(CollectionOptions{Path: colPath})
+	if err != nil {
+		return nil, err
+	}
+	defer col.Close()
+
+	return col.Put(localPath, opts)
+}
+
+func (c *conAPI) ObjStat(p string) (*ObjStat, error) {
+	return c.con.ObjStat(p)
+}
+
+func (c *conAPI) PathType(p string) (int, error) {
+	return c.con.PathType(p)
+}
+
+func (c *conAPI) List(p string) ([]ListingEntry, error) {
+	return c.con.List(p)
+}
+
+func (c *conAPI) Meta(p string) ([]AVU, error) {
+	obj, err := c.metaObj(p)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	return metaValues(obj.Meta())
+}
+
+func (c *conAPI) AddMeta(p string, m AVU) error {
+	obj, err := c.metaObj(p)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	_, err = obj.AddMeta(Meta{Attribute: m.Attribute, Value: m.Value, Units: m.Units})
+
+	return err
+}
+
+func (c *conAPI) DeleteMeta(p string, attr string) error {
+	obj, err := c.metaObj(p)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	_, err = obj.DeleteMeta(attr)
+
+	return err
+}
+
+func (c *conAPI) Disconnect() error {
+	return c.con.Disconnect()
+}
+
+// closableMetaObj is a *Collection or *DataObj
+type closableMetaObj interface {
+	MetaObj
+	Close() error
+}
+
+// metaObj returns the data object or collection at p
+func (c *conAPI) metaObj(p string) (closableMetaObj, error) {
+	typ, err := c.con.PathType(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ == CollectionType {
+		return c.con.Collection(CollectionOptions{Path: p})
+	}
+
+	return c.con.DataObject(p)
+}
+
+// metaValues copies the AVUs of mc
+func metaValues(mc *MetaCollection, err error) ([]AVU, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]AVU, 0, len(mc.Metas))
+	for _, m := range mc.Metas {
+		response = append(response, AVU{Attribute: m.Attribute, Value: m.Value, Units: m.Units})
+	}
+
+	return response, nil
+}
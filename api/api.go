@@ -0,0 +1,107 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+// Package api holds the interfaces of GoRODS (ConnectionAPI, CollectionAPI, DataObjAPI and ClientAPI), and the value
+// types they use. It doesn't depend on the iRODS C client library, so implementations like the in-memory catalog
+// in gorods/mock, and the code programmed against the interfaces, build and test without cgo. Package gorods
+// re-exports every name of this package, gorods.API returns its iRODS backed implementation.
+package api
+
+import (
+	"time"
+)
+
+// Object types, returned by PathType and in ObjStat.Type and ListingEntry.Type. They are
+// gorods.DataObjType and gorods.CollectionType.
+const (
+	DataObjType    = 0
+	CollectionType = 1
+)
+
+// Access levels, passed to Chmod. They are gorods.Null, gorods.Read, gorods.Write and gorods.Own.
+const (
+	Null  = 12
+	Read  = 13
+	Write = 14
+	Own   = 15
+)
+
+// AVU is an attribute, value and units triple of metadata
+type AVU struct {
+	Attribute string
+	Value     string
+	Units     string
+}
+
+// ClientAPI opens connections, it's implemented by *gorods.Client and by the in-memory catalog in gorods/mock,
+// so code that opens its own connections can be given either
+type ClientAPI interface {
+	Connect() (ConnectionAPI, error)
+}
+
+// ConnectionAPI is the path based subset of the connection API that applications can program against, so they
+// can be unit tested with the in-memory implementation in gorods/mock. Data objects and collections are created,
+// and their metadata managed, by path. CreateDataObj and Put create the data object opts.Name within the
+// collection colPath, Put names it after the local file if opts.Name is empty.
+type ConnectionAPI interface {
+	Collection(p string) (CollectionAPI, error)
+	DataObject(p string) (DataObjAPI, error)
+	CreateCollection(p string) (CollectionAPI, error)
+	CreateDataObj(colPath string, opts DataObjOptions) (DataObjAPI, error)
+	Put(localPath string, colPath string, opts DataObjOptions) (DataObjAPI, error)
+
+	ObjStat(p string) (*ObjStat, error)
+	PathType(p string) (int, error)
+	List(p string) ([]ListingEntry, error)
+
+	Meta(p string) ([]AVU, error)
+	AddMeta(p string, m AVU) error
+	DeleteMeta(p string, attr string) error
+
+	Disconnect() error
+}
+
+// CollectionAPI is the subset of collection operations that is part of ConnectionAPI, *gorods.Collection
+// implements it. MoveTo and CopyTo take the path of the destination collection (relative to the parent collection
+// if it isn't absolute), or a collection of the same implementation.
+type CollectionAPI interface {
+	Type() int
+	Name() string
+	Path() string
+	ObjStat() (*ObjStat, error)
+	List() ([]ListingEntry, error)
+
+	Chmod(userOrGroup string, accessLevel int, recursive bool) error
+	Rename(newName string) error
+	MoveTo(iRODSCollection interface{}) error
+	CopyTo(iRODSCollection interface{}) error
+	Delete(recursive bool) error
+	Close() error
+}
+
+// DataObjAPI is the subset of data object operations that is part of ConnectionAPI, *gorods.DataObj implements
+// it. Checksum returns the checksum recorded in the catalog, Chksum has the server compute it. MoveTo and CopyTo take
+// the destination collection like those of CollectionAPI, relative paths are resolved against the collection of the
+// data object.
+type DataObjAPI interface {
+	Type() int
+	Name() string
+	Path() string
+	Size() int64
+	Checksum() string
+	ModTime() time.Time
+	ObjStat() (*ObjStat, error)
+	Chksum() (string, error)
+
+	Read() ([]byte, error)
+	ReadBytes(pos int64, length int) ([]byte, error)
+	Write(data []byte) error
+	DownloadTo(localPath string) error
+
+	Chmod(userOrGroup string, accessLevel int, recursive bool) error
+	Rename(newName string) error
+	MoveTo(iRODSCollection interface{}) error
+	CopyTo(iRODSCollection interface{}) error
+	Delete(recursive bool) error
+	Close() error
+}
@@ -0,0 +1,76 @@
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// Log level constants
+const (
+	Info = iota
+	Warn
+	Fatal
+)
+
+// GoRodsError stores information about errors
+type GoRodsError struct {
+	LogLevel  int
+	Message   string
+	IRODSCode string
+	Time      time.Time
+
+	// Status is the iRODS error code (e.g. -818000), or -1 if the error didn't originate from the iRODS API.
+	// ErrorName is the symbolic name of Status, e.g. "CAT_NO_ACCESS_PERMISSION".
+	Status    int
+	ErrorName string
+}
+
+// Error returns error string, alias of String(). Sample output:
+//
+//	2016-04-22 10:02:30.802355258 -0400 EDT: Fatal - iRODS Connect Failed: rcConnect failed
+func (err *GoRodsError) Error() string {
+	return err.String()
+}
+
+// String returns error string. Sample output:
+//
+//	2016-04-22 10:02:30.802355258 -0400 EDT: Fatal - iRODS Connect Failed: rcConnect failed
+func (err *GoRodsError) String() string {
+	return fmt.Sprintf("%v: %v - %v%v", err.Time, err.lookupError(err.LogLevel), err.Message, err.IRODSCode)
+}
+
+// Is reports whether target is a *GoRodsError with the same ErrorName, so errors.Is can match
+// iRODS error names and sentinels like gorods.ErrInsufficientPrivilege
+func (err *GoRodsError) Is(target error) bool {
+	t, ok := target.(*GoRodsError)
+
+	return ok && t.ErrorName != "" && t.ErrorName == err.ErrorName
+}
+
+// notFoundErrors are the names of the errors iRODS returns for paths that don't exist
+var notFoundErrors = map[string]bool{
+	"USER_FILE_DOES_NOT_EXIST": true,
+	"OBJ_PATH_DOES_NOT_EXIST":  true,
+	"CAT_NO_ROWS_FOUND":        true,
+}
+
+// IsNotFound returns true if err is a *GoRodsError reporting that a data object or collection doesn't exist,
+// rather than another failure like a permission or network error
+func IsNotFound(err error) bool {
+	gErr, ok := err.(*GoRodsError)
+
+	return ok && notFoundErrors[gErr.ErrorName]
+}
+
+func (err *GoRodsError) lookupError(code int) string {
+	var constLookup = map[int]string{
+		Info:  "Info",
+		Warn:  "Warn",
+		Fatal: "Fatal",
+	}
+
+	return constLookup[code]
+}
@@ -0,0 +1,80 @@
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package api
+
+import (
+	"strconv"
+	"sync"
+)
+
+// DefaultUserMessage is returned by UserMessage when no catalog provides a message for an error.
+// It intentionally contains no internal details.
+const DefaultUserMessage = "An error occurred while accessing the data store"
+
+// MessageCatalog maps errors to localized, user-facing messages. Message should return an empty string if it has no
+// message for err in the language lang, so the lookup can fall back to the default.
+type MessageCatalog interface {
+	Message(err *GoRodsError, lang string) string
+}
+
+// MessageCatalogFunc is an adapter to allow the use of ordinary functions as a MessageCatalog
+type MessageCatalogFunc func(err *GoRodsError, lang string) string
+
+// Message calls f(err, lang)
+func (f MessageCatalogFunc) Message(err *GoRodsError, lang string) string {
+	return f(err, lang)
+}
+
+// MapCatalog is a simple MessageCatalog, keyed by language and then by iRODS error name (e.g. "CAT_NO_ACCESS_PERMISSION")
+// or numeric status (e.g. "-818000"). The language "" is used as a fallback for languages without a matching message.
+type MapCatalog map[string]map[string]string
+
+// Message implements MessageCatalog
+func (m MapCatalog) Message(err *GoRodsError, lang string) string {
+	for _, l := range []string{lang, ""} {
+		msgs, ok := m[l]
+		if !ok {
+			continue
+		}
+
+		if msg, ok := msgs[err.ErrorName]; ok && err.ErrorName != "" {
+			return msg
+		}
+
+		if msg, ok := msgs[strconv.Itoa(err.Status)]; ok {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+var (
+	messageCatalog   MessageCatalog
+	messageCatalogMu sync.RWMutex
+)
+
+// SetMessageCatalog installs the catalog used by UserMessage. Pass nil to remove it.
+func SetMessageCatalog(catalog MessageCatalog) {
+	messageCatalogMu.Lock()
+	defer messageCatalogMu.Unlock()
+
+	messageCatalog = catalog
+}
+
+// UserMessage returns a message suitable for showing to end users in the language lang (e.g. "en", "de").
+// Internal details like paths and C API function names are never included, unless the catalog adds them.
+func (err *GoRodsError) UserMessage(lang string) string {
+	messageCatalogMu.RLock()
+	catalog := messageCatalog
+	messageCatalogMu.RUnlock()
+
+	if catalog != nil {
+		if msg := catalog.Message(err, lang); msg != "" {
+			return msg
+		}
+	}
+
+	return DefaultUserMessage
+}
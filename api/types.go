@@ -0,0 +1,93 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ObjStat contains the system metadata of a data object or collection, as returned by ObjStat()
+// Type is DataObjType or CollectionType. DataType and ReplicaCount are only set for data objects.
+type ObjStat struct {
+	Path         string
+	Type         int
+	Size         int64
+	DataMode     int
+	DataId       string
+	Checksum     string
+	OwnerName    string
+	OwnerZone    string
+	CreateTime   time.Time
+	ModifyTime   time.Time
+	DataType     string
+	ReplicaCount int
+	RescHier     string
+}
+
+// Map returns the stat information as a map (key/value pairs), using the same keys as Stat().
+// The following keys can be used with the map:
+//
+// "objSize", "dataMode", "dataId", "chksum", "ownerName", "ownerZone", "createTime", "modifyTime"
+func (stat *ObjStat) Map() map[string]interface{} {
+	result := make(map[string]interface{})
+
+	result["objSize"] = int(stat.Size)
+	result["dataMode"] = stat.DataMode
+
+	result["dataId"] = stat.DataId
+	result["chksum"] = stat.Checksum
+	result["ownerName"] = stat.OwnerName
+	result["ownerZone"] = stat.OwnerZone
+	result["createTime"] = fmt.Sprintf("%011d", stat.CreateTime.Unix())
+	result["modifyTime"] = fmt.Sprintf("%011d", stat.ModifyTime.Unix())
+
+	return result
+}
+
+// ListingEntry is a single item of a collection listing, as returned by List and seen by Collection.Subscribe
+type ListingEntry struct {
+	Name       string
+	Path       string
+	Type       int
+	Size       int64
+	Checksum   string
+	ModifyTime time.Time
+}
+
+// DataObjOptions is used for passing options to the CreateDataObj and DataObj.Copy function.
+// Resource can be a *Resource, a resource name or a resource hierarchy ("root;child;leaf"), and defaults to
+// Connection.DefaultResource. Dedup is only used by Collection.Put: if a replica with the same size and checksum as the local file already
+// exists (in Resource, if set), it is copied server-side instead of uploading the file, and nothing is transferred if
+// it's the destination itself. Duplicates are looked for in the collection tree at DedupScope, the destination
+// collection by default (set it to the zone, like "/tempZone", to search the whole catalog).
+// Transforms are applied to the file by Collection.Put, ConnectionOptions.Transforms are used if nil (pass an empty
+// slice to store a file as is).
+type DataObjOptions struct {
+	Name       string
+	Size       int64
+	Mode       int
+	Force      bool
+	Resource   interface{}
+	Dedup      bool
+	DedupScope string
+	Transforms []Transform
+}
+
+// Transform is a reversible transformation of data object contents, like compression or encryption.
+// Encode wraps the destination of uploaded bytes, and Decode the source of downloaded ones. Name identifies the
+// transform in the gorods.TransformAttr AVU, downloads look it up in the transforms registered with
+// gorods.RegisterTransform.
+//
+// Transforms are applied by Collection.Put, and reversed by DataObj.DownloadTo and DataObj.NewReader on connections
+// with ConnectionOptions.Transforms or DecodeTransforms set. Every other read (DataObj.Read, ReadChunk, ReadAt and the
+// WebDAV, FUSE and REST gateways) returns the contents as stored, transformed. Writes that can't apply them
+// (DataObj.Write, WriteBytes, WriteAt, Collection.PutResumable, Versioning and write references minted by
+// Connection.Presign) fail on connections with ConnectionOptions.Transforms, so nothing is stored untransformed.
+type Transform interface {
+	Name() string
+	Encode(w io.Writer) (io.WriteCloser, error)
+	Decode(r io.Reader) (io.ReadCloser, error)
+}
@@ -0,0 +1,235 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ArchiveChunkSize is the size of the reads used to stream data objects into archive entries
+const ArchiveChunkSize = 4 * 1024 * 1024
+
+// AddToTar writes the data object to tw as a regular file entry called name (the data object's name if empty).
+// The size and modification time of the entry come from ObjStat, the content is streamed from iRODS without a
+// temporary file. Other entries, like local files, can be written to tw before or after.
+// Names that could escape the directory the archive is extracted to, like "../a.txt", are rejected.
+func (obj *DataObj) AddToTar(tw *tar.Writer, name string) error {
+	stat, err := obj.ObjStat()
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = obj.name
+	}
+
+	defer obj.Close()
+
+	return writeTarEntry(tw, name, obj, obj.path, stat.Size, stat.ModifyTime)
+}
+
+// AddToZip writes the data object to zw as a deflated entry called name (the data object's name if empty).
+// The size and modification time of the entry come from ObjStat, the content is streamed from iRODS without a
+// temporary file. Other entries, like local files, can be written to zw before or after.
+// Names that could escape the directory the archive is extracted to, like "../a.txt", are rejected.
+func (obj *DataObj) AddToZip(zw *zip.Writer, name string) error {
+	stat, err := obj.ObjStat()
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = obj.name
+	}
+
+	defer obj.Close()
+
+	return writeZipEntry(zw, name, obj, obj.path, stat.Size, stat.ModifyTime)
+}
+
+// AddToTar writes the collection and everything it contains to tw, as entries below prefix
+// (the collection's name if empty). See DataObj.AddToTar.
+func (col *Collection) AddToTar(tw *tar.Writer, prefix string) error {
+	if prefix == "" {
+		prefix = col.name
+	}
+
+	return col.con.walkArchive(col.path, strings.Trim(prefix, "/"), func(name string, e ListingEntry, obj *DataObj) error {
+		if obj == nil {
+			return writeTarDir(tw, name, e.Path, e.ModifyTime)
+		}
+		defer obj.Close()
+
+		return writeTarEntry(tw, name, obj, obj.path, e.Size, e.ModifyTime)
+	})
+}
+
+// AddToZip writes the collection and everything it contains to zw, as entries below prefix
+// (the collection's name if empty). See DataObj.AddToZip.
+func (col *Collection) AddToZip(zw *zip.Writer, prefix string) error {
+	if prefix == "" {
+		prefix = col.name
+	}
+
+	return col.con.walkArchive(col.path, strings.Trim(prefix, "/"), func(name string, e ListingEntry, obj *DataObj) error {
+		if obj == nil {
+			return writeZipDir(zw, name, e.Path, e.ModifyTime)
+		}
+		defer obj.Close()
+
+		return writeZipEntry(zw, name, obj, obj.path, e.Size, e.ModifyTime)
+	})
+}
+
+// walkArchive calls fn for the collection at p and everything below it, parents first. name is the entry name
+// below prefix, obj is nil for collections.
+func (con *Connection) walkArchive(p string, prefix string, fn func(name string, e ListingEntry, obj *DataObj) error) error {
+	stat, err := con.ObjStat(p)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(prefix, ListingEntry{Name: path.Base(p), Path: p, Type: CollectionType, ModifyTime: stat.ModifyTime}, nil); err != nil {
+		return err
+	}
+
+	entries, err := con.List(p)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		name := prefix + "/" + e.Name
+
+		if e.Type == CollectionType {
+			if err := con.walkArchive(e.Path, name, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		obj, err := con.DataObject(e.Path)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(name, e, obj); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// archiveName returns an error if name isn't a relative entry name, or could escape the directory the archive
+// is extracted to
+func archiveName(name string, p string) error {
+	if name == "" || strings.HasPrefix(name, "/") {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Archive Entry Failed: %v, invalid entry name %q", p, name))
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Archive Entry Failed: %v, invalid entry name %q", p, name))
+		}
+	}
+
+	return nil
+}
+
+// writeTarDir writes a directory entry for the collection at p, called name
+func writeTarDir(tw *tar.Writer, name string, p string, modTime time.Time) error {
+	if err := archiveName(name, p); err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{Name: name + "/", Mode: 0755, ModTime: modTime, Typeflag: tar.TypeDir}); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS AddToTar Failed: %v, %v", p, err))
+	}
+
+	return nil
+}
+
+// writeZipDir writes a directory entry for the collection at p, called name
+func writeZipDir(zw *zip.Writer, name string, p string, modTime time.Time) error {
+	if err := archiveName(name, p); err != nil {
+		return err
+	}
+
+	fh := &zip.FileHeader{Name: name + "/", Method: zip.Store, Modified: modTime}
+	fh.SetMode(os.ModeDir | 0755)
+
+	if _, err := zw.CreateHeader(fh); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS AddToZip Failed: %v, %v", p, err))
+	}
+
+	return nil
+}
+
+// writeTarEntry writes a regular file entry called name, with the size bytes of the data object at p read from r
+func writeTarEntry(tw *tar.Writer, name string, r io.ReaderAt, p string, size int64, modTime time.Time) error {
+	if err := archiveName(name, p); err != nil {
+		return err
+	}
+
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS AddToTar Failed: %v, %v", p, err))
+	}
+
+	return copyEntry(tw, r, p, size)
+}
+
+// writeZipEntry writes a deflated entry called name, with the size bytes of the data object at p read from r
+func writeZipEntry(zw *zip.Writer, name string, r io.ReaderAt, p string, size int64, modTime time.Time) error {
+	if err := archiveName(name, p); err != nil {
+		return err
+	}
+
+	fh := &zip.FileHeader{
+		Name:               name,
+		Method:             zip.Deflate,
+		Modified:           modTime,
+		UncompressedSize64: uint64(size),
+	}
+	fh.SetMode(0644)
+
+	w, err := zw.CreateHeader(fh)
+	if err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS AddToZip Failed: %v, %v", p, err))
+	}
+
+	return copyEntry(w, r, p, size)
+}
+
+// copyEntry streams size bytes of the data object at p from r to w, failing if the data object is shorter
+func copyEntry(w io.Writer, r io.ReaderAt, p string, size int64) error {
+	n, err := io.CopyBuffer(w, io.NewSectionReader(r, 0, size), make([]byte, ArchiveChunkSize))
+	if err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Archive Entry Failed: %v, %v", p, err))
+	}
+
+	if n != size {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Archive Entry Failed: %v, read %v of %v bytes", p, n, size))
+	}
+
+	return nil
+}
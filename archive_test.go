@@ -0,0 +1,142 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTarRoundTrip(t *testing.T) {
+	modTime := time.Date(2016, 5, 4, 12, 0, 0, 0, time.UTC)
+	content := "test123content"
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	if err := writeTarDir(tw, "data", "/tempZone/home/rods/data", modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeTarEntry(tw, "data/a.txt", strings.NewReader(content), "/tempZone/home/rods/data/a.txt", int64(len(content)), modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(&buf)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hdr.Name != "data/" || hdr.Typeflag != tar.TypeDir || !hdr.ModTime.Equal(modTime) {
+		t.Errorf("Unexpected directory entry %+v", hdr)
+	}
+
+	if hdr, err = tr.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	if hdr.Name != "data/a.txt" || hdr.Typeflag != tar.TypeReg || hdr.Size != int64(len(content)) || !hdr.ModTime.Equal(modTime) {
+		t.Errorf("Unexpected file entry %+v", hdr)
+	}
+
+	if b, err := ioutil.ReadAll(tr); err != nil || string(b) != content {
+		t.Errorf("Unexpected content %q, %v", b, err)
+	}
+
+	if _, err := tr.Next(); err != io.EOF {
+		t.Errorf("Expected only two entries, got %v", err)
+	}
+}
+
+func TestZipRoundTrip(t *testing.T) {
+	modTime := time.Date(2016, 5, 4, 12, 0, 0, 0, time.UTC)
+	content := "test123content"
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	if err := writeZipDir(zw, "data", "/tempZone/home/rods/data", modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeZipEntry(zw, "data/a.txt", strings.NewReader(content), "/tempZone/home/rods/data/a.txt", int64(len(content)), modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(zr.File) != 2 {
+		t.Fatalf("Expected two entries, got %v", len(zr.File))
+	}
+
+	if f := zr.File[0]; f.Name != "data/" || !f.FileInfo().IsDir() {
+		t.Errorf("Unexpected directory entry %+v", f.FileHeader)
+	}
+
+	f := zr.File[1]
+
+	if f.Name != "data/a.txt" || f.FileInfo().IsDir() || f.UncompressedSize64 != uint64(len(content)) || !f.Modified.Equal(modTime) {
+		t.Errorf("Unexpected file entry %+v", f.FileHeader)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	if b, err := ioutil.ReadAll(rc); err != nil || string(b) != content {
+		t.Errorf("Unexpected content %q, %v", b, err)
+	}
+}
+
+func TestArchiveRejectsEscapingNames(t *testing.T) {
+	tw := tar.NewWriter(ioutil.Discard)
+	zw := zip.NewWriter(ioutil.Discard)
+
+	for _, name := range []string{"../a.txt", "data/../../a.txt", "/etc/passwd", "", ".."} {
+		if err := writeTarEntry(tw, name, strings.NewReader("x"), "/tempZone/home/rods/a.txt", 1, time.Now()); err == nil {
+			t.Errorf("Expected the tar entry %q to be rejected", name)
+		}
+
+		if err := writeZipEntry(zw, name, strings.NewReader("x"), "/tempZone/home/rods/a.txt", 1, time.Now()); err == nil {
+			t.Errorf("Expected the zip entry %q to be rejected", name)
+		}
+
+		if err := writeTarDir(tw, name, "/tempZone/home/rods", time.Now()); err == nil {
+			t.Errorf("Expected the tar directory %q to be rejected", name)
+		}
+	}
+
+	if err := writeTarEntry(tw, "data/..a.txt", strings.NewReader("x"), "/tempZone/home/rods/..a.txt", 1, time.Now()); err != nil {
+		t.Errorf("Expected names starting with dots to be allowed, got %v", err)
+	}
+
+	// A data object shorter than its catalog size fails the entry
+	if err := writeTarEntry(tw, "short.txt", strings.NewReader("x"), "/tempZone/home/rods/short.txt", 2, time.Now()); err == nil {
+		t.Error("Expected an error for a truncated data object")
+	}
+}
@@ -0,0 +1,314 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Audit action ids recorded in R_OBJT_AUDIT, from icatDefines.h
+const (
+	AuditAccessGranted          = 1000
+	AuditRegisterDataObj        = 2010
+	AuditRegisterDataReplica    = 2011
+	AuditUnregisterDataObj      = 2012
+	AuditRegisterResource       = 2030
+	AuditDeleteResource         = 2031
+	AuditDeleteUser             = 2040
+	AuditRegisterCollByAdmin    = 2050
+	AuditRegisterColl           = 2051
+	AuditDeleteCollByAdmin      = 2060
+	AuditDeleteColl             = 2061
+	AuditModUserPassword        = 2076
+	AuditModGroup               = 2080
+	AuditModResource            = 2090
+	AuditRegisterUser           = 2100
+	AuditAddMeta                = 2110
+	AuditDeleteMeta             = 2111
+	AuditCopyMeta               = 2112
+	AuditAddMetaWild            = 2113
+	AuditModAccessDataObj       = 2120
+	AuditModAccessColl          = 2121
+	AuditModAccessCollRecursive = 2122
+	AuditModAccessResource      = 2123
+	AuditRenameDataObj          = 2130
+	AuditRenameColl             = 2131
+	AuditMoveDataObj            = 2140
+	AuditMoveColl               = 2141
+	AuditCreateTicket           = 2170
+	AuditModTicket              = 2171
+	AuditDeleteTicket           = 2172
+	AuditUseTicket              = 2173
+)
+
+var auditActionNames = map[int]string{
+	AuditAccessGranted:          "access granted",
+	AuditRegisterDataObj:        "register data object",
+	AuditRegisterDataReplica:    "register replica",
+	AuditUnregisterDataObj:      "unregister data object",
+	AuditRegisterResource:       "register resource",
+	AuditDeleteResource:         "delete resource",
+	AuditDeleteUser:             "delete user",
+	AuditRegisterCollByAdmin:    "register collection by admin",
+	AuditRegisterColl:           "register collection",
+	AuditDeleteCollByAdmin:      "delete collection by admin",
+	AuditDeleteColl:             "delete collection",
+	AuditModUserPassword:        "modify user password",
+	AuditModGroup:               "modify group",
+	AuditModResource:            "modify resource",
+	AuditRegisterUser:           "register user",
+	AuditAddMeta:                "add metadata",
+	AuditDeleteMeta:             "delete metadata",
+	AuditCopyMeta:               "copy metadata",
+	AuditAddMetaWild:            "add metadata wildcard",
+	AuditModAccessDataObj:       "modify data object access",
+	AuditModAccessColl:          "modify collection access",
+	AuditModAccessCollRecursive: "modify collection access recursive",
+	AuditModAccessResource:      "modify resource access",
+	AuditRenameDataObj:          "rename data object",
+	AuditRenameColl:             "rename collection",
+	AuditMoveDataObj:            "move data object",
+	AuditMoveColl:               "move collection",
+	AuditCreateTicket:           "create ticket",
+	AuditModTicket:              "modify ticket",
+	AuditDeleteTicket:           "delete ticket",
+	AuditUseTicket:              "use ticket",
+}
+
+// AuditActionName returns a readable name of the audit action id, or the id itself if it is unknown
+func AuditActionName(action int) string {
+	if name, ok := auditActionNames[action]; ok {
+		return name
+	}
+
+	return strconv.Itoa(action)
+}
+
+// AuditRecord is a row of the catalog's audit table. ObjectId is the id of the object acted on (data object,
+// collection, user, resource...), UserId and User identify who acted. Comment holds details such as the path or AVU.
+type AuditRecord struct {
+	ObjectId   string
+	UserId     string
+	User       string
+	Action     int
+	ActionName string
+	Comment    string
+	Time       time.Time
+}
+
+// String returns a one line description of the record
+func (r AuditRecord) String() string {
+	return fmt.Sprintf("%v %v %v %v: %v", r.Time.Format(time.RFC3339), r.User, r.ActionName, r.ObjectId, r.Comment)
+}
+
+// AuditOptions selects the audit records returned by AuditTrail.
+// Path limits records to actions on the data object or collection at that path, which needn't exist anymore: deleted
+// objects are found by the path recorded in their audit comments. User limits records to actions performed by the user.
+// After and Before bound the time range when not zero, Actions limits the action ids, Limit the number of records.
+type AuditOptions struct {
+	Path    string
+	User    string
+	After   time.Time
+	Before  time.Time
+	Actions []int
+	Limit   int
+}
+
+// AuditTrail returns the audit records matching opts, oldest first. The catalog only records them when auditing
+// is enabled on the server, otherwise no records are returned. You must have the proper rodsadmin privileges to use this function.
+func (con *Connection) AuditTrail(opts AuditOptions) ([]AuditRecord, error) {
+	if er := con.requireAdmin("AuditTrail"); er != nil {
+		return nil, er
+	}
+
+	var (
+		objIds []string
+		userId string
+	)
+
+	if opts.Path != "" {
+		ids, err := con.auditObjectIds(opts.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ids) == 0 {
+			return []AuditRecord{}, nil
+		}
+
+		objIds = ids
+	}
+
+	users, err := con.IQuest("select USER_ID, USER_NAME, USER_ZONE", false)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	for _, row := range users {
+		names[row["USER_ID"]] = row["USER_NAME"] + "#" + row["USER_ZONE"]
+
+		if opts.User != "" && (row["USER_NAME"] == opts.User || row["USER_NAME"]+"#"+row["USER_ZONE"] == opts.User) {
+			userId = row["USER_ID"]
+		}
+	}
+
+	if opts.User != "" && userId == "" {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS AuditTrail Failed: Unknown user %v", opts.User))
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+
+	var result []map[string]string
+
+	query := auditQuery(objIds, userId, opts)
+
+	if err := con.intercept(&Event{Op: OpQuery, Query: query}, func() (er error) {
+		result, _, er = con.listRange(query, "", 0, limit, false)
+		return
+	}); err != nil {
+		return nil, err
+	}
+
+	records := make([]AuditRecord, 0, len(result))
+
+	for _, row := range result {
+		r := auditRecordFromRow(row)
+		r.User = names[r.UserId]
+
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// AuditTrail returns the audit records of this data object, see Connection.AuditTrail
+func (obj *DataObj) AuditTrail(opts AuditOptions) ([]AuditRecord, error) {
+	opts.Path = obj.path
+	return obj.con.AuditTrail(opts)
+}
+
+// AuditTrail returns the audit records of this collection, see Connection.AuditTrail
+func (col *Collection) AuditTrail(opts AuditOptions) ([]AuditRecord, error) {
+	opts.Path = col.path
+	return col.con.AuditTrail(opts)
+}
+
+// auditObjectIds returns the ids of the data object or collection at p: its catalog id if it exists, and the ids
+// of the objects whose audit comments hold p, like the registration and removal of a deleted data object
+func (con *Connection) auditObjectIds(p string) ([]string, error) {
+	p = strings.TrimRight(p, "/")
+
+	pathLit, err := queryLiteral(p)
+	if err != nil {
+		return nil, err
+	}
+
+	dirLit, err := queryLiteral(path.Dir(p))
+	if err != nil {
+		return nil, err
+	}
+
+	nameLit, err := queryLiteral(path.Base(p))
+	if err != nil {
+		return nil, err
+	}
+
+	queries := map[string]string{
+		"DATA_ID":      fmt.Sprintf("select DATA_ID where COLL_NAME = %v and DATA_NAME = %v", dirLit, nameLit),
+		"COLL_ID":      fmt.Sprintf("select COLL_ID where COLL_NAME = %v", pathLit),
+		"AUDIT_OBJ_ID": fmt.Sprintf("select AUDIT_OBJ_ID where AUDIT_COMMENT = %v", pathLit),
+	}
+
+	ids := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, column := range []string{"DATA_ID", "COLL_ID", "AUDIT_OBJ_ID"} {
+		result, err := con.IQuest(queries[column], false)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range result {
+			if id := row[column]; id != "" && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// auditQuery returns the genquery of the audit records of the object ids and user id specified (either may be
+// empty), oldest first
+func auditQuery(objIds []string, userId string, opts AuditOptions) string {
+	conditions := make([]string, 0)
+
+	if len(objIds) > 0 {
+		conditions = append(conditions, "AUDIT_OBJ_ID"+inCondition(objIds))
+	}
+
+	if userId != "" {
+		conditions = append(conditions, fmt.Sprintf("AUDIT_USER_ID = '%v'", userId))
+	}
+
+	if !opts.After.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("AUDIT_CREATE_TIME >= '%011d'", opts.After.Unix()))
+	}
+
+	if !opts.Before.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("AUDIT_CREATE_TIME < '%011d'", opts.Before.Unix()))
+	}
+
+	if len(opts.Actions) > 0 {
+		actions := make([]string, len(opts.Actions))
+		for i, a := range opts.Actions {
+			actions[i] = strconv.Itoa(a)
+		}
+
+		conditions = append(conditions, "AUDIT_ACTION_ID"+inCondition(actions))
+	}
+
+	query := "select order(AUDIT_CREATE_TIME), AUDIT_OBJ_ID, AUDIT_USER_ID, AUDIT_ACTION_ID, AUDIT_COMMENT"
+
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+
+	return query
+}
+
+// inCondition returns the " = 'value'" or " in ('value', ...)" part of a condition matching any of the values,
+// which must not contain single quotes
+func inCondition(values []string) string {
+	if len(values) == 1 {
+		return fmt.Sprintf(" = '%v'", values[0])
+	}
+
+	return " in ('" + strings.Join(values, "', '") + "')"
+}
+
+func auditRecordFromRow(row map[string]string) AuditRecord {
+	action, _ := strconv.Atoi(row["AUDIT_ACTION_ID"])
+
+	return AuditRecord{
+		ObjectId:   row["AUDIT_OBJ_ID"],
+		UserId:     row["AUDIT_USER_ID"],
+		Action:     action,
+		ActionName: AuditActionName(action),
+		Comment:    row["AUDIT_COMMENT"],
+		Time:       timeStringToTime(row["AUDIT_CREATE_TIME"]),
+	}
+}
@@ -0,0 +1,54 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuditQuery(t *testing.T) {
+	if q := auditQuery(nil, "", AuditOptions{}); q != "select order(AUDIT_CREATE_TIME), AUDIT_OBJ_ID, AUDIT_USER_ID, AUDIT_ACTION_ID, AUDIT_COMMENT" {
+		t.Errorf("Unexpected query without conditions: %v", q)
+	}
+
+	q := auditQuery([]string{"10020"}, "10001", AuditOptions{
+		After:   time.Unix(1500000000, 0),
+		Before:  time.Unix(1500086400, 0),
+		Actions: []int{AuditAddMeta},
+	})
+
+	expected := "select order(AUDIT_CREATE_TIME), AUDIT_OBJ_ID, AUDIT_USER_ID, AUDIT_ACTION_ID, AUDIT_COMMENT where AUDIT_OBJ_ID = '10020' and AUDIT_USER_ID = '10001' and AUDIT_CREATE_TIME >= '01500000000' and AUDIT_CREATE_TIME < '01500086400' and AUDIT_ACTION_ID = '2110'"
+	if q != expected {
+		t.Errorf("Expected %v, got %v", expected, q)
+	}
+
+	q = auditQuery([]string{"10020", "10031"}, "", AuditOptions{Actions: []int{AuditAddMeta, AuditDeleteMeta}})
+
+	expected = "select order(AUDIT_CREATE_TIME), AUDIT_OBJ_ID, AUDIT_USER_ID, AUDIT_ACTION_ID, AUDIT_COMMENT where AUDIT_OBJ_ID in ('10020', '10031') and AUDIT_ACTION_ID in ('2110', '2111')"
+	if q != expected {
+		t.Errorf("Expected %v, got %v", expected, q)
+	}
+}
+
+func TestAuditRecordFromRow(t *testing.T) {
+	r := auditRecordFromRow(map[string]string{
+		"AUDIT_OBJ_ID":      "10020",
+		"AUDIT_USER_ID":     "10001",
+		"AUDIT_ACTION_ID":   "2130",
+		"AUDIT_COMMENT":     "/tempZone/home/rods/b.txt",
+		"AUDIT_CREATE_TIME": "01500000000",
+	})
+
+	if r.Action != AuditRenameDataObj || r.ActionName != "rename data object" || !r.Time.Equal(time.Unix(1500000000, 0)) {
+		t.Errorf("Unexpected record %+v", r)
+	}
+
+	if name := AuditActionName(9999); name != "9999" {
+		t.Errorf("Expected the id of an unknown action, got %v", name)
+	}
+}
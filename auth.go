@@ -0,0 +1,105 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+)
+
+// AuthFile returns the path of the scrambled password file used by icommands: the IRODS_AUTHENTICATION_FILE environment
+// variable if set, ~/.irods/.irodsA otherwise
+func AuthFile() (string, error) {
+	var (
+		cName *C.char
+		err   *C.char
+	)
+
+	if status := C.gorods_auth_file_name(&cName, &err); status != 0 {
+		return "", newError(Fatal, status, fmt.Sprintf("iRODS AuthFile Failed: %v", C.GoString(err)))
+	}
+	defer C.free(unsafe.Pointer(cName))
+
+	return C.GoString(cName), nil
+}
+
+// Authenticate connects with opts and password, and on success writes the password to the auth file like iinit,
+// see Client.SaveAuth. Returns a Client using the password for its connections.
+func Authenticate(opts ConnectionOptions, password string) (*Client, error) {
+	opts.Password = password
+
+	cli := &Client{Options: &opts}
+
+	con, err := NewConnection(cli.Options)
+	if err != nil {
+		return nil, err
+	}
+	defer con.Disconnect()
+
+	if err := saveAuth(con.authSecret()); err != nil {
+		return nil, err
+	}
+
+	return cli, nil
+}
+
+// SaveAuth writes the client's password, scrambled, to the auth file (see AuthFile), so icommands and other tools
+// run by the same local user can connect without iinit. With PAMAuth, the temporary password issued by the server
+// is saved instead, as iinit does. An existing auth file is overwritten.
+func (cli *Client) SaveAuth() error {
+	if cli.Options.AuthType != PAMAuth {
+		if cli.Options.Password == "" {
+			return newError(Fatal, -1, "iRODS SaveAuth Failed: No password set")
+		}
+
+		return saveAuth(cli.Options.Password)
+	}
+
+	con, err := NewConnection(cli.Options)
+	if err != nil {
+		return err
+	}
+	defer con.Disconnect()
+
+	return saveAuth(con.authSecret())
+}
+
+// authSecret returns the password to save in the auth file for this connection
+func (con *Connection) authSecret() string {
+	if con.Options.AuthType == PAMAuth {
+		return con.PAMToken
+	}
+
+	return con.Options.Password
+}
+
+func saveAuth(password string) error {
+	p, er := AuthFile()
+	if er != nil {
+		return er
+	}
+
+	if er := os.MkdirAll(filepath.Dir(p), 0700); er != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS SaveAuth Failed: %v", er))
+	}
+
+	var err *C.char
+
+	cPassword := C.CString(password)
+	defer C.free(unsafe.Pointer(cPassword))
+
+	if status := C.gorods_save_auth(cPassword, &err); status != 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS SaveAuth Failed: %v, %v", p, C.GoString(err)))
+	}
+
+	return nil
+}
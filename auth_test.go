@@ -0,0 +1,57 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveAuth(t *testing.T) {
+	authFile := filepath.Join(t.TempDir(), "irods", ".irodsA")
+
+	prev, wasSet := os.LookupEnv("IRODS_AUTHENTICATION_FILE")
+	os.Setenv("IRODS_AUTHENTICATION_FILE", authFile)
+	defer func() {
+		if wasSet {
+			os.Setenv("IRODS_AUTHENTICATION_FILE", prev)
+		} else {
+			os.Unsetenv("IRODS_AUTHENTICATION_FILE")
+		}
+	}()
+
+	if p, err := AuthFile(); err != nil || p != authFile {
+		t.Fatalf("Expected the auth file from the environment, got %v, %v", p, err)
+	}
+
+	if err := (&Client{Options: &ConnectionOptions{}}).SaveAuth(); err == nil {
+		t.Error("Expected an error without a password")
+	}
+
+	if err := (&Client{Options: &ConnectionOptions{Password: "password"}}).SaveAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The missing directory is created, and the password is scrambled
+	b, err := ioutil.ReadFile(authFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b) == 0 || strings.Contains(string(b), "password") {
+		t.Errorf("Expected a scrambled password, got %q", b)
+	}
+
+	pam := &Connection{Options: &ConnectionOptions{AuthType: PAMAuth, Password: "pam password"}, PAMToken: "token"}
+
+	if s := pam.authSecret(); s != "token" {
+		t.Errorf("Expected the PAM token to be saved, got %q", s)
+	}
+}
@@ -0,0 +1,236 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// throttleChunkSize is the size of the reads and writes of throttled Put and DownloadTo calls
+const throttleChunkSize = 256 * 1024
+
+// BandwidthLimit caps the combined transfer rate of the connections sharing it, in bytes per second for each
+// direction (0 means unlimited). Set it in ConnectionOptions.Bandwidth to limit a Client, the Pools created from it
+// and the connections they redirect transfers to, or share one between clients. Rates can be changed at any time,
+// like at the start and end of business hours.
+type BandwidthLimit struct {
+	mu    sync.RWMutex
+	read  *rateLimiter
+	write *rateLimiter
+}
+
+// NewBandwidthLimit returns a limit of readBytesPerSec for downloads and reads, and writeBytesPerSec for uploads
+// and writes
+func NewBandwidthLimit(readBytesPerSec int64, writeBytesPerSec int64) *BandwidthLimit {
+	b := new(BandwidthLimit)
+	b.SetRates(readBytesPerSec, writeBytesPerSec)
+
+	return b
+}
+
+// SetRates replaces the rates of the limit, transfers in progress slow down or speed up from their next chunk
+func (b *BandwidthLimit) SetRates(readBytesPerSec int64, writeBytesPerSec int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.read = newRateLimiter(readBytesPerSec)
+	b.write = newRateLimiter(writeBytesPerSec)
+}
+
+// Rates returns the rates of the limit, 0 if unlimited
+func (b *BandwidthLimit) Rates() (readBytesPerSec int64, writeBytesPerSec int64) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.read.bytesPerSec(), b.write.bytesPerSec()
+}
+
+// limiter returns the rate limiter of direction (BytesRead or BytesWritten), nil if unlimited
+func (b *BandwidthLimit) limiter(direction int) *rateLimiter {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if direction == BytesRead {
+		return b.read
+	}
+
+	return b.write
+}
+
+// bytesPerSec returns the rate of the limiter, 0 for nil limiters
+func (l *rateLimiter) bytesPerSec() int64 {
+	if l == nil {
+		return 0
+	}
+
+	return l.rate
+}
+
+// bandwidth returns the limit shared by the connection's client, nil if there is none
+func (con *Connection) bandwidth() *BandwidthLimit {
+	if con.Options == nil {
+		return nil
+	}
+
+	return con.Options.Bandwidth
+}
+
+// throttled returns true if transfers in direction are rate limited, by the policy, the client's
+// ConnectionOptions.Bandwidth or ConnectionOptions.TransferBytesPerSec. Put and DownloadTo then transfer in chunks.
+func (con *Connection) throttled(direction int) bool {
+	if bandwidthLimited(direction) || con.bandwidth().limiter(direction) != nil {
+		return true
+	}
+
+	return con.Options != nil && con.Options.TransferBytesPerSec > 0
+}
+
+// transferLimiter returns the limiter of ConnectionOptions.TransferBytesPerSec for the data object's transfers,
+// nil if unlimited
+func (obj *DataObj) transferLimiter() *rateLimiter {
+	obj.limiterOnce.Do(func() {
+		if obj.con.Options != nil {
+			obj.limiter = newRateLimiter(obj.con.Options.TransferBytesPerSec)
+		}
+	})
+
+	return obj.limiter
+}
+
+// recordBytes records n bytes transferred by the data object, and waits for its transfer limit
+func (obj *DataObj) recordBytes(direction int, n int64) {
+	obj.con.recordBytes(direction, n)
+
+	if l := obj.transferLimiter(); l != nil && n > 0 {
+		l.wait(n)
+	}
+}
+
+// putThrottled uploads localPath in chunks, so the rate limits apply during the transfer. Like Put, the upload goes
+// to the resource server the provider redirects it to. The content is written to a temporary name first and only
+// replaces an existing data object once complete, a failed upload is removed.
+func (col *Collection) putThrottled(localPath string, opts DataObjOptions, rescName string) (*DataObj, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Put DataObject Failed: %v", err))
+	}
+	defer f.Close()
+
+	tcol := col
+
+	if tcon := col.con.transferCon(col.path+"/"+opts.Name, rescName, true); tcon != col.con {
+		if rcol, er := tcon.Collection(CollectionOptions{Path: col.path, SkipCache: true}); er == nil {
+			tcol = rcol
+		}
+	}
+
+	obj, err := tcol.replaceDataObj(opts.Name, opts.Force, func(tmpName string) (*DataObj, error) {
+		tmpOpts := opts
+		tmpOpts.Name = tmpName
+		tmpOpts.Force = false
+
+		obj, err := createDataObj(tmpOpts, tcol)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, throttleChunkSize)
+
+		for {
+			n, er := f.Read(buf)
+
+			if n > 0 {
+				if err := obj.writeNext(buf[:n]); err != nil {
+					obj.Close()
+					return obj, err
+				}
+			}
+
+			if er == io.EOF {
+				break
+			} else if er != nil {
+				obj.Close()
+				return obj, newError(Fatal, -1, fmt.Sprintf("iRODS Put DataObject Failed: %v, %v", localPath, er))
+			}
+		}
+
+		if err := obj.Close(); err != nil {
+			return obj, err
+		}
+
+		return obj, nil
+	})
+	if err != nil || tcol == col {
+		return obj, err
+	}
+
+	// The data object is returned on the connection it was uploaded with
+	if err := col.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return getDataObj(col.path+"/"+opts.Name, col.con)
+}
+
+// downloadThrottled writes the data object to localPath in chunks, so the rate limits apply during the transfer. It
+// reads until the end of the data object, which may have grown since it was opened. The content is written to a
+// temporary file next to localPath, renamed once complete: a failed download leaves localPath untouched.
+func (obj *DataObj) downloadThrottled(localPath string) error {
+	f, err := ioutil.TempFile(filepath.Dir(localPath), "."+filepath.Base(localPath)+".gorods-")
+	if err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Download DataObject Failed: %v, %v", obj.path, err))
+	}
+
+	fail := func(err error) error {
+		f.Close()
+		os.Remove(f.Name())
+
+		return err
+	}
+
+	for offset := int64(0); ; {
+		data, err := obj.ReadBytes(offset, throttleChunkSize)
+		if err != nil {
+			return fail(err)
+		}
+
+		if len(data) == 0 {
+			break
+		}
+
+		if _, er := f.Write(data); er != nil {
+			return fail(newError(Fatal, -1, fmt.Sprintf("iRODS Download DataObject Failed: %v, %v", obj.path, er)))
+		}
+
+		offset += int64(len(data))
+	}
+
+	if er := f.Chmod(0644); er != nil {
+		return fail(newError(Fatal, -1, fmt.Sprintf("iRODS Download DataObject Failed: %v, %v", obj.path, er)))
+	}
+
+	if er := f.Close(); er != nil {
+		os.Remove(f.Name())
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Download DataObject Failed: %v, %v", obj.path, er))
+	}
+
+	if er := os.Rename(f.Name(), localPath); er != nil {
+		os.Remove(f.Name())
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Download DataObject Failed: %v, %v", obj.path, er))
+	}
+
+	return obj.Close()
+}
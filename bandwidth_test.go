@@ -0,0 +1,46 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBandwidthLimit(t *testing.T) {
+	var none *BandwidthLimit
+
+	if none.limiter(BytesRead) != nil || none.limiter(BytesWritten) != nil {
+		t.Error("Expected no limiters without a BandwidthLimit")
+	}
+
+	b := NewBandwidthLimit(0, 1000)
+
+	if read, write := b.Rates(); read != 0 || write != 1000 {
+		t.Errorf("Expected rates 0 and 1000, got %v and %v", read, write)
+	}
+
+	if b.limiter(BytesRead) != nil || b.limiter(BytesWritten) == nil {
+		t.Error("Expected only writes to be limited")
+	}
+
+	b.SetRates(2000, 0)
+
+	if b.limiter(BytesRead) == nil || b.limiter(BytesWritten) != nil {
+		t.Error("Expected only reads to be limited after SetRates")
+	}
+
+	start := time.Now()
+
+	l := b.limiter(BytesRead)
+	l.wait(100)
+	l.wait(100)
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected 200 bytes at 2000 bytes/sec to take 100ms, took %v", elapsed)
+	}
+}
@@ -0,0 +1,109 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWriteBundle(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run")
+
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string]string{"a.txt": "a", "sub/b.txt": "b"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"run/", "run/a.txt=a", "run/sub/", "run/sub/b.txt=b"}
+
+	readTar := func(r io.Reader) []string {
+		entries := make([]string, 0)
+		tr := tar.NewReader(r)
+
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			data, _ := ioutil.ReadAll(tr)
+			entries = append(entries, entryString(hdr.Name, data))
+		}
+
+		return entries
+	}
+
+	for _, format := range []string{FormatTar, FormatGzipTar, FormatZip} {
+		var buf bytes.Buffer
+
+		if err := writeBundle(&buf, dir, format); err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+
+		var entries []string
+
+		switch format {
+		case FormatTar:
+			entries = readTar(&buf)
+		case FormatGzipTar:
+			gr, err := gzip.NewReader(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries = readTar(gr)
+		case FormatZip:
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, f := range zr.File {
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				data, _ := ioutil.ReadAll(rc)
+				rc.Close()
+
+				entries = append(entries, entryString(f.Name, data))
+			}
+		}
+
+		sort.Strings(entries)
+
+		if !reflect.DeepEqual(entries, expected) {
+			t.Errorf("%v: expected %v, got %v", format, expected, entries)
+		}
+	}
+}
+
+func entryString(name string, data []byte) string {
+	if len(data) == 0 {
+		return name
+	}
+
+	return name + "=" + string(data)
+}
@@ -0,0 +1,281 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"container/list"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of entries stored in a CacheBackend. Keys are formed by CacheKey(kind, path).
+const (
+	CacheList = "list"
+	CacheStat = "stat"
+	CacheMeta = "meta"
+)
+
+// CacheBackend is a pluggable backend for the read-through cache enabled with ConnectionOptions.Cache.
+// Values must be returned exactly as they were stored; GoRODS copies them before handing them to callers.
+// Implementations must be safe for use by multiple goroutines.
+type CacheBackend interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{})
+	Invalidate(match func(key string) bool)
+	Purge()
+}
+
+// CacheKey returns the key used for the cache entry of the kind specified (CacheList, CacheStat or CacheMeta) for path
+func CacheKey(kind string, p string) string {
+	return kind + "\x00" + p
+}
+
+// SplitCacheKey returns the kind and path of a key created with CacheKey
+func SplitCacheKey(key string) (string, string) {
+	if i := strings.IndexByte(key, 0); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+
+	return "", key
+}
+
+// MemoryCache is an in-memory CacheBackend with a time to live and an optional limit on the number of entries.
+// When the limit is reached the least recently used entry is evicted.
+type MemoryCache struct {
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+	mu         sync.Mutex
+}
+
+type memoryCacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// NewMemoryCache returns a *MemoryCache whose entries expire after ttl (never if 0). A maxEntries of 0 means no limit.
+func NewMemoryCache(ttl time.Duration, maxEntries int) *MemoryCache {
+	c := new(MemoryCache)
+
+	c.ttl = ttl
+	c.maxEntries = maxEntries
+	c.entries = make(map[string]*list.Element)
+	c.lru = list.New()
+
+	return c
+}
+
+// Get returns the value stored for key, if it exists and hasn't expired
+func (c *MemoryCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*memoryCacheEntry)
+
+	if c.ttl > 0 && time.Now().After(entry.expires) {
+		c.remove(el)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+
+	return entry.value, true
+}
+
+// Set stores value for key, evicting the least recently used entry if the cache is full
+func (c *MemoryCache) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*memoryCacheEntry)
+		entry.value = value
+		entry.expires = expires
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&memoryCacheEntry{key: key, value: value, expires: expires})
+
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Invalidate removes every entry whose key is matched
+func (c *MemoryCache) Invalidate(match func(key string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, el := range c.entries {
+		if match(key) {
+			c.remove(el)
+		}
+	}
+}
+
+// Purge removes every entry
+func (c *MemoryCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// Len returns the number of entries in the cache, including expired entries that haven't been evicted yet
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*memoryCacheEntry).key)
+}
+
+func (con *Connection) cache() CacheBackend {
+	if con.Options == nil {
+		return nil
+	}
+
+	return con.Options.Cache
+}
+
+func (con *Connection) cacheGet(kind string, p string) (interface{}, bool) {
+	if c := con.cache(); c != nil {
+		return c.Get(CacheKey(kind, p))
+	}
+
+	return nil, false
+}
+
+func (con *Connection) cacheSet(kind string, p string, value interface{}) {
+	if c := con.cache(); c != nil {
+		c.Set(CacheKey(kind, p), value)
+	}
+}
+
+// InvalidateCache removes the cached listings, stats and metadata of the paths specified, the listings of their
+// parent collections, and everything cached below them. Mutating operations made through the connection call it
+// automatically; use it after changes made by other clients.
+func (con *Connection) InvalidateCache(paths ...string) {
+	c := con.cache()
+	if c == nil || len(paths) == 0 {
+		return
+	}
+
+	changedPaths := make([]string, len(paths))
+	for i := range paths {
+		changedPaths[i] = strings.TrimRight(paths[i], "/")
+	}
+
+	c.Invalidate(func(key string) bool {
+		kind, p := SplitCacheKey(key)
+
+		for _, changed := range changedPaths {
+			if p == changed || strings.HasPrefix(p, changed+"/") || (kind == CacheList && p == path.Dir(changed)) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Sources of changes passed to Connection.ObserveChange
+const (
+	ChangeSourceSubscription = "subscription"
+	ChangeSourceAudit        = "audit"
+	ChangeSourceWatch        = "watch"
+)
+
+// ObserveChange reports changes made to paths by other clients, as seen by source (ChangeSourceSubscription,
+// ChangeSourceAudit or any other name). The cache entries of the paths are invalidated right away, instead of
+// waiting for them to expire, and the OpChange hooks are called for each path. Subscriptions report the diffs
+// they find; feed audit events (e.g. from the audit plugin's message queue) to it to keep the cache coherent.
+func (con *Connection) ObserveChange(source string, paths ...string) {
+	if len(paths) == 0 {
+		return
+	}
+
+	con.InvalidateCache(paths...)
+
+	hooks := con.Hooks().get(OpChange)
+
+	for _, p := range paths {
+		e := &Event{Op: OpChange, Path: p, Params: map[string]string{"source": source}, Start: time.Now(), Con: con}
+
+		for _, h := range hooks {
+			if h.After != nil {
+				h.After(e)
+			}
+		}
+	}
+}
+
+// PurgeCache removes every entry from the connection's cache
+func (con *Connection) PurgeCache() {
+	if c := con.cache(); c != nil {
+		c.Purge()
+	}
+}
+
+// List returns the collections and data objects directly within the collection at p, sorted by name.
+// It uses general queries rather than opening the collection, and is served from ConnectionOptions.Cache when set.
+func (con *Connection) List(p string) ([]ListingEntry, error) {
+	p = strings.TrimRight(p, "/")
+
+	if v, ok := con.cacheGet(CacheList, p); ok {
+		return append([]ListingEntry(nil), v.([]ListingEntry)...), nil
+	}
+
+	entries, err := con.listEntries(p)
+	if err != nil {
+		return nil, err
+	}
+
+	listing := make([]ListingEntry, 0, len(entries))
+	for _, entry := range entries {
+		listing = append(listing, entry)
+	}
+
+	sort.Slice(listing, func(i, j int) bool {
+		return listing[i].Name < listing[j].Name
+	})
+
+	if len(listing) == 0 {
+		// An empty listing is indistinguishable from a missing collection, make sure it exists before caching
+		if _, er := con.objStat(p); er != nil {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS List Failed: %v", er))
+		}
+	}
+
+	con.cacheSet(CacheList, p, listing)
+
+	return append([]ListingEntry(nil), listing...), nil
+}
+
+// List returns the collections and data objects directly within the collection, sorted by name. See Connection.List.
+func (col *Collection) List() ([]ListingEntry, error) {
+	return col.con.List(col.path)
+}
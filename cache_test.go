@@ -0,0 +1,93 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache(50*time.Millisecond, 2)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+
+	if v, ok := c.Get("a"); !ok || v.(int) != 1 {
+		t.Errorf("Unexpected value %v, %v", v, ok)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if _, ok := c.Get("c"); ok {
+		t.Error("Expected the entry to expire")
+	}
+}
+
+func TestInvalidateCache(t *testing.T) {
+	c := NewMemoryCache(0, 0)
+	con := &Connection{Options: &ConnectionOptions{Cache: c}}
+
+	for _, key := range []string{
+		CacheKey(CacheList, "/tempZone/home/rods"),
+		CacheKey(CacheStat, "/tempZone/home/rods/sub"),
+		CacheKey(CacheMeta, "/tempZone/home/rods/sub/a.txt"),
+		CacheKey(CacheStat, "/tempZone/home/rods/subway"),
+		CacheKey(CacheList, "/tempZone/home"),
+	} {
+		c.Set(key, true)
+	}
+
+	con.InvalidateCache("/tempZone/home/rods/sub/")
+
+	for key, expected := range map[string]bool{
+		CacheKey(CacheList, "/tempZone/home/rods"):           false,
+		CacheKey(CacheStat, "/tempZone/home/rods/sub"):       false,
+		CacheKey(CacheMeta, "/tempZone/home/rods/sub/a.txt"): false,
+		CacheKey(CacheStat, "/tempZone/home/rods/subway"):    true,
+		CacheKey(CacheList, "/tempZone/home"):                true,
+	} {
+		if _, ok := c.Get(key); ok != expected {
+			kind, p := SplitCacheKey(key)
+			t.Errorf("Unexpected cache state for %v %v: %v", kind, p, ok)
+		}
+	}
+}
+
+func TestObserveChange(t *testing.T) {
+	c := NewMemoryCache(0, 0)
+	con := &Connection{Options: &ConnectionOptions{Cache: c, Hooks: NewHooks()}}
+
+	var observed []string
+
+	con.Hooks().OnChange(Hook{After: func(e *Event) {
+		observed = append(observed, e.Params["source"]+" "+e.Path)
+	}})
+
+	c.Set(CacheKey(CacheStat, "/tempZone/home/rods/a.txt"), true)
+	c.Set(CacheKey(CacheStat, "/tempZone/home/rods/b.txt"), true)
+
+	con.ObserveChange(ChangeSourceAudit, "/tempZone/home/rods/a.txt")
+
+	if _, ok := c.Get(CacheKey(CacheStat, "/tempZone/home/rods/a.txt")); ok {
+		t.Error("Expected the changed entry to be invalidated")
+	}
+
+	if _, ok := c.Get(CacheKey(CacheStat, "/tempZone/home/rods/b.txt")); !ok {
+		t.Error("Expected the other entry to be kept")
+	}
+
+	if len(observed) != 1 || observed[0] != "audit /tempZone/home/rods/a.txt" {
+		t.Errorf("Unexpected hook calls: %v", observed)
+	}
+}
@@ -0,0 +1,124 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+)
+
+// Capabilities describes what the server a connection is attached to supports, derived from its version when the
+// connection is established. Optional APIs are used when the server has them, with fallbacks for older servers
+// where GoRODS has one. Negotiation is the client-server negotiation result, like "CS_NEG_USE_SSL".
+type Capabilities struct {
+	Version     ServerVersion
+	Negotiation string
+	SSL         bool
+
+	// 4.1.0: ZoneReport
+	ZoneReport bool
+
+	// 4.2.8: Connection.ApplyMetaOperations and MetaStage.Commit in a single request
+	AtomicMetadata bool
+
+	// 4.2.9: replica open, close and finalize, the basis of the 4.2.9 replica state model, and the touch API
+	ReplicaAccess bool
+	Touch         bool
+
+	// 4.2.11: truncating a single replica
+	ReplicaTruncate bool
+
+	// 4.3.1: switching the client user of a connection, and the library features API
+	SwitchUser bool
+
+	// 4.3.2: GenQuery2
+	GenQuery2 bool
+
+	known bool
+}
+
+// Known returns true if the server version was detected. When it wasn't, every capability reads false but
+// operations still try the optional APIs and fall back if the server rejects them.
+func (c Capabilities) Known() bool {
+	return c.known
+}
+
+// String returns a short summary, like "4.2.11 (CS_NEG_USE_TCP)"
+func (c Capabilities) String() string {
+	if !c.known {
+		return "unknown"
+	}
+
+	if c.Negotiation == "" {
+		return c.Version.String()
+	}
+
+	return fmt.Sprintf("%v (%v)", c.Version, c.Negotiation)
+}
+
+// capabilitiesOf returns the capabilities of a server running version v
+func capabilitiesOf(v ServerVersion) Capabilities {
+	return Capabilities{
+		Version:         v,
+		ZoneReport:      v.AtLeast(4, 1, 0),
+		AtomicMetadata:  v.AtLeast(4, 2, 8),
+		Touch:           v.AtLeast(4, 2, 9),
+		ReplicaAccess:   v.AtLeast(4, 2, 9),
+		ReplicaTruncate: v.AtLeast(4, 2, 11),
+		SwitchUser:      v.AtLeast(4, 3, 1),
+		GenQuery2:       v.AtLeast(4, 3, 2),
+		known:           true,
+	}
+}
+
+// Capabilities returns what the server supports, as detected when the connection was established
+func (con *Connection) Capabilities() Capabilities {
+	con.mu.RLock()
+	defer con.mu.RUnlock()
+
+	return con.caps
+}
+
+// detectCapabilities reads the server version from the handshake of the connection, and stores its capabilities.
+// Failing to detect them isn't fatal, they're then unknown.
+func (con *Connection) detectCapabilities() {
+	v, err := con.ServerVersion()
+	if err != nil {
+		con.logf("unable to detect the version of %v: %v", con.Options.Host, err)
+		return
+	}
+
+	caps := capabilitiesOf(v)
+
+	ccon := con.GetCcon()
+	caps.Negotiation = C.GoString(&ccon.negotiation_results[0])
+	caps.SSL = ccon.ssl_on != 0
+	con.ReturnCcon(ccon)
+
+	con.mu.Lock()
+	con.caps = caps
+	con.mu.Unlock()
+}
+
+// lacks returns true if the server is known not to have the capability reported by has
+func (con *Connection) lacks(has func(Capabilities) bool) bool {
+	caps := con.Capabilities()
+
+	return caps.known && !has(caps)
+}
+
+// requireCapability returns an error for the operation specified if the server is known not to have the
+// capability reported by has, which was introduced in version since
+func (con *Connection) requireCapability(op string, since string, has func(Capabilities) bool) error {
+	if con.lacks(has) {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS %v Failed: requires iRODS %v or later, the server runs %v", op, since, con.Capabilities().Version))
+	}
+
+	return nil
+}
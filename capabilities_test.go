@@ -0,0 +1,62 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestCapabilitiesOf(t *testing.T) {
+	for _, c := range []struct {
+		release  string
+		atomic   bool
+		touch    bool
+		truncate bool
+		gq2      bool
+	}{
+		{"rods4.1.10", false, false, false, false},
+		{"rods4.2.8", true, false, false, false},
+		{"rods4.2.9", true, true, false, false},
+		{"rods4.2.11", true, true, true, false},
+		{"rods4.3.2", true, true, true, true},
+	} {
+		v, err := ParseServerVersion(c.release)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		caps := capabilitiesOf(v)
+
+		if !caps.Known() || !caps.ZoneReport {
+			t.Errorf("%v: expected known capabilities with ZoneReport, got %+v", c.release, caps)
+		}
+
+		if caps.AtomicMetadata != c.atomic || caps.Touch != c.touch || caps.ReplicaTruncate != c.truncate || caps.GenQuery2 != c.gq2 {
+			t.Errorf("%v: unexpected capabilities %+v", c.release, caps)
+		}
+	}
+}
+
+func TestCapabilitiesString(t *testing.T) {
+	var caps Capabilities
+
+	if caps.Known() || caps.String() != "unknown" {
+		t.Errorf("Expected unknown capabilities, got %v", caps)
+	}
+
+	caps = capabilitiesOf(ServerVersion{Major: 4, Minor: 2, Patch: 11})
+
+	if caps.String() != "4.2.11" {
+		t.Errorf("Unexpected summary %q", caps.String())
+	}
+
+	caps.Negotiation = "CS_NEG_USE_SSL"
+
+	if caps.String() != "4.2.11 (CS_NEG_USE_SSL)" {
+		t.Errorf("Unexpected summary %q", caps.String())
+	}
+}
@@ -0,0 +1,240 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// CASRefCountAttr is the attribute of the AVU holding the number of references to a content-addressed object
+const CASRefCountAttr = "gorods:cas:refcount"
+
+// CAS is a content-addressable store on top of an iRODS collection. Objects are stored by their SHA-256 hash
+// under root/ab/cd/abcd..., so identical content is only stored once. Every Put of existing content increments
+// a reference count AVU, and Release decrements it, deleting the object when no references remain.
+// Reference counting isn't atomic, so a CAS shouldn't be modified concurrently by multiple clients.
+type CAS struct {
+	con      *Connection
+	root     string
+	Resource interface{}
+}
+
+// CAS returns a content-addressable store rooted at the collection specified. An empty root uses /<zone>/cas.
+func (con *Connection) CAS(root string) *CAS {
+	cas := new(CAS)
+
+	if root == "" {
+		root = "/" + con.Options.Zone + "/cas"
+	}
+
+	cas.con = con
+	cas.root = strings.TrimRight(root, "/")
+
+	return cas
+}
+
+// Hash returns the hash used to address content, computed with the registered ChecksumSHA256 provider
+func (cas *CAS) Hash(content []byte) string {
+	h, _ := Checksummer(ChecksumSHA256)
+	h.Write(content)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Path returns the iRODS path of the object addressed by hash
+func (cas *CAS) Path(hash string) string {
+	return cas.root + "/" + hash[0:2] + "/" + hash[2:4] + "/" + hash
+}
+
+func validHash(hash string) error {
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha256.Size*2 {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS CAS Failed: invalid hash %q", hash))
+	}
+
+	return nil
+}
+
+// Exists returns true if content with the hash specified is stored. It returns an error if the hash is invalid or
+// the catalog can't be checked.
+func (cas *CAS) Exists(hash string) (bool, error) {
+	if err := validHash(hash); err != nil {
+		return false, err
+	}
+
+	typ, err := cas.con.PathType(cas.Path(hash))
+	if IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return typ == DataObjType, nil
+}
+
+// Put stores content, unless identical content is already stored, and adds a reference to it. Returns the content hash.
+func (cas *CAS) Put(content []byte) (string, error) {
+	hash := cas.Hash(content)
+
+	if exists, err := cas.Exists(hash); err != nil {
+		return "", err
+	} else if exists {
+		_, err := cas.addRef(hash, 1)
+		return hash, err
+	}
+
+	col, err := cas.mkdirs(path.Dir(cas.Path(hash)))
+	if err != nil {
+		return "", err
+	}
+
+	obj, err := col.CreateDataObj(DataObjOptions{
+		Name:     hash,
+		Size:     int64(len(content)),
+		Mode:     0750,
+		Resource: cas.Resource,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(content) > 0 {
+		if err := obj.Write(content); err != nil {
+			return "", err
+		}
+	} else if err := obj.Close(); err != nil {
+		return "", err
+	}
+
+	if _, err := obj.AddMeta(Meta{Attribute: CASRefCountAttr, Value: "1"}); err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// PutFile reads the local file specified and stores its contents with Put
+func (cas *CAS) PutFile(localPath string) (string, error) {
+	content, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		return "", newError(Fatal, -1, fmt.Sprintf("iRODS CAS Put Failed: %v", err))
+	}
+
+	return cas.Put(content)
+}
+
+// Get returns the content addressed by hash
+func (cas *CAS) Get(hash string) ([]byte, error) {
+	obj, err := cas.Open(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj.Read()
+}
+
+// Open returns the *DataObj holding the content addressed by hash, for streaming reads
+func (cas *CAS) Open(hash string) (*DataObj, error) {
+	if err := validHash(hash); err != nil {
+		return nil, err
+	}
+
+	return cas.con.DataObject(cas.Path(hash))
+}
+
+// RefCount returns the number of references to the content addressed by hash, without modifying its AVUs
+func (cas *CAS) RefCount(hash string) (int, error) {
+	return cas.addRef(hash, 0)
+}
+
+// Release removes a reference to the content addressed by hash, deleting the object when none remain.
+// Returns the remaining reference count.
+func (cas *CAS) Release(hash string) (int, error) {
+	count, err := cas.addRef(hash, -1)
+	if err != nil {
+		return 0, err
+	}
+
+	if count <= 0 {
+		obj, err := cas.Open(hash)
+		if err != nil {
+			return 0, err
+		}
+
+		return 0, obj.Delete(false)
+	}
+
+	return count, nil
+}
+
+// addRef adds delta to the reference count AVU and returns the new count
+func (cas *CAS) addRef(hash string, delta int) (int, error) {
+	obj, err := cas.Open(hash)
+	if err != nil {
+		return 0, err
+	}
+
+	mc, err := obj.Meta()
+	if err != nil {
+		return 0, err
+	}
+
+	m, err := mc.First(CASRefCountAttr)
+	if err != nil {
+		// Objects stored without a count are considered to have a single reference
+		if delta == 0 {
+			return 1, nil
+		}
+
+		if _, err := mc.Add(Meta{Attribute: CASRefCountAttr, Value: strconv.Itoa(1 + delta)}); err != nil {
+			return 0, err
+		}
+
+		return 1 + delta, nil
+	}
+
+	count, _ := strconv.Atoi(m.Value)
+
+	if delta == 0 {
+		return count, nil
+	}
+
+	count += delta
+
+	if _, err := m.SetValue(strconv.Itoa(count)); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// mkdirs opens the collection at p, creating it and any missing parents below the CAS root's parent
+func (cas *CAS) mkdirs(p string) (*Collection, error) {
+	if col, err := cas.con.Collection(CollectionOptions{Path: p, SkipCache: true}); err == nil {
+		return col, nil
+	}
+
+	if p == "/" || !strings.HasPrefix(p, path.Dir(cas.root)) {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS CAS Failed: can't create collection %v", p))
+	}
+
+	parent, err := cas.mkdirs(path.Dir(p))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := parent.CreateSubCollection(path.Base(p)); err != nil {
+		return nil, err
+	}
+
+	return cas.con.Collection(CollectionOptions{Path: p, SkipCache: true})
+}
@@ -0,0 +1,24 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestCASPath(t *testing.T) {
+	cas := (&Connection{Options: &ConnectionOptions{Zone: "tempZone"}}).CAS("")
+	hash := cas.Hash([]byte("hello"))
+
+	if p := cas.Path(hash); p != "/tempZone/cas/2c/f2/"+hash {
+		t.Errorf("Unexpected path %v", p)
+	}
+
+	if exists, err := cas.Exists("not-a-hash"); err == nil || exists {
+		t.Errorf("Expected an invalid hash to be reported, got %v, %v", exists, err)
+	}
+}
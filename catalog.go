@@ -0,0 +1,208 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+)
+
+// Token namespaces used by Tokens. The token table holds the values the catalog accepts for data types,
+// user types, zone types and so on.
+const (
+	TokenDataType     = "data_type"
+	TokenUserType     = "user_type"
+	TokenZoneType     = "zone_type"
+	TokenAccessType   = "access_type"
+	TokenObjectType   = "object_type"
+	TokenResourceType = "resc_type"
+)
+
+// Token is an entry of the catalog token table, like a registered data type
+type Token struct {
+	Id         string
+	Namespace  string
+	Name       string
+	Value      string
+	Value2     string
+	Value3     string
+	Comment    string
+	CreateTime time.Time
+	ModifyTime time.Time
+}
+
+const tokenQuery = "select TOKEN_ID, TOKEN_NAMESPACE, TOKEN_NAME, TOKEN_VALUE, TOKEN_VALUE2, TOKEN_VALUE3, TOKEN_COMMENT, TOKEN_CREATE_TIME, TOKEN_MODIFY_TIME"
+
+// Tokens returns the entries of the token table in the namespace specified (TokenDataType, TokenUserType, ...)
+func (con *Connection) Tokens(namespace string) ([]Token, error) {
+	result, err := con.IQuest(fmt.Sprintf("%v where TOKEN_NAMESPACE = '%v'", tokenQuery, namespace), false)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]Token, 0, len(result))
+
+	for _, row := range result {
+		response = append(response, Token{
+			Id:         row["TOKEN_ID"],
+			Namespace:  row["TOKEN_NAMESPACE"],
+			Name:       row["TOKEN_NAME"],
+			Value:      row["TOKEN_VALUE"],
+			Value2:     row["TOKEN_VALUE2"],
+			Value3:     row["TOKEN_VALUE3"],
+			Comment:    row["TOKEN_COMMENT"],
+			CreateTime: timeStringToTime(row["TOKEN_CREATE_TIME"]),
+			ModifyTime: timeStringToTime(row["TOKEN_MODIFY_TIME"]),
+		})
+	}
+
+	return response, nil
+}
+
+// DataTypes returns the names of the data types registered in the catalog, those reported in ObjStat.DataType
+func (con *Connection) DataTypes() ([]string, error) {
+	tokens, err := con.Tokens(TokenDataType)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]string, len(tokens))
+	for i, token := range tokens {
+		response[i] = token.Name
+	}
+
+	return response, nil
+}
+
+// TicketInfo describes a ticket, as listed by iticket ls. Path is the data object or collection the ticket
+// grants access to, and ObjectType is "data" or "collection". Limits of 0 mean unrestricted, and a zero Expiry
+// means the ticket never expires.
+type TicketInfo struct {
+	Id             string
+	Ticket         string
+	Type           string
+	ObjectType     string
+	Path           string
+	Owner          string
+	OwnerZone      string
+	UsesLimit      int
+	UsesCount      int
+	WriteFileLimit int
+	WriteFileCount int
+	WriteByteLimit int64
+	WriteByteCount int64
+	Expiry         time.Time
+	CreateTime     time.Time
+	ModifyTime     time.Time
+}
+
+// Expired returns true if the ticket has an expiry that has passed
+func (t TicketInfo) Expired() bool {
+	return !t.Expiry.IsZero() && time.Now().After(t.Expiry)
+}
+
+// Exhausted returns true if the ticket has a use limit that has been reached
+func (t TicketInfo) Exhausted() bool {
+	return t.UsesLimit > 0 && t.UsesCount >= t.UsesLimit
+}
+
+const ticketQuery = "select TICKET_ID, TICKET_STRING, TICKET_TYPE, TICKET_OBJECT_TYPE, TICKET_OWNER_NAME, TICKET_OWNER_ZONE, " +
+	"TICKET_USES_LIMIT, TICKET_USES_COUNT, TICKET_WRITE_FILE_LIMIT, TICKET_WRITE_FILE_COUNT, TICKET_WRITE_BYTE_LIMIT, " +
+	"TICKET_WRITE_BYTE_COUNT, TICKET_EXPIRY_TS, TICKET_CREATE_TIME, TICKET_MODIFY_TIME"
+
+// Tickets returns the tickets visible to the connected user: their own, or every ticket for an admin
+func (con *Connection) Tickets() ([]TicketInfo, error) {
+	return con.tickets("")
+}
+
+// Ticket returns the details of the ticket specified
+func (con *Connection) Ticket(ticket string) (*TicketInfo, error) {
+	tickets, err := con.tickets(fmt.Sprintf(" and TICKET_STRING = '%v'", ticket))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tickets) == 0 {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Ticket Failed: ticket %v not found", ticket))
+	}
+
+	return &tickets[0], nil
+}
+
+// PathTickets returns the tickets granting access to the data object or collection at p
+func (con *Connection) PathTickets(p string) ([]TicketInfo, error) {
+	tickets, err := con.Tickets()
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]TicketInfo, 0)
+
+	for _, t := range tickets {
+		if t.Path == p {
+			response = append(response, t)
+		}
+	}
+
+	return response, nil
+}
+
+// tickets runs the data object and collection ticket queries, filter is appended to their conditions.
+// The object columns join different tables, so the two kinds of tickets can't be fetched in a single query.
+func (con *Connection) tickets(filter string) ([]TicketInfo, error) {
+	response := make([]TicketInfo, 0)
+
+	data, err := con.IQuest(ticketQuery+", TICKET_DATA_NAME, TICKET_DATA_COLL_NAME where TICKET_OBJECT_TYPE = 'data'"+filter, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range data {
+		response = append(response, ticketFromRow(row, path.Join(row["TICKET_DATA_COLL_NAME"], row["TICKET_DATA_NAME"])))
+	}
+
+	colls, err := con.IQuest(ticketQuery+", TICKET_COLL_NAME where TICKET_OBJECT_TYPE = 'collection'"+filter, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range colls {
+		response = append(response, ticketFromRow(row, row["TICKET_COLL_NAME"]))
+	}
+
+	return response, nil
+}
+
+func ticketFromRow(row map[string]string, p string) TicketInfo {
+	t := TicketInfo{
+		Id:         row["TICKET_ID"],
+		Ticket:     row["TICKET_STRING"],
+		Type:       row["TICKET_TYPE"],
+		ObjectType: row["TICKET_OBJECT_TYPE"],
+		Path:       p,
+		Owner:      row["TICKET_OWNER_NAME"],
+		OwnerZone:  row["TICKET_OWNER_ZONE"],
+		CreateTime: timeStringToTime(row["TICKET_CREATE_TIME"]),
+		ModifyTime: timeStringToTime(row["TICKET_MODIFY_TIME"]),
+	}
+
+	t.UsesLimit, _ = strconv.Atoi(row["TICKET_USES_LIMIT"])
+	t.UsesCount, _ = strconv.Atoi(row["TICKET_USES_COUNT"])
+	t.WriteFileLimit, _ = strconv.Atoi(row["TICKET_WRITE_FILE_LIMIT"])
+	t.WriteFileCount, _ = strconv.Atoi(row["TICKET_WRITE_FILE_COUNT"])
+	t.WriteByteLimit, _ = strconv.ParseInt(row["TICKET_WRITE_BYTE_LIMIT"], 10, 64)
+	t.WriteByteCount, _ = strconv.ParseInt(row["TICKET_WRITE_BYTE_COUNT"], 10, 64)
+
+	// An expiry of 0 (or none) means the ticket never expires
+	if ts, _ := strconv.ParseInt(row["TICKET_EXPIRY_TS"], 10, 64); ts > 0 {
+		t.Expiry = time.Unix(ts, 0)
+	}
+
+	return t
+}
@@ -0,0 +1,38 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestTicketFromRow(t *testing.T) {
+	row := map[string]string{
+		"TICKET_STRING":      "abc",
+		"TICKET_TYPE":        "read",
+		"TICKET_OBJECT_TYPE": "data",
+		"TICKET_USES_LIMIT":  "3",
+		"TICKET_USES_COUNT":  "3",
+		"TICKET_EXPIRY_TS":   "0",
+	}
+
+	ticket := ticketFromRow(row, "/tempZone/home/rods/file.txt")
+
+	if ticket.Ticket != "abc" || ticket.Path != "/tempZone/home/rods/file.txt" {
+		t.Errorf("Unexpected ticket %+v", ticket)
+	}
+
+	if !ticket.Exhausted() || ticket.Expired() || !ticket.Expiry.IsZero() {
+		t.Errorf("Unexpected limits for ticket %+v", ticket)
+	}
+
+	row["TICKET_EXPIRY_TS"] = "1000"
+
+	if ticket = ticketFromRow(row, ""); !ticket.Expired() {
+		t.Errorf("Expected ticket %+v to be expired", ticket)
+	}
+}
@@ -0,0 +1,445 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Change is a single entry of a ChangeLog: a mutating call, its arguments and its result.
+// Op is the operation name (put, delete, mkdir, move, copy, meta, chmod or touch), Error is empty if the call succeeded.
+type Change struct {
+	Time   time.Time         `json:"time"`
+	Op     string            `json:"op"`
+	Path   string            `json:"path"`
+	Dest   string            `json:"dest,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+	Size   int64             `json:"size,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// ChangeLog is an append-only journal of every mutating call made by the connections it's set on
+// (ConnectionOptions.ChangeLog), written as JSON lines. Failed calls are logged too, with their error.
+// A ChangeLog can be replayed against another zone with Connection.Replay. It is safe for use by multiple goroutines.
+type ChangeLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	c   io.Closer
+	err error
+}
+
+// OpenChangeLog opens the journal at localPath for appending, creating it if needed
+func OpenChangeLog(localPath string) (*ChangeLog, error) {
+	f, err := os.OpenFile(localPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Change Log Failed: %v", err))
+	}
+
+	return &ChangeLog{enc: json.NewEncoder(f), c: f}, nil
+}
+
+// NewChangeLog returns a ChangeLog writing to w
+func NewChangeLog(w io.Writer) *ChangeLog {
+	return &ChangeLog{enc: json.NewEncoder(w)}
+}
+
+func (cl *ChangeLog) record(e *Event) {
+	c := Change{
+		Time:   e.Start,
+		Op:     opName(e.Op),
+		Path:   e.Path,
+		Dest:   e.Dest,
+		Params: e.Params,
+		Size:   e.Size,
+	}
+
+	if e.Err != nil {
+		c.Error = e.Err.Error()
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if err := cl.enc.Encode(c); err != nil && cl.err == nil {
+		cl.err = err
+	}
+}
+
+// Err returns the first error encountered writing the journal, if any
+func (cl *ChangeLog) Err() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	return cl.err
+}
+
+// Close closes the journal file, if it was opened by OpenChangeLog
+func (cl *ChangeLog) Close() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.c == nil {
+		return nil
+	}
+
+	return cl.c.Close()
+}
+
+func (con *Connection) changeLog() *ChangeLog {
+	if con.Options == nil {
+		return nil
+	}
+
+	return con.Options.ChangeLog
+}
+
+// isMutation returns true for the operations logged by ChangeLog
+func isMutation(op int) bool {
+	return op != OpOpen && op != OpQuery && op != OpChange
+}
+
+// ReplayOptions are used with Connection.Replay.
+// Zones maps source zone names to target zone names: paths and ACL principals in a mapped zone are rewritten.
+// Resources maps recorded resource names to target resources, unmapped ones use the target's default resource.
+// Source is used to read the content of puts whose local file is gone (or that were created with CreateDataObj),
+// note that the current content of the source object is copied, not the content at the time of the put.
+// Only changes made after Since are replayed, if set. ContinueOnError records failures in the report instead of stopping.
+type ReplayOptions struct {
+	Zones           map[string]string
+	Resources       map[string]string
+	Source          *Connection
+	Since           time.Time
+	ContinueOnError bool
+}
+
+// ReplayFailure records a change that couldn't be replayed
+type ReplayFailure struct {
+	Change Change
+	Err    error
+}
+
+// String returns the operation, path and error of the failure
+func (f ReplayFailure) String() string {
+	return fmt.Sprintf("%v %v: %v", f.Change.Op, f.Change.Path, f.Err)
+}
+
+// ReplayReport is returned by Connection.Replay. Skipped counts changes that failed when recorded, were made before
+// ReplayOptions.Since, or can't be replayed (metadata of users, groups and resources).
+type ReplayReport struct {
+	Applied  int
+	Skipped  int
+	Failures []ReplayFailure
+}
+
+// OK returns true if no change failed
+func (r *ReplayReport) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// ReplayFile is Replay for a journal file, which can be gzipped if its name ends in ".gz"
+func (con *Connection) ReplayFile(localPath string, opts ReplayOptions) (*ReplayReport, error) {
+	in, err := openImportFile(localPath)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Replay Failed: %v", err))
+	}
+	defer in.Close()
+
+	return con.Replay(in, opts)
+}
+
+// Replay applies the changes read from the journal r (written by a ChangeLog) to the connection's zone, in order.
+// Along with a ChangeLog set on the connections of a client, this lets a zone be migrated from the client side:
+// keep writing to the old zone while the journal is replayed against the new one, then switch over.
+func (con *Connection) Replay(r io.Reader, opts ReplayOptions) (*ReplayReport, error) {
+	report := new(ReplayReport)
+	dec := json.NewDecoder(r)
+
+	for {
+		var c Change
+
+		if err := dec.Decode(&c); err == io.EOF {
+			break
+		} else if err != nil {
+			return report, newError(Fatal, -1, fmt.Sprintf("iRODS Replay Failed: %v", err))
+		}
+
+		if c.Error != "" || (!opts.Since.IsZero() && !c.Time.After(opts.Since)) {
+			report.Skipped++
+			continue
+		}
+
+		applied, err := con.replayChange(c, opts)
+		if err != nil {
+			report.Failures = append(report.Failures, ReplayFailure{c, err})
+
+			if !opts.ContinueOnError {
+				return report, err
+			}
+
+			continue
+		}
+
+		if applied {
+			report.Applied++
+		} else {
+			report.Skipped++
+		}
+	}
+
+	return report, nil
+}
+
+// mapPath rewrites p if it is in one of the mapped zones
+func (opts ReplayOptions) mapPath(p string) string {
+	for from, to := range opts.Zones {
+		prefix := "/" + from
+
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return "/" + to + p[len(prefix):]
+		}
+	}
+
+	return p
+}
+
+// mapZone returns the target zone of zone
+func (opts ReplayOptions) mapZone(zone string) string {
+	if to, ok := opts.Zones[zone]; ok {
+		return to
+	}
+
+	return zone
+}
+
+// replayChange applies c to the connection's zone, returning false if the change can't be replayed
+func (con *Connection) replayChange(c Change, opts ReplayOptions) (bool, error) {
+	p := opts.mapPath(c.Path)
+	force := c.Params["force"] == "true"
+	recursive := c.Params["recursive"] == "true"
+
+	switch c.Op {
+	case "put":
+		return true, con.replayPut(c, p, opts)
+
+	case "delete":
+		if c.Params["type"] == "collection" {
+			col, err := con.Collection(CollectionOptions{Path: p, SkipCache: true})
+			if err != nil {
+				return true, err
+			}
+
+			return true, col.Rm(recursive, force)
+		}
+
+		obj, err := con.DataObject(p)
+		if err != nil {
+			return true, err
+		}
+
+		return true, obj.Rm(recursive, force)
+
+	case "mkdir":
+		parent, err := con.Collection(CollectionOptions{Path: path.Dir(p), SkipCache: true})
+		if err != nil {
+			return true, err
+		}
+
+		_, err = parent.CreateSubCollection(path.Base(p))
+
+		return true, err
+
+	case "move":
+		dest := opts.mapPath(c.Dest)
+
+		var obj interface {
+			MoveTo(interface{}) error
+			Rename(string) error
+			Name() string
+		}
+
+		if c.Params["type"] == "collection" {
+			col, err := con.Collection(CollectionOptions{Path: p, SkipCache: true})
+			if err != nil {
+				return true, err
+			}
+
+			obj = col
+		} else {
+			do, err := con.DataObject(p)
+			if err != nil {
+				return true, err
+			}
+
+			obj = do
+		}
+
+		if path.Dir(dest) != path.Dir(p) {
+			if err := obj.MoveTo(path.Dir(dest)); err != nil {
+				return true, err
+			}
+		}
+
+		if path.Base(dest) != obj.Name() {
+			return true, obj.Rename(path.Base(dest))
+		}
+
+		return true, nil
+
+	case "copy":
+		obj, err := con.DataObject(p)
+		if err != nil {
+			return true, err
+		}
+
+		return true, obj.CopyToOpts(path.Dir(opts.mapPath(c.Dest)), DataObjOptions{
+			Force:    force,
+			Resource: opts.Resources[c.Params["resource"]],
+		})
+
+	case "meta":
+		typ, ops, err := replayMetaOperations(c)
+		if err != nil {
+			return true, err
+		} else if ops == nil {
+			return false, nil
+		}
+
+		return true, con.ApplyMetaOperations(p, typ, ops)
+
+	case "chmod":
+		level, err := chmodLevelOf(c.Params["access"])
+		if err != nil {
+			return true, err
+		}
+
+		zone := c.Params["zone"]
+		if zone != "" {
+			zone = opts.mapZone(zone)
+		}
+
+		return true, con.chmodPath(p, c.Params["user"], zone, level, recursive)
+
+	case "touch":
+		touch := TouchOptions{NoCreate: c.Params["no_create"] == "true"}
+
+		if secs, err := strconv.ParseInt(c.Params["time"], 10, 64); err == nil {
+			touch.Time = time.Unix(secs, 0)
+		}
+
+		return true, con.Touch(p, touch)
+
+	case "truncate":
+		size, err := strconv.ParseInt(c.Params["size"], 10, 64)
+		if err != nil {
+			return true, newError(Fatal, -1, fmt.Sprintf("iRODS Replay Failed: invalid truncate size %q", c.Params["size"]))
+		}
+
+		return true, con.Truncate(p, size, TruncateOptions{Resource: opts.Resources[c.Params["resource"]]})
+	}
+
+	return false, nil
+}
+
+// replayPut uploads the local file of a put change to p, or the source object if the file is gone
+func (con *Connection) replayPut(c Change, p string, opts ReplayOptions) error {
+	localPath := c.Params["local"]
+
+	if _, err := os.Stat(localPath); localPath == "" || err != nil {
+		if opts.Source == nil {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Replay Failed: no content for %v, set ReplayOptions.Source", c.Path))
+		}
+
+		src, err := opts.Source.DataObject(c.Path)
+		if err != nil {
+			return err
+		}
+
+		tmp, err := ioutil.TempFile("", "gorods-replay")
+		if err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Replay Failed: %v", err))
+		}
+
+		localPath = tmp.Name()
+		tmp.Close()
+		defer os.Remove(localPath)
+
+		if err := src.DownloadTo(localPath); err != nil {
+			return err
+		}
+	}
+
+	col, err := con.Collection(CollectionOptions{Path: path.Dir(p), SkipCache: true})
+	if err != nil {
+		return err
+	}
+
+	_, err = col.Put(localPath, DataObjOptions{
+		Name:     path.Base(p),
+		Force:    c.Params["force"] == "true",
+		Resource: opts.Resources[c.Params["resource"]],
+	})
+
+	return err
+}
+
+// replayMetaOperations returns the atomic operations equivalent to a meta change, or nil ops if the change is
+// on an entity other than a data object or collection
+func replayMetaOperations(c Change) (int, []MetaOperation, error) {
+	var typ int
+
+	switch c.Params["type"] {
+	case "d", MetaEntityDataObj:
+		typ = DataObjType
+	case "C", MetaEntityCollection:
+		typ = CollectionType
+	default:
+		return 0, nil, nil
+	}
+
+	old := MetaOperation{Attribute: c.Params["attribute"], Value: c.Params["value"], Units: c.Params["units"]}
+
+	switch c.Params["action"] {
+	case "add":
+		old.Operation = MetaOpAdd
+		return typ, []MetaOperation{old}, nil
+	case "rm":
+		old.Operation = MetaOpRemove
+		return typ, []MetaOperation{old}, nil
+	case "set":
+		old.Operation = MetaOpRemove
+		set := MetaOperation{Operation: MetaOpAdd, Attribute: c.Params["new_attribute"], Value: c.Params["new_value"], Units: c.Params["new_units"]}
+		return typ, []MetaOperation{old, set}, nil
+	case "apply":
+		var ops []MetaOperation
+		if err := json.Unmarshal([]byte(c.Params["operations"]), &ops); err != nil {
+			return typ, nil, newError(Fatal, -1, fmt.Sprintf("iRODS Replay Failed: %v", err))
+		}
+		return typ, ops, nil
+	}
+
+	return typ, nil, newError(Fatal, -1, fmt.Sprintf("iRODS Replay Failed: unknown meta action %q", c.Params["action"]))
+}
+
+// chmodLevelOf parses the access level logged by a chmod change
+func chmodLevelOf(access string) (int, error) {
+	for _, level := range []int{Null, Read, Write, Own, Inherit, NoInherit} {
+		if getTypeString(level) == access {
+			return level, nil
+		}
+	}
+
+	return Null, newError(Fatal, -1, fmt.Sprintf("iRODS Replay Failed: unknown access level %q", access))
+}
@@ -0,0 +1,78 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChangeLogRecord(t *testing.T) {
+	var buf bytes.Buffer
+
+	cl := NewChangeLog(&buf)
+
+	cl.record(&Event{Op: OpMove, Path: "/tempZone/home/rods/a.txt", Dest: "/tempZone/home/rods/b.txt", Params: map[string]string{"type": "dataobj"}, Start: time.Unix(1500000000, 0)})
+	cl.record(&Event{Op: OpDelete, Path: "/tempZone/home/rods/c", Err: errors.New("denied")})
+
+	dec := json.NewDecoder(&buf)
+
+	var move, del Change
+
+	if err := dec.Decode(&move); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&del); err != nil {
+		t.Fatal(err)
+	}
+
+	if move.Op != "move" || move.Dest != "/tempZone/home/rods/b.txt" || move.Params["type"] != "dataobj" || move.Error != "" {
+		t.Errorf("Unexpected move change: %+v", move)
+	}
+
+	if del.Op != "delete" || del.Error != "denied" {
+		t.Errorf("Unexpected delete change: %+v", del)
+	}
+}
+
+func TestReplayMapping(t *testing.T) {
+	opts := ReplayOptions{Zones: map[string]string{"tempZone": "newZone"}}
+
+	for p, expected := range map[string]string{
+		"/tempZone":               "/newZone",
+		"/tempZone/home/rods":     "/newZone/home/rods",
+		"/tempZoneOld/home/rods":  "/tempZoneOld/home/rods",
+		"/otherZone/home/rods/ab": "/otherZone/home/rods/ab",
+	} {
+		if got := opts.mapPath(p); got != expected {
+			t.Errorf("mapPath(%v) = %v, expected %v", p, got, expected)
+		}
+	}
+
+	typ, ops, err := replayMetaOperations(Change{Op: "meta", Params: map[string]string{
+		"action": "set", "type": "C", "attribute": "a", "value": "1",
+		"new_attribute": "a", "new_value": "2", "new_units": "u",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if typ != CollectionType || len(ops) != 2 || ops[0].Operation != MetaOpRemove || ops[1].Operation != MetaOpAdd || ops[1].Value != "2" {
+		t.Errorf("Unexpected operations for a set: %v %+v", typ, ops)
+	}
+
+	if _, ops, err := replayMetaOperations(Change{Op: "meta", Params: map[string]string{"action": "add", "type": "u"}}); err != nil || ops != nil {
+		t.Errorf("Expected user metadata to be skipped, got %+v %v", ops, err)
+	}
+
+	if level, err := chmodLevelOf("write"); err != nil || level != Write {
+		t.Errorf("chmodLevelOf(write) = %v, %v", level, err)
+	}
+}
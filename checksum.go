@@ -0,0 +1,136 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Checksum algorithm names used with RegisterChecksum. MD5 and SHA256 are the algorithms iRODS itself uses
+// for catalog checksums, and are registered by default using the standard library.
+const (
+	ChecksumMD5    = "md5"
+	ChecksumSHA256 = "sha256"
+)
+
+var (
+	checksumProviders = map[string]func() hash.Hash{
+		ChecksumMD5:    md5.New,
+		ChecksumSHA256: sha256.New,
+	}
+	checksumMu sync.RWMutex
+)
+
+// RegisterChecksum sets the hash.Hash constructor used for the algorithm name specified. Registering ChecksumMD5
+// or ChecksumSHA256 replaces the standard library implementation used everywhere GoRODS digests data locally
+// (Sync, PutResumable, VerifyReplicas, CAS...), e.g. with a SIMD accelerated SHA-256. Other names (like "blake3") can be used
+// with LocalChecksum and Checksummer for application level verification.
+func RegisterChecksum(algorithm string, newHash func() hash.Hash) {
+	checksumMu.Lock()
+	defer checksumMu.Unlock()
+
+	checksumProviders[strings.ToLower(algorithm)] = newHash
+}
+
+// ChecksumAlgorithms returns the names of all registered checksum algorithms, sorted
+func ChecksumAlgorithms() []string {
+	checksumMu.RLock()
+	defer checksumMu.RUnlock()
+
+	names := make([]string, 0, len(checksumProviders))
+
+	for name := range checksumProviders {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Checksummer returns a new hash.Hash for the registered algorithm specified
+func Checksummer(algorithm string) (hash.Hash, error) {
+	checksumMu.RLock()
+	newHash, ok := checksumProviders[strings.ToLower(algorithm)]
+	checksumMu.RUnlock()
+
+	if !ok {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Checksum Failed: no provider registered for %v", algorithm))
+	}
+
+	return newHash(), nil
+}
+
+// LocalChecksum returns the hex encoded digest of the local file, using the registered algorithm specified
+func LocalChecksum(localPath string, algorithm string) (string, error) {
+	h, err := Checksummer(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	if err := digestFile(h, localPath); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// IRODSChecksum returns the checksum of the local file formatted like iRODS catalog checksums
+// ("sha2:" + base64 for SHA256, hex for MD5), so it can be compared with DataObj.Checksum()
+func IRODSChecksum(localPath string, algorithm string) (string, error) {
+	h, err := Checksummer(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	if err := digestFile(h, localPath); err != nil {
+		return "", err
+	}
+
+	return formatIRODSChecksum(algorithm, h.Sum(nil)), nil
+}
+
+func formatIRODSChecksum(algorithm string, sum []byte) string {
+	if strings.ToLower(algorithm) == ChecksumSHA256 {
+		return "sha2:" + base64.StdEncoding.EncodeToString(sum)
+	}
+
+	return hex.EncodeToString(sum)
+}
+
+// checksumAlgorithmOf returns the algorithm of an iRODS catalog checksum
+func checksumAlgorithmOf(chksum string) string {
+	if strings.HasPrefix(chksum, "sha2:") {
+		return ChecksumSHA256
+	}
+
+	return ChecksumMD5
+}
+
+func digestFile(h hash.Hash, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Checksum Failed: %v", err))
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Checksum Failed: %v", err))
+	}
+
+	return nil
+}
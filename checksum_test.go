@@ -0,0 +1,84 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"crypto/sha1"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestChecksumProviders(t *testing.T) {
+	f, err := ioutil.TempFile("", "gorods-checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString("hello")
+	f.Close()
+
+	if sum, err := IRODSChecksum(f.Name(), ChecksumMD5); err != nil || sum != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Unexpected md5 checksum %v, %v", sum, err)
+	}
+
+	if sum, err := IRODSChecksum(f.Name(), ChecksumSHA256); err != nil || sum != "sha2:LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=" {
+		t.Errorf("Unexpected sha2 checksum %v, %v", sum, err)
+	}
+
+	if _, err := LocalChecksum(f.Name(), "sha1"); err == nil {
+		t.Error("Expected an error for an unregistered algorithm")
+	}
+
+	RegisterChecksum("sha1", sha1.New)
+
+	t.Cleanup(func() {
+		checksumMu.Lock()
+		defer checksumMu.Unlock()
+
+		delete(checksumProviders, "sha1")
+	})
+
+	if sum, err := LocalChecksum(f.Name(), "SHA1"); err != nil || sum != "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d" {
+		t.Errorf("Unexpected sha1 checksum %v, %v", sum, err)
+	}
+}
+
+func TestLocalIRODSChecksums(t *testing.T) {
+	f, err := ioutil.TempFile("", "gorods-checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString("hello")
+	f.Close()
+
+	sums, err := localIRODSChecksums(f.Name(), ChecksumMD5, ChecksumSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sums) != 2 || sums[0] != "5d41402abc4b2a76b9719d911017c592" || sums[1] != "sha2:LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=" {
+		t.Errorf("Unexpected checksums %v", sums)
+	}
+}
+
+func TestInResource(t *testing.T) {
+	for hier, expected := range map[string]bool{
+		"demoResc":           true,
+		"demoResc;leaf":      true,
+		"demoResc2":          false,
+		"other;demoResc":     false,
+		"demoRescArchive;ab": false,
+	} {
+		if got := inResource(hier, "demoResc"); got != expected {
+			t.Errorf("Expected %v for %v, got %v", expected, hier, got)
+		}
+	}
+}
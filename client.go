@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, The BioTeam, Inc.                     ***
  *** For more information please refer to the LICENSE.md file  ***/
 
@@ -14,12 +17,53 @@ import (
 	// "strings"
 	// "time"
 	// "unsafe"
+
+	"github.com/jjacquay712/GoRODS/api"
 )
 
 // Client structs are used to store connection options, and instatiate connections with those options
 type Client struct {
 	Options    *ConnectionOptions
 	ConnectErr error
+
+	// Wrap, if set, is applied to every ConnectionAPI returned by Connect and OpenConnectionAPI,
+	// to layer logging, authorization or other decorators over the iRODS backed implementation
+	Wrap func(ConnectionAPI) ConnectionAPI
+}
+
+// ClientAPI is implemented by *Client and by the in-memory catalog in gorods/mock, so code that opens its own
+// connections can be given either
+type ClientAPI = api.ClientAPI
+
+// Connect opens a new connection using the client's options and returns it as a ConnectionAPI.
+// The caller must call Disconnect when done.
+func (cli *Client) Connect() (ConnectionAPI, error) {
+	if cli.ConnectErr != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Can't open new connection: %v", cli.ConnectErr))
+	}
+
+	con, err := NewConnection(cli.Options)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Can't open new connection: %v", err))
+	}
+
+	if cli.Wrap != nil {
+		return cli.Wrap(API(con)), nil
+	}
+
+	return API(con), nil
+}
+
+// OpenConnectionAPI is like OpenConnection, but passes the connection to the handler as a ConnectionAPI
+func (cli *Client) OpenConnectionAPI(handler func(ConnectionAPI)) error {
+	con, err := cli.Connect()
+	if err != nil {
+		return err
+	}
+
+	handler(con)
+
+	return con.Disconnect()
 }
 
 // OpenCollection will create a new connection using the previously configured iRODS client. It will execute the handler,
@@ -117,7 +161,7 @@ func (cli *Client) OpenConnection(handler func(*Connection)) error {
 // EnvironmentDefined and UserDefined constants are used in ConnectionOptions{ Type: ... }).
 // When EnvironmentDefined is specified, the options stored in ~/.irods/irods_environment.json will be used.
 // When UserDefined is specified you must also pass Host, Port, Username, and Zone. Password
-// should be set unless using an anonymous user account with tickets.
+// should be set unless using the anonymous user account (AuthType: AnonymousAuth) with tickets.
 func New(opts ConnectionOptions) (*Client, error) {
 	cli := new(Client)
 
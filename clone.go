@@ -0,0 +1,223 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// CloneOptions are used with Collection.CloneStructure. Meta lists the attributes of the collection AVUs to copy,
+// AllMeta copies every AVU instead. SkipACLs creates the collections without copying access levels or inheritance.
+type CloneOptions struct {
+	Meta     []string
+	AllMeta  bool
+	SkipACLs bool
+}
+
+// CloneFailure records a collection whose ACLs, inheritance or metadata couldn't be copied by Collection.CloneStructure
+type CloneFailure struct {
+	Path string
+	Err  error
+}
+
+// String returns the path and error of the failure
+func (f CloneFailure) String() string {
+	return fmt.Sprintf("%v: %v", f.Path, f.Err)
+}
+
+// CloneReport is returned by Collection.CloneStructure. Created is the number of collections created.
+type CloneReport struct {
+	Created  int
+	Failures []CloneFailure
+}
+
+// OK returns true if every collection was cloned completely
+func (r *CloneReport) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// cloneCollection is the state of a source collection read by CloneStructure
+type cloneCollection struct {
+	path    string
+	inherit bool
+	access  []pathAccess
+	meta    []MetaOperation
+}
+
+// CloneStructure recreates the collection and all of its sub-collections at dest, without copying any data objects.
+// The access levels and inheritance of each collection are copied (unless opts.SkipACLs), along with the AVUs selected by
+// opts. dest must not exist, its parent must. ACLs the new collections inherit from dest's parent are kept.
+// The returned error is only set if the source couldn't be read or dest couldn't be created, failures to copy the
+// ACLs or metadata of a collection are listed in the report.
+func (col *Collection) CloneStructure(dest string, opts CloneOptions) (*CloneReport, error) {
+	dest = strings.TrimRight(dest, "/")
+
+	if dest == col.path || strings.HasPrefix(dest, col.path+"/") {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS CloneStructure Failed: %v is inside %v", dest, col.path))
+	}
+
+	cols, err := col.con.cloneSource(col.path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := col.con.Collection(CollectionOptions{Path: path.Dir(dest), SkipCache: true})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CloneReport{Failures: make([]CloneFailure, 0)}
+
+	created := map[string]*Collection{path.Dir(dest): parent}
+
+	// Parents sort before their children, so every collection is created after its parent
+	for _, c := range cols {
+		target := dest + strings.TrimPrefix(c.path, col.path)
+
+		p, ok := created[path.Dir(target)]
+		if !ok {
+			continue
+		}
+
+		newCol, err := p.CreateSubCollection(path.Base(target))
+		if err != nil {
+			if c.path == col.path {
+				return nil, err
+			}
+
+			report.Failures = append(report.Failures, CloneFailure{Path: target, Err: err})
+			continue
+		}
+
+		created[target] = newCol
+		report.Created++
+	}
+
+	// ACLs and inheritance are copied once the whole tree exists, so the new sub-collections don't inherit them
+	for _, c := range cols {
+		target := dest + strings.TrimPrefix(c.path, col.path)
+
+		if _, ok := created[target]; !ok {
+			continue
+		}
+
+		if err := col.con.applyClone(target, c, opts); err != nil {
+			report.Failures = append(report.Failures, CloneFailure{Path: target, Err: err})
+		}
+	}
+
+	return report, nil
+}
+
+// applyClone copies the ACLs, inheritance and metadata of c to the collection at target
+func (con *Connection) applyClone(target string, c *cloneCollection, opts CloneOptions) error {
+	if !opts.SkipACLs {
+		for _, a := range c.access {
+			if err := con.chmodPath(target, a.Principal.Name, a.Principal.Zone, a.AccessLevel, false); err != nil {
+				return err
+			}
+		}
+
+		if c.inherit {
+			if err := con.chmodPath(target, "", "", Inherit, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return con.ApplyMetaOperations(target, CollectionType, c.meta)
+}
+
+// cloneSource reads the collections under p, with their ACLs, inheritance and the metadata selected by opts
+func (con *Connection) cloneSource(p string, opts CloneOptions) ([]*cloneCollection, error) {
+	zone, err := con.zoneHint(p)
+	if err != nil {
+		return nil, err
+	}
+
+	byPath := make(map[string]*cloneCollection)
+
+	for _, query := range []string{
+		fmt.Sprintf("select COLL_NAME, COLL_INHERITANCE where COLL_NAME = '%v'", p),
+		fmt.Sprintf("select COLL_NAME, COLL_INHERITANCE where COLL_NAME like '%v/%%'", p),
+	} {
+		result, err := con.IQuestZone(query, false, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range result {
+			byPath[row["COLL_NAME"]] = &cloneCollection{path: row["COLL_NAME"], inherit: row["COLL_INHERITANCE"] == "1"}
+		}
+	}
+
+	if _, ok := byPath[p]; !ok {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS CloneStructure Failed: Collection %v not found", p))
+	}
+
+	if !opts.SkipACLs {
+		_, access, err := con.treeAccess(p, true)
+		if err != nil {
+			return nil, err
+		}
+
+		for colPath, c := range byPath {
+			c.access = access[colPath]
+		}
+	}
+
+	if opts.AllMeta || len(opts.Meta) > 0 {
+		attrs := make(map[string]bool)
+		for _, a := range opts.Meta {
+			attrs[a] = true
+		}
+
+		for _, query := range []string{
+			fmt.Sprintf("select COLL_NAME, META_COLL_ATTR_NAME, META_COLL_ATTR_VALUE, META_COLL_ATTR_UNITS where COLL_NAME = '%v'", p),
+			fmt.Sprintf("select COLL_NAME, META_COLL_ATTR_NAME, META_COLL_ATTR_VALUE, META_COLL_ATTR_UNITS where COLL_NAME like '%v/%%'", p),
+		} {
+			result, err := con.IQuestZone(query, false, zone)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, row := range result {
+				c, ok := byPath[row["COLL_NAME"]]
+				if !ok || (!opts.AllMeta && !attrs[row["META_COLL_ATTR_NAME"]]) {
+					continue
+				}
+
+				c.meta = append(c.meta, MetaOperation{
+					Operation: MetaOpAdd,
+					Attribute: row["META_COLL_ATTR_NAME"],
+					Value:     row["META_COLL_ATTR_VALUE"],
+					Units:     row["META_COLL_ATTR_UNITS"],
+				})
+			}
+		}
+	}
+
+	return sortedCloneCollections(byPath), nil
+}
+
+// sortedCloneCollections returns the collections sorted by path, so parents come before their children
+func sortedCloneCollections(byPath map[string]*cloneCollection) []*cloneCollection {
+	cols := make([]*cloneCollection, 0, len(byPath))
+
+	for _, c := range byPath {
+		cols = append(cols, c)
+	}
+
+	sort.Slice(cols, func(i, j int) bool {
+		return cols[i].path < cols[j].path
+	})
+
+	return cols
+}
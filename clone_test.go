@@ -0,0 +1,33 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"path"
+	"testing"
+)
+
+func TestSortedCloneCollections(t *testing.T) {
+	byPath := make(map[string]*cloneCollection)
+	for _, p := range []string{"/tempZone/tpl/b/c", "/tempZone/tpl/b-2", "/tempZone/tpl", "/tempZone/tpl/b", "/tempZone/tpl/a"} {
+		byPath[p] = &cloneCollection{path: p}
+	}
+
+	cols := sortedCloneCollections(byPath)
+
+	if cols[0].path != "/tempZone/tpl" {
+		t.Fatalf("Expected the root first, got %v", cols[0].path)
+	}
+
+	seen := map[string]bool{cols[0].path: true}
+	for _, c := range cols[1:] {
+		if !seen[path.Dir(c.path)] {
+			t.Errorf("Expected the parent of %v before it", c.path)
+		}
+		seen[c.path] = true
+	}
+}
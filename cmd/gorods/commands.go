@@ -0,0 +1,358 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jjacquay712/GoRODS"
+)
+
+// accessLevels maps the chmod level names to their constants
+var accessLevels = map[string]int{
+	"null":      gorods.Null,
+	"read":      gorods.Read,
+	"write":     gorods.Write,
+	"own":       gorods.Own,
+	"inherit":   gorods.Inherit,
+	"noinherit": gorods.NoInherit,
+}
+
+// object opens the data object or collection at p
+func object(con *gorods.Connection, p string) (gorods.IRodsObj, error) {
+	typ, err := con.PathType(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ == gorods.CollectionType {
+		return con.Collection(gorods.CollectionOptions{Path: p, SkipCache: true})
+	}
+
+	return con.DataObject(p)
+}
+
+func runLs(con *gorods.Connection, args []string) error {
+	fs := newFlags("ls")
+	long := fs.Bool("l", false, "show sizes and modification times")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	entries, err := con.List(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		name := e.Name
+		if e.Type == gorods.CollectionType {
+			name += "/"
+		}
+
+		if *long {
+			fmt.Printf("%12d  %v  %v\n", e.Size, e.ModifyTime.Format("2006-01-02 15:04"), name)
+		} else {
+			fmt.Println(name)
+		}
+	}
+
+	return nil
+}
+
+func runGet(con *gorods.Connection, args []string) error {
+	fs := newFlags("get")
+	recursive := fs.Bool("r", false, "download a collection and its contents")
+	if err := parseArgs(fs, args, 1, 2); err != nil {
+		return err
+	}
+
+	p := fs.Arg(0)
+
+	localPath := path.Base(p)
+	if fs.NArg() == 2 {
+		localPath = fs.Arg(1)
+	}
+
+	obj, err := object(con, p)
+	if err != nil {
+		return err
+	}
+
+	if col, ok := obj.(*gorods.Collection); ok {
+		if !*recursive {
+			return fmt.Errorf("%v is a collection, use -r", p)
+		}
+
+		// Like iget -r, an existing destination directory receives the collection itself, not just its contents
+		if fs.NArg() == 2 {
+			localPath = localTarget(p, localPath)
+		}
+
+		if err := os.MkdirAll(localPath, 0755); err != nil {
+			return err
+		}
+
+		return col.DownloadTo(localPath)
+	}
+
+	return download(obj.(*gorods.DataObj), localTarget(p, localPath))
+}
+
+// localTarget returns the local path that the data object or collection at p is downloaded to: localPath, or
+// localPath/<name of p> if localPath is an existing directory
+func localTarget(p string, localPath string) string {
+	if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+		return filepath.Join(localPath, path.Base(p))
+	}
+
+	return localPath
+}
+
+// download streams the data object to localPath
+func download(obj *gorods.DataObj, localPath string) error {
+	f, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+
+	var werr error
+
+	err = obj.ReadChunk(4*1024*1024, func(chunk []byte) {
+		if werr == nil {
+			_, werr = f.Write(chunk)
+		}
+	})
+
+	if cerr := f.Close(); werr == nil {
+		werr = cerr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return werr
+}
+
+func runPut(con *gorods.Connection, args []string) error {
+	fs := newFlags("put")
+	force := fs.Bool("f", false, "overwrite existing data objects")
+	resource := fs.String("R", "", "target resource")
+	recursive := fs.Bool("r", false, "upload a directory and its contents")
+	if err := parseArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+
+	localPath, colPath := fs.Arg(0), strings.TrimRight(fs.Arg(1), "/")
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+
+	var resc interface{}
+	if *resource != "" {
+		resc = *resource
+	}
+
+	if info.IsDir() {
+		if !*recursive {
+			return fmt.Errorf("%v is a directory, use -r", localPath)
+		}
+
+		report, err := con.Sync(localPath, colPath+"/"+filepath.Base(filepath.Clean(localPath)), putSyncOptions(*force, resc))
+		if err != nil {
+			return err
+		}
+
+		return printSyncReport(report)
+	}
+
+	col, err := con.Collection(gorods.CollectionOptions{Path: colPath, SkipCache: true})
+	if err != nil {
+		return err
+	}
+
+	_, err = col.Put(localPath, gorods.DataObjOptions{
+		Name:     filepath.Base(localPath),
+		Size:     info.Size(),
+		Force:    *force,
+		Resource: resc,
+	})
+
+	return err
+}
+
+// putSyncOptions returns the options of the Sync uploading a directory for put -r, which replaces existing data
+// objects only with -f, like iput
+func putSyncOptions(force bool, resc interface{}) gorods.SyncOptions {
+	return gorods.SyncOptions{Resource: resc, NoOverwrite: !force}
+}
+
+func runRm(con *gorods.Connection, args []string) error {
+	fs := newFlags("rm")
+	recursive := fs.Bool("r", false, "remove a collection and its contents")
+	force := fs.Bool("f", false, "remove immediately instead of moving to the trash")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	obj, err := object(con, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if col, ok := obj.(*gorods.Collection); ok {
+		if !*recursive {
+			return fmt.Errorf("%v is a collection, use -r", fs.Arg(0))
+		}
+
+		return col.Rm(true, *force)
+	}
+
+	return obj.(*gorods.DataObj).Rm(false, *force)
+}
+
+func runMeta(con *gorods.Connection, args []string) error {
+	fs := newFlags("meta")
+	if err := parseArgs(fs, args, 2, -1); err != nil {
+		return err
+	}
+
+	action, p, avu := fs.Arg(0), fs.Arg(1), fs.Args()[2:]
+
+	o, err := object(con, p)
+	if err != nil {
+		return err
+	}
+
+	obj := o.(gorods.MetaObj)
+
+	switch action {
+	case "ls":
+		metas, err := obj.Meta()
+		if err != nil {
+			return err
+		}
+
+		all, err := metas.All()
+		if err != nil {
+			return err
+		}
+
+		for _, m := range all {
+			fmt.Printf("%v = %v", m.Attribute, m.Value)
+			if m.Units != "" {
+				fmt.Printf(" [%v]", m.Units)
+			}
+			fmt.Println()
+		}
+
+		return nil
+	case "add":
+		if len(avu) < 2 || len(avu) > 3 {
+			fs.Usage()
+			return errUsage
+		}
+
+		m := gorods.Meta{Attribute: avu[0], Value: avu[1]}
+		if len(avu) == 3 {
+			m.Units = avu[2]
+		}
+
+		_, err := obj.AddMeta(m)
+
+		return err
+	case "rm":
+		if len(avu) != 1 {
+			fs.Usage()
+			return errUsage
+		}
+
+		_, err := obj.DeleteMeta(avu[0])
+
+		return err
+	}
+
+	return fmt.Errorf("unknown action %v, expected ls, add or rm", action)
+}
+
+func runChmod(con *gorods.Connection, args []string) error {
+	fs := newFlags("chmod")
+	recursive := fs.Bool("r", false, "apply to the contents of a collection")
+	if err := parseArgs(fs, args, 3, 3); err != nil {
+		return err
+	}
+
+	level, ok := accessLevels[fs.Arg(0)]
+	if !ok {
+		return fmt.Errorf("unknown access level %v", fs.Arg(0))
+	}
+
+	obj, err := object(con, fs.Arg(2))
+	if err != nil {
+		return err
+	}
+
+	switch o := obj.(type) {
+	case *gorods.Collection:
+		return o.Chmod(fs.Arg(1), level, *recursive)
+	case *gorods.DataObj:
+		return o.Chmod(fs.Arg(1), level, false)
+	}
+
+	return nil
+}
+
+func runSync(con *gorods.Connection, args []string) error {
+	fs := newFlags("sync")
+	download := fs.Bool("download", false, "copy from iRODS to the local directory")
+	checksum := fs.Bool("checksum", false, "compare checksums instead of modification times")
+	del := fs.Bool("delete", false, "remove files missing from the source")
+	dryRun := fs.Bool("n", false, "only print the actions")
+	resource := fs.String("R", "", "target resource")
+	if err := parseArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+
+	opts := gorods.SyncOptions{
+		Checksum:      *checksum,
+		Delete:        *del,
+		DryRun:        *dryRun,
+		PreserveTimes: true,
+	}
+
+	if *download {
+		opts.Direction = gorods.SyncDownload
+	}
+
+	if *resource != "" {
+		opts.Resource = *resource
+	}
+
+	report, err := con.Sync(fs.Arg(0), fs.Arg(1), opts)
+	if err != nil {
+		return err
+	}
+
+	return printSyncReport(report)
+}
+
+// printSyncReport prints the actions of the report, returning an error if any failed
+func printSyncReport(report *gorods.SyncReport) error {
+	for _, a := range report.Actions {
+		fmt.Println(a)
+	}
+
+	if failed := report.Failed(); len(failed) > 0 {
+		return fmt.Errorf("%v of %v actions failed", len(failed), len(report.Actions))
+	}
+
+	return nil
+}
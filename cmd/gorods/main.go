@@ -0,0 +1,161 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+// Command gorods is a small iRODS client built on the GoRODS library, for hosts and containers without icommands.
+//
+// Usage:
+//
+//	gorods [connection flags] <command> [arguments]
+//
+// Commands are ls, get, put, rm, meta, chmod and sync, run "gorods <command> -h" for their flags.
+// The connection is read from ~/.irods/irods_environment.json unless -host is set (or IRODS_HOST). The password
+// is read from IRODS_PASSWORD.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/jjacquay712/GoRODS"
+)
+
+// commands are the subcommands, run with the arguments following their name
+var commands = map[string]func(con *gorods.Connection, args []string) error{
+	"ls":    runLs,
+	"get":   runGet,
+	"put":   runPut,
+	"rm":    runRm,
+	"meta":  runMeta,
+	"chmod": runChmod,
+	"sync":  runSync,
+}
+
+var usages = map[string]string{
+	"ls":    "ls [-l] <path>",
+	"get":   "get [-r] <path> [local path]",
+	"put":   "put [-f] [-R resource] [-r] <local path> <collection>",
+	"rm":    "rm [-r] [-f] <path>",
+	"meta":  "meta ls|add|rm <path> [attribute [value [units]]]",
+	"chmod": "chmod [-r] null|read|write|own|inherit|noinherit <user or group> <path>",
+	"sync":  "sync [-download] [-checksum] [-delete] [-n] [-R resource] <local dir> <collection>",
+}
+
+var order = []string{"ls", "get", "put", "rm", "meta", "chmod", "sync"}
+
+// errUsage is returned by the subcommands for invalid arguments, once their usage is printed
+var errUsage = errors.New("invalid arguments")
+
+// output receives the usage and flag errors of the subcommands
+var output io.Writer = os.Stderr
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: gorods [flags] <command> [arguments]\n\nCommands:\n")
+
+	for _, name := range order {
+		fmt.Fprintf(os.Stderr, "  %v\n", usages[name])
+	}
+
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	host := flag.String("host", os.Getenv("IRODS_HOST"), "iCAT server host, the environment file is used when empty")
+	port := flag.Int("port", envInt("IRODS_PORT", 1247), "iCAT server port")
+	zone := flag.String("zone", os.Getenv("IRODS_ZONE_NAME"), "zone name")
+	user := flag.String("user", os.Getenv("IRODS_USER_NAME"), "user name")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	run, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "gorods: unknown command %v\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	opts := &gorods.ConnectionOptions{Type: gorods.EnvironmentDefined, ProgramName: "gorods"}
+
+	if *host != "" {
+		opts = &gorods.ConnectionOptions{
+			Type:        gorods.UserDefined,
+			Host:        *host,
+			Port:        *port,
+			Zone:        *zone,
+			Username:    *user,
+			Password:    os.Getenv("IRODS_PASSWORD"),
+			ProgramName: "gorods",
+		}
+	}
+
+	con, err := gorods.NewConnection(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gorods: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = run(con, flag.Args()[1:])
+
+	con.Disconnect()
+
+	switch {
+	case err == flag.ErrHelp:
+	case err == errUsage:
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "gorods %v: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+}
+
+// envInt returns the integer value of the environment variable name, or def if unset or invalid
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil {
+		return v
+	}
+
+	return def
+}
+
+// newFlags returns the flag set of a subcommand, printing its usage line on errors
+func newFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gorods %v\n", usages[name])
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// parseArgs parses the arguments of a subcommand, which takes min to max (-1 for any number) positional arguments.
+// It returns errUsage if they're invalid, or flag.ErrHelp if -h was passed, the subcommand must return it to main
+// so that the connection is closed.
+func parseArgs(fs *flag.FlagSet, args []string, min int, max int) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+
+		return errUsage
+	}
+
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		fs.Usage()
+		return errUsage
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/jjacquay712/GoRODS"
+)
+
+func TestInvalidArguments(t *testing.T) {
+	output = ioutil.Discard
+
+	dir := t.TempDir()
+
+	// The arguments are rejected before the connection is used
+	for name, args := range map[string][]string{
+		"ls":    {},
+		"get":   {"a", "b", "c"},
+		"put":   {"-x", "a", "b"},
+		"rm":    {"a", "b"},
+		"meta":  {"add"},
+		"chmod": {"read", "rods"},
+		"sync":  {dir},
+	} {
+		if err := commands[name](nil, args); err != errUsage {
+			t.Errorf("Expected a usage error from %v %v, got %v", name, args, err)
+		}
+	}
+
+	if err := runLs(nil, []string{"-h"}); err != flag.ErrHelp {
+		t.Errorf("Expected flag.ErrHelp, got %v", err)
+	}
+
+	if err := runChmod(nil, []string{"execute", "rods", "/tempZone/home"}); err == nil || err == errUsage {
+		t.Errorf("Expected an unknown access level error, got %v", err)
+	}
+
+	if err := runPut(nil, []string{dir, "/tempZone/home/rods"}); err == nil || err == errUsage {
+		t.Errorf("Expected an error putting a directory without -r, got %v", err)
+	}
+}
+
+func TestLocalTarget(t *testing.T) {
+	dir := t.TempDir()
+
+	if p := localTarget("/tempZone/home/rods/data", dir); p != filepath.Join(dir, "data") {
+		t.Errorf("Expected a download into the existing directory, got %v", p)
+	}
+
+	missing := filepath.Join(dir, "new")
+
+	if p := localTarget("/tempZone/home/rods/data", missing); p != missing {
+		t.Errorf("Expected a new destination to be used as is, got %v", p)
+	}
+}
+
+func TestPutSyncOptions(t *testing.T) {
+	if opts := putSyncOptions(false, "demoResc"); !opts.NoOverwrite || opts.Resource != "demoResc" || opts.Direction != gorods.SyncUpload {
+		t.Errorf("Expected put -r to keep existing data objects, got %+v", opts)
+	}
+
+	if opts := putSyncOptions(true, nil); opts.NoOverwrite {
+		t.Errorf("Expected put -r -f to replace existing data objects, got %+v", opts)
+	}
+}
+
+func TestPrintSyncReport(t *testing.T) {
+	report := &gorods.SyncReport{Actions: []*gorods.SyncAction{
+		{Op: gorods.SyncPut, LocalPath: "a.txt", RodsPath: "/tempZone/home/rods/a.txt"},
+		{Op: gorods.SyncPut, LocalPath: "b.txt", RodsPath: "/tempZone/home/rods/b.txt", Err: errors.New("exists")},
+	}}
+
+	if err := printSyncReport(report); err == nil || err.Error() != "1 of 2 actions failed" {
+		t.Errorf("Expected the failure to be reported, got %v", err)
+	}
+}
@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
  *** For more information please refer to the LICENSE.md file                                   ***/
 
@@ -43,16 +46,32 @@ type Collection struct {
 
 	opened     bool
 	cColHandle C.collHandle_t
+
+	specColl   int
+	linkTarget string
 }
 
+// Link policy constants are used in CollectionOptions{ LinkPolicy: ... } to control how
+// linked and mounted (special) collections are handled during listings and Walk.
+// LinkResolve descends into special collections, unless the link points back into the tree being walked.
+// LinkSkip leaves special collections out of listings completely.
+// LinkReport lists special collections, but never descends into them.
+const (
+	LinkResolve = iota
+	LinkSkip
+	LinkReport
+)
+
 // CollectionOptions stores options relating to collection initialization.
 // Path is the full path of the collection you're requesting.
 // Recursive if set to true will load sub collections into memory, until the end of the collection "tree" is found.
+// LinkPolicy is one of LinkResolve (default), LinkSkip, or LinkReport.
 type CollectionOptions struct {
-	Path      string
-	Recursive bool
-	GetRepls  bool
-	SkipCache bool
+	Path       string
+	Recursive  bool
+	GetRepls   bool
+	SkipCache  bool
+	LinkPolicy int
 }
 
 // String shows the contents of the collection.
@@ -101,6 +120,13 @@ func initCollection(data *C.collEnt_t, acol *Collection) (*Collection, error) {
 
 	col.name = filepath.Base(col.path)
 
+	col.specColl = int(data.specColl.collClass)
+	if col.specColl == C.LINKED_COLL {
+		col.linkTarget = C.GoString(&data.specColl.phyPath[0])
+	} else if col.specColl != C.NO_SPEC_COLL {
+		col.linkTarget = C.GoString(&data.specColl.collection[0])
+	}
+
 	if usrs, err := col.con.Users(); err != nil {
 		return nil, err
 	} else {
@@ -111,7 +137,7 @@ func initCollection(data *C.collEnt_t, acol *Collection) (*Collection, error) {
 		}
 	}
 
-	if col.recursive {
+	if col.recursive && col.shouldDescend() {
 
 		if er := col.init(); er != nil {
 			return nil, er
@@ -123,55 +149,21 @@ func initCollection(data *C.collEnt_t, acol *Collection) (*Collection, error) {
 
 // Stat returns a map (key/value pairs) of the system meta information. The following keys can be used with the map:
 //
-// "objSize"
-//
-// "dataMode"
-//
-// "dataId"
-//
-// "chksum"
+// "objSize", "dataMode", "dataId", "chksum", "ownerName", "ownerZone", "createTime", "modifyTime"
 //
-// "ownerName"
-//
-// "ownerZone"
-//
-// "createTime"
-//
-// "modifyTime"
+// Use ObjStat for the typed struct, which holds more of the system metadata.
 func (col *Collection) Stat() (map[string]interface{}, error) {
-
-	var (
-		err        *C.char
-		statResult *C.rodsObjStat_t
-	)
-
-	path := C.CString(col.path)
-
-	defer C.free(unsafe.Pointer(path))
-
-	ccon := col.con.GetCcon()
-	defer col.con.ReturnCcon(ccon)
-
-	if status := C.gorods_stat_dataobject(path, &statResult, ccon, &err); status != 0 {
-		return nil, newError(Fatal, status, fmt.Sprintf("iRODS Stat Failed: %v, %v", col.path, C.GoString(err)))
+	stat, err := col.ObjStat()
+	if err != nil {
+		return nil, err
 	}
 
-	result := make(map[string]interface{})
-
-	result["objSize"] = int(statResult.objSize)
-	result["dataMode"] = int(statResult.dataMode)
-
-	result["dataId"] = C.GoString(&statResult.dataId[0])
-	result["chksum"] = C.GoString(&statResult.chksum[0])
-	result["ownerName"] = C.GoString(&statResult.ownerName[0])
-	result["ownerZone"] = C.GoString(&statResult.ownerZone[0])
-	result["createTime"] = C.GoString(&statResult.createTime[0])
-	result["modifyTime"] = C.GoString(&statResult.modifyTime[0])
-	//result["rescHier"] = C.GoString(&statResult.rescHier[0])
-
-	C.freeRodsObjStat(statResult)
+	return stat.Map(), nil
+}
 
-	return result, nil
+// ObjStat returns the system meta information of the collection in an *ObjStat struct
+func (col *Collection) ObjStat() (*ObjStat, error) {
+	return col.con.ObjStat(col.path)
 }
 
 // getCollection initializes specified collection located at startPath using gorods.connection.
@@ -220,16 +212,10 @@ func setupCollection(col *Collection) (*Collection, error) {
 		}
 	}
 
-	if info, err := col.Stat(); err == nil {
-		col.ownerName = info["ownerName"].(string)
-
-		cCreateTime := C.CString(info["createTime"].(string))
-		defer C.free(unsafe.Pointer(cCreateTime))
-		cModifyTime := C.CString(info["modifyTime"].(string))
-		defer C.free(unsafe.Pointer(cModifyTime))
-
-		col.createTime = cTimeToTime(cCreateTime)
-		col.modifyTime = cTimeToTime(cModifyTime)
+	if info, err := col.ObjStat(); err == nil {
+		col.ownerName = info.OwnerName
+		col.createTime = info.CreateTime
+		col.modifyTime = info.ModifyTime
 
 		if usrs, err := col.con.Users(); err != nil {
 			return nil, err
@@ -258,19 +244,31 @@ func CreateCollection(name string, coll *Collection) (*Collection, error) {
 
 	defer C.free(unsafe.Pointer(path))
 
-	ccon := coll.con.GetCcon()
+	if err := coll.con.intercept(&Event{Op: OpMkdir, Path: coll.path + "/" + name}, func() error {
+		ccon := coll.con.GetCcon()
+		defer coll.con.ReturnCcon(ccon)
 
-	if status := C.gorods_create_collection(path, ccon, &errMsg); status != 0 {
-		coll.con.ReturnCcon(ccon)
-		return nil, newError(Fatal, status, fmt.Sprintf("iRODS Create Collection Failed: %v, Does the collection already exist?", C.GoString(errMsg)))
+		if status := C.gorods_create_collection(path, ccon, &errMsg); status != 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Create Collection Failed: %v, Does the collection already exist?", C.GoString(errMsg)))
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
-	coll.con.ReturnCcon(ccon)
+	coll.con.InvalidateCache(coll.path + "/" + name)
 
 	coll.Refresh()
 
 	newCol := coll.Cd(name)
 
+	if newCol != nil {
+		if err := coll.inheritMeta(newCol); err != nil {
+			return newCol, err
+		}
+	}
+
 	return newCol, nil
 
 }
@@ -380,6 +378,9 @@ func (col *Collection) All() (IRodsObjs, error) {
 	return col.dataObjects, nil
 }
 
+// Walk recursively calls callback for every data object contained within the collection, callback is only passed
+// data objects. Linked and mounted collections are handled according to CollectionOptions.LinkPolicy: with LinkReport
+// (or when a link would cause a cycle) the special collection is skipped instead of being descended into.
 func (col *Collection) Walk(callback func(IRodsObj) error) error {
 
 	all, err := col.All()
@@ -389,10 +390,16 @@ func (col *Collection) Walk(callback func(IRodsObj) error) error {
 
 	for _, item := range all {
 		if item.Type() == CollectionType {
-			if cbErr := (item.(*Collection)).Walk(callback); cbErr != nil {
+			subCol := item.(*Collection)
+
+			if !subCol.shouldDescend() {
+				continue
+			}
+
+			if cbErr := subCol.Walk(callback); cbErr != nil {
 				return cbErr
 			}
-		} else {
+		} else if item.Type() == DataObjType {
 			if cbErr := callback(item); cbErr != nil {
 				return cbErr
 			}
@@ -402,6 +409,70 @@ func (col *Collection) Walk(callback func(IRodsObj) error) error {
 	return nil
 }
 
+// IsSpecial returns true if the collection is a linked, mounted, or structured file collection
+func (col *Collection) IsSpecial() bool {
+	return col.specColl != int(C.NO_SPEC_COLL)
+}
+
+// IsLink returns true if the collection is a soft link to another collection (imcoll -m link)
+func (col *Collection) IsLink() bool {
+	return col.specColl == int(C.LINKED_COLL)
+}
+
+// IsMounted returns true if the collection is a mounted collection (imcoll -m filesystem, tar, etc)
+func (col *Collection) IsMounted() bool {
+	return col.specColl == int(C.MOUNTED_COLL) || col.specColl == int(C.STRUCT_FILE_COLL)
+}
+
+// LinkTarget returns the path a linked collection points to, or the mount point of a mounted collection.
+// An empty string is returned for normal collections.
+func (col *Collection) LinkTarget() string {
+	return col.linkTarget
+}
+
+func (col *Collection) linkPolicy() int {
+	if col.options == nil {
+		return LinkResolve
+	}
+
+	return col.options.LinkPolicy
+}
+
+// shouldDescend returns false if the collection's content shouldn't be read according to the link policy
+func (col *Collection) shouldDescend() bool {
+	if !col.IsSpecial() {
+		return true
+	}
+
+	if col.linkPolicy() != LinkResolve {
+		return false
+	}
+
+	return !col.isCyclicLink()
+}
+
+// isCyclicLink checks whether the link target is the collection itself or one of its ancestors,
+// (or the target of an ancestor link) which would cause infinite recursion
+func (col *Collection) isCyclicLink() bool {
+	if !col.IsLink() {
+		return false
+	}
+
+	target := strings.TrimRight(col.linkTarget, "/")
+
+	if target == col.path || strings.HasPrefix(col.path, target+"/") {
+		return true
+	}
+
+	for p := col.parent; p != nil; p = p.parent {
+		if p.path == target || (p.linkTarget != "" && strings.TrimRight(p.linkTarget, "/") == target) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // SetInheritance sets the inheritance option of the collection. If true, sub-collections and data objects inherit the permissions (ACL) of this collection.
 func (col *Collection) SetInheritance(inherits bool, recursive bool) error {
 	var ih int
@@ -440,7 +511,12 @@ func (col *Collection) Inheritance() (bool, error) {
 
 // GrantAccess will add permissions (ACL) to the collection
 func (col *Collection) GrantAccess(userOrGroup AccessObject, accessLevel int, recursive bool) error {
-	return chmod(col, userOrGroup.Name(), accessLevel, recursive, true)
+	return col.SetAccess(PrincipalOf(userOrGroup), accessLevel, recursive)
+}
+
+// SetAccess changes the access level of the user or group principal on the collection, in the principal's zone
+func (col *Collection) SetAccess(p Principal, accessLevel int, recursive bool) error {
+	return p.chmod(col, accessLevel, recursive)
 }
 
 // Chmod changes the permissions/ACL of the collection
@@ -462,11 +538,11 @@ func (col *Collection) ACL() (ACLs, error) {
 		collName *C.char
 	)
 
-	zone, zErr := col.con.LocalZone()
+	zone, zErr := col.con.zoneHint(col.path)
 	if zErr != nil {
 		return nil, zErr
 	} else {
-		zoneHint = C.CString(zone.Name())
+		zoneHint = C.CString(zone)
 	}
 
 	collName = C.CString(col.path)
@@ -487,7 +563,12 @@ func (col *Collection) ACL() (ACLs, error) {
 
 // Size returns the total size in bytes of all contained data objects and collections, recursively
 func (col *Collection) Size() int64 {
-	result, err := col.con.IQuest("select sum(DATA_SIZE) where COLL_NAME like '"+col.path+"%'", false)
+	zone, err := col.con.zoneHint(col.path)
+	if err != nil {
+		return 0
+	}
+
+	result, err := col.con.IQuestZone("select sum(DATA_SIZE) where COLL_NAME like '"+col.path+"%'", false, zone)
 	if err != nil {
 		return 0
 	}
@@ -503,7 +584,12 @@ func (col *Collection) Size() int64 {
 
 // Length returns the total number of data objects and collections contained within the collection, recursively
 func (col *Collection) Length() int {
-	result, err := col.con.IQuest("select count(DATA_ID) where COLL_NAME like '"+col.path+"%'", false)
+	zone, err := col.con.zoneHint(col.path)
+	if err != nil {
+		return 0
+	}
+
+	result, err := col.con.IQuestZone("select count(DATA_ID) where COLL_NAME like '"+col.path+"%'", false, zone)
 	if err != nil {
 		return 0
 	}
@@ -618,6 +704,14 @@ func (col *Collection) Trash(recursive bool) error {
 
 // Rm is equivalent to irm {-r} {-f}
 func (col *Collection) Rm(recursive bool, force bool) error {
+	params := map[string]string{"type": "collection", "recursive": strconv.FormatBool(recursive), "force": strconv.FormatBool(force)}
+
+	return col.con.intercept(&Event{Op: OpDelete, Path: col.path, Params: params}, func() error {
+		return col.rm(recursive, force)
+	})
+}
+
+func (col *Collection) rm(recursive bool, force bool) error {
 	var errMsg *C.char
 
 	path := C.CString(col.path)
@@ -782,12 +876,13 @@ func (col *Collection) Open() error {
 		}
 
 		col.opened = true
+		col.con.watchLeak(col, "Collection", col.path)
 	}
 
 	return nil
 }
 
-// Close closes the Collection connection and resets the handle
+// Close closes the Collection connection and resets the handle. It is safe to call more than once.
 func (col *Collection) Close() error {
 	var errMsg *C.char
 
@@ -807,6 +902,7 @@ func (col *Collection) Close() error {
 		}
 
 		col.opened = false
+		col.con.unwatchLeak(col)
 	}
 
 	return nil
@@ -959,7 +1055,7 @@ func (col *Collection) MoveToResource(targetResource interface{}) error {
 }
 
 // Replicate recursively copies all data objects contained within the collection to the specified resource.
-// Accepts string or *Resource type for targetResource parameter.
+// Accepts string or *Resource type for targetResource parameter, a string can also be a resource hierarchy ("root;child;leaf").
 func (col *Collection) Replicate(targetResource interface{}, opts DataObjOptions) error {
 
 	// loop through data objects
@@ -1072,14 +1168,20 @@ func (col *Collection) MoveTo(iRODSCollection interface{}) error {
 	defer C.free(unsafe.Pointer(path))
 	defer C.free(unsafe.Pointer(dest))
 
-	ccon := col.con.GetCcon()
+	if er := col.con.intercept(&Event{Op: OpMove, Path: col.path, Dest: destination, Params: map[string]string{"type": "collection"}}, func() error {
+		ccon := col.con.GetCcon()
+		defer col.con.ReturnCcon(ccon)
 
-	if status := C.gorods_move_dataobject(path, dest, C.RENAME_COLL, ccon, &err); status != 0 {
-		col.con.ReturnCcon(ccon)
-		return newError(Fatal, status, fmt.Sprintf("iRODS Move Collection Failed: %v, D:%v, %v", col.path, destination, C.GoString(err)))
+		if status := C.gorods_move_dataobject(path, dest, C.RENAME_COLL, ccon, &err); status != 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Move Collection Failed: %v, D:%v, %v", col.path, destination, C.GoString(err)))
+		}
+
+		return nil
+	}); er != nil {
+		return er
 	}
 
-	col.con.ReturnCcon(ccon)
+	col.con.InvalidateCache(col.path, destination)
 
 	// Reload source collection, we are now detached... buggy?
 	//col.parent.Refresh()
@@ -1132,13 +1234,21 @@ func (col *Collection) Rename(newFileName string) error {
 	defer C.free(unsafe.Pointer(s))
 	defer C.free(unsafe.Pointer(d))
 
-	ccon := col.con.GetCcon()
-	defer col.con.ReturnCcon(ccon)
+	if er := col.con.intercept(&Event{Op: OpMove, Path: source, Dest: destination, Params: map[string]string{"type": "collection"}}, func() error {
+		ccon := col.con.GetCcon()
+		defer col.con.ReturnCcon(ccon)
+
+		if status := C.gorods_move_dataobject(s, d, C.RENAME_COLL, ccon, &err); status != 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Rename Collection Failed: %v, %v", col.path, C.GoString(err)))
+		}
 
-	if status := C.gorods_move_dataobject(s, d, C.RENAME_COLL, ccon, &err); status != 0 {
-		return newError(Fatal, status, fmt.Sprintf("iRODS Rename Collection Failed: %v, %v", col.path, C.GoString(err)))
+		return nil
+	}); er != nil {
+		return er
 	}
 
+	col.con.InvalidateCache(source, destination)
+
 	col.name = newFileName
 	col.path = destination
 
@@ -1278,6 +1388,10 @@ func (col *Collection) ReadCollection() error {
 
 		if isCollection {
 			if newCol, er := initCollection(&colEnt, col); er == nil {
+				if newCol.IsSpecial() && newCol.linkPolicy() == LinkSkip {
+					continue
+				}
+
 				theObj = newCol
 			} else {
 				return er
@@ -1338,6 +1452,57 @@ func (col *Collection) ReadCollection() error {
 
 // Put reads the entire file from localPath and adds it the collection, using the options specified.
 func (col *Collection) Put(localPath string, opts DataObjOptions) (*DataObj, error) {
+	return col.put(localPath, opts, true)
+}
+
+// put uploads localPath, only running the connection's content scanners if scan is true
+func (col *Collection) put(localPath string, opts DataObjOptions, scan bool) (*DataObj, error) {
+	var obj *DataObj
+
+	name := opts.Name
+	if name == "" {
+		name = filepath.Base(localPath)
+	}
+
+	params := map[string]string{"local": localPath, "force": strconv.FormatBool(opts.Force)}
+	if resc, er := resourceName(opts.Resource); er == nil && resc != "" {
+		params["resource"] = resc
+	}
+
+	pipeline := col.con.transformPipeline(opts)
+	if len(pipeline) > 0 {
+		params["transforms"] = transformNames(pipeline)
+	}
+
+	err := col.con.intercept(&Event{Op: OpPut, Path: col.path + "/" + name, Size: opts.Size, Params: params}, func() (err error) {
+		if len(pipeline) > 0 {
+			obj, err = col.putTransformed(localPath, opts, pipeline, scan)
+			return
+		}
+
+		obj, err = col.putFile(localPath, opts, scan)
+
+		// An overwritten data object may have been stored transformed before
+		if err == nil && opts.Force && col.con.decodesTransforms() {
+			err = setTransformMeta(obj, "")
+		}
+
+		return
+	})
+
+	if err == nil {
+		err = col.inheritMeta(obj)
+	}
+
+	// The checksum of transformed contents can't be compared with the local file
+	if err == nil && len(pipeline) == 0 {
+		err = applyChecksumPolicy(obj, localPath)
+	}
+
+	return obj, err
+}
+
+func (col *Collection) putFile(localPath string, opts DataObjOptions, scan bool) (*DataObj, error) {
 
 	var (
 		errMsg   *C.char
@@ -1345,30 +1510,58 @@ func (col *Collection) Put(localPath string, opts DataObjOptions) (*DataObj, err
 		resource *C.char
 	)
 
+	if opts.Name == "" {
+		opts.Name = filepath.Base(localPath)
+	}
+
+	if scan {
+		if err := col.con.scanFile(col.path+"/"+opts.Name, localPath); err != nil {
+			return nil, err
+		}
+	}
+
 	if opts.Force {
 		force = 1
 	} else {
 		force = 0
 	}
 
-	if opts.Resource != nil {
-		switch opts.Resource.(type) {
-		case string:
-			resource = C.CString(opts.Resource.(string))
-		case *Resource:
-			r := opts.Resource.(*Resource)
-			resource = C.CString(r.Name())
-		default:
-			return nil, newError(Fatal, -1, fmt.Sprintf("Wrong variable type passed in Resource field"))
+	rescName, err := col.con.targetResource(opts.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Resource = rescName
+
+	if opts.Dedup {
+		if copied, err := col.putDedup(localPath, opts); err != nil {
+			return nil, err
+		} else if copied {
+			if err := col.Refresh(); err != nil {
+				return nil, err
+			}
+
+			do, err := getDataObj(col.path+"/"+opts.Name, col.con)
+			if err == nil {
+				do.destination = rescName
+			}
+
+			return do, err
 		}
-	} else {
-		resource = C.CString("")
 	}
 
-	if opts.Name == "" {
-		opts.Name = filepath.Base(localPath)
+	// Rate limited uploads are written in chunks, so the limits apply during the transfer
+	if col.con.throttled(BytesWritten) {
+		do, err := col.putThrottled(localPath, opts, rescName)
+		if err == nil {
+			do.destination = rescName
+		}
+
+		return do, err
 	}
 
+	resource = C.CString(rescName)
+
 	path := C.CString(col.path + "/" + opts.Name)
 	cLocalPath := C.CString(localPath)
 
@@ -1376,13 +1569,22 @@ func (col *Collection) Put(localPath string, opts DataObjOptions) (*DataObj, err
 	defer C.free(unsafe.Pointer(resource))
 	defer C.free(unsafe.Pointer(cLocalPath))
 
-	ccon := col.con.GetCcon()
+	// The upload goes straight to the resource server the provider redirects it to
+	tcon := col.con.transferCon(col.path+"/"+opts.Name, rescName, true)
+
+	ccon := tcon.GetCcon()
 
 	if status := C.gorods_put_dataobject(cLocalPath, path, C.rodsLong_t(opts.Size), C.int(opts.Mode), C.int(force), resource, ccon, &errMsg); status != 0 {
-		col.con.ReturnCcon(ccon)
+		tcon.ReturnCcon(ccon)
 		return nil, newError(Fatal, status, fmt.Sprintf("iRODS Put DataObject Failed: %v, Does the file already exist?", C.GoString(errMsg)))
 	}
-	col.con.ReturnCcon(ccon)
+	tcon.ReturnCcon(ccon)
+
+	if col.con.recorder() != nil {
+		if info, err := os.Stat(localPath); err == nil {
+			col.con.recordBytes(BytesWritten, info.Size())
+		}
+	}
 
 	if err := col.Refresh(); err != nil {
 		return nil, err
@@ -1391,6 +1593,7 @@ func (col *Collection) Put(localPath string, opts DataObjOptions) (*DataObj, err
 	if do, err := getDataObj(C.GoString(path), col.con); err != nil {
 		return nil, err
 	} else {
+		do.destination = rescName
 		return do, nil
 	}
 
@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, The BioTeam, Inc.                     ***
  *** For more information please refer to the LICENSE.md file  ***/
 
@@ -0,0 +1,135 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The iRODS protocol has no compression of API payloads for clients to negotiate, general query results always
+// cross the network uncompressed. What can be compressed is everything GoRODS writes on the client side: exports
+// to files ending in ".gz" (ExportQuery, ExportMetaFile) are gzipped as they are streamed, and ImportMetaFile
+// reads them back, so harvested results are only ever stored or forwarded compressed.
+
+// isGzipPath returns true if localPath ends in ".gz"
+func isGzipPath(localPath string) bool {
+	return strings.ToLower(filepath.Ext(localPath)) == ".gz"
+}
+
+// exportFile wraps the file written by an export, compressing its contents if the path ends in ".gz"
+type exportFile struct {
+	io.Writer
+	f  *os.File
+	gz *gzip.Writer
+}
+
+// createExportFile creates localPath, returning a writer that gzips its input if the path ends in ".gz"
+func createExportFile(localPath string) (*exportFile, error) {
+	f, err := os.Create(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isGzipPath(localPath) {
+		return &exportFile{Writer: f, f: f}, nil
+	}
+
+	gz := gzip.NewWriter(f)
+
+	return &exportFile{Writer: gz, f: f, gz: gz}, nil
+}
+
+// Close flushes the compressed stream, if any, and closes the file
+func (ef *exportFile) Close() error {
+	if ef.gz != nil {
+		if err := ef.gz.Close(); err != nil {
+			ef.f.Close()
+			return err
+		}
+	}
+
+	return ef.f.Close()
+}
+
+// importFile is the reader of a file written by createExportFile
+type importFile struct {
+	io.Reader
+	f  *os.File
+	gz *gzip.Reader
+}
+
+// openImportFile opens localPath, decompressing it if the path ends in ".gz"
+func openImportFile(localPath string) (*importFile, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isGzipPath(localPath) {
+		return &importFile{Reader: f, f: f}, nil
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &importFile{Reader: gz, f: f, gz: gz}, nil
+}
+
+// Close closes the decompressor, if any, and the file
+func (imf *importFile) Close() error {
+	if imf.gz != nil {
+		imf.gz.Close()
+	}
+
+	return imf.f.Close()
+}
+
+// ExportQuery runs a general query and streams its rows to localPath as JSON lines (one object per row, keyed by
+// column name), page by page, so results of any size can be exported. The output is gzipped if localPath ends
+// in ".gz". Returns the number of rows written.
+func (con *Connection) ExportQuery(query string, localPath string, opts QueryOptions) (int, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = 256
+	}
+
+	out, err := createExportFile(localPath)
+	if err != nil {
+		return 0, newError(Fatal, -1, fmt.Sprintf("iRODS Export Query Failed: %v", err))
+	}
+
+	enc := json.NewEncoder(out)
+	count := 0
+
+	err = con.intercept(&Event{Op: OpQuery, Query: query, noRetry: true}, func() error {
+		return con.queryPages(query, opts, nil, func(page []map[string]string) error {
+			for _, row := range page {
+				if er := enc.Encode(row); er != nil {
+					return newError(Fatal, -1, fmt.Sprintf("iRODS Export Query Failed: %v", er))
+				}
+
+				count++
+			}
+
+			return nil
+		})
+	})
+
+	if er := out.Close(); er != nil && err == nil {
+		err = newError(Fatal, -1, fmt.Sprintf("iRODS Export Query Failed: %v", er))
+	}
+
+	return count, err
+}
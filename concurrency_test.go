@@ -0,0 +1,95 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"sync"
+	"testing"
+)
+
+// Run with go test -race to check that a single connection can be shared between goroutines
+func TestConcurrentConnection(t *testing.T) {
+	con, conErr := NewConnection(&ConnectionOptions{
+		Type: UserDefined,
+
+		Host: "localhost",
+		Port: 1247,
+		Zone: "tempZone",
+
+		Username: "rods",
+		Password: "password",
+	})
+
+	if conErr != nil {
+		t.Fatal(conErr)
+	}
+
+	defer con.Close()
+
+	obj, objErr := con.DataObject("/tempZone/home/rods/hello.txt")
+	if objErr != nil {
+		t.Fatal(objErr)
+	}
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 100)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(4)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := con.IQuest("select COLL_NAME where COLL_NAME like '/tempZone/home/%'", false); err != nil {
+				errs <- err
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := con.Zones(); err != nil {
+				errs <- err
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := con.Collection(CollectionOptions{Path: "/tempZone/home/rods"}); err != nil {
+				errs <- err
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			if contents, err := obj.ReadBytes(7, 6); err != nil {
+				errs <- err
+			} else if string(contents) != "World!" {
+				t.Errorf("Expected string 'World!', got '%s'", contents)
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if err := con.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second Close must be a no-op
+	if err := con.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,24 +1,32 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
  *** For more information please refer to the LICENSE.md file                                   ***/
 
 // Package gorods is a Golang binding for the iRODS C API (iRODS client library).
-// GoRods uses cgo to call iRODS client functions.
+// GoRods uses cgo to call iRODS client functions. Linux and macOS are supported, see the README for build instructions.
+// Additional include and library paths can be passed with the CGO_CFLAGS and CGO_LDFLAGS environment variables.
 package gorods
 
-// #cgo CFLAGS: -ggdb -I/usr/include/irods
-// #cgo LDFLAGS: -Wl,-rpath,"/opt/irods-externals/boost1.60.0-0/lib" -Wl,-rpath,"/opt/irods-externals/clang3.8-0/lib" -L/opt/irods-externals/clang3.8-0/lib -L/opt/irods-externals/boost1.60.0-0/lib /opt/irods-externals/boost1.60.0-0/lib/libboost_system.a /opt/irods-externals/boost1.60.0-0/lib/libboost_chrono.a /opt/irods-externals/jansson2.7-0/lib/libjansson.a -lirods_common -lirods_client -lc++ -lc++abi -lboost_regex -lboost_program_options -lboost_thread -lboost_filesystem -lz -lssl -lcrypto -ldl -lpthread -lm -lrt -lstdc++ -rdynamic -Wno-write-strings -DBOOST_SYSTEM_NO_DEPRECATED
+// #cgo linux CFLAGS: -ggdb -I/usr/include/irods
+// #cgo linux LDFLAGS: -Wl,-rpath,"/opt/irods-externals/boost1.60.0-0/lib" -Wl,-rpath,"/opt/irods-externals/clang3.8-0/lib" -L/opt/irods-externals/clang3.8-0/lib -L/opt/irods-externals/boost1.60.0-0/lib /opt/irods-externals/boost1.60.0-0/lib/libboost_system.a /opt/irods-externals/boost1.60.0-0/lib/libboost_chrono.a /opt/irods-externals/jansson2.7-0/lib/libjansson.a -lirods_common -lirods_client -lc++ -lc++abi -lboost_regex -lboost_program_options -lboost_thread -lboost_filesystem -lz -lssl -lcrypto -ldl -lpthread -lm -lrt -lstdc++ -rdynamic -Wno-write-strings -DBOOST_SYSTEM_NO_DEPRECATED
+// #cgo darwin CFLAGS: -ggdb -I/usr/local/include/irods -I/usr/include/irods -I/usr/local/opt/openssl/include
+// #cgo darwin LDFLAGS: -Wl,-rpath,/opt/irods-externals/boost1.60.0-0/lib -Wl,-rpath,/opt/irods-externals/clang3.8-0/lib -L/usr/local/lib -L/usr/local/opt/openssl/lib -L/opt/irods-externals/clang3.8-0/lib -L/opt/irods-externals/boost1.60.0-0/lib /opt/irods-externals/boost1.60.0-0/lib/libboost_system.a /opt/irods-externals/boost1.60.0-0/lib/libboost_chrono.a /opt/irods-externals/jansson2.7-0/lib/libjansson.a -lirods_common -lirods_client -lc++ -lc++abi -lboost_regex -lboost_program_options -lboost_thread -lboost_filesystem -lz -lssl -lcrypto -ldl -lpthread -lm -Wno-write-strings -DBOOST_SYSTEM_NO_DEPRECATED
 // #include "wrapper.h"
 import "C"
 
 import (
 	"fmt"
-	"math"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
+
+	"github.com/jjacquay712/GoRODS/api"
 )
 
 // EnvironmentDefined and UserDefined constants are used when calling
@@ -32,10 +40,11 @@ const (
 )
 
 // Used when calling Type() on different gorods objects
+// DataObjType, CollectionType and the access levels are those of package api.
 const (
-	DataObjType = iota
-	CollectionType
-	ResourceType
+	DataObjType    = api.DataObjType
+	CollectionType = api.CollectionType
+	ResourceType   = iota
 	ResourceGroupType
 	ZoneType
 	UserType
@@ -45,16 +54,17 @@ const (
 	UnknownType
 	Cache
 	Archive
-	Null
-	Read
-	Write
-	Own
-	Inherit
+	Null    = api.Null
+	Read    = api.Read
+	Write   = api.Write
+	Own     = api.Own
+	Inherit = iota
 	NoInherit
 	Local
 	Remote
 	PAMAuth
 	PasswordAuth
+	AnonymousAuth
 )
 
 type MetaObj interface {
@@ -78,6 +88,7 @@ type IRodsObj interface {
 	Con() *Connection
 	ACL() (ACLs, error)
 	Stat() (map[string]interface{}, error)
+	ObjStat() (*ObjStat, error)
 
 	Size() int64
 	Mode() os.FileMode
@@ -112,6 +123,7 @@ type IRodsObj interface {
 
 	Chmod(string, int, bool) error
 	GrantAccess(AccessObject, int, bool) error
+	SetAccess(Principal, int, bool) error
 
 	Replicate(interface{}, DataObjOptions) error
 	Backup(interface{}, DataObjOptions) error
@@ -230,26 +242,42 @@ func findRecursiveHelper(objs IRodsObjs, path string) IRodsObj {
 }
 
 func chmod(obj IRodsObj, user string, accessLevel int, recursive bool, includeZone bool) error {
+	var zone string
+
+	if includeZone {
+		p := ParsePrincipal(user)
+
+		if p.Zone != "" {
+			user = p.Name
+			zone = p.Zone
+		} else if z, err := obj.Con().LocalZone(); err == nil {
+			zone = z.Name()
+		} else {
+			return err
+		}
+	}
+
+	return chmodZone(obj, user, zone, accessLevel, recursive)
+}
+
+// chmodZone sets the access level of the user or group in zone (which can be empty for inheritance changes)
+func chmodZone(obj IRodsObj, user string, zone string, accessLevel int, recursive bool) error {
+	return obj.Con().chmodPath(obj.Path(), user, zone, accessLevel, recursive)
+}
+
+// chmodPath is chmodZone for a path, so objects don't need to be loaded
+func (con *Connection) chmodPath(p string, user string, zone string, accessLevel int, recursive bool) error {
 	var (
 		err        *C.char
 		cRecursive C.int
-		zone       string
 	)
 
 	if accessLevel != Null && accessLevel != Read && accessLevel != Write && accessLevel != Own && accessLevel != Inherit && accessLevel != NoInherit {
 		return newError(Fatal, -1, fmt.Sprintf("iRODS Chmod DataObject Failed: accessLevel must be Null | Read | Write | Own"))
 	}
 
-	if includeZone {
-		if z, err := obj.Con().LocalZone(); err == nil {
-			zone = z.Name()
-		} else {
-			return err
-		}
-	}
-
 	cUser := C.CString(user)
-	cPath := C.CString(obj.Path())
+	cPath := C.CString(p)
 	cZone := C.CString(zone)
 	cAccessLevel := C.CString(getTypeString(accessLevel))
 	defer C.free(unsafe.Pointer(cUser))
@@ -263,14 +291,23 @@ func chmod(obj IRodsObj, user string, accessLevel int, recursive bool, includeZo
 		cRecursive = C.int(0)
 	}
 
-	ccon := obj.Con().GetCcon()
-	defer obj.Con().ReturnCcon(ccon)
-
-	if status := C.gorods_chmod(ccon, cPath, cZone, cUser, cAccessLevel, cRecursive, &err); status != 0 {
-		return newError(Fatal, status, fmt.Sprintf("iRODS Chmod DataObject Failed: %v", C.GoString(err)))
+	params := map[string]string{
+		"user":      user,
+		"zone":      zone,
+		"access":    getTypeString(accessLevel),
+		"recursive": strconv.FormatBool(recursive),
 	}
 
-	return nil
+	return con.intercept(&Event{Op: OpChmod, Path: p, Params: params}, func() error {
+		ccon := con.GetCcon()
+		defer con.ReturnCcon(ccon)
+
+		if status := C.gorods_chmod(ccon, cPath, cZone, cUser, cAccessLevel, cRecursive, &err); status != 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Chmod DataObject Failed: %v", C.GoString(err)))
+		}
+
+		return nil
+	})
 }
 
 // ConnectionOptions are used when creating iRODS iCAT server connections see gorods.New() docs for more info.
@@ -283,21 +320,125 @@ type ConnectionOptions struct {
 	Host          string
 	Port          int
 	Zone          string
+	UserZone      string
 	Username      string
 	Password      string
 	Ticket        string
+	ClientUser    string
+	ClientZone    string
 	FastInit      bool
 	Threads       int
+	Scanners      []Scanner
+	Hooks         *Hooks
+	InheritMeta   bool
+	Recorder      Recorder
+	LeakWarnings  bool
+	Cache         CacheBackend
+	IdleCheck     time.Duration
+	IdlePing      bool
+	ProgramName   string
+
+	// DefaultResource is used when no resource is passed to Put, CreateDataObj or CopyTo, see Connection.DefaultResource
+	DefaultResource string
+
+	// ChangeLog records every mutating call made with the connection, see OpenChangeLog
+	ChangeLog *ChangeLog
+
+	// Labels (like tenant or job-id) are added to the log lines and leak reports of the connection, and passed to
+	// Recorders implementing LabeledRecorder. See also Connection.SetLabel.
+	Labels map[string]string
+
+	// Transforms (like GzipTransform) are applied to uploads by Collection.Put, see DataObjOptions.Transforms.
+	// DataObj.DownloadTo reverses them when Transforms is set, or DecodeTransforms for connections that only download.
+	Transforms       []Transform
+	DecodeTransforms bool
+
+	// ConnectTimeout limits the time taken to connect and authenticate, a connect that doesn't complete in time is
+	// abandoned. ReadTimeout and WriteTimeout limit the time a single socket read or write may block, authentication
+	// included. A call that held the connection handle as long as one of them may have left the protocol stream out
+	// of sync, so the handle is pinged, and replaced if needed, before its next use. No limit if 0.
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+
+	// Operations taking longer than SlowOperation are passed to SlowLog, or logged if SlowLog is nil
+	SlowOperation time.Duration
+	SlowLog       func(e *Event)
+
+	// ReopenHandles makes open data objects re-open themselves, and seek back to their offset, on their next use
+	// after the connection handle was replaced (see IdleCheck) or a read failed because the server dropped the
+	// connection, e.g. once its agent timed out. See DataObj.SetReopen.
+	ReopenHandles bool
+
+	// NoRedirect disables connecting directly to the resource server the provider redirects transfers to
+	// (rcGetHostForPut and rcGetHostForGet), for firewalled environments where only the provider is reachable.
+	// Redirection applies to Put, DownloadTo and the handles of data objects, so to their streaming reads and writes.
+	// The host of each resource is asked once per connection. Only UserDefined connections not using PAMAuth are
+	// redirected.
+	NoRedirect bool
+
+	// Logger receives the connection's diagnostics at LogLevel and above (LogWarn by default). They're written to
+	// the standard logger if nil.
+	Logger   Logger
+	LogLevel int
+
+	// Bandwidth caps the combined transfer rate of the connections opened with these options, shared by the
+	// Client and its Pools. TransferBytesPerSec caps each transfer: a Put, a DownloadTo, or the reads and writes of
+	// a DataObj. The process Policy's BandwidthPolicy applies too.
+	Bandwidth           *BandwidthLimit
+	TransferBytesPerSec int64
 }
 
 // Connection structs hold information about the iRODS iCAT server, and the user who's connecting. It also contains a cache of opened Collections and DataObjs
+//
+// A *Connection is safe for use by multiple goroutines. Every iRODS API call checks out the connection handle
+// with GetCcon, so calls from different goroutines are queued and run one at a time, in the order they arrive.
+// The cached users, groups, zones, resources and OpenedObjs are guarded by an internal lock, and positioned reads
+// and writes on a single *DataObj (ReadBytes, WriteBytes, LSeek...) are serialized so seeks and transfers don't interleave.
+// Sequences of separate calls (e.g. LSeek followed by WriteBytes) are not atomic; use the positioned methods instead.
 type Connection struct {
 	ccon       *C.rcComm_t
 	cconBuffer chan *C.rcComm_t
+	mu         sync.RWMutex
 	users      Users
 	groups     Groups
 	zones      Zones
 	resources  Resources
+	scanners   []Scanner
+	hooks      *Hooks
+	hooksOnce  sync.Once
+	span       Span
+	waiting    int32
+	lastUsed   int64
+	reconnects int32
+	suspect    int32
+	checkedOut int64
+
+	userType    int
+	userTypeSet bool
+
+	// initMu serializes init, zonesMu guards the load of the zones cache, which init calls back into
+	initMu      sync.Mutex
+	zonesMu     sync.Mutex
+	zonesLoaded bool
+
+	caps Capabilities
+
+	redirects map[string]*Connection
+
+	// transferHosts caches the hosts returned by hostFor, by resource and direction ("" keeps the transfer on con)
+	transferHosts map[string]string
+
+	envResc     string
+	envRescOnce sync.Once
+
+	labels     map[string]string
+	labeledRec Recorder
+	labelMu    sync.RWMutex
+
+	// handles are the open handles recorded by watchLeak, with LeakWarnings
+	handles   map[interface{}]Leak
+	handlesMu sync.Mutex
 
 	PAMToken   string
 	Connected  bool
@@ -317,6 +458,7 @@ func NewConnection(opts *ConnectionOptions) (*Connection, error) {
 	con.Options = opts
 
 	if err := con.InitCon(); err == nil {
+		con.watchLeak(con, "Connection", con.Options.Host)
 		return con, nil
 	} else {
 		return con, err
@@ -329,7 +471,7 @@ func (con *Connection) UserInfo() (map[string]string, error) {
 	var cUsrInfo C.userInfo_t
 	var cErr *C.char
 
-	cName := C.CString(con.Options.Username)
+	cName := C.CString(con.ClientUser())
 	defer C.free(unsafe.Pointer(cName))
 
 	ccon := con.GetCcon()
@@ -353,6 +495,9 @@ func (con *Connection) UserInfo() (map[string]string, error) {
 }
 
 func (con *Connection) InitCon() error {
+	con.mu.Lock()
+	con.userTypeSet = false
+	con.mu.Unlock()
 
 	if con.Connected {
 		if err := con.Disconnect(); err != nil {
@@ -361,6 +506,92 @@ func (con *Connection) InitCon() error {
 		// Should the con.Options.PAMToken be reset here?
 	}
 
+	if err := con.dial(); err != nil {
+		con.log(LogInfo, "connect failed", "host", con.Options.Host, "error", err)
+		return err
+	}
+
+	con.cconBuffer = make(chan *C.rcComm_t, 1)
+	con.cconBuffer <- con.ccon
+
+	con.Connected = true
+	atomic.StoreInt64(&con.lastUsed, time.Now().UnixNano())
+
+	con.SetThreads(con.Options.Threads)
+	con.detectCapabilities()
+
+	con.log(LogInfo, "connected", "host", con.Options.Host, "zone", con.Options.Zone, "user", con.ClientUser(), "server", con.Capabilities())
+
+	if con.Options.Ticket != "" {
+		if err := con.SetTicket(con.Options.Ticket); err != nil {
+			return err
+		}
+	}
+
+	if !con.Options.FastInit {
+		if err := con.init(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dial connects to the server and authenticates, setting con.ccon. With ConnectionOptions.ConnectTimeout, the connect
+// runs with a copy of the options, and is abandoned if it doesn't complete in time: its handle is closed whenever
+// it completes, and spOption stays locked until then, since rcConnect may still read it.
+func (con *Connection) dial() error {
+	restoreProgramName := setProgramName(con.Options.ProgramName)
+
+	timeout := con.Options.ConnectTimeout
+	if timeout <= 0 {
+		defer restoreProgramName()
+		return con.connect(restoreProgramName)
+	}
+
+	opts := *con.Options
+	attempt := &Connection{Options: &opts}
+	done := make(chan error, 1)
+
+	go func() {
+		done <- attempt.connect(restoreProgramName)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		restoreProgramName()
+
+		if err != nil {
+			return err
+		}
+	case <-timer.C:
+		go func() {
+			if err := <-done; err == nil {
+				C.rcDisconnect(attempt.ccon)
+			}
+
+			restoreProgramName()
+		}()
+
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Connect Failed: %v:%v didn't complete the connection within %v", con.Options.Host, con.Options.Port, timeout))
+	}
+
+	con.ccon = attempt.ccon
+	con.PAMToken = attempt.PAMToken
+
+	// The settings connect fills in
+	con.Options.Host, con.Options.Port, con.Options.Username, con.Options.Zone = opts.Host, opts.Port, opts.Username, opts.Zone
+	con.Options.AuthType, con.Options.PAMPassExpire = opts.AuthType, opts.PAMPassExpire
+
+	return nil
+}
+
+// connect connects to the server and authenticates, setting con.ccon. It calls restoreProgramName once the
+// connection is established. The handle is closed again if authentication fails.
+func (con *Connection) connect(restoreProgramName func()) (err error) {
 	var (
 		status    C.int
 		errMsg    *C.char
@@ -372,19 +603,39 @@ func (con *Connection) InitCon() error {
 	if con.Options.Type == UserDefined {
 		host := C.CString(con.Options.Host)
 		port := C.int(con.Options.Port)
-		username := C.CString(con.Options.Username)
+		username := C.CString(con.username())
 		zone := C.CString(con.Options.Zone)
 
+		// Remote (federated) users authenticate against their home zone
+		if con.Options.UserZone != "" {
+			C.free(unsafe.Pointer(zone))
+			zone = C.CString(con.Options.UserZone)
+		}
+
 		defer C.free(unsafe.Pointer(host))
 		defer C.free(unsafe.Pointer(username))
 		defer C.free(unsafe.Pointer(zone))
 
 		// BUG(jjacquay712): iRODS C API code outputs errors messages, need to implement connect wrapper (gorods_connect_env) from a lower level to suppress this output
 		// https://github.com/irods/irods/blob/master/iRODS/lib/core/src/rcConnect.cpp#L109
-		if status = C.gorods_connect_env(&con.ccon, host, port, username, zone, &errMsg); status != 0 {
+		if con.Options.ClientUser != "" {
+			clientUser := C.CString(con.Options.ClientUser)
+			clientZone := C.CString(con.clientZone())
+			defer C.free(unsafe.Pointer(clientUser))
+			defer C.free(unsafe.Pointer(clientZone))
+
+			status = C.gorods_connect_proxy(&con.ccon, host, port, username, zone, clientUser, clientZone, &errMsg)
+		} else {
+			status = C.gorods_connect_env(&con.ccon, host, port, username, zone, &errMsg)
+		}
+
+		if status != 0 {
 			return newError(Fatal, status, fmt.Sprintf("iRODS Connect Failed: %v", C.GoString(errMsg)))
 		}
 	} else {
+		if con.Options.ClientUser != "" {
+			return newError(Fatal, -1, "iRODS Connect Failed: ClientUser requires a UserDefined connection")
+		}
 
 		var cHost, cUsername, cZone *C.char
 		var cPort C.int
@@ -399,16 +650,23 @@ func (con *Connection) InitCon() error {
 		con.Options.Zone = C.GoString(cZone)
 	}
 
-	con.cconBuffer = make(chan *C.rcComm_t, 1)
-	con.cconBuffer <- con.ccon
+	restoreProgramName()
 
-	con.Connected = true
+	defer func() {
+		if err != nil {
+			C.rcDisconnect(con.ccon)
+		}
+	}()
+
+	if err := con.setSocketTimeouts(); err != nil {
+		return err
+	}
 
 	ipassword = C.CString(con.Options.Password)
 	defer C.free(unsafe.Pointer(ipassword))
 
 	if con.Options.AuthType == 0 {
-		con.Options.AuthType = PasswordAuth // Options: PasswordAuth PAMAuth
+		con.Options.AuthType = PasswordAuth // Options: PasswordAuth PAMAuth AnonymousAuth
 	}
 
 	if con.Options.PAMPassExpire == 0 {
@@ -505,6 +763,10 @@ func (con *Connection) InitCon() error {
 		}
 	} else if con.Options.AuthType == PasswordAuth {
 		opassword = ipassword
+	} else if con.Options.AuthType == AnonymousAuth {
+		// The anonymous user has no password
+		opassword = C.CString("")
+		defer C.free(unsafe.Pointer(opassword))
 	}
 
 	if status = C.clientLoginWithPassword(con.ccon, opassword); status != 0 {
@@ -540,20 +802,6 @@ func (con *Connection) InitCon() error {
 		return newError(Fatal, status, fmt.Sprintf("iRODS Connect Failed: %v", C.GoString(errMsg)))
 	}
 
-	con.SetThreads(con.Options.Threads)
-
-	if con.Options.Ticket != "" {
-		if err := con.SetTicket(con.Options.Ticket); err != nil {
-			return err
-		}
-	}
-
-	if !con.Options.FastInit {
-		if err := con.init(); err != nil {
-			return err
-		}
-	}
-
 	return nil
 }
 
@@ -585,13 +833,43 @@ func (con *Connection) fetchAndWritePAMPass(pamPassFile *os.File, ipassword *C.c
 
 // GetCcon checks out the connection handle for use in all iRODS operations. Basically a mutex for connections.
 // Other goroutines calling this function will block until the handle is returned with con.ReturnCcon, by the goroutine using it.
+// Don't call GetCcon again (or any GoRODS function) before returning the handle, it will deadlock.
 // This prevents errors in the net code since concurrent API calls aren't supported over a single iRODS connection.
 func (con *Connection) GetCcon() *C.rcComm_t {
-	return <-con.cconBuffer
+	r := con.recorder()
+	if r == nil {
+		return con.checkOut(<-con.cconBuffer)
+	}
+
+	start := time.Now()
+
+	atomic.AddInt32(&con.waiting, 1)
+	ccon := <-con.cconBuffer
+	atomic.AddInt32(&con.waiting, -1)
+
+	con.startSpan(r, time.Since(start))
+
+	return con.checkOut(ccon)
+}
+
+// checkOut runs the idle check on the handle taken by GetCcon, and records when it was checked out
+func (con *Connection) checkOut(ccon *C.rcComm_t) *C.rcComm_t {
+	ccon = con.checkIdle(ccon)
+
+	atomic.StoreInt64(&con.checkedOut, time.Now().UnixNano())
+
+	return ccon
 }
 
 // ReturnCcon returns the connection handle for use in other threads. Unlocks the mutex.
 func (con *Connection) ReturnCcon(ccon *C.rcComm_t) {
+	con.endSpan()
+
+	now := time.Now().UnixNano()
+
+	con.observeCheckout(time.Duration(now - atomic.LoadInt64(&con.checkedOut)))
+	atomic.StoreInt64(&con.lastUsed, now)
+
 	con.cconBuffer <- ccon
 }
 
@@ -631,7 +909,7 @@ func (con *Connection) EmptyTrash() error {
 	}
 
 	if con.Options.Username != "" {
-		user = con.Options.Username
+		user = con.ClientUser()
 	} else {
 		return fmt.Errorf("Empty trash for environment based connections not implemented yet")
 	}
@@ -733,11 +1011,20 @@ func (con *Connection) RegPhysObj(opts RegOptions) error {
 	return nil
 }
 
+// Close is an alias of Disconnect, so *Connection satisfies io.Closer. It is safe to call more than once.
+func (con *Connection) Close() error {
+	return con.Disconnect()
+}
+
 // Disconnect closes connection to iRODS iCAT server, returns error on failure or nil on success
 func (con *Connection) Disconnect() error {
 
 	if con.Connected {
-		for _, obj := range con.OpenedObjs {
+		con.mu.RLock()
+		opened := con.OpenedObjs
+		con.mu.RUnlock()
+
+		for _, obj := range opened {
 			if er := obj.Close(); er != nil {
 				return er
 			}
@@ -745,6 +1032,12 @@ func (con *Connection) Disconnect() error {
 
 		//con.OpenedObjs = make(IRodsObjs, 0)
 
+		con.reportLeaks()
+
+		if er := con.closeRedirects(); er != nil {
+			return er
+		}
+
 		ccon := con.GetCcon()
 		defer con.ReturnCcon(ccon)
 
@@ -753,6 +1046,9 @@ func (con *Connection) Disconnect() error {
 		}
 
 		con.Connected = false
+		con.unwatchLeak(con)
+
+		con.log(LogInfo, "disconnected", "host", con.Options.Host)
 	}
 
 	return nil
@@ -790,12 +1086,18 @@ func (con *Connection) Collection(opts CollectionOptions) (*Collection, error) {
 	recursive := opts.Recursive
 
 	// Check the cache
-	if collection := con.OpenedObjs.FindRecursive(startPath); collection == nil || opts.SkipCache {
+	con.mu.RLock()
+	collection := con.OpenedObjs.FindRecursive(startPath)
+	con.mu.RUnlock()
+
+	if collection == nil || opts.SkipCache {
 		//if collection := con.OpenedObjs.FindRecursive(startPath); true {
 
 		// Load collection, no cache found
 		if col, err := getCollection(opts, con); err == nil {
+			con.mu.Lock()
 			con.OpenedObjs = append(con.OpenedObjs, col)
+			con.mu.Unlock()
 
 			return col, nil
 		} else {
@@ -856,16 +1158,44 @@ func (con *Connection) PathType(p string) (int, error) {
 }
 
 func (con *Connection) SetThreads(num int) {
-	con.ccon.transStat.numThreads = C.int(num)
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	ccon.transStat.numThreads = C.int(num)
 }
 
 func (con *Connection) Threads() int {
-	return int(con.ccon.transStat.numThreads)
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	return int(ccon.transStat.numThreads)
 }
 
 // IQuest accepts a SQL query fragment, returns results in slice of maps
 // If upperCase is true, all records will be matched using their uppercase representation.
 func (con *Connection) IQuest(query string, upperCase bool) ([]map[string]string, error) {
+	z, zErr := con.LocalZone()
+	if zErr != nil {
+		return nil, zErr
+	}
+
+	return con.IQuestZone(query, upperCase, z.Name())
+}
+
+// IQuestZone is the same as IQuest, except the query is run against the catalog of the zone specified (iquest -z).
+// Use this to query remote (federated) zones.
+func (con *Connection) IQuestZone(query string, upperCase bool, zone string) ([]map[string]string, error) {
+	var result []map[string]string
+
+	err := con.intercept(&Event{Op: OpQuery, Query: query}, func() (err error) {
+		result, err = con.iquest(query, upperCase, zone)
+		return
+	})
+
+	return result, err
+}
+
+func (con *Connection) iquest(query string, upperCase bool, zone string) ([]map[string]string, error) {
 	var (
 		result C.goRodsHashResult_t
 		err    *C.char
@@ -874,17 +1204,12 @@ func (con *Connection) IQuest(query string, upperCase bool) ([]map[string]string
 
 	result.size = C.int(0)
 
-	z, zErr := con.LocalZone()
-	if zErr != nil {
-		return nil, zErr
-	}
-
 	if upperCase {
 		upper = 1
 	}
 
 	cQueryString := C.CString(query)
-	cZoneName := C.CString(z.Name())
+	cZoneName := C.CString(zone)
 	defer C.free(unsafe.Pointer(cZoneName))
 	defer C.free(unsafe.Pointer(cQueryString))
 
@@ -902,37 +1227,7 @@ func (con *Connection) IQuest(query string, upperCase bool) ([]map[string]string
 	con.ReturnCcon(ccon)
 	defer C.gorods_free_map_result(&result)
 
-	unsafeKeyArr := unsafe.Pointer(result.hashKeys)
-	keyArrLen := int(result.keySize)
-
-	unsafeValArr := unsafe.Pointer(result.hashValues)
-	valArrLen := int(result.size) * keyArrLen
-
-	response := make([]map[string]string, int(result.size))
-
-	// Convert C array to slice
-	keySlice := (*[1 << 30]*C.char)(unsafeKeyArr)[:keyArrLen:keyArrLen]
-	valSlice := (*[1 << 30]*C.char)(unsafeValArr)[:valArrLen:valArrLen]
-
-	for n, val := range valSlice {
-		mapInx := n / keyArrLen
-
-		var key string
-
-		if n == 0 {
-			key = C.GoString(keySlice[0])
-		} else {
-			key = C.GoString(keySlice[int(math.Mod(float64(n), float64(keyArrLen)))])
-		}
-
-		if response[mapInx] == nil {
-			response[mapInx] = make(map[string]string)
-		}
-
-		response[mapInx][key] = C.GoString(val)
-	}
-
-	return response, nil
+	return hashResultToMaps(&result), nil
 }
 
 // DataObject directly returns a specific DataObj without the need to traverse collections. Must pass full path of data object.
@@ -945,6 +1240,15 @@ func (con *Connection) DataObject(dataObjPath string) (dataobj *DataObj, err err
 
 // QueryMeta queries both data objects and collections for matching metadata. Returns IRodsObjs.
 func (con *Connection) QueryMeta(qString string) (response IRodsObjs, err error) {
+	err = con.intercept(&Event{Op: OpQuery, Query: qString}, func() (err error) {
+		response, err = con.queryMeta(qString)
+		return
+	})
+
+	return
+}
+
+func (con *Connection) queryMeta(qString string) (response IRodsObjs, err error) {
 
 	var errMsg *C.char
 	var query *C.char = C.CString(qString)
@@ -1012,39 +1316,85 @@ func (con *Connection) QueryMeta(qString string) (response IRodsObjs, err error)
 	return
 }
 
+// init loads the users, groups, zones and resources caches, once. Concurrent callers wait for the load, and a
+// failed load is retried by the next call. Init is only set once every cache is loaded.
 func (con *Connection) init() error {
-	if !con.Init {
-		con.Init = true
+	con.initMu.Lock()
+	defer con.initMu.Unlock()
 
-		info, iErr := con.UserInfo()
-		if iErr != nil {
-			return iErr
-		}
+	con.mu.RLock()
+	loaded := con.Init
+	con.mu.RUnlock()
 
-		// user must be rodsadmin
-		if info["type"] == "rodsadmin" {
-			if err := con.RefreshZones(); err != nil {
-				return err
-			}
-		}
+	if loaded {
+		return nil
+	}
+
+	// The cache loaders below call back into Zones (through LocalZone), so the zones are loaded first, with a guard
+	// of their own
+	if err := con.initZones(); err != nil {
+		return err
+	}
 
-		if err := con.RefreshResources(); err != nil {
+	typ, err := con.UserType()
+	if err != nil {
+		return err
+	}
+
+	if err := con.RefreshResources(); err != nil {
+		return err
+	}
+
+	// user must be rodsadmin
+	if typ == AdminType {
+		if err := con.RefreshUsers(); err != nil {
 			return err
 		}
+	}
 
-		// user must be rodsadmin
-		if info["type"] == "rodsadmin" {
-			if err := con.RefreshUsers(); err != nil {
-				return err
-			}
-		}
+	if err := con.RefreshGroups(); err != nil {
+		return err
+	}
 
-		if err := con.RefreshGroups(); err != nil {
+	con.mu.Lock()
+	con.Init = true
+	con.mu.Unlock()
+
+	return nil
+}
+
+// initZones loads the zones cache, once. A failed load is retried by the next call.
+func (con *Connection) initZones() error {
+	con.zonesMu.Lock()
+	defer con.zonesMu.Unlock()
+
+	if con.zonesLoaded {
+		return nil
+	}
+
+	typ, err := con.UserType()
+	if err != nil {
+		return err
+	}
+
+	// user must be rodsadmin, otherwise fall back to a general query. Zones are only used to resolve federated
+	// paths, so a server refusing the query leaves them unknown instead of failing the connection.
+	if typ == AdminType {
+		if err := con.RefreshZones(); err != nil {
 			return err
 		}
-
+	} else {
+		if zones, err := con.QueryZones(); err == nil {
+			con.mu.Lock()
+			con.zones = zones
+			con.mu.Unlock()
+		} else {
+			con.log(LogWarn, "unable to list the zones", "error", err)
+		}
 	}
 
+	con.zonesLoaded = true
+
 	return nil
 }
 
@@ -1054,6 +1404,10 @@ func (con *Connection) Groups() (Groups, error) {
 	if err := con.init(); err != nil {
 		return nil, err
 	}
+
+	con.mu.RLock()
+	defer con.mu.RUnlock()
+
 	return con.groups, nil
 }
 
@@ -1063,15 +1417,23 @@ func (con *Connection) Users() (Users, error) {
 	if err := con.init(); err != nil {
 		return nil, err
 	}
+
+	con.mu.RLock()
+	defer con.mu.RUnlock()
+
 	return con.users, nil
 }
 
-// Zones returns a slice of all *Zone in the iCAT.
-// You must have the proper rodsadmin privileges to use this function.
+// Zones returns a slice of all *Zone in the iCAT, including remote (federated) zones.
+// For users without rodsadmin privileges the zones are discovered with a general query.
 func (con *Connection) Zones() (Zones, error) {
-	if err := con.init(); err != nil {
+	if err := con.initZones(); err != nil {
 		return nil, err
 	}
+
+	con.mu.RLock()
+	defer con.mu.RUnlock()
+
 	return con.zones, nil
 }
 
@@ -1081,6 +1443,10 @@ func (con *Connection) Resources() (Resources, error) {
 	if err := con.init(); err != nil {
 		return nil, err
 	}
+
+	con.mu.RLock()
+	defer con.mu.RUnlock()
+
 	return con.resources, nil
 }
 
@@ -1146,7 +1512,9 @@ func (con *Connection) RefreshResources() error {
 	if resources, err := con.FetchResources(); err != nil {
 		return err
 	} else {
+		con.mu.Lock()
 		con.resources = resources
+		con.mu.Unlock()
 	}
 
 	return nil
@@ -1157,7 +1525,9 @@ func (con *Connection) RefreshUsers() error {
 	if users, err := con.FetchUsers(); err != nil {
 		return err
 	} else {
+		con.mu.Lock()
 		con.users = users
+		con.mu.Unlock()
 	}
 
 	return nil
@@ -1168,7 +1538,9 @@ func (con *Connection) RefreshZones() error {
 	if zones, err := con.FetchZones(); err != nil {
 		return err
 	} else {
+		con.mu.Lock()
 		con.zones = zones
+		con.mu.Unlock()
 	}
 
 	return nil
@@ -1179,7 +1551,9 @@ func (con *Connection) RefreshGroups() error {
 	if groups, err := con.FetchGroups(); err != nil {
 		return err
 	} else {
+		con.mu.Lock()
 		con.groups = groups
+		con.mu.Unlock()
 	}
 
 	return nil
@@ -1380,6 +1754,96 @@ func (con *Connection) FetchZones() (Zones, error) {
 	return response, nil
 }
 
+// QueryZones returns a slice of *Zone using a general query. Unlike FetchZones, it doesn't require rodsadmin privileges.
+func (con *Connection) QueryZones() (Zones, error) {
+
+	typeMap := map[string]int{
+		"local":  Local,
+		"remote": Remote,
+	}
+
+	result, err := con.IQuestZone("select ZONE_NAME, ZONE_TYPE, ZONE_CONNECTION, ZONE_COMMENT, ZONE_CREATE_TIME, ZONE_MODIFY_TIME, ZONE_ID", false, "")
+	if err != nil {
+		return nil, err
+	}
+
+	response := make(Zones, 0)
+
+	for _, row := range result {
+		zne, _ := initZone(row["ZONE_NAME"], con)
+
+		zne.typ = typeMap[row["ZONE_TYPE"]]
+		zne.conString = row["ZONE_CONNECTION"]
+		zne.comment = row["ZONE_COMMENT"]
+		zne.createTime = timeStringToTime(row["ZONE_CREATE_TIME"])
+		zne.modifyTime = timeStringToTime(row["ZONE_MODIFY_TIME"])
+		zne.id, _ = strconv.Atoi(row["ZONE_ID"])
+		zne.parentSlice = &response
+		zne.hasInit = true
+
+		response = append(response, zne)
+	}
+
+	return response, nil
+}
+
+// RemoteZones returns only the remote (federated) zones known to the local iCAT.
+func (con *Connection) RemoteZones() (Zones, error) {
+	zones, err := con.Zones()
+	if err != nil {
+		return nil, err
+	}
+
+	response := make(Zones, 0)
+
+	for _, zne := range zones {
+		if remote, er := zne.IsRemote(); er != nil {
+			return nil, er
+		} else if remote {
+			response = append(response, zne)
+		}
+	}
+
+	return response, nil
+}
+
+// PathZone returns the *Zone that an absolute iRODS path belongs to, e.g. /otherZone/home/user#tempZone returns otherZone.
+// It returns an error if the zone isn't known to the server.
+func (con *Connection) PathZone(p string) (*Zone, error) {
+	name := pathZoneName(p)
+	if name == "" {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS PathZone Failed: %v is not an absolute path", p))
+	}
+
+	zones, err := con.Zones()
+	if err != nil {
+		return nil, err
+	}
+
+	// FindByName would make up a zone for any name
+	for _, zone := range zones {
+		if zone.name == name {
+			return zone, nil
+		}
+	}
+
+	return nil, newError(Fatal, -1, fmt.Sprintf("iRODS PathZone Failed: unknown zone %v of %v", name, p))
+}
+
+// zoneHint returns the zone name to use for queries that concern the path specified, defaults to the local zone
+func (con *Connection) zoneHint(p string) (string, error) {
+	if name := pathZoneName(p); name != "" {
+		return name, nil
+	}
+
+	z, err := con.LocalZone()
+	if err != nil {
+		return "", err
+	}
+
+	return z.Name(), nil
+}
+
 // LocalZone returns the *Zone. First it checks the ConnectionOptions.Zone and uses that, otherwise it pulls it fresh from the iCAT server.
 func (con *Connection) LocalZone() (*Zone, error) {
 
@@ -1,9 +1,15 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, The BioTeam, Inc.                     ***
  *** For more information please refer to the LICENSE.md file  ***/
 
 package gorods
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestUserDefinedConnection(t *testing.T) {
 	if irods, err := NewConnection(&ConnectionOptions{
@@ -44,3 +50,66 @@ func TestPAMConnection(t *testing.T) {
 	}
 
 }
+
+func TestIdleCheck(t *testing.T) {
+	irods, err := NewConnection(&ConnectionOptions{
+		Type: UserDefined,
+
+		Host: "localhost",
+		Port: 1247,
+		Zone: "tempZone",
+
+		Username: "rods",
+		Password: "password",
+
+		IdleCheck: 10 * time.Millisecond,
+		IdlePing:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer irods.Disconnect()
+
+	time.Sleep(20 * time.Millisecond)
+
+	if err := irods.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	if irods.Reconnects() != 0 {
+		t.Errorf("Expected a healthy connection to be kept, got %v reconnects", irods.Reconnects())
+	}
+}
+
+func TestPathZone(t *testing.T) {
+	for p, expected := range map[string]string{
+		"/fedZone/home/rods/a.txt": "fedZone",
+		"/tempZone":                "tempZone",
+		"home/rods":                "",
+		"/":                        "",
+	} {
+		if name := pathZoneName(p); name != expected {
+			t.Errorf("Expected zone %q for %v, got %q", expected, p, name)
+		}
+	}
+
+	con := &Connection{Options: &ConnectionOptions{Zone: "tempZone"}, zonesLoaded: true}
+	con.zones = Zones{{name: "tempZone", con: con}, {name: "fedZone", typ: Remote, con: con}}
+
+	if zone, err := con.PathZone("/fedZone/home/rods"); err != nil || zone.Name() != "fedZone" {
+		t.Errorf("Expected the federated zone, got %v, %v", zone, err)
+	}
+
+	if zone, err := con.PathZone("/otherZone/home/rods"); err == nil {
+		t.Errorf("Expected an error for an unknown zone, got %v", zone)
+	}
+
+	if zone, err := con.PathZone("home/rods"); err == nil {
+		t.Errorf("Expected an error for a relative path, got %v", zone)
+	}
+
+	// Queries about federated paths are sent to the path's zone
+	if zone, err := con.zoneHint("/fedZone/home/rods"); err != nil || zone != "fedZone" {
+		t.Errorf("Expected the fedZone hint, got %q, %v", zone, err)
+	}
+}
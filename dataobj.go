@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
  *** For more information please refer to the LICENSE.md file                                   ***/
 
@@ -8,13 +11,17 @@ import "C"
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
+
+	"github.com/jjacquay712/GoRODS/api"
 )
 
 // DataObj structs contain information about single data objects in an iRODS zone.
@@ -34,7 +41,11 @@ type DataObj struct {
 	resource *Resource
 	phyPath  string
 
-	openedAs C.int
+	// destination is the resource targeted by the Put or CreateDataObj call that returned the data object
+	destination string
+
+	openedAs  C.int
+	appending bool
 
 	ownerName string
 	owner     *User
@@ -51,17 +62,37 @@ type DataObj struct {
 	col *Collection
 
 	chandle C.int
-}
 
-// DataObjOptions is used for passing options to the CreateDataObj and DataObj.Copy function
-type DataObjOptions struct {
-	Name     string
-	Size     int64
-	Mode     int
-	Force    bool
-	Resource interface{}
+	// hcon is the connection chandle was opened on: con, or the connection to the resource server transfers are
+	// redirected to
+	hcon *Connection
+
+	// openedGen is the Connection.Reconnects count when chandle was opened, reopen overrides ReopenHandles if reopenSet
+	openedGen int
+	reopen    bool
+	reopenSet bool
+
+	// limiter enforces ConnectionOptions.TransferBytesPerSec, see transferLimiter
+	limiter     *rateLimiter
+	limiterOnce sync.Once
+
+	// mu serializes seeks and transfers on the handle
+	mu sync.Mutex
 }
 
+// Open flags used with DataObj.OpenFlags. Combine one of O_RDONLY, O_WRONLY or O_RDWR with O_APPEND and O_TRUNC.
+// These are the values the iRODS protocol uses (those of Linux), whatever the flags of the client's platform are.
+const (
+	O_RDONLY = 0
+	O_WRONLY = 01
+	O_RDWR   = 02
+	O_TRUNC  = 01000
+	O_APPEND = 02000
+)
+
+// DataObjOptions is used for passing options to the CreateDataObj and DataObj.Copy function, see api.DataObjOptions
+type DataObjOptions = api.DataObjOptions
+
 // String returns path of data object
 func (obj *DataObj) String() string {
 	return "DataObject: " + obj.path
@@ -177,6 +208,26 @@ func getDataObj(startPath string, con *Connection) (*DataObj, error) {
 
 // CreateDataObj creates and adds a data object to the specified collection using provided options. Returns the newly created data object.
 func CreateDataObj(opts DataObjOptions, coll *Collection) (*DataObj, error) {
+	var obj *DataObj
+
+	params := map[string]string{"force": strconv.FormatBool(opts.Force)}
+	if resc, er := resourceName(opts.Resource); er == nil && resc != "" {
+		params["resource"] = resc
+	}
+
+	err := coll.con.intercept(&Event{Op: OpPut, Path: coll.path + "/" + opts.Name, Size: opts.Size, Params: params}, func() (err error) {
+		obj, err = createDataObj(opts, coll)
+		return
+	})
+
+	if err == nil {
+		err = coll.inheritMeta(obj)
+	}
+
+	return obj, err
+}
+
+func createDataObj(opts DataObjOptions, coll *Collection) (*DataObj, error) {
 
 	var (
 		errMsg   *C.char
@@ -191,20 +242,13 @@ func CreateDataObj(opts DataObjOptions, coll *Collection) (*DataObj, error) {
 		force = 0
 	}
 
-	if opts.Resource != nil {
-		switch opts.Resource.(type) {
-		case string:
-			resource = C.CString(opts.Resource.(string))
-		case *Resource:
-			r := opts.Resource.(*Resource)
-			resource = C.CString(r.Name())
-		default:
-			return nil, newError(Fatal, -1, fmt.Sprintf("Wrong variable type passed in Resource field"))
-		}
-	} else {
-		resource = C.CString("")
+	rescName, rErr := coll.con.targetResource(opts.Resource)
+	if rErr != nil {
+		return nil, rErr
 	}
 
+	resource = C.CString(rescName)
+
 	path := C.CString(coll.path + "/" + opts.Name)
 
 	defer C.free(unsafe.Pointer(path))
@@ -225,17 +269,27 @@ func CreateDataObj(opts DataObjOptions, coll *Collection) (*DataObj, error) {
 	if do, err := getDataObj(C.GoString(path), coll.con); err != nil {
 		return nil, err
 	} else {
+		do.destination = rescName
 		return do, nil
 	}
 
 }
 
+// handleCon returns the connection the handle of the data object was opened on
+func (obj *DataObj) handleCon() *Connection {
+	if obj.hcon != nil {
+		return obj.hcon
+	}
+
+	return obj.con
+}
+
 func (obj *DataObj) init() error {
 	if int(obj.chandle) < 0 {
 		return obj.Open()
 	}
 
-	return nil
+	return obj.revive()
 }
 
 func (obj *DataObj) initRW() error {
@@ -243,7 +297,7 @@ func (obj *DataObj) initRW() error {
 		return obj.OpenRW()
 	}
 
-	return nil
+	return obj.revive()
 }
 
 // GetACL retuns a slice of ACL structs. Example of slice in string format:
@@ -258,11 +312,11 @@ func (obj *DataObj) ACL() (ACLs, error) {
 		zoneHint *C.char
 	)
 
-	zone, zErr := obj.con.LocalZone()
+	zone, zErr := obj.con.zoneHint(obj.path)
 	if zErr != nil {
 		return nil, zErr
 	} else {
-		zoneHint = C.CString(zone.Name())
+		zoneHint = C.CString(zone)
 	}
 
 	cDataId := C.CString(obj.dataId)
@@ -290,7 +344,17 @@ func (obj *DataObj) Chmod(userOrGroup string, accessLevel int, recursive bool) e
 
 // GrantAccess will add permissions (ACL) to the data object.
 func (obj *DataObj) GrantAccess(userOrGroup AccessObject, accessLevel int, recursive bool) error {
-	return chmod(obj, userOrGroup.Name(), accessLevel, false, true)
+	return obj.SetAccess(PrincipalOf(userOrGroup), accessLevel, false)
+}
+
+// SetAccess changes the access level of the user or group principal on the data object, in the principal's zone.
+// Data objects have no contents to apply it to, recursive must be false.
+func (obj *DataObj) SetAccess(p Principal, accessLevel int, recursive bool) error {
+	if recursive {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Chmod Failed: %v is a data object, it can't be changed recursively", obj.path))
+	}
+
+	return p.chmod(obj, accessLevel, false)
 }
 
 // Handle returns the internal handle index
@@ -356,6 +420,12 @@ func (obj *DataObj) RescHier() string {
 	return obj.rescHier
 }
 
+// Destination returns the resource name or hierarchy the Put or CreateDataObj call that returned obj asked the server
+// to store it in, after applying the defaults (see Connection.DefaultResource). It is empty if the server chose the resource.
+func (obj *DataObj) Destination() string {
+	return obj.destination
+}
+
 func (obj *DataObj) ReplStatus() int {
 	return obj.replStatus
 }
@@ -418,6 +488,14 @@ func (obj *DataObj) Trash(recursive bool) error {
 
 // Rm is equivalent to irm {-r} {-f}
 func (obj *DataObj) Rm(recursive bool, force bool) error {
+	params := map[string]string{"type": "dataobj", "recursive": strconv.FormatBool(recursive), "force": strconv.FormatBool(force)}
+
+	return obj.con.intercept(&Event{Op: OpDelete, Path: obj.path, Params: params}, func() error {
+		return obj.rm(recursive, force)
+	})
+}
+
+func (obj *DataObj) rm(recursive bool, force bool) error {
 	var errMsg *C.char
 
 	path := C.CString(obj.path)
@@ -467,6 +545,10 @@ func (obj *DataObj) RmTrash() error {
 
 // Open opens a connection to iRODS and sets the data object handle
 func (obj *DataObj) Open() error {
+	return obj.con.intercept(&Event{Op: OpOpen, Path: obj.path}, obj.open)
+}
+
+func (obj *DataObj) open() error {
 	var errMsg *C.char
 
 	path := C.CString(obj.path)
@@ -476,20 +558,31 @@ func (obj *DataObj) Open() error {
 	defer C.free(unsafe.Pointer(resourceName))
 	defer C.free(unsafe.Pointer(replNum))
 
-	ccon := obj.con.GetCcon()
-	defer obj.con.ReturnCcon(ccon)
+	// The handle is opened on the resource server the provider redirects reads to
+	hcon := obj.con.transferCon(obj.path, obj.resource.Name(), false)
+
+	ccon := hcon.GetCcon()
+	defer hcon.ReturnCcon(ccon)
 
-	if status := C.gorods_open_dataobject(path, resourceName, replNum, C.O_RDONLY, &obj.chandle, ccon, &errMsg); status != 0 {
+	if status := C.gorods_open_dataobject(path, resourceName, replNum, O_RDONLY, &obj.chandle, ccon, &errMsg); status != 0 {
 		return newError(Fatal, status, fmt.Sprintf("iRODS Open DataObject Failed: %v, %v", obj.path, C.GoString(errMsg)))
 	}
 
-	obj.openedAs = C.O_RDONLY
+	obj.hcon = hcon
+	obj.openedAs = O_RDONLY
+	obj.openedGen = hcon.Reconnects()
+
+	obj.con.watchLeak(obj, "DataObj", obj.path)
 
 	return nil
 }
 
 // OpenRW opens a connection to iRODS and sets the data object handle for read/write access
 func (obj *DataObj) OpenRW() error {
+	return obj.con.intercept(&Event{Op: OpOpen, Path: obj.path}, obj.openRW)
+}
+
+func (obj *DataObj) openRW() error {
 	var errMsg *C.char
 
 	path := C.CString(obj.path)
@@ -499,32 +592,105 @@ func (obj *DataObj) OpenRW() error {
 	defer C.free(unsafe.Pointer(resourceName))
 	defer C.free(unsafe.Pointer(replNum))
 
-	ccon := obj.con.GetCcon()
-	defer obj.con.ReturnCcon(ccon)
+	hcon := obj.con.transferCon(obj.path, obj.resource.Name(), true)
+
+	ccon := hcon.GetCcon()
+	defer hcon.ReturnCcon(ccon)
 
-	if status := C.gorods_open_dataobject(path, resourceName, replNum, C.O_RDWR, &obj.chandle, ccon, &errMsg); status != 0 {
+	if status := C.gorods_open_dataobject(path, resourceName, replNum, O_RDWR, &obj.chandle, ccon, &errMsg); status != 0 {
 		return newError(Fatal, status, fmt.Sprintf("iRODS OpenRW DataObject Failed: %v, %v", obj.path, C.GoString(errMsg)))
 	}
 
-	obj.openedAs = C.O_RDWR
+	obj.hcon = hcon
+	obj.openedAs = O_RDWR
+	obj.openedGen = hcon.Reconnects()
+
+	obj.con.watchLeak(obj, "DataObj", obj.path)
 
 	return nil
 }
 
-// Close closes the data object, resets handler
+// OpenFlags opens the data object with the flags specified (O_RDONLY, O_WRONLY, O_RDWR, O_APPEND, O_TRUNC), closing the current handle first.
+// With O_APPEND every write is made at the end of the data object. With O_TRUNC the data object is emptied when opened.
+func (obj *DataObj) OpenFlags(flags int) error {
+	if er := obj.Close(); er != nil {
+		return er
+	}
+
+	return obj.con.intercept(&Event{Op: OpOpen, Path: obj.path}, func() error {
+		return obj.openFlags(flags)
+	})
+}
+
+func (obj *DataObj) openFlags(flags int) error {
+	var errMsg *C.char
+
+	access := flags & (O_RDONLY | O_WRONLY | O_RDWR)
+
+	if flags&(O_APPEND|O_TRUNC) != 0 && access == O_RDONLY {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS OpenFlags DataObject Failed: %v, O_APPEND and O_TRUNC require O_WRONLY or O_RDWR", obj.path))
+	}
+
+	path := C.CString(obj.path)
+	resourceName := C.CString(obj.resource.Name())
+	replNum := C.CString(strconv.Itoa(obj.replNum))
+	defer C.free(unsafe.Pointer(path))
+	defer C.free(unsafe.Pointer(resourceName))
+	defer C.free(unsafe.Pointer(replNum))
+
+	hcon := obj.con.transferCon(obj.path, obj.resource.Name(), access != O_RDONLY)
+
+	ccon := hcon.GetCcon()
+
+	// O_APPEND isn't honored by the server, it's emulated by seeking to the end before each write
+	if status := C.gorods_open_dataobject(path, resourceName, replNum, C.int(flags&^O_APPEND), &obj.chandle, ccon, &errMsg); status != 0 {
+		hcon.ReturnCcon(ccon)
+		return newError(Fatal, status, fmt.Sprintf("iRODS OpenFlags DataObject Failed: %v, %v", obj.path, C.GoString(errMsg)))
+	}
+
+	hcon.ReturnCcon(ccon)
+
+	obj.hcon = hcon
+	obj.openedAs = C.int(access)
+	obj.openedGen = hcon.Reconnects()
+	obj.appending = flags&O_APPEND != 0
+	obj.offset = 0
+
+	if flags&O_TRUNC != 0 {
+		obj.size = 0
+	}
+
+	obj.con.watchLeak(obj, "DataObj", obj.path)
+
+	return nil
+}
+
+// Close closes the data object, resets handler. It is safe to call more than once.
 func (obj *DataObj) Close() error {
 	var errMsg *C.char
 
+	// A handle opened before the connection handle was replaced died with it
+	if obj.stale() {
+		obj.dropHandle()
+	}
+
 	if int(obj.chandle) > -1 {
 
-		ccon := obj.con.GetCcon()
-		defer obj.con.ReturnCcon(ccon)
+		hcon := obj.handleCon()
+		ccon := hcon.GetCcon()
+		defer hcon.ReturnCcon(ccon)
 
 		if status := C.gorods_close_dataobject(obj.chandle, ccon, &errMsg); status != 0 {
 			return newError(Fatal, status, fmt.Sprintf("iRODS Close DataObject Failed: %v, %v", obj.path, C.GoString(errMsg)))
 		}
 
+		if obj.openedAs != O_RDONLY {
+			obj.con.InvalidateCache(obj.path)
+		}
+
 		obj.chandle = C.int(-1)
+		obj.appending = false
+		obj.con.unwatchLeak(obj)
 	}
 
 	return nil
@@ -532,6 +698,9 @@ func (obj *DataObj) Close() error {
 
 // Read reads the entire data object into memory and returns a []byte slice. Don't use this for large files.
 func (obj *DataObj) Read() ([]byte, error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
 	if er := obj.init(); er != nil {
 		return nil, er
 	}
@@ -542,24 +711,27 @@ func (obj *DataObj) Read() ([]byte, error) {
 		bytesRead C.int
 	)
 
-	if er := obj.LSeek(0); er != nil {
+	if er := obj.lseek(0); er != nil {
 		return nil, er
 	}
 
-	ccon := obj.con.GetCcon()
+	hcon := obj.handleCon()
+	ccon := hcon.GetCcon()
 
 	if status := C.gorods_read_dataobject(obj.chandle, C.rodsLong_t(obj.size), &buffer, &bytesRead, ccon, &err); status != 0 {
-		obj.con.ReturnCcon(ccon)
+		hcon.ReturnCcon(ccon)
 		return nil, newError(Fatal, status, fmt.Sprintf("iRODS Read DataObject Failed: %v, %v", obj.path, C.GoString(err)))
 	}
 
-	obj.con.ReturnCcon(ccon)
+	hcon.ReturnCcon(ccon)
 
 	buf := unsafe.Pointer(buffer.buf)
 	defer C.free(buf)
 
 	data := C.GoBytes(buf, bytesRead)
 
+	obj.recordBytes(BytesRead, int64(bytesRead))
+
 	return data, obj.Close()
 }
 
@@ -573,7 +745,11 @@ func (br ByteArr) Free() {
 }
 
 // ReadChunkFree is similar to ReadChunk, except it doesn't copy bytes into a new byte slice, making the process more efficient. It uses the existing C byte array and casts it as a go []byte. You must explicitally call ByteArr.Free on the returned struct or there will be a memory leak.
+// The data object is locked for the whole transfer, so callback must not call other methods of obj.
 func (obj *DataObj) ReadChunkFree(size int64, callback func(*ByteArr)) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
 	if er := obj.init(); er != nil {
 		return er
 	}
@@ -584,37 +760,40 @@ func (obj *DataObj) ReadChunkFree(size int64, callback func(*ByteArr)) error {
 		bytesRead C.int
 	)
 
-	if er := obj.LSeek(0); er != nil {
+	if er := obj.lseek(0); er != nil {
 		return er
 	}
 
 	for obj.offset < obj.size {
 
-		ccon := obj.con.GetCcon()
+		hcon := obj.handleCon()
+		ccon := hcon.GetCcon()
 
 		if status := C.gorods_read_dataobject(obj.chandle, C.rodsLong_t(size), &buffer, &bytesRead, ccon, &err); status != 0 {
-			obj.con.ReturnCcon(ccon)
+			hcon.ReturnCcon(ccon)
 			return newError(Fatal, status, fmt.Sprintf("iRODS Read DataObject Failed: %v, %v", obj.path, C.GoString(err)))
 		}
 
-		obj.con.ReturnCcon(ccon)
+		hcon.ReturnCcon(ccon)
 
 		buf := unsafe.Pointer(buffer.buf)
 
 		bufLen := int(buffer.len)
 		data := (*[1 << 30]byte)(unsafe.Pointer(buf))[:bufLen:bufLen]
 
+		obj.recordBytes(BytesRead, int64(bufLen))
+
 		callback(&ByteArr{
 			Contents: data,
 			Ptr:      buf,
 		})
 
-		if er := obj.LSeek(obj.offset + size); er != nil {
+		if er := obj.lseek(obj.offset + size); er != nil {
 			return er
 		}
 	}
 
-	if er := obj.LSeek(0); er != nil {
+	if er := obj.lseek(0); er != nil {
 		return er
 	}
 
@@ -623,6 +802,20 @@ func (obj *DataObj) ReadChunkFree(size int64, callback func(*ByteArr)) error {
 
 // FastReadFree is similar to ReadBytes, except it doesn't copy bytes into a new byte slice, making the process more efficient. It uses the existing C byte array and casts it as a go []byte. You must explicitally call ByteArr.Free on the returned struct or there will be a memory leak.
 func (obj *DataObj) FastReadFree(pos int64, length int) (*ByteArr, error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	var arr *ByteArr
+
+	err := obj.retryRead(func() (er error) {
+		arr, er = obj.fastReadFree(pos, length)
+		return er
+	})
+
+	return arr, err
+}
+
+func (obj *DataObj) fastReadFree(pos int64, length int) (*ByteArr, error) {
 	if er := obj.init(); er != nil {
 		return nil, er
 	}
@@ -633,12 +826,13 @@ func (obj *DataObj) FastReadFree(pos int64, length int) (*ByteArr, error) {
 		bytesRead C.int
 	)
 
-	if er := obj.LSeek(pos); er != nil {
+	if er := obj.lseek(pos); er != nil {
 		return nil, er
 	}
 
-	ccon := obj.con.GetCcon()
-	defer obj.con.ReturnCcon(ccon)
+	hcon := obj.handleCon()
+	ccon := hcon.GetCcon()
+	defer hcon.ReturnCcon(ccon)
 
 	if status := C.gorods_read_dataobject(obj.chandle, C.rodsLong_t(length), &buffer, &bytesRead, ccon, &err); status != 0 {
 		return nil, newError(Fatal, status, fmt.Sprintf("iRODS ReadBytes DataObject Failed: %v, %v", obj.path, C.GoString(err)))
@@ -649,6 +843,8 @@ func (obj *DataObj) FastReadFree(pos int64, length int) (*ByteArr, error) {
 	bufLen := int(buffer.len)
 	data := (*[1 << 30]byte)(unsafe.Pointer(buf))[:bufLen:bufLen]
 
+	obj.recordBytes(BytesRead, int64(bufLen))
+
 	return &ByteArr{
 		Contents: data,
 		Ptr:      buf,
@@ -658,6 +854,9 @@ func (obj *DataObj) FastReadFree(pos int64, length int) (*ByteArr, error) {
 // FastRead is similar to ReadBytes, except it doesn't copy bytes into a new byte slice, making the process more efficient. It uses the existing C byte array and casts it as a go []byte. Once your call back is run, the allocated memory is freed automatically.
 // This function will block until bytes are received and your callback has been run.
 func (obj *DataObj) FastRead(pos int64, length int, callback func([]byte) error) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
 	if er := obj.init(); er != nil {
 		return er
 	}
@@ -668,12 +867,13 @@ func (obj *DataObj) FastRead(pos int64, length int, callback func([]byte) error)
 		bytesRead C.int
 	)
 
-	if er := obj.LSeek(pos); er != nil {
+	if er := obj.lseek(pos); er != nil {
 		return er
 	}
 
-	ccon := obj.con.GetCcon()
-	defer obj.con.ReturnCcon(ccon)
+	hcon := obj.handleCon()
+	ccon := hcon.GetCcon()
+	defer hcon.ReturnCcon(ccon)
 
 	if status := C.gorods_read_dataobject(obj.chandle, C.rodsLong_t(length), &buffer, &bytesRead, ccon, &err); status != 0 {
 		return newError(Fatal, status, fmt.Sprintf("iRODS ReadBytes DataObject Failed: %v, %v", obj.path, C.GoString(err)))
@@ -686,11 +886,27 @@ func (obj *DataObj) FastRead(pos int64, length int, callback func([]byte) error)
 
 	data := (*[1 << 30]byte)(unsafe.Pointer(buf))[:bufLen:bufLen]
 
+	obj.recordBytes(BytesRead, int64(bufLen))
+
 	return callback(data)
 }
 
 // ReadBytes reads bytes from a data object at the specified position and length, returns []byte slice and error.
 func (obj *DataObj) ReadBytes(pos int64, length int) ([]byte, error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	var data []byte
+
+	err := obj.retryRead(func() (er error) {
+		data, er = obj.readBytes(pos, length)
+		return er
+	})
+
+	return data, err
+}
+
+func (obj *DataObj) readBytes(pos int64, length int) ([]byte, error) {
 	if er := obj.init(); er != nil {
 		return nil, er
 	}
@@ -701,12 +917,13 @@ func (obj *DataObj) ReadBytes(pos int64, length int) ([]byte, error) {
 		bytesRead C.int
 	)
 
-	if er := obj.LSeek(pos); er != nil {
+	if er := obj.lseek(pos); er != nil {
 		return nil, er
 	}
 
-	ccon := obj.con.GetCcon()
-	defer obj.con.ReturnCcon(ccon)
+	hcon := obj.handleCon()
+	ccon := hcon.GetCcon()
+	defer hcon.ReturnCcon(ccon)
 
 	if status := C.gorods_read_dataobject(obj.chandle, C.rodsLong_t(length), &buffer, &bytesRead, ccon, &err); status != 0 {
 		return nil, newError(Fatal, status, fmt.Sprintf("iRODS ReadBytes DataObject Failed: %v, %v", obj.path, C.GoString(err)))
@@ -717,11 +934,20 @@ func (obj *DataObj) ReadBytes(pos int64, length int) ([]byte, error) {
 
 	data := C.GoBytes(buf, bytesRead)
 
+	obj.recordBytes(BytesRead, int64(bytesRead))
+
 	return data, nil
 }
 
 // LSeek sets the read/write offset pointer of a data object, returns error
 func (obj *DataObj) LSeek(offset int64) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	return obj.lseek(offset)
+}
+
+func (obj *DataObj) lseek(offset int64) error {
 	if er := obj.init(); er != nil {
 		return er
 	}
@@ -730,8 +956,9 @@ func (obj *DataObj) LSeek(offset int64) error {
 		err *C.char
 	)
 
-	ccon := obj.con.GetCcon()
-	defer obj.con.ReturnCcon(ccon)
+	hcon := obj.handleCon()
+	ccon := hcon.GetCcon()
+	defer hcon.ReturnCcon(ccon)
 
 	if status := C.gorods_lseek_dataobject(obj.chandle, C.rodsLong_t(offset), ccon, &err); status != 0 {
 		return newError(Fatal, status, fmt.Sprintf("iRODS LSeek DataObject Failed: %v, %v", obj.path, C.GoString(err)))
@@ -743,7 +970,11 @@ func (obj *DataObj) LSeek(offset int64) error {
 }
 
 // ReadChunk reads the entire data object in chunks (size of chunk specified by size parameter), passing the data into a callback function for each chunk. Use this to read/write large files.
+// The data object is locked for the whole transfer, so callback must not call other methods of obj.
 func (obj *DataObj) ReadChunk(size int64, callback func([]byte)) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
 	if er := obj.init(); er != nil {
 		return er
 	}
@@ -754,20 +985,21 @@ func (obj *DataObj) ReadChunk(size int64, callback func([]byte)) error {
 		bytesRead C.int
 	)
 
-	if er := obj.LSeek(0); er != nil {
+	if er := obj.lseek(0); er != nil {
 		return er
 	}
 
 	for obj.offset < obj.size {
 
-		ccon := obj.con.GetCcon()
+		hcon := obj.handleCon()
+		ccon := hcon.GetCcon()
 
 		if status := C.gorods_read_dataobject(obj.chandle, C.rodsLong_t(size), &buffer, &bytesRead, ccon, &err); status != 0 {
-			obj.con.ReturnCcon(ccon)
+			hcon.ReturnCcon(ccon)
 			return newError(Fatal, status, fmt.Sprintf("iRODS Read DataObject Failed: %v, %v", obj.path, C.GoString(err)))
 		}
 
-		obj.con.ReturnCcon(ccon)
+		hcon.ReturnCcon(ccon)
 
 		buf := unsafe.Pointer(buffer.buf)
 
@@ -775,14 +1007,16 @@ func (obj *DataObj) ReadChunk(size int64, callback func([]byte)) error {
 
 		C.free(buf)
 
+		obj.recordBytes(BytesRead, int64(bytesRead))
+
 		callback(chunk)
 
-		if er := obj.LSeek(obj.offset + size); er != nil {
+		if er := obj.lseek(obj.offset + size); er != nil {
 			return er
 		}
 	}
 
-	if er := obj.LSeek(0); er != nil {
+	if er := obj.lseek(0); er != nil {
 		return er
 	}
 
@@ -790,17 +1024,42 @@ func (obj *DataObj) ReadChunk(size int64, callback func([]byte)) error {
 }
 
 // DownloadTo downloads and writes the entire data object to the provided path. Don't use this with large files unless you have RAM to spare, use ReadChunk() instead. Returns error.
+// On connections with ConnectionOptions.Transforms or DecodeTransforms set, data objects uploaded with Transforms are
+// streamed instead, and their original contents written.
 func (obj *DataObj) DownloadTo(localPath string) error {
 	if er := obj.init(); er != nil {
 		return er
 	}
 
+	if obj.con.decodesTransforms() {
+		if pipeline, er := obj.Transforms(); er != nil {
+			return er
+		} else if len(pipeline) > 0 {
+			return obj.downloadDecoded(localPath)
+		}
+	}
+
+	start := time.Now()
+
+	// The download comes straight from the resource server the provider redirects reads to, the handle is opened there
+	if obj.con.throttled(BytesRead) {
+		if err := obj.downloadThrottled(localPath); err != nil {
+			return err
+		}
+
+		obj.con.log(LogInfo, "downloaded", "path", obj.path, "size", obj.size, "duration", time.Since(start), "host", obj.handleCon().Options.Host)
+
+		return nil
+	}
+
 	if objContents, err := obj.Read(); err != nil {
 		return err
 	} else {
 		if er := ioutil.WriteFile(localPath, objContents, 0644); er != nil {
 			return newError(Fatal, -1, fmt.Sprintf("iRODS Download DataObject Failed: %v, %v", obj.path, er))
 		}
+
+		obj.con.log(LogInfo, "downloaded", "path", obj.path, "size", len(objContents), "duration", time.Since(start), "host", obj.handleCon().Options.Host)
 	}
 
 	return nil
@@ -808,16 +1067,27 @@ func (obj *DataObj) DownloadTo(localPath string) error {
 
 // Write writes the data to the data object, starting from the beginning. Returns error.
 func (obj *DataObj) Write(data []byte) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	if er := obj.con.checkUntransformedWrite(obj.path); er != nil {
+		return er
+	}
+
+	if er := obj.con.scanBytes(obj.path, data); er != nil {
+		return er
+	}
+
 	if er := obj.initRW(); er != nil {
 		return er
 	}
 
-	if !(obj.openedAs == C.O_RDWR || obj.openedAs == C.O_WRONLY) {
+	if !(obj.openedAs == O_RDWR || obj.openedAs == O_WRONLY) {
 		obj.Close()
 		obj.OpenRW()
 	}
 
-	if er := obj.LSeek(0); er != nil {
+	if er := obj.lseek(0); er != nil {
 		return er
 	}
 
@@ -827,14 +1097,17 @@ func (obj *DataObj) Write(data []byte) error {
 
 	var err *C.char
 
-	ccon := obj.con.GetCcon()
+	hcon := obj.handleCon()
+	ccon := hcon.GetCcon()
 
 	if status := C.gorods_write_dataobject(obj.chandle, dataPointer, C.int(size), ccon, &err); status != 0 {
-		obj.con.ReturnCcon(ccon)
+		hcon.ReturnCcon(ccon)
 		return newError(Fatal, status, fmt.Sprintf("iRODS Write DataObject Failed: %v, %v", obj.path, C.GoString(err)))
 	}
 
-	obj.con.ReturnCcon(ccon)
+	hcon.ReturnCcon(ccon)
+
+	obj.recordBytes(BytesWritten, size)
 
 	obj.size = size
 
@@ -843,11 +1116,33 @@ func (obj *DataObj) Write(data []byte) error {
 
 // WriteBytes writes to the data object wherever the object's offset pointer is currently set to. It advances the pointer to the end of the written data for supporting subsequent writes. Be sure to call obj.LSeek(0) before hand if you wish to write from the beginning. Returns error.
 func (obj *DataObj) WriteBytes(data []byte) error {
+	if er := obj.con.checkIncrementalWrite(obj.path); er != nil {
+		return er
+	}
+
+	return obj.writeNext(data)
+}
+
+// writeNext is WriteBytes without the scanner check, for content that was already scanned
+func (obj *DataObj) writeNext(data []byte) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	if obj.appending {
+		if er := obj.lseek(obj.size); er != nil {
+			return er
+		}
+	}
+
+	return obj.writeBytes(data)
+}
+
+func (obj *DataObj) writeBytes(data []byte) error {
 	if er := obj.initRW(); er != nil {
 		return er
 	}
 
-	if !(obj.openedAs == C.O_RDWR || obj.openedAs == C.O_WRONLY) {
+	if !(obj.openedAs == O_RDWR || obj.openedAs == O_WRONLY) {
 		obj.Close()
 		obj.OpenRW()
 	}
@@ -858,70 +1153,152 @@ func (obj *DataObj) WriteBytes(data []byte) error {
 
 	var err *C.char
 
-	ccon := obj.con.GetCcon()
+	hcon := obj.handleCon()
+	ccon := hcon.GetCcon()
 
 	if status := C.gorods_write_dataobject(obj.chandle, dataPointer, C.int(size), ccon, &err); status != 0 {
-		obj.con.ReturnCcon(ccon)
+		hcon.ReturnCcon(ccon)
 		return newError(Fatal, status, fmt.Sprintf("iRODS Write DataObject Failed: %v, %v", obj.path, C.GoString(err)))
 	}
 
-	obj.con.ReturnCcon(ccon)
+	hcon.ReturnCcon(ccon)
 
-	obj.size = size + obj.offset
+	obj.recordBytes(BytesWritten, size)
 
-	return obj.LSeek(obj.size)
+	end := obj.offset + size
+
+	if end > obj.size {
+		obj.size = end
+	}
+
+	return obj.lseek(end)
 }
 
-// Stat returns a map (key/value pairs) of the system meta information. The following keys can be used with the map:
-//
-// "objSize"
-//
-// "dataMode"
-//
-// "dataId"
-//
-// "chksum"
-//
-// "ownerName"
-//
-// "ownerZone"
-//
-// "createTime"
-//
-// "modifyTime"
-func (obj *DataObj) Stat() (map[string]interface{}, error) {
+// Seek sets the read/write offset pointer of the data object relative to whence (io.SeekStart, io.SeekCurrent or io.SeekEnd),
+// and returns the new offset. Implements io.Seeker.
+func (obj *DataObj) Seek(offset int64, whence int) (int64, error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
 
-	var (
-		err        *C.char
-		statResult *C.rodsObjStat_t
-	)
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += obj.offset
+	case io.SeekEnd:
+		offset += obj.size
+	default:
+		return obj.offset, newError(Fatal, -1, fmt.Sprintf("iRODS Seek DataObject Failed: %v, invalid whence %v", obj.path, whence))
+	}
 
-	path := C.CString(obj.path)
+	if offset < 0 {
+		return obj.offset, newError(Fatal, -1, fmt.Sprintf("iRODS Seek DataObject Failed: %v, negative offset %v", obj.path, offset))
+	}
 
-	defer C.free(unsafe.Pointer(path))
+	if er := obj.lseek(offset); er != nil {
+		return obj.offset, er
+	}
 
-	ccon := obj.con.GetCcon()
-	defer obj.con.ReturnCcon(ccon)
+	return obj.offset, nil
+}
+
+// ReadAt reads len(p) bytes starting at offset off, returning io.EOF if the end of the data object is reached first.
+// Implements io.ReaderAt. The offset pointer is left after the bytes read.
+func (obj *DataObj) ReadAt(p []byte, off int64) (int, error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
 
-	if status := C.gorods_stat_dataobject(path, &statResult, ccon, &err); status != 0 {
-		return nil, newError(Fatal, status, fmt.Sprintf("iRODS Close Stat Failed: %v, %v", obj.path, C.GoString(err)))
+	if len(p) == 0 {
+		return 0, nil
 	}
 
-	result := make(map[string]interface{})
+	if int(obj.chandle) > -1 && obj.openedAs == O_WRONLY {
+		if er := obj.Close(); er != nil {
+			return 0, er
+		}
+	}
+
+	var data []byte
 
-	result["objSize"] = int(statResult.objSize)
-	result["dataMode"] = int(statResult.dataMode)
+	err := obj.retryRead(func() (er error) {
+		data, er = obj.readBytes(off, len(p))
+		return er
+	})
+	if err != nil {
+		return 0, err
+	}
 
-	result["dataId"] = C.GoString(&statResult.dataId[0])
-	result["chksum"] = C.GoString(&statResult.chksum[0])
-	result["ownerName"] = C.GoString(&statResult.ownerName[0])
-	result["ownerZone"] = C.GoString(&statResult.ownerZone[0])
-	result["createTime"] = C.GoString(&statResult.createTime[0])
-	result["modifyTime"] = C.GoString(&statResult.modifyTime[0])
+	n := copy(p, data)
 
-	C.freeRodsObjStat(statResult)
+	obj.offset = off + int64(n)
 
-	return result, nil
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// WriteAt overwrites len(p) bytes of the data object starting at offset off, growing the data object if needed.
+// Implements io.WriterAt. Use it to update byte ranges of an existing data object in place.
+func (obj *DataObj) WriteAt(p []byte, off int64) (int, error) {
+	if er := obj.con.checkIncrementalWrite(obj.path); er != nil {
+		return 0, er
+	}
+
+	return obj.writeAt(p, off)
+}
+
+// writeAt is WriteAt without the scanner check, for content that was already scanned
+func (obj *DataObj) writeAt(p []byte, off int64) (int, error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if er := obj.initRW(); er != nil {
+		return 0, er
+	}
+
+	if !(obj.openedAs == O_RDWR || obj.openedAs == O_WRONLY) {
+		if er := obj.Close(); er != nil {
+			return 0, er
+		}
+
+		if er := obj.OpenRW(); er != nil {
+			return 0, er
+		}
+	}
+
+	if er := obj.lseek(off); er != nil {
+		return 0, er
+	}
+
+	if er := obj.writeBytes(p); er != nil {
+		return 0, er
+	}
+
+	return len(p), nil
+}
+
+// Stat returns a map (key/value pairs) of the system meta information. The following keys can be used with the map:
+//
+// "objSize", "dataMode", "dataId", "chksum", "ownerName", "ownerZone", "createTime", "modifyTime"
+//
+// Use ObjStat for the typed struct, which holds more of the system metadata.
+func (obj *DataObj) Stat() (map[string]interface{}, error) {
+	stat, err := obj.ObjStat()
+	if err != nil {
+		return nil, err
+	}
+
+	return stat.Map(), nil
+}
+
+// ObjStat returns the system meta information of the data object in an *ObjStat struct, without opening the data object
+func (obj *DataObj) ObjStat() (*ObjStat, error) {
+	return obj.con.ObjStat(obj.path)
 }
 
 // Attribute gets slice of Meta AVU triples, matching by Attribute name for DataObj
@@ -1000,22 +1377,38 @@ func (obj *DataObj) CopyTo(iRODSCollection interface{}) error {
 		return newError(Fatal, -1, fmt.Sprintf("iRODS Copy DataObject Failed, unknown variable type passed as collection"))
 	}
 
+	rescName, rErr := obj.con.targetResource(nil)
+	if rErr != nil {
+		return rErr
+	}
+
 	path := C.CString(obj.path)
 	dest := C.CString(destination)
-	resource := C.CString("")
+	resource := C.CString(rescName)
 
 	defer C.free(unsafe.Pointer(path))
 	defer C.free(unsafe.Pointer(dest))
 	defer C.free(unsafe.Pointer(resource))
 
-	ccon := obj.con.GetCcon()
+	params := map[string]string{"force": "false"}
+	if rescName != "" {
+		params["resource"] = rescName
+	}
 
-	if status := C.gorods_copy_dataobject(path, dest, C.int(0), resource, ccon, &err); status != 0 {
-		obj.con.ReturnCcon(ccon)
-		return newError(Fatal, status, fmt.Sprintf("iRODS Copy DataObject Failed: %v, %v", destination, C.GoString(err)))
+	if er := obj.con.intercept(&Event{Op: OpCopy, Path: obj.path, Dest: destination, Params: params}, func() error {
+		ccon := obj.con.GetCcon()
+		defer obj.con.ReturnCcon(ccon)
+
+		if status := C.gorods_copy_dataobject(path, dest, C.int(0), resource, ccon, &err); status != 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Copy DataObject Failed: %v, %v", destination, C.GoString(err)))
+		}
+
+		return nil
+	}); er != nil {
+		return er
 	}
 
-	obj.con.ReturnCcon(ccon)
+	obj.con.InvalidateCache(destination)
 
 	// Find & reload destination collection
 	switch iRODSCollection.(type) {
@@ -1082,16 +1475,13 @@ func (obj *DataObj) CopyToOpts(iRODSCollection interface{}, opts DataObjOptions)
 		force = 0
 	}
 
-	switch opts.Resource.(type) {
-	case string:
-		resource = C.CString(opts.Resource.(string))
-	case *Resource:
-		r := opts.Resource.(*Resource)
-		resource = C.CString(r.Name())
-	default:
-		newError(Fatal, -1, fmt.Sprintf("Wrong variable type passed in Resource field"))
+	rescName, rErr := obj.con.targetResource(opts.Resource)
+	if rErr != nil {
+		return rErr
 	}
 
+	resource = C.CString(rescName)
+
 	path := C.CString(obj.path)
 	dest := C.CString(destination)
 
@@ -1099,14 +1489,25 @@ func (obj *DataObj) CopyToOpts(iRODSCollection interface{}, opts DataObjOptions)
 	defer C.free(unsafe.Pointer(dest))
 	defer C.free(unsafe.Pointer(resource))
 
-	ccon := obj.con.GetCcon()
+	params := map[string]string{"force": strconv.FormatBool(opts.Force)}
+	if rescName != "" {
+		params["resource"] = rescName
+	}
 
-	if status := C.gorods_copy_dataobject(path, dest, C.int(force), resource, ccon, &err); status != 0 {
-		obj.con.ReturnCcon(ccon)
-		return newError(Fatal, status, fmt.Sprintf("iRODS Copy DataObject Failed: %v, %v", destination, C.GoString(err)))
+	if er := obj.con.intercept(&Event{Op: OpCopy, Path: obj.path, Dest: destination, Params: params}, func() error {
+		ccon := obj.con.GetCcon()
+		defer obj.con.ReturnCcon(ccon)
+
+		if status := C.gorods_copy_dataobject(path, dest, C.int(force), resource, ccon, &err); status != 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Copy DataObject Failed: %v, %v", destination, C.GoString(err)))
+		}
+
+		return nil
+	}); er != nil {
+		return er
 	}
 
-	obj.con.ReturnCcon(ccon)
+	obj.con.InvalidateCache(destination)
 
 	// Find & reload destination collection
 	switch iRODSCollection.(type) {
@@ -1171,14 +1572,20 @@ func (obj *DataObj) MoveTo(iRODSCollection interface{}) error {
 	defer C.free(unsafe.Pointer(path))
 	defer C.free(unsafe.Pointer(dest))
 
-	ccon := obj.con.GetCcon()
+	if er := obj.con.intercept(&Event{Op: OpMove, Path: obj.path, Dest: destination, Params: map[string]string{"type": "dataobj"}}, func() error {
+		ccon := obj.con.GetCcon()
+		defer obj.con.ReturnCcon(ccon)
 
-	if status := C.gorods_move_dataobject(path, dest, C.RENAME_DATA_OBJ, ccon, &err); status != 0 {
-		obj.con.ReturnCcon(ccon)
-		return newError(Fatal, status, fmt.Sprintf("iRODS Move DataObject Failed S:%v, D:%v, %v", obj.path, destination, C.GoString(err)))
+		if status := C.gorods_move_dataobject(path, dest, C.RENAME_DATA_OBJ, ccon, &err); status != 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Move DataObject Failed S:%v, D:%v, %v", obj.path, destination, C.GoString(err)))
+		}
+
+		return nil
+	}); er != nil {
+		return er
 	}
 
-	obj.con.ReturnCcon(ccon)
+	obj.con.InvalidateCache(obj.path, destination)
 
 	// Reload source collection, we are now detached
 	obj.col.Refresh()
@@ -1233,13 +1640,21 @@ func (obj *DataObj) Rename(newFileName string) error {
 	defer C.free(unsafe.Pointer(s))
 	defer C.free(unsafe.Pointer(d))
 
-	ccon := obj.con.GetCcon()
-	defer obj.con.ReturnCcon(ccon)
+	if er := obj.con.intercept(&Event{Op: OpMove, Path: source, Dest: destination, Params: map[string]string{"type": "dataobj"}}, func() error {
+		ccon := obj.con.GetCcon()
+		defer obj.con.ReturnCcon(ccon)
+
+		if status := C.gorods_move_dataobject(s, d, C.RENAME_DATA_OBJ, ccon, &err); status != 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Rename DataObject Failed: %v, %v", obj.path, C.GoString(err)))
+		}
 
-	if status := C.gorods_move_dataobject(s, d, C.RENAME_DATA_OBJ, ccon, &err); status != 0 {
-		return newError(Fatal, status, fmt.Sprintf("iRODS Rename DataObject Failed: %v, %v", obj.path, C.GoString(err)))
+		return nil
+	}); er != nil {
+		return er
 	}
 
+	obj.con.InvalidateCache(source, destination)
+
 	obj.name = newFileName
 	obj.path = destination
 
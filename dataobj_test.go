@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, The BioTeam, Inc.                     ***
  *** For more information please refer to the LICENSE.md file  ***/
 
@@ -5,6 +8,7 @@ package gorods
 
 import "testing"
 import "strings"
+import "io"
 
 //import "fmt"
 
@@ -48,6 +52,15 @@ func TestDataObjCreateDeleteWrite(t *testing.T) {
 			t.Fatal(statErr)
 		}
 
+		stat, objStatErr := do.ObjStat()
+		if objStatErr != nil {
+			t.Fatal(objStatErr)
+		}
+
+		if stat.Size != 14 || stat.Type != DataObjType || stat.ReplicaCount < 1 {
+			t.Errorf("Expected data object stat with size 14 and at least one replica, got %+v", stat)
+		}
+
 		if chErr := do.Chmod("developers", Write, false); chErr != nil {
 			t.Fatal(chErr)
 		}
@@ -162,3 +175,174 @@ func TestDataObjReadBytes(t *testing.T) {
 	}
 
 }
+
+func TestDataObjWriteAtAppend(t *testing.T) {
+	client, conErr := New(ConnectionOptions{
+		Type: UserDefined,
+
+		Host: "localhost",
+		Port: 1247,
+		Zone: "tempZone",
+
+		Username: "rods",
+		Password: "password",
+	})
+
+	// Ensure the client initialized successfully and connected to the iCAT server
+	if conErr != nil {
+		t.Fatal(conErr)
+	}
+
+	if openErr := client.OpenCollection(CollectionOptions{
+		Path: "/tempZone/home/rods",
+	}, func(col *Collection, con *Connection) {
+
+		do, createErr := col.CreateDataObj(DataObjOptions{
+			Name:  "test-writeat.txt",
+			Force: true,
+		})
+
+		if createErr != nil {
+			t.Fatal(createErr)
+		}
+
+		if wrErr := do.Write([]byte("hello world")); wrErr != nil {
+			t.Fatal(wrErr)
+		}
+
+		if _, wrErr := do.WriteAt([]byte("WORLD"), 6); wrErr != nil {
+			t.Fatal(wrErr)
+		}
+
+		if flagErr := do.OpenFlags(O_WRONLY | O_APPEND); flagErr != nil {
+			t.Fatal(flagErr)
+		}
+
+		if wrErr := do.WriteBytes([]byte("!")); wrErr != nil {
+			t.Fatal(wrErr)
+		}
+
+		buf := make([]byte, 6)
+
+		if n, readErr := do.ReadAt(buf, 6); readErr != nil || n != 6 {
+			t.Fatalf("ReadAt returned %v bytes, %v", n, readErr)
+		}
+
+		if string(buf) != "WORLD!" {
+			t.Errorf("Expected string 'WORLD!', got '%s'", string(buf))
+		}
+
+		if pos, seekErr := do.Seek(-1, io.SeekEnd); seekErr != nil || pos != 11 {
+			t.Errorf("Expected Seek to return 11, got %v, %v", pos, seekErr)
+		}
+
+		if delErr := do.Delete(false); delErr != nil {
+			t.Fatal(delErr)
+		}
+
+	}); openErr != nil {
+		t.Fatal(openErr)
+	}
+
+}
+
+func TestDataObjReopen(t *testing.T) {
+	client, conErr := New(ConnectionOptions{
+		Type: UserDefined,
+
+		Host: "localhost",
+		Port: 1247,
+		Zone: "tempZone",
+
+		Username: "rods",
+		Password: "password",
+
+		ReopenHandles: true,
+	})
+
+	if conErr != nil {
+		t.Fatal(conErr)
+	}
+
+	if openErr := client.OpenDataObject("/tempZone/home/rods/hello.txt", func(myFile *DataObj, con *Connection) {
+		if _, readErr := myFile.ReadBytes(0, 5); readErr != nil {
+			t.Fatal(readErr)
+		}
+
+		// Replace the connection handle, as the idle check does when the server dropped it
+		ccon := con.GetCcon()
+		con.ReturnCcon(con.redial(ccon))
+
+		if !myFile.stale() {
+			t.Fatal("Expected the handle to be stale after reconnecting")
+		}
+
+		if contents, readErr := myFile.ReadBytes(7, 6); readErr != nil {
+			t.Fatal(readErr)
+		} else if string(contents) != "World!" {
+			t.Errorf("Expected string 'World!' after re-opening, got '%s'", contents)
+		}
+
+		if myFile.stale() || con.Reconnects() != 1 {
+			t.Errorf("Expected a re-opened handle after 1 reconnect, got %v", con.Reconnects())
+		}
+	}); openErr != nil {
+		t.Fatal(openErr)
+	}
+}
+
+func TestDataObjTruncate(t *testing.T) {
+	client, conErr := New(ConnectionOptions{
+		Type: UserDefined,
+
+		Host: "localhost",
+		Port: 1247,
+		Zone: "tempZone",
+
+		Username: "rods",
+		Password: "password",
+	})
+
+	if conErr != nil {
+		t.Fatal(conErr)
+	}
+
+	if openErr := client.OpenCollection(CollectionOptions{
+		Path: "/tempZone/home/rods",
+	}, func(col *Collection, con *Connection) {
+
+		do, createErr := col.CreateDataObj(DataObjOptions{
+			Name:  "test-truncate.txt",
+			Force: true,
+		})
+
+		if createErr != nil {
+			t.Fatal(createErr)
+		}
+
+		if wrErr := do.Write([]byte("hello world")); wrErr != nil {
+			t.Fatal(wrErr)
+		}
+
+		if closeErr := do.Close(); closeErr != nil {
+			t.Fatal(closeErr)
+		}
+
+		if truncErr := do.Truncate(5); truncErr != nil {
+			t.Fatal(truncErr)
+		}
+
+		if contents, readErr := do.ReadBytes(0, 11); readErr != nil {
+			t.Fatal(readErr)
+		} else if string(contents) != "hello" || do.Size() != 5 {
+			t.Errorf("Expected string 'hello' of 5 bytes, got '%s' of %v bytes", contents, do.Size())
+		}
+
+		if delErr := do.Delete(false); delErr != nil {
+			t.Fatal(delErr)
+		}
+
+	}); openErr != nil {
+		t.Fatal(openErr)
+	}
+}
@@ -0,0 +1,242 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// DedupMatch is an existing replica with the same size and checksum as a local file
+type DedupMatch struct {
+	Path     string
+	RescHier string
+	Checksum string
+}
+
+// FindDuplicates returns the good replicas in the collection tree at scope with the same size and checksum (md5 or
+// sha2) as the local file. If resource is set (string or *Resource), only replicas whose hierarchy starts at that
+// resource are returned. Pass the zone ("/tempZone") as scope to search the whole catalog.
+func (con *Connection) FindDuplicates(localPath string, resource interface{}, scope string) ([]DedupMatch, error) {
+	resc, err := resourceName(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	info, er := os.Stat(localPath)
+	if er != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Find Duplicates Failed: %v", er))
+	}
+
+	sums, err := localIRODSChecksums(localPath, ChecksumMD5, ChecksumSHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	return con.findDuplicates(sums, info.Size(), resc, strings.TrimRight(scope, "/"))
+}
+
+// findDuplicates returns the good replicas below scope with one of the checksums and the size, in resc if set
+func (con *Connection) findDuplicates(sums []string, size int64, resc string, scope string) ([]DedupMatch, error) {
+	zone, err := con.zoneHint(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	scopeLit, err := queryLiteral(scope + "%")
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]DedupMatch, 0)
+
+	for _, sum := range sums {
+		query := fmt.Sprintf("select COLL_NAME, DATA_NAME, DATA_RESC_HIER where COLL_NAME like %v and DATA_CHECKSUM = '%v' and DATA_SIZE = '%v' and DATA_REPL_STATUS = '1'", scopeLit, sum, size)
+
+		result, err := con.IQuestZone(query, false, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range result {
+			hier := row["DATA_RESC_HIER"]
+
+			if !inTree(scope, row["COLL_NAME"]) {
+				continue
+			}
+
+			if resc != "" && !inResource(hier, resc) {
+				continue
+			}
+
+			matches = append(matches, DedupMatch{
+				Path:     row["COLL_NAME"] + "/" + row["DATA_NAME"],
+				RescHier: hier,
+				Checksum: sum,
+			})
+		}
+	}
+
+	return matches, nil
+}
+
+// inResource returns true if the resource hierarchy hier starts at resc
+func inResource(hier string, resc string) bool {
+	return hier == resc || strings.HasPrefix(hier, resc+";")
+}
+
+// localIRODSChecksums computes the iRODS formatted checksums of a local file for several algorithms in a single read
+func localIRODSChecksums(localPath string, algorithms ...string) ([]string, error) {
+	hashes := make([]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, len(algorithms))
+
+	for i, alg := range algorithms {
+		h, err := Checksummer(alg)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes[i] = h
+		writers[i] = h
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Checksum Failed: %v", err))
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Checksum Failed: %v", err))
+	}
+
+	sums := make([]string, len(algorithms))
+	for i, alg := range algorithms {
+		sums[i] = formatIRODSChecksum(alg, hashes[i].Sum(nil))
+	}
+
+	return sums, nil
+}
+
+// putDedup copies an existing identical replica server-side to the destination of a Put with DataObjOptions.Dedup set,
+// looking for one below DataObjOptions.DedupScope, the collection by default. Returns true without copying if the
+// destination already has the same contents. Returns false if no duplicate was found, or the copy failed, in which
+// case the file should be uploaded normally.
+func (col *Collection) putDedup(localPath string, opts DataObjOptions) (bool, error) {
+	resc, err := resourceName(opts.Resource)
+	if err != nil {
+		return false, err
+	}
+
+	info, er := os.Stat(localPath)
+	if er != nil {
+		return false, newError(Fatal, -1, fmt.Sprintf("Find Duplicates Failed: %v", er))
+	}
+
+	sums, err := localIRODSChecksums(localPath, ChecksumMD5, ChecksumSHA256)
+	if err != nil {
+		return false, err
+	}
+
+	destination := col.path + "/" + opts.Name
+
+	if same, err := col.con.hasReplica(destination, sums, info.Size(), resc); err != nil || same {
+		return same, err
+	}
+
+	scope := opts.DedupScope
+	if scope == "" {
+		scope = col.path
+	}
+
+	matches, err := col.con.findDuplicates(sums, info.Size(), resc, strings.TrimRight(scope, "/"))
+	if err != nil {
+		return false, err
+	}
+
+	force := 0
+	if opts.Force {
+		force = 1
+	}
+
+	dest := C.CString(destination)
+	resource := C.CString(resc)
+
+	defer C.free(unsafe.Pointer(dest))
+	defer C.free(unsafe.Pointer(resource))
+
+	for _, match := range matches {
+		if match.Path == destination {
+			continue
+		}
+
+		var errMsg *C.char
+
+		source := C.CString(match.Path)
+
+		ccon := col.con.GetCcon()
+		status := C.gorods_copy_dataobject(source, dest, C.int(force), resource, ccon, &errMsg)
+		col.con.ReturnCcon(ccon)
+
+		C.free(unsafe.Pointer(source))
+
+		if status == 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// hasReplica returns true if the data object at p has a good replica (in resc, if set) with one of the checksums and
+// the size. Replicas without a checksum never match.
+func (con *Connection) hasReplica(p string, sums []string, size int64, resc string) (bool, error) {
+	collLit, err := queryLiteral(path.Dir(p))
+	if err != nil {
+		return false, err
+	}
+
+	nameLit, err := queryLiteral(path.Base(p))
+	if err != nil {
+		return false, err
+	}
+
+	zone, err := con.zoneHint(p)
+	if err != nil {
+		return false, err
+	}
+
+	query := fmt.Sprintf("select DATA_CHECKSUM, DATA_SIZE, DATA_RESC_HIER where COLL_NAME = %v and DATA_NAME = %v and DATA_REPL_STATUS = '1'", collLit, nameLit)
+
+	result, err := con.IQuestZone(query, false, zone)
+	if err != nil {
+		return false, err
+	}
+
+	for _, row := range result {
+		if row["DATA_SIZE"] != strconv.FormatInt(size, 10) || (resc != "" && !inResource(row["DATA_RESC_HIER"], resc)) {
+			continue
+		}
+
+		for _, sum := range sums {
+			if row["DATA_CHECKSUM"] == sum {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
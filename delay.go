@@ -0,0 +1,209 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// Delayed rule fields that can be changed with DelayedRule.Modify (iqmod)
+const (
+	RuleFieldName             = "ruleName"
+	RuleFieldReiFilePath      = "reiFilePath"
+	RuleFieldUserName         = "userName"
+	RuleFieldExecAddress      = "exeAddress"
+	RuleFieldExecTime         = "exeTime"
+	RuleFieldFrequency        = "exeFrequency"
+	RuleFieldPriority         = "priority"
+	RuleFieldEstimateExecTime = "estimateExeTime"
+	RuleFieldNotificationAddr = "notificationAddr"
+	RuleFieldLastExecTime     = "lastExeTime"
+	RuleFieldStatus           = "exeStatus"
+)
+
+// DelayedRule is an entry of the delayed rule queue (iqstat). Frequency is empty for rules that run once.
+type DelayedRule struct {
+	Id               string
+	Name             string
+	UserName         string
+	ExecAddress      string
+	ExecTime         time.Time
+	LastExecTime     time.Time
+	Frequency        string
+	Priority         string
+	Status           string
+	NotificationAddr string
+	ReiFilePath      string
+
+	con *Connection
+}
+
+const delayedRuleQuery = "select RULE_EXEC_ID, RULE_EXEC_NAME, RULE_EXEC_USER_NAME, RULE_EXEC_ADDRESS, RULE_EXEC_TIME, RULE_EXEC_LAST_EXE_TIME, RULE_EXEC_FREQUENCY, RULE_EXEC_PRIORITY, RULE_EXEC_STATUS, RULE_EXEC_NOTIFICATION_ADDR, RULE_EXEC_REI_FILE_PATH"
+
+// DelayedRules returns every rule in the delay queue that the user can see. rodsadmin users see the rules of all users.
+func (con *Connection) DelayedRules() ([]*DelayedRule, error) {
+	return con.delayedRules("")
+}
+
+// UserDelayedRules returns the rules in the delay queue submitted by the user specified
+func (con *Connection) UserDelayedRules(user string) ([]*DelayedRule, error) {
+	return con.delayedRules(fmt.Sprintf(" where RULE_EXEC_USER_NAME = '%v'", user))
+}
+
+// DelayedRule returns the rule in the delay queue with the id specified
+func (con *Connection) DelayedRule(id string) (*DelayedRule, error) {
+	rules, err := con.delayedRules(fmt.Sprintf(" where RULE_EXEC_ID = '%v'", id))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rules) == 0 {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS DelayedRule Failed: rule %v not found in the delay queue", id))
+	}
+
+	return rules[0], nil
+}
+
+// FailedDelayedRules returns the rules in the delay queue whose last run failed
+func (con *Connection) FailedDelayedRules() ([]*DelayedRule, error) {
+	rules, err := con.DelayedRules()
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]*DelayedRule, 0)
+
+	for _, r := range rules {
+		if r.Failed() {
+			response = append(response, r)
+		}
+	}
+
+	return response, nil
+}
+
+func (con *Connection) delayedRules(filter string) ([]*DelayedRule, error) {
+	result, err := con.IQuest(delayedRuleQuery+filter, false)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]*DelayedRule, 0, len(result))
+
+	for _, row := range result {
+		response = append(response, delayedRuleFromRow(row, con))
+	}
+
+	return response, nil
+}
+
+func delayedRuleFromRow(row map[string]string, con *Connection) *DelayedRule {
+	return &DelayedRule{
+		Id:               row["RULE_EXEC_ID"],
+		Name:             row["RULE_EXEC_NAME"],
+		UserName:         row["RULE_EXEC_USER_NAME"],
+		ExecAddress:      row["RULE_EXEC_ADDRESS"],
+		ExecTime:         timeStringToTime(row["RULE_EXEC_TIME"]),
+		LastExecTime:     timeStringToTime(row["RULE_EXEC_LAST_EXE_TIME"]),
+		Frequency:        row["RULE_EXEC_FREQUENCY"],
+		Priority:         row["RULE_EXEC_PRIORITY"],
+		Status:           row["RULE_EXEC_STATUS"],
+		NotificationAddr: row["RULE_EXEC_NOTIFICATION_ADDR"],
+		ReiFilePath:      row["RULE_EXEC_REI_FILE_PATH"],
+		con:              con,
+	}
+}
+
+// Running returns true if the rule is being executed by the delay server
+func (r *DelayedRule) Running() bool {
+	return r.Status == "RE_RUNNING"
+}
+
+// Failed returns true if the last run of the rule failed and it is waiting to be retried
+func (r *DelayedRule) Failed() bool {
+	return strings.Contains(r.Status, "FAIL")
+}
+
+// String returns the id, name and next execution time of the rule
+func (r *DelayedRule) String() string {
+	return fmt.Sprintf("%v: %v @ %v %v", r.Id, r.Name, r.ExecTime.Format(time.RFC3339), r.Status)
+}
+
+// Delete removes the rule from the delay queue (iqdel)
+func (r *DelayedRule) Delete() error {
+	var err *C.char
+
+	cId := C.CString(r.Id)
+	defer C.free(unsafe.Pointer(cId))
+
+	ccon := r.con.GetCcon()
+	defer r.con.ReturnCcon(ccon)
+
+	if status := C.gorods_rule_exec_del(cId, ccon, &err); status < 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS Delete Delayed Rule Failed: %v, %v", r.Id, C.GoString(err)))
+	}
+
+	return nil
+}
+
+// Modify changes a field of the rule (iqmod), field is one of the RuleField constants. value can't be empty.
+// The times used by RuleFieldExecTime and RuleFieldLastExecTime can be formatted with RuleTime.
+func (r *DelayedRule) Modify(field string, value string) error {
+	if value == "" {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Modify Delayed Rule Failed: %v %v, empty value", r.Id, field))
+	}
+
+	var err *C.char
+
+	cId := C.CString(r.Id)
+	cField := C.CString(field)
+	cValue := C.CString(value)
+	defer C.free(unsafe.Pointer(cId))
+	defer C.free(unsafe.Pointer(cField))
+	defer C.free(unsafe.Pointer(cValue))
+
+	ccon := r.con.GetCcon()
+	defer r.con.ReturnCcon(ccon)
+
+	if status := C.gorods_rule_exec_mod(cId, cField, cValue, ccon, &err); status < 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS Modify Delayed Rule Failed: %v %v, %v", r.Id, field, C.GoString(err)))
+	}
+
+	return nil
+}
+
+// Reschedule sets the next execution time of the rule
+func (r *DelayedRule) Reschedule(at time.Time) error {
+	if err := r.Modify(RuleFieldExecTime, RuleTime(at)); err != nil {
+		return err
+	}
+
+	r.ExecTime = time.Unix(at.Unix(), 0)
+
+	return nil
+}
+
+// Requeue schedules a rule that isn't running to run again as soon as possible. The catalog doesn't accept an
+// empty exeStatus, so the status of a failed rule is kept until the delay server runs it again.
+func (r *DelayedRule) Requeue() error {
+	if r.Running() {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Requeue Delayed Rule Failed: %v is running", r.Id))
+	}
+
+	return r.Reschedule(time.Now())
+}
+
+// RuleTime formats t the way iRODS stores times in the catalog
+func RuleTime(t time.Time) string {
+	return fmt.Sprintf("%011d", t.Unix())
+}
@@ -0,0 +1,54 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelayedRuleFromRow(t *testing.T) {
+	r := delayedRuleFromRow(map[string]string{
+		"RULE_EXEC_ID":            "10042",
+		"RULE_EXEC_NAME":          "myRule",
+		"RULE_EXEC_USER_NAME":     "rods",
+		"RULE_EXEC_TIME":          "01462363200",
+		"RULE_EXEC_LAST_EXE_TIME": "01462359600",
+		"RULE_EXEC_FREQUENCY":     "1h",
+		"RULE_EXEC_STATUS":        "RE_FAILED",
+	}, nil)
+
+	if r.Id != "10042" || r.Name != "myRule" || r.UserName != "rods" || r.Frequency != "1h" {
+		t.Errorf("Unexpected rule %+v", r)
+	}
+
+	if !r.ExecTime.Equal(time.Date(2016, 5, 4, 12, 0, 0, 0, time.UTC)) || !r.LastExecTime.Equal(time.Date(2016, 5, 4, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected times %v, %v", r.ExecTime, r.LastExecTime)
+	}
+
+	if !r.Failed() || r.Running() {
+		t.Errorf("Expected a failed rule, got status %v", r.Status)
+	}
+
+	if r := delayedRuleFromRow(map[string]string{"RULE_EXEC_STATUS": "RE_RUNNING"}, nil); !r.Running() || r.Failed() {
+		t.Errorf("Expected a running rule, got status %v", r.Status)
+	}
+
+	if err := r.Modify(RuleFieldStatus, ""); err == nil {
+		t.Error("Expected an error setting an empty value")
+	}
+}
+
+func TestRuleTime(t *testing.T) {
+	if s := RuleTime(time.Date(2016, 5, 4, 12, 0, 0, 0, time.UTC)); s != "01462363200" {
+		t.Errorf("Unexpected rule time %v", s)
+	}
+
+	if ts := timeStringToTime(RuleTime(time.Unix(1462363200, 0))); ts.Unix() != 1462363200 {
+		t.Errorf("Expected RuleTime to round-trip, got %v", ts)
+	}
+}
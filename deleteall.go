@@ -0,0 +1,300 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+// DefaultDeleteWorkers is the number of connections used by Collection.DeleteAll, see DeleteAllOptions.Workers
+const DefaultDeleteWorkers = 4
+
+// DeleteAllOptions are used with Collection.DeleteAll. Recursive includes the content of sub-collections. Filter, if
+// set, selects the data objects to delete by path. Force deletes permanently instead of moving to the trash, and
+// Unregister removes the catalog entries only, leaving the physical files in place (like irm -U). Workers is the
+// number of connections deleting concurrently (DefaultDeleteWorkers if 0), the collection's connection being one
+// of them. Progress, if set, is called after each path is handled.
+type DeleteAllOptions struct {
+	Recursive  bool
+	Filter     func(p string) bool
+	Force      bool
+	Unregister bool
+	Workers    int
+	Progress   func(p string, err error)
+}
+
+// DeleteAllResult is returned by Collection.DeleteAll. Deleted are the paths removed, in path order. Errors holds
+// the failures by path, which don't stop DeleteAll.
+type DeleteAllResult struct {
+	Deleted []string
+	Errors  map[string]error
+}
+
+// deleteTask is a path removed by a worker of DeleteAll, isCol removes a collection with its content
+type deleteTask struct {
+	path  string
+	isCol bool
+}
+
+// DeleteAll deletes the data objects of the collection. When every data object of the tree is deleted (Recursive
+// without Filter nor Unregister), each sub-collection is removed with its content in a single request, so the server
+// deletes the tree in bulk; otherwise the data objects are listed from the catalog and deleted one by one. Either
+// way, requests are spread over opts.Workers connections. The collection itself isn't removed. The error returned
+// is for listing failures, per-path failures are in the result.
+func (col *Collection) DeleteAll(opts DeleteAllOptions) (*DeleteAllResult, error) {
+	con := col.con
+
+	bulk := opts.Recursive && opts.Filter == nil && !opts.Unregister
+
+	tasks, err := con.deleteTasks(col.path, opts.Recursive && !bulk, bulk)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Filter != nil {
+		filtered := tasks[:0]
+
+		for _, t := range tasks {
+			if opts.Filter(t.path) {
+				filtered = append(filtered, t)
+			}
+		}
+
+		tasks = filtered
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultDeleteWorkers
+	}
+
+	if workers > len(tasks) {
+		workers = len(tasks)
+	}
+
+	cons := con.workerCons(workers)
+	defer func() {
+		for _, wcon := range cons[1:] {
+			wcon.Disconnect()
+		}
+	}()
+
+	result := runDeleteTasks(tasks, cons, func(wcon *Connection, t deleteTask) error {
+		if opts.Unregister {
+			return wcon.UnregisterReplica(t.path, -1)
+		}
+
+		return wcon.rmPath(t.path, t.isCol, opts.Force)
+	}, opts.Progress)
+
+	con.InvalidateCache(col.path)
+
+	return result, nil
+}
+
+// deleteTasks lists the data objects of the collection at p, those of the whole tree if recursive. If subCols is
+// set, sub-collections are listed as tasks as well.
+func (con *Connection) deleteTasks(p string, recursive bool, subCols bool) ([]deleteTask, error) {
+	zone, err := con.zoneHint(p)
+	if err != nil {
+		return nil, err
+	}
+
+	queries, err := deleteQueries(p, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	tasks := make([]deleteTask, 0)
+
+	for _, query := range queries {
+		err := con.intercept(&Event{Op: OpQuery, Query: query, noRetry: true}, func() error {
+			return con.queryPages(query, QueryOptions{PageSize: 256, Zone: zone}, nil, func(page []map[string]string) error {
+				tasks = appendDeleteTasks(tasks, p, page, seen)
+				return nil
+			})
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if subCols {
+		lit, err := queryLiteral(p)
+		if err != nil {
+			return nil, err
+		}
+
+		rows, err := con.IQuestZone(fmt.Sprintf("select COLL_NAME where COLL_PARENT_NAME = %v", lit), false, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			if row["COLL_NAME"] != p && inTree(p, row["COLL_NAME"]) {
+				tasks = append(tasks, deleteTask{path: row["COLL_NAME"], isCol: true})
+			}
+		}
+	}
+
+	return tasks, nil
+}
+
+// appendDeleteTasks appends the data objects of rows that are in the tree at p to tasks, once each
+// deleteQueries returns the queries listing the data objects of the collection at p, and those below it if recursive
+func deleteQueries(p string, recursive bool) ([]string, error) {
+	lit, err := queryLiteral(p)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := []string{fmt.Sprintf("select COLL_NAME, DATA_NAME where COLL_NAME = %v", lit)}
+
+	if recursive {
+		likeLit, err := queryLiteral(p + "/%")
+		if err != nil {
+			return nil, err
+		}
+
+		queries = append(queries, fmt.Sprintf("select COLL_NAME, DATA_NAME where COLL_NAME like %v", likeLit))
+	}
+
+	return queries, nil
+}
+
+func appendDeleteTasks(tasks []deleteTask, p string, rows []map[string]string, seen map[string]bool) []deleteTask {
+	for _, row := range rows {
+		if !inTree(p, row["COLL_NAME"]) {
+			continue
+		}
+
+		// Replicas are listed once each
+		if obj := row["COLL_NAME"] + "/" + row["DATA_NAME"]; !seen[obj] {
+			seen[obj] = true
+			tasks = append(tasks, deleteTask{path: obj})
+		}
+	}
+
+	return tasks
+}
+
+// workerCons returns n connections: con, and n - 1 new connections opened with its options. Connections that fail to
+// open are logged and skipped, so fewer may be returned.
+func (con *Connection) workerCons(n int) []*Connection {
+	cons := []*Connection{con}
+
+	for i := 1; i < n; i++ {
+		opts := *con.Options
+		opts.FastInit = true
+
+		wcon, err := NewConnection(&opts)
+		if err != nil {
+			con.log(LogWarn, "unable to open a worker connection", "host", opts.Host, "error", err)
+			break
+		}
+
+		cons = append(cons, wcon)
+	}
+
+	return cons
+}
+
+// runDeleteTasks runs del on each task, with one goroutine per connection of cons
+func runDeleteTasks(tasks []deleteTask, cons []*Connection, del func(*Connection, deleteTask) error, progress func(string, error)) *DeleteAllResult {
+	result := &DeleteAllResult{Deleted: make([]string, 0, len(tasks)), Errors: make(map[string]error)}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	queue := make(chan deleteTask)
+
+	for _, wcon := range cons {
+		wg.Add(1)
+
+		go func(wcon *Connection) {
+			defer wg.Done()
+
+			for t := range queue {
+				err := del(wcon, t)
+
+				mu.Lock()
+
+				if err != nil {
+					result.Errors[t.path] = err
+				} else {
+					result.Deleted = append(result.Deleted, t.path)
+				}
+
+				if progress != nil {
+					progress(t.path, err)
+				}
+
+				mu.Unlock()
+			}
+		}(wcon)
+	}
+
+	for _, t := range tasks {
+		queue <- t
+	}
+
+	close(queue)
+	wg.Wait()
+
+	sort.Strings(result.Deleted)
+
+	return result
+}
+
+// rmPath removes the data object at p, or the collection at p with its content if isCol is set
+func (con *Connection) rmPath(p string, isCol bool, force bool) error {
+	typ := "dataobj"
+	if isCol {
+		typ = "collection"
+	}
+
+	params := map[string]string{"type": typ, "recursive": strconv.FormatBool(isCol), "force": strconv.FormatBool(force)}
+
+	return con.intercept(&Event{Op: OpDelete, Path: p, Params: params}, func() error {
+		var (
+			errMsg *C.char
+			cIsCol C.int
+			cForce C.int
+		)
+
+		if isCol {
+			cIsCol = C.int(1)
+		}
+
+		if force {
+			cForce = C.int(1)
+		}
+
+		cPath := C.CString(p)
+		defer C.free(unsafe.Pointer(cPath))
+
+		ccon := con.GetCcon()
+		defer con.ReturnCcon(ccon)
+
+		if status := C.gorods_rm(cPath, cIsCol, cIsCol, cForce, C.int(0), ccon, &errMsg); status != 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Rm Failed: %v, %v", p, C.GoString(errMsg)))
+		}
+
+		return nil
+	})
+}
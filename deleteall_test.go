@@ -0,0 +1,108 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestRunDeleteTasks(t *testing.T) {
+	tasks := make([]deleteTask, 0)
+
+	for _, p := range []string{"/z/c/d", "/z/c/b", "/z/c/fail", "/z/c/a", "/z/c/sub"} {
+		tasks = append(tasks, deleteTask{path: p, isCol: p == "/z/c/sub"})
+	}
+
+	cons := []*Connection{new(Connection), new(Connection), new(Connection)}
+
+	var (
+		mu       sync.Mutex
+		used     = make(map[*Connection]bool)
+		progress int
+	)
+
+	result := runDeleteTasks(tasks, cons, func(con *Connection, task deleteTask) error {
+		mu.Lock()
+		used[con] = true
+		mu.Unlock()
+
+		if task.path == "/z/c/fail" {
+			return errors.New("no permission")
+		}
+
+		return nil
+	}, func(p string, err error) {
+		progress++
+	})
+
+	if len(result.Deleted) != 4 || result.Deleted[0] != "/z/c/a" || result.Deleted[3] != "/z/c/sub" {
+		t.Errorf("Unexpected deleted paths %v", result.Deleted)
+	}
+
+	if len(result.Errors) != 1 || result.Errors["/z/c/fail"] == nil {
+		t.Errorf("Expected an error for /z/c/fail, got %v", result.Errors)
+	}
+
+	if progress != len(tasks) {
+		t.Errorf("Expected progress for %v paths, got %v", len(tasks), progress)
+	}
+
+	if len(used) == 0 || len(used) > len(cons) {
+		t.Errorf("Unexpected number of connections used: %v", len(used))
+	}
+}
+
+func TestAppendDeleteTasks(t *testing.T) {
+	// _ is a wildcard of like conditions, so the recursive query of /z/a_b also returns /z/aXb
+	rows := []map[string]string{
+		{"COLL_NAME": "/z/a_b", "DATA_NAME": "1.txt"},
+		{"COLL_NAME": "/z/a_b/sub", "DATA_NAME": "2.txt"},
+		{"COLL_NAME": "/z/a_b/sub", "DATA_NAME": "2.txt"},
+		{"COLL_NAME": "/z/aXb/sub", "DATA_NAME": "3.txt"},
+		{"COLL_NAME": "/z/a_bc", "DATA_NAME": "4.txt"},
+	}
+
+	tasks := appendDeleteTasks(nil, "/z/a_b", rows, make(map[string]bool))
+
+	if len(tasks) != 2 || tasks[0].path != "/z/a_b/1.txt" || tasks[1].path != "/z/a_b/sub/2.txt" {
+		t.Errorf("Unexpected tasks %+v", tasks)
+	}
+
+	if _, err := queryLiteral("/z/o'brien"); err == nil {
+		t.Error("Expected an error for a path with a single quote")
+	}
+
+	if lit, err := queryLiteral("/z/a_b"); err != nil || lit != "'/z/a_b'" {
+		t.Errorf("Unexpected literal %v, %v", lit, err)
+	}
+}
+
+func TestDeleteQueries(t *testing.T) {
+	queries, err := deleteQueries("/z/a_b", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"select COLL_NAME, DATA_NAME where COLL_NAME = '/z/a_b'",
+		"select COLL_NAME, DATA_NAME where COLL_NAME like '/z/a_b/%'",
+	}
+
+	if len(queries) != 2 || queries[0] != expected[0] || queries[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, queries)
+	}
+
+	if queries, err := deleteQueries("/z/a_b", false); err != nil || len(queries) != 1 {
+		t.Errorf("Expected only the collection's own query, got %v, %v", queries, err)
+	}
+
+	if _, err := deleteQueries("/z/o'brien", true); err == nil {
+		t.Error("Expected an error for a path with a single quote")
+	}
+}
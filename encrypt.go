@@ -0,0 +1,316 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// AVUs stored on data objects encrypted by AESGCMTransform
+const (
+	EncryptionKeyAttr     = "gorods::encryption::key"
+	EncryptionKeyIdAttr   = "gorods::encryption::key_id"
+	EncryptionSegmentAttr = "gorods::encryption::segment"
+)
+
+// DefaultEncryptionSegment is the number of plaintext bytes sealed together by AESGCMTransform
+const DefaultEncryptionSegment = 64 * 1024
+
+// AESGCMTransform encrypts data objects with AES-256-GCM before they leave the client, so the vault only ever holds
+// ciphertext. Every data object gets a random data key, which is wrapped by Wrap (typically a call to a key
+// management service) and stored, wrapped, in the EncryptionKeyAttr AVU along with the id returned by Wrap.
+// Downloads pass them to Unwrap. The contents are sealed in segments of SegmentSize bytes (DefaultEncryptionSegment
+// if 0), each authenticated on its own, so data objects of any size are streamed and truncation is detected.
+//
+// Register the transform with RegisterTransform for downloads, and add it to DataObjOptions.Transforms or
+// ConnectionOptions.Transforms for uploads. Compressing transforms must come before it in a pipeline. Set in
+// ConnectionOptions.Transforms, it's enforced: writes that can't encrypt, like DataObj.WriteBytes, fail. The wrapped
+// key AVUs are added in the same request as the TransformAttr one, before the data object gets its name.
+type AESGCMTransform struct {
+	Wrap        func(dataKey []byte) (wrapped []byte, keyId string, err error)
+	Unwrap      func(wrapped []byte, keyId string) (dataKey []byte, err error)
+	SegmentSize int
+}
+
+// Name returns "aes-gcm"
+func (t *AESGCMTransform) Name() string {
+	return "aes-gcm"
+}
+
+// Encode fails, the transform needs the AVUs of the data object, see EncodeObject
+func (t *AESGCMTransform) Encode(w io.Writer) (io.WriteCloser, error) {
+	return nil, newError(Fatal, -1, "iRODS Encrypt Failed: aes-gcm can only be applied to data objects")
+}
+
+// Decode fails, the transform needs the AVUs of the data object, see DecodeObject
+func (t *AESGCMTransform) Decode(r io.Reader) (io.ReadCloser, error) {
+	return nil, newError(Fatal, -1, "iRODS Decrypt Failed: aes-gcm can only be reversed for data objects")
+}
+
+// EncodeObject generates and wraps a data key, returning the encrypting writer and the AVUs holding the wrapped key
+func (t *AESGCMTransform) EncodeObject(w io.Writer) (io.WriteCloser, []Meta, error) {
+	if t.Wrap == nil {
+		return nil, nil, newError(Fatal, -1, "iRODS Encrypt Failed: no Wrap function set")
+	}
+
+	dataKey := make([]byte, 32)
+	defer zeroBytes(dataKey)
+
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, nil, newError(Fatal, -1, fmt.Sprintf("iRODS Encrypt Failed: %v", err))
+	}
+
+	wrapped, keyId, err := t.Wrap(dataKey)
+	if err != nil {
+		return nil, nil, newError(Fatal, -1, fmt.Sprintf("iRODS Encrypt Failed: wrapping the data key, %v", err))
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seg := t.segmentSize()
+
+	metas := []Meta{
+		{Attribute: EncryptionKeyAttr, Value: base64.StdEncoding.EncodeToString(wrapped)},
+		{Attribute: EncryptionSegmentAttr, Value: strconv.Itoa(seg)},
+	}
+
+	if keyId != "" {
+		metas = append(metas, Meta{Attribute: EncryptionKeyIdAttr, Value: keyId})
+	}
+
+	return &gcmWriter{w: w, aead: aead, seg: seg, buf: make([]byte, 0, seg)}, metas, nil
+}
+
+// DecodeObject unwraps the data key stored in metas, returning the decrypting reader
+func (t *AESGCMTransform) DecodeObject(r io.Reader, metas Metas) (io.ReadCloser, error) {
+	if t.Unwrap == nil {
+		return nil, newError(Fatal, -1, "iRODS Decrypt Failed: no Unwrap function set")
+	}
+
+	var wrapped, keyId, segment string
+
+	for _, m := range metas {
+		switch m.Attribute {
+		case EncryptionKeyAttr:
+			wrapped = m.Value
+		case EncryptionKeyIdAttr:
+			keyId = m.Value
+		case EncryptionSegmentAttr:
+			segment = m.Value
+		}
+	}
+
+	if wrapped == "" {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Decrypt Failed: no %v AVU", EncryptionKeyAttr))
+	}
+
+	key, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Decrypt Failed: invalid %v AVU, %v", EncryptionKeyAttr, err))
+	}
+
+	seg, err := strconv.Atoi(segment)
+	if err != nil || seg <= 0 {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Decrypt Failed: invalid %v AVU %q", EncryptionSegmentAttr, segment))
+	}
+
+	dataKey, err := t.Unwrap(key, keyId)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Decrypt Failed: unwrapping the data key, %v", err))
+	}
+	defer zeroBytes(dataKey)
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gcmReader{r: bufio.NewReader(r), aead: aead, seg: seg, frame: make([]byte, seg+aead.Overhead())}, nil
+}
+
+func (t *AESGCMTransform) segmentSize() int {
+	if t.SegmentSize > 0 {
+		return t.SegmentSize
+	}
+
+	return DefaultEncryptionSegment
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Encryption Failed: data keys must be 32 bytes, got %v", len(key)))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Encryption Failed: %v", err))
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Encryption Failed: %v", err))
+	}
+
+	return aead, nil
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// segmentNonce returns the nonce of segment n. The first byte marks the final segment, so dropping
+// segments from the end fails authentication.
+func segmentNonce(n uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+
+	if final {
+		nonce[0] = 1
+	}
+
+	binary.BigEndian.PutUint64(nonce[4:], n)
+
+	return nonce
+}
+
+// gcmWriter seals its input in segments. A segment is only sealed once more input follows it,
+// so Close always writes a final segment, empty if needed.
+type gcmWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	seg   int
+	buf   []byte
+	out   []byte
+	n     uint64
+	final bool
+}
+
+func (gw *gcmWriter) Write(p []byte) (int, error) {
+	if gw.final {
+		return 0, newError(Fatal, -1, "iRODS Encrypt Failed: write after close")
+	}
+
+	written := len(p)
+
+	for len(p) > 0 {
+		if len(gw.buf) == gw.seg {
+			if err := gw.seal(false); err != nil {
+				return 0, err
+			}
+		}
+
+		take := gw.seg - len(gw.buf)
+		if take > len(p) {
+			take = len(p)
+		}
+
+		gw.buf = append(gw.buf, p[:take]...)
+		p = p[take:]
+	}
+
+	return written, nil
+}
+
+// Close seals the final segment, it doesn't close the underlying writer
+func (gw *gcmWriter) Close() error {
+	if gw.final {
+		return nil
+	}
+
+	return gw.seal(true)
+}
+
+func (gw *gcmWriter) seal(final bool) error {
+	gw.out = gw.aead.Seal(gw.out[:0], segmentNonce(gw.n, final), gw.buf, nil)
+	gw.buf = gw.buf[:0]
+	gw.n++
+	gw.final = final
+
+	if _, err := gw.w.Write(gw.out); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// gcmReader opens the segments written by gcmWriter
+type gcmReader struct {
+	r     *bufio.Reader
+	aead  cipher.AEAD
+	seg   int
+	frame []byte
+	plain []byte
+	n     uint64
+	done  bool
+}
+
+func (gr *gcmReader) Read(p []byte) (int, error) {
+	for len(gr.plain) == 0 {
+		if gr.done {
+			return 0, io.EOF
+		}
+
+		if err := gr.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, gr.plain)
+	gr.plain = gr.plain[n:]
+
+	return n, nil
+}
+
+// next reads and opens the next segment. Only the last segment of the stream may be shorter than a full one.
+func (gr *gcmReader) next() error {
+	n, err := io.ReadFull(gr.r, gr.frame)
+
+	final := false
+
+	switch err {
+	case nil:
+		if _, perr := gr.r.Peek(1); perr == io.EOF {
+			final = true
+		} else if perr != nil {
+			return perr
+		}
+	case io.ErrUnexpectedEOF:
+		final = true
+	case io.EOF:
+		return newError(Fatal, -1, "iRODS Decrypt Failed: contents truncated")
+	default:
+		return err
+	}
+
+	plain, err := gr.aead.Open(gr.frame[:0], segmentNonce(gr.n, final), gr.frame[:n], nil)
+	if err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Decrypt Failed: segment %v, %v", gr.n, err))
+	}
+
+	gr.plain = plain
+	gr.n++
+	gr.done = final
+
+	return nil
+}
+
+// Close does nothing, the data object is closed by the pipeline
+func (gr *gcmReader) Close() error {
+	return nil
+}
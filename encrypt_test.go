@@ -0,0 +1,114 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// testKeyWrapper wraps data keys by XOR with a fixed key encryption key
+func testKeyWrapper() *AESGCMTransform {
+	kek := bytes.Repeat([]byte{0x5a}, 32)
+
+	xor := func(key []byte) []byte {
+		out := make([]byte, len(key))
+		for i := range key {
+			out[i] = key[i] ^ kek[i%len(kek)]
+		}
+		return out
+	}
+
+	return &AESGCMTransform{
+		Wrap: func(dataKey []byte) ([]byte, string, error) {
+			return xor(dataKey), "test-kek", nil
+		},
+		Unwrap: func(wrapped []byte, keyId string) ([]byte, error) {
+			return xor(wrapped), nil
+		},
+		SegmentSize: 16,
+	}
+}
+
+func encryptTest(t *testing.T, tr *AESGCMTransform, data []byte) ([]byte, Metas) {
+	var stored bytes.Buffer
+
+	w, avus, err := tr.EncodeObject(&stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	metas := make(Metas, len(avus))
+	for i := range avus {
+		metas[i] = &avus[i]
+	}
+
+	return stored.Bytes(), metas
+}
+
+func TestAESGCMTransform(t *testing.T) {
+	tr := testKeyWrapper()
+
+	for _, size := range []int{0, 1, 15, 16, 17, 48, 100} {
+		data := bytes.Repeat([]byte("x"), size)
+
+		stored, metas := encryptTest(t, tr, data)
+
+		if size > 0 && bytes.Contains(stored, data) {
+			t.Errorf("Size %v: plaintext found in the stored contents", size)
+		}
+
+		r, err := tr.DecodeObject(bytes.NewReader(stored), metas)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("Size %v: %v", size, err)
+		} else if !bytes.Equal(out, data) {
+			t.Errorf("Size %v: got %v bytes back", size, len(out))
+		}
+	}
+}
+
+func TestAESGCMTransformTampering(t *testing.T) {
+	tr := testKeyWrapper()
+
+	stored, metas := encryptTest(t, tr, bytes.Repeat([]byte("y"), 40))
+
+	// Drop the final segment
+	segment := 16 + 16
+	r, err := tr.DecodeObject(bytes.NewReader(stored[:2*segment]), metas)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Error("Expected an error for truncated contents")
+	}
+
+	stored[3] ^= 1
+
+	r, err = tr.DecodeObject(bytes.NewReader(stored), metas)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Error("Expected an error for modified contents")
+	}
+}
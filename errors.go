@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
  *** For more information please refer to the LICENSE.md file                                   ***/
 
@@ -7,48 +10,26 @@ package gorods
 import "C"
 
 import (
-	"fmt"
 	"time"
 	"unsafe"
+
+	"github.com/jjacquay712/GoRODS/api"
 )
 
 // Log level constants
 const (
-	Info = iota
-	Warn
-	Fatal
+	Info  = api.Info
+	Warn  = api.Warn
+	Fatal = api.Fatal
 )
 
-// GoRodsError stores information about errors
-type GoRodsError struct {
-	LogLevel  int
-	Message   string
-	IRODSCode string
-	Time      time.Time
-}
-
-// Error returns error string, alias of String(). Sample output:
-//
-// 	2016-04-22 10:02:30.802355258 -0400 EDT: Fatal - iRODS Connect Failed: rcConnect failed
-func (err *GoRodsError) Error() string {
-	return err.String()
-}
-
-// String returns error string. Sample output:
-//
-// 	2016-04-22 10:02:30.802355258 -0400 EDT: Fatal - iRODS Connect Failed: rcConnect failed
-func (err *GoRodsError) String() string {
-	return fmt.Sprintf("%v: %v - %v%v", err.Time, err.lookupError(err.LogLevel), err.Message, err.IRODSCode)
-}
-
-func (err *GoRodsError) lookupError(code int) string {
-	var constLookup = map[int]string{
-		Info:  "Info",
-		Warn:  "Warn",
-		Fatal: "Fatal",
-	}
+// GoRodsError stores information about errors, see api.GoRodsError
+type GoRodsError = api.GoRodsError
 
-	return constLookup[code]
+// IsNotFound returns true if err is a *GoRodsError reporting that a data object or collection doesn't exist,
+// rather than another failure like a permission or network error
+func IsNotFound(err error) bool {
+	return api.IsNotFound(err)
 }
 
 func newError(logLevel int, status C.int, message string) *GoRodsError {
@@ -62,6 +43,7 @@ func newError(logLevel int, status C.int, message string) *GoRodsError {
 	err.LogLevel = logLevel
 	err.Message = message
 	err.Time = time.Now()
+	err.Status = int(status)
 
 	if status != -1 {
 		defer C.free(unsafe.Pointer(errStr))
@@ -69,7 +51,8 @@ func newError(logLevel int, status C.int, message string) *GoRodsError {
 
 		errStr = C.rodsErrorName(status, &subErrStr)
 
-		err.IRODSCode = " " + C.GoString(errStr) + " " + C.GoString(subErrStr)
+		err.ErrorName = C.GoString(errStr)
+		err.IRODSCode = " " + err.ErrorName + " " + C.GoString(subErrStr)
 	}
 
 	return err
@@ -0,0 +1,279 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+// Package fuse mounts an iRODS collection as a local filesystem with read/write support, using bazil.org/fuse.
+// File attributes and directory listings are cached for a configurable TTL to avoid round trips to the iCAT server.
+package fuse
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+	"time"
+
+	bfuse "bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"github.com/jjacquay712/GoRODS"
+)
+
+// Options are used when calling Mount. Path is the iRODS collection to mount.
+// AttrTTL controls how long file attributes are cached (by the kernel and GoRODS), DirTTL how long directory listings are cached.
+// Both default to one second. Uid and Gid are reported as the owner of every file, those of the current process if
+// nil, use Owner to set them.
+type Options struct {
+	Connection *gorods.Connection
+	Path       string
+	ReadOnly   bool
+	AttrTTL    time.Duration
+	DirTTL     time.Duration
+	Uid        *uint32
+	Gid        *uint32
+}
+
+// Owner returns opts with Uid and Gid set to uid and gid, which may be 0 (root)
+func (opts Options) Owner(uid uint32, gid uint32) Options {
+	opts.Uid = &uid
+	opts.Gid = &gid
+
+	return opts
+}
+
+// FS is a mounted iRODS collection. Use Mount to create one.
+type FS struct {
+	opts Options
+	conn *bfuse.Conn
+	dir  string
+	uid  uint32
+	gid  uint32
+
+	cache *cache
+}
+
+// Mount mounts the collection specified in opts at the local directory mountpoint. Call Serve to start handling requests.
+func Mount(mountpoint string, opts Options) (*FS, error) {
+	if opts.AttrTTL == 0 {
+		opts.AttrTTL = time.Second
+	}
+
+	if opts.DirTTL == 0 {
+		opts.DirTTL = time.Second
+	}
+
+	mountOpts := []bfuse.MountOption{
+		bfuse.FSName("gorods"),
+		bfuse.Subtype("irodsfs"),
+	}
+
+	if opts.ReadOnly {
+		mountOpts = append(mountOpts, bfuse.ReadOnly())
+	}
+
+	c, err := bfuse.Mount(mountpoint, mountOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	filesys := new(FS)
+
+	filesys.opts = opts
+	filesys.conn = c
+	filesys.dir = mountpoint
+	filesys.uid, filesys.gid = opts.owner()
+	filesys.cache = newCache(opts.AttrTTL, opts.DirTTL)
+
+	return filesys, nil
+}
+
+// owner returns the uid and gid files are reported to be owned by
+func (opts Options) owner() (uint32, uint32) {
+	uid, gid := uint32(os.Getuid()), uint32(os.Getgid())
+
+	if opts.Uid != nil {
+		uid = *opts.Uid
+	}
+
+	if opts.Gid != nil {
+		gid = *opts.Gid
+	}
+
+	return uid, gid
+}
+
+// Serve handles filesystem requests until the filesystem is unmounted
+func (filesys *FS) Serve() error {
+	return fs.Serve(filesys.conn, filesys)
+}
+
+// Unmount unmounts the filesystem and closes the FUSE connection
+func (filesys *FS) Unmount() error {
+	if err := bfuse.Unmount(filesys.dir); err != nil {
+		return err
+	}
+
+	return filesys.conn.Close()
+}
+
+// Root implements fs.FS
+func (filesys *FS) Root() (fs.Node, error) {
+	return &Dir{fs: filesys, path: path.Clean(filesys.opts.Path)}, nil
+}
+
+func (filesys *FS) con() *gorods.Connection {
+	return filesys.opts.Connection
+}
+
+// stat returns the (cached) ObjStat for p
+func (filesys *FS) stat(p string) (*gorods.ObjStat, error) {
+	if stat := filesys.cache.getAttr(p); stat != nil {
+		return stat, nil
+	}
+
+	stat, err := filesys.con().ObjStat(p)
+	if err != nil {
+		return nil, bfuse.ENOENT
+	}
+
+	filesys.cache.putAttr(p, stat)
+
+	return stat, nil
+}
+
+func (filesys *FS) fillAttr(stat *gorods.ObjStat, a *bfuse.Attr) {
+	a.Valid = filesys.opts.AttrTTL
+	a.Size = uint64(stat.Size)
+	a.Mtime = stat.ModifyTime
+	a.Ctime = stat.ModifyTime
+	a.Crtime = stat.CreateTime
+	a.Uid = filesys.uid
+	a.Gid = filesys.gid
+
+	perm := os.FileMode(0644)
+	if filesys.opts.ReadOnly {
+		perm = 0444
+	}
+
+	if stat.Type == gorods.CollectionType {
+		a.Mode = os.ModeDir | perm | 0111
+	} else {
+		a.Mode = perm
+	}
+}
+
+func (filesys *FS) writable() error {
+	if filesys.opts.ReadOnly {
+		return bfuse.Errno(syscall.EROFS)
+	}
+
+	return nil
+}
+
+// collection opens the collection at p without using the GoRODS collection cache
+func (filesys *FS) collection(p string) (*gorods.Collection, error) {
+	col, err := filesys.con().Collection(gorods.CollectionOptions{
+		Path:      p,
+		Recursive: false,
+		SkipCache: true,
+	})
+	if err != nil {
+		return nil, bfuse.ENOENT
+	}
+
+	return col, nil
+}
+
+// asideName returns the hidden name an existing file called name is renamed to while it's being replaced, like
+// ".a.txt.gorods-1f2e3d4c"
+func asideName(name string) (string, error) {
+	b := make([]byte, 4)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "." + name + ".gorods-" + hex.EncodeToString(b), nil
+}
+
+// cache holds ObjStats and directory listings, keyed by iRODS path
+type cache struct {
+	attrTTL time.Duration
+	dirTTL  time.Duration
+
+	attrs map[string]attrEntry
+	dirs  map[string]dirEntry
+
+	mu sync.Mutex
+}
+
+type attrEntry struct {
+	stat    *gorods.ObjStat
+	expires time.Time
+}
+
+type dirEntry struct {
+	stats   []*gorods.ObjStat
+	expires time.Time
+}
+
+func newCache(attrTTL time.Duration, dirTTL time.Duration) *cache {
+	c := new(cache)
+
+	c.attrTTL = attrTTL
+	c.dirTTL = dirTTL
+	c.attrs = make(map[string]attrEntry)
+	c.dirs = make(map[string]dirEntry)
+
+	return c
+}
+
+func (c *cache) getAttr(p string) *gorods.ObjStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.attrs[p]; ok && time.Now().Before(e.expires) {
+		return e.stat
+	}
+
+	return nil
+}
+
+func (c *cache) putAttr(p string, stat *gorods.ObjStat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.attrs[p] = attrEntry{stat, time.Now().Add(c.attrTTL)}
+}
+
+func (c *cache) getDir(p string) []*gorods.ObjStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.dirs[p]; ok && time.Now().Before(e.expires) {
+		return e.stats
+	}
+
+	return nil
+}
+
+func (c *cache) putDir(p string, stats []*gorods.ObjStat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dirs[p] = dirEntry{stats, time.Now().Add(c.dirTTL)}
+
+	for _, stat := range stats {
+		c.attrs[stat.Path] = attrEntry{stat, time.Now().Add(c.attrTTL)}
+	}
+}
+
+// invalidate drops cached information for p and its parent directory listing
+func (c *cache) invalidate(p string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.attrs, p)
+	delete(c.dirs, p)
+	delete(c.dirs, path.Dir(p))
+}
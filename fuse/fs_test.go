@@ -0,0 +1,94 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package fuse
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	bfuse "bazil.org/fuse"
+	"github.com/jjacquay712/GoRODS"
+)
+
+func TestOwner(t *testing.T) {
+	if uid, gid := (Options{}).owner(); uid != uint32(os.Getuid()) || gid != uint32(os.Getgid()) {
+		t.Errorf("Expected the owner of the current process, got %v:%v", uid, gid)
+	}
+
+	if uid, gid := (Options{}).Owner(0, 0).owner(); uid != 0 || gid != 0 {
+		t.Errorf("Expected root to be a valid owner, got %v:%v", uid, gid)
+	}
+
+	if uid, gid := (Options{}).Owner(1000, 100).owner(); uid != 1000 || gid != 100 {
+		t.Errorf("Expected 1000:100, got %v:%v", uid, gid)
+	}
+}
+
+func TestFillAttr(t *testing.T) {
+	filesys := &FS{opts: Options{ReadOnly: true, AttrTTL: time.Second}, uid: 0, gid: 0}
+
+	var a bfuse.Attr
+
+	filesys.fillAttr(&gorods.ObjStat{Type: gorods.CollectionType}, &a)
+
+	if a.Mode != os.ModeDir|0555 || a.Uid != 0 || a.Gid != 0 || a.Valid != time.Second {
+		t.Errorf("Unexpected collection attributes %+v", a)
+	}
+
+	filesys.opts.ReadOnly = false
+	filesys.fillAttr(&gorods.ObjStat{Type: gorods.DataObjType, Size: 42}, &a)
+
+	if a.Mode != 0644 || a.Size != 42 {
+		t.Errorf("Unexpected data object attributes %+v", a)
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := newCache(time.Minute, time.Minute)
+
+	dir := &gorods.ObjStat{Path: "/tempZone/home/rods", Type: gorods.CollectionType}
+	file := &gorods.ObjStat{Path: "/tempZone/home/rods/a.txt", Type: gorods.DataObjType}
+
+	c.putAttr(dir.Path, dir)
+	c.putDir(dir.Path, []*gorods.ObjStat{file})
+
+	if c.getAttr(file.Path) != file {
+		t.Error("Expected directory listings to cache the attributes of their entries")
+	}
+
+	c.invalidate(file.Path)
+
+	if c.getAttr(file.Path) != nil || c.getDir(dir.Path) != nil {
+		t.Error("Expected invalidate to drop the attributes and the parent's listing")
+	}
+
+	if c.getAttr(dir.Path) != dir {
+		t.Error("Expected invalidate to keep the parent's attributes")
+	}
+
+	expired := newCache(-time.Second, -time.Second)
+	expired.putAttr(dir.Path, dir)
+
+	if expired.getAttr(dir.Path) != nil {
+		t.Error("Expected expired attributes to be dropped")
+	}
+}
+
+func TestAsideName(t *testing.T) {
+	a, err := asideName("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := asideName("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(a, ".a.txt.gorods-") || a == b {
+		t.Errorf("Unexpected names %q and %q", a, b)
+	}
+}
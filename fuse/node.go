@@ -0,0 +1,486 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package fuse
+
+import (
+	"context"
+	"path"
+	"sync"
+	"syscall"
+
+	bfuse "bazil.org/fuse"
+	"bazil.org/fuse/fs"
+
+	"github.com/jjacquay712/GoRODS"
+)
+
+// Dir is a collection node
+type Dir struct {
+	fs   *FS
+	path string
+}
+
+// Attr implements fs.Node
+func (d *Dir) Attr(ctx context.Context, a *bfuse.Attr) error {
+	stat, err := d.fs.stat(d.path)
+	if err != nil {
+		return err
+	}
+
+	d.fs.fillAttr(stat, a)
+
+	return nil
+}
+
+func (d *Dir) child(name string) string {
+	return path.Join(d.path, name)
+}
+
+// Lookup implements fs.NodeStringLookuper
+func (d *Dir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	p := d.child(name)
+
+	stat, err := d.fs.stat(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if stat.Type == gorods.CollectionType {
+		return &Dir{fs: d.fs, path: p}, nil
+	}
+
+	return &File{fs: d.fs, path: p}, nil
+}
+
+func (d *Dir) list() ([]*gorods.ObjStat, error) {
+	if stats := d.fs.cache.getDir(d.path); stats != nil {
+		return stats, nil
+	}
+
+	col, err := d.fs.collection(d.path)
+	if err != nil {
+		return nil, err
+	}
+
+	objs, err := col.All()
+	if err != nil {
+		return nil, bfuse.EIO
+	}
+
+	stats := make([]*gorods.ObjStat, 0, len(objs))
+
+	for _, obj := range objs {
+		stat := &gorods.ObjStat{
+			Path:       obj.Path(),
+			Type:       obj.Type(),
+			OwnerName:  obj.OwnerName(),
+			CreateTime: obj.CreateTime(),
+			ModifyTime: obj.ModifyTime(),
+		}
+
+		if do, ok := obj.(*gorods.DataObj); ok {
+			stat.Size = do.Size()
+			stat.Checksum = do.Checksum()
+			stat.DataId = do.DataId()
+		}
+
+		stats = append(stats, stat)
+	}
+
+	d.fs.cache.putDir(d.path, stats)
+
+	return stats, nil
+}
+
+// ReadDirAll implements fs.HandleReadDirAller
+func (d *Dir) ReadDirAll(ctx context.Context) ([]bfuse.Dirent, error) {
+	stats, err := d.list()
+	if err != nil {
+		return nil, err
+	}
+
+	dirents := make([]bfuse.Dirent, 0, len(stats))
+
+	for _, stat := range stats {
+		dirent := bfuse.Dirent{Name: path.Base(stat.Path), Type: bfuse.DT_File}
+
+		if stat.Type == gorods.CollectionType {
+			dirent.Type = bfuse.DT_Dir
+		}
+
+		dirents = append(dirents, dirent)
+	}
+
+	return dirents, nil
+}
+
+// Mkdir implements fs.NodeMkdirer
+func (d *Dir) Mkdir(ctx context.Context, req *bfuse.MkdirRequest) (fs.Node, error) {
+	if err := d.fs.writable(); err != nil {
+		return nil, err
+	}
+
+	col, err := d.fs.collection(d.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := col.CreateSubCollection(req.Name); err != nil {
+		return nil, bfuse.EPERM
+	}
+
+	p := d.child(req.Name)
+	d.fs.cache.invalidate(p)
+
+	return &Dir{fs: d.fs, path: p}, nil
+}
+
+// Create implements fs.NodeCreater
+func (d *Dir) Create(ctx context.Context, req *bfuse.CreateRequest, resp *bfuse.CreateResponse) (fs.Node, fs.Handle, error) {
+	if err := d.fs.writable(); err != nil {
+		return nil, nil, err
+	}
+
+	col, err := d.fs.collection(d.path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	obj, err := col.CreateDataObj(gorods.DataObjOptions{
+		Name:  req.Name,
+		Mode:  int(req.Mode.Perm()),
+		Force: true,
+	})
+	if err != nil {
+		return nil, nil, bfuse.EPERM
+	}
+
+	p := d.child(req.Name)
+	d.fs.cache.invalidate(p)
+
+	f := &File{fs: d.fs, path: p}
+
+	return f, &FileHandle{file: f, obj: obj, writable: true}, nil
+}
+
+// Remove implements fs.NodeRemover
+func (d *Dir) Remove(ctx context.Context, req *bfuse.RemoveRequest) error {
+	if err := d.fs.writable(); err != nil {
+		return err
+	}
+
+	p := d.child(req.Name)
+
+	var (
+		obj gorods.IRodsObj
+		err error
+	)
+
+	if req.Dir {
+		obj, err = d.fs.collection(p)
+	} else {
+		obj, err = d.fs.con().DataObject(p)
+	}
+
+	if err != nil {
+		return bfuse.ENOENT
+	}
+
+	// Like rmdir(2), refuse to remove collections that aren't empty. The collection is read without recursion, so
+	// only its direct children are listed, empty sub-collections included.
+	if req.Dir {
+		children, err := obj.(*gorods.Collection).All()
+		if err != nil {
+			return bfuse.EIO
+		}
+
+		if len(children) > 0 {
+			return bfuse.Errno(syscall.ENOTEMPTY)
+		}
+	}
+
+	if err := obj.Delete(true); err != nil {
+		return bfuse.EPERM
+	}
+
+	d.fs.cache.invalidate(p)
+
+	return nil
+}
+
+// Rename implements fs.NodeRenamer
+func (d *Dir) Rename(ctx context.Context, req *bfuse.RenameRequest, newDir fs.Node) error {
+	if err := d.fs.writable(); err != nil {
+		return err
+	}
+
+	target, ok := newDir.(*Dir)
+	if !ok {
+		return bfuse.EIO
+	}
+
+	src := d.child(req.OldName)
+	dst := target.child(req.NewName)
+
+	stat, err := d.fs.stat(src)
+	if err != nil {
+		return err
+	}
+
+	var obj gorods.IRodsObj
+
+	if stat.Type == gorods.CollectionType {
+		obj, err = d.fs.collection(src)
+	} else {
+		obj, err = d.fs.con().DataObject(src)
+	}
+
+	if err != nil {
+		return bfuse.ENOENT
+	}
+
+	// rename(2) replaces an existing destination file. iRODS can't rename over it, so it's renamed aside first, and
+	// only deleted once the rename succeeded.
+	var existing *gorods.DataObj
+
+	if stat.Type != gorods.CollectionType {
+		if existing, err = d.fs.con().DataObject(dst); err == nil {
+			aside, err := asideName(req.NewName)
+			if err != nil {
+				return bfuse.EIO
+			}
+
+			if err := existing.Rename(aside); err != nil {
+				return bfuse.EPERM
+			}
+
+			d.fs.cache.invalidate(existing.Path())
+		} else {
+			existing = nil
+		}
+	}
+
+	if err := d.move(obj, target, req.OldName, req.NewName); err != nil {
+		if existing != nil {
+			existing.Rename(req.NewName)
+		}
+
+		d.fs.cache.invalidate(src)
+		d.fs.cache.invalidate(dst)
+
+		return bfuse.EPERM
+	}
+
+	d.fs.cache.invalidate(src)
+	d.fs.cache.invalidate(dst)
+
+	if existing != nil {
+		if err := existing.Delete(false); err != nil {
+			return bfuse.EPERM
+		}
+
+		d.fs.cache.invalidate(existing.Path())
+	}
+
+	return nil
+}
+
+// move moves obj, called oldName in d, to newName in target. If it fails after moving obj to target, obj is moved
+// back to d.
+func (d *Dir) move(obj gorods.IRodsObj, target *Dir, oldName string, newName string) error {
+	if target.path != d.path {
+		if err := obj.MoveTo(target.path); err != nil {
+			return err
+		}
+	}
+
+	if oldName != newName {
+		if err := obj.Rename(newName); err != nil {
+			if target.path != d.path {
+				obj.MoveTo(d.path)
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// File is a data object node
+type File struct {
+	fs   *FS
+	path string
+}
+
+// Attr implements fs.Node
+func (f *File) Attr(ctx context.Context, a *bfuse.Attr) error {
+	stat, err := f.fs.stat(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.fs.fillAttr(stat, a)
+
+	return nil
+}
+
+// Open implements fs.NodeOpener
+func (f *File) Open(ctx context.Context, req *bfuse.OpenRequest, resp *bfuse.OpenResponse) (fs.Handle, error) {
+	writable := !req.Flags.IsReadOnly()
+
+	if writable {
+		if err := f.fs.writable(); err != nil {
+			return nil, err
+		}
+	}
+
+	obj, err := f.fs.con().DataObject(f.path)
+	if err != nil {
+		return nil, bfuse.ENOENT
+	}
+
+	// A truncated object is returned already opened
+	if req.Flags&bfuse.OpenTruncate != 0 {
+		if obj, err = f.truncate(obj); err != nil {
+			return nil, err
+		}
+	} else if writable {
+		err = obj.OpenRW()
+	} else {
+		err = obj.Open()
+	}
+
+	if err != nil {
+		return nil, bfuse.EPERM
+	}
+
+	return &FileHandle{file: f, obj: obj, writable: writable}, nil
+}
+
+// truncate empties the data object and opens it for writing, keeping its metadata and ACLs
+func (f *File) truncate(obj *gorods.DataObj) (*gorods.DataObj, error) {
+	if err := obj.Truncate(0); err != nil {
+		return nil, bfuse.EPERM
+	}
+
+	f.fs.cache.invalidate(f.path)
+
+	if err := obj.OpenRW(); err != nil {
+		return nil, bfuse.EPERM
+	}
+
+	return obj, nil
+}
+
+// Setattr implements fs.NodeSetattrer. Only the size can be changed.
+func (f *File) Setattr(ctx context.Context, req *bfuse.SetattrRequest, resp *bfuse.SetattrResponse) error {
+	if req.Valid.Size() {
+		if err := f.fs.writable(); err != nil {
+			return err
+		}
+
+		obj, err := f.fs.con().DataObject(f.path)
+		if err != nil {
+			return bfuse.ENOENT
+		}
+
+		if err := obj.Truncate(int64(req.Size)); err != nil {
+			return bfuse.EPERM
+		}
+
+		f.fs.cache.invalidate(f.path)
+	}
+
+	return f.Attr(ctx, &resp.Attr)
+}
+
+// FileHandle is an opened data object
+type FileHandle struct {
+	file     *File
+	obj      *gorods.DataObj
+	writable bool
+	dirty    bool
+
+	mu sync.Mutex
+}
+
+// Read implements fs.HandleReader
+func (h *FileHandle) Read(ctx context.Context, req *bfuse.ReadRequest, resp *bfuse.ReadResponse) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, err := h.obj.ReadBytes(req.Offset, req.Size)
+	if err != nil {
+		return bfuse.EIO
+	}
+
+	resp.Data = data
+
+	return nil
+}
+
+// Write implements fs.HandleWriter
+func (h *FileHandle) Write(ctx context.Context, req *bfuse.WriteRequest, resp *bfuse.WriteResponse) error {
+	if !h.writable {
+		return bfuse.Errno(syscall.EBADF)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n, err := h.obj.WriteAt(req.Data, req.Offset)
+	if err != nil {
+		return bfuse.EIO
+	}
+
+	h.dirty = true
+	resp.Size = n
+
+	return nil
+}
+
+// Flush implements fs.HandleFlusher
+func (h *FileHandle) Flush(ctx context.Context, req *bfuse.FlushRequest) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.dirty {
+		h.file.fs.cache.invalidate(h.file.path)
+	}
+
+	return nil
+}
+
+// Release implements fs.HandleReleaser
+func (h *FileHandle) Release(ctx context.Context, req *bfuse.ReleaseRequest) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.obj.Close(); err != nil {
+		return bfuse.EIO
+	}
+
+	if h.dirty {
+		h.file.fs.cache.invalidate(h.file.path)
+	}
+
+	return nil
+}
+
+var (
+	_ fs.FS                 = (*FS)(nil)
+	_ fs.NodeStringLookuper = (*Dir)(nil)
+	_ fs.HandleReadDirAller = (*Dir)(nil)
+	_ fs.NodeMkdirer        = (*Dir)(nil)
+	_ fs.NodeCreater        = (*Dir)(nil)
+	_ fs.NodeRemover        = (*Dir)(nil)
+	_ fs.NodeRenamer        = (*Dir)(nil)
+	_ fs.NodeOpener         = (*File)(nil)
+	_ fs.NodeSetattrer      = (*File)(nil)
+	_ fs.HandleReader       = (*FileHandle)(nil)
+	_ fs.HandleWriter       = (*FileHandle)(nil)
+	_ fs.HandleFlusher      = (*FileHandle)(nil)
+	_ fs.HandleReleaser     = (*FileHandle)(nil)
+)
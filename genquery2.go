@@ -0,0 +1,524 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// GenQuery2Options are used with Connection.GenQuery2. Zone runs the query against a remote zone. Classic runs the
+// query with the classic general query API even if the server has GenQuery2.
+type GenQuery2Options struct {
+	Zone    string
+	Classic bool
+}
+
+// genQuery2 is a parsed GenQuery2 query. Columns are the selected expressions, as written. Limit and Offset are -1
+// when not set.
+type genQuery2 struct {
+	Columns  []string
+	Distinct bool
+	Where    string
+	GroupBy  []string
+	Having   string
+	OrderBy  []genQuery2Order
+	Limit    int
+	Offset   int
+}
+
+type genQuery2Order struct {
+	Column string
+	Desc   bool
+}
+
+// genQuery1 is a GenQuery2 query translated for the classic general query API. Keys maps the column names of the
+// classic result rows to the expressions selected by the GenQuery2 query, columns not in Keys are dropped.
+type genQuery1 struct {
+	Query  string
+	Keys   map[string]string
+	Limit  int
+	Offset int
+}
+
+var (
+	genQuery2Clause    = regexp.MustCompile(`(?i)^(select|where|group\s+by|having|order\s+by|limit|offset)\s`)
+	genQuery2Column    = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	genQuery2Aggregate = regexp.MustCompile(`(?i)^(count|sum|min|max|avg)\s*\(\s*([A-Z][A-Z0-9_]*)\s*\)$`)
+)
+
+// GenQuery2 runs a query with the GenQuery2 API of iRODS 4.3.2 and later servers, like
+// "select COLL_NAME, sum(DATA_SIZE) where COLL_NAME like '/tempZone/home/%' order by COLL_NAME desc limit 10".
+// Rows are keyed by the selected expressions, as written in the query. On older servers, or when GoRODS is built
+// against an older client library, the query is translated for the classic general query API, which supports a
+// subset: columns and count, sum, min, max and avg of columns, conditions joined by and, order by selected columns,
+// limit and offset. Rows are then distinct.
+func (con *Connection) GenQuery2(query string, opts GenQuery2Options) ([]map[string]string, error) {
+	q, err := parseGenQuery2(query)
+	if err != nil {
+		return nil, err
+	}
+
+	zone := opts.Zone
+	if zone == "" {
+		z, er := con.LocalZone()
+		if er != nil {
+			return nil, er
+		}
+
+		zone = z.Name()
+	}
+
+	var rows []map[string]string
+
+	err = con.intercept(&Event{Op: OpQuery, Query: query, Params: map[string]string{"api": "genquery2"}}, func() (err error) {
+		if opts.Classic || con.lacks(func(c Capabilities) bool { return c.GenQuery2 }) {
+			rows, err = con.genQuery1(q, zone)
+			return
+		}
+
+		var status C.int
+
+		if rows, status, err = con.genQuery2(query, q.Columns, zone); status == C.SYS_UNMATCHED_API_NUM {
+			rows, err = con.genQuery1(q, zone)
+		}
+
+		return
+	})
+
+	return rows, err
+}
+
+// genQuery2 runs query with the GenQuery2 API, returning the status of the call
+func (con *Connection) genQuery2(query string, columns []string, zone string) ([]map[string]string, C.int, error) {
+	var (
+		errMsg *C.char
+		output *C.char
+	)
+
+	cQuery := C.CString(query)
+	cZone := C.CString(zone)
+	defer C.free(unsafe.Pointer(cQuery))
+	defer C.free(unsafe.Pointer(cZone))
+
+	ccon := con.GetCcon()
+	status := C.gorods_genquery2(cQuery, cZone, &output, ccon, &errMsg)
+	con.ReturnCcon(ccon)
+
+	var js string
+
+	if output != nil {
+		js = C.GoString(output)
+		C.free(unsafe.Pointer(output))
+	}
+
+	if status < 0 {
+		return nil, status, newError(Fatal, status, fmt.Sprintf("iRODS GenQuery2 Failed: %v, %v %v", query, C.GoString(errMsg), js))
+	}
+
+	rows, err := genQuery2Rows(js, columns)
+	if err != nil {
+		return nil, status, err
+	}
+
+	return rows, status, nil
+}
+
+// genQuery2Rows converts the JSON output of the GenQuery2 API, an array of rows of values, into rows keyed by columns
+func genQuery2Rows(js string, columns []string) ([]map[string]string, error) {
+	var values [][]string
+
+	if err := json.Unmarshal([]byte(js), &values); err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GenQuery2 Failed: invalid output: %v", err))
+	}
+
+	rows := make([]map[string]string, len(values))
+
+	for i, vals := range values {
+		if len(vals) != len(columns) {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GenQuery2 Failed: expected %v columns, got %v", len(columns), len(vals)))
+		}
+
+		rows[i] = make(map[string]string, len(columns))
+
+		for n, col := range columns {
+			rows[i][col] = vals[n]
+		}
+	}
+
+	return rows, nil
+}
+
+// genQuery1 runs q with the classic general query API
+func (con *Connection) genQuery1(q *genQuery2, zone string) ([]map[string]string, error) {
+	gq, err := q.genQuery1()
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := 256
+	if gq.Limit >= 0 && gq.Limit < pageSize {
+		pageSize = gq.Limit
+	}
+
+	rows := make([]map[string]string, 0)
+
+	if gq.Limit == 0 {
+		return rows, nil
+	}
+
+	err = con.queryPages(gq.Query, QueryOptions{PageSize: pageSize, Offset: gq.Offset, Zone: zone}, nil, func(page []map[string]string) error {
+		for _, row := range page {
+			rows = append(rows, gq.row(row))
+
+			if len(rows) == gq.Limit {
+				return errPageFull
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil && err != errPageFull {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// row returns a classic result row keyed by the expressions of the GenQuery2 query
+func (gq *genQuery1) row(row map[string]string) map[string]string {
+	keyed := make(map[string]string, len(gq.Keys))
+
+	for key, expr := range gq.Keys {
+		keyed[expr] = row[key]
+	}
+
+	return keyed
+}
+
+// parseGenQuery2 splits a GenQuery2 query into its clauses
+func parseGenQuery2(query string) (*genQuery2, error) {
+	clauses, err := genQuery2Clauses(query)
+	if err != nil {
+		return nil, err
+	}
+
+	sel, ok := clauses["select"]
+	if !ok {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GenQuery2 Failed: %q has no select clause", query))
+	}
+
+	q := &genQuery2{Where: clauses["where"], Having: clauses["having"], Limit: -1, Offset: -1}
+
+	if len(sel) > 9 && strings.EqualFold(sel[:9], "distinct ") {
+		q.Distinct = true
+		sel = sel[9:]
+	}
+
+	q.Columns = genQuery2List(sel)
+
+	for _, col := range q.Columns {
+		if col == "" {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GenQuery2 Failed: empty column in %q", query))
+		}
+	}
+
+	if groupBy, ok := clauses["group by"]; ok {
+		q.GroupBy = genQuery2List(groupBy)
+	}
+
+	if orderBy, ok := clauses["order by"]; ok {
+		for _, item := range genQuery2List(orderBy) {
+			fields := strings.Fields(item)
+			order := genQuery2Order{Column: item}
+
+			if n := len(fields); n > 1 && (strings.EqualFold(fields[n-1], "asc") || strings.EqualFold(fields[n-1], "desc")) {
+				order.Column = strings.Join(fields[:n-1], " ")
+				order.Desc = strings.EqualFold(fields[n-1], "desc")
+			}
+
+			q.OrderBy = append(q.OrderBy, order)
+		}
+	}
+
+	for _, kw := range []string{"limit", "offset"} {
+		s, ok := clauses[kw]
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GenQuery2 Failed: invalid %v %q", kw, s))
+		}
+
+		if kw == "limit" {
+			q.Limit = n
+		} else {
+			q.Offset = n
+		}
+	}
+
+	return q, nil
+}
+
+// genQuery2Clauses returns the clauses of query by lowercase keyword, like "order by". Keywords within quotes or
+// parentheses are ignored. The select clause must come first.
+func genQuery2Clauses(query string) (map[string]string, error) {
+	clauses := make(map[string]string)
+
+	var (
+		keyword string
+		start   int
+		depth   int
+		quoted  bool
+	)
+
+	query = strings.TrimSpace(query)
+
+	for i := 0; i <= len(query); i++ {
+		if i < len(query) {
+			switch c := query[i]; {
+			case c == '\'':
+				quoted = !quoted
+				continue
+			case quoted:
+				continue
+			case c == '(':
+				depth++
+				continue
+			case c == ')':
+				depth--
+				continue
+			}
+
+			if depth > 0 || (i > 0 && query[i-1] != ' ' && query[i-1] != '\t' && query[i-1] != '\n') {
+				continue
+			}
+		}
+
+		var match []int
+
+		if i < len(query) {
+			if match = genQuery2Clause.FindStringSubmatchIndex(query[i:]); match == nil {
+				continue
+			}
+		}
+
+		if keyword == "" && i > 0 {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GenQuery2 Failed: %q doesn't start with select", query))
+		}
+
+		if keyword != "" {
+			if _, dup := clauses[keyword]; dup {
+				return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GenQuery2 Failed: duplicate %v clause in %q", keyword, query))
+			}
+
+			clauses[keyword] = strings.TrimSpace(query[start:i])
+		}
+
+		if match == nil {
+			break
+		}
+
+		keyword = strings.ToLower(strings.Join(strings.Fields(query[i+match[2]:i+match[3]]), " "))
+		start = i + match[1]
+		i += match[1] - 1
+	}
+
+	if quoted || depth != 0 {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GenQuery2 Failed: unbalanced quotes or parentheses in %q", query))
+	}
+
+	return clauses, nil
+}
+
+// genQuery2List splits a comma separated list, ignoring commas within quotes or parentheses
+func genQuery2List(s string) []string {
+	items := make([]string, 0)
+
+	var (
+		start  int
+		depth  int
+		quoted bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(items, strings.TrimSpace(s[start:]))
+}
+
+// genQuery1 translates the query for the classic general query API, failing if it uses features it doesn't have
+func (q *genQuery2) genQuery1() (*genQuery1, error) {
+	unsupported := func(feature string) error {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS GenQuery2 Failed: %v requires GenQuery2 (iRODS 4.3.2 or later)", feature))
+	}
+
+	if q.Having != "" {
+		return nil, unsupported("having")
+	}
+
+	gq := &genQuery1{Keys: make(map[string]string), Limit: q.Limit, Offset: q.Offset}
+
+	if gq.Offset < 0 {
+		gq.Offset = 0
+	}
+
+	selects := make([]string, 0, len(q.Columns))
+	plain := make(map[string]int)
+
+	for _, expr := range q.Columns {
+		key, sel := expr, expr
+
+		if m := genQuery2Aggregate.FindStringSubmatch(expr); m != nil {
+			key, sel = m[2], strings.ToLower(m[1])+"("+m[2]+")"
+		} else if genQuery2Column.MatchString(expr) {
+			plain[expr] = len(selects)
+		} else {
+			return nil, unsupported(fmt.Sprintf("selecting %q", expr))
+		}
+
+		if _, dup := gq.Keys[key]; dup {
+			return nil, unsupported(fmt.Sprintf("selecting %v more than once", key))
+		}
+
+		gq.Keys[key] = expr
+		selects = append(selects, sel)
+	}
+
+	// The classic API groups by every column that isn't aggregated
+	for _, col := range q.GroupBy {
+		if _, ok := plain[col]; !ok {
+			return nil, unsupported(fmt.Sprintf("grouping by %q, which isn't selected", col))
+		}
+	}
+
+	for _, order := range q.OrderBy {
+		if !genQuery2Column.MatchString(order.Column) {
+			return nil, unsupported(fmt.Sprintf("ordering by %q", order.Column))
+		}
+
+		fn := "order"
+		if order.Desc {
+			fn = "order_desc"
+		}
+
+		if n, ok := plain[order.Column]; ok {
+			selects[n] = fn + "(" + order.Column + ")"
+		} else if _, ok := gq.Keys[order.Column]; ok {
+			return nil, unsupported(fmt.Sprintf("ordering by the aggregated %v", order.Column))
+		} else {
+			// Ordering requires selecting the column, it's dropped from the rows
+			plain[order.Column] = len(selects)
+			selects = append(selects, fn+"("+order.Column+")")
+		}
+	}
+
+	gq.Query = "select " + strings.Join(selects, ", ")
+
+	if q.Where != "" {
+		where, err := genQuery1Where(q.Where)
+		if err != nil {
+			return nil, err
+		}
+
+		gq.Query += " where " + where
+	}
+
+	return gq, nil
+}
+
+// genQuery1Where translates a GenQuery2 where clause, lowercasing keywords outside quotes as the classic API expects
+func genQuery1Where(where string) (string, error) {
+	var (
+		b      strings.Builder
+		word   strings.Builder
+		quoted bool
+		prev   string
+	)
+
+	flush := func() error {
+		w := word.String()
+		word.Reset()
+
+		switch upper := strings.ToUpper(w); upper {
+		case "AND", "LIKE", "NOT", "IN", "BETWEEN":
+			w = strings.ToLower(w)
+		case "OR", "IS", "NULL", "EXISTS":
+			return newError(Fatal, -1, fmt.Sprintf("iRODS GenQuery2 Failed: %v conditions require GenQuery2 (iRODS 4.3.2 or later)", upper))
+		}
+
+		if w != "" {
+			prev = strings.ToLower(w)
+		}
+
+		b.WriteString(w)
+
+		return nil
+	}
+
+	for i := 0; i < len(where); i++ {
+		c := where[i]
+
+		if quoted {
+			b.WriteByte(c)
+			quoted = c != '\''
+			continue
+		}
+
+		if c == '_' || c == '%' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			word.WriteByte(c)
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return "", err
+		}
+
+		switch c {
+		case '\'':
+			quoted = true
+		case '(':
+			if prev != "in" {
+				return "", newError(Fatal, -1, "iRODS GenQuery2 Failed: parenthesized conditions require GenQuery2 (iRODS 4.3.2 or later)")
+			}
+		}
+
+		if c != ' ' && c != '\t' && c != '\n' {
+			prev = string(c)
+		}
+
+		b.WriteByte(c)
+	}
+
+	if err := flush(); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,100 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestParseGenQuery2(t *testing.T) {
+	q, err := parseGenQuery2("SELECT DISTINCT COLL_NAME, sum(DATA_SIZE) WHERE DATA_NAME like 'a, b order by' GROUP BY COLL_NAME ORDER BY COLL_NAME desc, DATA_NAME LIMIT 10 OFFSET 20")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !q.Distinct || len(q.Columns) != 2 || q.Columns[0] != "COLL_NAME" || q.Columns[1] != "sum(DATA_SIZE)" {
+		t.Errorf("Unexpected columns %v", q.Columns)
+	}
+
+	if q.Where != "DATA_NAME like 'a, b order by'" {
+		t.Errorf("Unexpected where clause %q", q.Where)
+	}
+
+	if len(q.GroupBy) != 1 || len(q.OrderBy) != 2 || !q.OrderBy[0].Desc || q.OrderBy[1].Desc || q.OrderBy[1].Column != "DATA_NAME" {
+		t.Errorf("Unexpected group by %v, order by %+v", q.GroupBy, q.OrderBy)
+	}
+
+	if q.Limit != 10 || q.Offset != 20 {
+		t.Errorf("Expected limit 10 offset 20, got %v %v", q.Limit, q.Offset)
+	}
+
+	for _, bad := range []string{"", "COLL_NAME where", "select COLL_NAME limit x", "select COLL_NAME where DATA_NAME = 'a", "select COLL_NAME,"} {
+		if _, err := parseGenQuery2(bad); err == nil {
+			t.Errorf("Expected an error parsing %q", bad)
+		}
+	}
+}
+
+func TestGenQuery2Translate(t *testing.T) {
+	q, err := parseGenQuery2("select COLL_NAME, count(DATA_ID) where COLL_NAME LIKE '/z/home/%' AND DATA_NAME IN ('a', 'b') order by COLL_NAME desc, DATA_OWNER_NAME limit 5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gq, err := q.genQuery1()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gq.Query != "select order_desc(COLL_NAME), count(DATA_ID), order(DATA_OWNER_NAME) where COLL_NAME like '/z/home/%' and DATA_NAME in ('a', 'b')" {
+		t.Errorf("Unexpected translation %q", gq.Query)
+	}
+
+	if gq.Limit != 5 || gq.Offset != 0 {
+		t.Errorf("Expected limit 5 offset 0, got %v %v", gq.Limit, gq.Offset)
+	}
+
+	row := gq.row(map[string]string{"COLL_NAME": "/z/home/a", "DATA_ID": "3", "DATA_OWNER_NAME": "rods"})
+
+	if len(row) != 2 || row["COLL_NAME"] != "/z/home/a" || row["count(DATA_ID)"] != "3" {
+		t.Errorf("Unexpected row %v", row)
+	}
+
+	for _, unsupported := range []string{
+		"select COLL_NAME where COLL_NAME = 'a' or COLL_NAME = 'b'",
+		"select COLL_NAME where (COLL_NAME = 'a')",
+		"select COLL_NAME where DATA_CHECKSUM is null",
+		"select COLL_NAME, count(DATA_ID) group by COLL_NAME having count(DATA_ID) > 1",
+		"select COLL_NAME, count(DATA_ID) order by count(DATA_ID)",
+		"select DATA_SIZE, sum(DATA_SIZE)",
+		"select lower(COLL_NAME)",
+	} {
+		q, err := parseGenQuery2(unsupported)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := q.genQuery1(); err == nil {
+			t.Errorf("Expected %q to require GenQuery2", unsupported)
+		}
+	}
+}
+
+func TestGenQuery2Rows(t *testing.T) {
+	rows, err := genQuery2Rows(`[["/z/home/a","12"],["/z/home/b","0"]]`, []string{"COLL_NAME", "sum(DATA_SIZE)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[1]["COLL_NAME"] != "/z/home/b" || rows[0]["sum(DATA_SIZE)"] != "12" {
+		t.Errorf("Unexpected rows %v", rows)
+	}
+
+	if _, err := genQuery2Rows(`[["/z/home/a"]]`, []string{"COLL_NAME", "DATA_NAME"}); err == nil {
+		t.Error("Expected an error for rows with missing columns")
+	}
+}
@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, The BioTeam, Inc.                     ***
  *** For more information please refer to the LICENSE.md file  ***/
 
@@ -448,6 +451,9 @@ func (grp *Group) Meta() (*MetaCollection, error) {
 }
 
 func addToGroup(userName string, zone *Zone, groupName string, con *Connection) error {
+	if er := con.requireGroupAdmin("AddToGroup"); er != nil {
+		return er
+	}
 
 	var (
 		err *C.char
@@ -471,6 +477,10 @@ func addToGroup(userName string, zone *Zone, groupName string, con *Connection)
 }
 
 func removeFromGroup(userName string, zone *Zone, groupName string, con *Connection) error {
+	if er := con.requireGroupAdmin("RemoveFromGroup"); er != nil {
+		return er
+	}
+
 	var (
 		err *C.char
 	)
@@ -493,6 +503,10 @@ func removeFromGroup(userName string, zone *Zone, groupName string, con *Connect
 }
 
 func deleteGroup(groupName string, zone *Zone, con *Connection) error {
+	if er := con.requireGroupAdmin("DeleteGroup"); er != nil {
+		return er
+	}
+
 	var (
 		err *C.char
 	)
@@ -513,6 +527,10 @@ func deleteGroup(groupName string, zone *Zone, con *Connection) error {
 }
 
 func createGroup(groupName string, zone *Zone, con *Connection) error {
+	if er := con.requireGroupAdmin("CreateGroup"); er != nil {
+		return er
+	}
+
 	var (
 		err *C.char
 	)
@@ -0,0 +1,113 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Ping makes a no-op API call (rcGetMiscSvrInfo) to check that the server is reachable over the connection
+func (con *Connection) Ping() error {
+	var err *C.char
+
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	if status := C.gorods_ping(ccon, &err); status < 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS Ping Failed: %v", C.GoString(err)))
+	}
+
+	return nil
+}
+
+// Reconnects returns the number of times a dead connection handle was replaced by the idle check
+func (con *Connection) Reconnects() int {
+	return int(atomic.LoadInt32(&con.reconnects))
+}
+
+// IdleFor returns the time elapsed since the connection handle was last used
+func (con *Connection) IdleFor() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&con.lastUsed)))
+}
+
+// checkIdle is called by GetCcon with the checked out handle. If ConnectionOptions.IdleCheck is set and the handle
+// has been idle for longer, it is checked (socket state, plus a ping if IdlePing is set) and replaced if it is dead.
+// Handles which may have hit a socket timeout are always checked with a ping.
+func (con *Connection) checkIdle(ccon *C.rcComm_t) *C.rcComm_t {
+	if con.Options == nil || !con.Connected {
+		return ccon
+	}
+
+	suspect := atomic.CompareAndSwapInt32(&con.suspect, 1, 0)
+
+	if !suspect && (con.Options.IdleCheck <= 0 || con.IdleFor() < con.Options.IdleCheck) {
+		return ccon
+	}
+
+	if C.gorods_socket_alive(ccon) != 0 {
+		if !con.Options.IdlePing && !suspect {
+			return ccon
+		}
+
+		var err *C.char
+
+		if status := C.gorods_ping(ccon, &err); status >= 0 {
+			return ccon
+		}
+	}
+
+	return con.redial(ccon)
+}
+
+// redial replaces the dead handle old with a new, authenticated one, and restores the session settings.
+// The caller must hold the handle. If the server can't be reached old is returned, so the caller's
+// operation fails with the usual error. Data objects that were open on the old handle must be reopened.
+func (con *Connection) redial(old *C.rcComm_t) *C.rcComm_t {
+	if err := con.dial(); err != nil {
+		con.logf("unable to replace dead connection to %v: %v", con.Options.Host, err)
+		con.ccon = old
+		return old
+	}
+
+	C.rcDisconnect(old)
+
+	con.ccon.transStat.numThreads = C.int(con.Options.Threads)
+
+	if con.Options.Ticket != "" {
+		var err *C.char
+
+		session := C.CString("session")
+		ticket := C.CString(con.Options.Ticket)
+		empty := C.CString("")
+		defer C.free(unsafe.Pointer(session))
+		defer C.free(unsafe.Pointer(ticket))
+		defer C.free(unsafe.Pointer(empty))
+
+		if status := C.gorods_ticket_admin(session, ticket, empty, empty, empty, empty, con.ccon, &err); status < 0 {
+			con.logf("unable to restore session ticket after reconnecting to %v: %v", con.Options.Host, status)
+		}
+	}
+
+	atomic.AddInt32(&con.reconnects, 1)
+
+	con.log(LogWarn, "reconnected", "host", con.Options.Host, "reconnects", con.Reconnects())
+
+	return con.ccon
+}
+
+// refresh runs the idle check on the connection handle without using it, so a handle that died while idle is
+// replaced before data objects check whether their own handle is stale
+func (con *Connection) refresh() {
+	ccon := con.checkIdle(<-con.cconBuffer)
+	con.cconBuffer <- ccon
+}
@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
  *** For more information please refer to the LICENSE.md file                                   ***/
 
@@ -9,6 +12,7 @@ import "C"
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 	"unsafe"
 )
@@ -117,6 +121,20 @@ func getTypeString(typ int) string {
 	}
 }
 
+// accessLevelOf converts a catalog access name ("own", "modify object", "read object") to Own, Write, Read or Null
+func accessLevelOf(accessString string) int {
+	switch accessString {
+	case "own":
+		return Own
+	case "modify object":
+		return Write
+	case "read object":
+		return Read
+	default:
+		return Null
+	}
+}
+
 func aclSliceToResponse(result *C.goRodsACLResult_t, con *Connection) (ACLs, error) {
 	defer C.gorods_free_acl_result(result)
 
@@ -132,57 +150,27 @@ func aclSliceToResponse(result *C.goRodsACLResult_t, con *Connection) (ACLs, err
 
 		typeString := C.GoString(acl.acltype)
 
-		typeMap := map[string]int{
-			"rodsgroup":  GroupType,
-			"rodsuser":   UserType,
-			"rodsadmin":  AdminType,
-			"groupadmin": GroupAdminType,
-		}
-		var aclType int = typeMap[typeString]
-		if aclType == 0 {
-			aclType = UnknownType
-		}
+		aclType := ParseUserType(typeString)
+		accessLevel := accessLevelOf(C.GoString(acl.dataAccess))
 
-		accessString := C.GoString(acl.dataAccess)
-		var accessLevel int
-		switch accessString {
-		case "own":
-			accessLevel = Own
-		case "modify object":
-			accessLevel = Write
-		case "read object":
-			accessLevel = Read
-		default:
-			accessLevel = Null
+		principal := Principal{
+			Name: C.GoString(acl.name),
+			Zone: C.GoString(acl.zone),
+			Type: aclType,
 		}
 
-		var accessObject AccessObject
-		if aclType == UserType || aclType == AdminType || aclType == GroupAdminType {
-			if usrs, err := con.Users(); err == nil {
-				if existingUsr := usrs.FindByName(C.GoString(acl.name), con); existingUsr != nil {
-					accessObject = existingUsr
-				} else {
-					return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GetACL Failed: can't find iRODS user by string"))
-				}
-			} else {
-				return nil, err
-			}
-		} else if aclType == GroupType {
-			if grps, err := con.Groups(); err == nil {
-				if existingGrp := grps.FindByName(C.GoString(acl.name), con); existingGrp != nil {
-					accessObject = existingGrp
-				} else {
-					return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GetACL Failed: can't find iRODS group by string"))
-				}
-			} else {
-				return nil, err
-			}
-		} else if aclType == UnknownType {
+		if aclType == UnknownType {
 			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GetACL Failed: Unknown Type"))
 		}
 
+		accessObject, err := principalAccessObject(principal, con)
+		if err != nil {
+			return nil, err
+		}
+
 		response = append(response, &ACL{
 			AccessObject: accessObject,
+			Principal:    principal,
 			AccessLevel:  accessLevel,
 			Type:         aclType,
 		})
@@ -192,6 +180,78 @@ func aclSliceToResponse(result *C.goRodsACLResult_t, con *Connection) (ACLs, err
 	return response, nil
 }
 
+// principalAccessObject finds the *User or *Group matching both the name and zone of the principal.
+// Principals from other zones that aren't known locally get a new *User or *Group in that zone.
+func principalAccessObject(p Principal, con *Connection) (AccessObject, error) {
+	var zone *Zone
+
+	if p.Zone != "" {
+		zones, err := con.Zones()
+		if err != nil {
+			return nil, err
+		}
+
+		zone = zones.FindByName(p.Zone, con)
+	}
+
+	if p.IsGroup() {
+		grps, err := con.Groups()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, grp := range grps {
+			if grp.name == p.Name && (grp.zone == nil || grp.zone.Name() == p.Zone) {
+				return grp, nil
+			}
+		}
+
+		grp, err := initGroup(p.Name, con)
+		if err != nil {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GetACL Failed: can't find iRODS group %v", p))
+		}
+
+		if zone != nil {
+			grp.zone = zone
+		}
+
+		return grp, nil
+	}
+
+	usrs, err := con.Users()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, usr := range usrs {
+		if usr.name == p.Name && (usr.zone == nil || usr.zone.Name() == p.Zone) {
+			return usr, nil
+		}
+	}
+
+	if zone != nil {
+		usr, _ := initUser(p.Name, zone, con)
+		usr.typ = p.Type
+
+		return usr, nil
+	}
+
+	if usr := usrs.FindByName(p.Name, con); usr != nil {
+		return usr, nil
+	}
+
+	return nil, newError(Fatal, -1, fmt.Sprintf("iRODS GetACL Failed: can't find iRODS user %v", p))
+}
+
+// pathZoneName returns the zone name of an absolute iRODS path (it's first element), or an empty string for relative paths
+func pathZoneName(p string) string {
+	if len(p) < 2 || p[0] != '/' {
+		return ""
+	}
+
+	return strings.SplitN(p[1:], "/", 2)[0]
+}
+
 func isString(obj interface{}) bool {
 	switch obj.(type) {
 	case string:
@@ -201,3 +261,17 @@ func isString(obj interface{}) bool {
 
 	return false
 }
+
+// resourceName resolves a resource parameter, which can be a string or *Resource, to the resource name. nil returns "".
+func resourceName(resource interface{}) (string, error) {
+	switch r := resource.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return r, nil
+	case *Resource:
+		return r.Name(), nil
+	default:
+		return "", newError(Fatal, -1, fmt.Sprintf("Wrong variable type passed in Resource field"))
+	}
+}
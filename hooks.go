@@ -0,0 +1,207 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Operation constants, used in Event.Op and when registering hooks
+const (
+	OpOpen = iota
+	OpPut
+	OpDelete
+	OpQuery
+	OpMkdir
+	OpMove
+	OpCopy
+	OpMeta
+	OpChmod
+	OpTouch
+	OpChange
+	OpTruncate
+)
+
+// Event describes an operation passed to hooks. Path is set for every operation except OpQuery, which sets Query.
+// Dest is the new path for OpMove and OpCopy. Params holds the other arguments of mutating operations, as logged
+// by ChangeLog, and the source of OpChange events (see Connection.ObserveChange). Size is the data size for OpPut, if known. Duration and Err are only set when After hooks are called.
+type Event struct {
+	Op       int
+	Path     string
+	Dest     string
+	Query    string
+	Params   map[string]string
+	Size     int64
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+	Con      *Connection
+
+	// noRetry disables policy retries for operations that can't safely be re-run, like paged queries
+	noRetry bool
+}
+
+// Hook is a pair of functions called around an operation. Before can veto the operation by returning an error,
+// which is returned to the caller of the operation. After is called once the operation completes (or fails). Either can be nil.
+type Hook struct {
+	Before func(*Event) error
+	After  func(*Event)
+}
+
+// Hooks holds the hooks registered for each operation. It is safe for use by multiple goroutines.
+// Set ConnectionOptions.Hooks to share hooks between connections (e.g. those opened by a Client).
+type Hooks struct {
+	hooks map[int][]Hook
+	mu    sync.RWMutex
+}
+
+// NewHooks returns an empty *Hooks
+func NewHooks() *Hooks {
+	h := new(Hooks)
+	h.hooks = make(map[int][]Hook)
+
+	return h
+}
+
+// Add registers hook for the operation op (one of the Op* constants)
+func (h *Hooks) Add(op int, hook Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.hooks == nil {
+		h.hooks = make(map[int][]Hook)
+	}
+
+	h.hooks[op] = append(h.hooks[op], hook)
+}
+
+// OnOpen registers a hook called when data objects are opened
+func (h *Hooks) OnOpen(hook Hook) {
+	h.Add(OpOpen, hook)
+}
+
+// OnPut registers a hook called when data objects are created or uploaded
+func (h *Hooks) OnPut(hook Hook) {
+	h.Add(OpPut, hook)
+}
+
+// OnDelete registers a hook called when data objects or collections are removed
+func (h *Hooks) OnDelete(hook Hook) {
+	h.Add(OpDelete, hook)
+}
+
+// OnQuery registers a hook called for general and metadata queries
+func (h *Hooks) OnQuery(hook Hook) {
+	h.Add(OpQuery, hook)
+}
+
+// OnChange registers a hook called when changes made by other clients are observed. Only After is called.
+func (h *Hooks) OnChange(hook Hook) {
+	h.Add(OpChange, hook)
+}
+
+func (h *Hooks) get(op int) []Hook {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.hooks[op]
+}
+
+// Hooks returns the hooks used by the connection, for registering new ones.
+// These are the ConnectionOptions.Hooks if set, otherwise a set private to this connection.
+func (con *Connection) Hooks() *Hooks {
+	con.hooksOnce.Do(func() {
+		if con.Options != nil && con.Options.Hooks != nil {
+			con.hooks = con.Options.Hooks
+		} else {
+			con.hooks = NewHooks()
+		}
+	})
+
+	return con.hooks
+}
+
+// intercept runs fn surrounded by the hooks registered for e.Op
+func (con *Connection) intercept(e *Event, fn func() error) error {
+	hooks := con.Hooks().get(e.Op)
+
+	e.Con = con
+
+	if err := checkPolicy(e); err != nil {
+		return err
+	}
+
+	for _, h := range hooks {
+		if h.Before == nil {
+			continue
+		}
+
+		if err := h.Before(e); err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Operation Vetoed: %v, %v", opName(e.Op), err))
+		}
+	}
+
+	e.Start = time.Now()
+	e.Err = runWithRetry(e, fn)
+	e.Duration = time.Since(e.Start)
+
+	con.observeDuration(e)
+	con.logEvent(e)
+
+	if e.Op == OpPut || e.Op == OpDelete {
+		con.InvalidateCache(e.Path)
+	}
+
+	if cl := con.changeLog(); cl != nil && isMutation(e.Op) {
+		cl.record(e)
+	}
+
+	if r := con.recorder(); r != nil {
+		r.ObserveOperation(opName(e.Op), e.Duration, e.Err)
+	}
+
+	for _, h := range hooks {
+		if h.After != nil {
+			h.After(e)
+		}
+	}
+
+	return e.Err
+}
+
+func opName(op int) string {
+	switch op {
+	case OpOpen:
+		return "open"
+	case OpPut:
+		return "put"
+	case OpDelete:
+		return "delete"
+	case OpQuery:
+		return "query"
+	case OpMkdir:
+		return "mkdir"
+	case OpMove:
+		return "move"
+	case OpCopy:
+		return "copy"
+	case OpMeta:
+		return "meta"
+	case OpChmod:
+		return "chmod"
+	case OpTouch:
+		return "touch"
+	case OpChange:
+		return "change"
+	case OpTruncate:
+		return "truncate"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,90 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestHookOrdering(t *testing.T) {
+	con := new(Connection)
+
+	var calls []string
+
+	for _, name := range []string{"first", "second"} {
+		name := name
+
+		con.Hooks().OnQuery(Hook{
+			Before: func(e *Event) error {
+				calls = append(calls, "before "+name)
+				return nil
+			},
+			After: func(e *Event) {
+				calls = append(calls, "after "+name)
+
+				if e.Err == nil || e.Con != con {
+					t.Errorf("Expected the after hook to see the result, got %+v", e)
+				}
+			},
+		})
+	}
+
+	// Hooks for other operations aren't called
+	con.Hooks().OnDelete(Hook{After: func(e *Event) {
+		calls = append(calls, "delete")
+	}})
+
+	failed := errors.New("query failed")
+
+	err := con.intercept(&Event{Op: OpQuery, Query: "select DATA_NAME"}, func() error {
+		calls = append(calls, "query")
+		return failed
+	})
+
+	if err != failed {
+		t.Errorf("Expected the operation's error, got %v", err)
+	}
+
+	expected := []string{"before first", "before second", "query", "after first", "after second"}
+
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, calls)
+	}
+}
+
+func TestHookVeto(t *testing.T) {
+	con := new(Connection)
+
+	afterCalled := false
+
+	con.Hooks().OnQuery(Hook{Before: func(e *Event) error {
+		return errors.New("not allowed")
+	}})
+
+	con.Hooks().OnQuery(Hook{
+		Before: func(e *Event) error {
+			t.Error("Expected the hooks after a veto to be skipped")
+			return nil
+		},
+		After: func(e *Event) {
+			afterCalled = true
+		},
+	})
+
+	called := false
+
+	err := con.intercept(&Event{Op: OpQuery, Query: "select DATA_NAME"}, func() error {
+		called = true
+		return nil
+	})
+
+	if err == nil || called || afterCalled {
+		t.Errorf("Expected the operation to be vetoed, got %v (called %v, after %v)", err, called, afterCalled)
+	}
+}
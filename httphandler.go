@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, The BioTeam, Inc.                     ***
  *** For more information please refer to the LICENSE.md file  ***/
 
@@ -11,7 +14,6 @@ import (
 	"fmt"
 	"html/template"
 	"io"
-	"log"
 	"mime"
 	"mime/multipart"
 	"net/http"
@@ -73,10 +75,19 @@ type HttpHandler struct {
 
 var check func(error) = func(err error) {
 	if err != nil {
-		log.Print(err)
+		Log(LogError, "HTTP request failed", "error", err)
 	}
 }
 
+// log writes msg and the key-value pairs in args at level to the Logger set with SetLogger, with the path requested
+func (handler *HttpHandler) log(level int, msg string, args ...interface{}) {
+	if handler.request != nil {
+		args = append([]interface{}{"url", handler.request.URL.Path}, args...)
+	}
+
+	Log(level, msg, args...)
+}
+
 var tpl = `
 <!DOCTYPE html>
 <html lang="en">
@@ -132,7 +143,7 @@ var tpl = `
 
 	<script type="text/javascript">
 
-	var me = {{ .Con.Options.Username }};
+	var me = {{ .Con.ProxyUser }};
 	var users = {{ usersJSON }};
 	var groups = {{ groupsJSON }};
 
@@ -1020,7 +1031,7 @@ func (handler *HttpHandler) ServeDataObj(obj *DataObj) {
 					// should we only get last bytes
 					if firstByte == "" {
 						if lastByteN, convErr = strconv.ParseInt(lastByte, 10, 64); convErr != nil {
-							log.Print("Error parsing byte range")
+							handler.log(LogWarn, "unable to parse the byte range", "range", rangeHeader)
 							return
 						}
 
@@ -1028,19 +1039,19 @@ func (handler *HttpHandler) ServeDataObj(obj *DataObj) {
 						lastByteN = obj.Size() - 1
 					} else if lastByte == "" {
 						if firstByteN, convErr = strconv.ParseInt(firstByte, 10, 64); convErr != nil {
-							log.Print("Error parsing byte range")
+							handler.log(LogWarn, "unable to parse the byte range", "range", rangeHeader)
 							return
 						}
 
 						lastByteN = obj.Size() - 1
 					} else {
 						if firstByteN, convErr = strconv.ParseInt(firstByte, 10, 64); convErr != nil {
-							log.Print("Error parsing byte range")
+							handler.log(LogWarn, "unable to parse the byte range", "range", rangeHeader)
 							return
 						}
 
 						if lastByteN, convErr = strconv.ParseInt(lastByte, 10, 64); convErr != nil {
-							log.Print("Error parsing byte range")
+							handler.log(LogWarn, "unable to parse the byte range", "range", rangeHeader)
 							return
 						}
 					}
@@ -1056,11 +1067,11 @@ func (handler *HttpHandler) ServeDataObj(obj *DataObj) {
 						})
 
 					} else {
-						log.Print(err)
+						handler.log(LogError, "HTTP request failed", "error", err)
 					}
 
 				} else {
-					log.Print("Error parsing byte range")
+					handler.log(LogWarn, "unable to parse the byte range", "range", rangeHeader)
 				}
 			}
 
@@ -1083,7 +1094,7 @@ func (handler *HttpHandler) ServeDataObj(obj *DataObj) {
 					headers.Add("Content-Range", outputSegment.ContentRange)
 
 					if writer, err := mpWriter.CreatePart(headers); err != nil {
-						log.Print(err)
+						handler.log(LogError, "HTTP request failed", "error", err)
 						continue
 					} else {
 						writer.Write(outputSegment.ByteContent)
@@ -1120,7 +1131,7 @@ func (handler *HttpHandler) ServeDataObj(obj *DataObj) {
 			if readEr := obj.ReadChunkFree(10240000, func(chunk *ByteArr) {
 				outBuff <- chunk
 			}); readEr != nil {
-				log.Print(readEr)
+				handler.log(LogError, "HTTP request failed", "error", readEr)
 
 				handler.response.WriteHeader(http.StatusInternalServerError)
 				handler.response.Write([]byte("Error: " + readEr.Error()))
@@ -1147,7 +1158,7 @@ func (handler *HttpHandler) getObjMime(obj *DataObj) string {
 		mimeType = mime.TypeByExtension(ext)
 
 		if mimeType == "" {
-			log.Printf("Can't find mime type for %s extension", ext)
+			handler.log(LogDebug, "unknown mime type", "extension", ext)
 			mimeType = "application/octet-stream"
 		}
 	} else {
@@ -1421,7 +1432,7 @@ func (handler *HttpHandler) Upload(col *Collection) {
 
 			if pErr != nil {
 				response.Message = pErr.Error()
-				log.Print(pErr)
+				handler.log(LogError, "HTTP request failed", "error", pErr)
 				break MPLoop
 			}
 
@@ -1440,7 +1451,7 @@ func (handler *HttpHandler) Upload(col *Collection) {
 					n, fErr := part.Read(contents)
 
 					if fErr != nil && fErr != io.EOF {
-						log.Print(fErr.Error())
+						handler.log(LogError, "HTTP request failed", "error", fErr)
 						panic(fErr)
 					}
 
@@ -1452,7 +1463,7 @@ func (handler *HttpHandler) Upload(col *Collection) {
 						response.Message = wEr.Error()
 						response.Success = false
 
-						log.Print(wEr)
+						handler.log(LogError, "HTTP request failed", "error", wEr)
 
 						break ReadLoop
 					}
@@ -1461,22 +1472,22 @@ func (handler *HttpHandler) Upload(col *Collection) {
 				obj.Close()
 
 			} else {
-				log.Print(cEr)
+				handler.log(LogError, "HTTP request failed", "error", cEr)
 				response.Message = cEr.Error()
 			}
 		}
 
 	} else {
-		log.Print(err)
+		handler.log(LogError, "HTTP request failed", "error", err)
 		response.Message = err.Error()
 	}
 
 	if jsonBytes, jErr := json.Marshal(response); jErr == nil {
 		if _, wErr := handler.response.Write(jsonBytes); wErr != nil {
-			log.Print(wErr)
+			handler.log(LogError, "HTTP request failed", "error", wErr)
 		}
 	} else {
-		log.Print(jErr)
+		handler.log(LogError, "HTTP request failed", "error", jErr)
 	}
 }
 
@@ -1521,11 +1532,11 @@ func (handler *HttpHandler) ServeHTTP(response http.ResponseWriter, request *htt
 					}
 
 					if cErr := obj.Close(); cErr != nil {
-						log.Print(cErr)
+						handler.log(LogError, "HTTP request failed", "error", cErr)
 					}
 
 				} else {
-					log.Print(er)
+					handler.log(LogError, "HTTP request failed", "error", er)
 				}
 			} else if objType == CollectionType {
 
@@ -1575,11 +1586,11 @@ func (handler *HttpHandler) ServeHTTP(response http.ResponseWriter, request *htt
 					}
 
 					if cErr := col.Close(); cErr != nil {
-						log.Print(cErr)
+						handler.log(LogError, "HTTP request failed", "error", cErr)
 					}
 
 				} else {
-					log.Print(er)
+					handler.log(LogError, "HTTP request failed", "error", er)
 				}
 			}
 
@@ -1587,14 +1598,14 @@ func (handler *HttpHandler) ServeHTTP(response http.ResponseWriter, request *htt
 
 			handler.Serve404()
 
-			log.Print(err)
+			handler.log(LogError, "HTTP request failed", "error", err)
 		}
 
 	}
 
 	if handler.client != nil {
 		if er := handler.client.OpenConnection(handlerMain); er != nil {
-			log.Print(er)
+			handler.log(LogError, "HTTP request failed", "error", er)
 			return
 		}
 	} else if handler.connection != nil {
@@ -0,0 +1,63 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"os"
+	"sync"
+)
+
+// spOption is the environment variable rcConnect reads the client program name from (SP_OPTION in rodsDef.h).
+// The server records it with the connection, so it shows up in ips, the server log and audit plugins.
+const spOption = "spOption"
+
+// spOptionMu serializes connects, since the environment is shared by the whole process: rcConnect reads spOption
+// from a C thread, which would race with another connect setting it
+var spOptionMu sync.Mutex
+
+// setProgramName sets spOption to name for the next rcConnect, and returns a function restoring the previous value.
+// Every connect calls it, even with an empty name, so no rcConnect runs while another sets spOption. The returned
+// function must be called once the connection is established, it is safe to call more than once.
+// spOption isn't changed if name is empty, rcConnect then reports the environment's value or its own default.
+func setProgramName(name string) func() {
+	spOptionMu.Lock()
+
+	var once sync.Once
+
+	if name == "" {
+		return func() {
+			once.Do(spOptionMu.Unlock)
+		}
+	}
+
+	prev, wasSet := os.LookupEnv(spOption)
+	os.Setenv(spOption, name)
+
+	return func() {
+		once.Do(func() {
+			if wasSet {
+				os.Setenv(spOption, prev)
+			} else {
+				os.Unsetenv(spOption)
+			}
+
+			spOptionMu.Unlock()
+		})
+	}
+}
+
+// ProgramName returns the client program name the connection reported to the server, set with
+// ConnectionOptions.ProgramName, like "my-ingest-service v2.3"
+func (con *Connection) ProgramName() string {
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	return C.GoString(&ccon.option[0])
+}
@@ -0,0 +1,71 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSetProgramName(t *testing.T) {
+	prev, wasSet := os.LookupEnv(spOption)
+	defer func() {
+		if wasSet {
+			os.Setenv(spOption, prev)
+		} else {
+			os.Unsetenv(spOption)
+		}
+	}()
+
+	os.Setenv(spOption, "from-env")
+
+	restore := setProgramName("ingest v2.3")
+
+	if v := os.Getenv(spOption); v != "ingest v2.3" {
+		t.Errorf("Expected the program name to be set, got %q", v)
+	}
+
+	// Other connects wait until the connect reading spOption is done with it
+	acquired := make(chan func())
+
+	go func() {
+		acquired <- setProgramName("")
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected a concurrent connect to wait for the program name to be restored")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	restore()
+	restore()
+
+	if v := os.Getenv(spOption); v != "from-env" {
+		t.Errorf("Expected the previous value to be restored, got %q", v)
+	}
+
+	select {
+	case next := <-acquired:
+		// An empty name leaves the environment's value
+		if v := os.Getenv(spOption); v != "from-env" {
+			t.Errorf("Expected an empty name to leave spOption alone, got %q", v)
+		}
+
+		next()
+	case <-time.After(time.Second):
+		t.Fatal("Expected the waiting connect to proceed once the program name was restored")
+	}
+
+	os.Unsetenv(spOption)
+	setProgramName("ingest v2.3")()
+
+	if _, ok := os.LookupEnv(spOption); ok {
+		t.Error("Expected spOption to be unset again")
+	}
+}
@@ -0,0 +1,93 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"strings"
+)
+
+// InheritAttrPrefix marks a collection AVU as inheritable. An AVU "gorods:inherit:project" = "alpha" on a collection
+// is applied as "project" = "alpha" to data objects and collections created in it through GoRODS,
+// when ConnectionOptions.InheritMeta is enabled. Sub collections also receive the inheritable AVU, so defaults propagate down the tree.
+const InheritAttrPrefix = "gorods:inherit:"
+
+// AddInheritableMeta adds an inheritable AVU to the collection. m.Attribute should not include InheritAttrPrefix.
+func (col *Collection) AddInheritableMeta(m Meta) (*Meta, error) {
+	m.Attribute = InheritAttrPrefix + m.Attribute
+
+	return col.AddMeta(m)
+}
+
+// DeleteInheritableMeta removes the inheritable AVUs with the attribute specified (without InheritAttrPrefix)
+func (col *Collection) DeleteInheritableMeta(attr string) (*MetaCollection, error) {
+	return col.DeleteMeta(InheritAttrPrefix + attr)
+}
+
+// InheritableMeta returns the collection's inheritable AVUs, with InheritAttrPrefix removed from their attributes
+func (col *Collection) InheritableMeta() ([]Meta, error) {
+	mc, err := col.Meta()
+	if err != nil {
+		return nil, err
+	}
+
+	metas := make([]Meta, 0)
+
+	for _, m := range mc.Metas {
+		if strings.HasPrefix(m.Attribute, InheritAttrPrefix) {
+			metas = append(metas, Meta{
+				Attribute: strings.TrimPrefix(m.Attribute, InheritAttrPrefix),
+				Value:     m.Value,
+				Units:     m.Units,
+			})
+		}
+	}
+
+	return metas, nil
+}
+
+// ApplyInheritableMeta adds the collection's inheritable AVUs to obj, which is usually contained in the collection.
+// Attributes obj already has are left untouched, so existing values take precedence over the defaults.
+func (col *Collection) ApplyInheritableMeta(obj IRodsObj) error {
+	inherited, err := col.InheritableMeta()
+	if err != nil || len(inherited) == 0 {
+		return err
+	}
+
+	mc, err := obj.Meta()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range inherited {
+		if _, er := mc.Get(m.Attribute); er != nil {
+			if _, er := mc.Add(m); er != nil {
+				return er
+			}
+		}
+
+		if obj.Type() == CollectionType {
+			attr := InheritAttrPrefix + m.Attribute
+
+			if _, er := mc.Get(attr); er != nil {
+				if _, er := mc.Add(Meta{Attribute: attr, Value: m.Value, Units: m.Units}); er != nil {
+					return er
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// inheritMeta applies inheritable AVUs to newly created objects if ConnectionOptions.InheritMeta is enabled
+func (col *Collection) inheritMeta(obj IRodsObj) error {
+	if obj == nil || col.con.Options == nil || !col.con.Options.InheritMeta {
+		return nil
+	}
+
+	return col.ApplyInheritableMeta(obj)
+}
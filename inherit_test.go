@@ -0,0 +1,92 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestInheritableMeta(t *testing.T) {
+	client, conErr := New(ConnectionOptions{
+		Type: UserDefined,
+
+		Host: "localhost",
+		Port: 1247,
+		Zone: "tempZone",
+
+		Username: "rods",
+		Password: "password",
+
+		InheritMeta: true,
+	})
+
+	if conErr != nil {
+		t.Fatal(conErr)
+	}
+
+	if openErr := client.OpenCollection(CollectionOptions{
+		Path: "/tempZone/home/rods",
+	}, func(col *Collection, con *Connection) {
+		parent, err := col.CreateSubCollection("inherit_test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer parent.Rm(true, true)
+
+		if _, err := parent.AddInheritableMeta(Meta{Attribute: "project", Value: "alpha"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if metas, err := parent.InheritableMeta(); err != nil || len(metas) != 1 || metas[0].Attribute != "project" || metas[0].Value != "alpha" {
+			t.Fatalf("Unexpected inheritable AVUs %v, %v", metas, err)
+		}
+
+		do, err := parent.CreateDataObj(DataObjOptions{Name: "a.txt"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mc, err := do.Meta()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m, err := mc.First("project"); err != nil || m.Value != "alpha" {
+			t.Errorf("Expected the data object to inherit project = alpha, got %v, %v", m, err)
+		}
+
+		if _, err := mc.First(InheritAttrPrefix + "project"); err == nil {
+			t.Error("Expected data objects not to receive the inheritable AVU itself")
+		}
+
+		// Sub collections pass the default on
+		sub, err := parent.CreateSubCollection("sub")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if mc, err = sub.Meta(); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, attr := range []string{"project", InheritAttrPrefix + "project"} {
+			if m, err := mc.First(attr); err != nil || m.Value != "alpha" {
+				t.Errorf("Expected the sub collection to have %v = alpha, got %v, %v", attr, m, err)
+			}
+		}
+
+		if _, err := parent.DeleteInheritableMeta("project"); err != nil {
+			t.Fatal(err)
+		}
+
+		if metas, err := parent.InheritableMeta(); err != nil || len(metas) != 0 {
+			t.Errorf("Expected the inheritable AVU to be removed, got %v, %v", metas, err)
+		}
+	}); openErr != nil {
+		t.Fatal(openErr)
+	}
+}
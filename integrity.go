@@ -0,0 +1,286 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Integrity issue kinds, used in IntegrityIssue.Kind
+const (
+	IntegrityMismatch = iota
+	IntegrityMissing
+	IntegrityStale
+	IntegrityNoChecksum
+	IntegrityError
+)
+
+// IntegrityOptions are used with Collection.Scan. MinReplicas reports data objects with fewer good replicas as
+// missing replicas. Resource limits the verification to replicas on the named resource. SkipVerify only checks the
+// catalog (stale replicas, replica counts and checksums), without asking the server to read the replicas.
+// Progress, if set, is called after each data object with the report so far.
+type IntegrityOptions struct {
+	MinReplicas int
+	Resource    string
+	SkipVerify  bool
+	Progress    func(*IntegrityReport)
+}
+
+// IntegrityIssue is a problem found by Collection.Scan. ReplNum is -1 for issues of the data object as a whole,
+// like missing replicas.
+type IntegrityIssue struct {
+	Kind     int
+	Path     string
+	ReplNum  int
+	Resource string
+	Checksum string
+	Err      error
+}
+
+// String returns a short description of the issue
+func (i *IntegrityIssue) String() string {
+	where := fmt.Sprintf("%v (repl %v on %v)", i.Path, i.ReplNum, i.Resource)
+
+	switch i.Kind {
+	case IntegrityMismatch:
+		return fmt.Sprintf("%v: checksum mismatch, catalog %v", where, i.Checksum)
+	case IntegrityMissing:
+		if i.ReplNum < 0 {
+			return fmt.Sprintf("%v: %v", i.Path, i.Err)
+		}
+		return fmt.Sprintf("%v: physical file missing", where)
+	case IntegrityStale:
+		return fmt.Sprintf("%v: stale replica", where)
+	case IntegrityNoChecksum:
+		return fmt.Sprintf("%v: no checksum", where)
+	default:
+		return fmt.Sprintf("%v: %v", where, i.Err)
+	}
+}
+
+// IntegrityReport is returned by Collection.Scan. Verified is the number of replicas whose checksum the server
+// verified successfully, Replicas the number examined.
+type IntegrityReport struct {
+	Start    time.Time
+	End      time.Time
+	Objects  int
+	Replicas int
+	Verified int
+	Issues   []*IntegrityIssue
+}
+
+// OK returns true if no issue was found
+func (r *IntegrityReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// ByKind returns the issues of the kind specified
+func (r *IntegrityReport) ByKind(kind int) []*IntegrityIssue {
+	issues := make([]*IntegrityIssue, 0)
+
+	for _, i := range r.Issues {
+		if i.Kind == kind {
+			issues = append(issues, i)
+		}
+	}
+
+	return issues
+}
+
+// integrityReplica is a replica as listed by the catalog
+type integrityReplica struct {
+	ReplNum  int
+	Status   string
+	Checksum string
+	Resource string
+}
+
+// Scan checks the integrity of every data object within the collection (recursively): the server recomputes the
+// checksum of each replica and compares it with the catalog's, stale replicas and replicas without a checksum are
+// reported, as are data objects with fewer than opts.MinReplicas good replicas. Data objects are streamed from the
+// catalog in path order, so collections of any size can be scanned. Verification failures don't stop the scan,
+// they're reported as issues.
+func (col *Collection) Scan(opts IntegrityOptions) (*IntegrityReport, error) {
+	con := col.con
+	report := &IntegrityReport{Start: time.Now(), Issues: make([]*IntegrityIssue, 0)}
+
+	zone, err := con.zoneHint(col.path)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("select order(COLL_NAME), order(DATA_NAME), DATA_REPL_NUM, DATA_REPL_STATUS, DATA_CHECKSUM, DATA_RESC_NAME where COLL_NAME = '%v' || like '%v/%%'", col.path, col.path)
+
+	var (
+		current  string
+		replicas []integrityReplica
+	)
+
+	flush := func() {
+		if current == "" {
+			return
+		}
+
+		con.scanObject(current, replicas, opts, report)
+
+		if opts.Progress != nil {
+			opts.Progress(report)
+		}
+	}
+
+	err = con.intercept(&Event{Op: OpQuery, Query: query, noRetry: true}, func() error {
+		return con.queryPages(query, QueryOptions{PageSize: 256, Zone: zone}, nil, func(page []map[string]string) error {
+			for _, row := range page {
+				p := row["COLL_NAME"] + "/" + row["DATA_NAME"]
+
+				if p != current {
+					flush()
+					current, replicas = p, replicas[:0]
+				}
+
+				replNum, _ := strconv.Atoi(row["DATA_REPL_NUM"])
+
+				replicas = append(replicas, integrityReplica{
+					ReplNum:  replNum,
+					Status:   row["DATA_REPL_STATUS"],
+					Checksum: row["DATA_CHECKSUM"],
+					Resource: row["DATA_RESC_NAME"],
+				})
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	flush()
+
+	report.End = time.Now()
+
+	return report, nil
+}
+
+// scanObject checks the replicas of the data object at p, adding the issues found to report
+func (con *Connection) scanObject(p string, replicas []integrityReplica, opts IntegrityOptions, report *IntegrityReport) {
+	report.Objects++
+
+	issues := catalogIssues(p, replicas, opts)
+
+	for _, r := range replicas {
+		if opts.Resource != "" && r.Resource != opts.Resource {
+			continue
+		}
+
+		report.Replicas++
+
+		if opts.SkipVerify || r.Checksum == "" {
+			continue
+		}
+
+		err := con.verifyReplica(p, r.ReplNum, false)
+		if err == nil {
+			report.Verified++
+		} else {
+			issues = append(issues, &IntegrityIssue{
+				Kind:     verifyIssueKind(err),
+				Path:     p,
+				ReplNum:  r.ReplNum,
+				Resource: r.Resource,
+				Checksum: r.Checksum,
+				Err:      err,
+			})
+		}
+	}
+
+	report.Issues = append(report.Issues, issues...)
+}
+
+// verifySyncAction checks the local file of a completed SyncPut or SyncGet against the checksum of its data object,
+// which the server computes if the catalog has none. Returns that checksum.
+func (con *Connection) verifySyncAction(a *SyncAction) (string, error) {
+	obj, err := con.DataObject(a.RodsPath)
+	if err != nil {
+		return "", err
+	}
+
+	chksum := obj.Checksum()
+	if chksum == "" {
+		if chksum, err = obj.Chksum(); err != nil {
+			return "", err
+		}
+	}
+
+	local, err := fileChecksum(a.LocalPath, chksum)
+	if err != nil {
+		return "", err
+	}
+
+	if local != chksum {
+		return "", newError(Fatal, -1, fmt.Sprintf("iRODS Sync Failed: checksum mismatch between %v (%v) and %v (%v)", a.LocalPath, local, a.RodsPath, chksum))
+	}
+
+	return chksum, nil
+}
+
+// catalogIssues returns the issues of the data object found in the catalog alone: stale replicas, replicas without
+// a checksum and missing replicas
+func catalogIssues(p string, replicas []integrityReplica, opts IntegrityOptions) []*IntegrityIssue {
+	issues := make([]*IntegrityIssue, 0)
+	good := 0
+
+	for _, r := range replicas {
+		if r.Status == "1" {
+			good++
+		}
+
+		if opts.Resource != "" && r.Resource != opts.Resource {
+			continue
+		}
+
+		if r.Status == "0" {
+			issues = append(issues, &IntegrityIssue{Kind: IntegrityStale, Path: p, ReplNum: r.ReplNum, Resource: r.Resource, Checksum: r.Checksum})
+		}
+
+		if r.Checksum == "" {
+			issues = append(issues, &IntegrityIssue{Kind: IntegrityNoChecksum, Path: p, ReplNum: r.ReplNum, Resource: r.Resource})
+		}
+	}
+
+	if good < opts.MinReplicas {
+		issues = append(issues, &IntegrityIssue{
+			Kind:    IntegrityMissing,
+			Path:    p,
+			ReplNum: -1,
+			Err:     newError(Fatal, -1, fmt.Sprintf("%v good replicas, %v required", good, opts.MinReplicas)),
+		})
+	}
+
+	return issues
+}
+
+// verifyIssueKind classifies the error returned by the server's checksum verification
+func verifyIssueKind(err error) int {
+	gErr, ok := err.(*GoRodsError)
+	if !ok {
+		return IntegrityError
+	}
+
+	switch {
+	case gErr.ErrorName == "USER_CHKSUM_MISMATCH":
+		return IntegrityMismatch
+	case strings.HasPrefix(gErr.ErrorName, "UNIX_FILE_OPEN_ERR"), strings.HasPrefix(gErr.ErrorName, "UNIX_FILE_STAT_ERR"):
+		return IntegrityMissing
+	}
+
+	return IntegrityError
+}
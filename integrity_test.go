@@ -0,0 +1,62 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCatalogIssues(t *testing.T) {
+	replicas := []integrityReplica{
+		{ReplNum: 0, Status: "1", Checksum: "sha2:abc", Resource: "demoResc"},
+		{ReplNum: 1, Status: "0", Checksum: "sha2:abc", Resource: "archive"},
+		{ReplNum: 2, Status: "1", Resource: "archive"},
+	}
+
+	issues := catalogIssues("/z/a.txt", replicas, IntegrityOptions{MinReplicas: 3})
+
+	kinds := []int{IntegrityStale, IntegrityNoChecksum, IntegrityMissing}
+
+	if len(issues) != len(kinds) {
+		t.Fatalf("Expected %v issues, got %v", len(kinds), issues)
+	}
+
+	for i, issue := range issues {
+		if issue.Kind != kinds[i] {
+			t.Errorf("Issue %v: expected kind %v, got %v", i, kinds[i], issue)
+		}
+	}
+
+	if issues[2].ReplNum != -1 {
+		t.Errorf("Missing replicas should be reported for the data object, got repl %v", issues[2].ReplNum)
+	}
+
+	issues = catalogIssues("/z/a.txt", replicas, IntegrityOptions{MinReplicas: 2, Resource: "demoResc"})
+
+	if len(issues) != 0 {
+		t.Errorf("Expected no issues on demoResc, got %v", issues)
+	}
+}
+
+func TestVerifyIssueKind(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind int
+	}{
+		{&GoRodsError{ErrorName: "USER_CHKSUM_MISMATCH"}, IntegrityMismatch},
+		{&GoRodsError{ErrorName: "UNIX_FILE_OPEN_ERR"}, IntegrityMissing},
+		{&GoRodsError{ErrorName: "SYS_INTERNAL_NULL_INPUT_ERR"}, IntegrityError},
+		{errors.New("boom"), IntegrityError},
+	}
+
+	for _, c := range cases {
+		if kind := verifyIssueKind(c.err); kind != c.kind {
+			t.Errorf("%v: expected kind %v, got %v", c.err, c.kind, kind)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+// Package userpool keeps the iRODS handles of the users authenticated by the webdav and rest handlers, so the
+// requests of a user reuse the handle opened with their password.
+package userpool
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"sync"
+	"time"
+)
+
+// Handle is what's kept per user, like a connection or a *gorods.Pool
+type Handle interface {
+	Close() error
+}
+
+// Dialer authenticates username with password, returning their new handle
+type Dialer func(username string, password string) (Handle, error)
+
+// entry is the handle of a user. refs counts the requests using it, it's only closed once they released it.
+type entry struct {
+	h        Handle
+	secret   [sha256.Size]byte
+	lastUsed time.Time
+	refs     int
+	retired  bool
+}
+
+// Pool keeps a Handle per user. Handles unused for longer than its ttl are closed.
+type Pool struct {
+	dial  Dialer
+	ttl   time.Duration
+	users map[string]*entry
+	mu    sync.Mutex
+}
+
+// New returns an empty Pool opening handles with dial. Handles are kept open until Close if ttl is 0.
+func New(dial Dialer, ttl time.Duration) *Pool {
+	return &Pool{
+		dial:  dial,
+		ttl:   ttl,
+		users: make(map[string]*entry),
+	}
+}
+
+// Get returns the handle of username, and the function to call once done with it. A pooled handle is only returned
+// if password matches the one it was opened with, otherwise username is authenticated again, without holding up the
+// requests of other users. The pooled handle is only replaced if that succeeds: a wrong password doesn't close the
+// handle of the user. Handles are never closed while in use.
+func (p *Pool) Get(username string, password string) (Handle, func(), error) {
+	secret := sha256.Sum256([]byte(password))
+
+	p.mu.Lock()
+
+	closing := p.reap()
+
+	if e, ok := p.users[username]; ok && subtle.ConstantTimeCompare(e.secret[:], secret[:]) == 1 {
+		e.refs++
+		p.mu.Unlock()
+
+		closeAll(closing)
+
+		return e.h, p.releaser(e), nil
+	}
+
+	p.mu.Unlock()
+
+	closeAll(closing)
+
+	h, err := p.dial(username, password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	e := &entry{h: h, secret: secret, refs: 1}
+
+	p.mu.Lock()
+
+	closing = nil
+
+	if old, ok := p.users[username]; ok {
+		closing = p.retire(old, closing)
+	}
+
+	p.users[username] = e
+
+	p.mu.Unlock()
+
+	closeAll(closing)
+
+	return h, p.releaser(e), nil
+}
+
+// Len returns the number of users with a pooled handle
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.users)
+}
+
+// releaser returns the function releasing a reference to e
+func (p *Pool) releaser(e *entry) func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+
+			e.refs--
+			e.lastUsed = time.Now()
+
+			closing := e.retired && e.refs == 0
+
+			p.mu.Unlock()
+
+			if closing {
+				e.h.Close()
+			}
+		})
+	}
+}
+
+// retire marks e, which is no longer pooled, to be closed once released, appending it to closing if it isn't in
+// use. p.mu must be held.
+func (p *Pool) retire(e *entry, closing []Handle) []Handle {
+	e.retired = true
+
+	if e.refs == 0 {
+		return append(closing, e.h)
+	}
+
+	return closing
+}
+
+// reap removes the handles unused for longer than the ttl, and returns those to close. p.mu must be held.
+func (p *Pool) reap() []Handle {
+	if p.ttl <= 0 {
+		return nil
+	}
+
+	var closing []Handle
+
+	for username, e := range p.users {
+		if e.refs == 0 && time.Since(e.lastUsed) > p.ttl {
+			closing = p.retire(e, closing)
+			delete(p.users, username)
+		}
+	}
+
+	return closing
+}
+
+// Close closes the pooled handles, those in use once they're released
+func (p *Pool) Close() {
+	p.mu.Lock()
+
+	var closing []Handle
+
+	for username, e := range p.users {
+		closing = p.retire(e, closing)
+		delete(p.users, username)
+	}
+
+	p.mu.Unlock()
+
+	closeAll(closing)
+}
+
+func closeAll(handles []Handle) {
+	for _, h := range handles {
+		h.Close()
+	}
+}
@@ -0,0 +1,264 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package userpool
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeHandle struct {
+	user   string
+	mu     sync.Mutex
+	closed int
+}
+
+func (h *fakeHandle) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed++
+
+	return nil
+}
+
+func (h *fakeHandle) isClosed() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.closed > 0
+}
+
+// fakeDialer accepts the passwords of users, and counts the handles it opened
+type fakeDialer struct {
+	users map[string]string
+	mu    sync.Mutex
+	dials int
+}
+
+func (d *fakeDialer) dial(username string, password string) (Handle, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.users[username] != password {
+		return nil, fmt.Errorf("authentication failed for %v", username)
+	}
+
+	d.dials++
+
+	return &fakeHandle{user: username}, nil
+}
+
+func (d *fakeDialer) setPassword(username string, password string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.users[username] = password
+}
+
+func TestGetReusesHandles(t *testing.T) {
+	d := &fakeDialer{users: map[string]string{"alice": "secret", "bob": "hunter2"}}
+	p := New(d.dial, 0)
+
+	a1, release1, err := p.Get("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release1()
+
+	a2, release2, err := p.Get("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release2()
+
+	if a1 != a2 || d.dials != 1 {
+		t.Errorf("Expected the handle of alice to be reused, got %v dials", d.dials)
+	}
+
+	b, releaseB, err := p.Get("bob", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseB()
+
+	if b == a1 || p.Len() != 2 {
+		t.Errorf("Expected a handle per user, got %v", p.Len())
+	}
+}
+
+func TestGetWrongPasswordKeepsHandle(t *testing.T) {
+	d := &fakeDialer{users: map[string]string{"alice": "secret"}}
+	p := New(d.dial, 0)
+
+	h, release, err := p.Get("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	if _, _, err := p.Get("alice", "wrong"); err == nil {
+		t.Fatal("Expected a wrong password to fail")
+	}
+
+	if h.(*fakeHandle).isClosed() {
+		t.Error("Expected a wrong password to leave the handle of the user open")
+	}
+
+	again, release, err := p.Get("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	if again != h {
+		t.Error("Expected the pooled handle after a failed authentication")
+	}
+}
+
+func TestGetNewPasswordReplacesHandle(t *testing.T) {
+	d := &fakeDialer{users: map[string]string{"alice": "secret"}}
+	p := New(d.dial, 0)
+
+	old, releaseOld, err := p.Get("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.setPassword("alice", "changed")
+
+	h, release, err := p.Get("alice", "changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	if h == old {
+		t.Fatal("Expected a new handle for the new password")
+	}
+
+	if old.(*fakeHandle).isClosed() {
+		t.Error("Expected the replaced handle to stay open while in use")
+	}
+
+	releaseOld()
+	releaseOld()
+
+	if c := old.(*fakeHandle).closed; c != 1 {
+		t.Errorf("Expected the replaced handle to be closed once on release, got %v", c)
+	}
+}
+
+func TestReapSkipsHandlesInUse(t *testing.T) {
+	d := &fakeDialer{users: map[string]string{"alice": "secret", "bob": "hunter2", "carol": "pw"}}
+	p := New(d.dial, time.Millisecond)
+
+	busy, releaseBusy, err := p.Get("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idle, release, err := p.Get("bob", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	time.Sleep(5 * time.Millisecond)
+
+	// Getting any handle reaps the idle ones
+	_, release, err = p.Get("carol", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	if busy.(*fakeHandle).isClosed() {
+		t.Error("Expected the handle in use not to be reaped")
+	}
+
+	if !idle.(*fakeHandle).isClosed() {
+		t.Error("Expected the idle handle to be reaped")
+	}
+
+	releaseBusy()
+
+	p.Close()
+
+	if !busy.(*fakeHandle).isClosed() {
+		t.Error("Expected Close to close released handles")
+	}
+}
+
+func TestCloseWaitsForRelease(t *testing.T) {
+	d := &fakeDialer{users: map[string]string{"alice": "secret"}}
+	p := New(d.dial, 0)
+
+	h, release, err := p.Get("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Close()
+
+	if h.(*fakeHandle).isClosed() {
+		t.Error("Expected Close to leave the handle in use open")
+	}
+
+	release()
+
+	if !h.(*fakeHandle).isClosed() {
+		t.Error("Expected the handle to be closed on release after Close")
+	}
+}
+
+func TestDialDoesntHoldLock(t *testing.T) {
+	block := make(chan struct{})
+	dialing := make(chan struct{})
+
+	p := New(func(username string, password string) (Handle, error) {
+		if username == "slow" {
+			close(dialing)
+			<-block
+		}
+
+		return &fakeHandle{user: username}, nil
+	}, 0)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if _, release, err := p.Get("slow", "pw"); err == nil {
+			release()
+		}
+	}()
+
+	<-dialing
+
+	got := make(chan error, 1)
+
+	go func() {
+		_, release, err := p.Get("fast", "pw")
+		if err == nil {
+			release()
+		}
+		got <- err
+	}()
+
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected a user to authenticate while another one is authenticating")
+	}
+
+	close(block)
+	<-done
+}
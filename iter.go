@@ -0,0 +1,147 @@
+//go:build cgo && go1.23
+// +build cgo,go1.23
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"iter"
+)
+
+// errIterStopped is returned by the callbacks of iterators when the range loop breaks
+var errIterStopped = errors.New("gorods: iteration stopped")
+
+// Seq returns an iterator over the objects, for use with range
+func (objs IRodsObjs) Seq() iter.Seq[IRodsObj] {
+	return func(yield func(IRodsObj) bool) {
+		for _, obj := range objs {
+			if !yield(obj) {
+				return
+			}
+		}
+	}
+}
+
+// Iter returns an iterator over the data objects and collections directly within the collection.
+// If the collection can't be read, the error is yielded once and iteration ends.
+func (col *Collection) Iter() iter.Seq2[IRodsObj, error] {
+	return func(yield func(IRodsObj, error) bool) {
+		all, err := col.All()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, obj := range all {
+			if !yield(obj, nil) {
+				return
+			}
+		}
+	}
+}
+
+// DataObjsIter returns an iterator over the data objects directly within the collection, see Iter
+func (col *Collection) DataObjsIter() iter.Seq2[*DataObj, error] {
+	return func(yield func(*DataObj, error) bool) {
+		objs, err := col.DataObjs()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, obj := range objs {
+			if !yield(obj.(*DataObj), nil) {
+				return
+			}
+		}
+	}
+}
+
+// CollectionsIter returns an iterator over the sub-collections directly within the collection, see Iter
+func (col *Collection) CollectionsIter() iter.Seq2[*Collection, error] {
+	return func(yield func(*Collection, error) bool) {
+		cols, err := col.Collections()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, c := range cols {
+			if !yield(c.(*Collection), nil) {
+				return
+			}
+		}
+	}
+}
+
+// WalkIter returns an iterator over what Walk passes to its callback. Sub-collections are only read as the
+// iteration reaches them, so breaking out of the loop early avoids reading the rest of the tree.
+func (col *Collection) WalkIter() iter.Seq2[IRodsObj, error] {
+	return func(yield func(IRodsObj, error) bool) {
+		err := col.Walk(func(obj IRodsObj) error {
+			if !yield(obj, nil) {
+				return errIterStopped
+			}
+
+			return nil
+		})
+
+		if err != nil && err != errIterStopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// QueryIter runs a general query like Query, yielding rows as each page is fetched instead of collecting them.
+// Breaking out of the loop closes the query on the server. Errors are yielded once, with a nil row, and end iteration.
+// The connection can be used inside the loop.
+func (con *Connection) QueryIter(query string, opts QueryOptions) iter.Seq2[map[string]string, error] {
+	return func(yield func(map[string]string, error) bool) {
+		if opts.PageSize <= 0 {
+			opts.PageSize = 256
+		}
+
+		err := con.intercept(&Event{Op: OpQuery, Query: query, noRetry: true}, func() error {
+			err := con.queryPages(query, opts, nil, func(page []map[string]string) error {
+				for _, row := range page {
+					if !yield(row, nil) {
+						return errIterStopped
+					}
+				}
+
+				return nil
+			})
+
+			// Stopping early isn't a failure of the query
+			if err == errIterStopped {
+				return nil
+			}
+
+			return err
+		})
+
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Rows returns an iterator over the rows of the result, see Each
+func (res *QueryResult) Rows() iter.Seq2[map[string]string, error] {
+	return func(yield func(map[string]string, error) bool) {
+		err := res.Each(func(row map[string]string) error {
+			if !yield(row, nil) {
+				return errIterStopped
+			}
+
+			return nil
+		})
+
+		if err != nil && err != errIterStopped {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+//go:build cgo && go1.23
+// +build cgo,go1.23
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestQueryResultRows(t *testing.T) {
+	res := &QueryResult{rows: []map[string]string{{"DATA_NAME": "a"}, {"DATA_NAME": "b"}, {"DATA_NAME": "c"}}, count: 3}
+
+	names := make([]string, 0)
+	for row, err := range res.Rows() {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, row["DATA_NAME"])
+
+		if len(names) == 2 {
+			break
+		}
+	}
+
+	if len(names) != 2 || names[1] != "b" {
+		t.Errorf("Expected iteration to stop after b, got %v", names)
+	}
+}
+
+func TestIRodsObjsSeq(t *testing.T) {
+	objs := IRodsObjs{&DataObj{path: "/tempZone/a"}, &DataObj{path: "/tempZone/b"}}
+
+	n := 0
+	for obj := range objs.Seq() {
+		if obj.Path() != objs[n].Path() {
+			t.Errorf("Expected %v, got %v", objs[n].Path(), obj.Path())
+		}
+		n++
+	}
+
+	if n != 2 {
+		t.Errorf("Expected 2 objects, got %v", n)
+	}
+}
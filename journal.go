@@ -0,0 +1,235 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MarkerAttrPrefix is prepended to the operation name to form the attribute of marker AVUs written by MetaJournal
+const MarkerAttrPrefix = "gorods:done:"
+
+// Journal records completed per-object operations, so batch jobs can skip items that were already processed on a previous run.
+// Op is an application-defined operation name, like "replicate-to-archive" or "ingest-v2".
+type Journal interface {
+	IsDone(op string, obj IRodsObj) (bool, error)
+	MarkDone(op string, obj IRodsObj) error
+}
+
+// MetaJournal stores completion markers as AVUs on the objects themselves. For data objects, the marker value is the
+// size and modification time at the time of completion, so an object that has been modified since is considered
+// not done.
+type MetaJournal struct{}
+
+// NewMetaJournal returns a Journal that stores markers as AVUs on the processed objects
+func NewMetaJournal() *MetaJournal {
+	return new(MetaJournal)
+}
+
+// markerValue returns the value of the marker AVU of obj. The size and modification time of data objects are fetched
+// from the server: unlike the checksum cached when obj was loaded, they reflect changes made by the operation itself
+// or since, and they're always set.
+func markerValue(obj IRodsObj) (string, error) {
+	if obj.Type() != DataObjType {
+		return "done", nil
+	}
+
+	do := obj.(*DataObj)
+
+	// Bypass ConnectionOptions.Cache, a cached stat would predate the operation being marked
+	stat, err := do.con.objStat(do.path)
+	if err != nil {
+		return "", err
+	}
+
+	cached := *stat
+	do.con.cacheSet(CacheStat, do.path, &cached)
+
+	return statMarker(stat), nil
+}
+
+// statMarker returns the marker value of a data object, like "1024@2016-05-04T10:00:00Z"
+func statMarker(stat *ObjStat) string {
+	return fmt.Sprintf("%v@%v", stat.Size, stat.ModifyTime.UTC().Format(time.RFC3339))
+}
+
+// IsDone returns true if obj has a marker AVU for op, matching the object's current size and modification time
+func (j *MetaJournal) IsDone(op string, obj IRodsObj) (bool, error) {
+	mc, err := obj.Meta()
+	if err != nil {
+		return false, err
+	}
+
+	metas, err := mc.Get(MarkerAttrPrefix + op)
+	if err != nil {
+		// No match
+		return false, nil
+	}
+
+	value, err := markerValue(obj)
+	if err != nil {
+		return false, err
+	}
+
+	for _, m := range metas {
+		if m.Value == value {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// MarkDone replaces any existing marker for op on obj with one matching the object's current size and modification time
+func (j *MetaJournal) MarkDone(op string, obj IRodsObj) error {
+	mc, err := obj.Meta()
+	if err != nil {
+		return err
+	}
+
+	attr := MarkerAttrPrefix + op
+
+	value, err := markerValue(obj)
+	if err != nil {
+		return err
+	}
+
+	if _, er := mc.Get(attr); er == nil {
+		if er := mc.Delete(attr); er != nil {
+			return er
+		}
+	}
+
+	_, err = mc.Add(Meta{
+		Attribute: attr,
+		Value:     value,
+		Units:     time.Now().Format(time.RFC3339),
+	})
+
+	return err
+}
+
+// FileJournal stores completion markers in a local append-only file, one "op<TAB>path" line per completed operation.
+// It is safe for use by multiple goroutines.
+type FileJournal struct {
+	path string
+	file *os.File
+	done map[string]bool
+	mu   sync.Mutex
+}
+
+// OpenFileJournal opens (or creates) the journal file at localPath and loads the operations it has recorded
+func OpenFileJournal(localPath string) (*FileJournal, error) {
+	j := new(FileJournal)
+
+	j.path = localPath
+	j.done = make(map[string]bool)
+
+	if f, err := os.Open(localPath); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				j.done[line] = true
+			}
+		}
+		f.Close()
+
+		if err := scanner.Err(); err != nil {
+			return nil, newError(Fatal, -1, fmt.Sprintf("Open Journal Failed: %v", err))
+		}
+	}
+
+	f, err := os.OpenFile(localPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Open Journal Failed: %v", err))
+	}
+
+	j.file = f
+
+	return j, nil
+}
+
+func journalKey(op string, obj IRodsObj) string {
+	return op + "\t" + obj.Path()
+}
+
+// IsDone returns true if the journal has a record of op completing for obj
+func (j *FileJournal) IsDone(op string, obj IRodsObj) (bool, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.done[journalKey(op, obj)], nil
+}
+
+// MarkDone appends a record of op completing for obj, and syncs the file to disk
+func (j *FileJournal) MarkDone(op string, obj IRodsObj) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key := journalKey(op, obj)
+
+	if j.done[key] {
+		return nil
+	}
+
+	if _, err := j.file.WriteString(key + "\n"); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Journal MarkDone Failed: %v", err))
+	}
+
+	if err := j.file.Sync(); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Journal MarkDone Failed: %v", err))
+	}
+
+	j.done[key] = true
+
+	return nil
+}
+
+// Close closes the underlying journal file
+func (j *FileJournal) Close() error {
+	return j.file.Close()
+}
+
+// Once runs handler on obj unless the journal shows that op already completed for it.
+// The operation is marked done only if handler returns nil. Returns true if obj was skipped.
+func Once(j Journal, op string, obj IRodsObj, handler func(IRodsObj) error) (bool, error) {
+	if done, err := j.IsDone(op, obj); err != nil {
+		return false, err
+	} else if done {
+		return true, nil
+	}
+
+	if err := handler(obj); err != nil {
+		return false, err
+	}
+
+	return false, j.MarkDone(op, obj)
+}
+
+// EachOnce calls Once for every object in objs, stopping at the first error.
+// Returns the number of objects that were skipped because they were already done.
+func EachOnce(j Journal, op string, objs IRodsObjs, handler func(IRodsObj) error) (int, error) {
+	skipped := 0
+
+	for _, obj := range objs {
+		s, err := Once(j, op, obj, handler)
+		if err != nil {
+			return skipped, err
+		}
+
+		if s {
+			skipped++
+		}
+	}
+
+	return skipped, nil
+}
@@ -0,0 +1,79 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileJournal(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "journal")
+	a := &Collection{path: "/tempZone/home/rods/a", typ: CollectionType}
+	b := &Collection{path: "/tempZone/home/rods/b", typ: CollectionType}
+
+	j, err := OpenFileJournal(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	handler := func(obj IRodsObj) error {
+		calls++
+
+		if obj == b {
+			return errors.New("failed")
+		}
+
+		return nil
+	}
+
+	if skipped, err := EachOnce(j, "ingest", IRodsObjs{a, b}, handler); err == nil || skipped != 0 {
+		t.Errorf("Expected the failure of b, got %v skipped, %v", skipped, err)
+	}
+
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new run skips what the previous one completed
+	j, err = OpenFileJournal(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	if done, _ := j.IsDone("ingest", a); !done {
+		t.Error("Expected a to be done")
+	}
+
+	if done, _ := j.IsDone("ingest", b); done {
+		t.Error("Expected b not to be done after failing")
+	}
+
+	if done, _ := j.IsDone("replicate", a); done {
+		t.Error("Expected markers to be kept per operation")
+	}
+
+	if skipped, err := Once(j, "ingest", a, handler); err != nil || !skipped || calls != 2 {
+		t.Errorf("Expected a to be skipped, got %v, %v, %v calls", skipped, err, calls)
+	}
+}
+
+func TestStatMarker(t *testing.T) {
+	modified := time.Date(2016, 5, 4, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+
+	if m := statMarker(&ObjStat{Size: 1024, ModifyTime: modified}); m != "1024@2016-05-04T10:00:00Z" {
+		t.Errorf("Unexpected marker %v", m)
+	}
+
+	if m, err := markerValue(&Collection{typ: CollectionType}); err != nil || m != "done" {
+		t.Errorf("Unexpected collection marker %v, %v", m, err)
+	}
+}
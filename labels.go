@@ -0,0 +1,95 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"sort"
+	"strings"
+)
+
+// LabeledRecorder is implemented by Recorders that attribute observations to the labels of the connection which
+// made them (see ConnectionOptions.Labels). WithLabels returns the Recorder used by connections with labels, for
+// example a Prometheus vector curried with them. It is called again whenever a connection's labels change.
+type LabeledRecorder interface {
+	Recorder
+	WithLabels(labels map[string]string) Recorder
+}
+
+// Labels returns the connection's labels: ConnectionOptions.Labels, overridden by those set with SetLabel
+func (con *Connection) Labels() map[string]string {
+	labels := make(map[string]string)
+
+	if con.Options != nil {
+		for k, v := range con.Options.Labels {
+			labels[k] = v
+		}
+	}
+
+	con.labelMu.RLock()
+	defer con.labelMu.RUnlock()
+
+	for k, v := range con.labels {
+		if v == "" {
+			delete(labels, k)
+		} else {
+			labels[k] = v
+		}
+	}
+
+	return labels
+}
+
+// SetLabel sets a label of this connection only, like the job currently using a pooled connection.
+// An empty value removes the label, including one set in ConnectionOptions.Labels.
+func (con *Connection) SetLabel(key string, value string) {
+	con.labelMu.Lock()
+	defer con.labelMu.Unlock()
+
+	if con.labels == nil {
+		con.labels = make(map[string]string)
+	}
+
+	con.labels[key] = value
+	con.labeledRec = nil
+}
+
+// labeledRecorder returns the Recorder of lr for the connection's labels, or lr itself without labels
+func (con *Connection) labeledRecorder(lr LabeledRecorder) Recorder {
+	con.labelMu.RLock()
+	r := con.labeledRec
+	con.labelMu.RUnlock()
+
+	if r != nil {
+		return r
+	}
+
+	labels := con.Labels()
+	if len(labels) == 0 {
+		return lr
+	}
+
+	r = lr.WithLabels(labels)
+
+	con.labelMu.Lock()
+	con.labeledRec = r
+	con.labelMu.Unlock()
+
+	return r
+}
+
+// formatLabels returns the labels as "key=value" pairs sorted by key, like "job=42 tenant=acme"
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,44 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnectionLabels(t *testing.T) {
+	stats := NewStatsRecorder()
+
+	con := &Connection{Options: &ConnectionOptions{
+		Labels:   map[string]string{"tenant": "acme", "job": "1"},
+		Recorder: stats,
+	}}
+
+	con.SetLabel("job", "42")
+
+	if got := formatLabels(con.Labels()); got != "job=42 tenant=acme" {
+		t.Errorf("Unexpected labels: %v", got)
+	}
+
+	con.recorder().ObserveOperation("put", time.Second, nil)
+
+	child, ok := stats.Labeled()["job=42 tenant=acme"]
+	if !ok {
+		t.Fatalf("Expected totals for the label set, got %v", stats.Labeled())
+	}
+
+	if child.Ops()["put"].Count != 1 || stats.Ops()["put"].Count != 1 {
+		t.Errorf("Expected the operation in both the labeled and overall totals")
+	}
+
+	con.SetLabel("job", "")
+
+	if got := formatLabels(con.Labels()); got != "tenant=acme" {
+		t.Errorf("Unexpected labels after removing job: %v", got)
+	}
+}
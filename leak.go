@@ -0,0 +1,177 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Leak describes a handle that was not closed. Kind is "Connection", "DataObj", "Collection" or "QueryResult",
+// Path is the iRODS path (or query) of the handle, and Stack is the stack trace of the code that opened it.
+// Labels are the labels of the connection.
+type Leak struct {
+	Kind   string
+	Path   string
+	Stack  string
+	Labels map[string]string
+}
+
+var (
+	leakHandler   func(Leak) = logLeak
+	leakHandlerMu sync.RWMutex
+
+	// openConns are the connected connections created with LeakWarnings, and where they were opened
+	openConns   = make(map[*Connection]Leak)
+	openConnsMu sync.Mutex
+)
+
+// SetLeakHandler sets the function called when a leaked handle is detected: a DataObj, Collection or QueryResult
+// still open when its connection is disconnected. The default handler writes the leak to the Logger set with SetLogger.
+// Leak detection is only enabled for connections created with ConnectionOptions.LeakWarnings.
+func SetLeakHandler(handler func(Leak)) {
+	leakHandlerMu.Lock()
+	defer leakHandlerMu.Unlock()
+
+	if handler == nil {
+		handler = logLeak
+	}
+
+	leakHandler = handler
+}
+
+func logLeak(l Leak) {
+	logLabeled(LogWarn, l.Labels, fmt.Sprintf("%v %v was not closed, opened at:\n%v", l.Kind, l.Path, l.Stack))
+}
+
+func reportLeak(l Leak) {
+	leakHandlerMu.RLock()
+	handler := leakHandler
+	leakHandlerMu.RUnlock()
+
+	handler(l)
+}
+
+// OpenHandles returns the connections created with ConnectionOptions.LeakWarnings that are still connected,
+// followed by their open handles, e.g. to check for leaks when a service shuts down
+func OpenHandles() []Leak {
+	openConnsMu.Lock()
+	cons := make([]*Connection, 0, len(openConns))
+	leaks := make([]Leak, 0, len(openConns))
+
+	for con, l := range openConns {
+		cons = append(cons, con)
+		leaks = append(leaks, l)
+	}
+	openConnsMu.Unlock()
+
+	sortLeaks(leaks)
+
+	for _, con := range cons {
+		leaks = append(leaks, con.OpenHandles()...)
+	}
+
+	return leaks
+}
+
+// OpenHandles returns the DataObjs, Collections and QueryResults of the connection that are still open, when it
+// was created with ConnectionOptions.LeakWarnings
+func (con *Connection) OpenHandles() []Leak {
+	con.handlesMu.Lock()
+	defer con.handlesMu.Unlock()
+
+	leaks := make([]Leak, 0, len(con.handles))
+	for _, l := range con.handles {
+		leaks = append(leaks, l)
+	}
+
+	sortLeaks(leaks)
+
+	return leaks
+}
+
+func sortLeaks(leaks []Leak) {
+	sort.SliceStable(leaks, func(i, j int) bool {
+		if leaks[i].Kind != leaks[j].Kind {
+			return leaks[i].Kind < leaks[j].Kind
+		}
+
+		return leaks[i].Path < leaks[j].Path
+	})
+}
+
+// watchLeak records obj as open on the connection, along with the stack trace of the code that opened it, until
+// unwatchLeak is called. Handles are tracked explicitly rather than with finalizers, which never run for the
+// handles still referenced by their connection or collection.
+func (con *Connection) watchLeak(obj interface{}, kind string, path string) {
+	if con.Options == nil || !con.Options.LeakWarnings {
+		return
+	}
+
+	buf := make([]byte, 4096)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	l := Leak{
+		Kind:   kind,
+		Path:   path,
+		Stack:  string(buf),
+		Labels: con.Labels(),
+	}
+
+	if c, ok := obj.(*Connection); ok {
+		openConnsMu.Lock()
+		openConns[c] = l
+		openConnsMu.Unlock()
+
+		return
+	}
+
+	con.handlesMu.Lock()
+	defer con.handlesMu.Unlock()
+
+	if con.handles == nil {
+		con.handles = make(map[interface{}]Leak)
+	}
+
+	con.handles[obj] = l
+}
+
+// unwatchLeak forgets obj, once the handle has been closed
+func (con *Connection) unwatchLeak(obj interface{}) {
+	if con.Options == nil || !con.Options.LeakWarnings {
+		return
+	}
+
+	if c, ok := obj.(*Connection); ok {
+		openConnsMu.Lock()
+		delete(openConns, c)
+		openConnsMu.Unlock()
+
+		return
+	}
+
+	con.handlesMu.Lock()
+	defer con.handlesMu.Unlock()
+
+	delete(con.handles, obj)
+}
+
+// reportLeaks reports the handles still open on the connection to the leak handler, and forgets them.
+// Disconnect calls it once it closed OpenedObjs, the handles left were never closed by their users.
+func (con *Connection) reportLeaks() {
+	leaks := con.OpenHandles()
+
+	con.handlesMu.Lock()
+	con.handles = nil
+	con.handlesMu.Unlock()
+
+	for _, l := range leaks {
+		reportLeak(l)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestLeakTracking(t *testing.T) {
+	con := &Connection{Options: &ConnectionOptions{LeakWarnings: true, Host: "localhost"}}
+
+	var reported []Leak
+	SetLeakHandler(func(l Leak) {
+		reported = append(reported, l)
+	})
+	defer SetLeakHandler(nil)
+
+	con.watchLeak(con, "Connection", con.Options.Host)
+	defer con.unwatchLeak(con)
+
+	a := &DataObj{path: "/tempZone/home/rods/a.txt"}
+	b := &DataObj{path: "/tempZone/home/rods/b.txt"}
+
+	// The handles reference their connection, which a finalizer would never see through
+	a.con, b.con = con, con
+
+	con.watchLeak(b, "DataObj", b.path)
+	con.watchLeak(a, "DataObj", a.path)
+
+	if open := con.OpenHandles(); len(open) != 2 || open[0].Path != a.path || open[1].Path != b.path || open[0].Stack == "" {
+		t.Fatalf("Expected both handles to be open, got %+v", open)
+	}
+
+	found := false
+	for _, l := range OpenHandles() {
+		if l.Kind == "Connection" && l.Path == "localhost" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Error("Expected the connection to be listed by OpenHandles")
+	}
+
+	con.unwatchLeak(a)
+	con.reportLeaks()
+
+	if len(reported) != 1 || reported[0].Path != b.path {
+		t.Errorf("Expected only the unclosed handle to be reported, got %+v", reported)
+	}
+
+	if open := con.OpenHandles(); len(open) != 0 {
+		t.Errorf("Expected reported handles to be forgotten, got %+v", open)
+	}
+
+	untracked := &Connection{Options: &ConnectionOptions{}}
+	untracked.watchLeak(a, "DataObj", a.path)
+
+	if open := untracked.OpenHandles(); len(open) != 0 {
+		t.Errorf("Expected no tracking without LeakWarnings, got %+v", open)
+	}
+}
@@ -0,0 +1,310 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Listing sort keys, used in ListOptions.Sort
+const (
+	SortByName = iota
+	SortBySize
+	SortByModifyTime
+)
+
+// errPageFull stops queryPages once a listing page has all its entries
+var errPageFull = errors.New("gorods: page full")
+
+// ListOptions selects a page of a collection listing, see Connection.ListPage. Collections are listed before data
+// objects, and Offset and Limit apply to the whole listing. Limit 0 returns every entry after Offset. Entries are
+// sorted by the catalog on Sort, then name. Collections have no size, SortBySize sorts them by name.
+// Count requests the totals of the listing, which costs an extra query when Offset is past the end of either part.
+type ListOptions struct {
+	Offset          int
+	Limit           int
+	Sort            int
+	Descending      bool
+	SkipCollections bool
+	SkipDataObjs    bool
+	Count           bool
+}
+
+// ListPage is a page of a collection listing. The totals are -1 unless ListOptions.Count was set.
+type ListPage struct {
+	Entries         []ListingEntry
+	Offset          int
+	CollectionTotal int
+	DataObjTotal    int
+	Total           int
+
+	con *Connection
+}
+
+// listPart is one of the two queries making up a listing
+type listPart struct {
+	typ   int
+	query string
+}
+
+// ListPage returns a page of the listing of the collection at p, without reading the whole collection. A data object
+// with replicas in different states (size or modify time) may appear once per state, like in Connection.List.
+// The checksums of the entries aren't set.
+func (con *Connection) ListPage(p string, opts ListOptions) (*ListPage, error) {
+	p = strings.TrimRight(p, "/")
+
+	zone, err := con.zoneHint(p)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ListPage{
+		Entries:         make([]ListingEntry, 0),
+		Offset:          opts.Offset,
+		CollectionTotal: -1,
+		DataObjTotal:    -1,
+		Total:           -1,
+		con:             con,
+	}
+
+	offset := opts.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	parts := listQueries(p, opts)
+
+	for i, part := range parts {
+		remain := -1
+		if opts.Limit > 0 {
+			if remain = opts.Limit - len(page.Entries); remain <= 0 && !opts.Count {
+				break
+			}
+		}
+
+		// The number of collections tells how many data objects the offset skips
+		count := opts.Count || (part.typ == CollectionType && i < len(parts)-1 && offset > 0)
+
+		rows, total, er := con.listRange(part.query, zone, offset, remain, count)
+		if er != nil {
+			return nil, er
+		}
+
+		for _, row := range rows {
+			page.Entries = append(page.Entries, listPageEntry(p, part.typ, row))
+		}
+
+		if opts.Count && part.typ == CollectionType {
+			page.CollectionTotal = total
+		} else if opts.Count {
+			page.DataObjTotal = total
+		}
+
+		// Past the first part, the offset only skips what the previous part didn't
+		if len(rows) > 0 || total >= offset {
+			offset = 0
+		} else if total >= 0 {
+			offset -= total
+		}
+	}
+
+	if opts.Count {
+		page.Total = 0
+		for _, t := range []int{page.CollectionTotal, page.DataObjTotal} {
+			if t > 0 {
+				page.Total += t
+			}
+		}
+	}
+
+	return page, nil
+}
+
+// ListPage returns a page of the collection's listing, see Connection.ListPage
+func (col *Collection) ListPage(opts ListOptions) (*ListPage, error) {
+	return col.con.ListPage(col.path, opts)
+}
+
+// HasMore returns true if entries follow the page. It is only known if the totals were counted.
+func (page *ListPage) HasMore() bool {
+	return page.Total >= 0 && page.Offset+len(page.Entries) < page.Total
+}
+
+// Objs opens the entries of the page as Collections and DataObjs, one by one. Sub-collections aren't read until used.
+func (page *ListPage) Objs() (IRodsObjs, error) {
+	objs := make(IRodsObjs, 0, len(page.Entries))
+
+	for _, entry := range page.Entries {
+		if entry.Type == CollectionType {
+			col, err := getCollection(CollectionOptions{Path: entry.Path}, page.con)
+			if err != nil {
+				return nil, err
+			}
+
+			objs = append(objs, col)
+			continue
+		}
+
+		obj, err := getDataObj(entry.Path, page.con)
+		if err != nil {
+			return nil, err
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+// listRange returns up to limit rows of the query after offset, all of them if limit is negative, and its total
+// row count if count is set, -1 otherwise
+func (con *Connection) listRange(query string, zone string, offset int, limit int, count bool) ([]map[string]string, int, error) {
+	rows := make([]map[string]string, 0)
+	total := -1
+
+	if limit != 0 {
+		var info *pageInfo
+		if count {
+			info = new(pageInfo)
+		}
+
+		opts := QueryOptions{Offset: offset, PageSize: 256, Zone: zone}
+		if limit > 0 && limit < opts.PageSize {
+			opts.PageSize = limit
+		}
+
+		err := con.queryPages(query, opts, info, func(page []map[string]string) error {
+			for _, row := range page {
+				if len(rows) == limit {
+					return errPageFull
+				}
+				rows = append(rows, row)
+			}
+
+			if len(rows) == limit {
+				return errPageFull
+			}
+
+			return nil
+		})
+
+		if err != nil && err != errPageFull {
+			return nil, -1, err
+		}
+
+		if info != nil {
+			total = info.Total
+		}
+	}
+
+	if !count {
+		return rows, -1, nil
+	}
+
+	// The catalog only reports the total along with rows
+	if total < 0 && (offset > 0 || limit == 0) {
+		info := new(pageInfo)
+
+		err := con.queryPages(query, QueryOptions{PageSize: 1, Zone: zone}, info, func([]map[string]string) error {
+			return errPageFull
+		})
+
+		if err != nil && err != errPageFull {
+			return nil, -1, err
+		}
+
+		total = info.Total
+	}
+
+	if total < 0 {
+		total = len(rows)
+	}
+
+	return rows, total, nil
+}
+
+// listQueries returns the general queries listing the collections and data objects within p, sorted as opts requests
+func listQueries(p string, opts ListOptions) []listPart {
+	order := "order"
+	if opts.Descending {
+		order = "order_desc"
+	}
+
+	parts := make([]listPart, 0, 2)
+
+	if !opts.SkipCollections {
+		var cols string
+
+		switch opts.Sort {
+		case SortByModifyTime:
+			cols = fmt.Sprintf("%v(COLL_MODIFY_TIME), %v(COLL_NAME)", order, order)
+		default:
+			cols = fmt.Sprintf("%v(COLL_NAME), COLL_MODIFY_TIME", order)
+		}
+
+		parts = append(parts, listPart{CollectionType, fmt.Sprintf("select %v where COLL_PARENT_NAME = '%v'", cols, p)})
+	}
+
+	if !opts.SkipDataObjs {
+		var cols string
+
+		switch opts.Sort {
+		case SortBySize:
+			cols = fmt.Sprintf("%v(DATA_SIZE), %v(DATA_NAME), DATA_MODIFY_TIME", order, order)
+		case SortByModifyTime:
+			cols = fmt.Sprintf("%v(DATA_MODIFY_TIME), %v(DATA_NAME), DATA_SIZE", order, order)
+		default:
+			cols = fmt.Sprintf("%v(DATA_NAME), DATA_SIZE, DATA_MODIFY_TIME", order)
+		}
+
+		parts = append(parts, listPart{DataObjType, fmt.Sprintf("select %v where COLL_NAME = '%v'", cols, p)})
+	}
+
+	return parts
+}
+
+// listPageEntry returns the entry of a row returned by a listQueries query
+func listPageEntry(p string, typ int, row map[string]string) ListingEntry {
+	if typ == CollectionType {
+		return ListingEntry{
+			Name:       path.Base(row["COLL_NAME"]),
+			Path:       row["COLL_NAME"],
+			Type:       CollectionType,
+			ModifyTime: timeStringToTime(row["COLL_MODIFY_TIME"]),
+		}
+	}
+
+	size, _ := strconv.ParseInt(row["DATA_SIZE"], 10, 64)
+
+	return ListingEntry{
+		Name:       row["DATA_NAME"],
+		Path:       p + "/" + row["DATA_NAME"],
+		Type:       DataObjType,
+		Size:       size,
+		ModifyTime: timeStringToTime(row["DATA_MODIFY_TIME"]),
+	}
+}
+
+// queryLiteral returns s quoted for a general query condition. The general query parser has no escape for single
+// quotes, so values containing one are rejected.
+func queryLiteral(s string) (string, error) {
+	if strings.ContainsRune(s, '\'') {
+		return "", newError(Fatal, -1, fmt.Sprintf("iRODS Query Failed: %q can't be used in a condition, it contains a single quote", s))
+	}
+
+	return "'" + s + "'", nil
+}
+
+// inTree returns true if the collection coll is p or below it. Rows of "COLL_NAME like 'p/%'" conditions must be
+// checked with it, since the _ and % of p are wildcards too.
+func inTree(p string, coll string) bool {
+	return coll == p || strings.HasPrefix(coll, p+"/")
+}
@@ -0,0 +1,64 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestListQueries(t *testing.T) {
+	parts := listQueries("/z/home/a", ListOptions{Sort: SortBySize, Descending: true})
+
+	expected := []string{
+		"select order_desc(COLL_NAME), COLL_MODIFY_TIME where COLL_PARENT_NAME = '/z/home/a'",
+		"select order_desc(DATA_SIZE), order_desc(DATA_NAME), DATA_MODIFY_TIME where COLL_NAME = '/z/home/a'",
+	}
+
+	if len(parts) != len(expected) {
+		t.Fatalf("Expected %v queries, got %v", len(expected), parts)
+	}
+
+	for i, part := range parts {
+		if part.query != expected[i] {
+			t.Errorf("Query %v: expected %q, got %q", i, expected[i], part.query)
+		}
+	}
+
+	if parts[0].typ != CollectionType || parts[1].typ != DataObjType {
+		t.Errorf("Unexpected part types %v", parts)
+	}
+
+	parts = listQueries("/z/home/a", ListOptions{Sort: SortByModifyTime, SkipCollections: true})
+
+	if len(parts) != 1 || parts[0].query != "select order(DATA_MODIFY_TIME), order(DATA_NAME), DATA_SIZE where COLL_NAME = '/z/home/a'" {
+		t.Errorf("Unexpected queries %v", parts)
+	}
+}
+
+func TestListPageEntry(t *testing.T) {
+	entry := listPageEntry("/z/home/a", DataObjType, map[string]string{"DATA_NAME": "x.txt", "DATA_SIZE": "42", "DATA_MODIFY_TIME": "01500000000"})
+
+	if entry.Path != "/z/home/a/x.txt" || entry.Size != 42 || entry.ModifyTime.Unix() != 1500000000 {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+
+	entry = listPageEntry("/z/home/a", CollectionType, map[string]string{"COLL_NAME": "/z/home/a/sub"})
+
+	if entry.Name != "sub" || entry.Type != CollectionType {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+
+	page := &ListPage{Entries: make([]ListingEntry, 10), Offset: 20, Total: 31}
+	if !page.HasMore() {
+		t.Error("Expected more entries after the page")
+	}
+
+	page.Total = -1
+	if page.HasMore() {
+		t.Error("HasMore should be false when the total isn't known")
+	}
+}
@@ -0,0 +1,140 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// Lock types used with DataObj.Lock. Any number of clients can hold a ReadLock on a data object at the same time,
+// a WriteLock excludes every other lock.
+const (
+	ReadLock = iota
+	WriteLock
+)
+
+// ObjLock is an advisory lock on a data object, returned by DataObj.Lock and Connection.LockPath.
+// Locks are advisory: they only coordinate clients that take them, and don't prevent anyone from writing.
+// The server holds the lock for the connection that took it, so it is also released when the connection is closed,
+// or when its handle is replaced after a failed idle check (see Held).
+type ObjLock struct {
+	Path string
+	Type int
+
+	con        *Connection
+	fd         int
+	reconnects int
+	released   bool
+	mu         sync.Mutex
+}
+
+// Lock takes an advisory lock of lockType (ReadLock or WriteLock) on the data object. If wait is set, Lock blocks
+// until conflicting locks held by other clients are released, otherwise it fails immediately.
+func (obj *DataObj) Lock(lockType int, wait bool) (*ObjLock, error) {
+	return obj.con.LockPath(obj.path, lockType, wait)
+}
+
+// LockPath is DataObj.Lock for a path, so the data object doesn't need to be loaded
+func (con *Connection) LockPath(p string, lockType int, wait bool) (*ObjLock, error) {
+	var (
+		errMsg *C.char
+		cWrite C.int
+		cWait  C.int
+	)
+
+	switch lockType {
+	case ReadLock:
+	case WriteLock:
+		cWrite = C.int(1)
+	default:
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Lock Failed: lockType must be ReadLock or WriteLock"))
+	}
+
+	if wait {
+		cWait = C.int(1)
+	}
+
+	cPath := C.CString(p)
+	defer C.free(unsafe.Pointer(cPath))
+
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	fd := C.gorods_lock_dataobject(ccon, cPath, cWrite, cWait, &errMsg)
+	if fd < 0 {
+		return nil, newError(Fatal, fd, fmt.Sprintf("iRODS Lock Failed: %v, %v", p, C.GoString(errMsg)))
+	}
+
+	return &ObjLock{
+		Path:       p,
+		Type:       lockType,
+		con:        con,
+		fd:         int(fd),
+		reconnects: con.Reconnects(),
+	}, nil
+}
+
+// Held returns false once the lock was released, or lost because the connection handle was replaced
+func (l *ObjLock) Held() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return !l.released && l.con.Reconnects() == l.reconnects
+}
+
+// Unlock releases the lock. It is safe to call more than once.
+func (l *ObjLock) Unlock() error {
+	var errMsg *C.char
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.released {
+		return nil
+	}
+
+	l.released = true
+
+	// The server released the lock along with the old handle
+	if l.con.Reconnects() != l.reconnects {
+		return nil
+	}
+
+	cPath := C.CString(l.Path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	ccon := l.con.GetCcon()
+	defer l.con.ReturnCcon(ccon)
+
+	if status := C.gorods_unlock_dataobject(ccon, cPath, C.int(l.fd), &errMsg); status < 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS Unlock Failed: %v, %v", l.Path, C.GoString(errMsg)))
+	}
+
+	return nil
+}
+
+// WithLock runs fn while holding a lock of lockType on the data object, waiting for conflicting locks.
+// The lock is released when fn returns, and fn's error is returned.
+func (obj *DataObj) WithLock(lockType int, fn func() error) error {
+	l, err := obj.Lock(lockType, true)
+	if err != nil {
+		return err
+	}
+
+	fnErr := fn()
+
+	if err := l.Unlock(); err != nil && fnErr == nil {
+		return err
+	}
+
+	return fnErr
+}
@@ -0,0 +1,210 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Log levels, used in ConnectionOptions.LogLevel. LogWarn is the default.
+const (
+	LogDebug = iota - 2
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// Logger receives the diagnostics of a connection: its lifecycle (connects, reconnects, redirects and disconnects)
+// at LogInfo and LogWarn, policy retries at LogWarn, failed calls with their iRODS error code at LogInfo, completed
+// transfers at LogInfo and every other operation at LogDebug. Messages are followed by key-value pairs, like
+// "host", "icat.example.org", and the connection's labels. A *slog.Logger is a Logger.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// stdLogger is the Logger used when neither ConnectionOptions.Logger nor SetLogger set one. It writes to the standard logger,
+// prefixed with the connection's labels, like "gorods [job=42]: reconnected host=icat.example.org".
+type stdLogger struct {
+	labels string
+}
+
+func (l stdLogger) Debug(msg string, args ...interface{}) { l.print(msg, args) }
+func (l stdLogger) Info(msg string, args ...interface{})  { l.print(msg, args) }
+func (l stdLogger) Warn(msg string, args ...interface{})  { l.print(msg, args) }
+func (l stdLogger) Error(msg string, args ...interface{}) { l.print(msg, args) }
+
+func (l stdLogger) print(msg string, args []interface{}) {
+	if kv := formatKeyValues(args); kv != "" {
+		msg += " " + kv
+	}
+
+	if l.labels != "" {
+		log.Printf("gorods [%v]: %v", l.labels, msg)
+		return
+	}
+
+	log.Printf("gorods: %v", msg)
+}
+
+// formatKeyValues returns key-value pairs as "key=value" separated by spaces, values containing spaces are quoted
+func formatKeyValues(args []interface{}) string {
+	pairs := make([]string, 0, len(args)/2)
+
+	for i := 0; i+1 < len(args); i += 2 {
+		v := fmt.Sprint(args[i+1])
+
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+
+		pairs = append(pairs, fmt.Sprintf("%v=%v", args[i], v))
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// pkgLog is the Logger set with SetLogger
+var pkgLog struct {
+	mu     sync.RWMutex
+	logger Logger
+	level  int
+}
+
+// SetLogger sets the Logger receiving the diagnostics that aren't tied to a connection at level and above (LogWarn
+// by default): policy loading, leak reports, and the failures of HttpHandler and of the rest and webdav packages.
+// It also receives the diagnostics of connections without ConnectionOptions.Logger. A nil logger writes them to
+// the standard logger.
+func SetLogger(logger Logger, level int) {
+	pkgLog.mu.Lock()
+	defer pkgLog.mu.Unlock()
+
+	pkgLog.logger = logger
+	pkgLog.level = level
+}
+
+// Log writes msg and the key-value pairs in args at level to the Logger set with SetLogger, for the packages built
+// on GoRODS
+func Log(level int, msg string, args ...interface{}) {
+	logLabeled(level, nil, msg, args...)
+}
+
+// logLabeled is Log for diagnostics of a connection's labels
+func logLabeled(level int, labels map[string]string, msg string, args ...interface{}) {
+	pkgLog.mu.RLock()
+	logger, min := pkgLog.logger, pkgLog.level
+	pkgLog.mu.RUnlock()
+
+	if level < min {
+		return
+	}
+
+	writeLog(logger, level, labels, msg, args)
+}
+
+// log writes msg and the key-value pairs in args at level, if ConnectionOptions.LogLevel allows it
+func (con *Connection) log(level int, msg string, args ...interface{}) {
+	var logger Logger
+
+	if con.Options != nil {
+		if level < con.Options.LogLevel {
+			return
+		}
+
+		logger = con.Options.Logger
+	} else if level < LogWarn {
+		return
+	}
+
+	if logger == nil {
+		pkgLog.mu.RLock()
+		logger = pkgLog.logger
+		pkgLog.mu.RUnlock()
+	}
+
+	writeLog(logger, level, con.Labels(), msg, args)
+}
+
+// writeLog hands msg to logger, or to the standard logger if nil, with the labels appended to the key-value pairs
+func writeLog(logger Logger, level int, labels map[string]string, msg string, args []interface{}) {
+	if logger == nil {
+		logger = stdLogger{labels: formatLabels(labels)}
+	} else if len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+
+		for k := range labels {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			args = append(args, k, labels[k])
+		}
+	}
+
+	switch {
+	case level >= LogError:
+		logger.Error(msg, args...)
+	case level == LogWarn:
+		logger.Warn(msg, args...)
+	case level == LogInfo:
+		logger.Info(msg, args...)
+	default:
+		logger.Debug(msg, args...)
+	}
+}
+
+// logf writes a formatted warning
+func (con *Connection) logf(format string, args ...interface{}) {
+	con.log(LogWarn, fmt.Sprintf(format, args...))
+}
+
+// logEvent is called by intercept once an operation ran
+func (con *Connection) logEvent(e *Event) {
+	args := []interface{}{"op", opName(e.Op)}
+
+	if e.Path != "" {
+		args = append(args, "path", e.Path)
+	}
+
+	if e.Dest != "" {
+		args = append(args, "dest", e.Dest)
+	}
+
+	if e.Query != "" {
+		args = append(args, "query", e.Query)
+	}
+
+	if e.Size > 0 {
+		args = append(args, "size", e.Size)
+	}
+
+	args = append(args, "duration", e.Duration)
+
+	if e.Err != nil {
+		if gErr, ok := e.Err.(*GoRodsError); ok {
+			args = append(args, "status", gErr.Status, "code", gErr.ErrorName)
+		}
+
+		con.log(LogInfo, "operation failed", append(args, "error", e.Err)...)
+		return
+	}
+
+	if e.Op == OpPut {
+		con.log(LogInfo, "transferred", args...)
+		return
+	}
+
+	con.log(LogDebug, "operation done", args...)
+}
@@ -0,0 +1,102 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) write(level string, msg string, args []interface{}) {
+	l.lines = append(l.lines, level+" "+msg+" "+formatKeyValues(args))
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) { l.write("DEBUG", msg, args) }
+func (l *testLogger) Info(msg string, args ...interface{})  { l.write("INFO", msg, args) }
+func (l *testLogger) Warn(msg string, args ...interface{})  { l.write("WARN", msg, args) }
+func (l *testLogger) Error(msg string, args ...interface{}) { l.write("ERROR", msg, args) }
+
+func TestLogger(t *testing.T) {
+	logger := new(testLogger)
+
+	con := &Connection{Options: &ConnectionOptions{
+		Logger:   logger,
+		LogLevel: LogInfo,
+		Labels:   map[string]string{"tenant": "acme", "job": "42"},
+	}}
+
+	con.log(LogDebug, "hidden")
+	con.logf("unable to reach %v", "icat")
+	con.logEvent(&Event{Op: OpPut, Path: "/z/a b.txt", Size: 10, Duration: time.Second})
+	con.logEvent(&Event{Op: OpQuery, Query: "select COLL_NAME", Duration: time.Millisecond})
+	con.logEvent(&Event{Op: OpOpen, Path: "/z/c.txt", Err: &GoRodsError{Status: -818000, ErrorName: "CAT_NO_ACCESS_PERMISSION", Message: "denied"}})
+
+	expected := []string{
+		"WARN unable to reach icat job=42 tenant=acme",
+		`INFO transferred op=put path="/z/a b.txt" size=10 duration=1s job=42 tenant=acme`,
+	}
+
+	if len(logger.lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %q", logger.lines)
+	}
+
+	for i, line := range expected {
+		if logger.lines[i] != line {
+			t.Errorf("Expected %q, got %q", line, logger.lines[i])
+		}
+	}
+
+	if want := "INFO operation failed op=open path=/z/c.txt duration=0s status=-818000 code=CAT_NO_ACCESS_PERMISSION"; logger.lines[2][:len(want)] != want {
+		t.Errorf("Unexpected failure line %q", logger.lines[2])
+	}
+}
+
+func TestFormatKeyValues(t *testing.T) {
+	if s := formatKeyValues([]interface{}{"host", "icat", "msg", "two words", "n", 3, "empty", "", "dangling"}); s != `host=icat msg="two words" n=3 empty=""` {
+		t.Errorf("Unexpected key-values %q", s)
+	}
+
+	if s := fmt.Sprint(LogDebug, LogInfo, LogWarn, LogError); s != "-2 -1 0 1" {
+		t.Errorf("Unexpected levels %v", s)
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	logger := new(testLogger)
+
+	SetLogger(logger, LogWarn)
+	defer SetLogger(nil, LogWarn)
+
+	Log(LogInfo, "hidden")
+	Log(LogError, "request failed", "url", "/z/a.txt")
+	logLeak(Leak{Kind: "DataObj", Path: "/z/b.txt", Stack: "main()", Labels: map[string]string{"job": "42"}})
+
+	// Connections without a Logger of their own use it too
+	con := &Connection{Options: &ConnectionOptions{LogLevel: LogInfo}}
+	con.log(LogInfo, "connected", "host", "icat")
+
+	expected := []string{
+		"ERROR request failed url=/z/a.txt",
+		"WARN DataObj /z/b.txt was not closed, opened at:\nmain() job=42",
+		"INFO connected host=icat",
+	}
+
+	if len(logger.lines) != len(expected) {
+		t.Fatalf("Expected %v lines, got %q", len(expected), logger.lines)
+	}
+
+	for i, line := range expected {
+		if logger.lines[i] != line {
+			t.Errorf("Expected %q, got %q", line, logger.lines[i])
+		}
+	}
+}
@@ -0,0 +1,43 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"github.com/jjacquay712/GoRODS/api"
+)
+
+// DefaultUserMessage is returned by UserMessage when no catalog provides a message for an error.
+// It intentionally contains no internal details.
+const DefaultUserMessage = api.DefaultUserMessage
+
+// MessageCatalog maps errors to localized, user-facing messages, see api.MessageCatalog
+type MessageCatalog = api.MessageCatalog
+
+// MessageCatalogFunc is an adapter to allow the use of ordinary functions as a MessageCatalog
+type MessageCatalogFunc = api.MessageCatalogFunc
+
+// MapCatalog is a simple MessageCatalog, keyed by language and then by iRODS error name or numeric status,
+// see api.MapCatalog
+type MapCatalog = api.MapCatalog
+
+// SetMessageCatalog installs the catalog used by UserMessage. Pass nil to remove it.
+func SetMessageCatalog(catalog MessageCatalog) {
+	api.SetMessageCatalog(catalog)
+}
+
+// UserMessage returns err.UserMessage(lang) if err is a *GoRodsError, and DefaultUserMessage for any other non-nil error
+func UserMessage(err error, lang string) string {
+	if err == nil {
+		return ""
+	}
+
+	if gerr, ok := err.(*GoRodsError); ok {
+		return gerr.UserMessage(lang)
+	}
+
+	return DefaultUserMessage
+}
@@ -0,0 +1,76 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMessageCatalog(t *testing.T) {
+	denied := &GoRodsError{Status: -818000, ErrorName: "CAT_NO_ACCESS_PERMISSION", Message: "iRODS Open Failed: /tempZone/home/rods/a.txt"}
+	unnamed := &GoRodsError{Status: -1, Message: "iRODS Put Failed: /tmp/a.txt"}
+
+	SetMessageCatalog(nil)
+
+	if msg := UserMessage(denied, "en"); msg != DefaultUserMessage {
+		t.Errorf("Expected the default message without a catalog, got %q", msg)
+	}
+
+	SetMessageCatalog(MapCatalog{
+		"de": {"CAT_NO_ACCESS_PERMISSION": "Zugriff verweigert"},
+		"":   {"CAT_NO_ACCESS_PERMISSION": "Access denied", "-1": "Something went wrong"},
+	})
+	defer SetMessageCatalog(nil)
+
+	if msg := UserMessage(denied, "de"); msg != "Zugriff verweigert" {
+		t.Errorf("Expected the German message, got %q", msg)
+	}
+
+	// Languages without a message fall back to the "" language, names without an entry to the numeric status
+	if msg := UserMessage(denied, "fr"); msg != "Access denied" {
+		t.Errorf("Expected the fallback message, got %q", msg)
+	}
+
+	if msg := UserMessage(unnamed, "de"); msg != "Something went wrong" {
+		t.Errorf("Expected a lookup by status, got %q", msg)
+	}
+
+	if msg := UserMessage(&GoRodsError{Status: -808000, ErrorName: "CAT_NO_ROWS_FOUND"}, "de"); msg != DefaultUserMessage {
+		t.Errorf("Expected the default message for unknown errors, got %q", msg)
+	}
+
+	if msg := UserMessage(errors.New("/tempZone/home/rods: boom"), "en"); msg != DefaultUserMessage {
+		t.Errorf("Expected the default message for other errors, got %q", msg)
+	}
+
+	if msg := UserMessage(nil, "en"); msg != "" {
+		t.Errorf("Expected no message without an error, got %q", msg)
+	}
+}
+
+func TestMessageCatalogFunc(t *testing.T) {
+	SetMessageCatalog(MessageCatalogFunc(func(err *GoRodsError, lang string) string {
+		if lang != "en" {
+			return ""
+		}
+
+		return fmt.Sprintf("Request failed (%v, code %d)", err.ErrorName, err.Status)
+	}))
+	defer SetMessageCatalog(nil)
+
+	err := &GoRodsError{Status: -818000, ErrorName: "CAT_NO_ACCESS_PERMISSION"}
+
+	if msg := err.UserMessage("en"); msg != "Request failed (CAT_NO_ACCESS_PERMISSION, code -818000)" {
+		t.Errorf("Unexpected formatted message %q", msg)
+	}
+
+	if msg := err.UserMessage("de"); msg != DefaultUserMessage {
+		t.Errorf("Expected an empty catalog message to fall back to the default, got %q", msg)
+	}
+}
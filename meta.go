@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
  *** For more information please refer to the LICENSE.md file                                   ***/
 
@@ -77,6 +80,25 @@ func (m *Meta) getTypeRodsString() string {
 	return GetShortTypeString(m.Parent.Obj.Type())
 }
 
+// event returns the OpMeta event of action (add, rm or set) on the AVU, set holds the new AVU of a set
+func (m *Meta) event(action string, set *Meta) *Event {
+	params := map[string]string{
+		"action":    action,
+		"type":      m.getTypeRodsString(),
+		"attribute": m.Attribute,
+		"value":     m.Value,
+		"units":     m.Units,
+	}
+
+	if set != nil {
+		params["new_attribute"] = set.Attribute
+		params["new_value"] = set.Value
+		params["new_units"] = set.Units
+	}
+
+	return &Event{Op: OpMeta, Path: m.Parent.Obj.Path(), Params: params}
+}
+
 // SetValue will modify metadata AVU value only
 func (m *Meta) SetValue(value string) (*Meta, error) {
 	return m.Set(value, m.Units)
@@ -114,16 +136,20 @@ func (m *Meta) Delete() (*MetaCollection, error) {
 
 	var err *C.char
 
-	ccon := m.Parent.Con.GetCcon()
+	if er := m.Parent.Con.intercept(m.event("rm", nil), func() error {
+		ccon := m.Parent.Con.GetCcon()
+		defer m.Parent.Con.ReturnCcon(ccon)
 
-	if status := C.gorods_rm_meta(mT, path, oa, ov, ou, ccon, &err); status < 0 {
-		m.Parent.Con.ReturnCcon(ccon)
+		if status := C.gorods_rm_meta(mT, path, oa, ov, ou, ccon, &err); status < 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS rm Meta Failed: %v, %v", m.Parent.Obj.Path(), C.GoString(err)))
+		}
 
-		return m.Parent, newError(Fatal, status, fmt.Sprintf("iRODS rm Meta Failed: %v, %v", m.Parent.Obj.Path(), C.GoString(err)))
+		return nil
+	}); er != nil {
+		return m.Parent, er
 	}
 
-	m.Parent.Con.ReturnCcon(ccon)
-
+	m.Parent.Con.InvalidateCache(m.Parent.Obj.Path())
 	m.Parent.Refresh()
 
 	return m.Parent, nil
@@ -153,15 +179,22 @@ func (m *Meta) SetAll(attributeName string, value string, units string) (newMeta
 
 		var err *C.char
 
-		ccon := m.Parent.Con.GetCcon()
+		set := &Meta{Attribute: attributeName, Value: value, Units: units}
+
+		if e = m.Parent.Con.intercept(m.event("set", set), func() error {
+			ccon := m.Parent.Con.GetCcon()
+			defer m.Parent.Con.ReturnCcon(ccon)
+
+			if status := C.gorods_mod_meta(mT, path, oa, ov, ou, na, nv, nu, ccon, &err); status < 0 {
+				return newError(Fatal, status, fmt.Sprintf("iRODS Set Meta Failed: %v, %v", m.Parent.Obj.Path(), C.GoString(err)))
+			}
 
-		if status := C.gorods_mod_meta(mT, path, oa, ov, ou, na, nv, nu, ccon, &err); status < 0 {
-			m.Parent.Con.ReturnCcon(ccon)
-			e = newError(Fatal, status, fmt.Sprintf("iRODS Set Meta Failed: %v, %v", m.Parent.Obj.Path(), C.GoString(err)))
+			return nil
+		}); e != nil {
 			return
 		}
 
-		m.Parent.Con.ReturnCcon(ccon)
+		m.Parent.Con.InvalidateCache(m.Parent.Obj.Path())
 
 		m.Attribute = attributeName
 		m.Value = value
@@ -185,14 +218,49 @@ func (mc *MetaCollection) init() error {
 	return nil
 }
 
-// Refresh clears existing metadata triples and grabs updated copy from iCAT server.
-// It's an alias of ReadMeta()
+// Refresh clears existing metadata triples and grabs updated copy from iCAT server, bypassing ConnectionOptions.Cache
 func (mc *MetaCollection) Refresh() error {
+	if mc.Con.cache() != nil {
+		mc.Con.cache().Invalidate(func(key string) bool {
+			return key == CacheKey(CacheMeta, mc.Obj.Path())
+		})
+	}
+
 	return mc.ReadMeta()
 }
 
 // ReadMeta clears existing metadata triples and grabs updated copy from iCAT server.
+// Results are served from ConnectionOptions.Cache when set.
 func (mc *MetaCollection) ReadMeta() error {
+	if v, ok := mc.Con.cacheGet(CacheMeta, mc.Obj.Path()); ok {
+		mc.Metas = make(Metas, 0)
+
+		for _, cm := range v.([]Meta) {
+			m := cm
+			m.Parent = mc
+			mc.Metas = append(mc.Metas, &m)
+		}
+
+		return nil
+	}
+
+	if err := mc.readMeta(); err != nil {
+		return err
+	}
+
+	if mc.Con.cache() != nil {
+		cached := make([]Meta, 0, len(mc.Metas))
+		for _, m := range mc.Metas {
+			cached = append(cached, Meta{Attribute: m.Attribute, Value: m.Value, Units: m.Units})
+		}
+
+		mc.Con.cacheSet(CacheMeta, mc.Obj.Path(), cached)
+	}
+
+	return nil
+}
+
+func (mc *MetaCollection) readMeta() error {
 	var (
 		err        *C.char
 		metaResult C.goRodsMetaResult_t
@@ -431,15 +499,20 @@ func (mc *MetaCollection) Add(m Meta) (*Meta, error) {
 
 		var err *C.char
 
-		ccon := m.Parent.Con.GetCcon()
+		if er := m.Parent.Con.intercept(m.event("add", nil), func() error {
+			ccon := m.Parent.Con.GetCcon()
+			defer m.Parent.Con.ReturnCcon(ccon)
 
-		if status := C.gorods_add_meta(mT, path, na, nv, nu, ccon, &err); status < 0 {
-			m.Parent.Con.ReturnCcon(ccon)
-			return nil, newError(Fatal, status, fmt.Sprintf("iRODS Add Meta Failed: %v, %v", m.Parent.Obj.Path(), C.GoString(err)))
-		}
+			if status := C.gorods_add_meta(mT, path, na, nv, nu, ccon, &err); status < 0 {
+				return newError(Fatal, status, fmt.Sprintf("iRODS Add Meta Failed: %v, %v", m.Parent.Obj.Path(), C.GoString(err)))
+			}
 
-		m.Parent.Con.ReturnCcon(ccon)
+			return nil
+		}); er != nil {
+			return nil, er
+		}
 
+		m.Parent.Con.InvalidateCache(m.Parent.Obj.Path())
 		m.Parent.Refresh()
 
 	} else {
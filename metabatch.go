@@ -0,0 +1,518 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// Entity types used in metadata manifests, matching the atomic metadata API
+const (
+	MetaEntityDataObj    = "data_object"
+	MetaEntityCollection = "collection"
+)
+
+// Operations used in metadata manifests. An empty operation is treated as MetaOpAdd.
+const (
+	MetaOpAdd    = "add"
+	MetaOpRemove = "remove"
+)
+
+// DefaultMetaBatchSize is the number of AVU operations sent in a single atomic request by ImportMeta
+const DefaultMetaBatchSize = 100
+
+// metaCSVHeader is the header row written and expected by the CSV manifest format
+var metaCSVHeader = []string{"path", "type", "attribute", "value", "units", "operation"}
+
+// MetaRecord is a single AVU of a single object, as exported by ExportMeta or read from a manifest file
+type MetaRecord struct {
+	Path      string `json:"path"`
+	Type      string `json:"type"`
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+	Units     string `json:"units,omitempty"`
+	Operation string `json:"operation,omitempty"`
+}
+
+// MetaOperation is a single add or remove operation applied by ApplyMetaOperations
+type MetaOperation struct {
+	Operation string `json:"operation"`
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+	Units     string `json:"units,omitempty"`
+}
+
+// MetaImportOptions are used by ImportMeta. BatchSize is the maximum number of operations sent per atomic
+// request (DefaultMetaBatchSize if 0). ContinueOnError records failed objects in the report instead of stopping.
+type MetaImportOptions struct {
+	BatchSize       int
+	ContinueOnError bool
+}
+
+// MetaImportFailure records an object whose metadata couldn't be applied by ImportMeta
+type MetaImportFailure struct {
+	Path string
+	Err  error
+}
+
+// String returns the path and error of the failure
+func (f MetaImportFailure) String() string {
+	return fmt.Sprintf("%v: %v", f.Path, f.Err)
+}
+
+// MetaImportReport is returned by ImportMeta
+type MetaImportReport struct {
+	Objects  int
+	Applied  int
+	Batches  int
+	Failures []MetaImportFailure
+}
+
+// OK returns true if no object failed
+func (r *MetaImportReport) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// metaEntityType maps a DataObjType or CollectionType to its manifest entity type
+func metaEntityType(typ int) string {
+	if typ == CollectionType {
+		return MetaEntityCollection
+	}
+
+	return MetaEntityDataObj
+}
+
+// ApplyMetaOperations applies ops to the object at p. typ is DataObjType or CollectionType. With iRODS 4.2.8 and
+// later servers and client libraries, the operations are sent in a single atomic request: either every operation
+// succeeds or none do. Older ones are sent the operations one by one, and those which succeeded aren't rolled back
+// if one fails. MetaCollections already loaded by open objects aren't refreshed; call Refresh() on them if needed.
+func (con *Connection) ApplyMetaOperations(p string, typ int, ops []MetaOperation) error {
+	_, err := con.applyMetaOperations(p, typ, ops)
+
+	return err
+}
+
+// applyMetaOperations applies ops like ApplyMetaOperations, and returns the number of leading operations applied:
+// all or none of them with the atomic API, those before the failed one otherwise
+func (con *Connection) applyMetaOperations(p string, typ int, ops []MetaOperation) (int, error) {
+	if len(ops) == 0 {
+		return 0, nil
+	}
+
+	for i := range ops {
+		if ops[i].Operation == "" {
+			ops[i].Operation = MetaOpAdd
+		}
+		if ops[i].Operation != MetaOpAdd && ops[i].Operation != MetaOpRemove {
+			return 0, newError(Fatal, -1, fmt.Sprintf("iRODS Apply Meta Failed: unknown operation %q", ops[i].Operation))
+		}
+	}
+
+	input, er := json.Marshal(struct {
+		EntityName string          `json:"entity_name"`
+		EntityType string          `json:"entity_type"`
+		Operations []MetaOperation `json:"operations"`
+	}{p, metaEntityType(typ), ops})
+	if er != nil {
+		return 0, newError(Fatal, -1, fmt.Sprintf("iRODS Apply Meta Failed: %v", er))
+	}
+
+	cInput := C.CString(string(input))
+	defer C.free(unsafe.Pointer(cInput))
+
+	var (
+		err     *C.char
+		output  *C.char
+		applied int
+	)
+
+	opsJSON, _ := json.Marshal(ops)
+	params := map[string]string{"action": "apply", "type": metaEntityType(typ), "operations": string(opsJSON)}
+
+	er = con.intercept(&Event{Op: OpMeta, Path: p, Params: params}, func() error {
+		applied = 0
+
+		if con.lacks(func(c Capabilities) bool { return c.AtomicMetadata }) {
+			return con.applyEachMetaOperation(p, typ, ops, &applied)
+		}
+
+		ccon := con.GetCcon()
+		status := C.gorods_atomic_apply_metadata_operations(cInput, &output, ccon, &err)
+		con.ReturnCcon(ccon)
+
+		var detail string
+
+		if output != nil {
+			detail = C.GoString(output)
+			C.free(unsafe.Pointer(output))
+			output = nil
+		}
+
+		if status == C.SYS_UNMATCHED_API_NUM {
+			return con.applyEachMetaOperation(p, typ, ops, &applied)
+		}
+
+		if status < 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Apply Meta Failed: %v, %v %v", p, C.GoString(err), detail))
+		}
+
+		applied = len(ops)
+
+		return nil
+	})
+
+	con.InvalidateCache(p)
+
+	return applied, er
+}
+
+// applyEachMetaOperation applies ops to the object at p with a separate add or rm request each, counting those
+// applied in applied. It stops at the first failure.
+func (con *Connection) applyEachMetaOperation(p string, typ int, ops []MetaOperation, applied *int) error {
+	mT := C.CString(GetShortTypeString(typ))
+	path := C.CString(p)
+	defer C.free(unsafe.Pointer(mT))
+	defer C.free(unsafe.Pointer(path))
+
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	for _, op := range ops {
+		var err *C.char
+
+		a := C.CString(op.Attribute)
+		v := C.CString(op.Value)
+		u := C.CString(op.Units)
+
+		var status C.int
+		if op.Operation == MetaOpRemove {
+			status = C.gorods_rm_meta(mT, path, a, v, u, ccon, &err)
+		} else {
+			status = C.gorods_add_meta(mT, path, a, v, u, ccon, &err)
+		}
+
+		C.free(unsafe.Pointer(a))
+		C.free(unsafe.Pointer(v))
+		C.free(unsafe.Pointer(u))
+
+		if status < 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Apply Meta Failed: %v, %v %v of %v", p, C.GoString(err), op.Operation, op.Attribute))
+		}
+
+		*applied++
+	}
+
+	return nil
+}
+
+// ExportMeta returns every AVU attached to the collection at p and to the collections and data objects it contains, recursively.
+// Records are sorted by path and attribute.
+func (con *Connection) ExportMeta(p string) ([]MetaRecord, error) {
+	p = strings.TrimRight(p, "/")
+
+	zone, err := con.zoneHint(p)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]MetaRecord, 0)
+
+	for _, query := range []string{
+		fmt.Sprintf("select COLL_NAME, META_COLL_ATTR_NAME, META_COLL_ATTR_VALUE, META_COLL_ATTR_UNITS where COLL_NAME = '%v'", p),
+		fmt.Sprintf("select COLL_NAME, META_COLL_ATTR_NAME, META_COLL_ATTR_VALUE, META_COLL_ATTR_UNITS where COLL_NAME like '%v/%%'", p),
+	} {
+		result, err := con.IQuestZone(query, false, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range result {
+			records = append(records, MetaRecord{
+				Path:      row["COLL_NAME"],
+				Type:      MetaEntityCollection,
+				Attribute: row["META_COLL_ATTR_NAME"],
+				Value:     row["META_COLL_ATTR_VALUE"],
+				Units:     row["META_COLL_ATTR_UNITS"],
+			})
+		}
+	}
+
+	for _, query := range []string{
+		fmt.Sprintf("select COLL_NAME, DATA_NAME, META_DATA_ATTR_NAME, META_DATA_ATTR_VALUE, META_DATA_ATTR_UNITS where COLL_NAME = '%v'", p),
+		fmt.Sprintf("select COLL_NAME, DATA_NAME, META_DATA_ATTR_NAME, META_DATA_ATTR_VALUE, META_DATA_ATTR_UNITS where COLL_NAME like '%v/%%'", p),
+	} {
+		result, err := con.IQuestZone(query, false, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range result {
+			records = append(records, MetaRecord{
+				Path:      row["COLL_NAME"] + "/" + row["DATA_NAME"],
+				Type:      MetaEntityDataObj,
+				Attribute: row["META_DATA_ATTR_NAME"],
+				Value:     row["META_DATA_ATTR_VALUE"],
+				Units:     row["META_DATA_ATTR_UNITS"],
+			})
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Path != records[j].Path {
+			return records[i].Path < records[j].Path
+		}
+		return records[i].Attribute < records[j].Attribute
+	})
+
+	return records, nil
+}
+
+// ExportMeta returns every AVU attached to the collection and everything it contains, recursively
+func (col *Collection) ExportMeta() ([]MetaRecord, error) {
+	return col.con.ExportMeta(col.path)
+}
+
+// ExportMetaFile writes every AVU under the collection at p to localPath.
+// The format is CSV if localPath ends in ".csv" (or ".csv.gz"), JSON otherwise, gzipped if it ends in ".gz".
+func (con *Connection) ExportMetaFile(p string, localPath string) error {
+	records, err := con.ExportMeta(p)
+	if err != nil {
+		return err
+	}
+
+	f, er := createExportFile(localPath)
+	if er != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Export Meta Failed: %v", er))
+	}
+
+	if isCSVPath(localPath) {
+		err = WriteMetaCSV(f, records)
+	} else {
+		err = WriteMetaJSON(f, records)
+	}
+
+	if er := f.Close(); er != nil && err == nil {
+		err = newError(Fatal, -1, fmt.Sprintf("Export Meta Failed: %v", er))
+	}
+
+	return err
+}
+
+// ImportMeta applies the records to their objects, grouping the operations of each object into atomic batches of
+// at most opts.BatchSize. Records with an empty Type default to a data object.
+func (con *Connection) ImportMeta(records []MetaRecord, opts MetaImportOptions) (*MetaImportReport, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultMetaBatchSize
+	}
+
+	report := new(MetaImportReport)
+	report.Failures = make([]MetaImportFailure, 0)
+
+	order := make([]string, 0)
+	ops := make(map[string][]MetaOperation)
+	types := make(map[string]int)
+
+	for _, rec := range records {
+		if rec.Path == "" || rec.Attribute == "" {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Import Meta Failed: record is missing a path or attribute: %+v", rec))
+		}
+
+		p := strings.TrimRight(rec.Path, "/")
+
+		if _, ok := ops[p]; !ok {
+			order = append(order, p)
+
+			switch rec.Type {
+			case MetaEntityCollection:
+				types[p] = CollectionType
+			case MetaEntityDataObj, "":
+				types[p] = DataObjType
+			default:
+				return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Import Meta Failed: unknown entity type %q for %v", rec.Type, p))
+			}
+		}
+
+		ops[p] = append(ops[p], MetaOperation{
+			Operation: rec.Operation,
+			Attribute: rec.Attribute,
+			Value:     rec.Value,
+			Units:     rec.Units,
+		})
+	}
+
+	for _, p := range order {
+		report.Objects++
+
+		list := ops[p]
+
+		for start := 0; start < len(list); start += batchSize {
+			end := start + batchSize
+			if end > len(list) {
+				end = len(list)
+			}
+
+			report.Batches++
+
+			if err := con.ApplyMetaOperations(p, types[p], list[start:end]); err != nil {
+				if !opts.ContinueOnError {
+					return report, err
+				}
+
+				report.Failures = append(report.Failures, MetaImportFailure{Path: p, Err: err})
+				break
+			}
+
+			report.Applied += end - start
+		}
+	}
+
+	return report, nil
+}
+
+// ImportMetaFile reads a manifest from localPath and applies it with ImportMeta.
+// The format is CSV if localPath ends in ".csv" (or ".csv.gz"), JSON otherwise, gzipped if it ends in ".gz".
+func (con *Connection) ImportMetaFile(localPath string, opts MetaImportOptions) (*MetaImportReport, error) {
+	f, er := openImportFile(localPath)
+	if er != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Import Meta Failed: %v", er))
+	}
+	defer f.Close()
+
+	var (
+		records []MetaRecord
+		err     error
+	)
+
+	if isCSVPath(localPath) {
+		records, err = ReadMetaCSV(f)
+	} else {
+		records, err = ReadMetaJSON(f)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return con.ImportMeta(records, opts)
+}
+
+func isCSVPath(localPath string) bool {
+	if isGzipPath(localPath) {
+		localPath = localPath[:len(localPath)-len(filepath.Ext(localPath))]
+	}
+
+	return strings.ToLower(filepath.Ext(localPath)) == ".csv"
+}
+
+// WriteMetaJSON writes the records to w as a JSON array
+func WriteMetaJSON(w io.Writer, records []MetaRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(records); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Write Meta JSON Failed: %v", err))
+	}
+
+	return nil
+}
+
+// ReadMetaJSON reads a JSON array of records written by WriteMetaJSON, or a hand-written manifest in the same format
+func ReadMetaJSON(r io.Reader) ([]MetaRecord, error) {
+	records := make([]MetaRecord, 0)
+
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Read Meta JSON Failed: %v", err))
+	}
+
+	return records, nil
+}
+
+// WriteMetaCSV writes the records to w as CSV, with a "path,type,attribute,value,units,operation" header row
+func WriteMetaCSV(w io.Writer, records []MetaRecord) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(metaCSVHeader); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Write Meta CSV Failed: %v", err))
+	}
+
+	for _, rec := range records {
+		if err := cw.Write([]string{rec.Path, rec.Type, rec.Attribute, rec.Value, rec.Units, rec.Operation}); err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("Write Meta CSV Failed: %v", err))
+		}
+	}
+
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Write Meta CSV Failed: %v", err))
+	}
+
+	return nil
+}
+
+// ReadMetaCSV reads records written by WriteMetaCSV. The header row is required, but the columns can be in any order
+// and the "type", "units" and "operation" columns are optional.
+func ReadMetaCSV(r io.Reader) ([]MetaRecord, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Read Meta CSV Failed: %v", err))
+	}
+
+	cols := make(map[string]int)
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, required := range []string{"path", "attribute", "value"} {
+		if _, ok := cols[required]; !ok {
+			return nil, newError(Fatal, -1, fmt.Sprintf("Read Meta CSV Failed: missing %q column", required))
+		}
+	}
+
+	field := func(row []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	records := make([]MetaRecord, 0)
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, newError(Fatal, -1, fmt.Sprintf("Read Meta CSV Failed: %v", err))
+		}
+
+		records = append(records, MetaRecord{
+			Path:      field(row, "path"),
+			Type:      field(row, "type"),
+			Attribute: field(row, "attribute"),
+			Value:     field(row, "value"),
+			Units:     field(row, "units"),
+			Operation: field(row, "operation"),
+		})
+	}
+
+	return records, nil
+}
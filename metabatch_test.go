@@ -0,0 +1,96 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetaManifestFormats(t *testing.T) {
+	records := []MetaRecord{
+		{Path: "/tempZone/home/rods/a.txt", Type: MetaEntityDataObj, Attribute: "project", Value: "x, y", Units: "tag"},
+		{Path: "/tempZone/home/rods/sub", Type: MetaEntityCollection, Attribute: "owner", Value: "curation", Operation: MetaOpRemove},
+	}
+
+	var buf bytes.Buffer
+
+	if err := WriteMetaCSV(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+
+	if read, err := ReadMetaCSV(&buf); err != nil || !reflect.DeepEqual(read, records) {
+		t.Errorf("CSV round trip failed: %+v, %v", read, err)
+	}
+
+	buf.Reset()
+
+	if err := WriteMetaJSON(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+
+	if read, err := ReadMetaJSON(&buf); err != nil || !reflect.DeepEqual(read, records) {
+		t.Errorf("JSON round trip failed: %+v, %v", read, err)
+	}
+
+	// Optional columns may be omitted and reordered
+	read, err := ReadMetaCSV(strings.NewReader("value,path,attribute\n1,/tempZone/home/rods/b.txt,run\n"))
+	if err != nil || len(read) != 1 || read[0].Value != "1" || read[0].Attribute != "run" || read[0].Type != "" {
+		t.Errorf("Unexpected records %+v, %v", read, err)
+	}
+
+	if _, err := ReadMetaCSV(strings.NewReader("path,value\n")); err == nil {
+		t.Error("Expected an error for a missing attribute column")
+	}
+}
+
+func TestMetaManifestGzip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gorods-meta-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	records := []MetaRecord{{Path: "/tempZone/home/rods/a.txt", Type: MetaEntityDataObj, Attribute: "project", Value: "x"}}
+	localPath := filepath.Join(dir, "manifest.csv.gz")
+
+	if !isCSVPath(localPath) || isCSVPath(filepath.Join(dir, "manifest.json.gz")) {
+		t.Error("isCSVPath should ignore the .gz extension")
+	}
+
+	out, err := createExportFile(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteMetaCSV(out, records); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := ioutil.ReadFile(localPath); len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		t.Error("Expected a gzip stream")
+	}
+
+	in, err := openImportFile(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	if read, err := ReadMetaCSV(in); err != nil || !reflect.DeepEqual(read, records) {
+		t.Errorf("Gzip round trip failed: %+v, %v", read, err)
+	}
+}
@@ -0,0 +1,177 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"sync"
+)
+
+// MetaStage collects AVU changes to a data object or collection locally, like the unsaved edits of a form, until
+// Commit applies them all in a single atomic request or Discard drops them. Nothing is sent to the server before Commit.
+type MetaStage struct {
+	obj MetaObj
+	ops []MetaOperation
+	mu  sync.Mutex
+}
+
+// StageMeta returns an empty MetaStage for the AVUs of the data object
+func (obj *DataObj) StageMeta() *MetaStage {
+	return &MetaStage{obj: obj}
+}
+
+// StageMeta returns an empty MetaStage for the AVUs of the collection
+func (col *Collection) StageMeta() *MetaStage {
+	return &MetaStage{obj: col}
+}
+
+// Add stages the addition of the AVU
+func (s *MetaStage) Add(m Meta) *MetaStage {
+	return s.stage(MetaOperation{Operation: MetaOpAdd, Attribute: m.Attribute, Value: m.Value, Units: m.Units})
+}
+
+// Remove stages the removal of the AVU
+func (s *MetaStage) Remove(m Meta) *MetaStage {
+	return s.stage(MetaOperation{Operation: MetaOpRemove, Attribute: m.Attribute, Value: m.Value, Units: m.Units})
+}
+
+// Set stages the replacement of every AVU with the attribute by the one specified. The current AVUs are read
+// from the object's MetaCollection.
+func (s *MetaStage) Set(attr string, value string, units string) error {
+	current, err := s.View()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range current {
+		if m.Attribute == attr {
+			s.Remove(*m)
+		}
+	}
+
+	s.Add(Meta{Attribute: attr, Value: value, Units: units})
+
+	return nil
+}
+
+// Unset stages the removal of every AVU with the attribute, see Set
+func (s *MetaStage) Unset(attr string) error {
+	current, err := s.View()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range current {
+		if m.Attribute == attr {
+			s.Remove(*m)
+		}
+	}
+
+	return nil
+}
+
+func (s *MetaStage) stage(op MetaOperation) *MetaStage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ops = append(s.ops, op)
+
+	return s
+}
+
+// Pending returns the staged operations, in the order they were staged
+func (s *MetaStage) Pending() []MetaOperation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]MetaOperation(nil), s.ops...)
+}
+
+// Dirty returns true if there are staged operations
+func (s *MetaStage) Dirty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.ops) > 0
+}
+
+// View returns the AVUs the object will have once the staged operations are committed
+func (s *MetaStage) View() (Metas, error) {
+	mc, err := s.obj.Meta()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := mc.All()
+	if err != nil {
+		return nil, err
+	}
+
+	return applyStagedMeta(current, s.Pending()), nil
+}
+
+// applyStagedMeta returns the AVUs resulting from applying ops to current, without modifying current
+func applyStagedMeta(current Metas, ops []MetaOperation) Metas {
+	view := make(Metas, 0, len(current))
+
+	for _, m := range current {
+		c := *m
+		view = append(view, &c)
+	}
+
+	for _, op := range ops {
+		idx := -1
+		for i, m := range view {
+			if m.Attribute == op.Attribute && m.Value == op.Value && m.Units == op.Units {
+				idx = i
+				break
+			}
+		}
+
+		if op.Operation == MetaOpRemove {
+			if idx >= 0 {
+				view = append(view[:idx], view[idx+1:]...)
+			}
+		} else if idx < 0 {
+			view = append(view, &Meta{Attribute: op.Attribute, Value: op.Value, Units: op.Units})
+		}
+	}
+
+	return view
+}
+
+// Discard drops the staged operations
+func (s *MetaStage) Discard() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ops = nil
+}
+
+// Commit applies the staged operations with Connection.ApplyMetaOperations and clears them: in a single atomic
+// request with iRODS 4.2.8 and later servers, one by one otherwise, those which succeeded not being rolled back if
+// one fails. If Commit fails, the operations that weren't applied stay staged, so committing again retries only
+// those.
+func (s *MetaStage) Commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.ops) == 0 {
+		return nil
+	}
+
+	ops := append([]MetaOperation(nil), s.ops...)
+
+	applied, err := s.obj.Con().applyMetaOperations(s.obj.Path(), s.obj.Type(), ops)
+
+	if mc, er := s.obj.Meta(); er == nil {
+		mc.Refresh()
+	}
+
+	s.ops = s.ops[applied:]
+
+	return err
+}
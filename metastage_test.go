@@ -0,0 +1,37 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestApplyStagedMeta(t *testing.T) {
+	current := Metas{
+		{Attribute: "status", Value: "draft"},
+		{Attribute: "owner", Value: "alice"},
+	}
+
+	view := applyStagedMeta(current, []MetaOperation{
+		{Operation: MetaOpRemove, Attribute: "status", Value: "draft"},
+		{Operation: MetaOpAdd, Attribute: "status", Value: "final"},
+		{Operation: MetaOpAdd, Attribute: "owner", Value: "alice"},
+		{Operation: MetaOpRemove, Attribute: "missing", Value: "x"},
+	})
+
+	if len(view) != 2 {
+		t.Fatalf("Expected 2 AVUs, got %v", len(view))
+	}
+
+	if view.MatchOne(&Meta{Attribute: "status", Value: "final"}) == nil || view.MatchOne(&Meta{Attribute: "status", Value: "draft"}) != nil {
+		t.Errorf("Expected status to be replaced, got %v", view)
+	}
+
+	if current[0].Value != "draft" || len(current) != 2 {
+		t.Error("Expected the current AVUs to be left unchanged")
+	}
+}
@@ -0,0 +1,451 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetaNamespacer is implemented by metadata structs whose AVUs are namespaced: their attributes are prefixed with
+// MetaNamespace() and a colon, like "sequencing:sample_id".
+type MetaNamespacer interface {
+	MetaNamespace() string
+}
+
+// MetaValidator is implemented by metadata structs with checks of their own. Validate is called by SetMetaStruct
+// before any AVU is written, and by GetMetaStruct once the struct is filled.
+type MetaValidator interface {
+	Validate() error
+}
+
+// metaField is a field of a metadata struct, see SetMetaStruct
+type metaField struct {
+	index     int
+	attribute string
+	units     string
+	required  bool
+}
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// SetMetaStruct stores the fields of the struct v (or pointer to struct) as AVUs of the data object, see
+// SetMetaStruct (the function)
+func (obj *DataObj) SetMetaStruct(v interface{}) error {
+	return SetMetaStruct(obj, v)
+}
+
+// GetMetaStruct fills the struct pointed to by v from the AVUs of the data object, see GetMetaStruct (the function)
+func (obj *DataObj) GetMetaStruct(v interface{}) error {
+	return GetMetaStruct(obj, v)
+}
+
+// SetMetaStruct stores the fields of the struct v (or pointer to struct) as AVUs of the collection, see
+// SetMetaStruct (the function)
+func (col *Collection) SetMetaStruct(v interface{}) error {
+	return SetMetaStruct(col, v)
+}
+
+// GetMetaStruct fills the struct pointed to by v from the AVUs of the collection, see GetMetaStruct (the function)
+func (col *Collection) GetMetaStruct(v interface{}) error {
+	return GetMetaStruct(col, v)
+}
+
+// SetMetaStruct stores the fields of the struct v (or pointer to struct) as AVUs of obj. Fields are mapped by their
+// "meta" tag, like `meta:"sample_id,required"` or `meta:"read_length,units=bp"`: the attribute name (the field name
+// if empty, "-" skips the field), the units of the AVU, and required, which rejects unset fields. Strings, bools,
+// numbers, time.Duration and types implementing encoding.TextMarshaler (like time.Time) are supported, and slices
+// of those, stored as one AVU per element. Empty strings, zero times and empty slices are unset: their AVUs are
+// removed. The AVUs of the struct's attributes are replaced in a single atomic request (see MetaStage.Commit),
+// the other AVUs of obj are left alone.
+func SetMetaStruct(obj MetaObj, v interface{}) error {
+	desired, attrs, err := metaStructMetas(v)
+	if err != nil {
+		return err
+	}
+
+	mc, err := obj.Meta()
+	if err != nil {
+		return err
+	}
+
+	current, err := mc.All()
+	if err != nil {
+		return err
+	}
+
+	stage := &MetaStage{obj: obj}
+
+	for _, op := range metaStructOps(current, desired, attrs) {
+		stage.stage(op)
+	}
+
+	return stage.Commit()
+}
+
+// GetMetaStruct fills the struct pointed to by v from the AVUs of obj, see SetMetaStruct for the mapping. Fields
+// without AVUs are left as they are, unless required. Units are ignored.
+func GetMetaStruct(obj MetaObj, v interface{}) error {
+	mc, err := obj.Meta()
+	if err != nil {
+		return err
+	}
+
+	current, err := mc.All()
+	if err != nil {
+		return err
+	}
+
+	return metaStructDecode(current, v)
+}
+
+// metaSchema returns the fields of the struct type t, with their namespaced attributes
+func metaSchema(t reflect.Type, namespace string) ([]metaField, error) {
+	fields := make([]metaField, 0, t.NumField())
+	seen := make(map[string]bool)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag, tagged := sf.Tag.Lookup("meta")
+		if tag == "-" || sf.PkgPath != "" || (sf.Anonymous && !tagged) {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+
+		field := metaField{index: i, attribute: parts[0]}
+		if field.attribute == "" {
+			field.attribute = sf.Name
+		}
+
+		for _, opt := range parts[1:] {
+			switch {
+			case opt == "required":
+				field.required = true
+			case strings.HasPrefix(opt, "units="):
+				field.units = strings.TrimPrefix(opt, "units=")
+			default:
+				return nil, newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: unknown option %q of field %v", opt, sf.Name))
+			}
+		}
+
+		if !metaType(sf.Type) {
+			return nil, newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: field %v has unsupported type %v", sf.Name, sf.Type))
+		}
+
+		if namespace != "" {
+			field.attribute = namespace + ":" + field.attribute
+		}
+
+		if seen[field.attribute] {
+			return nil, newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: attribute %v is used by more than one field", field.attribute))
+		}
+
+		seen[field.attribute] = true
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// metaType returns true if values of t can be stored in AVUs
+func metaType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		t = t.Elem()
+	}
+
+	if t.Implements(textMarshalerType) && reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
+
+// metaStructValue returns the struct value of v, a struct or pointer to struct, and its namespace
+func metaStructValue(v interface{}, settable bool) (reflect.Value, string, error) {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	} else if settable {
+		return rv, "", newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: expected a pointer to a struct, got %T", v))
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return rv, "", newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: expected a struct, got %T", v))
+	}
+
+	namespace := ""
+	if ns, ok := metaStructPtr(rv).(MetaNamespacer); ok {
+		namespace = ns.MetaNamespace()
+	}
+
+	return rv, namespace, nil
+}
+
+// metaStructPtr returns a pointer to the struct rv (to a copy if rv isn't addressable). Its method set has the methods
+// of both T and *T, so MetaNamespacer and MetaValidator are found whichever receiver they are declared on, and a
+// struct passed by value is handled like a pointer to it.
+func metaStructPtr(rv reflect.Value) interface{} {
+	if rv.CanAddr() {
+		return rv.Addr().Interface()
+	}
+
+	ptr := reflect.New(rv.Type())
+	ptr.Elem().Set(rv)
+
+	return ptr.Interface()
+}
+
+// metaStructMetas validates v and returns the AVUs of its fields, and the set of the struct's attributes
+func metaStructMetas(v interface{}) ([]Meta, map[string]bool, error) {
+	rv, namespace, err := metaStructValue(v, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fields, err := metaSchema(rv.Type(), namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if validator, ok := metaStructPtr(rv).(MetaValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, nil, newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: %v", err))
+		}
+	}
+
+	metas := make([]Meta, 0, len(fields))
+	attrs := make(map[string]bool, len(fields))
+
+	for _, field := range fields {
+		attrs[field.attribute] = true
+
+		fv := rv.Field(field.index)
+
+		values := []reflect.Value{fv}
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			values = values[:0]
+
+			for i := 0; i < fv.Len(); i++ {
+				values = append(values, fv.Index(i))
+			}
+		}
+
+		set := false
+
+		for _, value := range values {
+			s, err := formatMetaValue(value)
+			if err != nil {
+				return nil, nil, newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: %v, %v", field.attribute, err))
+			}
+
+			if s != "" {
+				metas = append(metas, Meta{Attribute: field.attribute, Value: s, Units: field.units})
+				set = true
+			}
+		}
+
+		if field.required && !set {
+			return nil, nil, newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: %v is required", field.attribute))
+		}
+	}
+
+	return metas, attrs, nil
+}
+
+// formatMetaValue returns the AVU value of v, "" if v is unset
+func formatMetaValue(v reflect.Value) (string, error) {
+	if z, ok := v.Interface().(interface{ IsZero() bool }); ok && z.IsZero() {
+		return "", nil
+	}
+
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String(), nil
+	}
+
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	default:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+}
+
+// parseMetaValue sets v from the AVU value s
+func parseMetaValue(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err == nil {
+			v.SetInt(int64(d))
+		}
+
+		return err
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	default:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	}
+
+	return nil
+}
+
+// metaStructDecode fills the struct pointed to by v from metas
+func metaStructDecode(metas Metas, v interface{}) error {
+	rv, namespace, err := metaStructValue(v, true)
+	if err != nil {
+		return err
+	}
+
+	fields, err := metaSchema(rv.Type(), namespace)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string][]string)
+
+	for _, m := range metas {
+		values[m.Attribute] = append(values[m.Attribute], m.Value)
+	}
+
+	for _, field := range fields {
+		vals := values[field.attribute]
+
+		if len(vals) == 0 {
+			if field.required {
+				return newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: %v is required", field.attribute))
+			}
+
+			continue
+		}
+
+		fv := rv.Field(field.index)
+
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+
+			for i, s := range vals {
+				if err := parseMetaValue(slice.Index(i), s); err != nil {
+					return newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: %v, invalid value %q: %v", field.attribute, s, err))
+				}
+			}
+
+			fv.Set(slice)
+			continue
+		}
+
+		if len(vals) > 1 {
+			return newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: %v has %v values, its field isn't a slice", field.attribute, len(vals)))
+		}
+
+		if err := parseMetaValue(fv, vals[0]); err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: %v, invalid value %q: %v", field.attribute, vals[0], err))
+		}
+	}
+
+	if validator, ok := metaStructPtr(rv).(MetaValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("Meta Struct Failed: %v", err))
+		}
+	}
+
+	return nil
+}
+
+// metaStructOps returns the operations turning the AVUs of attrs in current into desired. AVUs already in place
+// are left alone, and duplicates in desired are added once.
+func metaStructOps(current Metas, desired []Meta, attrs map[string]bool) []MetaOperation {
+	want := make(map[Meta]bool, len(desired))
+	for _, m := range desired {
+		want[m] = true
+	}
+
+	ops := make([]MetaOperation, 0)
+	have := make(map[Meta]bool)
+
+	for _, m := range current {
+		if !attrs[m.Attribute] {
+			continue
+		}
+
+		avu := Meta{Attribute: m.Attribute, Value: m.Value, Units: m.Units}
+
+		if want[avu] {
+			have[avu] = true
+			continue
+		}
+
+		ops = append(ops, MetaOperation{Operation: MetaOpRemove, Attribute: m.Attribute, Value: m.Value, Units: m.Units})
+	}
+
+	for _, m := range desired {
+		if have[m] {
+			continue
+		}
+
+		have[m] = true
+		ops = append(ops, MetaOperation{Operation: MetaOpAdd, Attribute: m.Attribute, Value: m.Value, Units: m.Units})
+	}
+
+	return ops
+}
@@ -0,0 +1,176 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testSample struct {
+	ID         string        `meta:"sample_id,required"`
+	Instrument string        `meta:"instrument"`
+	RunDate    time.Time     `meta:"run_date"`
+	Length     int           `meta:"read_length,units=bp"`
+	Paired     bool          `meta:"paired"`
+	Runtime    time.Duration `meta:"runtime"`
+	Tags       []string      `meta:"tag"`
+	Notes      string        `meta:"-"`
+	internal   string
+}
+
+func (s testSample) MetaNamespace() string {
+	return "seq"
+}
+
+func (s testSample) Validate() error {
+	if s.Length < 0 {
+		return errors.New("negative read length")
+	}
+
+	return nil
+}
+
+func TestMetaStructMetas(t *testing.T) {
+	run := time.Date(2016, 5, 4, 12, 0, 0, 0, time.UTC)
+
+	metas, attrs, err := metaStructMetas(testSample{ID: "S1", RunDate: run, Length: 150, Runtime: 90 * time.Minute, Tags: []string{"a", "b"}, Notes: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Meta{
+		{Attribute: "seq:sample_id", Value: "S1"},
+		{Attribute: "seq:run_date", Value: "2016-05-04T12:00:00Z"},
+		{Attribute: "seq:read_length", Value: "150", Units: "bp"},
+		{Attribute: "seq:paired", Value: "false"},
+		{Attribute: "seq:runtime", Value: "1h30m0s"},
+		{Attribute: "seq:tag", Value: "a"},
+		{Attribute: "seq:tag", Value: "b"},
+	}
+
+	if !reflect.DeepEqual(metas, expected) {
+		t.Errorf("Unexpected AVUs %v", metas)
+	}
+
+	if len(attrs) != 7 || !attrs["seq:instrument"] || attrs["seq:Notes"] {
+		t.Errorf("Unexpected attributes %v", attrs)
+	}
+
+	if _, _, err := metaStructMetas(testSample{Length: 1}); err == nil {
+		t.Error("Expected an error for a missing required field")
+	}
+
+	if _, _, err := metaStructMetas(testSample{ID: "S1", Length: -1}); err == nil {
+		t.Error("Expected a validation error")
+	}
+
+	if _, _, err := metaStructMetas(struct{ M map[string]string }{}); err == nil {
+		t.Error("Expected an error for an unsupported field type")
+	}
+}
+
+func TestMetaStructDecode(t *testing.T) {
+	metas := Metas{
+		{Attribute: "seq:sample_id", Value: "S2"},
+		{Attribute: "seq:read_length", Value: "100", Units: "bp"},
+		{Attribute: "seq:runtime", Value: "2s"},
+		{Attribute: "seq:tag", Value: "x"},
+		{Attribute: "seq:tag", Value: "y"},
+		{Attribute: "other", Value: "ignored"},
+	}
+
+	var s testSample
+
+	if err := metaStructDecode(metas, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.ID != "S2" || s.Length != 100 || s.Runtime != 2*time.Second || !reflect.DeepEqual(s.Tags, []string{"x", "y"}) {
+		t.Errorf("Unexpected struct %+v", s)
+	}
+
+	if err := metaStructDecode(metas, s); err == nil {
+		t.Error("Expected an error decoding into a non-pointer")
+	}
+
+	if err := metaStructDecode(append(metas, &Meta{Attribute: "seq:read_length", Value: "200"}), &s); err == nil {
+		t.Error("Expected an error for multiple values of a scalar field")
+	}
+
+	if err := metaStructDecode(Metas{{Attribute: "seq:sample_id", Value: "S3"}, {Attribute: "seq:paired", Value: "maybe"}}, &s); err == nil {
+		t.Error("Expected an error for an invalid bool")
+	}
+
+	if err := metaStructDecode(Metas{{Attribute: "seq:instrument", Value: "NovaSeq"}}, &s); err == nil {
+		t.Error("Expected an error for a missing required attribute")
+	}
+}
+
+type testPtrSample struct {
+	ID string `meta:"sample_id"`
+}
+
+func (s *testPtrSample) MetaNamespace() string {
+	return "ptr"
+}
+
+func (s *testPtrSample) Validate() error {
+	if s.ID == "invalid" {
+		return errors.New("invalid sample id")
+	}
+
+	return nil
+}
+
+func TestMetaStructPointerReceivers(t *testing.T) {
+	metas, _, err := metaStructMetas(testPtrSample{ID: "P1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metas) != 1 || metas[0].Attribute != "ptr:sample_id" {
+		t.Fatalf("Expected the namespace of a pointer receiver on a struct value, got %v", metas)
+	}
+
+	var s testPtrSample
+
+	if err := metaStructDecode(Metas{&metas[0]}, &s); err != nil || s.ID != "P1" {
+		t.Errorf("Expected the AVUs to round-trip, got %+v, %v", s, err)
+	}
+
+	if _, _, err := metaStructMetas(testPtrSample{ID: "invalid"}); err == nil {
+		t.Error("Expected the pointer receiver validator to run on a struct value")
+	}
+}
+
+func TestMetaStructOps(t *testing.T) {
+	current := Metas{
+		{Attribute: "seq:sample_id", Value: "S1"},
+		{Attribute: "seq:tag", Value: "old"},
+		{Attribute: "unrelated", Value: "kept"},
+	}
+
+	desired := []Meta{
+		{Attribute: "seq:sample_id", Value: "S1"},
+		{Attribute: "seq:tag", Value: "new"},
+		{Attribute: "seq:tag", Value: "new"},
+	}
+
+	ops := metaStructOps(current, desired, map[string]bool{"seq:sample_id": true, "seq:tag": true})
+
+	expected := []MetaOperation{
+		{Operation: MetaOpRemove, Attribute: "seq:tag", Value: "old"},
+		{Operation: MetaOpAdd, Attribute: "seq:tag", Value: "new"},
+	}
+
+	if !reflect.DeepEqual(ops, expected) {
+		t.Errorf("Unexpected operations %v", ops)
+	}
+}
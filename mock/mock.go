@@ -0,0 +1,907 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+// Package mock provides an in-memory implementation of gorods.ConnectionAPI, so applications built on GoRODS can be
+// unit tested without an iRODS server. It only imports gorods/api, so tests build without cgo or the iRODS C client
+// library. Collections, data objects and their metadata are kept in a tree rooted at /<zone>; the home collection
+// of the user is created by New. Errors are *api.GoRodsError (gorods.GoRodsError) values carrying the same ErrorName
+// an iRODS server would return, so errors.Is checks behave the same against both implementations.
+package mock
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jjacquay712/GoRODS/api"
+)
+
+// Connection is an in-memory iRODS catalog implementing api.ConnectionAPI.
+// It is safe for use by multiple goroutines.
+type Connection struct {
+	Zone     string
+	Username string
+
+	// Resource is reported in ObjStat.RescHier for data objects created without DataObjOptions.Resource
+	Resource string
+
+	nodes  map[string]*node
+	nextId int
+	mu     sync.Mutex
+}
+
+type node struct {
+	typ        int
+	id         string
+	data       []byte
+	resource   string
+	meta       []api.AVU
+	access     map[string]int
+	createTime time.Time
+	modifyTime time.Time
+}
+
+// New returns an empty catalog for zone containing the home collection of username, /<zone>/home/<username>
+func New(zone string, username string) *Connection {
+	con := new(Connection)
+
+	con.Zone = zone
+	con.Username = username
+	con.Resource = "demoResc"
+	con.nodes = make(map[string]*node)
+
+	for _, p := range []string{"/" + zone, "/" + zone + "/home", con.Home()} {
+		con.nodes[p] = con.newNode(api.CollectionType)
+	}
+
+	return con
+}
+
+// Home returns the path of the user's home collection
+func (con *Connection) Home() string {
+	return "/" + con.Zone + "/home/" + con.Username
+}
+
+// WriteFile creates or replaces the data object at p with data, creating any missing parent collections.
+// It is meant for seeding the catalog in tests.
+func (con *Connection) WriteFile(p string, data []byte) error {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	p = path.Clean(p)
+
+	if err := con.mkdirAll(path.Dir(p)); err != nil {
+		return err
+	}
+
+	if n, ok := con.nodes[p]; ok {
+		if n.typ != api.DataObjType {
+			return pathTypeError(p)
+		}
+
+		n.write(data)
+		return nil
+	}
+
+	n := con.newNode(api.DataObjType)
+	n.write(data)
+	con.nodes[p] = n
+
+	return nil
+}
+
+// ReadFile returns the contents of the data object at p
+func (con *Connection) ReadFile(p string) ([]byte, error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	n, err := con.lookup(path.Clean(p), api.DataObjType)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(nil), n.data...), nil
+}
+
+// Collection returns the collection at p
+func (con *Connection) Collection(p string) (api.CollectionAPI, error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	p = path.Clean(p)
+
+	if _, err := con.lookup(p, api.CollectionType); err != nil {
+		return nil, err
+	}
+
+	return &Collection{con, p}, nil
+}
+
+// DataObject returns the data object at p
+func (con *Connection) DataObject(p string) (api.DataObjAPI, error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	p = path.Clean(p)
+
+	if _, err := con.lookup(p, api.DataObjType); err != nil {
+		return nil, err
+	}
+
+	return &DataObj{con, p}, nil
+}
+
+// CreateCollection creates the collection at p. Its parent must exist.
+func (con *Connection) CreateCollection(p string) (api.CollectionAPI, error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	p = path.Clean(p)
+
+	if err := con.create(p, con.newNode(api.CollectionType)); err != nil {
+		return nil, err
+	}
+
+	return &Collection{con, p}, nil
+}
+
+// ObjStat returns the catalog information of the data object or collection at p
+func (con *Connection) ObjStat(p string) (*api.ObjStat, error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	p = path.Clean(p)
+
+	n, err := con.lookup(p, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	stat := &api.ObjStat{
+		Path:       p,
+		Type:       n.typ,
+		DataId:     n.id,
+		OwnerName:  con.Username,
+		OwnerZone:  con.Zone,
+		CreateTime: n.createTime,
+		ModifyTime: n.modifyTime,
+	}
+
+	if n.typ == api.DataObjType {
+		stat.Size = int64(len(n.data))
+		stat.Checksum = n.checksum()
+		stat.DataType = "generic"
+		stat.ReplicaCount = 1
+		stat.RescHier = n.resource
+	}
+
+	return stat, nil
+}
+
+// PathType returns api.DataObjType or api.CollectionType for p
+func (con *Connection) PathType(p string) (int, error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	n, err := con.lookup(path.Clean(p), -1)
+	if err != nil {
+		return -1, err
+	}
+
+	return n.typ, nil
+}
+
+// List returns the collections and data objects directly within the collection at p, sorted by name
+func (con *Connection) List(p string) ([]api.ListingEntry, error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	p = path.Clean(p)
+
+	if _, err := con.lookup(p, api.CollectionType); err != nil {
+		return nil, err
+	}
+
+	listing := make([]api.ListingEntry, 0)
+
+	for _, child := range con.children(p) {
+		n := con.nodes[child]
+
+		entry := api.ListingEntry{
+			Name:       path.Base(child),
+			Path:       child,
+			Type:       n.typ,
+			ModifyTime: n.modifyTime,
+		}
+
+		if n.typ == api.DataObjType {
+			entry.Size = int64(len(n.data))
+			entry.Checksum = n.checksum()
+		}
+
+		listing = append(listing, entry)
+	}
+
+	return listing, nil
+}
+
+// CreateDataObj creates an empty data object opts.Name within the collection colPath. An existing object is only
+// replaced if opts.Force is set.
+func (con *Connection) CreateDataObj(colPath string, opts api.DataObjOptions) (api.DataObjAPI, error) {
+	return con.createDataObj(path.Clean(colPath), nil, opts)
+}
+
+// Put stores the contents of the local file in a data object within the collection colPath, named after the file
+// unless opts.Name is set. An existing object is only replaced if opts.Force is set.
+func (con *Connection) Put(localPath string, colPath string, opts api.DataObjOptions) (api.DataObjAPI, error) {
+	data, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		return nil, newError(-1, "", fmt.Sprintf("Can't read %v: %v", localPath, err))
+	}
+
+	if opts.Name == "" {
+		opts.Name = filepath.Base(localPath)
+	}
+
+	return con.createDataObj(path.Clean(colPath), data, opts)
+}
+
+func (con *Connection) createDataObj(colPath string, data []byte, opts api.DataObjOptions) (api.DataObjAPI, error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	p := colPath + "/" + opts.Name
+
+	if opts.Force {
+		if n, ok := con.nodes[p]; ok && n.typ == api.DataObjType {
+			delete(con.nodes, p)
+		}
+	}
+
+	n := con.newNode(api.DataObjType)
+	n.write(data)
+
+	if resc, ok := opts.Resource.(string); ok && resc != "" {
+		n.resource = resc
+	}
+
+	if err := con.create(p, n); err != nil {
+		return nil, err
+	}
+
+	return &DataObj{con, p}, nil
+}
+
+// Meta returns the AVUs of the data object or collection at p
+func (con *Connection) Meta(p string) ([]api.AVU, error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	n, err := con.lookup(path.Clean(p), -1)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]api.AVU(nil), n.meta...), nil
+}
+
+// AddMeta adds an AVU to the data object or collection at p
+func (con *Connection) AddMeta(p string, m api.AVU) error {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	p = path.Clean(p)
+
+	n, err := con.lookup(p, -1)
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range n.meta {
+		if existing == m {
+			return newError(-809000, "CATALOG_ALREADY_HAS_ITEM_BY_THAT_NAME", fmt.Sprintf("%v already has AVU %v", p, m.Attribute))
+		}
+	}
+
+	n.meta = append(n.meta, m)
+
+	return nil
+}
+
+// DeleteMeta removes every AVU of the data object or collection at p with the attribute specified
+func (con *Connection) DeleteMeta(p string, attr string) error {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	n, err := con.lookup(path.Clean(p), -1)
+	if err != nil {
+		return err
+	}
+
+	kept := n.meta[:0]
+	for _, m := range n.meta {
+		if m.Attribute != attr {
+			kept = append(kept, m)
+		}
+	}
+	n.meta = kept
+
+	return nil
+}
+
+// Access returns the access levels (api.Read, api.Write or api.Own) granted on the data object or collection at p
+// with Chmod, by user or group name. The owner isn't listed.
+func (con *Connection) Access(p string) (map[string]int, error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	n, err := con.lookup(path.Clean(p), -1)
+	if err != nil {
+		return nil, err
+	}
+
+	access := make(map[string]int, len(n.access))
+	for name, level := range n.access {
+		access[name] = level
+	}
+
+	return access, nil
+}
+
+// Connect returns the catalog itself, so a *Connection can stand in for a *gorods.Client as an api.ClientAPI
+func (con *Connection) Connect() (api.ConnectionAPI, error) {
+	return con, nil
+}
+
+// Disconnect does nothing, the catalog stays usable
+func (con *Connection) Disconnect() error {
+	return nil
+}
+
+func (con *Connection) newNode(typ int) *node {
+	con.nextId++
+
+	now := time.Now()
+
+	return &node{
+		typ:        typ,
+		id:         strconv.Itoa(10000 + con.nextId),
+		resource:   con.Resource,
+		createTime: now,
+		modifyTime: now,
+	}
+}
+
+// lookup returns the node at p, which must be of the type specified (any type if -1)
+func (con *Connection) lookup(p string, typ int) (*node, error) {
+	n, ok := con.nodes[p]
+	if !ok {
+		return nil, newError(-358000, "OBJ_PATH_DOES_NOT_EXIST", fmt.Sprintf("%v does not exist", p))
+	}
+
+	if typ != -1 && n.typ != typ {
+		return nil, pathTypeError(p)
+	}
+
+	return n, nil
+}
+
+// create adds n at p, whose parent collection must exist
+func (con *Connection) create(p string, n *node) error {
+	if _, err := con.lookup(path.Dir(p), api.CollectionType); err != nil {
+		return err
+	}
+
+	if _, ok := con.nodes[p]; ok {
+		return newError(-809000, "CATALOG_ALREADY_HAS_ITEM_BY_THAT_NAME", fmt.Sprintf("%v already exists", p))
+	}
+
+	con.nodes[p] = n
+	con.touch(path.Dir(p))
+
+	return nil
+}
+
+func (con *Connection) mkdirAll(p string) error {
+	if n, ok := con.nodes[p]; ok {
+		if n.typ != api.CollectionType {
+			return pathTypeError(p)
+		}
+
+		return nil
+	}
+
+	if p == "/" {
+		return newError(-358000, "OBJ_PATH_DOES_NOT_EXIST", "/ does not exist")
+	}
+
+	if err := con.mkdirAll(path.Dir(p)); err != nil {
+		return err
+	}
+
+	return con.create(p, con.newNode(api.CollectionType))
+}
+
+// children returns the sorted paths directly within the collection at p
+func (con *Connection) children(p string) []string {
+	response := make([]string, 0)
+
+	for child := range con.nodes {
+		if child != p && path.Dir(child) == p {
+			response = append(response, child)
+		}
+	}
+
+	sort.Strings(response)
+
+	return response
+}
+
+// descendants returns p and every path below it
+func (con *Connection) descendants(p string) []string {
+	response := make([]string, 0)
+
+	for child := range con.nodes {
+		if child == p || strings.HasPrefix(child, p+"/") {
+			response = append(response, child)
+		}
+	}
+
+	return response
+}
+
+// move renames p and everything below it to dest. They are copied instead if keep is true.
+func (con *Connection) move(p string, dest string, keep bool) error {
+	if _, err := con.lookup(p, -1); err != nil {
+		return err
+	}
+
+	if _, err := con.lookup(path.Dir(dest), api.CollectionType); err != nil {
+		return err
+	}
+
+	if _, ok := con.nodes[dest]; ok {
+		return newError(-809000, "CATALOG_ALREADY_HAS_ITEM_BY_THAT_NAME", fmt.Sprintf("%v already exists", dest))
+	}
+
+	if dest == p || strings.HasPrefix(dest, p+"/") {
+		return newError(-317000, "USER_INPUT_PATH_ERR", fmt.Sprintf("Can't move %v into itself", p))
+	}
+
+	if keep {
+		for _, child := range con.descendants(p) {
+			con.nodes[dest+strings.TrimPrefix(child, p)] = con.copyNode(con.nodes[child])
+		}
+	} else {
+		for _, child := range con.descendants(p) {
+			con.nodes[dest+strings.TrimPrefix(child, p)] = con.nodes[child]
+			delete(con.nodes, child)
+		}
+
+		con.touch(path.Dir(p))
+	}
+
+	con.touch(path.Dir(dest))
+
+	return nil
+}
+
+// copyNode returns a new node with the contents, resource and AVUs of n
+func (con *Connection) copyNode(n *node) *node {
+	c := con.newNode(n.typ)
+	c.write(n.data)
+	c.resource = n.resource
+	c.meta = append([]api.AVU(nil), n.meta...)
+
+	return c
+}
+
+// chmod sets the access level of userOrGroup on p, and everything below it if recursive is set
+func (con *Connection) chmod(p string, userOrGroup string, accessLevel int, recursive bool) error {
+	if _, err := con.lookup(p, -1); err != nil {
+		return err
+	}
+
+	if accessLevel != api.Null && accessLevel != api.Read && accessLevel != api.Write && accessLevel != api.Own {
+		return newError(-1, "", fmt.Sprintf("Invalid access level %v", accessLevel))
+	}
+
+	paths := []string{p}
+	if recursive {
+		paths = con.descendants(p)
+	}
+
+	for _, child := range paths {
+		n := con.nodes[child]
+
+		if accessLevel == api.Null {
+			delete(n.access, userOrGroup)
+			continue
+		}
+
+		if n.access == nil {
+			n.access = make(map[string]int)
+		}
+
+		n.access[userOrGroup] = accessLevel
+	}
+
+	return nil
+}
+
+func (con *Connection) remove(p string, recursive bool) error {
+	if _, err := con.lookup(p, -1); err != nil {
+		return err
+	}
+
+	if !recursive && len(con.children(p)) > 0 {
+		return newError(-821000, "CAT_COLLECTION_NOT_EMPTY", fmt.Sprintf("%v is not empty", p))
+	}
+
+	for _, child := range con.descendants(p) {
+		delete(con.nodes, child)
+	}
+
+	con.touch(path.Dir(p))
+
+	return nil
+}
+
+func (con *Connection) touch(p string) {
+	if n, ok := con.nodes[p]; ok {
+		n.modifyTime = time.Now()
+	}
+}
+
+// resolve returns the path of the collection iRODSCollection, a path relative to the collection dir if it isn't
+// absolute, or a CollectionAPI
+func resolve(dir string, iRODSCollection interface{}) (string, error) {
+	switch col := iRODSCollection.(type) {
+	case string:
+		if strings.HasPrefix(col, "/") {
+			return path.Clean(col), nil
+		}
+
+		return path.Join(dir, col), nil
+	case api.CollectionAPI:
+		return col.Path(), nil
+	}
+
+	return "", newError(-1, "", "Unknown variable type passed as collection")
+}
+
+func (n *node) write(data []byte) {
+	n.data = append([]byte(nil), data...)
+	n.modifyTime = time.Now()
+}
+
+func (n *node) checksum() string {
+	sum := md5.Sum(n.data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// newError returns an error like those of the iRODS backed implementation. Status is -1 for errors that don't
+// originate from the catalog, with an empty name.
+func newError(status int, name string, message string) *api.GoRodsError {
+	err := &api.GoRodsError{
+		LogLevel:  api.Fatal,
+		Message:   message,
+		Time:      time.Now(),
+		Status:    status,
+		ErrorName: name,
+	}
+
+	if name != "" {
+		err.IRODSCode = " " + name
+	}
+
+	return err
+}
+
+func pathTypeError(p string) *api.GoRodsError {
+	return newError(-317000, "USER_INPUT_PATH_ERR", fmt.Sprintf("%v is not of the expected type", p))
+}
+
+// Collection is a collection in a mock Connection, implementing api.CollectionAPI
+type Collection struct {
+	con  *Connection
+	path string
+}
+
+// Type returns api.CollectionType
+func (col *Collection) Type() int {
+	return api.CollectionType
+}
+
+// Name returns the name of the collection
+func (col *Collection) Name() string {
+	return path.Base(col.path)
+}
+
+// Path returns the full path of the collection
+func (col *Collection) Path() string {
+	return col.path
+}
+
+// ObjStat returns the catalog information of the collection
+func (col *Collection) ObjStat() (*api.ObjStat, error) {
+	return col.con.ObjStat(col.path)
+}
+
+// List returns the collections and data objects directly within the collection
+func (col *Collection) List() ([]api.ListingEntry, error) {
+	return col.con.List(col.path)
+}
+
+// Chmod grants userOrGroup accessLevel on the collection, and its contents if recursive is set. api.Null removes
+// the access of userOrGroup. Access returns the levels granted.
+func (col *Collection) Chmod(userOrGroup string, accessLevel int, recursive bool) error {
+	col.con.mu.Lock()
+	defer col.con.mu.Unlock()
+
+	return col.con.chmod(col.path, userOrGroup, accessLevel, recursive)
+}
+
+// Rename renames the collection within its parent
+func (col *Collection) Rename(newName string) error {
+	return col.moveTo(path.Dir(col.path)+"/"+newName, false)
+}
+
+// MoveTo moves the collection into iRODSCollection, see api.CollectionAPI
+func (col *Collection) MoveTo(iRODSCollection interface{}) error {
+	dest, err := resolve(path.Dir(col.path), iRODSCollection)
+	if err != nil {
+		return err
+	}
+
+	return col.moveTo(dest+"/"+col.Name(), false)
+}
+
+// CopyTo copies the collection, its contents and their AVUs into iRODSCollection, see api.CollectionAPI
+func (col *Collection) CopyTo(iRODSCollection interface{}) error {
+	dest, err := resolve(path.Dir(col.path), iRODSCollection)
+	if err != nil {
+		return err
+	}
+
+	return col.moveTo(dest+"/"+col.Name(), true)
+}
+
+func (col *Collection) moveTo(dest string, keep bool) error {
+	col.con.mu.Lock()
+	defer col.con.mu.Unlock()
+
+	if _, err := col.con.lookup(col.path, api.CollectionType); err != nil {
+		return err
+	}
+
+	if err := col.con.move(col.path, dest, keep); err != nil {
+		return err
+	}
+
+	if !keep {
+		col.path = dest
+	}
+
+	return nil
+}
+
+// Delete removes the collection, and its contents if recursive is set
+func (col *Collection) Delete(recursive bool) error {
+	col.con.mu.Lock()
+	defer col.con.mu.Unlock()
+
+	return col.con.remove(col.path, recursive)
+}
+
+// Close does nothing
+func (col *Collection) Close() error {
+	return nil
+}
+
+// DataObj is a data object in a mock Connection, implementing api.DataObjAPI
+type DataObj struct {
+	con  *Connection
+	path string
+}
+
+// Type returns api.DataObjType
+func (obj *DataObj) Type() int {
+	return api.DataObjType
+}
+
+// Name returns the name of the data object
+func (obj *DataObj) Name() string {
+	return path.Base(obj.path)
+}
+
+// Path returns the full path of the data object
+func (obj *DataObj) Path() string {
+	return obj.path
+}
+
+// Size returns the size of the data object, or 0 if it no longer exists
+func (obj *DataObj) Size() int64 {
+	if stat, err := obj.ObjStat(); err == nil {
+		return stat.Size
+	}
+
+	return 0
+}
+
+// Checksum returns the MD5 checksum of the data object, as a hex string
+func (obj *DataObj) Checksum() string {
+	if stat, err := obj.ObjStat(); err == nil {
+		return stat.Checksum
+	}
+
+	return ""
+}
+
+// ModTime returns the modify time of the data object
+func (obj *DataObj) ModTime() time.Time {
+	if stat, err := obj.ObjStat(); err == nil {
+		return stat.ModifyTime
+	}
+
+	return time.Time{}
+}
+
+// ObjStat returns the catalog information of the data object
+func (obj *DataObj) ObjStat() (*api.ObjStat, error) {
+	return obj.con.ObjStat(obj.path)
+}
+
+// Chksum returns the MD5 checksum of the data object's contents, like Checksum
+func (obj *DataObj) Chksum() (string, error) {
+	stat, err := obj.ObjStat()
+	if err != nil {
+		return "", err
+	}
+
+	return stat.Checksum, nil
+}
+
+// Read returns the contents of the data object
+func (obj *DataObj) Read() ([]byte, error) {
+	return obj.con.ReadFile(obj.path)
+}
+
+// ReadBytes returns up to length bytes of the data object, starting at pos
+func (obj *DataObj) ReadBytes(pos int64, length int) ([]byte, error) {
+	data, err := obj.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if pos < 0 || pos > int64(len(data)) {
+		return nil, newError(-1, "", fmt.Sprintf("Invalid offset %v for %v", pos, obj.path))
+	}
+
+	end := pos + int64(length)
+	if end > int64(len(data)) {
+		end = int64(len(data))
+	}
+
+	return data[pos:end], nil
+}
+
+// Write replaces the contents of the data object with data
+func (obj *DataObj) Write(data []byte) error {
+	obj.con.mu.Lock()
+	defer obj.con.mu.Unlock()
+
+	n, err := obj.con.lookup(obj.path, api.DataObjType)
+	if err != nil {
+		return err
+	}
+
+	n.write(data)
+
+	return nil
+}
+
+// DownloadTo writes the contents of the data object to the local file at localPath
+func (obj *DataObj) DownloadTo(localPath string) error {
+	data, err := obj.Read()
+	if err != nil {
+		return err
+	}
+
+	if er := ioutil.WriteFile(localPath, data, os.FileMode(0644)); er != nil {
+		return newError(-1, "", fmt.Sprintf("Can't write %v: %v", localPath, er))
+	}
+
+	return nil
+}
+
+// Chmod grants userOrGroup accessLevel on the data object, api.Null removes the access of userOrGroup. Access
+// returns the levels granted.
+func (obj *DataObj) Chmod(userOrGroup string, accessLevel int, recursive bool) error {
+	obj.con.mu.Lock()
+	defer obj.con.mu.Unlock()
+
+	if _, err := obj.con.lookup(obj.path, api.DataObjType); err != nil {
+		return err
+	}
+
+	return obj.con.chmod(obj.path, userOrGroup, accessLevel, false)
+}
+
+// Rename renames the data object within its collection
+func (obj *DataObj) Rename(newName string) error {
+	return obj.moveTo(path.Dir(obj.path)+"/"+newName, false)
+}
+
+// MoveTo moves the data object into iRODSCollection, see api.DataObjAPI
+func (obj *DataObj) MoveTo(iRODSCollection interface{}) error {
+	dest, err := resolve(path.Dir(obj.path), iRODSCollection)
+	if err != nil {
+		return err
+	}
+
+	return obj.moveTo(dest+"/"+obj.Name(), false)
+}
+
+// CopyTo copies the data object and its AVUs into iRODSCollection, see api.DataObjAPI
+func (obj *DataObj) CopyTo(iRODSCollection interface{}) error {
+	dest, err := resolve(path.Dir(obj.path), iRODSCollection)
+	if err != nil {
+		return err
+	}
+
+	return obj.moveTo(dest+"/"+obj.Name(), true)
+}
+
+func (obj *DataObj) moveTo(dest string, keep bool) error {
+	obj.con.mu.Lock()
+	defer obj.con.mu.Unlock()
+
+	if _, err := obj.con.lookup(obj.path, api.DataObjType); err != nil {
+		return err
+	}
+
+	if err := obj.con.move(obj.path, dest, keep); err != nil {
+		return err
+	}
+
+	if !keep {
+		obj.path = dest
+	}
+
+	return nil
+}
+
+// Delete removes the data object, recursive is ignored
+func (obj *DataObj) Delete(recursive bool) error {
+	obj.con.mu.Lock()
+	defer obj.con.mu.Unlock()
+
+	if _, err := obj.con.lookup(obj.path, api.DataObjType); err != nil {
+		return err
+	}
+
+	return obj.con.remove(obj.path, false)
+}
+
+// Close does nothing
+func (obj *DataObj) Close() error {
+	return nil
+}
+
+var (
+	_ api.ClientAPI     = (*Connection)(nil)
+	_ api.ConnectionAPI = (*Connection)(nil)
+	_ api.CollectionAPI = (*Collection)(nil)
+	_ api.DataObjAPI    = (*DataObj)(nil)
+)
@@ -0,0 +1,133 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package mock
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jjacquay712/GoRODS/api"
+)
+
+func TestMockTree(t *testing.T) {
+	var con api.ConnectionAPI = New("tempZone", "rods")
+
+	col, err := con.CreateCollection("/tempZone/home/rods/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := con.CreateDataObj(col.Path(), api.DataObjOptions{Name: "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := obj.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := con.CreateDataObj(col.Path(), api.DataObjOptions{Name: "a.txt"}); err == nil {
+		t.Error("Expected an error creating an existing data object without Force")
+	}
+
+	if err := con.AddMeta(obj.Path(), api.AVU{Attribute: "project", Value: "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := col.Rename("moved"); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := con.DataObject("/tempZone/home/rods/moved/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := moved.ReadBytes(1, 3); string(data) != "ell" {
+		t.Errorf("Unexpected contents %q", data)
+	}
+
+	if meta, _ := con.Meta(moved.Path()); len(meta) != 1 || meta[0].Value != "x" {
+		t.Errorf("Unexpected metadata %v", meta)
+	}
+
+	if moved.Checksum() != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Unexpected checksum %v", moved.Checksum())
+	}
+
+	listing, err := con.List("/tempZone/home/rods")
+	if err != nil || len(listing) != 1 || listing[0].Name != "moved" || listing[0].Type != api.CollectionType {
+		t.Errorf("Unexpected listing %v, %v", listing, err)
+	}
+
+	if err := col.Delete(false); err == nil {
+		t.Error("Expected an error deleting a non-empty collection")
+	}
+
+	if err := col.Delete(true); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = con.ObjStat("/tempZone/home/rods/moved/a.txt")
+	if !errors.Is(err, &api.GoRodsError{ErrorName: "OBJ_PATH_DOES_NOT_EXIST"}) || !api.IsNotFound(err) {
+		t.Errorf("Expected OBJ_PATH_DOES_NOT_EXIST, got %v", err)
+	}
+}
+
+func TestMockCopyAndChmod(t *testing.T) {
+	con := New("tempZone", "rods")
+
+	if err := con.WriteFile(con.Home()+"/src/sub/a.txt", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := con.AddMeta(con.Home()+"/src/sub/a.txt", api.AVU{Attribute: "k", Value: "v"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := con.CreateCollection(con.Home() + "/dest"); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := con.Collection(con.Home() + "/src")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := src.CopyTo("dest"); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := con.ReadFile(con.Home() + "/dest/src/sub/a.txt"); err != nil || string(data) != "a" {
+		t.Errorf("Expected the copy of the tree, got %q, %v", data, err)
+	}
+
+	if meta, _ := con.Meta(con.Home() + "/dest/src/sub/a.txt"); len(meta) != 1 {
+		t.Errorf("Expected the AVUs to be copied, got %v", meta)
+	}
+
+	if err := src.Chmod("public", api.Read, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if access, _ := con.Access(con.Home() + "/src/sub/a.txt"); access["public"] != api.Read {
+		t.Errorf("Expected a recursive chmod, got %v", access)
+	}
+
+	if access, _ := con.Access(con.Home() + "/dest/src/sub/a.txt"); len(access) != 0 {
+		t.Errorf("Expected the copy to keep its own access, got %v", access)
+	}
+
+	if err := src.Chmod("public", api.Null, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if access, _ := con.Access(con.Home() + "/src"); len(access) != 0 {
+		t.Errorf("Expected api.Null to remove the access, got %v", access)
+	}
+
+	if err := src.MoveTo(42); err == nil {
+		t.Error("Expected an error moving into an invalid collection")
+	}
+}
@@ -0,0 +1,602 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package native
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jjacquay712/GoRODS/api"
+)
+
+// Client opens connections with Options, it implements api.ClientAPI (gorods.ClientAPI). Code that opens its
+// connections through a ClientAPI runs over the pure Go client when given a *Client, and over the iRODS C client
+// library when given a *gorods.Client.
+type Client struct {
+	Options Options
+}
+
+// Connect dials a new connection, and returns it as an api.ConnectionAPI
+func (cli *Client) Connect() (api.ConnectionAPI, error) {
+	con, err := Dial(cli.Options)
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	return API(con), nil
+}
+
+// API returns the connection as an api.ConnectionAPI (gorods.ConnectionAPI). Errors are *api.GoRodsError values
+// carrying the ErrorName of known iRODS error codes, so api.IsNotFound and errors.Is behave like with gorods.
+// Collection.CopyTo copies the data objects of the tree one by one, and like icp it doesn't copy AVUs.
+func API(con *Conn) api.ConnectionAPI {
+	return &conAPI{con}
+}
+
+// ConnOf returns the *Conn behind a ConnectionAPI returned by API or Client.Connect, or nil if c is another
+// implementation
+func ConnOf(c api.ConnectionAPI) *Conn {
+	if ca, ok := c.(*conAPI); ok {
+		return ca.con
+	}
+
+	return nil
+}
+
+// errorNames are the names of the iRODS error codes (rodsErrorTable.h) that callers of api.ConnectionAPI test for
+var errorNames = map[int]string{
+	errUserFileDoesNotExist:  "USER_FILE_DOES_NOT_EXIST",
+	errOverwriteWithoutForce: "OVERWRITE_WITHOUT_FORCE_FLAG",
+	errUserInputPath:         "USER_INPUT_PATH_ERR",
+	errObjPathDoesNotExist:   "OBJ_PATH_DOES_NOT_EXIST",
+	errCatNoRowsFound:        "CAT_NO_ROWS_FOUND",
+	-809000:                  "CATALOG_ALREADY_HAS_ITEM_BY_THAT_NAME",
+	errCatUnknownCollection:  "CAT_UNKNOWN_COLLECTION",
+	errCatUnknownFile:        "CAT_UNKNOWN_FILE",
+	-818000:                  "CAT_NO_ACCESS_PERMISSION",
+	-821000:                  "CAT_COLLECTION_NOT_EMPTY",
+	-826000:                  "CAT_INVALID_AUTHENTICATION",
+	-827000:                  "CAT_INVALID_USER",
+}
+
+// apiError converts an error of this package to an *api.GoRodsError
+func apiError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	gErr := &api.GoRodsError{LogLevel: api.Fatal, Message: err.Error(), Time: time.Now(), Status: -1}
+
+	if e, ok := err.(*Error); ok {
+		gErr.Message, gErr.Status = e.Message, e.Status
+
+		if name, ok := errorNames[e.Status/1000*1000]; ok {
+			gErr.ErrorName = name
+			gErr.IRODSCode = " " + name
+		}
+	}
+
+	return gErr
+}
+
+func pathTypeError(p string) error {
+	return apiError(&Error{Status: errUserInputPath, Message: fmt.Sprintf("iRODS Stat Failed: %v is not of the expected type", p)})
+}
+
+// accessLevels maps the access levels of api.CollectionAPI.Chmod to those of Conn.Chmod
+var accessLevels = map[int]string{
+	api.Null:  AccessNull,
+	api.Read:  AccessRead,
+	api.Write: AccessWrite,
+	api.Own:   AccessOwn,
+}
+
+func (con *Conn) chmodLevel(p string, userOrGroup string, accessLevel int, recursive bool) error {
+	level, ok := accessLevels[accessLevel]
+	if !ok {
+		return apiError(&Error{Status: -1, Message: fmt.Sprintf("iRODS Chmod Failed: unknown access level %v", accessLevel)})
+	}
+
+	return apiError(con.Chmod(p, userOrGroup, level, recursive))
+}
+
+// resourceName returns the resource name of DataObjOptions.Resource, which can only be a string with this package
+func resourceName(resource interface{}) (string, error) {
+	switch r := resource.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return r, nil
+	}
+
+	return "", apiError(&Error{Status: -1, Message: "Unknown variable type passed as resource, only names are supported"})
+}
+
+// resolve returns the path of the collection iRODSCollection, a path relative to the collection dir if it isn't
+// absolute, or a CollectionAPI
+func resolve(dir string, iRODSCollection interface{}) (string, error) {
+	switch col := iRODSCollection.(type) {
+	case string:
+		if strings.HasPrefix(col, "/") {
+			return path.Clean(col), nil
+		}
+
+		return path.Join(dir, col), nil
+	case api.CollectionAPI:
+		return col.Path(), nil
+	}
+
+	return "", apiError(&Error{Status: -1, Message: "Unknown variable type passed as collection"})
+}
+
+func objStat(stat *ObjStat) *api.ObjStat {
+	return &api.ObjStat{
+		Path:       stat.Path,
+		Type:       stat.Type,
+		Size:       stat.Size,
+		DataMode:   stat.DataMode,
+		DataId:     stat.DataId,
+		Checksum:   stat.Checksum,
+		OwnerName:  stat.OwnerName,
+		OwnerZone:  stat.OwnerZone,
+		CreateTime: stat.CreateTime,
+		ModifyTime: stat.ModifyTime,
+	}
+}
+
+type conAPI struct {
+	con *Conn
+}
+
+func (c *conAPI) lookup(p string, typ int) (*ObjStat, error) {
+	p = path.Clean(p)
+
+	stat, err := c.con.Stat(p)
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	if stat.Type != typ {
+		return nil, pathTypeError(p)
+	}
+
+	return stat, nil
+}
+
+func (c *conAPI) Collection(p string) (api.CollectionAPI, error) {
+	stat, err := c.lookup(p, CollectionType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Collection{c, stat.Path}, nil
+}
+
+func (c *conAPI) DataObject(p string) (api.DataObjAPI, error) {
+	stat, err := c.lookup(p, DataObjType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataObj{c, stat.Path}, nil
+}
+
+func (c *conAPI) CreateCollection(p string) (api.CollectionAPI, error) {
+	p = path.Clean(p)
+
+	if err := c.con.CreateCollection(p, false); err != nil {
+		return nil, apiError(err)
+	}
+
+	return &Collection{c, p}, nil
+}
+
+func (c *conAPI) CreateDataObj(colPath string, opts api.DataObjOptions) (api.DataObjAPI, error) {
+	f, err := c.createFile(colPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, apiError(err)
+	}
+
+	return &DataObj{c, f.Path()}, nil
+}
+
+func (c *conAPI) Put(localPath string, colPath string, opts api.DataObjOptions) (api.DataObjAPI, error) {
+	local, er := os.Open(localPath)
+	if er != nil {
+		return nil, apiError(fmt.Errorf("iRODS Put DataObject Failed: %v", er))
+	}
+	defer local.Close()
+
+	if opts.Name == "" {
+		opts.Name = filepath.Base(localPath)
+	}
+
+	f, err := c.createFile(colPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hide the io.WriterTo of *os.File, so each request carries a whole buffer
+	if _, err := io.CopyBuffer(f, struct{ io.Reader }{local}, make([]byte, readChunkSize)); err != nil {
+		f.Close()
+		return nil, apiError(err)
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, apiError(err)
+	}
+
+	return &DataObj{c, f.Path()}, nil
+}
+
+func (c *conAPI) createFile(colPath string, opts api.DataObjOptions) (*File, error) {
+	resource, err := resourceName(opts.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := c.con.create(path.Clean(colPath)+"/"+opts.Name, resource, opts.Force)
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	return f, nil
+}
+
+func (c *conAPI) ObjStat(p string) (*api.ObjStat, error) {
+	stat, err := c.con.Stat(path.Clean(p))
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	return objStat(stat), nil
+}
+
+func (c *conAPI) PathType(p string) (int, error) {
+	stat, err := c.con.Stat(path.Clean(p))
+	if err != nil {
+		return -1, apiError(err)
+	}
+
+	return stat.Type, nil
+}
+
+func (c *conAPI) List(p string) ([]api.ListingEntry, error) {
+	if _, err := c.lookup(p, CollectionType); err != nil {
+		return nil, err
+	}
+
+	entries, err := c.con.List(path.Clean(p))
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	listing := make([]api.ListingEntry, 0, len(entries))
+	for _, e := range entries {
+		listing = append(listing, api.ListingEntry{Name: e.Name, Path: e.Path, Type: e.Type, Size: e.Size, Checksum: e.Checksum, ModifyTime: e.ModifyTime})
+	}
+
+	return listing, nil
+}
+
+func (c *conAPI) Meta(p string) ([]api.AVU, error) {
+	avus, err := c.con.Meta(path.Clean(p))
+
+	return avus, apiError(err)
+}
+
+func (c *conAPI) AddMeta(p string, m api.AVU) error {
+	return apiError(c.con.AddMeta(path.Clean(p), m))
+}
+
+func (c *conAPI) DeleteMeta(p string, attr string) error {
+	return apiError(c.con.DeleteMeta(path.Clean(p), attr))
+}
+
+func (c *conAPI) Disconnect() error {
+	return apiError(c.con.Close())
+}
+
+// Collection is a collection of a connection returned by API, implementing api.CollectionAPI
+type Collection struct {
+	c    *conAPI
+	path string
+}
+
+// Type returns CollectionType
+func (col *Collection) Type() int {
+	return CollectionType
+}
+
+// Name returns the name of the collection
+func (col *Collection) Name() string {
+	return path.Base(col.path)
+}
+
+// Path returns the full path of the collection
+func (col *Collection) Path() string {
+	return col.path
+}
+
+// ObjStat returns the catalog information of the collection
+func (col *Collection) ObjStat() (*api.ObjStat, error) {
+	return col.c.ObjStat(col.path)
+}
+
+// List returns the collections and data objects directly within the collection
+func (col *Collection) List() ([]api.ListingEntry, error) {
+	return col.c.List(col.path)
+}
+
+// Chmod grants userOrGroup accessLevel on the collection, and its contents if recursive is set
+func (col *Collection) Chmod(userOrGroup string, accessLevel int, recursive bool) error {
+	return col.c.con.chmodLevel(col.path, userOrGroup, accessLevel, recursive)
+}
+
+// Rename renames the collection within its parent
+func (col *Collection) Rename(newName string) error {
+	return col.moveTo(path.Dir(col.path) + "/" + newName)
+}
+
+// MoveTo moves the collection into iRODSCollection, see api.CollectionAPI
+func (col *Collection) MoveTo(iRODSCollection interface{}) error {
+	dest, err := resolve(path.Dir(col.path), iRODSCollection)
+	if err != nil {
+		return err
+	}
+
+	return col.moveTo(dest + "/" + col.Name())
+}
+
+func (col *Collection) moveTo(dest string) error {
+	if err := col.c.con.Rename(col.path, dest); err != nil {
+		return apiError(err)
+	}
+
+	col.path = dest
+
+	return nil
+}
+
+// CopyTo copies the collection and its contents into iRODSCollection, see api.CollectionAPI. AVUs aren't copied.
+func (col *Collection) CopyTo(iRODSCollection interface{}) error {
+	dest, err := resolve(path.Dir(col.path), iRODSCollection)
+	if err != nil {
+		return err
+	}
+
+	return col.c.copyTree(col.path, dest+"/"+col.Name())
+}
+
+// copyTree copies the collection src to the new collection dest, recursively
+func (c *conAPI) copyTree(src string, dest string) error {
+	if err := c.con.CreateCollection(dest, false); err != nil {
+		return apiError(err)
+	}
+
+	entries, err := c.con.List(src)
+	if err != nil {
+		return apiError(err)
+	}
+
+	for _, e := range entries {
+		if e.Type == CollectionType {
+			err = c.copyTree(e.Path, dest+"/"+e.Name)
+		} else {
+			err = apiError(c.con.Copy(e.Path, dest+"/"+e.Name, "", false))
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the collection, and its contents if recursive is set, bypassing the trash like irm -f
+func (col *Collection) Delete(recursive bool) error {
+	return apiError(col.c.con.RemoveCollection(col.path, recursive, true))
+}
+
+// Close does nothing, the connection is closed with Disconnect
+func (col *Collection) Close() error {
+	return nil
+}
+
+// DataObj is a data object of a connection returned by API, implementing api.DataObjAPI
+type DataObj struct {
+	c    *conAPI
+	path string
+}
+
+// Type returns DataObjType
+func (obj *DataObj) Type() int {
+	return DataObjType
+}
+
+// Name returns the name of the data object
+func (obj *DataObj) Name() string {
+	return path.Base(obj.path)
+}
+
+// Path returns the full path of the data object
+func (obj *DataObj) Path() string {
+	return obj.path
+}
+
+// Size returns the size of the data object, or 0 if it can't be stat'ed
+func (obj *DataObj) Size() int64 {
+	if stat, err := obj.ObjStat(); err == nil {
+		return stat.Size
+	}
+
+	return 0
+}
+
+// Checksum returns the checksum recorded in the catalog, or an empty string if there is none
+func (obj *DataObj) Checksum() string {
+	if stat, err := obj.ObjStat(); err == nil {
+		return stat.Checksum
+	}
+
+	return ""
+}
+
+// ModTime returns the modify time of the data object
+func (obj *DataObj) ModTime() time.Time {
+	if stat, err := obj.ObjStat(); err == nil {
+		return stat.ModifyTime
+	}
+
+	return time.Time{}
+}
+
+// ObjStat returns the catalog information of the data object
+func (obj *DataObj) ObjStat() (*api.ObjStat, error) {
+	return obj.c.ObjStat(obj.path)
+}
+
+// Chksum has the server compute the checksum of the data object, see Conn.Checksum
+func (obj *DataObj) Chksum() (string, error) {
+	chksum, err := obj.c.con.Checksum(obj.path, true)
+
+	return chksum, apiError(err)
+}
+
+// Read returns the contents of the data object
+func (obj *DataObj) Read() ([]byte, error) {
+	data, err := obj.c.con.ReadFile(obj.path)
+
+	return data, apiError(err)
+}
+
+// ReadBytes returns up to length bytes of the data object, starting at pos
+func (obj *DataObj) ReadBytes(pos int64, length int) ([]byte, error) {
+	f, err := obj.c.con.Open(obj.path, O_RDONLY)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(pos, io.SeekStart); err != nil {
+		return nil, apiError(err)
+	}
+
+	data := make([]byte, length)
+
+	n, err := io.ReadFull(f, data)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, apiError(err)
+	}
+
+	return data[:n], nil
+}
+
+// Write replaces the contents of the data object with data
+func (obj *DataObj) Write(data []byte) error {
+	f, err := obj.c.con.Open(obj.path, O_WRONLY|O_TRUNC)
+	if err != nil {
+		return apiError(err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return apiError(err)
+	}
+
+	return apiError(f.Close())
+}
+
+// DownloadTo writes the contents of the data object to the local file at localPath
+func (obj *DataObj) DownloadTo(localPath string) error {
+	f, err := obj.c.con.Open(obj.path, O_RDONLY)
+	if err != nil {
+		return apiError(err)
+	}
+	defer f.Close()
+
+	local, er := os.Create(localPath)
+	if er != nil {
+		return apiError(fmt.Errorf("iRODS Download Failed: %v", er))
+	}
+
+	// Hide the io.ReaderFrom of *os.File, so each request asks for a whole buffer
+	if _, err := io.CopyBuffer(struct{ io.Writer }{local}, f, make([]byte, readChunkSize)); err != nil {
+		local.Close()
+		os.Remove(localPath)
+		return apiError(err)
+	}
+
+	if er := local.Close(); er != nil {
+		return apiError(fmt.Errorf("iRODS Download Failed: %v", er))
+	}
+
+	return nil
+}
+
+// Chmod grants userOrGroup accessLevel on the data object. A data object has no contents, so recursive is ignored.
+func (obj *DataObj) Chmod(userOrGroup string, accessLevel int, recursive bool) error {
+	return obj.c.con.chmodLevel(obj.path, userOrGroup, accessLevel, false)
+}
+
+// Rename renames the data object within its collection
+func (obj *DataObj) Rename(newName string) error {
+	return obj.moveTo(path.Dir(obj.path) + "/" + newName)
+}
+
+// MoveTo moves the data object into iRODSCollection, see api.DataObjAPI
+func (obj *DataObj) MoveTo(iRODSCollection interface{}) error {
+	dest, err := resolve(path.Dir(obj.path), iRODSCollection)
+	if err != nil {
+		return err
+	}
+
+	return obj.moveTo(dest + "/" + obj.Name())
+}
+
+func (obj *DataObj) moveTo(dest string) error {
+	if err := obj.c.con.Rename(obj.path, dest); err != nil {
+		return apiError(err)
+	}
+
+	obj.path = dest
+
+	return nil
+}
+
+// CopyTo copies the data object into iRODSCollection server-side, see api.DataObjAPI. AVUs aren't copied.
+func (obj *DataObj) CopyTo(iRODSCollection interface{}) error {
+	dest, err := resolve(path.Dir(obj.path), iRODSCollection)
+	if err != nil {
+		return err
+	}
+
+	return apiError(obj.c.con.Copy(obj.path, dest+"/"+obj.Name(), "", false))
+}
+
+// Delete removes the data object, bypassing the trash like irm -f. recursive is ignored.
+func (obj *DataObj) Delete(recursive bool) error {
+	return apiError(obj.c.con.Remove(obj.path, true))
+}
+
+// Close does nothing, the connection is closed with Disconnect
+func (obj *DataObj) Close() error {
+	return nil
+}
+
+var (
+	_ api.ClientAPI     = (*Client)(nil)
+	_ api.CollectionAPI = (*Collection)(nil)
+	_ api.DataObjAPI    = (*DataObj)(nil)
+)
@@ -0,0 +1,300 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+// Package native is an iRODS client written in pure Go, speaking the iRODS XML protocol directly instead of calling
+// the iRODS C client library. Programs importing only this package build with CGO_ENABLED=0, cross compile, and run
+// on hosts without the iRODS client libraries installed.
+//
+// It covers the core of the GoRODS API: native (password) authentication, stat, general queries, listing,
+// reading and writing data objects, checksums, server-side copies, metadata, access control, and creating, renaming
+// and removing collections and data objects. API returns a connection as an api.ConnectionAPI, the interface
+// gorods.API implements over the C client library, so code written against it can use either.
+// Connections don't negotiate SSL, so use them on trusted networks or through a tunnel.
+package native
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Protocol constants sent in the startup pack
+const (
+	relVersion = "rods4.2.0"
+	apiVersion = "d"
+
+	// maxPasswordLen is MAX_PASSWORD_LEN, the length the password is padded to in the challenge response
+	maxPasswordLen = 50
+	challengeLen   = 64
+)
+
+// DefaultPort is the iRODS server port used when Options.Port is 0
+const DefaultPort = 1247
+
+// Options are used by Dial. ProgramName is reported to the server like ConnectionOptions.ProgramName in GoRODS.
+// Timeout limits the time taken to connect and authenticate (no limit if 0). ClientUser and ClientZone (Zone if empty)
+// make a rodsadmin Username act on behalf of another user, like in GoRODS. The anonymous user logs in with no Password.
+type Options struct {
+	Host        string
+	Port        int
+	Zone        string
+	Username    string
+	Password    string
+	ClientUser  string
+	ClientZone  string
+	ProgramName string
+	Timeout     time.Duration
+}
+
+// Conn is an authenticated connection to an iRODS server. Calls are serialized, so a *Conn is safe for use
+// by multiple goroutines, but open Files share the connection with every other call.
+type Conn struct {
+	Options Options
+
+	// ServerVersion is the server's release, like "rods4.2.8"
+	ServerVersion string
+
+	conn net.Conn
+	mu   sync.Mutex
+}
+
+// Dial connects to the iRODS server and logs in with the native authentication scheme
+func Dial(opts Options) (*Conn, error) {
+	if opts.Port == 0 {
+		opts.Port = DefaultPort
+	}
+
+	nc, err := net.DialTimeout("tcp", net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port)), opts.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("iRODS Connect Failed: %v", err)
+	}
+
+	con, err := newConn(nc, opts)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	return con, nil
+}
+
+// newConn runs the startup and login exchanges over an established network connection
+func newConn(nc net.Conn, opts Options) (*Conn, error) {
+	con := &Conn{Options: opts, conn: nc}
+
+	if opts.Timeout > 0 {
+		nc.SetDeadline(time.Now().Add(opts.Timeout))
+		defer nc.SetDeadline(time.Time{})
+	}
+
+	if err := con.startup(); err != nil {
+		return nil, err
+	}
+
+	if err := con.login(); err != nil {
+		return nil, err
+	}
+
+	return con, nil
+}
+
+// startupPack returns the startup pack of the connection, naming the client user on behalf of which it acts
+func (con *Conn) startupPack() *startupPack {
+	clientUser, clientZone := con.Options.Username, con.Options.Zone
+
+	if con.Options.ClientUser != "" {
+		clientUser = con.Options.ClientUser
+
+		if con.Options.ClientZone != "" {
+			clientZone = con.Options.ClientZone
+		}
+	}
+
+	return &startupPack{
+		IrodsProt:      1,
+		ProxyUser:      con.Options.Username,
+		ProxyRcatZone:  con.Options.Zone,
+		ClientUser:     clientUser,
+		ClientRcatZone: clientZone,
+		RelVersion:     relVersion,
+		APIVersion:     apiVersion,
+		Option:         con.Options.ProgramName,
+	}
+}
+
+func (con *Conn) startup() error {
+	body, err := encode(con.startupPack())
+	if err != nil {
+		return err
+	}
+
+	if err := writeMessage(con.conn, &message{Type: msgConnect, Body: body}); err != nil {
+		return fmt.Errorf("iRODS Connect Failed: %v", err)
+	}
+
+	reply, err := readMessage(con.conn)
+	if err != nil {
+		return fmt.Errorf("iRODS Connect Failed: %v", err)
+	}
+
+	var version versionPI
+
+	if err := decode(reply.Body, &version); err != nil {
+		return fmt.Errorf("iRODS Connect Failed: %v", err)
+	}
+
+	if version.Status < 0 {
+		return replyError("Connect", version.Status, reply)
+	}
+
+	con.ServerVersion = version.RelVersion
+
+	return nil
+}
+
+func (con *Conn) login() error {
+	var challenge authRequestOut
+
+	if _, err := con.call(authRequestAN, nil, nil, &challenge); err != nil {
+		return err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(challenge.Challenge)
+	if err != nil || len(raw) < challengeLen {
+		return fmt.Errorf("iRODS Login Failed: invalid challenge")
+	}
+
+	response := &authResponseInp{
+		Response: challengeResponse(raw, con.Options.Password),
+		Username: con.Options.Username,
+	}
+
+	if _, err := con.call(authResponseAN, response, nil, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// challengeResponse returns the base64 encoded MD5 digest of the challenge followed by the padded password.
+// Zero bytes of the digest are replaced by ones, as the server treats the digest as a C string.
+func challengeResponse(challenge []byte, password string) string {
+	padded := make([]byte, maxPasswordLen)
+	copy(padded, password)
+
+	h := md5.New()
+	h.Write(challenge[:challengeLen])
+	h.Write(padded)
+
+	sum := h.Sum(nil)
+	for i := range sum {
+		if sum[i] == 0 {
+			sum[i] = 1
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(sum)
+}
+
+// call sends an API request and returns the reply, unmarshalling its body into out if set.
+// A negative status in the reply is returned as an *Error.
+func (con *Conn) call(api int, in interface{}, bs []byte, out interface{}) (*message, error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	var body []byte
+
+	if in != nil {
+		var err error
+		if body, err = encode(in); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeMessage(con.conn, &message{Type: msgAPIRequest, Body: body, Bs: bs, IntInfo: api}); err != nil {
+		return nil, &Error{Status: -1, Message: fmt.Sprintf("iRODS API %v Failed: %v", api, err)}
+	}
+
+	reply, err := readMessage(con.conn)
+
+	// Long running collection operations report progress, each report must be acknowledged
+	for err == nil && reply.IntInfo == svrToCliCollStat {
+		if _, err = con.conn.Write(collStatReply()); err == nil {
+			reply, err = readMessage(con.conn)
+		}
+	}
+
+	if err != nil {
+		return nil, &Error{Status: -1, Message: fmt.Sprintf("iRODS API %v Failed: %v", api, err)}
+	}
+
+	if reply.IntInfo < 0 {
+		return reply, replyError(fmt.Sprintf("API %v", api), reply.IntInfo, reply)
+	}
+
+	if out != nil && len(reply.Body) > 0 {
+		if err := decode(reply.Body, out); err != nil {
+			return reply, &Error{Status: -1, Message: fmt.Sprintf("iRODS API %v Failed: %v", api, err)}
+		}
+	}
+
+	return reply, nil
+}
+
+// Close ends the session and closes the network connection
+func (con *Conn) Close() error {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	writeMessage(con.conn, &message{Type: msgDisconnect})
+
+	return con.conn.Close()
+}
+
+// Error is an error returned by the iRODS server. Status is the iRODS error code, e.g. -818000,
+// or -1 for errors of the connection itself.
+type Error struct {
+	Status  int
+	Message string
+}
+
+// Error returns the error message and its status
+func (err *Error) Error() string {
+	if err.Status == -1 {
+		return err.Message
+	}
+
+	return fmt.Sprintf("%v (status %v)", err.Message, err.Status)
+}
+
+// IsNotExist returns true if err reports that a path or catalog entry doesn't exist
+func IsNotExist(err error) bool {
+	e, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+
+	switch e.Status / 1000 * 1000 {
+	case errObjPathDoesNotExist, errCatNoRowsFound, errCatUnknownCollection, errCatUnknownFile, errUserFileDoesNotExist:
+		return true
+	}
+
+	return false
+}
+
+func replyError(op string, status int, reply *message) *Error {
+	msg := fmt.Sprintf("iRODS %v Failed", op)
+
+	var rerr rError
+	if len(reply.Error) > 0 && decode(reply.Error, &rerr) == nil {
+		for _, m := range rerr.Messages {
+			msg += ": " + m.Msg
+		}
+	}
+
+	return &Error{Status: status, Message: msg}
+}
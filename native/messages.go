@@ -0,0 +1,373 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package native
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Message types of MsgHeader_PI
+const (
+	msgConnect     = "RODS_CONNECT"
+	msgVersion     = "RODS_VERSION"
+	msgAPIRequest  = "RODS_API_REQ"
+	msgAPIReply    = "RODS_API_REPLY"
+	msgDisconnect  = "RODS_DISCONNECT"
+	maxHeaderLen   = 1024 * 1024
+	maxMessageLen  = 256 * 1024 * 1024
+	collStatAckLen = 4
+)
+
+// API numbers (apiNumber.h)
+const (
+	dataObjCreateAN = 601
+	dataObjOpenAN   = 602
+	dataObjUnlinkAN = 615
+	dataObjRenameAN = 627
+	dataObjChksumAN = 629
+	objStatAN       = 633
+	dataObjCloseAN  = 673
+	dataObjLseekAN  = 674
+	dataObjReadAN   = 675
+	dataObjWriteAN  = 676
+	rmCollAN        = 679
+	collCreateAN    = 681
+	dataObjCopyAN   = 696
+	genQueryAN      = 702
+	authRequestAN   = 703
+	authResponseAN  = 704
+	modAVUMetaAN    = 706
+	modAccessAN     = 707
+)
+
+// Status codes (rodsErrorTable.h) and collection operation progress markers
+const (
+	errUserFileDoesNotExist  = -310000
+	errOverwriteWithoutForce = -312000
+	errUserInputPath         = -317000
+	errObjPathDoesNotExist   = -358000
+	errCatNoRowsFound        = -808000
+	errCatUnknownCollection  = -814000
+	errCatUnknownFile        = -817000
+
+	svrToCliCollStat      = 99999996
+	cliToSvrCollStatReply = 99999997
+)
+
+// Operation types of DataObjInp_PI
+const (
+	oprCopyDest      = 9
+	oprCopySrc       = 10
+	oprRenameDataObj = 11
+	oprRenameColl    = 12
+)
+
+// message is a protocol message: a header followed by the body, an error and a byte stream
+type message struct {
+	Type    string
+	Body    []byte
+	Error   []byte
+	Bs      []byte
+	IntInfo int
+}
+
+type msgHeader struct {
+	XMLName  xml.Name `xml:"MsgHeader_PI"`
+	Type     string   `xml:"type"`
+	MsgLen   int      `xml:"msgLen"`
+	ErrorLen int      `xml:"errorLen"`
+	BsLen    int      `xml:"bsLen"`
+	IntInfo  int      `xml:"intInfo"`
+}
+
+// writeMessage sends msg, prefixed by the length of its header as a 4 byte big endian integer
+func writeMessage(w io.Writer, msg *message) error {
+	header, err := xml.Marshal(&msgHeader{
+		Type:     msg.Type,
+		MsgLen:   len(msg.Body),
+		ErrorLen: len(msg.Error),
+		BsLen:    len(msg.Bs),
+		IntInfo:  msg.IntInfo,
+	})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, uint32(len(header)))
+	buf.Write(header)
+	buf.Write(msg.Body)
+	buf.Write(msg.Error)
+	buf.Write(msg.Bs)
+
+	_, err = w.Write(buf.Bytes())
+
+	return err
+}
+
+// readMessage receives a message sent with writeMessage
+func readMessage(r io.Reader) (*message, error) {
+	var headerLen uint32
+
+	if err := binary.Read(r, binary.BigEndian, &headerLen); err != nil {
+		return nil, err
+	}
+
+	if headerLen > maxHeaderLen {
+		return nil, fmt.Errorf("invalid message header length %v", headerLen)
+	}
+
+	headerData := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, headerData); err != nil {
+		return nil, err
+	}
+
+	var header msgHeader
+
+	if err := xml.Unmarshal(headerData, &header); err != nil {
+		return nil, fmt.Errorf("invalid message header: %v", err)
+	}
+
+	msg := &message{Type: header.Type, IntInfo: header.IntInfo}
+
+	for _, part := range []struct {
+		dst *[]byte
+		n   int
+	}{{&msg.Body, header.MsgLen}, {&msg.Error, header.ErrorLen}, {&msg.Bs, header.BsLen}} {
+		if part.n < 0 || part.n > maxMessageLen {
+			return nil, fmt.Errorf("invalid message length %v", part.n)
+		}
+
+		*part.dst = make([]byte, part.n)
+		if _, err := io.ReadFull(r, *part.dst); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+// collStatReply is the acknowledgement of a collection operation progress report
+func collStatReply() []byte {
+	b := make([]byte, collStatAckLen)
+	binary.BigEndian.PutUint32(b, cliToSvrCollStatReply)
+
+	return b
+}
+
+// toServer rewrites the character references produced by encoding/xml that the server's parser doesn't understand
+var toServer = strings.NewReplacer("&#34;", "&quot;", "&#39;", "&apos;", "&#x9;", "\t", "&#xA;", "\n", "&#xD;", "\r")
+
+// encode marshals a packing instruction for the server
+func encode(v interface{}) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, &Error{Status: -1, Message: fmt.Sprintf("Unable to encode request: %v", err)}
+	}
+
+	return []byte(toServer.Replace(string(data))), nil
+}
+
+// decode unmarshals a packing instruction sent by the server
+func decode(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+type startupPack struct {
+	XMLName        xml.Name `xml:"StartupPack_PI"`
+	IrodsProt      int      `xml:"irodsProt"`
+	ReconnFlag     int      `xml:"reconnFlag"`
+	ConnectCnt     int      `xml:"connectCnt"`
+	ProxyUser      string   `xml:"proxyUser"`
+	ProxyRcatZone  string   `xml:"proxyRcatZone"`
+	ClientUser     string   `xml:"clientUser"`
+	ClientRcatZone string   `xml:"clientRcatZone"`
+	RelVersion     string   `xml:"relVersion"`
+	APIVersion     string   `xml:"apiVersion"`
+	Option         string   `xml:"option"`
+}
+
+type versionPI struct {
+	XMLName    xml.Name `xml:"Version_PI"`
+	Status     int      `xml:"status"`
+	RelVersion string   `xml:"relVersion"`
+	APIVersion string   `xml:"apiVersion"`
+	ReconnPort int      `xml:"reconnPort"`
+	ReconnAddr string   `xml:"reconnAddr"`
+	Cookie     int      `xml:"cookie"`
+}
+
+type rError struct {
+	XMLName  xml.Name    `xml:"RError_PI"`
+	Count    int         `xml:"count"`
+	Messages []rErrorMsg `xml:"RErrMsg_PI"`
+}
+
+type rErrorMsg struct {
+	Status int    `xml:"status"`
+	Msg    string `xml:"msg"`
+}
+
+type authRequestOut struct {
+	XMLName   xml.Name `xml:"authRequestOut_PI"`
+	Challenge string   `xml:"challenge"`
+}
+
+type authResponseInp struct {
+	XMLName  xml.Name `xml:"authResponseInp_PI"`
+	Response string   `xml:"response"`
+	Username string   `xml:"username"`
+}
+
+type keyValPair struct {
+	XMLName xml.Name `xml:"KeyValPair_PI"`
+	Len     int      `xml:"ssLen"`
+	Keys    []string `xml:"keyWord"`
+	Values  []string `xml:"svalue"`
+}
+
+// kvp returns a KeyValPair_PI of the keyword, value pairs specified
+func kvp(pairs ...string) keyValPair {
+	kv := keyValPair{}
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		kv.Keys = append(kv.Keys, pairs[i])
+		kv.Values = append(kv.Values, pairs[i+1])
+	}
+
+	kv.Len = len(kv.Keys)
+
+	return kv
+}
+
+type dataObjInp struct {
+	XMLName    xml.Name   `xml:"DataObjInp_PI"`
+	ObjPath    string     `xml:"objPath"`
+	CreateMode int        `xml:"createMode"`
+	OpenFlags  int        `xml:"openFlags"`
+	Offset     int64      `xml:"offset"`
+	DataSize   int64      `xml:"dataSize"`
+	NumThreads int        `xml:"numThreads"`
+	OprType    int        `xml:"oprType"`
+	CondInput  keyValPair `xml:"KeyValPair_PI"`
+}
+
+// dataObjCopyInp holds the source and the destination, in that order
+type dataObjCopyInp struct {
+	XMLName xml.Name     `xml:"DataObjCopyInp_PI"`
+	Objs    []dataObjInp `xml:"DataObjInp_PI"`
+}
+
+type openedDataObjInp struct {
+	XMLName      xml.Name   `xml:"OpenedDataObjInp_PI"`
+	L1descInx    int        `xml:"l1descInx"`
+	Len          int        `xml:"len"`
+	Whence       int        `xml:"whence"`
+	OprType      int        `xml:"oprType"`
+	Offset       int64      `xml:"offset"`
+	BytesWritten int64      `xml:"bytesWritten"`
+	CondInput    keyValPair `xml:"KeyValPair_PI"`
+}
+
+type fileLseekOut struct {
+	XMLName xml.Name `xml:"fileLseekOut_PI"`
+	Offset  int64    `xml:"offset"`
+}
+
+type collInpNew struct {
+	XMLName   xml.Name   `xml:"CollInpNew_PI"`
+	CollName  string     `xml:"collName"`
+	Flags     int        `xml:"flags"`
+	OprType   int        `xml:"oprType"`
+	CondInput keyValPair `xml:"KeyValPair_PI"`
+}
+
+type rodsObjStat struct {
+	XMLName    xml.Name `xml:"RodsObjStat_PI"`
+	ObjSize    int64    `xml:"objSize"`
+	ObjType    int      `xml:"objType"`
+	DataMode   int      `xml:"dataMode"`
+	DataId     string   `xml:"dataId"`
+	Chksum     string   `xml:"chksum"`
+	OwnerName  string   `xml:"ownerName"`
+	OwnerZone  string   `xml:"ownerZone"`
+	CreateTime string   `xml:"createTime"`
+	ModifyTime string   `xml:"modifyTime"`
+}
+
+type inxIvalPair struct {
+	XMLName xml.Name `xml:"InxIvalPair_PI"`
+	Len     int      `xml:"iiLen"`
+	Inx     []int    `xml:"inx"`
+	Values  []int    `xml:"ivalue"`
+}
+
+type inxValPair struct {
+	XMLName xml.Name `xml:"InxValPair_PI"`
+	Len     int      `xml:"isLen"`
+	Inx     []int    `xml:"inx"`
+	Values  []string `xml:"svalue"`
+}
+
+type genQueryInp struct {
+	XMLName           xml.Name    `xml:"GenQueryInp_PI"`
+	MaxRows           int         `xml:"maxRows"`
+	ContinueInx       int         `xml:"continueInx"`
+	PartialStartIndex int         `xml:"partialStartIndex"`
+	Options           int         `xml:"options"`
+	CondInput         keyValPair  `xml:"KeyValPair_PI"`
+	Select            inxIvalPair `xml:"InxIvalPair_PI"`
+	Where             inxValPair  `xml:"InxValPair_PI"`
+}
+
+type genQueryOut struct {
+	XMLName       xml.Name    `xml:"GenQueryOut_PI"`
+	RowCnt        int         `xml:"rowCnt"`
+	AttriCnt      int         `xml:"attriCnt"`
+	ContinueInx   int         `xml:"continueInx"`
+	TotalRowCount int         `xml:"totalRowCount"`
+	Results       []sqlResult `xml:"SqlResult_PI"`
+}
+
+type sqlResult struct {
+	AttriInx int      `xml:"attriInx"`
+	ResLen   int      `xml:"reslen"`
+	Values   []string `xml:"value"`
+}
+
+type strPI struct {
+	XMLName xml.Name `xml:"STR_PI"`
+	MyStr   string   `xml:"myStr"`
+}
+
+// modAVUMetadataInp holds the imeta style arguments of a metadata operation: operation, object type, path,
+// attribute, value and units
+type modAVUMetadataInp struct {
+	XMLName xml.Name `xml:"ModAVUMetadataInp_PI"`
+	Arg0    string   `xml:"arg0"`
+	Arg1    string   `xml:"arg1"`
+	Arg2    string   `xml:"arg2"`
+	Arg3    string   `xml:"arg3"`
+	Arg4    string   `xml:"arg4"`
+	Arg5    string   `xml:"arg5"`
+	Arg6    string   `xml:"arg6"`
+	Arg7    string   `xml:"arg7"`
+	Arg8    string   `xml:"arg8"`
+	Arg9    string   `xml:"arg9"`
+}
+
+type modAccessControlInp struct {
+	XMLName       xml.Name `xml:"modAccessControlInp_PI"`
+	RecursiveFlag int      `xml:"recursiveFlag"`
+	AccessLevel   string   `xml:"accessLevel"`
+	UserName      string   `xml:"userName"`
+	Zone          string   `xml:"zone"`
+	Path          string   `xml:"path"`
+}
@@ -0,0 +1,111 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package native
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jjacquay712/GoRODS/api"
+)
+
+// AVU is an attribute, value and units triple of metadata, like gorods.AVU
+type AVU = api.AVU
+
+// Access levels passed to Chmod, like the ichmod arguments
+const (
+	AccessNull  = "null"
+	AccessRead  = "read"
+	AccessWrite = "write"
+	AccessOwn   = "own"
+)
+
+// metaTarget returns the imeta object type flag (-d or -C) and the query conditions selecting the AVUs of the data
+// object or collection at p, along with the names of its attribute, value and units columns
+func (con *Conn) metaTarget(p string) (string, []Condition, []string, error) {
+	stat, err := con.Stat(p)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if stat.Type == CollectionType {
+		return "-C", []Condition{Equals("COLL_NAME", p)}, []string{"META_COLL_ATTR_NAME", "META_COLL_ATTR_VALUE", "META_COLL_ATTR_UNITS"}, nil
+	}
+
+	conds := []Condition{Equals("COLL_NAME", path.Dir(p)), Equals("DATA_NAME", path.Base(p))}
+
+	return "-d", conds, []string{"META_DATA_ATTR_NAME", "META_DATA_ATTR_VALUE", "META_DATA_ATTR_UNITS"}, nil
+}
+
+// Meta returns the AVUs of the data object or collection at p
+func (con *Conn) Meta(p string) ([]AVU, error) {
+	p = strings.TrimRight(p, "/")
+
+	_, conds, cols, err := con.metaTarget(p)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := con.Query(Query{Columns: cols, Conditions: conds})
+	if err != nil {
+		return nil, err
+	}
+
+	avus := make([]AVU, 0, len(rows))
+	for _, row := range rows {
+		avus = append(avus, AVU{Attribute: row[cols[0]], Value: row[cols[1]], Units: row[cols[2]]})
+	}
+
+	return avus, nil
+}
+
+// AddMeta adds the AVU to the data object or collection at p
+func (con *Conn) AddMeta(p string, m AVU) error {
+	return con.modMeta("add", p, m.Attribute, m.Value, m.Units)
+}
+
+// DeleteMeta removes every AVU of attribute attr from the data object or collection at p
+func (con *Conn) DeleteMeta(p string, attr string) error {
+	return con.modMeta("rmw", p, attr, "%", "%")
+}
+
+func (con *Conn) modMeta(op string, p string, attr string, value string, units string) error {
+	p = strings.TrimRight(p, "/")
+
+	flag, _, _, err := con.metaTarget(p)
+	if err != nil {
+		return err
+	}
+
+	inp := &modAVUMetadataInp{Arg0: op, Arg1: flag, Arg2: p, Arg3: attr, Arg4: value, Arg5: units}
+
+	_, err = con.call(modAVUMetaAN, inp, nil, nil)
+
+	return err
+}
+
+// Chmod grants userOrGroup ("name" or "name#zone") accessLevel (AccessNull removes its access) on the data object or
+// collection at p, and on the contents of a collection if recursive is set
+func (con *Conn) Chmod(p string, userOrGroup string, accessLevel string, recursive bool) error {
+	switch accessLevel {
+	case AccessNull, AccessRead, AccessWrite, AccessOwn:
+	default:
+		return &Error{Status: -1, Message: fmt.Sprintf("iRODS Chmod Failed: unknown access level %v", accessLevel)}
+	}
+
+	inp := &modAccessControlInp{AccessLevel: accessLevel, UserName: userOrGroup, Path: strings.TrimRight(p, "/")}
+
+	if i := strings.Index(userOrGroup, "#"); i >= 0 {
+		inp.UserName, inp.Zone = userOrGroup[:i], userOrGroup[i+1:]
+	}
+
+	if recursive {
+		inp.RecursiveFlag = 1
+	}
+
+	_, err := con.call(modAccessAN, inp, nil, nil)
+
+	return err
+}
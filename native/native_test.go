@@ -0,0 +1,287 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package native
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/jjacquay712/GoRODS/api"
+)
+
+// fakeServer answers the startup and login exchanges, then each API request with the handler for its number
+func fakeServer(t *testing.T, nc net.Conn, password string, handlers map[int]func(*message) *message) {
+	defer nc.Close()
+
+	msg, err := readMessage(nc)
+	if err != nil || msg.Type != msgConnect {
+		t.Errorf("Expected a startup pack, got %v, %v", msg, err)
+		return
+	}
+
+	var pack startupPack
+	if err := decode(msg.Body, &pack); err != nil || pack.Option != "test-suite v1" {
+		t.Errorf("Unexpected startup pack %+v, %v", pack, err)
+	}
+
+	version, _ := encode(&versionPI{RelVersion: "rods4.2.8", APIVersion: "d"})
+	writeMessage(nc, &message{Type: msgVersion, Body: version})
+
+	challenge := bytes.Repeat([]byte{7}, challengeLen)
+
+	handlers[authRequestAN] = func(*message) *message {
+		body, _ := encode(&authRequestOut{Challenge: base64.StdEncoding.EncodeToString(challenge)})
+		return &message{Type: msgAPIReply, Body: body}
+	}
+
+	handlers[authResponseAN] = func(req *message) *message {
+		var resp authResponseInp
+		decode(req.Body, &resp)
+
+		if resp.Response != challengeResponse(challenge, password) {
+			return &message{Type: msgAPIReply, IntInfo: -826000}
+		}
+
+		return &message{Type: msgAPIReply}
+	}
+
+	for {
+		req, err := readMessage(nc)
+		if err != nil || req.Type == msgDisconnect {
+			return
+		}
+
+		handler, ok := handlers[req.IntInfo]
+		if !ok {
+			t.Errorf("Unexpected API request %v", req.IntInfo)
+			return
+		}
+
+		writeMessage(nc, handler(req))
+	}
+}
+
+func dialFake(t *testing.T, password string, handlers map[int]func(*message) *message) (*Conn, error) {
+	client, server := net.Pipe()
+
+	go fakeServer(t, server, password, handlers)
+
+	con, err := newConn(client, Options{Zone: "tempZone", Username: "rods", Password: "rods", ProgramName: "test-suite v1"})
+	if err != nil {
+		client.Close()
+	}
+
+	return con, err
+}
+
+func TestLoginAndStat(t *testing.T) {
+	con, err := dialFake(t, "rods", map[int]func(*message) *message{
+		objStatAN: func(req *message) *message {
+			var inp dataObjInp
+			decode(req.Body, &inp)
+
+			if inp.ObjPath == "/tempZone/home/rods/it's.txt" {
+				body, _ := encode(&rodsObjStat{ObjSize: 42, ObjType: 1, Chksum: "sha2:abc", ModifyTime: "01500000000"})
+				return &message{Type: msgAPIReply, Body: body}
+			}
+
+			return &message{Type: msgAPIReply, IntInfo: errObjPathDoesNotExist}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer con.Close()
+
+	if con.ServerVersion != "rods4.2.8" {
+		t.Errorf("Unexpected server version %v", con.ServerVersion)
+	}
+
+	stat, err := con.Stat("/tempZone/home/rods/it's.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stat.Type != DataObjType || stat.Size != 42 || stat.Checksum != "sha2:abc" || stat.ModifyTime.Unix() != 1500000000 {
+		t.Errorf("Unexpected stat %+v", stat)
+	}
+
+	if _, err := con.Stat("/tempZone/home/rods/missing"); !IsNotExist(err) {
+		t.Errorf("Expected a not exist error, got %v", err)
+	}
+}
+
+func TestLoginFailure(t *testing.T) {
+	if _, err := dialFake(t, "other", map[int]func(*message) *message{}); err == nil {
+		t.Error("Expected the login to fail with the wrong password")
+	}
+}
+
+func TestStartupPackProxy(t *testing.T) {
+	con := &Conn{Options: Options{Zone: "tempZone", Username: "portal", ClientUser: "alice", ClientZone: "otherZone"}}
+
+	pack := con.startupPack()
+
+	if pack.ProxyUser != "portal" || pack.ProxyRcatZone != "tempZone" || pack.ClientUser != "alice" || pack.ClientRcatZone != "otherZone" {
+		t.Errorf("Unexpected startup pack %+v", pack)
+	}
+
+	con.Options.ClientUser, con.Options.ClientZone = "", ""
+
+	if pack := con.startupPack(); pack.ClientUser != "portal" || pack.ClientRcatZone != "tempZone" {
+		t.Errorf("Unexpected startup pack %+v", pack)
+	}
+}
+
+func TestQueryPages(t *testing.T) {
+	con, err := dialFake(t, "rods", map[int]func(*message) *message{
+		genQueryAN: func(req *message) *message {
+			var inp genQueryInp
+			decode(req.Body, &inp)
+
+			out := genQueryOut{RowCnt: 1, AttriCnt: 1, ContinueInx: 1}
+			out.Results = []sqlResult{{AttriInx: 403, Values: []string{"a.txt"}}}
+
+			if inp.ContinueInx == 1 {
+				out.ContinueInx = 0
+				out.Results[0].Values[0] = "b.txt"
+			}
+
+			body, _ := encode(&out)
+			return &message{Type: msgAPIReply, Body: body}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer con.Close()
+
+	rows, err := con.Query(Query{Columns: []string{"DATA_NAME"}, Conditions: []Condition{Equals("COLL_NAME", "/tempZone/home/rods")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0]["DATA_NAME"] != "a.txt" || rows[1]["DATA_NAME"] != "b.txt" {
+		t.Errorf("Unexpected rows %v", rows)
+	}
+
+	if _, err := con.Query(Query{Columns: []string{"NOT_A_COLUMN"}}); err == nil {
+		t.Error("Expected an error for an unknown column")
+	}
+}
+
+func TestEncodeEscapes(t *testing.T) {
+	data, err := encode(&dataObjInp{ObjPath: `/tempZone/a "b" 'c' <d>&`, CondInput: kvp()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := string(data)
+
+	if !strings.Contains(s, "&quot;b&quot; &apos;c&apos; &lt;d&gt;&amp;") || strings.Contains(s, "&#") {
+		t.Errorf("Unexpected escaping in %v", s)
+	}
+
+	var inp dataObjInp
+	if err := xml.Unmarshal(data, &inp); err != nil || inp.ObjPath != `/tempZone/a "b" 'c' <d>&` {
+		t.Errorf("Round trip failed: %v, %v", inp.ObjPath, err)
+	}
+}
+
+func TestAPI(t *testing.T) {
+	var (
+		meta   modAVUMetadataInp
+		access modAccessControlInp
+	)
+
+	con, err := dialFake(t, "rods", map[int]func(*message) *message{
+		objStatAN: func(req *message) *message {
+			var inp dataObjInp
+			decode(req.Body, &inp)
+
+			switch inp.ObjPath {
+			case "/tempZone/home/rods":
+				body, _ := encode(&rodsObjStat{ObjType: 2})
+				return &message{Type: msgAPIReply, Body: body}
+			case "/tempZone/home/rods/a.txt":
+				body, _ := encode(&rodsObjStat{ObjSize: 3, ObjType: 1})
+				return &message{Type: msgAPIReply, Body: body}
+			}
+
+			return &message{Type: msgAPIReply, IntInfo: errUserFileDoesNotExist}
+		},
+		dataObjChksumAN: func(req *message) *message {
+			body, _ := encode(&strPI{MyStr: "sha2:abc"})
+			return &message{Type: msgAPIReply, Body: body}
+		},
+		modAVUMetaAN: func(req *message) *message {
+			decode(req.Body, &meta)
+			return &message{Type: msgAPIReply}
+		},
+		modAccessAN: func(req *message) *message {
+			decode(req.Body, &access)
+			return &message{Type: msgAPIReply, IntInfo: -818000}
+		},
+		genQueryAN: func(req *message) *message {
+			out := genQueryOut{RowCnt: 1, AttriCnt: 3}
+			out.Results = []sqlResult{{AttriInx: 600, Values: []string{"k"}}, {AttriInx: 601, Values: []string{"v"}}, {AttriInx: 602, Values: []string{""}}}
+
+			body, _ := encode(&out)
+			return &message{Type: msgAPIReply, Body: body}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c api.ConnectionAPI = API(con)
+	defer c.Disconnect()
+
+	obj, err := c.DataObject("/tempZone/home/rods/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if obj.Size() != 3 {
+		t.Errorf("Unexpected size %v", obj.Size())
+	}
+
+	if chksum, err := obj.Chksum(); err != nil || chksum != "sha2:abc" {
+		t.Errorf("Unexpected checksum %v, %v", chksum, err)
+	}
+
+	if _, err := c.Collection(obj.Path()); err == nil {
+		t.Error("Expected an error opening a data object as a collection")
+	}
+
+	if _, err := c.DataObject("/tempZone/home/rods/missing"); !api.IsNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+
+	if err := c.AddMeta(obj.Path(), api.AVU{Attribute: "k", Value: "v"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if meta.Arg0 != "add" || meta.Arg1 != "-d" || meta.Arg2 != obj.Path() || meta.Arg3 != "k" || meta.Arg4 != "v" {
+		t.Errorf("Unexpected metadata request %+v", meta)
+	}
+
+	if avus, err := c.Meta(obj.Path()); err != nil || len(avus) != 1 || avus[0] != (api.AVU{Attribute: "k", Value: "v"}) {
+		t.Errorf("Unexpected AVUs %v, %v", avus, err)
+	}
+
+	err = obj.Chmod("public#otherZone", api.Read, true)
+	if !errors.Is(err, &api.GoRodsError{ErrorName: "CAT_NO_ACCESS_PERMISSION"}) {
+		t.Errorf("Expected CAT_NO_ACCESS_PERMISSION, got %v", err)
+	}
+
+	if access.UserName != "public" || access.Zone != "otherZone" || access.AccessLevel != AccessRead || access.RecursiveFlag != 0 {
+		t.Errorf("Unexpected access request %+v", access)
+	}
+}
@@ -0,0 +1,414 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package native
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Object types, with the same values as gorods.DataObjType and gorods.CollectionType
+const (
+	DataObjType = iota
+	CollectionType
+)
+
+// Open flags (fcntl.h on Linux, which the server expects regardless of the client platform)
+const (
+	O_RDONLY = 0
+	O_WRONLY = 1
+	O_RDWR   = 2
+	O_CREAT  = 64
+	O_TRUNC  = 512
+)
+
+// ObjStat is the catalog information of a data object or collection, like gorods.ObjStat
+type ObjStat struct {
+	Path       string
+	Type       int
+	Size       int64
+	DataMode   int
+	DataId     string
+	Checksum   string
+	OwnerName  string
+	OwnerZone  string
+	CreateTime time.Time
+	ModifyTime time.Time
+}
+
+// Stat returns the catalog information of the data object or collection at p
+func (con *Conn) Stat(p string) (*ObjStat, error) {
+	var out rodsObjStat
+
+	if _, err := con.call(objStatAN, &dataObjInp{ObjPath: p, CondInput: kvp()}, nil, &out); err != nil {
+		return nil, err
+	}
+
+	stat := &ObjStat{
+		Path:       p,
+		Size:       out.ObjSize,
+		DataMode:   out.DataMode,
+		DataId:     out.DataId,
+		Checksum:   out.Chksum,
+		OwnerName:  out.OwnerName,
+		OwnerZone:  out.OwnerZone,
+		CreateTime: parseTime(out.CreateTime),
+		ModifyTime: parseTime(out.ModifyTime),
+	}
+
+	// objType_t: DATA_OBJ_T is 1, COLL_OBJ_T is 2
+	switch out.ObjType {
+	case 1:
+		stat.Type = DataObjType
+	case 2:
+		stat.Type = CollectionType
+	default:
+		return nil, &Error{Status: errObjPathDoesNotExist, Message: fmt.Sprintf("iRODS Stat Failed: %v does not exist", p)}
+	}
+
+	return stat, nil
+}
+
+// Entry is an item of a collection listing, like gorods.ListingEntry
+type Entry struct {
+	Name       string
+	Path       string
+	Type       int
+	Size       int64
+	Checksum   string
+	ModifyTime time.Time
+}
+
+// List returns the collections and data objects directly within the collection at p, sorted by name.
+// Data objects with several replicas are listed once.
+func (con *Conn) List(p string) ([]Entry, error) {
+	p = strings.TrimRight(p, "/")
+
+	colls, err := con.Query(Query{
+		Columns:    []string{"COLL_NAME", "COLL_MODIFY_TIME"},
+		Conditions: []Condition{Equals("COLL_PARENT_NAME", p)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	objs, err := con.Query(Query{
+		Columns:    []string{"DATA_NAME", "DATA_SIZE", "DATA_CHECKSUM", "DATA_MODIFY_TIME"},
+		Conditions: []Condition{Equals("COLL_NAME", p)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(colls)+len(objs))
+	seen := make(map[string]bool)
+
+	for _, row := range colls {
+		// The root collection is its own parent
+		if row["COLL_NAME"] == p {
+			continue
+		}
+
+		entries = append(entries, Entry{
+			Name:       path.Base(row["COLL_NAME"]),
+			Path:       row["COLL_NAME"],
+			Type:       CollectionType,
+			ModifyTime: parseTime(row["COLL_MODIFY_TIME"]),
+		})
+	}
+
+	for _, row := range objs {
+		if seen[row["DATA_NAME"]] {
+			continue
+		}
+		seen[row["DATA_NAME"]] = true
+
+		size, _ := strconv.ParseInt(row["DATA_SIZE"], 10, 64)
+
+		entries = append(entries, Entry{
+			Name:       row["DATA_NAME"],
+			Path:       p + "/" + row["DATA_NAME"],
+			Type:       DataObjType,
+			Size:       size,
+			Checksum:   row["DATA_CHECKSUM"],
+			ModifyTime: parseTime(row["DATA_MODIFY_TIME"]),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries, nil
+}
+
+// File is an open data object. It implements io.Reader, io.Writer, io.Seeker and io.Closer.
+type File struct {
+	con  *Conn
+	path string
+	desc int
+}
+
+// Open opens the data object at p with the flags specified (O_RDONLY, O_WRONLY, O_RDWR)
+func (con *Conn) Open(p string, flags int) (*File, error) {
+	reply, err := con.call(dataObjOpenAN, &dataObjInp{ObjPath: p, OpenFlags: flags, CondInput: kvp()}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{con: con, path: p, desc: reply.IntInfo}, nil
+}
+
+// Create creates the data object at p, replacing any existing one, and opens it for writing.
+// An empty resource uses the server's default resource.
+func (con *Conn) Create(p string, resource string) (*File, error) {
+	return con.create(p, resource, true)
+}
+
+// create is Create, failing with OVERWRITE_WITHOUT_FORCE_FLAG if the data object exists and force isn't set
+func (con *Conn) create(p string, resource string, force bool) (*File, error) {
+	pairs := []string{"dataType", "generic"}
+	if force {
+		pairs = append(pairs, "forceFlag", "")
+	}
+	if resource != "" {
+		pairs = append(pairs, "destRescName", resource)
+	}
+
+	inp := &dataObjInp{ObjPath: p, CreateMode: 0644, OpenFlags: O_WRONLY | O_CREAT | O_TRUNC, DataSize: -1, CondInput: kvp(pairs...)}
+
+	reply, err := con.call(dataObjCreateAN, inp, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{con: con, path: p, desc: reply.IntInfo}, nil
+}
+
+// Path returns the path of the data object
+func (f *File) Path() string {
+	return f.path
+}
+
+// Read reads up to len(b) bytes from the current offset
+func (f *File) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	reply, err := f.con.call(dataObjReadAN, &openedDataObjInp{L1descInx: f.desc, Len: len(b), CondInput: kvp()}, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	n := copy(b, reply.Bs)
+	if n == 0 {
+		return 0, io.EOF
+	}
+
+	return n, nil
+}
+
+// Write writes b at the current offset
+func (f *File) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	reply, err := f.con.call(dataObjWriteAN, &openedDataObjInp{L1descInx: f.desc, Len: len(b), CondInput: kvp()}, b, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if reply.IntInfo < len(b) {
+		return reply.IntInfo, io.ErrShortWrite
+	}
+
+	return reply.IntInfo, nil
+}
+
+// Seek sets the offset of the next Read or Write, like os.File.Seek
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	var out fileLseekOut
+
+	if _, err := f.con.call(dataObjLseekAN, &openedDataObjInp{L1descInx: f.desc, Offset: offset, Whence: whence, CondInput: kvp()}, nil, &out); err != nil {
+		return 0, err
+	}
+
+	return out.Offset, nil
+}
+
+// Close closes the data object, which also updates its size and modify time in the catalog
+func (f *File) Close() error {
+	if f.desc < 0 {
+		return nil
+	}
+
+	_, err := f.con.call(dataObjCloseAN, &openedDataObjInp{L1descInx: f.desc, CondInput: kvp()}, nil, nil)
+	f.desc = -1
+
+	return err
+}
+
+// readChunkSize is the size of each read made by ReadFile
+const readChunkSize = 4 * 1024 * 1024
+
+// ReadFile returns the contents of the data object at p
+func (con *Conn) ReadFile(p string) ([]byte, error) {
+	f, err := con.Open(p, O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, 0)
+	buf := make([]byte, readChunkSize)
+
+	for {
+		n, err := f.Read(buf)
+		data = append(data, buf[:n]...)
+
+		if err == io.EOF {
+			return data, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// WriteFile stores data in the data object at p, replacing any existing contents
+func (con *Conn) WriteFile(p string, data []byte, resource string) error {
+	f, err := con.Create(p, resource)
+	if err != nil {
+		return err
+	}
+
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > readChunkSize {
+			chunk = chunk[:readChunkSize]
+		}
+
+		if _, err := f.Write(chunk); err != nil {
+			f.Close()
+			return err
+		}
+
+		data = data[len(chunk):]
+	}
+
+	return f.Close()
+}
+
+// Remove deletes the data object at p, bypassing the trash if force is set
+func (con *Conn) Remove(p string, force bool) error {
+	inp := &dataObjInp{ObjPath: p, CondInput: kvp()}
+	if force {
+		inp.CondInput = kvp("forceFlag", "")
+	}
+
+	_, err := con.call(dataObjUnlinkAN, inp, nil, nil)
+
+	return err
+}
+
+// CreateCollection creates the collection at p, and its missing parents if parents is set
+func (con *Conn) CreateCollection(p string, parents bool) error {
+	inp := &collInpNew{CollName: p, CondInput: kvp()}
+	if parents {
+		inp.CondInput = kvp("recursiveOpr", "")
+	}
+
+	_, err := con.call(collCreateAN, inp, nil, nil)
+
+	return err
+}
+
+// RemoveCollection deletes the collection at p, and its contents if recursive is set, bypassing the trash if force is set
+func (con *Conn) RemoveCollection(p string, recursive bool, force bool) error {
+	pairs := make([]string, 0)
+	if recursive {
+		pairs = append(pairs, "recursiveOpr", "")
+	}
+	if force {
+		pairs = append(pairs, "forceFlag", "")
+	}
+
+	_, err := con.call(rmCollAN, &collInpNew{CollName: p, CondInput: kvp(pairs...)}, nil, nil)
+
+	return err
+}
+
+// Rename moves the data object or collection at src to dest
+func (con *Conn) Rename(src string, dest string) error {
+	stat, err := con.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	opr := oprRenameDataObj
+	if stat.Type == CollectionType {
+		opr = oprRenameColl
+	}
+
+	inp := &dataObjCopyInp{Objs: []dataObjInp{
+		{ObjPath: src, OprType: opr, CondInput: kvp()},
+		{ObjPath: dest, OprType: opr, CondInput: kvp()},
+	}}
+
+	_, err = con.call(dataObjRenameAN, inp, nil, nil)
+
+	return err
+}
+
+// Checksum has the server compute the checksum of the data object at p, and record it in the catalog. The checksum
+// already recorded is returned unless force is set. Like ichksum, the result is an MD5 hex string or, depending
+// on the server's hashing scheme, prefixed like "sha2:".
+func (con *Conn) Checksum(p string, force bool) (string, error) {
+	inp := &dataObjInp{ObjPath: p, CondInput: kvp()}
+	if force {
+		inp.CondInput = kvp("forceChksum", "")
+	}
+
+	var out strPI
+
+	if _, err := con.call(dataObjChksumAN, inp, nil, &out); err != nil {
+		return "", err
+	}
+
+	return out.MyStr, nil
+}
+
+// Copy copies the data object at src to dest server-side, replacing an existing dest if force is set.
+// An empty resource uses the server's default resource.
+func (con *Conn) Copy(src string, dest string, resource string, force bool) error {
+	pairs := make([]string, 0)
+	if force {
+		pairs = append(pairs, "forceFlag", "")
+	}
+	if resource != "" {
+		pairs = append(pairs, "destRescName", resource)
+	}
+
+	inp := &dataObjCopyInp{Objs: []dataObjInp{
+		{ObjPath: src, OprType: oprCopySrc, CondInput: kvp()},
+		{ObjPath: dest, CreateMode: 0644, OprType: oprCopyDest, DataSize: -1, CondInput: kvp(pairs...)},
+	}}
+
+	_, err := con.call(dataObjCopyAN, inp, nil, nil)
+
+	return err
+}
+
+// parseTime converts a catalog timestamp (seconds since the epoch, zero padded) to a time.Time
+func parseTime(ts string) time.Time {
+	secs, _ := strconv.ParseInt(strings.TrimSpace(ts), 10, 64)
+
+	return time.Unix(secs, 0)
+}
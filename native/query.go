@@ -0,0 +1,193 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package native
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// columns maps the column names used by iquest to their indexes (rodsGenQuery.h)
+var columns = map[string]int{
+	"ZONE_ID":   101,
+	"ZONE_NAME": 102,
+	"ZONE_TYPE": 103,
+
+	"USER_ID":   201,
+	"USER_NAME": 202,
+	"USER_TYPE": 203,
+	"USER_ZONE": 204,
+
+	"RESC_ID":         301,
+	"RESC_NAME":       302,
+	"RESC_ZONE_NAME":  303,
+	"RESC_TYPE_NAME":  304,
+	"RESC_CLASS_NAME": 305,
+	"RESC_LOC":        306,
+	"RESC_VAULT_PATH": 307,
+
+	"DATA_ID":          401,
+	"DATA_COLL_ID":     402,
+	"DATA_NAME":        403,
+	"DATA_REPL_NUM":    404,
+	"DATA_VERSION":     405,
+	"DATA_TYPE_NAME":   406,
+	"DATA_SIZE":        407,
+	"DATA_RESC_NAME":   409,
+	"DATA_PATH":        410,
+	"DATA_OWNER_NAME":  411,
+	"DATA_OWNER_ZONE":  412,
+	"DATA_REPL_STATUS": 413,
+	"DATA_STATUS":      414,
+	"DATA_CHECKSUM":    415,
+	"DATA_EXPIRY":      416,
+	"DATA_COMMENTS":    418,
+	"DATA_CREATE_TIME": 419,
+	"DATA_MODIFY_TIME": 420,
+	"DATA_MODE":        421,
+	"DATA_RESC_HIER":   422,
+
+	"COLL_ID":          500,
+	"COLL_NAME":        501,
+	"COLL_PARENT_NAME": 502,
+	"COLL_OWNER_NAME":  503,
+	"COLL_OWNER_ZONE":  504,
+	"COLL_INHERITANCE": 506,
+	"COLL_COMMENTS":    507,
+	"COLL_CREATE_TIME": 508,
+	"COLL_MODIFY_TIME": 509,
+
+	"META_DATA_ATTR_NAME":  600,
+	"META_DATA_ATTR_VALUE": 601,
+	"META_DATA_ATTR_UNITS": 602,
+	"META_COLL_ATTR_NAME":  610,
+	"META_COLL_ATTR_VALUE": 611,
+	"META_COLL_ATTR_UNITS": 612,
+}
+
+var columnsMu sync.RWMutex
+
+// RegisterColumn makes a general query column usable in Query, under its iquest name (without the COL_ prefix)
+func RegisterColumn(name string, inx int) {
+	columnsMu.Lock()
+	defer columnsMu.Unlock()
+
+	columns[name] = inx
+}
+
+func columnIndex(name string) (int, error) {
+	columnsMu.RLock()
+	defer columnsMu.RUnlock()
+
+	inx, ok := columns[strings.ToUpper(name)]
+	if !ok {
+		return 0, &Error{Status: -1, Message: fmt.Sprintf("Unknown query column %v, see RegisterColumn", name)}
+	}
+
+	return inx, nil
+}
+
+// Condition restricts a Query: Expr is the comparison applied to Column, like "= '/tempZone/home'" or "like '%.csv'"
+type Condition struct {
+	Column string
+	Expr   string
+}
+
+// Equals returns a Condition matching rows whose column equals value. The general query syntax has no escape for
+// single quotes, so value can't contain any.
+func Equals(column string, value string) Condition {
+	return Condition{Column: column, Expr: "= '" + value + "'"}
+}
+
+// Query is a general query, the structured equivalent of "select <Columns> where <Conditions>"
+type Query struct {
+	Columns    []string
+	Conditions []Condition
+}
+
+// queryPageSize is the number of rows fetched per round trip
+const queryPageSize = 500
+
+// Query runs the general query and returns its rows, keyed by column name like GoRODS' IQuest
+func (con *Conn) Query(q Query) ([]map[string]string, error) {
+	inp := &genQueryInp{MaxRows: queryPageSize, CondInput: kvp()}
+
+	for _, col := range q.Columns {
+		inx, err := columnIndex(col)
+		if err != nil {
+			return nil, err
+		}
+
+		inp.Select.Inx = append(inp.Select.Inx, inx)
+		inp.Select.Values = append(inp.Select.Values, 1)
+	}
+
+	for _, cond := range q.Conditions {
+		inx, err := columnIndex(cond.Column)
+		if err != nil {
+			return nil, err
+		}
+
+		inp.Where.Inx = append(inp.Where.Inx, inx)
+		inp.Where.Values = append(inp.Where.Values, cond.Expr)
+	}
+
+	inp.Select.Len = len(inp.Select.Inx)
+	inp.Where.Len = len(inp.Where.Inx)
+
+	names := make(map[int]string)
+	for i, inx := range inp.Select.Inx {
+		names[inx] = strings.ToUpper(q.Columns[i])
+	}
+
+	rows := make([]map[string]string, 0)
+
+	for {
+		var out genQueryOut
+
+		if _, err := con.call(genQueryAN, inp, nil, &out); err != nil {
+			if e, ok := err.(*Error); ok && e.Status == errCatNoRowsFound {
+				break
+			}
+			return nil, err
+		}
+
+		for i := 0; i < out.RowCnt; i++ {
+			row := make(map[string]string, len(out.Results))
+
+			for _, res := range out.Results {
+				if i < len(res.Values) {
+					row[names[res.AttriInx]] = res.Values[i]
+				}
+			}
+
+			rows = append(rows, row)
+		}
+
+		if out.ContinueInx == 0 {
+			break
+		}
+
+		inp.ContinueInx = out.ContinueInx
+	}
+
+	return rows, nil
+}
+
+// Columns returns the names of the columns usable in Query, sorted
+func Columns() []string {
+	columnsMu.RLock()
+	defer columnsMu.RUnlock()
+
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
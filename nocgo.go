@@ -0,0 +1,12 @@
+//go:build !cgo
+// +build !cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// GoRODS calls the iRODS C client library, so it can't be built with CGO_ENABLED=0 or when cross compiling
+// without a C toolchain. This reference fails the build with a readable message in that case.
+// Programs that must build without cgo can use the pure Go client in gorods/native instead.
+var _ = gorods_requires_cgo_and_the_irods_client_library_see_README
@@ -0,0 +1,229 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"fmt"
+)
+
+// OwnershipChange is an access level change made (or planned) by Collection.TransferOwnership.
+// Previous is the access level the principal had on Path before the change.
+type OwnershipChange struct {
+	Path        string
+	Principal   Principal
+	Previous    int
+	AccessLevel int
+}
+
+// String returns the change in path: principal previous -> access level format
+func (c OwnershipChange) String() string {
+	return fmt.Sprintf("%v: %v %v -> %v", c.Path, c.Principal, getTypeString(c.Previous), getTypeString(c.AccessLevel))
+}
+
+// OwnershipReport is returned by Collection.TransferOwnership.
+// Applied lists the changes made before Failure, if any. When a change fails, the applied changes are reverted in
+// reverse order: RolledBack is true if all of them were, otherwise RollbackFailures lists the ones left in place.
+type OwnershipReport struct {
+	Owner            Principal
+	PreviousOwners   []Principal
+	Applied          []OwnershipChange
+	Failure          *ChmodFailure
+	RolledBack       bool
+	RollbackFailures []ChmodFailure
+}
+
+// OK returns true if the transfer completed
+func (r *OwnershipReport) OK() bool {
+	return r.Failure == nil
+}
+
+// pathAccess is an access level granted to a principal on a path
+type pathAccess struct {
+	Principal   Principal
+	AccessLevel int
+}
+
+// TransferOwnership gives newOwner ("name" or "name#zone") own access to the collection, and to all of its
+// sub-collections and data objects if recursive is set, and revokes the own access of every other principal.
+//
+// Own access is granted everywhere first, then revoked from the previous owners, the connected user last, so the
+// transfer can be reverted as long as possible. If any change fails, the changes already applied are reverted.
+// The returned error is only set if the collection tree or its access lists couldn't be read, check the report
+// with OK() for the result of the transfer itself.
+func (col *Collection) TransferOwnership(newOwner string, recursive bool) (*OwnershipReport, error) {
+	z, err := col.con.LocalZone()
+	if err != nil {
+		return nil, err
+	}
+
+	owner := ParsePrincipal(newOwner)
+	if owner.Zone == "" {
+		owner.Zone = z.Name()
+	}
+
+	paths, access, err := col.con.treeAccess(col.path, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	self := Principal{Name: col.con.ClientUser(), Zone: z.Name(), Type: UnknownType}
+
+	changes := planOwnershipTransfer(paths, access, owner, self)
+
+	report := &OwnershipReport{
+		Owner:            owner,
+		PreviousOwners:   make([]Principal, 0),
+		Applied:          make([]OwnershipChange, 0),
+		RollbackFailures: make([]ChmodFailure, 0),
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range changes {
+		if c.AccessLevel == Null && !seen[c.Principal.String()] {
+			seen[c.Principal.String()] = true
+			report.PreviousOwners = append(report.PreviousOwners, c.Principal)
+		}
+	}
+
+	for _, c := range changes {
+		if er := col.con.chmodPath(c.Path, c.Principal.Name, c.Principal.Zone, c.AccessLevel, false); er != nil {
+			report.Failure = &ChmodFailure{Path: c.Path, Err: er}
+			break
+		}
+
+		report.Applied = append(report.Applied, c)
+	}
+
+	if report.Failure != nil {
+		for i := len(report.Applied) - 1; i >= 0; i-- {
+			c := report.Applied[i]
+
+			if er := col.con.chmodPath(c.Path, c.Principal.Name, c.Principal.Zone, c.Previous, false); er != nil {
+				report.RollbackFailures = append(report.RollbackFailures, ChmodFailure{Path: c.Path, Err: er})
+			}
+		}
+
+		report.RolledBack = len(report.RollbackFailures) == 0
+	}
+
+	col.con.InvalidateCache(paths...)
+
+	return report, nil
+}
+
+// planOwnershipTransfer returns the changes giving owner own access to every path and revoking the own access of the
+// other principals, in the order they should be applied: grants, revocations, then revocations from self
+func planOwnershipTransfer(paths []string, access map[string][]pathAccess, owner Principal, self Principal) []OwnershipChange {
+	grants := make([]OwnershipChange, 0)
+	revokes := make([]OwnershipChange, 0)
+	selfRevokes := make([]OwnershipChange, 0)
+
+	for _, p := range paths {
+		previous := Null
+
+		for _, a := range access[p] {
+			if a.Principal.Equal(owner) {
+				previous = a.AccessLevel
+			}
+		}
+
+		if previous != Own {
+			grants = append(grants, OwnershipChange{Path: p, Principal: owner, Previous: previous, AccessLevel: Own})
+		}
+
+		for _, a := range access[p] {
+			if a.AccessLevel != Own || a.Principal.Equal(owner) {
+				continue
+			}
+
+			c := OwnershipChange{Path: p, Principal: a.Principal, Previous: Own, AccessLevel: Null}
+
+			if a.Principal.Equal(self) {
+				selfRevokes = append(selfRevokes, c)
+			} else {
+				revokes = append(revokes, c)
+			}
+		}
+	}
+
+	return append(append(grants, revokes...), selfRevokes...)
+}
+
+// treeAccess returns the paths of the collection at p (with its sub-collections and data objects if recursive is set),
+// and the access levels granted on each of them
+func (con *Connection) treeAccess(p string, recursive bool) ([]string, map[string][]pathAccess, error) {
+	zone, err := con.zoneHint(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	users, err := con.IQuestZone("select USER_ID, USER_NAME, USER_ZONE, USER_TYPE", false, zone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	principals := make(map[string]Principal)
+	for _, row := range users {
+		principals[row["USER_ID"]] = Principal{Name: row["USER_NAME"], Zone: row["USER_ZONE"], Type: ParseUserType(row["USER_TYPE"])}
+	}
+
+	paths := []string{p}
+	access := make(map[string][]pathAccess)
+
+	add := func(path string, userId string, accessName string) error {
+		principal, ok := principals[userId]
+		if !ok {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Get ACL Failed: Unknown user id %v on %v", userId, path))
+		}
+
+		if _, ok := access[path]; !ok && path != p {
+			paths = append(paths, path)
+		}
+
+		access[path] = append(access[path], pathAccess{Principal: principal, AccessLevel: accessLevelOf(accessName)})
+
+		return nil
+	}
+
+	collQueries := []string{fmt.Sprintf("select COLL_NAME, COLL_ACCESS_USER_ID, COLL_ACCESS_NAME where COLL_NAME = '%v'", p)}
+	dataQueries := make([]string, 0)
+
+	if recursive {
+		collQueries = append(collQueries, fmt.Sprintf("select COLL_NAME, COLL_ACCESS_USER_ID, COLL_ACCESS_NAME where COLL_NAME like '%v/%%'", p))
+		dataQueries = append(dataQueries,
+			fmt.Sprintf("select COLL_NAME, DATA_NAME, DATA_ACCESS_USER_ID, DATA_ACCESS_NAME where COLL_NAME = '%v'", p),
+			fmt.Sprintf("select COLL_NAME, DATA_NAME, DATA_ACCESS_USER_ID, DATA_ACCESS_NAME where COLL_NAME like '%v/%%'", p))
+	}
+
+	for _, query := range collQueries {
+		result, err := con.IQuestZone(query, false, zone)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, row := range result {
+			if err := add(row["COLL_NAME"], row["COLL_ACCESS_USER_ID"], row["COLL_ACCESS_NAME"]); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	for _, query := range dataQueries {
+		result, err := con.IQuestZone(query, false, zone)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, row := range result {
+			if err := add(row["COLL_NAME"]+"/"+row["DATA_NAME"], row["DATA_ACCESS_USER_ID"], row["DATA_ACCESS_NAME"]); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return paths, access, nil
+}
@@ -0,0 +1,49 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestPlanOwnershipTransfer(t *testing.T) {
+	owner := UserPrincipal("bob", "tempZone")
+	self := Principal{Name: "alice", Zone: "tempZone", Type: UnknownType}
+
+	paths := []string{"/tempZone/project", "/tempZone/project/a.txt"}
+	access := map[string][]pathAccess{
+		"/tempZone/project": {
+			{Principal: UserPrincipal("alice", "tempZone"), AccessLevel: Own},
+			{Principal: UserPrincipal("carol", "tempZone"), AccessLevel: Own},
+			{Principal: owner, AccessLevel: Read},
+		},
+		"/tempZone/project/a.txt": {
+			{Principal: UserPrincipal("alice", "tempZone"), AccessLevel: Own},
+			{Principal: owner, AccessLevel: Own},
+		},
+	}
+
+	changes := planOwnershipTransfer(paths, access, owner, self)
+
+	if len(changes) != 4 {
+		t.Fatalf("Expected 4 changes, got %v", changes)
+	}
+
+	if c := changes[0]; c.Path != "/tempZone/project" || !c.Principal.Equal(owner) || c.Previous != Read || c.AccessLevel != Own {
+		t.Errorf("Expected the grant first, got %v", c)
+	}
+
+	if c := changes[1]; c.Principal.Name != "carol" || c.AccessLevel != Null {
+		t.Errorf("Expected carol's revocation second, got %v", c)
+	}
+
+	for _, c := range changes[2:] {
+		if c.Principal.Name != "alice" || c.Previous != Own || c.AccessLevel != Null {
+			t.Errorf("Expected the connected user's revocations last, got %v", c)
+		}
+	}
+}
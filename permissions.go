@@ -0,0 +1,145 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"fmt"
+)
+
+// ChmodOptions are used with Collection.ChmodRecursive.
+// PerObject skips the server-side recursive change and always sets the access level object by object, so every failure is reported.
+// Inherit also enables permission inheritance on the collection and all of its sub-collections.
+type ChmodOptions struct {
+	PerObject bool
+	Inherit   bool
+}
+
+// ChmodFailure records an object whose access level couldn't be changed by Collection.ChmodRecursive
+type ChmodFailure struct {
+	Path string
+	Err  error
+}
+
+// String returns the path and error of the failure
+func (f ChmodFailure) String() string {
+	return fmt.Sprintf("%v: %v", f.Path, f.Err)
+}
+
+// ChmodReport is returned by Collection.ChmodRecursive. ServerSide is true if the change was applied with a single
+// recursive request, in which case Applied isn't counted.
+type ChmodReport struct {
+	ServerSide bool
+	Applied    int
+	Failures   []ChmodFailure
+}
+
+// OK returns true if no object failed
+func (r *ChmodReport) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// ChmodRecursive sets the access level of the principal on the collection and everything it contains.
+// The change is first attempted server-side with a single recursive request. If that fails (for example because
+// the user doesn't own some of the objects) the access level is applied to each collection and data object
+// individually, and the objects that couldn't be changed are listed in the report instead of aborting.
+// The returned error is only set if the collection tree couldn't be listed.
+func (col *Collection) ChmodRecursive(p Principal, accessLevel int, opts ChmodOptions) (*ChmodReport, error) {
+	report := new(ChmodReport)
+	report.Failures = make([]ChmodFailure, 0)
+
+	zone := p.Zone
+
+	if zone == "" {
+		z, err := col.con.LocalZone()
+		if err != nil {
+			return nil, err
+		}
+
+		zone = z.Name()
+	}
+
+	if !opts.PerObject {
+		if err := col.con.chmodPath(col.path, p.Name, zone, accessLevel, true); err == nil {
+			report.ServerSide = true
+
+			if opts.Inherit {
+				if er := col.SetInheritance(true, true); er != nil {
+					report.Failures = append(report.Failures, ChmodFailure{Path: col.path, Err: er})
+				}
+			}
+
+			return report, nil
+		}
+	}
+
+	cols, objs, err := col.con.treePaths(col.path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range cols {
+		if er := col.con.chmodPath(c, p.Name, zone, accessLevel, false); er != nil {
+			report.Failures = append(report.Failures, ChmodFailure{Path: c, Err: er})
+			continue
+		}
+
+		if opts.Inherit {
+			if er := col.con.chmodPath(c, "", "", Inherit, false); er != nil {
+				report.Failures = append(report.Failures, ChmodFailure{Path: c, Err: er})
+				continue
+			}
+		}
+
+		report.Applied++
+	}
+
+	for _, o := range objs {
+		if er := col.con.chmodPath(o, p.Name, zone, accessLevel, false); er != nil {
+			report.Failures = append(report.Failures, ChmodFailure{Path: o, Err: er})
+		} else {
+			report.Applied++
+		}
+	}
+
+	return report, nil
+}
+
+// treePaths returns the paths of the collection at p and all of its sub-collections, and of every data object they contain
+func (con *Connection) treePaths(p string) ([]string, []string, error) {
+	zone, err := con.zoneHint(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cols := []string{p}
+	objs := make([]string, 0)
+
+	subCols, err := con.IQuestZone(fmt.Sprintf("select COLL_NAME where COLL_NAME like '%v/%%'", p), false, zone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, row := range subCols {
+		cols = append(cols, row["COLL_NAME"])
+	}
+
+	for _, query := range []string{
+		fmt.Sprintf("select COLL_NAME, DATA_NAME where COLL_NAME = '%v'", p),
+		fmt.Sprintf("select COLL_NAME, DATA_NAME where COLL_NAME like '%v/%%'", p),
+	} {
+		result, err := con.IQuestZone(query, false, zone)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, row := range result {
+			objs = append(objs, row["COLL_NAME"]+"/"+row["DATA_NAME"])
+		}
+	}
+
+	return cols, objs, nil
+}
@@ -0,0 +1,394 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// PolicyEnv is the environment variable holding the path of a policy file, loaded the first time a policy is needed
+const PolicyEnv = "GORODS_POLICY"
+
+// Checksum policies for Policy.Checksum
+const (
+	ChecksumPolicyNone     = "none"
+	ChecksumPolicyRegister = "register"
+	ChecksumPolicyVerify   = "verify"
+)
+
+// Policy controls client behavior for every Connection and Client in the process. It is usually loaded from
+// a JSON or YAML file with LoadPolicy, or from the file named by the GORODS_POLICY environment variable.
+//
+// DefaultResource is used when DataObjOptions.Resource isn't set. Checksum is applied after each Put:
+// "register" computes and stores the server checksum, "verify" also compares it with the local file.
+// ProtectedPaths lists collection prefixes that can't be changed: data objects and collections within them can't be
+// deleted, moved, renamed, overwritten (Put or Copy with Force), truncated or touched, and their access and metadata
+// can't be modified. New data objects and collections can still be created within them.
+type Policy struct {
+	DefaultResource string          `json:"default_resource" yaml:"default_resource"`
+	Checksum        string          `json:"checksum" yaml:"checksum"`
+	Retry           RetryPolicy     `json:"retry" yaml:"retry"`
+	Bandwidth       BandwidthPolicy `json:"bandwidth" yaml:"bandwidth"`
+	ProtectedPaths  []string        `json:"protected_paths" yaml:"protected_paths"`
+}
+
+// RetryPolicy controls how failed operations that can safely be repeated are retried: opens, queries, chmod, touch
+// and truncate. Puts, deletes, moves, copies, metadata changes and collection creation aren't retried, since a
+// repeated attempt after a partial success could fail or apply the change twice. Backoff and MaxBackoff are
+// durations like "200ms" or "5s"; the backoff doubles after each attempt, up to MaxBackoff.
+type RetryPolicy struct {
+	Attempts   int    `json:"attempts" yaml:"attempts"`
+	Backoff    string `json:"backoff" yaml:"backoff"`
+	MaxBackoff string `json:"max_backoff" yaml:"max_backoff"`
+}
+
+// BandwidthPolicy caps the transfer rate of data object reads and writes, in bytes per second, across the process. 0 means unlimited.
+type BandwidthPolicy struct {
+	ReadBytesPerSec  int64 `json:"read_bytes_per_sec" yaml:"read_bytes_per_sec"`
+	WriteBytesPerSec int64 `json:"write_bytes_per_sec" yaml:"write_bytes_per_sec"`
+}
+
+var policyState struct {
+	mu     sync.RWMutex
+	once   sync.Once
+	policy *Policy
+	read   *rateLimiter
+	write  *rateLimiter
+}
+
+// LoadPolicy reads a policy file and makes it the process policy. Files ending in ".yaml" or ".yml" are parsed
+// as YAML, anything else as JSON.
+func LoadPolicy(localPath string) (*Policy, error) {
+	data, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Load Policy Failed: %v", err))
+	}
+
+	ext := strings.ToLower(filepath.Ext(localPath))
+
+	p, er := ParsePolicy(data, ext == ".yaml" || ext == ".yml")
+	if er != nil {
+		return nil, er
+	}
+
+	SetPolicy(p)
+
+	return p, nil
+}
+
+// ParsePolicy decodes a policy from JSON, or from YAML if isYAML is true, and validates it
+func ParsePolicy(data []byte, isYAML bool) (*Policy, error) {
+	p := new(Policy)
+
+	unmarshal := json.Unmarshal
+	if isYAML {
+		unmarshal = yaml.Unmarshal
+	}
+
+	if err := unmarshal(data, p); err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Parse Policy Failed: %v", err))
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Validate returns an error if the policy contains an unknown checksum policy or invalid durations
+func (p *Policy) Validate() error {
+	switch p.Checksum {
+	case "", ChecksumPolicyNone, ChecksumPolicyRegister, ChecksumPolicyVerify:
+	default:
+		return newError(Fatal, -1, fmt.Sprintf("Invalid Policy: unknown checksum policy %q", p.Checksum))
+	}
+
+	for _, d := range []string{p.Retry.Backoff, p.Retry.MaxBackoff} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d); err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("Invalid Policy: %v", err))
+		}
+	}
+
+	if p.Retry.Attempts < 0 || p.Bandwidth.ReadBytesPerSec < 0 || p.Bandwidth.WriteBytesPerSec < 0 {
+		return newError(Fatal, -1, fmt.Sprintf("Invalid Policy: negative values aren't allowed"))
+	}
+
+	return nil
+}
+
+// SetPolicy replaces the process policy. A nil policy restores the defaults.
+func SetPolicy(p *Policy) {
+	if p == nil {
+		p = new(Policy)
+	}
+
+	// Don't let a later lazy load from GORODS_POLICY override an explicit policy
+	policyState.once.Do(func() {})
+
+	policyState.mu.Lock()
+	defer policyState.mu.Unlock()
+
+	policyState.policy = p
+	policyState.read = newRateLimiter(p.Bandwidth.ReadBytesPerSec)
+	policyState.write = newRateLimiter(p.Bandwidth.WriteBytesPerSec)
+}
+
+// CurrentPolicy returns the process policy. Callers must not modify it; use SetPolicy instead.
+func CurrentPolicy() *Policy {
+	policyState.once.Do(func() {
+		p := new(Policy)
+
+		if localPath := os.Getenv(PolicyEnv); localPath != "" {
+			data, err := ioutil.ReadFile(localPath)
+			if err == nil {
+				ext := strings.ToLower(filepath.Ext(localPath))
+				p, err = ParsePolicy(data, ext == ".yaml" || ext == ".yml")
+			}
+			if err != nil {
+				Log(LogWarn, "ignoring the policy", "env", PolicyEnv, "path", localPath, "error", err)
+				p = new(Policy)
+			}
+		}
+
+		policyState.mu.Lock()
+		policyState.policy = p
+		policyState.read = newRateLimiter(p.Bandwidth.ReadBytesPerSec)
+		policyState.write = newRateLimiter(p.Bandwidth.WriteBytesPerSec)
+		policyState.mu.Unlock()
+	})
+
+	policyState.mu.RLock()
+	defer policyState.mu.RUnlock()
+
+	return policyState.policy
+}
+
+// Protected returns true if p is one of the policy's protected paths, or is contained within one
+func (p *Policy) Protected(path string) bool {
+	path = strings.TrimRight(path, "/")
+
+	for _, prefix := range p.ProtectedPaths {
+		prefix = strings.TrimRight(prefix, "/")
+
+		if prefix != "" && (path == prefix || strings.HasPrefix(path, prefix+"/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainsProtected returns true if removing path would also remove a protected path within it
+func (p *Policy) ContainsProtected(path string) bool {
+	path = strings.TrimRight(path, "/") + "/"
+
+	for _, prefix := range p.ProtectedPaths {
+		if strings.HasPrefix(strings.TrimRight(prefix, "/"), path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff returns the delay before retry number attempt (starting at 1)
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	d, _ := time.ParseDuration(r.Backoff)
+	if d <= 0 {
+		d = 100 * time.Millisecond
+	}
+
+	max, _ := time.ParseDuration(r.MaxBackoff)
+
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if max > 0 && d >= max {
+			return max
+		}
+	}
+
+	return d
+}
+
+// defaultResource returns resc, or the policy's default resource if resc isn't set
+func defaultResource(resc interface{}) interface{} {
+	if resc == nil {
+		if r := CurrentPolicy().DefaultResource; r != "" {
+			return r
+		}
+	}
+
+	return resc
+}
+
+// checkPolicy vetoes operations not allowed by the policy. It is called by intercept before any hooks.
+func checkPolicy(e *Event) error {
+	policy := CurrentPolicy()
+
+	if len(policy.ProtectedPaths) == 0 {
+		return nil
+	}
+
+	var protected bool
+
+	switch e.Op {
+	case OpDelete, OpMove:
+		protected = policy.Protected(e.Path) || policy.ContainsProtected(e.Path)
+	case OpPut:
+		protected = e.Params["force"] == "true" && policy.Protected(e.Path)
+	case OpCopy:
+		protected = e.Params["force"] == "true" && policy.Protected(e.Dest)
+	case OpChmod:
+		protected = policy.Protected(e.Path) || e.Params["recursive"] == "true" && policy.ContainsProtected(e.Path)
+	case OpMeta, OpTouch, OpTruncate:
+		protected = policy.Protected(e.Path)
+	}
+
+	if protected {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Operation Vetoed: %v, %v is a protected path", opName(e.Op), e.Path))
+	}
+
+	return nil
+}
+
+// retryable are the operations that can be repeated after a failure without applying them twice
+var retryable = map[int]bool{
+	OpOpen:     true,
+	OpQuery:    true,
+	OpChmod:    true,
+	OpTouch:    true,
+	OpTruncate: true,
+}
+
+// runWithRetry runs fn, retrying failed operations that can safely be repeated according to the policy
+func runWithRetry(e *Event, fn func() error) error {
+	err := fn()
+
+	if e.noRetry || !retryable[e.Op] {
+		return err
+	}
+
+	retry := CurrentPolicy().Retry
+
+	for attempt := 1; err != nil && attempt <= retry.Attempts; attempt++ {
+		if e.Con != nil {
+			e.Con.log(LogWarn, "retrying", "op", opName(e.Op), "path", e.Path, "attempt", attempt, "error", err)
+		}
+
+		time.Sleep(retry.backoff(attempt))
+		err = fn()
+	}
+
+	return err
+}
+
+// applyChecksumPolicy computes (and for ChecksumPolicyVerify, verifies) the checksum of an uploaded object
+func applyChecksumPolicy(obj *DataObj, localPath string) error {
+	policy := CurrentPolicy().Checksum
+
+	if policy == "" || policy == ChecksumPolicyNone {
+		return nil
+	}
+
+	chksum, err := obj.Chksum()
+	if err != nil {
+		return err
+	}
+
+	if policy == ChecksumPolicyVerify {
+		local, err := fileChecksum(localPath, chksum)
+		if err != nil {
+			return err
+		}
+
+		if local != chksum {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Put Checksum Mismatch: %v, local %v, remote %v", obj.path, local, chksum))
+		}
+	}
+
+	return nil
+}
+
+// bandwidthLimited returns true if the policy caps transfers in the direction specified (BytesRead or BytesWritten)
+func bandwidthLimited(direction int) bool {
+	CurrentPolicy()
+
+	policyState.mu.RLock()
+	defer policyState.mu.RUnlock()
+
+	if direction == BytesRead {
+		return policyState.read != nil
+	}
+
+	return policyState.write != nil
+}
+
+// throttle delays the caller so transfers in the direction specified stay within the policy's bandwidth cap
+func throttle(direction int, n int64) {
+	if n <= 0 {
+		return
+	}
+
+	CurrentPolicy()
+
+	policyState.mu.RLock()
+	l := policyState.write
+	if direction == BytesRead {
+		l = policyState.read
+	}
+	policyState.mu.RUnlock()
+
+	if l != nil {
+		l.wait(n)
+	}
+}
+
+// rateLimiter spaces transfers out so their average rate doesn't exceed rate bytes per second
+type rateLimiter struct {
+	rate int64
+	next time.Time
+	mu   sync.Mutex
+}
+
+func newRateLimiter(rate int64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{rate: rate}
+}
+
+func (l *rateLimiter) wait(n int64) {
+	l.mu.Lock()
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.rate) * float64(time.Second)))
+	delay := l.next.Sub(now)
+
+	l.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
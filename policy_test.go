@@ -0,0 +1,119 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	yaml := `
+# Operations team defaults
+default_resource: archiveResc
+checksum: verify
+retry:
+  attempts: 3
+  backoff: 200ms   # doubled on each attempt
+  max_backoff: "1s"
+bandwidth:
+  write_bytes_per_sec: 1048576
+protected_paths:
+  - /tempZone/home/rods/archive
+  - "/tempZone/projects"
+`
+
+	p, err := ParsePolicy([]byte(yaml), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Policy{
+		DefaultResource: "archiveResc",
+		Checksum:        ChecksumPolicyVerify,
+		Retry:           RetryPolicy{Attempts: 3, Backoff: "200ms", MaxBackoff: "1s"},
+		Bandwidth:       BandwidthPolicy{WriteBytesPerSec: 1048576},
+		ProtectedPaths:  []string{"/tempZone/home/rods/archive", "/tempZone/projects"},
+	}
+
+	if !reflect.DeepEqual(p, expected) {
+		t.Errorf("Unexpected policy %+v", p)
+	}
+
+	if json, err := ParsePolicy([]byte(`{"default_resource": "archiveResc", "retry": {"attempts": 3, "backoff": "200ms", "max_backoff": "1s"}, "checksum": "verify", "bandwidth": {"write_bytes_per_sec": 1048576}, "protected_paths": ["/tempZone/home/rods/archive", "/tempZone/projects"]}`), false); err != nil || !reflect.DeepEqual(json, expected) {
+		t.Errorf("Unexpected JSON policy %+v, %v", json, err)
+	}
+
+	if d := p.Retry.backoff(3); d != 800*time.Millisecond {
+		t.Errorf("Unexpected backoff %v", d)
+	}
+
+	if !p.Protected("/tempZone/projects/a/b.txt") || p.Protected("/tempZone/projects2") || !p.ContainsProtected("/tempZone/home") {
+		t.Error("Unexpected protected path result")
+	}
+
+	if _, err := ParsePolicy([]byte("checksum: sometimes\n"), true); err == nil {
+		t.Error("Expected an error for an invalid checksum policy")
+	}
+
+	if _, err := ParsePolicy([]byte("protected_paths: [/tempZone/a\n"), true); err == nil {
+		t.Error("Expected an error for malformed YAML")
+	}
+}
+
+func TestPolicyVetoesProtectedDelete(t *testing.T) {
+	SetPolicy(&Policy{ProtectedPaths: []string{"/tempZone/home/rods/archive"}})
+	defer SetPolicy(nil)
+
+	con := new(Connection)
+
+	for _, tc := range []struct {
+		e      *Event
+		vetoed bool
+	}{
+		{&Event{Op: OpDelete, Path: "/tempZone/home/rods"}, true},
+		{&Event{Op: OpMove, Path: "/tempZone/home/rods/archive/a.txt", Dest: "/tempZone/home/rods/a.txt"}, true},
+		{&Event{Op: OpPut, Path: "/tempZone/home/rods/archive/a.txt", Params: map[string]string{"force": "true"}}, true},
+		{&Event{Op: OpPut, Path: "/tempZone/home/rods/archive/new.txt", Params: map[string]string{"force": "false"}}, false},
+		{&Event{Op: OpCopy, Path: "/tempZone/home/rods/a.txt", Dest: "/tempZone/home/rods/archive/a.txt", Params: map[string]string{"force": "true"}}, true},
+		{&Event{Op: OpChmod, Path: "/tempZone/home/rods", Params: map[string]string{"recursive": "true"}}, true},
+		{&Event{Op: OpChmod, Path: "/tempZone/home/rods", Params: map[string]string{"recursive": "false"}}, false},
+		{&Event{Op: OpMeta, Path: "/tempZone/home/rods/archive"}, true},
+		{&Event{Op: OpTruncate, Path: "/tempZone/home/rods/archive/a.txt"}, true},
+		{&Event{Op: OpMkdir, Path: "/tempZone/home/rods/archive/2017"}, false},
+	} {
+		called := false
+
+		err := con.intercept(tc.e, func() error {
+			called = true
+			return nil
+		})
+
+		if vetoed := err != nil && !called; vetoed != tc.vetoed {
+			t.Errorf("Expected %v on %v vetoed to be %v, got %v", opName(tc.e.Op), tc.e.Path, tc.vetoed, err)
+		}
+	}
+}
+
+func TestPolicyRetriesRepeatableOperations(t *testing.T) {
+	SetPolicy(&Policy{Retry: RetryPolicy{Attempts: 2, Backoff: "1ms"}})
+	defer SetPolicy(nil)
+
+	for op, expected := range map[int]int{OpChmod: 3, OpQuery: 3, OpPut: 1, OpMeta: 1} {
+		calls := 0
+
+		runWithRetry(&Event{Op: op}, func() error {
+			calls++
+			return newError(Fatal, -1, "failed")
+		})
+
+		if calls != expected {
+			t.Errorf("Expected %v attempts of %v, got %v", expected, opName(op), calls)
+		}
+	}
+}
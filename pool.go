@@ -0,0 +1,277 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"fmt"
+	"sync"
+)
+
+// QoS classes used with Pool.Do. Interactive work is served first, Batch work gets the remaining connections
+// without being starved (see PoolOptions).
+const (
+	QoSInteractive = iota
+	QoSBatch
+)
+
+// PoolOptions are used with Client.NewPool. Size is the number of connections (defaults to 4).
+// MaxBatch is the number of connections batch work can hold at once (defaults to Size - 1, so one connection is
+// always left for interactive work). BatchEvery is how many interactive requests are served in a row while batch
+// requests are waiting, before one batch request is (defaults to 4).
+type PoolOptions struct {
+	Size       int
+	MaxBatch   int
+	BatchEvery int
+}
+
+// PoolStats is a snapshot of a Pool's connections and queues, per QoS class
+type PoolStats struct {
+	Open     int
+	InUse    [2]int
+	Waiting  [2]int
+	Requests [2]int
+}
+
+// Pool shares a fixed number of connections between goroutines, each getting a connection of its own for the
+// duration of Do, so concurrent transfers to distinct objects don't queue behind each other on one handle.
+// Requests are scheduled by QoS class, so bulk background jobs can't starve latency sensitive requests.
+type Pool struct {
+	cli   *Client
+	sched *scheduler
+
+	// connect opens the pool's connections, NewConnection except in tests
+	connect func(*ConnectionOptions) (*Connection, error)
+
+	mu     sync.Mutex
+	idle   []*Connection
+	open   int
+	closed bool
+}
+
+// NewPool returns a pool of connections opened with the client's options. Connections are opened as needed.
+func (cli *Client) NewPool(opts PoolOptions) *Pool {
+	if opts.Size <= 0 {
+		opts.Size = 4
+	}
+
+	if opts.MaxBatch <= 0 || opts.MaxBatch > opts.Size {
+		opts.MaxBatch = opts.Size - 1
+		if opts.MaxBatch == 0 {
+			opts.MaxBatch = 1
+		}
+	}
+
+	if opts.BatchEvery <= 0 {
+		opts.BatchEvery = 4
+	}
+
+	return &Pool{
+		cli:     cli,
+		sched:   newScheduler(opts.Size, opts.MaxBatch, opts.BatchEvery),
+		connect: NewConnection,
+		idle:    make([]*Connection, 0, opts.Size),
+	}
+}
+
+// Do waits for a connection according to class (QoSInteractive or QoSBatch), and calls fn with it.
+// The connection is returned to the pool when fn returns, and fn's error is returned.
+func (p *Pool) Do(class int, fn func(*Connection) error) error {
+	if class != QoSInteractive && class != QoSBatch {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Pool Failed: class must be QoSInteractive or QoSBatch"))
+	}
+
+	p.sched.acquire(class)
+	defer p.sched.release(class)
+
+	con, err := p.get()
+	if err != nil {
+		return err
+	}
+	defer p.put(con)
+
+	return fn(con)
+}
+
+func (p *Pool) get() (*Connection, error) {
+	p.mu.Lock()
+
+	if p.closed {
+		p.mu.Unlock()
+		return nil, newError(Fatal, -1, "iRODS Pool Failed: the pool is closed")
+	}
+
+	if n := len(p.idle); n > 0 {
+		con := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+
+		return con, nil
+	}
+
+	p.open++
+	p.mu.Unlock()
+
+	if p.cli.ConnectErr != nil {
+		p.dropped()
+		return nil, newError(Fatal, -1, fmt.Sprintf("Can't open new connection: %v", p.cli.ConnectErr))
+	}
+
+	// Each connection gets its own copy of the options: connecting writes the host, port, user and
+	// authentication outcome back into them
+	opts := *p.cli.Options
+
+	con, err := p.connect(&opts)
+	if err != nil {
+		p.dropped()
+		return nil, newError(Fatal, -1, fmt.Sprintf("Can't open new connection: %v", err))
+	}
+
+	return con, nil
+}
+
+func (p *Pool) put(con *Connection) {
+	p.mu.Lock()
+
+	if !p.closed && con.Connected {
+		p.idle = append(p.idle, con)
+		p.mu.Unlock()
+
+		return
+	}
+
+	p.mu.Unlock()
+
+	if con.Connected {
+		con.Disconnect()
+	}
+
+	p.dropped()
+}
+
+func (p *Pool) dropped() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.open--
+}
+
+// Stats returns the number of open connections, and the requests in use, waiting and served in each class
+func (p *Pool) Stats() PoolStats {
+	stats := p.sched.stats()
+
+	p.mu.Lock()
+	stats.Open = p.open
+	p.mu.Unlock()
+
+	return stats
+}
+
+// Close disconnects the idle connections. Connections in use are disconnected when returned, and Do fails from now on.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	var err error
+
+	for _, con := range idle {
+		if er := con.Disconnect(); er != nil && err == nil {
+			err = er
+		}
+
+		p.dropped()
+	}
+
+	return err
+}
+
+// scheduler hands out size slots to waiting requests. Interactive requests are served first, except that after
+// batchEvery interactive grants in a row while batch requests wait, the next grant goes to a batch request.
+// Batch requests never hold more than maxBatch slots.
+type scheduler struct {
+	mu         sync.Mutex
+	free       int
+	maxBatch   int
+	batchEvery int
+	streak     int
+	inUse      [2]int
+	waiting    [2][]chan struct{}
+	requests   [2]int
+}
+
+func newScheduler(size int, maxBatch int, batchEvery int) *scheduler {
+	return &scheduler{free: size, maxBatch: maxBatch, batchEvery: batchEvery}
+}
+
+// acquire blocks until a slot is granted to the request of class
+func (s *scheduler) acquire(class int) {
+	ch := make(chan struct{})
+
+	s.mu.Lock()
+	s.requests[class]++
+	s.waiting[class] = append(s.waiting[class], ch)
+	s.dispatch()
+	s.mu.Unlock()
+
+	<-ch
+}
+
+// release returns the slot held by a request of class
+func (s *scheduler) release(class int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inUse[class]--
+	s.free++
+	s.dispatch()
+}
+
+// dispatch grants free slots to waiting requests, with s.mu held
+func (s *scheduler) dispatch() {
+	for s.free > 0 {
+		interactive := len(s.waiting[QoSInteractive]) > 0
+		batch := len(s.waiting[QoSBatch]) > 0 && s.inUse[QoSBatch] < s.maxBatch
+
+		var class int
+
+		switch {
+		case interactive && batch && s.streak >= s.batchEvery:
+			class = QoSBatch
+			s.streak = 0
+		case interactive && batch:
+			class = QoSInteractive
+			s.streak++
+		case interactive:
+			class = QoSInteractive
+		case batch:
+			class = QoSBatch
+			s.streak = 0
+		default:
+			return
+		}
+
+		ch := s.waiting[class][0]
+		s.waiting[class] = s.waiting[class][1:]
+		s.inUse[class]++
+		s.free--
+
+		close(ch)
+	}
+}
+
+func (s *scheduler) stats() PoolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return PoolStats{
+		InUse:    s.inUse,
+		Waiting:  [2]int{len(s.waiting[QoSInteractive]), len(s.waiting[QoSBatch])},
+		Requests: s.requests,
+	}
+}
@@ -0,0 +1,164 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSchedulerFairness(t *testing.T) {
+	s := newScheduler(1, 1, 2)
+
+	// Hold the only slot while the queues fill up
+	s.acquire(QoSInteractive)
+
+	granted := make(chan int)
+
+	for _, class := range []int{QoSBatch, QoSBatch, QoSInteractive, QoSInteractive, QoSInteractive, QoSInteractive} {
+		go func(class int) {
+			s.acquire(class)
+			granted <- class
+		}(class)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if st := s.stats(); st.Waiting[QoSInteractive] == 4 && st.Waiting[QoSBatch] == 2 {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Requests didn't queue: %+v", st)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	order := make([]int, 0)
+	last := QoSInteractive
+
+	for i := 0; i < 6; i++ {
+		s.release(last)
+		last = <-granted
+		order = append(order, last)
+	}
+
+	s.release(last)
+
+	expected := []int{QoSInteractive, QoSInteractive, QoSBatch, QoSInteractive, QoSInteractive, QoSBatch}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected grants %v, got %v", expected, order)
+	}
+
+	if st := s.stats(); st.InUse != [2]int{0, 0} || st.Requests != [2]int{5, 2} {
+		t.Errorf("Unexpected stats %+v", st)
+	}
+}
+
+func TestSchedulerMaxBatch(t *testing.T) {
+	s := newScheduler(2, 1, 4)
+
+	s.acquire(QoSBatch)
+
+	done := make(chan bool)
+	go func() {
+		s.acquire(QoSBatch)
+		done <- true
+	}()
+
+	// The second slot is reserved for interactive work
+	s.acquire(QoSInteractive)
+
+	select {
+	case <-done:
+		t.Fatal("A second batch request was granted beyond MaxBatch")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	s.release(QoSBatch)
+	<-done
+}
+
+func TestPoolDoPanic(t *testing.T) {
+	p := new(Client).NewPool(PoolOptions{Size: 1})
+
+	con := &Connection{Connected: true}
+	p.idle, p.open = append(p.idle, con), 1
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the panic to propagate")
+			}
+		}()
+
+		p.Do(QoSInteractive, func(*Connection) error {
+			panic("failed")
+		})
+	}()
+
+	if st := p.Stats(); st.Open != 1 || st.InUse != [2]int{0, 0} || len(p.idle) != 1 {
+		t.Errorf("Expected the connection to be returned to the pool, got %+v", st)
+	}
+}
+
+func TestPoolConnectionOptions(t *testing.T) {
+	cli := &Client{Options: &ConnectionOptions{Host: "icat.example.org", Port: 1247}}
+	p := cli.NewPool(PoolOptions{Size: 4})
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[*ConnectionOptions]bool)
+		n    int
+	)
+
+	// Like dial, the fake writes back into the options it's given; -race catches options shared between connections
+	p.connect = func(opts *ConnectionOptions) (*Connection, error) {
+		mu.Lock()
+		n++
+		host := "icat" + strconv.Itoa(n) + ".example.org"
+		mu.Unlock()
+
+		opts.Host = host
+		opts.Port = 1248
+
+		mu.Lock()
+		seen[opts] = true
+		mu.Unlock()
+
+		return &Connection{Connected: true, Options: opts}, nil
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			p.Do(QoSInteractive, func(con *Connection) error {
+				// Hold the connection so every request opens its own
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			})
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	if len(seen) != n || seen[cli.Options] {
+		t.Errorf("Expected %v connections with options of their own, got %v distinct", n, len(seen))
+	}
+
+	if cli.Options.Host != "icat.example.org" || cli.Options.Port != 1247 {
+		t.Errorf("Expected the client's options to be left untouched, got %+v", cli.Options)
+	}
+}
@@ -0,0 +1,486 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PresignParam is the URL query parameter holding an encoded SignedRef
+const PresignParam = "ref"
+
+// SignedRef is a time-limited reference to a data object, minted by Connection.Presign. It carries a ticket, sealed
+// by Signer.Sign so only services holding the signing key can read it, and an HMAC signature, so a download service
+// can validate it without any shared state. Write references allow uploading the data object instead of downloading it.
+type SignedRef struct {
+	Path      string `json:"p"`
+	Ticket    string `json:"t"`
+	Expires   int64  `json:"e"`
+	Write     bool   `json:"w,omitempty"`
+	KeyID     string `json:"k,omitempty"`
+	Signature string `json:"s"`
+}
+
+// ExpiresAt returns the time after which the reference is no longer valid
+func (ref *SignedRef) ExpiresAt() time.Time {
+	return time.Unix(ref.Expires, 0)
+}
+
+// Encode returns the reference as a URL-safe string
+func (ref *SignedRef) Encode() string {
+	data, _ := json.Marshal(ref)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// URL returns the download URL of the reference on the service at base, like "https://dl.example.org/get"
+func (ref *SignedRef) URL(base string) string {
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+
+	return base + sep + PresignParam + "=" + url.QueryEscape(ref.Encode())
+}
+
+// ObjectURL returns the URL of the data object on the REST gateway at base (see the rest package), like
+// "https://gw.example.org/api/objects/tempZone/home/rods/a.txt?ref=...". Like an S3 presigned URL, it names the
+// object in its path and carries the signature in its query, so it can be used with plain GET (or PUT, for write
+// references) requests, without credentials.
+func (ref *SignedRef) ObjectURL(base string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return strings.TrimRight(base, "/") + "/objects" + ref.Path + "?" + PresignParam + "=" + url.QueryEscape(ref.Encode())
+	}
+
+	u.Path = strings.TrimRight(u.Path, "/") + "/objects" + ref.Path
+	u.RawPath = ""
+	u.RawQuery = PresignParam + "=" + url.QueryEscape(ref.Encode())
+
+	return u.String()
+}
+
+// sealedPrefix marks sealed tickets, which can't be mistaken for ticket strings since those are alphanumeric
+const sealedPrefix = "~"
+
+// ParseSignedRef decodes a reference encoded with SignedRef.Encode. The signature isn't checked; use Signer.Verify.
+func ParseSignedRef(s string) (*SignedRef, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Invalid Signed Reference: %v", err))
+	}
+
+	ref := new(SignedRef)
+
+	if err := json.Unmarshal(data, ref); err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Invalid Signed Reference: %v", err))
+	}
+
+	return ref, nil
+}
+
+// Signer signs and verifies SignedRefs with HMAC-SHA256. Several keys can be registered to allow key rotation:
+// references are signed with the most recently added key and verified with whichever key they name.
+// It is safe for use by multiple goroutines.
+type Signer struct {
+	keys    map[string][]byte
+	current string
+	mu      sync.RWMutex
+}
+
+// NewSigner returns a *Signer that signs with key, identified by keyID
+func NewSigner(keyID string, key []byte) *Signer {
+	s := new(Signer)
+	s.keys = make(map[string][]byte)
+	s.AddKey(keyID, key)
+
+	return s
+}
+
+// AddKey registers a key and makes it the one used for signing new references
+func (s *Signer) AddKey(keyID string, key []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[keyID] = append([]byte(nil), key...)
+	s.current = keyID
+}
+
+// RemoveKey unregisters a key, invalidating every reference signed with it
+func (s *Signer) RemoveKey(keyID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, keyID)
+}
+
+// ticketCipher returns the AEAD sealing the tickets of references signed with key
+func ticketCipher(key []byte) (cipher.AEAD, error) {
+	sealKey := sha256.Sum256(append([]byte("gorods ticket\n"), key...))
+
+	block, err := aes.NewCipher(sealKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts ticket with key, bound to the reference's path
+func seal(key []byte, p string, ticket string) (string, error) {
+	aead, err := ticketCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return sealedPrefix + base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(ticket), []byte(p))), nil
+}
+
+// unseal decrypts a ticket sealed with key for the reference's path
+func unseal(key []byte, p string, sealed string) (string, error) {
+	if !strings.HasPrefix(sealed, sealedPrefix) {
+		return "", fmt.Errorf("the ticket isn't sealed")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := ticketCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("the sealed ticket is too short")
+	}
+
+	ticket, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(p))
+	if err != nil {
+		return "", err
+	}
+
+	return string(ticket), nil
+}
+
+func (s *Signer) mac(key []byte, ref *SignedRef) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(ref.KeyID + "\n" + ref.Path + "\n" + ref.Ticket + "\n" + strconv.FormatInt(ref.Expires, 10)))
+
+	if ref.Write {
+		h.Write([]byte("\nwrite"))
+	}
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Sign seals the Ticket of ref, and sets its KeyID and Signature. References that were already signed are resealed
+// and signed with the current key.
+func (s *Signer) Sign(ref *SignedRef) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ticket := ref.Ticket
+
+	if strings.HasPrefix(ticket, sealedPrefix) {
+		key, ok := s.keys[ref.KeyID]
+		if !ok {
+			return newError(Fatal, -1, fmt.Sprintf("Unable to sign reference: unknown key %q", ref.KeyID))
+		}
+
+		t, err := unseal(key, ref.Path, ticket)
+		if err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("Unable to sign reference: %v", err))
+		}
+
+		ticket = t
+	}
+
+	sealed, err := seal(s.keys[s.current], ref.Path, ticket)
+	if err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Unable to sign reference: %v", err))
+	}
+
+	ref.Ticket = sealed
+	ref.KeyID = s.current
+	ref.Signature = s.mac(s.keys[s.current], ref)
+
+	return nil
+}
+
+// Verify returns an error if ref wasn't signed by one of the registered keys, or has expired
+func (s *Signer) Verify(ref *SignedRef) error {
+	s.mu.RLock()
+	key, ok := s.keys[ref.KeyID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return newError(Fatal, -1, fmt.Sprintf("Invalid Signed Reference: unknown key %q", ref.KeyID))
+	}
+
+	if !hmac.Equal([]byte(s.mac(key, ref)), []byte(ref.Signature)) {
+		return newError(Fatal, -1, fmt.Sprintf("Invalid Signed Reference: bad signature"))
+	}
+
+	if time.Now().After(ref.ExpiresAt()) {
+		return newError(Fatal, -1, fmt.Sprintf("Invalid Signed Reference: expired at %v", ref.ExpiresAt()))
+	}
+
+	return nil
+}
+
+// Ticket verifies ref and returns its unsealed ticket
+func (s *Signer) Ticket(ref *SignedRef) (string, error) {
+	if err := s.Verify(ref); err != nil {
+		return "", err
+	}
+
+	return s.unsealTicket(ref)
+}
+
+// unsealTicket returns the ticket of ref, which must carry a valid signature but may have expired
+func (s *Signer) unsealTicket(ref *SignedRef) (string, error) {
+	s.mu.RLock()
+	key, ok := s.keys[ref.KeyID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return "", newError(Fatal, -1, fmt.Sprintf("Invalid Signed Reference: unknown key %q", ref.KeyID))
+	}
+
+	if !hmac.Equal([]byte(s.mac(key, ref)), []byte(ref.Signature)) {
+		return "", newError(Fatal, -1, fmt.Sprintf("Invalid Signed Reference: bad signature"))
+	}
+
+	ticket, err := unseal(key, ref.Path, ref.Ticket)
+	if err != nil {
+		return "", newError(Fatal, -1, fmt.Sprintf("Invalid Signed Reference: %v", err))
+	}
+
+	return ticket, nil
+}
+
+// PresignOptions are used by Connection.Presign. TTL defaults to one hour. MaxUses limits the number of
+// times the underlying ticket can be used (unlimited if 0). Write mints a reference for uploading the data object,
+// which allows MaxUses uploads (one if 0) of at most MaxBytes each (unlimited if 0).
+type PresignOptions struct {
+	TTL      time.Duration
+	MaxUses  int
+	Write    bool
+	MaxBytes int64
+}
+
+// Presign creates a ticket for the data object at p that expires after opts.TTL, and returns a reference to it
+// signed by signer. Read references can be handed to a DownloadServer, and both read and write references to the
+// REST gateway (see SignedRef.ObjectURL), which serve the object using the ticket without needing the caller's
+// credentials. For a write reference to a data object that doesn't exist yet, an empty data object is created
+// first: tickets only ever grant access to the data object itself, never to its collection.
+func (con *Connection) Presign(p string, signer *Signer, opts PresignOptions) (*SignedRef, error) {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Hour
+	}
+
+	expires := time.Now().Add(opts.TTL)
+
+	typ := TicketRead
+
+	if opts.Write {
+		typ = TicketWrite
+
+		// The upload is made by the service the reference is handed to, bypassing the connection's scanners
+		if err := con.checkIncrementalWrite(p); err != nil {
+			return nil, err
+		}
+
+		if t, err := con.PathType(p); IsNotFound(err) {
+			if err := con.createEmpty(p); err != nil {
+				return nil, err
+			}
+		} else if err != nil {
+			return nil, err
+		} else if t != DataObjType {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Presign Failed: %v is not a data object", p))
+		}
+	}
+
+	ticket, err := con.CreateTicket(p, typ)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := con.SetTicketExpiry(ticket, expires); err != nil {
+		con.DeleteTicket(ticket)
+		return nil, err
+	}
+
+	if opts.MaxUses > 0 {
+		if err := con.SetTicketUses(ticket, opts.MaxUses); err != nil {
+			con.DeleteTicket(ticket)
+			return nil, err
+		}
+	}
+
+	if opts.Write {
+		files := opts.MaxUses
+		if files <= 0 {
+			files = 1
+		}
+
+		if err := con.ModifyTicket(ticket, "write-file", strconv.Itoa(files)); err != nil {
+			con.DeleteTicket(ticket)
+			return nil, err
+		}
+
+		if opts.MaxBytes > 0 {
+			if err := con.ModifyTicket(ticket, "write-byte", strconv.FormatInt(opts.MaxBytes, 10)); err != nil {
+				con.DeleteTicket(ticket)
+				return nil, err
+			}
+		}
+	}
+
+	ref := &SignedRef{
+		Path:    p,
+		Ticket:  ticket,
+		Expires: expires.Unix(),
+		Write:   opts.Write,
+	}
+
+	if err := signer.Sign(ref); err != nil {
+		con.DeleteTicket(ticket)
+		return nil, err
+	}
+
+	return ref, nil
+}
+
+// createEmpty creates an empty data object at p
+func (con *Connection) createEmpty(p string) error {
+	col, err := con.Collection(CollectionOptions{Path: path.Dir(p), SkipCache: true})
+	if err != nil {
+		return err
+	}
+
+	obj, err := col.CreateDataObj(DataObjOptions{Name: path.Base(p)})
+	if err != nil {
+		return err
+	}
+
+	return obj.Close()
+}
+
+// Presign creates a signed reference to the data object. See Connection.Presign.
+func (obj *DataObj) Presign(signer *Signer, opts PresignOptions) (*SignedRef, error) {
+	return obj.con.Presign(obj.path, signer, opts)
+}
+
+// Revoke deletes the ticket behind the reference signed by signer, so it can no longer be used even before it expires
+func (con *Connection) Revoke(ref *SignedRef, signer *Signer) error {
+	ticket, err := signer.unsealTicket(ref)
+	if err != nil {
+		return err
+	}
+
+	return con.DeleteTicket(ticket)
+}
+
+// DownloadServer is a stateless http.Handler that serves data objects referenced by SignedRefs, passed in the
+// "ref" query parameter. Each request opens its own connection using Options (typically an anonymous account)
+// with the reference's ticket. ChunkSize is the size of each read from iRODS, 1MiB by default.
+type DownloadServer struct {
+	Signer    *Signer
+	Options   ConnectionOptions
+	ChunkSize int64
+}
+
+// ServeHTTP implements the http.Handler interface
+func (ds *DownloadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ref, err := ParseSignedRef(r.URL.Query().Get(PresignParam))
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	ticket, err := ds.Signer.Ticket(ref)
+	if err != nil || ref.Write {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	opts := ds.Options
+	opts.Ticket = ticket
+	opts.FastInit = true
+
+	con, err := NewConnection(&opts)
+	if err != nil {
+		con.log(LogError, "download failed", "path", ref.Path, "error", err)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	defer con.Disconnect()
+
+	obj, err := con.DataObject(ref.Path)
+	if err != nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	defer obj.Close()
+
+	mimeType := mime.TypeByExtension(path.Ext(ref.Path))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Content-Length", strconv.FormatInt(obj.Size(), 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": obj.Name()}))
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.FormatInt(int64(time.Until(ref.ExpiresAt())/time.Second), 10))
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	chunkSize := ds.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 1024 * 1024
+	}
+
+	// io.CopyBuffer stops at the first failed write, when the client went away. w is wrapped so that its ReadFrom
+	// doesn't replace the buffer, and each read from iRODS is chunkSize.
+	src := io.NewSectionReader(obj, 0, obj.Size())
+
+	if _, err := io.CopyBuffer(struct{ io.Writer }{w}, src, make([]byte, chunkSize)); err != nil {
+		con.log(LogError, "download failed", "path", ref.Path, "error", err)
+	}
+}
@@ -0,0 +1,116 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSignedRef(t *testing.T) {
+	signer := NewSigner("k1", []byte("secret"))
+
+	ref := &SignedRef{Path: "/tempZone/home/rods/hello.txt", Ticket: "abc", Expires: time.Now().Add(time.Minute).Unix()}
+	signer.Sign(ref)
+
+	u, err := url.Parse(ref.URL("https://dl.example.org/get"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := ParseSignedRef(u.Query().Get(PresignParam))
+	if err != nil || *decoded != *ref {
+		t.Fatalf("Unexpected decoded reference %+v, %v", decoded, err)
+	}
+
+	if err := signer.Verify(decoded); err != nil {
+		t.Error(err)
+	}
+
+	// The ticket is sealed, only the signer can read it
+	if ticket, err := signer.Ticket(decoded); err != nil || ticket != "abc" || decoded.Ticket == "abc" {
+		t.Errorf("Unexpected ticket %q (sealed as %q), %v", ticket, decoded.Ticket, err)
+	}
+
+	if _, err := NewSigner("k1", []byte("other")).Ticket(decoded); err == nil {
+		t.Error("Expected an error unsealing the ticket with another key")
+	}
+
+	// Old references stay valid after a key rotation, until the old key is removed
+	signer.AddKey("k2", []byte("secret2"))
+
+	if err := signer.Verify(decoded); err != nil {
+		t.Error(err)
+	}
+
+	// Signing again reseals the ticket with the new key
+	resigned := *decoded
+	if err := signer.Sign(&resigned); err != nil || resigned.KeyID != "k2" {
+		t.Fatalf("Unexpected resigned reference %+v, %v", resigned, err)
+	}
+
+	if ticket, err := signer.Ticket(&resigned); err != nil || ticket != "abc" {
+		t.Errorf("Unexpected resealed ticket %q, %v", ticket, err)
+	}
+
+	signer.RemoveKey("k1")
+
+	if err := signer.Verify(decoded); err == nil {
+		t.Error("Expected an error for a removed key")
+	}
+
+	signer.Sign(ref)
+	ref.Path = "/tempZone/home/rods/other.txt"
+
+	if err := signer.Verify(ref); err == nil {
+		t.Error("Expected an error for a tampered reference")
+	}
+
+	expired := &SignedRef{Path: "/tempZone/home/rods/hello.txt", Ticket: "abc", Expires: time.Now().Add(-time.Minute).Unix()}
+	signer.Sign(expired)
+
+	if err := signer.Verify(expired); err == nil {
+		t.Error("Expected an error for an expired reference")
+	}
+
+	if ticket, err := NewTicketString(); err != nil || len(ticket) != ticketLength {
+		t.Errorf("Unexpected ticket %v, %v", ticket, err)
+	}
+}
+
+func TestSignedRefWrite(t *testing.T) {
+	signer := NewSigner("k1", []byte("secret"))
+
+	ref := &SignedRef{Path: "/tempZone/home/rods/new file.txt", Ticket: "abc", Expires: time.Now().Add(time.Minute).Unix(), Write: true}
+	signer.Sign(ref)
+
+	if err := signer.Verify(ref); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(ref.ObjectURL("https://gw.example.org/api/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Host != "gw.example.org" || u.Path != "/api/objects/tempZone/home/rods/new file.txt" {
+		t.Errorf("Unexpected object URL %v", u)
+	}
+
+	decoded, err := ParseSignedRef(u.Query().Get(PresignParam))
+	if err != nil || *decoded != *ref {
+		t.Fatalf("Unexpected decoded reference %+v, %v", decoded, err)
+	}
+
+	// A write reference can't be turned into a read reference, or the other way around
+	decoded.Write = false
+
+	if err := signer.Verify(decoded); err == nil {
+		t.Error("Expected an error for a read reference with a write signature")
+	}
+}
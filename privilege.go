@@ -0,0 +1,128 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"fmt"
+)
+
+// ErrInsufficientPrivilege is returned (wrapped in a more specific *GoRodsError) when the connected user's type
+// doesn't allow an operation. The check is done client-side, before calling the iRODS server. Test for it with errors.Is.
+var ErrInsufficientPrivilege = &GoRodsError{
+	LogLevel:  Fatal,
+	Message:   "iRODS Insufficient Privilege",
+	Status:    -1,
+	ErrorName: "INSUFFICIENT_PRIVILEGE",
+}
+
+// ParseUserType returns UserType, AdminType, GroupAdminType or GroupType for the iRODS user type name specified
+// ("rodsuser", "rodsadmin", "groupadmin" or "rodsgroup"), or UnknownType
+func ParseUserType(name string) int {
+	switch name {
+	case "rodsuser":
+		return UserType
+	case "rodsadmin":
+		return AdminType
+	case "groupadmin":
+		return GroupAdminType
+	case "rodsgroup":
+		return GroupType
+	}
+
+	return UnknownType
+}
+
+// UserTypeName returns the iRODS user type name of UserType, AdminType, GroupAdminType or GroupType
+func UserTypeName(typ int) string {
+	switch typ {
+	case UserType:
+		return "rodsuser"
+	case AdminType:
+		return "rodsadmin"
+	case GroupAdminType:
+		return "groupadmin"
+	case GroupType:
+		return "rodsgroup"
+	}
+
+	return "unknown"
+}
+
+// UserType returns the type of the connected user: UserType, AdminType or GroupAdminType.
+// The type is fetched from the iCAT server once, and cached until the connection is re-initialized.
+func (con *Connection) UserType() (int, error) {
+	con.mu.RLock()
+	typ, ok := con.userType, con.userTypeSet
+	con.mu.RUnlock()
+
+	if ok {
+		return typ, nil
+	}
+
+	info, err := con.UserInfo()
+	if err != nil {
+		return UnknownType, err
+	}
+
+	typ = ParseUserType(info["type"])
+
+	con.mu.Lock()
+	con.userType = typ
+	con.userTypeSet = true
+	con.mu.Unlock()
+
+	return typ, nil
+}
+
+// IsAdmin returns true if the connected user is a rodsadmin
+func (con *Connection) IsAdmin() (bool, error) {
+	typ, err := con.UserType()
+	if err != nil {
+		return false, err
+	}
+
+	return typ == AdminType, nil
+}
+
+// IsGroupAdmin returns true if the connected user can manage groups, which means it's a groupadmin or a rodsadmin
+func (con *Connection) IsGroupAdmin() (bool, error) {
+	typ, err := con.UserType()
+	if err != nil {
+		return false, err
+	}
+
+	return typ == AdminType || typ == GroupAdminType, nil
+}
+
+// requireAdmin returns an ErrInsufficientPrivilege error for the operation specified if the connected user isn't a rodsadmin
+func (con *Connection) requireAdmin(op string) error {
+	if ok, err := con.IsAdmin(); err != nil {
+		return err
+	} else if !ok {
+		return privilegeError(op, con.ClientUser(), "rodsadmin")
+	}
+
+	return nil
+}
+
+// requireGroupAdmin returns an ErrInsufficientPrivilege error for the operation specified if the connected user can't manage groups
+func (con *Connection) requireGroupAdmin(op string) error {
+	if ok, err := con.IsGroupAdmin(); err != nil {
+		return err
+	} else if !ok {
+		return privilegeError(op, con.ClientUser(), "groupadmin or rodsadmin")
+	}
+
+	return nil
+}
+
+func privilegeError(op string, user string, required string) error {
+	err := newError(Fatal, -1, fmt.Sprintf("iRODS %v Failed: %v is not a %v", op, user, required))
+	err.ErrorName = ErrInsufficientPrivilege.ErrorName
+
+	return err
+}
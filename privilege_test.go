@@ -0,0 +1,30 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUserTypes(t *testing.T) {
+	for _, typ := range []int{UserType, AdminType, GroupAdminType, GroupType} {
+		if got := ParseUserType(UserTypeName(typ)); got != typ {
+			t.Errorf("ParseUserType(UserTypeName(%v)) returned %v", typ, got)
+		}
+	}
+
+	if ParseUserType("rodsmonkey") != UnknownType {
+		t.Error("Expected UnknownType for an unknown user type name")
+	}
+
+	err := privilegeError("CreateUser", "alice", "rodsadmin")
+
+	if !errors.Is(err, ErrInsufficientPrivilege) {
+		t.Errorf("Expected %v to match ErrInsufficientPrivilege", err)
+	}
+}
@@ -0,0 +1,242 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ProvisionTemplate is the layout of a project: Collections are paths relative to the project root, like "raw" or
+// "analysis/scratch" (missing parents are created too), and Meta holds the AVUs stamped on the collections, keyed
+// by their relative path ("" for the root). A template can be shared by every project of a platform.
+type ProvisionTemplate struct {
+	Collections []string
+	Meta        map[string][]Meta
+}
+
+// ProvisionACL is an access level granted by Connection.Provision on the whole project tree
+type ProvisionACL struct {
+	Principal   Principal
+	AccessLevel int
+}
+
+// ProvisionOptions are used with Connection.Provision. Path is the project root, which must not exist (its parent
+// must). Group, if set, is created if it doesn't exist, with Members added to it, and granted GroupAccess (Write by
+// default) on the project. ACLs are granted as well, and Inherit enables inheritance on every collection of the
+// project so content created later gets the same ACLs. Meta is stamped on the root, after the template's AVUs.
+type ProvisionOptions struct {
+	Path        string
+	Template    ProvisionTemplate
+	Group       string
+	Members     []string
+	GroupAccess int
+	ACLs        []ProvisionACL
+	Inherit     bool
+	Meta        []Meta
+}
+
+// ProvisionResult is returned by Connection.Provision. Collections are the paths created, parents first.
+type ProvisionResult struct {
+	Root         *Collection
+	Group        *Group
+	GroupCreated bool
+	Collections  []string
+}
+
+// Provision creates a project in one call: the collection tree of opts.Template, the project group, the ACLs (with
+// inheritance) and the metadata. If any step fails, what was created is removed again (the tree, and the group if
+// Provision created it) and the error returned. Creating groups requires groupadmin or rodsadmin privileges.
+func (con *Connection) Provision(opts ProvisionOptions) (*ProvisionResult, error) {
+	root := strings.TrimRight(opts.Path, "/")
+
+	paths, err := provisionPaths(root, opts.Template.Collections)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := con.PathType(root); err == nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Provision Failed: %v already exists", root))
+	}
+
+	parent, err := con.Collection(CollectionOptions{Path: path.Dir(root), SkipCache: true})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ProvisionResult{Collections: make([]string, 0, len(paths))}
+
+	if err := con.provision(parent, paths, opts, result); err != nil {
+		if er := con.rollbackProvision(result); er != nil {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Provision Failed: %v, rollback failed: %v", err, er))
+		}
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// provision runs the steps of Provision, recording what it created in result
+func (con *Connection) provision(parent *Collection, paths []string, opts ProvisionOptions, result *ProvisionResult) error {
+	if opts.Group != "" {
+		grp, created, err := con.provisionGroup(opts.Group, opts.Members)
+		result.Group, result.GroupCreated = grp, created
+
+		if err != nil {
+			return err
+		}
+	}
+
+	cols := map[string]*Collection{parent.path: parent}
+
+	for _, p := range paths {
+		col, err := cols[path.Dir(p)].CreateSubCollection(path.Base(p))
+		if err != nil {
+			return err
+		}
+
+		if result.Root == nil {
+			result.Root = col
+		}
+
+		cols[p] = col
+		result.Collections = append(result.Collections, p)
+	}
+
+	root := result.Root.path
+
+	acls := opts.ACLs
+
+	if result.Group != nil {
+		access := opts.GroupAccess
+		if access == 0 {
+			access = Write
+		}
+
+		acls = append([]ProvisionACL{{Principal: PrincipalOf(result.Group), AccessLevel: access}}, acls...)
+	}
+
+	for _, acl := range acls {
+		if err := con.chmodPath(root, acl.Principal.Name, acl.Principal.Zone, acl.AccessLevel, true); err != nil {
+			return err
+		}
+	}
+
+	if opts.Inherit {
+		if err := con.chmodPath(root, "", "", Inherit, true); err != nil {
+			return err
+		}
+	}
+
+	for rel, metas := range opts.Template.Meta {
+		col, ok := cols[path.Join(root, rel)]
+		if !ok {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Provision Failed: template metadata for %q, which isn't a template collection", rel))
+		}
+
+		for _, m := range metas {
+			if _, err := col.AddMeta(m); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, m := range opts.Meta {
+		if _, err := result.Root.AddMeta(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// provisionGroup returns the group name, creating it with members if it doesn't exist
+func (con *Connection) provisionGroup(name string, members []string) (*Group, bool, error) {
+	if err := con.RefreshGroups(); err != nil {
+		return nil, false, err
+	}
+
+	groups, err := con.Groups()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if grp := groups.FindByName(name, con); grp != nil {
+		return grp, false, nil
+	}
+
+	grp, err := con.CreateGroup(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, member := range members {
+		if err := grp.AddUser(member); err != nil {
+			return grp, true, err
+		}
+	}
+
+	return grp, true, nil
+}
+
+// rollbackProvision removes the project tree and the group created by a failed Provision
+func (con *Connection) rollbackProvision(result *ProvisionResult) error {
+	if result.Root != nil {
+		if err := result.Root.Rm(true, true); err != nil {
+			return err
+		}
+	}
+
+	if result.GroupCreated && result.Group != nil {
+		if err := result.Group.Delete(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// provisionPaths returns the absolute paths of root and of the template collections rel below it, with their
+// missing parents, sorted so parents come before their children
+func provisionPaths(root string, rel []string) ([]string, error) {
+	if root == "" || !strings.HasPrefix(root, "/") {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Provision Failed: invalid project path %q", root))
+	}
+
+	set := map[string]bool{root: true}
+
+	for _, r := range rel {
+		clean := path.Clean("/" + r)
+
+		if strings.Contains(r, "..") || clean == "/" {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Provision Failed: invalid template collection %q", r))
+		}
+
+		for p := root + clean; p != root; p = path.Dir(p) {
+			set[p] = true
+		}
+	}
+
+	paths := make([]string, 0, len(set))
+
+	for p := range set {
+		paths = append(paths, p)
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		if di, dj := strings.Count(paths[i], "/"), strings.Count(paths[j], "/"); di != dj {
+			return di < dj
+		}
+
+		return paths[i] < paths[j]
+	})
+
+	return paths, nil
+}
@@ -0,0 +1,41 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProvisionPaths(t *testing.T) {
+	paths, err := provisionPaths("/tempZone/projects/p1", []string{"raw", "analysis/scratch/", "docs", "raw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"/tempZone/projects/p1",
+		"/tempZone/projects/p1/analysis",
+		"/tempZone/projects/p1/docs",
+		"/tempZone/projects/p1/raw",
+		"/tempZone/projects/p1/analysis/scratch",
+	}
+
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+
+	for _, rel := range []string{"../other", "raw/../../x", ""} {
+		if _, err := provisionPaths("/tempZone/projects/p1", []string{rel}); err == nil {
+			t.Errorf("Expected an error for template collection %q", rel)
+		}
+	}
+
+	if _, err := provisionPaths("projects/p1", nil); err == nil {
+		t.Error("Expected an error for a relative project path")
+	}
+}
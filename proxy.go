@@ -0,0 +1,88 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+// AnonymousUser is the iRODS account used by AnonymousAuth connections when no Username is set
+const AnonymousUser = "anonymous"
+
+// ProxyFor returns a Client whose connections authenticate as the client's user, which must be a rodsadmin, but act
+// on behalf of user: the server applies the permissions and quotas of user, and records it as the owner of what they
+// create. zone is the zone of user, the connection's zone if empty. This lets a portal serve many users while only
+// holding the password of its service account. The client's options are copied, and must be UserDefined.
+func (cli *Client) ProxyFor(user string, zone string) *Client {
+	opts := *cli.Options
+
+	opts.ClientUser = user
+	opts.ClientZone = zone
+
+	return &Client{Options: &opts, ConnectErr: cli.ConnectErr, Wrap: cli.Wrap}
+}
+
+// ProxyFor returns the client of a new connection acting on behalf of user, see Client.ProxyFor
+func ProxyFor(opts ConnectionOptions, user string, zone string) (*Client, error) {
+	opts.ClientUser = user
+	opts.ClientZone = zone
+
+	return New(opts)
+}
+
+// ClientUser returns the name of the user the connection acts on behalf of: ConnectionOptions.ClientUser when
+// proxying, the authenticated user otherwise
+func (con *Connection) ClientUser() string {
+	if con.Options.ClientUser != "" {
+		return con.Options.ClientUser
+	}
+
+	return con.username()
+}
+
+// ProxyUser returns the name of the user the connection authenticated as. It is the ClientUser unless proxying.
+func (con *Connection) ProxyUser() string {
+	return con.username()
+}
+
+// username returns ConnectionOptions.Username, or AnonymousUser for AnonymousAuth connections without one. The
+// options can be shared by the connections of a Client, so the default isn't stored in them.
+func (con *Connection) username() string {
+	if con.Options.AuthType == AnonymousAuth && con.Options.Username == "" {
+		return AnonymousUser
+	}
+
+	return con.Options.Username
+}
+
+// IsProxy returns true if the connection acts on behalf of another user than the one it authenticated as
+func (con *Connection) IsProxy() bool {
+	return con.Options.ClientUser != "" && (con.Options.ClientUser != con.username() || con.clientZone() != con.proxyZone())
+}
+
+// IsAnonymous returns true if the connection uses the anonymous account
+func (con *Connection) IsAnonymous() bool {
+	return con.Options.AuthType == AnonymousAuth || con.ClientUser() == AnonymousUser
+}
+
+// proxyZone returns the zone the connection authenticates against
+func (con *Connection) proxyZone() string {
+	if con.Options.UserZone != "" {
+		return con.Options.UserZone
+	}
+
+	return con.Options.Zone
+}
+
+// clientZone returns the zone of the user the connection acts on behalf of
+func (con *Connection) clientZone() string {
+	if con.Options.ClientZone != "" {
+		return con.Options.ClientZone
+	}
+
+	if con.Options.ClientUser != "" {
+		return con.Options.Zone
+	}
+
+	return con.proxyZone()
+}
@@ -0,0 +1,50 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestProxyUsers(t *testing.T) {
+	cli := &Client{Options: &ConnectionOptions{Type: UserDefined, Username: "portal", Zone: "tempZone"}}
+
+	proxied := cli.ProxyFor("alice", "")
+
+	if cli.Options.ClientUser != "" {
+		t.Fatal("ProxyFor modified the options of the original client")
+	}
+
+	con := &Connection{Options: proxied.Options}
+
+	if con.ClientUser() != "alice" || con.ProxyUser() != "portal" || con.clientZone() != "tempZone" {
+		t.Errorf("Unexpected users %v (%v), proxy %v", con.ClientUser(), con.clientZone(), con.ProxyUser())
+	}
+
+	if !con.IsProxy() {
+		t.Error("Expected a proxy connection")
+	}
+
+	remote := &Connection{Options: &ConnectionOptions{Username: "portal", Zone: "tempZone", UserZone: "otherZone"}}
+
+	if remote.IsProxy() || remote.ClientUser() != "portal" || remote.clientZone() != "otherZone" {
+		t.Errorf("Unexpected remote user %v (%v)", remote.ClientUser(), remote.clientZone())
+	}
+
+	anon := &Connection{Options: &ConnectionOptions{AuthType: AnonymousAuth, Username: AnonymousUser}}
+
+	if !anon.IsAnonymous() || anon.IsProxy() {
+		t.Error("Expected an anonymous, non proxy connection")
+	}
+
+	shared := &ConnectionOptions{AuthType: AnonymousAuth}
+	anon = &Connection{Options: shared}
+
+	if anon.ClientUser() != AnonymousUser || anon.ProxyUser() != AnonymousUser || shared.Username != "" {
+		t.Errorf("Expected %v without changing the shared options, got %v", AnonymousUser, anon.ClientUser())
+	}
+}
@@ -0,0 +1,291 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+	"unsafe"
+)
+
+// hashResultToMaps converts a goRodsHashResult_t into a slice of maps keyed by column name
+func hashResultToMaps(result *C.goRodsHashResult_t) []map[string]string {
+	keyArrLen := int(result.keySize)
+	valArrLen := int(result.size) * keyArrLen
+
+	response := make([]map[string]string, int(result.size))
+
+	if valArrLen == 0 {
+		return response
+	}
+
+	// Convert C array to slice
+	keySlice := (*[1 << 30]*C.char)(unsafe.Pointer(result.hashKeys))[:keyArrLen:keyArrLen]
+	valSlice := (*[1 << 30]*C.char)(unsafe.Pointer(result.hashValues))[:valArrLen:valArrLen]
+
+	for n, val := range valSlice {
+		mapInx := n / keyArrLen
+
+		if response[mapInx] == nil {
+			response[mapInx] = make(map[string]string, keyArrLen)
+		}
+
+		response[mapInx][C.GoString(keySlice[n%keyArrLen])] = C.GoString(val)
+	}
+
+	return response
+}
+
+// QueryOptions are used with Connection.Query. MemRows is the number of rows kept in memory before the remaining rows
+// are spilled to a temporary file in TempDir (defaults to 100000 rows, and os.TempDir()). PageSize is the number of rows
+// fetched from the server per request (defaults to 256). Offset skips rows on the server, before the first page.
+// Zone runs the query against a remote zone.
+type QueryOptions struct {
+	MemRows   int
+	PageSize  int
+	Offset    int
+	TempDir   string
+	UpperCase bool
+	Zone      string
+}
+
+// QueryResult holds the rows returned by Connection.Query. Rows beyond QueryOptions.MemRows are stored in a
+// temporary file, which is removed by Close.
+type QueryResult struct {
+	rows  []map[string]string
+	spill *os.File
+	count int
+	con   *Connection
+}
+
+// Query runs a general query like IQuest, but fetches results page by page and spills rows to disk once
+// QueryOptions.MemRows is exceeded, so very large results don't need to fit in memory.
+// Always call Close on the result when done.
+func (con *Connection) Query(query string, opts QueryOptions) (*QueryResult, error) {
+	if opts.MemRows <= 0 {
+		opts.MemRows = 100000
+	}
+
+	if opts.PageSize <= 0 {
+		opts.PageSize = 256
+	}
+
+	return con.collectQuery(query, opts, func(callback func([]map[string]string) error) error {
+		return con.queryPages(query, opts, nil, callback)
+	})
+}
+
+// collectQuery stores the rows of every page passed to callback by pages into a QueryResult, spilling them to disk
+// beyond opts.MemRows. The spill file is removed if pages fails.
+func (con *Connection) collectQuery(query string, opts QueryOptions, pages func(callback func([]map[string]string) error) error) (*QueryResult, error) {
+	res := new(QueryResult)
+	res.con = con
+
+	err := con.intercept(&Event{Op: OpQuery, Query: query, noRetry: true}, func() error {
+		return pages(func(page []map[string]string) error {
+			return res.add(page, opts)
+		})
+	})
+
+	if err != nil {
+		res.Close()
+		return nil, err
+	}
+
+	if res.spill != nil {
+		if _, er := res.spill.Seek(0, io.SeekStart); er != nil {
+			res.Close()
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Query Failed: %v", er))
+		}
+
+		con.watchLeak(res, "QueryResult", query)
+	}
+
+	return res, nil
+}
+
+// pageInfo is updated by queryPages before each page is passed to the callback.
+// Total is the number of rows reported by the catalog, or -1 if it didn't report one.
+type pageInfo struct {
+	Total   int
+	Pages   int
+	Elapsed time.Duration
+}
+
+// queryPages runs the query, passing each page of rows to callback. If callback returns an error, the query is closed on the server.
+// If info isn't nil, the total row count is requested and info is kept up to date.
+func (con *Connection) queryPages(query string, opts QueryOptions, info *pageInfo, callback func([]map[string]string) error) error {
+	var (
+		upper       C.int
+		continueInx C.int
+		total       C.int
+		cTotal      *C.int
+	)
+
+	if opts.UpperCase {
+		upper = C.int(1)
+	}
+
+	if info != nil {
+		info.Total = -1
+		total = C.int(-1)
+		cTotal = &total
+	}
+
+	cQuery := C.CString(query)
+	cZone := C.CString(opts.Zone)
+	defer C.free(unsafe.Pointer(cQuery))
+	defer C.free(unsafe.Pointer(cZone))
+
+	for {
+		var (
+			result C.goRodsHashResult_t
+			err    *C.char
+		)
+
+		start := time.Now()
+
+		ccon := con.GetCcon()
+		status := C.gorods_iquest_page(ccon, cQuery, upper, cZone, C.int(opts.Offset), C.int(opts.PageSize), &continueInx, cTotal, &result, &err)
+		con.ReturnCcon(ccon)
+
+		if info != nil {
+			info.Elapsed += time.Since(start)
+		}
+
+		if status == C.CAT_NO_ROWS_FOUND {
+			return nil
+		} else if status < 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Query Failed: %v", C.GoString(err)))
+		}
+
+		page := hashResultToMaps(&result)
+		C.gorods_free_map_result(&result)
+
+		if info != nil {
+			info.Total = int(total)
+			info.Pages++
+		}
+
+		if cbErr := callback(page); cbErr != nil {
+			if continueInx > 0 {
+				ccon := con.GetCcon()
+				C.gorods_iquest_page(ccon, cQuery, upper, cZone, C.int(0), C.int(0), &continueInx, nil, &result, &err)
+				con.ReturnCcon(ccon)
+			}
+
+			return cbErr
+		}
+
+		if continueInx <= 0 {
+			return nil
+		}
+	}
+}
+
+func (res *QueryResult) add(page []map[string]string, opts QueryOptions) error {
+	for _, row := range page {
+		res.count++
+
+		if res.spill == nil && len(res.rows) < opts.MemRows {
+			res.rows = append(res.rows, row)
+			continue
+		}
+
+		if res.spill == nil {
+			f, err := ioutil.TempFile(opts.TempDir, "gorods-query-")
+			if err != nil {
+				return newError(Fatal, -1, fmt.Sprintf("iRODS Query Failed: %v", err))
+			}
+
+			res.spill = f
+		}
+
+		line, err := json.Marshal(row)
+		if err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Query Failed: %v", err))
+		}
+
+		if _, err := res.spill.Write(append(line, '\n')); err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Query Failed: %v", err))
+		}
+	}
+
+	return nil
+}
+
+// Len returns the total number of rows
+func (res *QueryResult) Len() int {
+	return res.count
+}
+
+// Spilled returns true if some of the rows were written to disk
+func (res *QueryResult) Spilled() bool {
+	return res.spill != nil
+}
+
+// Each calls fn for every row, in the order returned by the server. Iteration stops at the first error returned by fn.
+// Each can be called multiple times.
+func (res *QueryResult) Each(fn func(row map[string]string) error) error {
+	for _, row := range res.rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	if res.spill == nil {
+		return nil
+	}
+
+	if _, err := res.spill.Seek(0, io.SeekStart); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Query Read Failed: %v", err))
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(res.spill))
+
+	for {
+		var row map[string]string
+
+		if err := dec.Decode(&row); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Query Read Failed: %v", err))
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// Close removes the temporary file used to spill rows, if any. It is safe to call more than once.
+func (res *QueryResult) Close() error {
+	if res.spill == nil {
+		return nil
+	}
+
+	name := res.spill.Name()
+	res.spill.Close()
+	res.spill = nil
+	res.rows = nil
+
+	res.con.unwatchLeak(res)
+
+	if err := os.Remove(name); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Query Close Failed: %v", err))
+	}
+
+	return nil
+}
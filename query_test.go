@@ -0,0 +1,133 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func queryTestPage(from int, to int) []map[string]string {
+	page := make([]map[string]string, 0, to-from)
+
+	for i := from; i < to; i++ {
+		page = append(page, map[string]string{"DATA_NAME": "file" + strconv.Itoa(i), "DATA_SIZE": strconv.Itoa(i * 10)})
+	}
+
+	return page
+}
+
+func TestQuerySpill(t *testing.T) {
+	dir := t.TempDir()
+	con := new(Connection)
+
+	res, err := con.collectQuery("select DATA_NAME, DATA_SIZE", QueryOptions{MemRows: 3, TempDir: dir}, func(callback func([]map[string]string) error) error {
+		if err := callback(queryTestPage(0, 2)); err != nil {
+			return err
+		}
+
+		return callback(queryTestPage(2, 5))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Len() != 5 || !res.Spilled() || len(res.rows) != 3 {
+		t.Fatalf("Expected 3 of 5 rows in memory, got %v of %v (spilled %v)", len(res.rows), res.Len(), res.Spilled())
+	}
+
+	spill := res.spill.Name()
+
+	// Rows are returned in order, across memory and the spill file, on every call
+	for pass := 0; pass < 2; pass++ {
+		i := 0
+
+		if err := res.Each(func(row map[string]string) error {
+			if row["DATA_NAME"] != "file"+strconv.Itoa(i) || row["DATA_SIZE"] != strconv.Itoa(i*10) {
+				t.Errorf("Pass %v: unexpected row %v: %v", pass, i, row)
+			}
+
+			i++
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if i != 5 {
+			t.Errorf("Pass %v: expected 5 rows, got %v", pass, i)
+		}
+	}
+
+	stop := errors.New("stop")
+	seen := 0
+
+	if err := res.Each(func(row map[string]string) error {
+		seen++
+		if seen == 4 {
+			return stop
+		}
+		return nil
+	}); err != stop || seen != 4 {
+		t.Errorf("Expected iteration to stop at the fourth row, got %v after %v rows", err, seen)
+	}
+
+	if err := res.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(spill); !os.IsNotExist(err) {
+		t.Errorf("Expected Close to remove the spill file, got %v", err)
+	}
+
+	if err := res.Close(); err != nil {
+		t.Errorf("Expected a second Close to be a no-op, got %v", err)
+	}
+}
+
+func TestQueryInMemory(t *testing.T) {
+	dir := t.TempDir()
+
+	res, err := new(Connection).collectQuery("select DATA_NAME", QueryOptions{MemRows: 3, TempDir: dir}, func(callback func([]map[string]string) error) error {
+		return callback(queryTestPage(0, 3))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+
+	if res.Len() != 3 || res.Spilled() {
+		t.Errorf("Expected 3 rows below the threshold to stay in memory, got %v (spilled %v)", res.Len(), res.Spilled())
+	}
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected no spill file, got %v", files)
+	}
+}
+
+func TestQuerySpillRemovedOnError(t *testing.T) {
+	dir := t.TempDir()
+	failed := errors.New("connection lost")
+
+	res, err := new(Connection).collectQuery("select DATA_NAME", QueryOptions{MemRows: 1, TempDir: dir}, func(callback func([]map[string]string) error) error {
+		if err := callback(queryTestPage(0, 3)); err != nil {
+			return err
+		}
+
+		return failed
+	})
+
+	if err != failed || res != nil {
+		t.Errorf("Expected the query to fail, got %v, %v", res, err)
+	}
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected the spill file to be removed, got %v", files)
+	}
+}
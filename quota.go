@@ -0,0 +1,209 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"unsafe"
+)
+
+// QuotaTotal is the Resource of global quotas, which apply to the total usage across all resources
+const QuotaTotal = "total"
+
+// Quota is a storage quota set on a user or group. OwnerType is UserType or GroupType.
+// Over is the number of bytes the owner is over the limit (negative when under), as of the last usage calculation.
+type Quota struct {
+	Owner      string
+	Zone       string
+	OwnerType  int
+	Resource   string
+	Limit      int64
+	Over       int64
+	ModifyTime time.Time
+}
+
+// Exceeded returns true if the owner was over the limit at the last usage calculation
+func (q Quota) Exceeded() bool {
+	return q.Over > 0
+}
+
+// QuotaUsage is the storage used by a user on a resource, as of the last usage calculation
+type QuotaUsage struct {
+	Owner      string
+	Zone       string
+	Resource   string
+	Usage      int64
+	ModifyTime time.Time
+}
+
+// Quotas returns every quota set in the local zone, global (Resource is QuotaTotal) and per resource
+func (con *Connection) Quotas() ([]Quota, error) {
+	return con.quotas("")
+}
+
+// Quotas returns the global and per resource quotas set on the user
+func (usr *User) Quotas() ([]Quota, error) {
+	return usr.con.quotas(fmt.Sprintf(" and QUOTA_USER_NAME = '%v'", usr.name))
+}
+
+// Quotas returns the global and per resource quotas set on the group
+func (grp *Group) Quotas() ([]Quota, error) {
+	return grp.con.quotas(fmt.Sprintf(" and QUOTA_USER_NAME = '%v'", grp.name))
+}
+
+// Usage returns the storage used by the user on each resource, as of the last usage calculation
+func (usr *User) Usage() ([]QuotaUsage, error) {
+	return usr.con.quotaUsage(fmt.Sprintf(" where QUOTA_USER_NAME = '%v'", usr.name))
+}
+
+// QuotaUsage returns the storage used by every user on each resource, as of the last usage calculation
+func (con *Connection) QuotaUsage() ([]QuotaUsage, error) {
+	return con.quotaUsage("")
+}
+
+// SetUserQuota sets the quota of the user on the resource specified (string or *Resource), or the global quota if resource is QuotaTotal.
+// A limit of 0 removes the quota. You must have the proper rodsadmin privileges to use this function.
+func (con *Connection) SetUserQuota(user string, resource interface{}, limit int64) error {
+	return con.setQuota("user", user, resource, limit)
+}
+
+// SetGroupQuota sets the quota of the group on the resource specified (string or *Resource), or the global quota if resource is QuotaTotal.
+// A limit of 0 removes the quota. You must have the proper rodsadmin privileges to use this function.
+func (con *Connection) SetGroupQuota(group string, resource interface{}, limit int64) error {
+	return con.setQuota("group", group, resource, limit)
+}
+
+// CalculateQuotaUsage updates the usage and over-quota values of every quota (iadmin cu).
+// You must have the proper rodsadmin privileges to use this function.
+func (con *Connection) CalculateQuotaUsage() error {
+	if er := con.requireAdmin("CalculateQuotaUsage"); er != nil {
+		return er
+	}
+
+	var err *C.char
+
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	if status := C.gorods_calculate_quota_usage(ccon, &err); status != 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS CalculateQuotaUsage Failed: %v", C.GoString(err)))
+	}
+
+	return nil
+}
+
+func (con *Connection) setQuota(typ string, owner string, resource interface{}, limit int64) error {
+	if er := con.requireAdmin("SetQuota"); er != nil {
+		return er
+	}
+
+	resc, er := resourceName(resource)
+	if er != nil {
+		return er
+	}
+
+	if resc == "" {
+		resc = QuotaTotal
+	}
+
+	var err *C.char
+
+	cType := C.CString(typ)
+	cOwner := C.CString(owner)
+	cResc := C.CString(resc)
+	cLimit := C.CString(strconv.FormatInt(limit, 10))
+	defer C.free(unsafe.Pointer(cType))
+	defer C.free(unsafe.Pointer(cOwner))
+	defer C.free(unsafe.Pointer(cResc))
+	defer C.free(unsafe.Pointer(cLimit))
+
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	if status := C.gorods_set_quota(cType, cOwner, cResc, cLimit, ccon, &err); status != 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS SetQuota Failed: %v %v on %v, %v", typ, owner, resc, C.GoString(err)))
+	}
+
+	return nil
+}
+
+// quotas runs the global and per resource quota queries, filter is appended to their conditions
+func (con *Connection) quotas(filter string) ([]Quota, error) {
+	response := make([]Quota, 0)
+
+	global, err := con.IQuest("select QUOTA_USER_NAME, QUOTA_USER_ZONE, QUOTA_USER_TYPE, QUOTA_LIMIT, QUOTA_OVER, QUOTA_MODIFY_TIME where QUOTA_RESC_ID = '0'"+filter, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range global {
+		response = append(response, quotaFromRow(row, QuotaTotal))
+	}
+
+	perResc, err := con.IQuest("select QUOTA_USER_NAME, QUOTA_USER_ZONE, QUOTA_USER_TYPE, QUOTA_RESC_NAME, QUOTA_LIMIT, QUOTA_OVER, QUOTA_MODIFY_TIME where QUOTA_RESC_ID <> '0'"+filter, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range perResc {
+		response = append(response, quotaFromRow(row, row["QUOTA_RESC_NAME"]))
+	}
+
+	return response, nil
+}
+
+func quotaFromRow(row map[string]string, resource string) Quota {
+	q := Quota{
+		Owner:      row["QUOTA_USER_NAME"],
+		Zone:       row["QUOTA_USER_ZONE"],
+		OwnerType:  UserType,
+		Resource:   resource,
+		ModifyTime: timeStringToTime(row["QUOTA_MODIFY_TIME"]),
+	}
+
+	if row["QUOTA_USER_TYPE"] == "rodsgroup" {
+		q.OwnerType = GroupType
+	}
+
+	q.Limit, _ = strconv.ParseInt(row["QUOTA_LIMIT"], 10, 64)
+	q.Over, _ = strconv.ParseInt(row["QUOTA_OVER"], 10, 64)
+
+	return q
+}
+
+func (con *Connection) quotaUsage(filter string) ([]QuotaUsage, error) {
+	result, err := con.IQuest("select QUOTA_USER_NAME, QUOTA_USER_ZONE, QUOTA_RESC_NAME, QUOTA_USAGE, QUOTA_USAGE_MODIFY_TIME"+filter, false)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]QuotaUsage, 0, len(result))
+
+	for _, row := range result {
+		response = append(response, quotaUsageFromRow(row))
+	}
+
+	return response, nil
+}
+
+func quotaUsageFromRow(row map[string]string) QuotaUsage {
+	u := QuotaUsage{
+		Owner:      row["QUOTA_USER_NAME"],
+		Zone:       row["QUOTA_USER_ZONE"],
+		Resource:   row["QUOTA_RESC_NAME"],
+		ModifyTime: timeStringToTime(row["QUOTA_USAGE_MODIFY_TIME"]),
+	}
+
+	u.Usage, _ = strconv.ParseInt(row["QUOTA_USAGE"], 10, 64)
+
+	return u
+}
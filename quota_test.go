@@ -0,0 +1,57 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotaFromRow(t *testing.T) {
+	q := quotaFromRow(map[string]string{
+		"QUOTA_USER_NAME":   "research",
+		"QUOTA_USER_ZONE":   "tempZone",
+		"QUOTA_USER_TYPE":   "rodsgroup",
+		"QUOTA_LIMIT":       "1000",
+		"QUOTA_OVER":        "-250",
+		"QUOTA_MODIFY_TIME": "01462363200",
+	}, QuotaTotal)
+
+	if q.Owner != "research" || q.Zone != "tempZone" || q.OwnerType != GroupType || q.Resource != QuotaTotal {
+		t.Errorf("Unexpected quota %+v", q)
+	}
+
+	if q.Limit != 1000 || q.Over != -250 || q.Exceeded() {
+		t.Errorf("Expected a limit of 1000, 250 bytes under, got %+v", q)
+	}
+
+	if !q.ModifyTime.Equal(time.Date(2016, 5, 4, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected modify time %v", q.ModifyTime)
+	}
+
+	if q := quotaFromRow(map[string]string{"QUOTA_USER_TYPE": "rodsuser", "QUOTA_OVER": "10"}, "demoResc"); q.OwnerType != UserType || q.Resource != "demoResc" || !q.Exceeded() {
+		t.Errorf("Expected an exceeded user quota on demoResc, got %+v", q)
+	}
+}
+
+func TestQuotaUsageFromRow(t *testing.T) {
+	u := quotaUsageFromRow(map[string]string{
+		"QUOTA_USER_NAME":         "rods",
+		"QUOTA_USER_ZONE":         "tempZone",
+		"QUOTA_RESC_NAME":         "demoResc",
+		"QUOTA_USAGE":             "4096",
+		"QUOTA_USAGE_MODIFY_TIME": "01462363200",
+	})
+
+	if u.Owner != "rods" || u.Zone != "tempZone" || u.Resource != "demoResc" || u.Usage != 4096 {
+		t.Errorf("Unexpected usage %+v", u)
+	}
+
+	if !u.ModifyTime.Equal(time.Date(2016, 5, 4, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected modify time %v", u.ModifyTime)
+	}
+}
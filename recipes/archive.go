@@ -0,0 +1,246 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+// Package recipes composes the lower level GoRODS APIs into ready-made functions for common data management tasks:
+// archiving a directory with a manifest, verifying fixity, sharing a link, staging a dataset, publishing a release,
+// verified transfers, and tagging. Each recipe is a short, readable function, meant to be used as is or copied
+// as a starting point.
+package recipes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jjacquay712/GoRODS"
+)
+
+// ManifestName is the name of the manifest data object written by ArchiveDirectory
+const ManifestName = "MANIFEST.json"
+
+// ManifestEntry is a single file of an archive. Path is relative to the archive collection,
+// Checksum is formatted like iRODS catalog checksums.
+type ManifestEntry struct {
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+// Manifest lists the files of an archive collection, as recorded when it was created
+type Manifest struct {
+	Source  string          `json:"source"`
+	Created time.Time       `json:"created"`
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// ArchiveDirectory uploads localDir into collection (created if needed), has the server checksum every file and
+// compares it with the local copy, then stores a manifest of the archive as ManifestName within the collection.
+// Files already archived with the same checksum aren't sent again, the others are uploaded with UploadVerified.
+// Any file that failed to upload or whose checksum differs is an error.
+func ArchiveDirectory(con gorods.ConnectionAPI, localDir string, collection string) (*Manifest, error) {
+	collection = strings.TrimRight(collection, "/")
+
+	manifest := &Manifest{Source: localDir, Created: time.Now().UTC(), Entries: make([]ManifestEntry, 0)}
+
+	err := filepath.Walk(localDir, func(localPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(localDir, localPath)
+		rel = filepath.ToSlash(rel)
+
+		target := collection
+		if rel != "." {
+			target = collection + "/" + rel
+		}
+
+		if info.IsDir() {
+			return ensureCollection(con, target)
+		}
+
+		chksum, err := archiveFile(con, localPath, target)
+		if err != nil {
+			return newError(fmt.Sprintf("iRODS Archive Directory Failed: %v, %v", localDir, err))
+		}
+
+		manifest.Entries = append(manifest.Entries, ManifestEntry{Path: rel, Size: info.Size(), Checksum: chksum})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeManifest(con, collection, manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// archiveFile keeps the data object at p if the server checksum matches the local file, and uploads the local
+// file with UploadVerified otherwise. It returns the checksum of the data object.
+func archiveFile(con gorods.ConnectionAPI, localPath string, p string) (string, error) {
+	obj, err := con.DataObject(p)
+	if err == nil {
+		chksum, er := verifyLocal(obj, localPath)
+		obj.Close()
+
+		if er == nil {
+			return chksum, nil
+		}
+	} else if !gorods.IsNotFound(err) {
+		return "", err
+	}
+
+	obj, err = UploadVerified(con, localPath, path.Dir(p))
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	return obj.Chksum()
+}
+
+// ensureCollection creates the collection at p if it doesn't exist
+func ensureCollection(con gorods.ConnectionAPI, p string) error {
+	typ, err := con.PathType(p)
+	if err == nil {
+		if typ != gorods.CollectionType {
+			return newError(fmt.Sprintf("iRODS Archive Directory Failed: %v is not a collection", p))
+		}
+
+		return nil
+	}
+
+	if !gorods.IsNotFound(err) {
+		return err
+	}
+
+	col, err := con.CreateCollection(p)
+	if err != nil {
+		return err
+	}
+
+	return col.Close()
+}
+
+// ReadManifest returns the manifest stored in collection by ArchiveDirectory
+func ReadManifest(con gorods.ConnectionAPI, collection string) (*Manifest, error) {
+	obj, err := con.DataObject(strings.TrimRight(collection, "/") + "/" + ManifestName)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	data, err := obj.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := new(Manifest)
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, newError(fmt.Sprintf("iRODS Read Manifest Failed: %v, %v", collection, err))
+	}
+
+	return manifest, nil
+}
+
+// FixityReport is returned by VerifyFixity. Missing and Changed hold the paths, relative to the collection,
+// of manifest entries that no longer exist or whose content no longer matches.
+type FixityReport struct {
+	Checked int
+	Missing []string
+	Changed []string
+}
+
+// OK returns true if every file of the manifest was found intact
+func (r *FixityReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Changed) == 0
+}
+
+// VerifyFixity has the server recompute the checksum of every file listed in the manifest of the archive collection,
+// and reports the files that are missing or have changed since the archive was created. Failures other than
+// missing files, like a lost connection, are returned as errors instead of being reported.
+func VerifyFixity(con gorods.ConnectionAPI, collection string) (*FixityReport, error) {
+	manifest, err := ReadManifest(con, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	report := new(FixityReport)
+
+	for _, entry := range manifest.Entries {
+		report.Checked++
+
+		obj, err := con.DataObject(path.Join(collection, entry.Path))
+		if gorods.IsNotFound(err) {
+			report.Missing = append(report.Missing, entry.Path)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		chksum, err := obj.Chksum()
+		obj.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if chksum != entry.Checksum || obj.Size() != entry.Size {
+			report.Changed = append(report.Changed, entry.Path)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Changed)
+
+	return report, nil
+}
+
+// writeManifest stores manifest as ManifestName in collection, replacing the previous manifest once written
+func writeManifest(con gorods.ConnectionAPI, collection string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpName, err := tempName(ManifestName)
+	if err != nil {
+		return err
+	}
+
+	obj, err := con.CreateDataObj(collection, gorods.DataObjOptions{Name: tmpName, Size: int64(len(data)), Mode: 0644})
+	if err != nil {
+		return err
+	}
+
+	if err := obj.Write(data); err != nil {
+		obj.Delete(false)
+		return err
+	}
+
+	if err := replace(con, obj, collection+"/"+ManifestName); err != nil {
+		obj.Delete(false)
+		return err
+	}
+
+	return obj.Close()
+}
+
+// checksumAlgorithm returns the algorithm of the iRODS checksum chksum
+func checksumAlgorithm(chksum string) string {
+	if strings.HasPrefix(chksum, "sha2:") {
+		return gorods.ChecksumSHA256
+	}
+
+	return gorods.ChecksumMD5
+}
@@ -0,0 +1,192 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package recipes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jjacquay712/GoRODS"
+)
+
+// AVUs set on release collections by PublishRelease
+const (
+	ReleaseVersionAttr   = "release.version"
+	ReleaseSourceAttr    = "release.source"
+	ReleasePublishedAttr = "release.published"
+)
+
+// PublicGroup is the group granted read access to published releases
+const PublicGroup = "public"
+
+// PublishRelease copies the contents of the collection src to <releases>/<version>, tags the copy with its
+// version, source and publication time, and grants PublicGroup read access to it. It returns the path of the
+// release. Publishing a version that already exists is an error.
+func PublishRelease(con gorods.ConnectionAPI, src string, releases string, version string) (string, error) {
+	releases = strings.TrimRight(releases, "/")
+	dest := releases + "/" + version
+
+	if _, err := con.ObjStat(dest); err == nil {
+		return "", newError(fmt.Sprintf("iRODS Publish Release Failed: %v, release %v already exists", src, dest))
+	} else if !gorods.IsNotFound(err) {
+		return "", err
+	}
+
+	items, err := con.List(src)
+	if err != nil {
+		return "", err
+	}
+
+	release, err := con.CreateCollection(dest)
+	if err != nil {
+		return "", err
+	}
+	defer release.Close()
+
+	for _, item := range items {
+		if err := copyTo(con, item, dest); err != nil {
+			return "", err
+		}
+	}
+
+	for _, m := range []gorods.AVU{
+		{Attribute: ReleaseVersionAttr, Value: version},
+		{Attribute: ReleaseSourceAttr, Value: src},
+		{Attribute: ReleasePublishedAttr, Value: time.Now().UTC().Format(time.RFC3339)},
+	} {
+		if err := con.AddMeta(dest, m); err != nil {
+			return "", err
+		}
+	}
+
+	if err := release.Chmod(PublicGroup, gorods.Read, true); err != nil {
+		return "", err
+	}
+
+	return dest, nil
+}
+
+// copyTo copies the data object or collection of the listing entry into the collection dest
+func copyTo(con gorods.ConnectionAPI, entry gorods.ListingEntry, dest string) error {
+	if entry.Type == gorods.CollectionType {
+		col, err := con.Collection(entry.Path)
+		if err != nil {
+			return err
+		}
+		defer col.Close()
+
+		return col.CopyTo(dest)
+	}
+
+	obj, err := con.DataObject(entry.Path)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	return obj.CopyTo(dest)
+}
+
+// Releases returns the versions published by PublishRelease in the collection releases, sorted by name
+func Releases(con gorods.ConnectionAPI, releases string) ([]string, error) {
+	listing, err := con.List(releases)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0)
+
+	for _, entry := range listing {
+		if entry.Type != gorods.CollectionType {
+			continue
+		}
+
+		metas, err := con.Meta(entry.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range metas {
+			if m.Attribute == ReleaseVersionAttr {
+				versions = append(versions, entry.Name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(versions)
+
+	return versions, nil
+}
+
+// TagTree adds the AVU m to every data object within the collection and its sub collections.
+// Objects already carrying the AVU are skipped. It returns the number of objects tagged.
+func TagTree(con gorods.ConnectionAPI, collection string, m gorods.AVU) (int, error) {
+	listing, err := con.List(collection)
+	if err != nil {
+		return 0, err
+	}
+
+	tagged := 0
+
+	for _, entry := range listing {
+		if entry.Type == gorods.CollectionType {
+			n, err := TagTree(con, entry.Path, m)
+			tagged += n
+
+			if err != nil {
+				return tagged, err
+			}
+
+			continue
+		}
+
+		metas, err := con.Meta(entry.Path)
+		if err != nil {
+			return tagged, err
+		}
+
+		if hasAVU(metas, m) {
+			continue
+		}
+
+		if err := con.AddMeta(entry.Path, m); err != nil {
+			return tagged, err
+		}
+
+		tagged++
+	}
+
+	return tagged, nil
+}
+
+func hasAVU(metas []gorods.AVU, m gorods.AVU) bool {
+	for _, existing := range metas {
+		if existing == m {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindTagged returns the paths of the data objects and collections carrying the AVU attr = value, sorted.
+// It runs a metadata query, so it takes a *gorods.Connection.
+func FindTagged(con *gorods.Connection, attr string, value string) ([]string, error) {
+	objs, err := con.QueryMeta(fmt.Sprintf("%v = %v", attr, value))
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		paths = append(paths, obj.Path())
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
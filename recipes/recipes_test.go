@@ -0,0 +1,304 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package recipes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jjacquay712/GoRODS"
+	"github.com/jjacquay712/GoRODS/mock"
+)
+
+// badChecksumCon reports wrong server checksums for the data objects it hands out, and counts its uploads
+type badChecksumCon struct {
+	*mock.Connection
+	bad  bool
+	puts int
+}
+
+type badChecksumObj struct {
+	gorods.DataObjAPI
+}
+
+func (o badChecksumObj) Chksum() (string, error) {
+	return "00000000000000000000000000000000", nil
+}
+
+func (c *badChecksumCon) wrap(obj gorods.DataObjAPI, err error) (gorods.DataObjAPI, error) {
+	if err != nil || !c.bad {
+		return obj, err
+	}
+
+	return badChecksumObj{obj}, nil
+}
+
+func (c *badChecksumCon) Put(localPath string, colPath string, opts gorods.DataObjOptions) (gorods.DataObjAPI, error) {
+	c.puts++
+
+	return c.wrap(c.Connection.Put(localPath, colPath, opts))
+}
+
+func (c *badChecksumCon) DataObject(p string) (gorods.DataObjAPI, error) {
+	return c.wrap(c.Connection.DataObject(p))
+}
+
+func writeLocal(t *testing.T, p string, data string) {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readRemote(t *testing.T, con *mock.Connection, p string) string {
+	data, err := con.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func names(t *testing.T, con gorods.ConnectionAPI, p string) []string {
+	listing, err := con.List(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := make([]string, 0, len(listing))
+	for _, entry := range listing {
+		response = append(response, entry.Name)
+	}
+
+	return response
+}
+
+func TestUploadVerified(t *testing.T) {
+	con := &badChecksumCon{Connection: mock.New("tempZone", "rods")}
+	local := filepath.Join(t.TempDir(), "f.txt")
+	remote := con.Home() + "/data"
+
+	writeLocal(t, local, "new")
+
+	if err := con.WriteFile(remote+"/f.txt", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+
+	con.bad = true
+
+	if _, err := UploadVerified(con, local, remote); err == nil {
+		t.Fatal("Expected a checksum mismatch to fail")
+	}
+
+	if data := readRemote(t, con.Connection, remote+"/f.txt"); data != "old" {
+		t.Errorf("Expected a mismatch to keep the existing data object, got %q", data)
+	}
+
+	if n := names(t, con, remote); !reflect.DeepEqual(n, []string{"f.txt"}) {
+		t.Errorf("Expected the failed upload to be removed, got %v", n)
+	}
+
+	con.bad = false
+
+	obj, err := UploadVerified(con, local, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if obj.Path() != remote+"/f.txt" {
+		t.Errorf("Unexpected path %v", obj.Path())
+	}
+
+	if data := readRemote(t, con.Connection, remote+"/f.txt"); data != "new" {
+		t.Errorf("Expected the upload to replace the data object, got %q", data)
+	}
+
+	if n := names(t, con, remote); !reflect.DeepEqual(n, []string{"f.txt"}) {
+		t.Errorf("Expected no temporary data objects left, got %v", n)
+	}
+}
+
+func TestDownloadVerified(t *testing.T) {
+	con := &badChecksumCon{Connection: mock.New("tempZone", "rods")}
+	local := filepath.Join(t.TempDir(), "f.txt")
+	remote := con.Home() + "/f.txt"
+
+	if err := con.WriteFile(remote, []byte("remote")); err != nil {
+		t.Fatal(err)
+	}
+
+	writeLocal(t, local, "local")
+
+	con.bad = true
+
+	if err := DownloadVerified(con, remote, local); err == nil {
+		t.Fatal("Expected a checksum mismatch to fail")
+	}
+
+	if data, _ := ioutil.ReadFile(local); string(data) != "local" {
+		t.Errorf("Expected a mismatch to keep the local file, got %q", data)
+	}
+
+	con.bad = false
+
+	if err := DownloadVerified(con, remote, local); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := ioutil.ReadFile(local); string(data) != "remote" {
+		t.Errorf("Expected the downloaded contents, got %q", data)
+	}
+
+	if entries, _ := ioutil.ReadDir(filepath.Dir(local)); len(entries) != 1 {
+		t.Errorf("Expected no temporary files left, got %v entries", len(entries))
+	}
+}
+
+func TestArchiveAndVerifyFixity(t *testing.T) {
+	con := &badChecksumCon{Connection: mock.New("tempZone", "rods")}
+	dir := t.TempDir()
+	archive := con.Home() + "/archive"
+
+	writeLocal(t, filepath.Join(dir, "a.txt"), "a")
+	writeLocal(t, filepath.Join(dir, "sub", "b.txt"), "b")
+
+	manifest, err := ArchiveDirectory(con, dir, archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manifest.Entries) != 2 || manifest.Entries[0].Path != "a.txt" || manifest.Entries[1].Path != "sub/b.txt" {
+		t.Fatalf("Unexpected manifest %+v", manifest.Entries)
+	}
+
+	if con.puts != 2 {
+		t.Errorf("Expected 2 uploads, got %v", con.puts)
+	}
+
+	// Archiving again only checks the files
+	if _, err := ArchiveDirectory(con, dir, archive); err != nil {
+		t.Fatal(err)
+	}
+
+	if con.puts != 2 {
+		t.Errorf("Expected unchanged files not to be uploaded again, got %v uploads", con.puts)
+	}
+
+	stored, err := ReadManifest(con, archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(stored.Entries, manifest.Entries) {
+		t.Errorf("Expected the stored manifest %+v, got %+v", manifest.Entries, stored.Entries)
+	}
+
+	report, err := VerifyFixity(con, archive)
+	if err != nil || !report.OK() || report.Checked != 2 {
+		t.Fatalf("Expected an intact archive, got %+v, %v", report, err)
+	}
+
+	if err := con.WriteFile(archive+"/a.txt", []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := con.DataObject(archive + "/sub/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := obj.Delete(false); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = VerifyFixity(con, archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(report.Changed, []string{"a.txt"}) || !reflect.DeepEqual(report.Missing, []string{"sub/b.txt"}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestPublishRelease(t *testing.T) {
+	con := mock.New("tempZone", "rods")
+	src := con.Home() + "/work"
+	releases := con.Home() + "/releases"
+
+	for p, data := range map[string]string{src + "/x.txt": "x", src + "/sub/y.txt": "y"} {
+		if err := con.WriteFile(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := con.CreateCollection(releases); err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := PublishRelease(con, src, releases, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dest != releases+"/v1" {
+		t.Errorf("Unexpected release path %v", dest)
+	}
+
+	if data := readRemote(t, con, dest+"/sub/y.txt"); data != "y" {
+		t.Errorf("Expected the release to contain the sources, got %q", data)
+	}
+
+	if access, _ := con.Access(dest + "/x.txt"); access[PublicGroup] != gorods.Read {
+		t.Errorf("Expected %v to be granted read access, got %v", PublicGroup, access)
+	}
+
+	if _, err := PublishRelease(con, src, releases, "v1"); err == nil {
+		t.Error("Expected an error publishing an existing release")
+	}
+
+	if _, err := con.CreateCollection(releases + "/scratch"); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := Releases(con, releases)
+	if err != nil || !reflect.DeepEqual(versions, []string{"v1"}) {
+		t.Errorf("Expected the published versions only, got %v, %v", versions, err)
+	}
+}
+
+func TestTagTree(t *testing.T) {
+	con := mock.New("tempZone", "rods")
+	root := con.Home() + "/tree"
+	m := gorods.AVU{Attribute: "project", Value: "p1"}
+
+	for _, p := range []string{root + "/a.txt", root + "/sub/b.txt", root + "/sub/deeper/c.txt"} {
+		if err := con.WriteFile(p, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := con.AddMeta(root+"/a.txt", m); err != nil {
+		t.Fatal(err)
+	}
+
+	tagged, err := TagTree(con, root, m)
+	if err != nil || tagged != 2 {
+		t.Errorf("Expected 2 data objects tagged, got %v, %v", tagged, err)
+	}
+
+	if meta, _ := con.Meta(root + "/sub"); len(meta) != 0 {
+		t.Errorf("Expected collections not to be tagged, got %v", meta)
+	}
+
+	if tagged, err := TagTree(con, root, m); err != nil || tagged != 0 {
+		t.Errorf("Expected tagged data objects to be skipped, got %v, %v", tagged, err)
+	}
+}
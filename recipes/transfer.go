@@ -0,0 +1,190 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package recipes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jjacquay712/GoRODS"
+)
+
+// ShareLink creates a read ticket for the data object at p, valid for ttl, and returns a signed download link
+// for the gorods.DownloadServer running at baseURL with the same signer. Tickets aren't part of ConnectionAPI,
+// so it takes a *gorods.Connection.
+func ShareLink(con *gorods.Connection, p string, signer *gorods.Signer, baseURL string, ttl time.Duration) (string, error) {
+	ref, err := con.Presign(p, signer, gorods.PresignOptions{TTL: ttl})
+	if err != nil {
+		return "", err
+	}
+
+	return ref.URL(baseURL), nil
+}
+
+// StageDataset copies the collection to scratchDir for processing, only transferring files that are missing
+// or differ by checksum, and removing local files that are no longer part of the dataset. It uses Connection.Sync,
+// so it takes a *gorods.Connection.
+func StageDataset(con *gorods.Connection, collection string, scratchDir string) (*gorods.SyncReport, error) {
+	if err := os.MkdirAll(scratchDir, 0755); err != nil {
+		return nil, newError(fmt.Sprintf("iRODS Stage Dataset Failed: %v, %v", collection, err))
+	}
+
+	report, err := con.Sync(scratchDir, collection, gorods.SyncOptions{Direction: gorods.SyncDownload, Checksum: true, Delete: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if failed := report.Failed(); len(failed) > 0 {
+		return report, newError(fmt.Sprintf("iRODS Stage Dataset Failed: %v, %v files not downloaded, first: %v", collection, len(failed), failed[0]))
+	}
+
+	return report, nil
+}
+
+// UploadVerified stores the local file in collection, replacing any existing data object of the same name,
+// and has the server checksum it. The file is uploaded to a temporary name, and only renamed into place once
+// its checksum matches: a failed upload or a checksum mismatch leaves the existing data object untouched.
+func UploadVerified(con gorods.ConnectionAPI, localPath string, collection string) (gorods.DataObjAPI, error) {
+	collection = strings.TrimRight(collection, "/")
+	name := filepath.Base(localPath)
+
+	tmpName, err := tempName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := con.Put(localPath, collection, gorods.DataObjOptions{Name: tmpName})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := verifyLocal(obj, localPath); err != nil {
+		obj.Delete(false)
+		return nil, err
+	}
+
+	if err := replace(con, obj, collection+"/"+name); err != nil {
+		obj.Delete(false)
+		return nil, err
+	}
+
+	return con.DataObject(collection + "/" + name)
+}
+
+// DownloadVerified writes the data object at p to localPath, and checks the local copy against a checksum
+// computed by the server. The data object is downloaded to a temporary file next to localPath, which is only
+// renamed to localPath if the checksums match: an existing local file is kept otherwise.
+func DownloadVerified(con gorods.ConnectionAPI, p string, localPath string) error {
+	obj, err := con.DataObject(p)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	f, err := ioutil.TempFile(filepath.Dir(localPath), "."+filepath.Base(localPath)+".gorods-")
+	if err != nil {
+		return newError(fmt.Sprintf("iRODS Download Verified Failed: %v, %v", p, err))
+	}
+	f.Close()
+
+	tmpPath := f.Name()
+
+	if err := obj.DownloadTo(tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if _, err := verifyLocal(obj, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, localPath); err != nil {
+		os.Remove(tmpPath)
+		return newError(fmt.Sprintf("iRODS Download Verified Failed: %v, %v", p, err))
+	}
+
+	return nil
+}
+
+// verifyLocal has the server checksum obj, and compares it with the checksum of the local file. It returns the
+// checksum.
+func verifyLocal(obj gorods.DataObjAPI, localPath string) (string, error) {
+	chksum, err := obj.Chksum()
+	if err != nil {
+		return "", err
+	}
+
+	local, err := gorods.IRODSChecksum(localPath, checksumAlgorithm(chksum))
+	if err != nil {
+		return "", err
+	}
+
+	if local != chksum {
+		return "", newError(fmt.Sprintf("iRODS Verify Failed: checksum mismatch between %v (%v) and %v (%v)", filepath.Base(localPath), local, obj.Path(), chksum))
+	}
+
+	return chksum, nil
+}
+
+// replace renames obj to p, replacing the data object at p if there is one. The existing data object is renamed
+// aside first, restored if obj can't be renamed into place, and deleted otherwise.
+func replace(con gorods.ConnectionAPI, obj gorods.DataObjAPI, p string) error {
+	existing, err := con.DataObject(p)
+	if err != nil {
+		if !gorods.IsNotFound(err) {
+			return err
+		}
+
+		return obj.Rename(path.Base(p))
+	}
+	defer existing.Close()
+
+	asideName, err := tempName(path.Base(p))
+	if err != nil {
+		return err
+	}
+
+	if err := existing.Rename(asideName); err != nil {
+		return err
+	}
+
+	if err := obj.Rename(path.Base(p)); err != nil {
+		existing.Rename(path.Base(p))
+		return err
+	}
+
+	// obj is in place, a copy left aside if this fails only wastes space
+	existing.Delete(false)
+
+	return nil
+}
+
+// tempName returns a hidden name next to name, for content that replaces name once complete
+func tempName(name string) (string, error) {
+	b := make([]byte, 4)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", newError(fmt.Sprintf("iRODS Put DataObject Failed: %v, %v", name, err))
+	}
+
+	return "." + name + ".gorods-" + hex.EncodeToString(b), nil
+}
+
+// newError returns a *gorods.GoRodsError like those of GoRODS itself, for failures of the recipes
+func newError(message string) *gorods.GoRodsError {
+	return &gorods.GoRodsError{
+		LogLevel: gorods.Fatal,
+		Message:  message,
+		Time:     time.Now(),
+		Status:   -1,
+	}
+}
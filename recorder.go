@@ -0,0 +1,272 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Byte transfer direction constants, used with Recorder.AddBytes
+const (
+	BytesRead = iota
+	BytesWritten
+)
+
+// Recorder receives instrumentation from connections, so metrics and tracing systems (Prometheus, OpenTelemetry, expvar...)
+// can be plugged in without GoRODS depending on them. Set it with ConnectionOptions.Recorder. Implementations must be safe
+// for use by multiple goroutines, and should return quickly.
+type Recorder interface {
+	// ObserveOperation is called after each hooked operation (see OpOpen, OpPut, OpDelete, OpQuery)
+	ObserveOperation(op string, d time.Duration, err error)
+
+	// AddBytes is called with the number of bytes read from or written to data objects
+	AddBytes(direction int, n int64)
+
+	// ObserveConnWait is called with the time spent waiting for the connection handle, and the number
+	// of goroutines that were waiting for it at the time
+	ObserveConnWait(d time.Duration, waiting int)
+
+	// StartSpan is called before each iRODS API call. name is the calling GoRODS function, like "(*DataObj).ReadBytes".
+	// The returned Span is ended when the call completes. Return nil to skip tracing.
+	StartSpan(name string) Span
+}
+
+// Span is a single traced iRODS API call
+type Span interface {
+	End()
+}
+
+// NopRecorder is a Recorder that does nothing. Embed it to implement only some of the Recorder methods.
+type NopRecorder struct{}
+
+// ObserveOperation implements Recorder
+func (NopRecorder) ObserveOperation(op string, d time.Duration, err error) {}
+
+// AddBytes implements Recorder
+func (NopRecorder) AddBytes(direction int, n int64) {}
+
+// ObserveConnWait implements Recorder
+func (NopRecorder) ObserveConnWait(d time.Duration, waiting int) {}
+
+// StartSpan implements Recorder
+func (NopRecorder) StartSpan(name string) Span { return nil }
+
+// OpStats holds the totals recorded for a single operation type by StatsRecorder
+type OpStats struct {
+	Count    int64
+	Errors   int64
+	Duration time.Duration
+	Max      time.Duration
+}
+
+// StatsRecorder is a simple Recorder that keeps totals in memory, useful for logging or exposing with expvar.
+// It implements LabeledRecorder: observations of labeled connections are also totaled per label set, see Labeled.
+type StatsRecorder struct {
+	NopRecorder
+
+	ops          map[string]*OpStats
+	bytesRead    int64
+	bytesWritten int64
+	connWait     time.Duration
+
+	parent  *StatsRecorder
+	labeled map[string]*StatsRecorder
+
+	mu sync.Mutex
+}
+
+// NewStatsRecorder returns an empty *StatsRecorder
+func NewStatsRecorder() *StatsRecorder {
+	r := new(StatsRecorder)
+	r.ops = make(map[string]*OpStats)
+
+	return r
+}
+
+// ObserveOperation implements Recorder
+func (r *StatsRecorder) ObserveOperation(op string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.ops[op]
+	if !ok {
+		s = new(OpStats)
+		r.ops[op] = s
+	}
+
+	s.Count++
+	s.Duration += d
+
+	if d > s.Max {
+		s.Max = d
+	}
+
+	if err != nil {
+		s.Errors++
+	}
+
+	if r.parent != nil {
+		r.parent.ObserveOperation(op, d, err)
+	}
+}
+
+// AddBytes implements Recorder
+func (r *StatsRecorder) AddBytes(direction int, n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if direction == BytesRead {
+		r.bytesRead += n
+	} else {
+		r.bytesWritten += n
+	}
+
+	if r.parent != nil {
+		r.parent.AddBytes(direction, n)
+	}
+}
+
+// ObserveConnWait implements Recorder
+func (r *StatsRecorder) ObserveConnWait(d time.Duration, waiting int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.connWait += d
+
+	if r.parent != nil {
+		r.parent.ObserveConnWait(d, waiting)
+	}
+}
+
+// WithLabels implements LabeledRecorder. The returned recorder keeps the totals of the label set, and adds them to r's.
+func (r *StatsRecorder) WithLabels(labels map[string]string) Recorder {
+	key := formatLabels(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.labeled == nil {
+		r.labeled = make(map[string]*StatsRecorder)
+	}
+
+	child, ok := r.labeled[key]
+	if !ok {
+		child = NewStatsRecorder()
+		child.parent = r
+		r.labeled[key] = child
+	}
+
+	return child
+}
+
+// Labeled returns the recorders of each label set seen, keyed by labels formatted like "job=42 tenant=acme"
+func (r *StatsRecorder) Labeled() map[string]*StatsRecorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	labeled := make(map[string]*StatsRecorder, len(r.labeled))
+
+	for k, v := range r.labeled {
+		labeled[k] = v
+	}
+
+	return labeled
+}
+
+// Ops returns a copy of the per operation totals
+func (r *StatsRecorder) Ops() map[string]OpStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ops := make(map[string]OpStats, len(r.ops))
+
+	for k, v := range r.ops {
+		ops[k] = *v
+	}
+
+	return ops
+}
+
+// Bytes returns the total number of bytes read and written
+func (r *StatsRecorder) Bytes() (read int64, written int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.bytesRead, r.bytesWritten
+}
+
+// ConnWait returns the total time spent waiting for connection handles
+func (r *StatsRecorder) ConnWait() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.connWait
+}
+
+func (con *Connection) recorder() Recorder {
+	if con.Options == nil {
+		return nil
+	}
+
+	if lr, ok := con.Options.Recorder.(LabeledRecorder); ok {
+		return con.labeledRecorder(lr)
+	}
+
+	return con.Options.Recorder
+}
+
+func (con *Connection) recordBytes(direction int, n int64) {
+	if r := con.recorder(); r != nil && n > 0 {
+		r.AddBytes(direction, n)
+	}
+
+	throttle(direction, n)
+
+	if l := con.bandwidth().limiter(direction); l != nil && n > 0 {
+		l.wait(n)
+	}
+}
+
+// callerName returns the name of the GoRODS function that called GetCcon, like "(*DataObj).ReadBytes"
+func callerName() string {
+	pc, _, _, ok := runtime.Caller(3)
+	if !ok {
+		return "unknown"
+	}
+
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+
+	name := fn.Name()
+
+	if i := strings.LastIndex(name, "/"); i > -1 {
+		name = name[i+1:]
+	}
+
+	return strings.TrimPrefix(name, "gorods.")
+}
+
+// startSpan is called by GetCcon once the handle is checked out
+func (con *Connection) startSpan(r Recorder, waited time.Duration) {
+	r.ObserveConnWait(waited, int(atomic.LoadInt32(&con.waiting)))
+
+	con.span = r.StartSpan(callerName())
+}
+
+// endSpan is called by ReturnCcon before the handle is returned
+func (con *Connection) endSpan() {
+	if con.span != nil {
+		con.span.End()
+		con.span = nil
+	}
+}
@@ -0,0 +1,83 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type testSpanRecorder struct {
+	NopRecorder
+
+	started []string
+	ended   int
+}
+
+func (r *testSpanRecorder) StartSpan(name string) Span {
+	r.started = append(r.started, name)
+	return r
+}
+
+func (r *testSpanRecorder) End() {
+	r.ended++
+}
+
+func TestStatsRecorder(t *testing.T) {
+	stats := NewStatsRecorder()
+	con := &Connection{Options: &ConnectionOptions{Recorder: stats}}
+
+	con.intercept(&Event{Op: OpQuery, Query: "select DATA_NAME"}, func() error {
+		return nil
+	})
+
+	con.intercept(&Event{Op: OpQuery, Query: "select DATA_NAME"}, func() error {
+		time.Sleep(time.Millisecond)
+		return errors.New("query failed")
+	})
+
+	query := stats.Ops()["query"]
+
+	if query.Count != 2 || query.Errors != 1 || query.Max < time.Millisecond || query.Duration < query.Max {
+		t.Errorf("Unexpected query totals %+v", query)
+	}
+
+	con.recordBytes(BytesRead, 100)
+	con.recordBytes(BytesWritten, 40)
+	con.recordBytes(BytesWritten, 2)
+	con.recordBytes(BytesRead, 0)
+
+	if read, written := stats.Bytes(); read != 100 || written != 42 {
+		t.Errorf("Expected 100 bytes read and 42 written, got %v and %v", read, written)
+	}
+
+	stats.ObserveConnWait(time.Second, 2)
+	stats.ObserveConnWait(time.Second, 1)
+
+	if d := stats.ConnWait(); d != 2*time.Second {
+		t.Errorf("Expected 2s of waiting, got %v", d)
+	}
+}
+
+func TestRecorderSpans(t *testing.T) {
+	r := new(testSpanRecorder)
+	con := &Connection{Options: &ConnectionOptions{Recorder: r}}
+
+	con.startSpan(con.recorder(), 0)
+	con.endSpan()
+	con.endSpan()
+
+	if len(r.started) != 1 || r.started[0] == "" || r.ended != 1 {
+		t.Errorf("Expected a single span to be started and ended once, got %v started, %v ended", r.started, r.ended)
+	}
+
+	// Connections without a recorder record nothing
+	if rec := new(Connection).recorder(); rec != nil {
+		t.Errorf("Expected no recorder, got %v", rec)
+	}
+}
@@ -0,0 +1,206 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// thisAddress is the host returned by rcGetHostForGet and rcGetHostForPut when no redirection is needed
+const thisAddress = "thisAddress"
+
+// Redirects returns the hosts of the resource servers the connection opened connections to, to transfer data
+// objects directly (see ConnectionOptions.NoRedirect)
+func (con *Connection) Redirects() []string {
+	con.mu.RLock()
+	defer con.mu.RUnlock()
+
+	hosts := make([]string, 0, len(con.redirects))
+
+	for host := range con.redirects {
+		hosts = append(hosts, host)
+	}
+
+	return hosts
+}
+
+// redirectable returns true if transfers may be redirected: the connection options must be reusable to connect to
+// another server, which excludes environment defined connections and (one time) PAM passwords
+func (con *Connection) redirectable() bool {
+	return con.Options != nil && !con.Options.NoRedirect && con.Options.Type == UserDefined && con.Options.AuthType != PAMAuth
+}
+
+// transferCon returns the connection to transfer the data object at p with: a connection to the resource server
+// the server redirects the transfer to, or con itself. Failing to redirect isn't fatal, con is then used.
+// The host a resource is transferred with doesn't depend on the data object, so the server is only asked once per
+// resource and direction.
+func (con *Connection) transferCon(p string, resc string, forPut bool) *Connection {
+	if !con.redirectable() {
+		return con
+	}
+
+	host, err := con.transferHost(p, resc, forPut)
+	if err != nil {
+		con.logf("unable to get the transfer host of %v: %v", p, err)
+		return con
+	}
+
+	if host == "" {
+		return con
+	}
+
+	rcon, err := con.redirectCon(host)
+	if err != nil {
+		con.logf("unable to connect to %v for the transfer of %v, using %v: %v", host, p, con.Options.Host, err)
+		return con
+	}
+
+	return rcon
+}
+
+// transferHost returns the host transfers to or from resc are redirected to, "" if they stay on con, asking the
+// server the first time only
+func (con *Connection) transferHost(p string, resc string, forPut bool) (string, error) {
+	key := "get:" + resc
+	if forPut {
+		key = "put:" + resc
+	}
+
+	con.mu.RLock()
+	host, ok := con.transferHosts[key]
+	con.mu.RUnlock()
+
+	if ok {
+		return host, nil
+	}
+
+	host, err := con.hostFor(p, resc, forPut)
+	if err != nil {
+		return "", err
+	}
+
+	host = redirectHost(host, con.Options.Host)
+
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	if con.transferHosts == nil {
+		con.transferHosts = make(map[string]string)
+	}
+
+	con.transferHosts[key] = host
+
+	return host, nil
+}
+
+// hostFor asks the server which host the data object at p should be transferred with
+func (con *Connection) hostFor(p string, resc string, forPut bool) (string, error) {
+	var (
+		errMsg *C.char
+		host   *C.char
+		put    int
+	)
+
+	if forPut {
+		put = 1
+	}
+
+	cPath := C.CString(p)
+	cResc := C.CString(resc)
+	defer C.free(unsafe.Pointer(cPath))
+	defer C.free(unsafe.Pointer(cResc))
+
+	ccon := con.GetCcon()
+	status := C.gorods_get_host_for(ccon, cPath, cResc, C.int(put), &host, &errMsg)
+	con.ReturnCcon(ccon)
+
+	if status < 0 {
+		return "", newError(Fatal, status, fmt.Sprintf("iRODS Get Host Failed: %v, %v", p, C.GoString(errMsg)))
+	}
+
+	if host == nil {
+		return "", nil
+	}
+
+	defer C.free(unsafe.Pointer(host))
+
+	return C.GoString(host), nil
+}
+
+// redirectHost returns the host a transfer is redirected to, given the host returned by the server and the host
+// of the connection, or "" if the transfer stays on the connection
+func redirectHost(host string, current string) string {
+	host = strings.TrimSpace(host)
+
+	if host == "" || host == thisAddress || strings.EqualFold(host, current) {
+		return ""
+	}
+
+	return host
+}
+
+// redirectCon returns the connection to host, opening it with the options of con if needed
+func (con *Connection) redirectCon(host string) (*Connection, error) {
+	con.mu.RLock()
+	rcon, ok := con.redirects[host]
+	con.mu.RUnlock()
+
+	if ok && rcon.Connected {
+		return rcon, nil
+	}
+
+	opts := *con.Options
+	opts.Host = host
+	opts.FastInit = true
+
+	rcon, err := NewConnection(&opts)
+	if err != nil {
+		return nil, err
+	}
+
+	con.mu.Lock()
+	defer con.mu.Unlock()
+
+	// Another goroutine may have connected meanwhile
+	if other, ok := con.redirects[host]; ok && other.Connected {
+		rcon.Disconnect()
+		return other, nil
+	}
+
+	if con.redirects == nil {
+		con.redirects = make(map[string]*Connection)
+	}
+
+	con.redirects[host] = rcon
+
+	con.log(LogInfo, "redirecting transfers", "host", host)
+
+	return rcon, nil
+}
+
+// closeRedirects disconnects the connections opened by redirectCon
+func (con *Connection) closeRedirects() error {
+	con.mu.Lock()
+	redirects := con.redirects
+	con.redirects = nil
+	con.mu.Unlock()
+
+	var err error
+
+	for _, rcon := range redirects {
+		if er := rcon.Disconnect(); er != nil {
+			err = er
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,25 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestRedirectHost(t *testing.T) {
+	for host, expected := range map[string]string{
+		"":                    "",
+		"thisAddress":         "",
+		"ICAT.example.org":    "",
+		"resc1.example.org":   "resc1.example.org",
+		" resc2.example.org ": "resc2.example.org",
+	} {
+		if got := redirectHost(host, "icat.example.org"); got != expected {
+			t.Errorf("Expected redirect host %q for %q, got %q", expected, host, got)
+		}
+	}
+}
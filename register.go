@@ -0,0 +1,117 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+)
+
+// Checksum constants used with RegisterOptions.Checksum. RegChecksum computes and stores the checksum of
+// registered files (ireg -k), RegVerifyChecksum also verifies it after registration (ireg -K).
+const (
+	RegNoChecksum = iota
+	RegChecksum
+	RegVerifyChecksum
+)
+
+// RegisterOptions are used with Connection.RegisterPath. Recursive registers a directory tree as a collection (ireg -C).
+// Replica registers the file as an additional replica of an existing data object (ireg --repl).
+// DataType defaults to "generic". ExcludeFiles is a list of file names to skip, as used by ireg --exclude-from.
+type RegisterOptions struct {
+	Recursive    bool
+	Force        bool
+	Replica      bool
+	Checksum     int
+	DataType     string
+	ExcludeFiles string
+}
+
+// RegisterPath registers a file or directory that already exists on the resource server (in a vault, or on a filesystem
+// mounted by the server) into the catalog at logicalPath, without copying any data. Unlike RegPhysObj,
+// physicalPath is a path on the server hosting the resource, not on the local machine. resource can be a string or *Resource.
+// Registering paths outside of a vault requires rodsadmin privileges.
+func (con *Connection) RegisterPath(physicalPath string, logicalPath string, resource interface{}, opts RegisterOptions) error {
+	var (
+		errMsg     *C.char
+		cRecursive C.int
+		cForce     C.int
+		cReplica   C.int
+	)
+
+	if physicalPath == "" || logicalPath == "" {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS RegisterPath Failed: physicalPath or logicalPath not set"))
+	}
+
+	rescName, err := resourceName(resource)
+	if err != nil {
+		return err
+	}
+
+	if opts.Recursive {
+		cRecursive = C.int(1)
+	}
+
+	if opts.Force {
+		cForce = C.int(1)
+	}
+
+	if opts.Replica {
+		cReplica = C.int(1)
+	}
+
+	cPhysPath := C.CString(physicalPath)
+	cRodsPath := C.CString(logicalPath)
+	cResource := C.CString(rescName)
+	cDataType := C.CString(opts.DataType)
+	cExcludeFiles := C.CString(opts.ExcludeFiles)
+
+	defer C.free(unsafe.Pointer(cPhysPath))
+	defer C.free(unsafe.Pointer(cRodsPath))
+	defer C.free(unsafe.Pointer(cResource))
+	defer C.free(unsafe.Pointer(cDataType))
+	defer C.free(unsafe.Pointer(cExcludeFiles))
+
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	if status := C.gorods_register_path(cPhysPath, cRodsPath, cResource, cDataType, cRecursive, cForce, cReplica, C.int(opts.Checksum), cExcludeFiles, ccon, &errMsg); status < 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS RegisterPath Failed: %v, %v", physicalPath, C.GoString(errMsg)))
+	}
+
+	return nil
+}
+
+// UnregisterReplica removes the catalog entry of a replica without deleting its physical file (irm -U).
+// Pass a negative replNum to unregister all replicas, removing the data object from the catalog.
+func (con *Connection) UnregisterReplica(logicalPath string, replNum int) error {
+	var errMsg *C.char
+
+	repl := ""
+	if replNum >= 0 {
+		repl = strconv.Itoa(replNum)
+	}
+
+	cRodsPath := C.CString(logicalPath)
+	cReplNum := C.CString(repl)
+
+	defer C.free(unsafe.Pointer(cRodsPath))
+	defer C.free(unsafe.Pointer(cReplNum))
+
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	if status := C.gorods_unregister_replica(cRodsPath, cReplNum, ccon, &errMsg); status < 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS UnregisterReplica Failed: %v, %v", logicalPath, C.GoString(errMsg)))
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestRegisterPathArguments(t *testing.T) {
+	con := new(Connection)
+
+	if err := con.RegisterPath("", "/tempZone/home/rods/a.txt", nil, RegisterOptions{}); err == nil {
+		t.Error("Expected an error without a physical path")
+	}
+
+	if err := con.RegisterPath("/var/lib/irods/Vault/a.txt", "", nil, RegisterOptions{}); err == nil {
+		t.Error("Expected an error without a logical path")
+	}
+
+	if err := con.RegisterPath("/var/lib/irods/Vault/a.txt", "/tempZone/home/rods/a.txt", 42, RegisterOptions{}); err == nil {
+		t.Error("Expected an error for an invalid resource")
+	}
+}
+
+func TestUnregisterAndRegisterPath(t *testing.T) {
+	client, conErr := New(ConnectionOptions{
+		Type: UserDefined,
+
+		Host: "localhost",
+		Port: 1247,
+		Zone: "tempZone",
+
+		Username: "rods",
+		Password: "password",
+	})
+
+	if conErr != nil {
+		t.Fatal(conErr)
+	}
+
+	if openErr := client.OpenCollection(CollectionOptions{
+		Path: "/tempZone/home/rods",
+	}, func(col *Collection, con *Connection) {
+		do, err := col.CreateDataObj(DataObjOptions{Name: "register_test.txt"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := do.Write([]byte("test123content")); err != nil {
+			t.Fatal(err)
+		}
+
+		rows, err := con.IQuest("select DATA_PATH where COLL_NAME = '/tempZone/home/rods' and DATA_NAME = 'register_test.txt'", false)
+		if err != nil || len(rows) != 1 {
+			t.Fatalf("Unable to find the physical path: %v, %v", rows, err)
+		}
+
+		// The physical file is kept, so it can be registered again
+		if err := con.UnregisterReplica(do.Path(), -1); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := con.PathType(do.Path()); !IsNotFound(err) {
+			t.Fatalf("Expected the data object to be unregistered, got %v", err)
+		}
+
+		if err := con.RegisterPath(rows[0]["DATA_PATH"], do.Path(), "demoResc", RegisterOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		stat, err := con.ObjStat(do.Path())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if stat.Size != 14 {
+			t.Errorf("Expected the registered data object to keep its size, got %+v", stat)
+		}
+
+		registered, err := con.DataObject(do.Path())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := registered.Delete(true); err != nil {
+			t.Fatal(err)
+		}
+	}); openErr != nil {
+		t.Fatal(openErr)
+	}
+}
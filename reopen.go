@@ -0,0 +1,102 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"sync/atomic"
+)
+
+// SetReopen overrides ConnectionOptions.ReopenHandles for the data object. With reopen set, a handle lost with its
+// connection (the server times out idle agents) is re-opened lazily, on the next read, write or seek, with the same
+// access mode and at the same offset, so long running readers like HTTP range servers needn't reconnect themselves.
+// O_TRUNC isn't applied again.
+func (obj *DataObj) SetReopen(reopen bool) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	obj.reopen = reopen
+	obj.reopenSet = true
+}
+
+// reopens returns true if the handle is re-opened after the connection handle was replaced
+func (obj *DataObj) reopens() bool {
+	if obj.reopenSet {
+		return obj.reopen
+	}
+
+	return obj.con.Options != nil && obj.con.Options.ReopenHandles
+}
+
+// stale returns true if the handle was opened on a connection handle that has since been replaced
+func (obj *DataObj) stale() bool {
+	return int(obj.chandle) > -1 && obj.openedGen != obj.handleCon().Reconnects()
+}
+
+// dropHandle forgets a stale handle. It can't be closed, the server side agent that opened it is gone.
+func (obj *DataObj) dropHandle() {
+	obj.chandle = C.int(-1)
+	obj.con.unwatchLeak(obj)
+}
+
+// revive re-opens the handle, if re-opening is enabled and the handle is stale, and seeks back to the offset
+func (obj *DataObj) revive() error {
+	if !obj.reopens() {
+		return nil
+	}
+
+	obj.handleCon().refresh()
+
+	if !obj.stale() {
+		return nil
+	}
+
+	offset := obj.offset
+	flags := int(obj.openedAs)
+
+	if obj.appending {
+		flags |= O_APPEND
+	}
+
+	obj.con.logf("re-opening %v after reconnecting to %v", obj.path, obj.handleCon().Options.Host)
+
+	obj.dropHandle()
+
+	if er := obj.con.intercept(&Event{Op: OpOpen, Path: obj.path}, func() error {
+		return obj.openFlags(flags)
+	}); er != nil {
+		return er
+	}
+
+	return obj.lseek(offset)
+}
+
+// retryRead runs the read fn, and runs it again once if it failed because the server dropped the connection and the
+// handle could be re-opened
+func (obj *DataObj) retryRead(fn func() error) error {
+	err := fn()
+	if err == nil || !obj.reopens() || int(obj.chandle) < 0 {
+		return err
+	}
+
+	// The idle check pings suspect handles, and replaces them if they're dead
+	hcon := obj.handleCon()
+	atomic.StoreInt32(&hcon.suspect, 1)
+	hcon.refresh()
+
+	if !obj.stale() {
+		return err
+	}
+
+	if er := obj.revive(); er != nil {
+		return err
+	}
+
+	return fn()
+}
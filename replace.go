@@ -0,0 +1,170 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// tempName returns the hidden name a data object called name is uploaded to before it replaces it, like
+// ".a.txt.gorods-1f2e3d4c"
+func tempName(name string) (string, error) {
+	b := make([]byte, 4)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", newError(Fatal, -1, fmt.Sprintf("iRODS Put DataObject Failed: %v, %v", name, err))
+	}
+
+	return "." + name + ".gorods-" + hex.EncodeToString(b), nil
+}
+
+// replaceDataObj creates the data object called name in the collection by uploading it to a temporary name first,
+// and renaming it once upload succeeded: readers never see partial content, and a failed upload leaves the data
+// object it would have replaced untouched. upload writes the content to the temporary name it's passed, and returns
+// the data object it created. An existing data object is an error unless force is set, it's then replaced with
+// swapInto: its AVUs and ACLs aren't carried over.
+func (col *Collection) replaceDataObj(name string, force bool, upload func(tmpName string) (*DataObj, error)) (*DataObj, error) {
+	con := col.con
+	p := col.path + "/" + name
+
+	exists := false
+
+	if typ, err := con.PathType(p); err == nil {
+		if typ != DataObjType {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Put DataObject Failed: %v is a collection", p))
+		} else if !force {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Put DataObject Failed: %v already exists", p))
+		}
+
+		exists = true
+	} else if !IsNotFound(err) {
+		return nil, err
+	}
+
+	tmp, err := tempName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := upload(tmp)
+	if err != nil {
+		con.removeTemp(col.path + "/" + tmp)
+		return nil, err
+	}
+
+	if err := col.swapInto(obj, name, exists); err != nil {
+		con.removeTemp(obj.path)
+		return nil, err
+	}
+
+	if err := col.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// PutReader uploads the content read from r to the data object opts.Name of the collection. The content is written
+// to a temporary name and only replaces an existing data object (which requires opts.Force) once r is read to its
+// end, so a failed upload leaves it untouched. Like DataObj.WriteBytes, it can't be used on connections with content
+// transforms or scanners.
+func (col *Collection) PutReader(r io.Reader, opts DataObjOptions) (*DataObj, error) {
+	if opts.Name == "" {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Put DataObject Failed: %v, a name is required", col.path))
+	}
+
+	if err := col.con.checkIncrementalWrite(col.path + "/" + opts.Name); err != nil {
+		return nil, err
+	}
+
+	return col.replaceDataObj(opts.Name, opts.Force, func(tmpName string) (*DataObj, error) {
+		tmpOpts := opts
+		tmpOpts.Name = tmpName
+		tmpOpts.Force = false
+
+		obj, err := createDataObj(tmpOpts, col)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := bufio.NewWriterSize(&dataObjWriter{obj: obj}, ArchiveChunkSize)
+
+		_, er := io.Copy(buf, r)
+		if er == nil {
+			er = buf.Flush()
+		}
+
+		if er != nil {
+			obj.Close()
+
+			if _, ok := er.(*GoRodsError); ok {
+				return obj, er
+			}
+
+			return obj, newError(Fatal, -1, fmt.Sprintf("iRODS Put DataObject Failed: %v, %v", obj.path, er))
+		}
+
+		if err := obj.Close(); err != nil {
+			return obj, err
+		}
+
+		return obj, nil
+	})
+}
+
+// swapInto renames obj, a data object of the collection, to name. iRODS can't rename over an existing data object, so
+// if exists is set, the data object called name is first renamed to a temporary name, renamed back if obj's rename
+// fails, and deleted once it succeeded: the data object at name is missing for the time of a rename, but never lost.
+func (col *Collection) swapInto(obj *DataObj, name string, exists bool) error {
+	p := col.path + "/" + name
+
+	var old *DataObj
+
+	if exists {
+		var err error
+
+		if old, err = col.con.DataObject(p); err != nil {
+			return err
+		}
+
+		aside, err := tempName(name)
+		if err != nil {
+			return err
+		}
+
+		if err := old.Rename(aside); err != nil {
+			return err
+		}
+	}
+
+	if err := obj.Rename(name); err != nil {
+		if old != nil {
+			if er := old.Rename(name); er != nil {
+				return newError(Fatal, -1, fmt.Sprintf("iRODS Put DataObject Failed: %v, %v, and the data object it replaces was left at %v: %v", p, err, old.path, er))
+			}
+		}
+
+		return err
+	}
+
+	if old != nil {
+		col.con.removeTemp(old.path)
+	}
+
+	return nil
+}
+
+// removeTemp deletes the temporary data object at p left by a failed upload, logging failures
+func (con *Connection) removeTemp(p string) {
+	if err := con.rmPath(p, false, true); err != nil && !IsNotFound(err) {
+		con.log(LogWarn, "unable to remove a partial upload", "path", p, "error", err)
+	}
+}
@@ -0,0 +1,105 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTempName(t *testing.T) {
+	a, err := tempName("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := tempName("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(a, ".a.txt.gorods-") || len(a) != len(".a.txt.gorods-")+8 || a == b {
+		t.Errorf("Unexpected temporary names %q and %q", a, b)
+	}
+}
+
+type failAfter struct {
+	r io.Reader
+}
+
+func (f *failAfter) Read(p []byte) (int, error) {
+	if n, err := f.r.Read(p); err != io.EOF {
+		return n, err
+	}
+
+	return 0, errors.New("connection reset")
+}
+
+func TestPutReaderKeepsDataObjOnFailure(t *testing.T) {
+	client, conErr := New(ConnectionOptions{
+		Type: UserDefined,
+
+		Host: "localhost",
+		Port: 1247,
+		Zone: "tempZone",
+
+		Username: "rods",
+		Password: "password",
+	})
+
+	if conErr != nil {
+		t.Fatal(conErr)
+	}
+
+	if openErr := client.OpenCollection(CollectionOptions{
+		Path: "/tempZone/home/rods",
+	}, func(col *Collection, con *Connection) {
+		obj, err := col.PutReader(strings.NewReader("original"), DataObjOptions{Name: "putreader.txt", Force: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer obj.Delete(false)
+
+		if _, err := col.PutReader(strings.NewReader("new"), DataObjOptions{Name: "putreader.txt"}); err == nil {
+			t.Error("Expected an existing data object to require Force")
+		}
+
+		if _, err := col.PutReader(&failAfter{strings.NewReader("partial")}, DataObjOptions{Name: "putreader.txt", Force: true}); err == nil {
+			t.Error("Expected the failed read to fail the upload")
+		}
+
+		if content := readDataObj(t, con, obj.Path()); content != "original" {
+			t.Errorf("Expected a failed upload to keep the data object, got %q", content)
+		}
+
+		if _, err := col.PutReader(strings.NewReader("replaced"), DataObjOptions{Name: "putreader.txt", Force: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		if content := readDataObj(t, con, obj.Path()); content != "replaced" {
+			t.Errorf("Expected the data object to be replaced, got %q", content)
+		}
+	}); openErr != nil {
+		t.Fatal(openErr)
+	}
+}
+
+func readDataObj(t *testing.T, con *Connection, p string) string {
+	obj, err := con.DataObject(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := obj.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, The BioTeam, Inc.                     ***
  *** For more information please refer to the LICENSE.md file  ***/
 
@@ -365,3 +368,38 @@ func (resc *Resource) FetchInfo() (map[string]string, error) {
 
 	return response, nil
 }
+
+// DefaultResource returns the resource used when none is passed to Put, CreateDataObj or CopyTo:
+// ConnectionOptions.DefaultResource, the policy's default_resource, or irods_default_resource from the iRODS
+// environment, in that order. An empty string lets the server choose (usually the zone's default resource).
+func (con *Connection) DefaultResource() string {
+	if con.Options != nil && con.Options.DefaultResource != "" {
+		return con.Options.DefaultResource
+	}
+
+	if r, ok := defaultResource(nil).(string); ok {
+		return r
+	}
+
+	con.envRescOnce.Do(func() {
+		var cResc *C.char
+
+		C.gorods_get_default_resource(&cResc)
+		defer C.free(unsafe.Pointer(cResc))
+
+		con.envResc = C.GoString(cResc)
+	})
+
+	return con.envResc
+}
+
+// targetResource resolves a resource parameter (string, *Resource or nil) to the resource name or hierarchy to
+// pass to the server, using the connection's default resource when it isn't set
+func (con *Connection) targetResource(resource interface{}) (string, error) {
+	name, err := resourceName(resource)
+	if err != nil || name != "" {
+		return name, err
+	}
+
+	return con.DefaultResource(), nil
+}
@@ -0,0 +1,50 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestTargetResource(t *testing.T) {
+	con := &Connection{Options: &ConnectionOptions{}}
+
+	// Skip reading the iRODS environment
+	con.envRescOnce.Do(func() {
+		con.envResc = "envResc"
+	})
+
+	if r, err := con.targetResource(nil); err != nil || r != "envResc" {
+		t.Errorf("Expected the environment's default resource, got %q, %v", r, err)
+	}
+
+	SetPolicy(&Policy{DefaultResource: "policyResc"})
+	defer SetPolicy(nil)
+
+	if r, err := con.targetResource(nil); err != nil || r != "policyResc" {
+		t.Errorf("Expected the policy's default resource, got %q, %v", r, err)
+	}
+
+	con.Options.DefaultResource = "archiveResc"
+
+	if r, err := con.targetResource(nil); err != nil || r != "archiveResc" {
+		t.Errorf("Expected the connection's default resource, got %q, %v", r, err)
+	}
+
+	// Explicit resources and hierarchies win over every default
+	if r, err := con.targetResource("root;child;leaf"); err != nil || r != "root;child;leaf" {
+		t.Errorf("Expected the hierarchy to be passed as is, got %q, %v", r, err)
+	}
+
+	if r, err := con.targetResource(&Resource{name: "demoResc"}); err != nil || r != "demoResc" {
+		t.Errorf("Expected the *Resource's name, got %q, %v", r, err)
+	}
+
+	if _, err := con.targetResource(42); err == nil {
+		t.Error("Expected an error for an invalid resource type")
+	}
+}
@@ -0,0 +1,34 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package rest
+
+import (
+	"github.com/jjacquay712/GoRODS"
+	"github.com/jjacquay712/GoRODS/internal/userpool"
+)
+
+// userPool is the gorods.Pool of a user, so concurrent requests of one user get connections of their own
+type userPool struct {
+	*gorods.Pool
+}
+
+// newPools returns the pools of the authenticated users. Pools idle for longer than opts.IdleTimeout are closed.
+func newPools(opts Options) *userpool.Pool {
+	return userpool.New(func(username string, password string) (userpool.Handle, error) {
+		cli, err := gorods.New(gorods.ConnectionOptions{
+			Type:     gorods.UserDefined,
+			Host:     opts.Server.Host,
+			Port:     opts.Server.Port,
+			Zone:     opts.Server.Zone,
+			FastInit: true,
+			Username: username,
+			Password: password,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return userPool{cli.NewPool(gorods.PoolOptions{Size: opts.PoolSize})}, nil
+	}, opts.IdleTimeout)
+}
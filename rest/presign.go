@@ -0,0 +1,86 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jjacquay712/GoRODS"
+)
+
+// servePresigned serves a download or upload authorized by the signed reference encoded in s, instead of basic
+// auth. The request runs on a connection of its own, as the anonymous user with the reference's ticket.
+func (h *RESTHandler) servePresigned(w http.ResponseWriter, r *http.Request, s string) {
+	ref, err := gorods.ParseSignedRef(s)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	ticket, err := h.opts.Signer.Ticket(ref)
+	if err != nil {
+		writeError(w, err, http.StatusForbidden)
+		return
+	}
+
+	resource, p := route(strings.TrimPrefix(r.URL.Path, h.opts.StripPrefix))
+
+	if resource != "objects" || p != ref.Path {
+		writeError(w, fmt.Errorf("the reference is for %v", ref.Path), http.StatusForbidden)
+		return
+	}
+
+	var handle func(*restRequest)
+
+	switch {
+	case !ref.Write && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		handle = (*restRequest).download
+	case ref.Write && r.Method == http.MethodPut:
+		handle = (*restRequest).overwrite
+	default:
+		writeError(w, fmt.Errorf("%v isn't allowed by the reference", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	con, err := gorods.NewConnection(&gorods.ConnectionOptions{
+		Type:     gorods.UserDefined,
+		AuthType: gorods.AnonymousAuth,
+		Host:     h.opts.Server.Host,
+		Port:     h.opts.Server.Port,
+		Zone:     h.opts.Server.Zone,
+		Username: h.opts.AnonymousUser,
+		Ticket:   ticket,
+		FastInit: true,
+	})
+	if err != nil {
+		gorods.Log(gorods.LogError, "unable to connect with the ticket", "host", h.opts.Server.Host, "error", err)
+		writeError(w, fmt.Errorf("unable to connect"), http.StatusBadGateway)
+		return
+	}
+	defer con.Disconnect()
+
+	handle(&restRequest{con: con, p: p, w: w, r: r})
+}
+
+// overwrite replaces the content of the data object at the request's path with the request body. The tickets of
+// write references only grant access to the data object itself, which Connection.Presign creates if needed, so it's
+// written in place.
+func (req *restRequest) overwrite() {
+	obj, err := req.con.DataObject(req.p)
+	if err != nil {
+		writeError(req.w, err, http.StatusNotFound)
+		return
+	}
+
+	if err := obj.OpenFlags(gorods.O_WRONLY | gorods.O_TRUNC); err != nil {
+		writeError(req.w, err, http.StatusForbidden)
+		return
+	}
+
+	if req.writeBody(obj) {
+		writeJSON(req.w, http.StatusOK, nil)
+	}
+}
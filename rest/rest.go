@@ -0,0 +1,633 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+// Package rest provides an http.Handler that exposes iRODS collections, data objects, metadata and ACLs as a JSON
+// REST API, so web applications can use iRODS directly. Each request is authenticated with HTTP basic auth and runs
+// on a pooled iRODS connection of that user. Routes, relative to Options.StripPrefix:
+//
+//	GET    /collections/{path}             list the collection
+//	POST   /collections/{path}             create the collection
+//	DELETE /collections/{path}?force=true  remove the collection and its contents
+//	GET    /objects/{path}                 download the data object, Range requests are supported
+//	PUT    /objects/{path}                 upload the data object, the body can be sent chunked
+//	DELETE /objects/{path}?force=true      remove the data object
+//	GET    /metadata/{path}                list the AVUs of the collection or data object
+//	POST   /metadata/{path}                add the AVU in the body, {"attribute": "a", "value": "v", "units": "u"}
+//	DELETE /metadata/{path}?attribute=a    remove the AVUs of attribute a (only those with value v if &value=v)
+//	GET    /acls/{path}                    list the ACLs of the collection or data object
+//	PUT    /acls/{path}                    set an ACL, {"name": "alice#tempZone", "access": "read", "recursive": false}
+//
+// Errors are returned as {"error": "message"} with a matching status code.
+//
+// If Options.Signer is set, object downloads and uploads may be authorized by a reference signed with it instead,
+// passed in the "ref" query parameter: share links minted with gorods.Connection.Presign and SignedRef.ObjectURL,
+// which run as the anonymous user with the reference's ticket.
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jjacquay712/GoRODS"
+	"github.com/jjacquay712/GoRODS/internal/userpool"
+)
+
+// ConnectionTemplate holds the iCAT server information used to open the connections of each user
+type ConnectionTemplate struct {
+	Host string
+	Port int
+	Zone string
+}
+
+// Options are used when creating a handler with Handler(). StripPrefix is removed from request paths before routing.
+// IdleTimeout is how long a user's connections are kept open between requests (defaults to 5 minutes).
+// PoolSize is the number of connections opened per user (defaults to 4).
+// Signer verifies presigned references, AnonymousUser is the account they run as (gorods.AnonymousUser if empty).
+type Options struct {
+	Server        ConnectionTemplate
+	StripPrefix   string
+	Realm         string
+	IdleTimeout   time.Duration
+	PoolSize      int
+	Signer        *gorods.Signer
+	AnonymousUser string
+}
+
+// RESTHandler serves REST requests. Use Handler() to create one.
+type RESTHandler struct {
+	opts  Options
+	pools *userpool.Pool
+}
+
+// Entry is a collection or data object in a listing
+type Entry struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Type       string    `json:"type"`
+	Size       int64     `json:"size"`
+	Checksum   string    `json:"checksum,omitempty"`
+	ModifyTime time.Time `json:"modify_time"`
+}
+
+// AVU is a metadata triple
+type AVU struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+	Units     string `json:"units,omitempty"`
+}
+
+// ACL is an access control entry. Name is "user#zone" or "group", Access is null, read, write or own.
+type ACL struct {
+	Name      string `json:"name"`
+	Type      string `json:"type,omitempty"`
+	Access    string `json:"access"`
+	Recursive bool   `json:"recursive,omitempty"`
+}
+
+// accessLevels maps access names to their constants
+var accessLevels = map[string]int{
+	"null":  gorods.Null,
+	"read":  gorods.Read,
+	"write": gorods.Write,
+	"own":   gorods.Own,
+}
+
+// Handler returns a new *RESTHandler using the options specified
+func Handler(opts Options) *RESTHandler {
+	if opts.Realm == "" {
+		opts.Realm = "iRODS"
+	}
+
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = 5 * time.Minute
+	}
+
+	return &RESTHandler{opts: opts, pools: newPools(opts)}
+}
+
+// Close disconnects all pooled iRODS connections
+func (h *RESTHandler) Close() {
+	h.pools.Close()
+}
+
+// restRequest holds the state of a single request
+type restRequest struct {
+	con *gorods.Connection
+	p   string
+	w   http.ResponseWriter
+	r   *http.Request
+}
+
+func (h *RESTHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ref := r.URL.Query().Get(gorods.PresignParam); ref != "" && h.opts.Signer != nil {
+		h.servePresigned(w, r, ref)
+		return
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok || username == "" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", h.opts.Realm))
+		writeError(w, fmt.Errorf("authentication required"), http.StatusUnauthorized)
+		return
+	}
+
+	resource, p := route(strings.TrimPrefix(r.URL.Path, h.opts.StripPrefix))
+
+	var handle func(*restRequest)
+
+	switch resource + " " + r.Method {
+	case "collections GET":
+		handle = (*restRequest).listCollection
+	case "collections POST":
+		handle = (*restRequest).createCollection
+	case "collections DELETE", "objects DELETE":
+		handle = (*restRequest).remove
+	case "objects GET", "objects HEAD":
+		handle = (*restRequest).download
+	case "objects PUT":
+		handle = (*restRequest).upload
+	case "metadata GET":
+		handle = (*restRequest).listMeta
+	case "metadata POST":
+		handle = (*restRequest).addMeta
+	case "metadata DELETE":
+		handle = (*restRequest).removeMeta
+	case "acls GET":
+		handle = (*restRequest).listACLs
+	case "acls PUT":
+		handle = (*restRequest).setACL
+	default:
+		writeError(w, fmt.Errorf("no route for %v %v", r.Method, r.URL.Path), http.StatusNotFound)
+		return
+	}
+
+	up, release, err := h.pools.Get(username, password)
+	if err != nil {
+		gorods.Log(gorods.LogWarn, "REST authentication failed", "user", username, "error", err)
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", h.opts.Realm))
+		writeError(w, fmt.Errorf("authentication failed"), http.StatusUnauthorized)
+		return
+	}
+	defer release()
+
+	// Transfers are batch work, so they can't hold up listings and metadata requests
+	class := gorods.QoSInteractive
+	if resource == "objects" && r.Method != "DELETE" {
+		class = gorods.QoSBatch
+	}
+
+	if err := up.(userPool).Do(class, func(con *gorods.Connection) error {
+		handle(&restRequest{con: con, p: p, w: w, r: r})
+		return nil
+	}); err != nil {
+		writeError(w, err, http.StatusServiceUnavailable)
+	}
+}
+
+// route splits a request path into the resource type and the iRODS path, like "objects" and "/tempZone/a.txt"
+func route(urlPath string) (string, string) {
+	urlPath = strings.TrimPrefix(urlPath, "/")
+
+	i := strings.IndexByte(urlPath, '/')
+	if i < 0 {
+		return urlPath, "/"
+	}
+
+	if p := strings.TrimRight(path.Clean(urlPath[i:]), "/"); p != "" {
+		return urlPath[:i], p
+	}
+
+	return urlPath[:i], "/"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if v != nil {
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			gorods.Log(gorods.LogError, "unable to write the REST response", "error", err)
+		}
+	}
+}
+
+func writeError(w http.ResponseWriter, err error, status int) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// stat returns the system metadata of the request's path, writing a 404 if it doesn't exist
+func (req *restRequest) stat() (*gorods.ObjStat, bool) {
+	stat, err := req.con.ObjStat(req.p)
+	if err != nil {
+		writeError(req.w, err, http.StatusNotFound)
+		return nil, false
+	}
+
+	return stat, true
+}
+
+// open returns the data object or collection at the request's path, writing a 404 if it doesn't exist
+func (req *restRequest) open() (gorods.IRodsObj, bool) {
+	stat, ok := req.stat()
+	if !ok {
+		return nil, false
+	}
+
+	var (
+		obj gorods.IRodsObj
+		err error
+	)
+
+	if stat.Type == gorods.CollectionType {
+		obj, err = req.con.Collection(gorods.CollectionOptions{Path: req.p, SkipCache: true})
+	} else {
+		obj, err = req.con.DataObject(req.p)
+	}
+
+	if err != nil {
+		writeError(req.w, err, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return obj, true
+}
+
+// parent returns the collection containing the request's path
+func (req *restRequest) parent() (*gorods.Collection, bool) {
+	col, err := req.con.Collection(gorods.CollectionOptions{Path: path.Dir(req.p), SkipCache: true})
+	if err != nil {
+		writeError(req.w, err, http.StatusConflict)
+		return nil, false
+	}
+
+	return col, true
+}
+
+func (req *restRequest) listCollection() {
+	stat, ok := req.stat()
+	if !ok {
+		return
+	} else if stat.Type != gorods.CollectionType {
+		writeError(req.w, fmt.Errorf("%v is not a collection", req.p), http.StatusBadRequest)
+		return
+	}
+
+	list, err := req.con.List(req.p)
+	if err != nil {
+		writeError(req.w, err, http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]Entry, len(list))
+
+	for i, e := range list {
+		entries[i] = Entry{
+			Name:       e.Name,
+			Path:       e.Path,
+			Type:       "dataobj",
+			Size:       e.Size,
+			Checksum:   e.Checksum,
+			ModifyTime: e.ModifyTime,
+		}
+
+		if e.Type == gorods.CollectionType {
+			entries[i].Type = "collection"
+		}
+	}
+
+	writeJSON(req.w, http.StatusOK, entries)
+}
+
+func (req *restRequest) createCollection() {
+	if _, err := req.con.ObjStat(req.p); err == nil {
+		writeError(req.w, fmt.Errorf("%v already exists", req.p), http.StatusConflict)
+		return
+	}
+
+	col, ok := req.parent()
+	if !ok {
+		return
+	}
+
+	if _, err := col.CreateSubCollection(path.Base(req.p)); err != nil {
+		writeError(req.w, err, http.StatusForbidden)
+		return
+	}
+
+	writeJSON(req.w, http.StatusCreated, nil)
+}
+
+func (req *restRequest) remove() {
+	obj, ok := req.open()
+	if !ok {
+		return
+	}
+
+	force := req.r.URL.Query().Get("force") == "true"
+
+	var err error
+
+	switch o := obj.(type) {
+	case *gorods.Collection:
+		err = o.Rm(true, force)
+	case *gorods.DataObj:
+		err = o.Rm(false, force)
+	}
+
+	if err != nil {
+		writeError(req.w, err, http.StatusForbidden)
+		return
+	}
+
+	writeJSON(req.w, http.StatusNoContent, nil)
+}
+
+func (req *restRequest) download() {
+	stat, ok := req.stat()
+	if !ok {
+		return
+	} else if stat.Type != gorods.DataObjType {
+		writeError(req.w, fmt.Errorf("%v is a collection", req.p), http.StatusBadRequest)
+		return
+	}
+
+	obj, err := req.con.DataObject(req.p)
+	if err != nil {
+		writeError(req.w, err, http.StatusInternalServerError)
+		return
+	}
+	defer obj.Close()
+
+	if t := mime.TypeByExtension(filepath.Ext(req.p)); t != "" {
+		req.w.Header().Set("Content-Type", t)
+	} else {
+		req.w.Header().Set("Content-Type", "application/octet-stream")
+	}
+
+	if stat.Checksum != "" {
+		req.w.Header().Set("ETag", fmt.Sprintf("%q", stat.Checksum))
+	}
+
+	// ServeContent handles Range, HEAD and conditional requests with positioned reads
+	http.ServeContent(req.w, req.r, path.Base(req.p), stat.ModifyTime, io.NewSectionReader(obj, 0, stat.Size))
+}
+
+func (req *restRequest) upload() {
+	status := http.StatusCreated
+	if stat, err := req.con.ObjStat(req.p); err == nil {
+		if stat.Type == gorods.CollectionType {
+			writeError(req.w, fmt.Errorf("%v is a collection", req.p), http.StatusConflict)
+			return
+		}
+		status = http.StatusOK
+	}
+
+	col, ok := req.parent()
+	if !ok {
+		return
+	}
+
+	size := req.r.ContentLength
+	if size < 0 {
+		size = 0
+	}
+
+	// The body is uploaded to a temporary name first, an existing data object is only replaced once it's complete
+	body := &bodyReader{r: req.r.Body}
+
+	if _, err := col.PutReader(body, gorods.DataObjOptions{
+		Name:  path.Base(req.p),
+		Size:  size,
+		Mode:  0750,
+		Force: true,
+	}); err != nil {
+		if body.err != nil {
+			writeError(req.w, body.err, http.StatusBadRequest)
+		} else {
+			writeError(req.w, err, http.StatusForbidden)
+		}
+		return
+	}
+
+	writeJSON(req.w, status, nil)
+}
+
+// bodyReader records the error reading a request body, to tell it apart from upload failures
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+
+	return n, err
+}
+
+// writeBody writes the request body to obj, opened for writing, and closes it. It returns false after writing an
+// error response if that fails.
+func (req *restRequest) writeBody(obj *gorods.DataObj) bool {
+	buf := make([]byte, 1024000)
+
+	for {
+		n, rErr := req.r.Body.Read(buf)
+
+		if n > 0 {
+			if wErr := obj.WriteBytes(buf[:n]); wErr != nil {
+				obj.Close()
+				writeError(req.w, wErr, http.StatusInternalServerError)
+				return false
+			}
+		}
+
+		if rErr == io.EOF {
+			break
+		} else if rErr != nil {
+			obj.Close()
+			writeError(req.w, rErr, http.StatusBadRequest)
+			return false
+		}
+	}
+
+	if err := obj.Close(); err != nil {
+		writeError(req.w, err, http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
+
+// metaObj returns the collection or data object at the request's path, for metadata and ACL requests
+func (req *restRequest) metaObj() (gorods.MetaObj, bool) {
+	obj, ok := req.open()
+	if !ok {
+		return nil, false
+	}
+
+	return obj.(gorods.MetaObj), true
+}
+
+func (req *restRequest) listMeta() {
+	obj, ok := req.metaObj()
+	if !ok {
+		return
+	}
+
+	mc, err := obj.Meta()
+	if err != nil {
+		writeError(req.w, err, http.StatusInternalServerError)
+		return
+	}
+
+	metas, err := mc.All()
+	if err != nil {
+		writeError(req.w, err, http.StatusInternalServerError)
+		return
+	}
+
+	avus := make([]AVU, len(metas))
+	for i, m := range metas {
+		avus[i] = AVU{m.Attribute, m.Value, m.Units}
+	}
+
+	writeJSON(req.w, http.StatusOK, avus)
+}
+
+func (req *restRequest) addMeta() {
+	var avu AVU
+
+	if err := json.NewDecoder(req.r.Body).Decode(&avu); err != nil || avu.Attribute == "" || avu.Value == "" {
+		writeError(req.w, fmt.Errorf("expected an AVU with an attribute and a value"), http.StatusBadRequest)
+		return
+	}
+
+	obj, ok := req.metaObj()
+	if !ok {
+		return
+	}
+
+	if _, err := obj.AddMeta(gorods.Meta{Attribute: avu.Attribute, Value: avu.Value, Units: avu.Units}); err != nil {
+		writeError(req.w, err, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(req.w, http.StatusCreated, nil)
+}
+
+func (req *restRequest) removeMeta() {
+	attr, value := req.r.URL.Query().Get("attribute"), req.r.URL.Query().Get("value")
+	if attr == "" {
+		writeError(req.w, fmt.Errorf("the attribute parameter is required"), http.StatusBadRequest)
+		return
+	}
+
+	obj, ok := req.metaObj()
+	if !ok {
+		return
+	}
+
+	metas, err := obj.Attribute(attr)
+	if err != nil {
+		writeError(req.w, err, http.StatusNotFound)
+		return
+	}
+
+	for _, m := range metas {
+		if value != "" && m.Value != value {
+			continue
+		}
+
+		if _, err := m.Delete(); err != nil {
+			writeError(req.w, err, http.StatusForbidden)
+			return
+		}
+	}
+
+	writeJSON(req.w, http.StatusNoContent, nil)
+}
+
+func (req *restRequest) listACLs() {
+	obj, ok := req.open()
+	if !ok {
+		return
+	}
+
+	var (
+		acls gorods.ACLs
+		err  error
+	)
+
+	switch o := obj.(type) {
+	case *gorods.Collection:
+		acls, err = o.ACL()
+	case *gorods.DataObj:
+		acls, err = o.ACL()
+	}
+
+	if err != nil {
+		writeError(req.w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]ACL, len(acls))
+
+	for i, acl := range acls {
+		response[i] = ACL{
+			Name:   acl.Principal.String(),
+			Type:   "user",
+			Access: acl.AccessLevelString(),
+		}
+
+		if acl.Type == gorods.GroupType {
+			response[i].Type = "group"
+		}
+	}
+
+	writeJSON(req.w, http.StatusOK, response)
+}
+
+func (req *restRequest) setACL() {
+	var acl ACL
+
+	if err := json.NewDecoder(req.r.Body).Decode(&acl); err != nil || acl.Name == "" {
+		writeError(req.w, fmt.Errorf("expected an ACL with a name and an access level"), http.StatusBadRequest)
+		return
+	}
+
+	level, ok := accessLevels[acl.Access]
+	if !ok {
+		writeError(req.w, fmt.Errorf("unknown access level %q", acl.Access), http.StatusBadRequest)
+		return
+	}
+
+	obj, ok := req.open()
+	if !ok {
+		return
+	}
+
+	var err error
+
+	switch o := obj.(type) {
+	case *gorods.Collection:
+		err = o.Chmod(acl.Name, level, acl.Recursive)
+	case *gorods.DataObj:
+		err = o.Chmod(acl.Name, level, false)
+	}
+
+	if err != nil {
+		writeError(req.w, err, http.StatusForbidden)
+		return
+	}
+
+	writeJSON(req.w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,93 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package rest
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRoute(t *testing.T) {
+	for urlPath, want := range map[string][2]string{
+		"/objects/tempZone/home/alice/a.txt": {"objects", "/tempZone/home/alice/a.txt"},
+		"/collections/tempZone/home/alice/":  {"collections", "/tempZone/home/alice"},
+		"/collections/tempZone/../etc":       {"collections", "/etc"},
+		"/collections":                       {"collections", "/"},
+		"/metadata/":                         {"metadata", "/"},
+	} {
+		if resource, p := route(urlPath); resource != want[0] || p != want[1] {
+			t.Errorf("route(%q): expected %v %v, got %v %v", urlPath, want[0], want[1], resource, p)
+		}
+	}
+}
+
+func TestServeHTTPRequiresAuth(t *testing.T) {
+	h := Handler(Options{Realm: "Lab"})
+	defer h.Close()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/collections/tempZone/home", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %v", w.Code)
+	}
+
+	if a := w.Header().Get("WWW-Authenticate"); a != `Basic realm="Lab"` {
+		t.Errorf("Unexpected WWW-Authenticate header %q", a)
+	}
+}
+
+func TestServeHTTPUnknownRoute(t *testing.T) {
+	h := Handler(Options{})
+	defer h.Close()
+
+	for _, c := range []struct{ method, path string }{
+		{"GET", "/files/tempZone/home"},
+		{"PATCH", "/objects/tempZone/home/a.txt"},
+		{"PUT", "/collections/tempZone/home"},
+	} {
+		r := httptest.NewRequest(c.method, c.path, nil)
+		r.SetBasicAuth("alice", "secret")
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%v %v: expected status 404, got %v", c.method, c.path, w.Code)
+		}
+	}
+
+	if h.pools.Len() != 0 {
+		t.Error("Expected unrouted requests not to authenticate")
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestBodyReader(t *testing.T) {
+	body := &bodyReader{r: strings.NewReader("content")}
+
+	if b, err := ioutil.ReadAll(body); err != nil || string(b) != "content" {
+		t.Fatalf("Unexpected read %q, %v", b, err)
+	}
+
+	if body.err != nil {
+		t.Errorf("Expected no error at the end of the body, got %v", body.err)
+	}
+
+	body = &bodyReader{r: io.MultiReader(strings.NewReader("partial"), failingReader{})}
+
+	if _, err := ioutil.ReadAll(body); err == nil || body.err != err {
+		t.Errorf("Expected the read error to be recorded, got %v and %v", err, body.err)
+	}
+}
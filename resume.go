@@ -0,0 +1,540 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ResumeAttrPrefix is prepended to the attribute names of the AVUs written by MetaResumeStore
+const ResumeAttrPrefix = "gorods:resume:"
+
+// UploadSession is the state of a resumable upload started with Collection.PutResumable.
+// Partial is the path of the intermediate data object, which is renamed to Target once every chunk has been written.
+// ModTime (in Unix nanoseconds) and SHA256 identify the content of the local file, a session is only resumed for a
+// file with the same size, modification time and checksum.
+type UploadSession struct {
+	ID        string
+	Target    string
+	Partial   string
+	Size      int64
+	ModTime   int64
+	SHA256    string
+	ChunkSize int64
+	Written   int64
+	Chunks    []bool
+}
+
+// Complete returns true if every chunk has been written
+func (s *UploadSession) Complete() bool {
+	for _, done := range s.Chunks {
+		if !done {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ChunkMap returns the written chunks as a list of ranges, like "0-15,18,20-31"
+func (s *UploadSession) ChunkMap() string {
+	ranges := make([]string, 0)
+
+	for i := 0; i < len(s.Chunks); i++ {
+		if !s.Chunks[i] {
+			continue
+		}
+
+		j := i
+		for j+1 < len(s.Chunks) && s.Chunks[j+1] {
+			j++
+		}
+
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(i))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%v-%v", i, j))
+		}
+
+		i = j
+	}
+
+	return strings.Join(ranges, ",")
+}
+
+// setChunkMap parses a list of ranges returned by ChunkMap
+func (s *UploadSession) setChunkMap(m string) error {
+	if m == "-" {
+		return nil
+	}
+
+	for _, r := range strings.Split(m, ",") {
+		if r == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(r, "-", 2)
+
+		from, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return err
+		}
+
+		to := from
+
+		if len(bounds) == 2 {
+			if to, err = strconv.Atoi(bounds[1]); err != nil {
+				return err
+			}
+		}
+
+		if from < 0 || to >= len(s.Chunks) || from > to {
+			return fmt.Errorf("chunk range %v out of bounds", r)
+		}
+
+		for i := from; i <= to; i++ {
+			s.Chunks[i] = true
+		}
+	}
+
+	return nil
+}
+
+func newUploadSession(target string, partial string, size int64, chunkSize int64) (*UploadSession, error) {
+	s := new(UploadSession)
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS PutResumable Failed: %v", err))
+	}
+
+	s.ID = hex.EncodeToString(id)
+	s.Target = target
+	s.Partial = partial
+	s.Size = size
+	s.ChunkSize = chunkSize
+	s.Chunks = make([]bool, int((size+chunkSize-1)/chunkSize))
+
+	return s, nil
+}
+
+// matches returns true if the session is the upload of a file of size bytes, last modified at modTime, with the
+// SHA-256 checksum sum
+func (s *UploadSession) matches(size int64, modTime int64, sum string) bool {
+	return s.Size == size && s.ModTime == modTime && s.SHA256 == sum
+}
+
+// fileSHA256 returns the hex encoded SHA-256 checksum of the content of f, computed with the registered
+// ChecksumSHA256 provider
+func fileSHA256(f *os.File) (string, error) {
+	h, err := Checksummer(ChecksumSHA256)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(h, io.NewSectionReader(f, 0, 1<<62)); err != nil {
+		return "", newError(Fatal, -1, fmt.Sprintf("iRODS PutResumable Failed: %v", err))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ResumeStore persists UploadSession state between attempts of Collection.PutResumable.
+// Load returns nil (and no error) if there is no session for the partial object.
+type ResumeStore interface {
+	Load(con *Connection, partial string) (*UploadSession, error)
+	Save(con *Connection, s *UploadSession) error
+	Clear(con *Connection, s *UploadSession) error
+}
+
+// FileResumeStore keeps session state in local JSON files within Dir (os.TempDir() if empty).
+// Uploads can only be resumed from the same host.
+type FileResumeStore struct {
+	Dir string
+}
+
+func (fs FileResumeStore) file(partial string) string {
+	dir := fs.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	sum := sha256.Sum256([]byte(partial))
+
+	return filepath.Join(dir, "gorods-resume-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// Load implements ResumeStore
+func (fs FileResumeStore) Load(con *Connection, partial string) (*UploadSession, error) {
+	f, err := os.Open(fs.file(partial))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Resume Failed: %v", err))
+	}
+	defer f.Close()
+
+	s := new(UploadSession)
+
+	if err := json.NewDecoder(f).Decode(s); err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Resume Failed: %v", err))
+	}
+
+	return s, nil
+}
+
+// Save implements ResumeStore
+func (fs FileResumeStore) Save(con *Connection, s *UploadSession) error {
+	name := fs.file(s.Partial)
+
+	f, err := os.Create(name + ".tmp")
+	if err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Resume Save Failed: %v", err))
+	}
+
+	if err := json.NewEncoder(f).Encode(s); err != nil {
+		f.Close()
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Resume Save Failed: %v", err))
+	}
+
+	f.Close()
+
+	if err := os.Rename(name+".tmp", name); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Resume Save Failed: %v", err))
+	}
+
+	return nil
+}
+
+// Clear implements ResumeStore
+func (fs FileResumeStore) Clear(con *Connection, s *UploadSession) error {
+	if err := os.Remove(fs.file(s.Partial)); err != nil && !os.IsNotExist(err) {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Resume Clear Failed: %v", err))
+	}
+
+	return nil
+}
+
+// MetaResumeStore keeps session state as AVUs (prefixed with ResumeAttrPrefix) on the partial data object,
+// so an interrupted upload can be resumed by any client host that has a copy of the source file with the same
+// modification time (like one copied with rsync -t).
+type MetaResumeStore struct{}
+
+var resumeAttrs = []string{"id", "target", "size", "mtime", "sha256", "chunk_size", "written", "chunks"}
+
+// Load implements ResumeStore
+func (ms MetaResumeStore) Load(con *Connection, partial string) (*UploadSession, error) {
+	if typ, err := con.PathType(partial); err != nil || typ != DataObjType {
+		return nil, nil
+	}
+
+	obj, err := con.DataObject(partial)
+	if err != nil {
+		return nil, err
+	}
+
+	mc, err := obj.Meta()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+
+	for _, attr := range resumeAttrs {
+		if m, er := mc.First(ResumeAttrPrefix + attr); er == nil {
+			values[attr] = m.Value
+		} else {
+			// Incomplete state, start over
+			return nil, nil
+		}
+	}
+
+	s := new(UploadSession)
+
+	s.ID = values["id"]
+	s.Target = values["target"]
+	s.Partial = partial
+	s.SHA256 = values["sha256"]
+
+	if s.Size, err = strconv.ParseInt(values["size"], 10, 64); err != nil {
+		return nil, nil
+	}
+
+	if s.ModTime, err = strconv.ParseInt(values["mtime"], 10, 64); err != nil {
+		return nil, nil
+	}
+
+	if s.ChunkSize, err = strconv.ParseInt(values["chunk_size"], 10, 64); err != nil || s.ChunkSize <= 0 {
+		return nil, nil
+	}
+
+	if s.Written, err = strconv.ParseInt(values["written"], 10, 64); err != nil {
+		return nil, nil
+	}
+
+	s.Chunks = make([]bool, int((s.Size+s.ChunkSize-1)/s.ChunkSize))
+
+	if err := s.setChunkMap(values["chunks"]); err != nil {
+		return nil, nil
+	}
+
+	return s, nil
+}
+
+// Save implements ResumeStore
+func (ms MetaResumeStore) Save(con *Connection, s *UploadSession) error {
+	obj, err := con.DataObject(s.Partial)
+	if err != nil {
+		return err
+	}
+
+	mc, err := obj.Meta()
+	if err != nil {
+		return err
+	}
+
+	values := map[string]string{
+		"id":         s.ID,
+		"target":     s.Target,
+		"size":       strconv.FormatInt(s.Size, 10),
+		"mtime":      strconv.FormatInt(s.ModTime, 10),
+		"sha256":     s.SHA256,
+		"chunk_size": strconv.FormatInt(s.ChunkSize, 10),
+		"written":    strconv.FormatInt(s.Written, 10),
+		"chunks":     s.ChunkMap(),
+	}
+
+	for _, attr := range resumeAttrs {
+		value := values[attr]
+
+		// AVU values can't be empty
+		if value == "" {
+			value = "-"
+		}
+
+		if m, er := mc.First(ResumeAttrPrefix + attr); er == nil {
+			if m.Value != value {
+				if _, er := m.SetValue(value); er != nil {
+					return er
+				}
+			}
+		} else if _, er := mc.Add(Meta{Attribute: ResumeAttrPrefix + attr, Value: value}); er != nil {
+			return er
+		}
+	}
+
+	return nil
+}
+
+// Clear implements ResumeStore. Once the partial object was moved into place the AVUs are removed from the target.
+func (ms MetaResumeStore) Clear(con *Connection, s *UploadSession) error {
+	p := s.Partial
+
+	if _, er := con.PathType(p); IsNotFound(er) && s.Target != "" {
+		p = s.Target
+	}
+
+	obj, err := con.DataObject(p)
+	if err != nil {
+		return err
+	}
+
+	mc, err := obj.Meta()
+	if err != nil {
+		return err
+	}
+
+	for _, attr := range resumeAttrs {
+		if _, er := mc.Get(ResumeAttrPrefix + attr); er == nil {
+			if er := mc.Delete(ResumeAttrPrefix + attr); er != nil {
+				return er
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResumableOptions are used with Collection.PutResumable. Name defaults to the base name of the local file,
+// ChunkSize to 8 MiB and Store to a FileResumeStore in os.TempDir(). Use MetaResumeStore to allow other hosts to resume the upload.
+// Force replaces an existing data object at the target path.
+type ResumableOptions struct {
+	Name      string
+	Resource  interface{}
+	ChunkSize int64
+	Store     ResumeStore
+	Force     bool
+}
+
+// PartialName returns the name of the intermediate data object used while uploading name
+func PartialName(name string) string {
+	return "." + name + ".gorods-partial"
+}
+
+// PutResumable uploads the local file to the collection in chunks, recording progress in opts.Store after each chunk.
+// If a previous upload of the same file was interrupted, only the missing chunks are sent: the file is checksummed
+// first, to make sure its content didn't change since. The data is written to an intermediate data object (see
+// PartialName), which is renamed to the target name once complete. With Force, an existing data object at the
+// target is moved aside during the rename and deleted after it, so it's kept if the rename fails.
+func (col *Collection) PutResumable(localPath string, opts ResumableOptions) (*DataObj, error) {
+	if opts.Name == "" {
+		opts.Name = filepath.Base(localPath)
+	}
+
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 8 * 1024 * 1024
+	}
+
+	if opts.Store == nil {
+		opts.Store = FileResumeStore{}
+	}
+
+	if er := col.con.checkUntransformedWrite(col.path + "/" + opts.Name); er != nil {
+		return nil, er
+	}
+
+	if er := col.con.scanFile(col.path+"/"+opts.Name, localPath); er != nil {
+		return nil, er
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS PutResumable Failed: %v", err))
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS PutResumable Failed: %v", err))
+	}
+
+	sum, err := fileSHA256(f)
+	if err != nil {
+		return nil, err
+	}
+
+	modTime := info.ModTime().UnixNano()
+
+	target := col.path + "/" + opts.Name
+	partial := col.path + "/" + PartialName(opts.Name)
+
+	session, err := opts.Store.Load(col.con, partial)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj *DataObj
+
+	// A session for a different file (or one whose partial object is gone) can't be resumed
+	if session != nil && session.matches(info.Size(), modTime, sum) {
+		if obj, err = col.con.DataObject(partial); err != nil {
+			session = nil
+		}
+	} else {
+		session = nil
+	}
+
+	if session == nil {
+		if obj, err = col.CreateDataObj(DataObjOptions{
+			Name:     PartialName(opts.Name),
+			Mode:     int(info.Mode().Perm()),
+			Resource: opts.Resource,
+			Force:    true,
+		}); err != nil {
+			return nil, err
+		}
+
+		if session, err = newUploadSession(target, partial, info.Size(), opts.ChunkSize); err != nil {
+			return nil, err
+		}
+
+		session.ModTime = modTime
+		session.SHA256 = sum
+
+		if err := opts.Store.Save(col.con, session); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := make([]byte, session.ChunkSize)
+
+	for i, done := range session.Chunks {
+		if done {
+			continue
+		}
+
+		off := int64(i) * session.ChunkSize
+
+		n, er := f.ReadAt(buf, off)
+		if er != nil && er != io.EOF {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS PutResumable Failed: %v", er))
+		}
+
+		if _, er := obj.writeAt(buf[:n], off); er != nil {
+			return nil, er
+		}
+
+		session.Chunks[i] = true
+		session.Written += int64(n)
+
+		if er := opts.Store.Save(col.con, session); er != nil {
+			return nil, er
+		}
+	}
+
+	if er := obj.Close(); er != nil {
+		return nil, er
+	}
+
+	exists := false
+
+	if typ, er := col.con.PathType(target); er == nil {
+		if typ != DataObjType {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS PutResumable Failed: %v is a collection", target))
+		} else if !opts.Force {
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS PutResumable Failed: %v already exists", target))
+		}
+
+		exists = true
+	} else if !IsNotFound(er) {
+		return nil, er
+	}
+
+	if er := col.swapInto(obj, opts.Name, exists); er != nil {
+		return nil, er
+	}
+
+	// Only forget the session once the upload is in place, a failed swap can still be resumed
+	if er := opts.Store.Clear(col.con, session); er != nil {
+		return nil, er
+	}
+
+	if er := col.Refresh(); er != nil {
+		return nil, er
+	}
+
+	return col.con.DataObject(target)
+}
+
+// UploadSession returns the state of an interrupted resumable upload of name into the collection, read from store,
+// or nil if there is none
+func (col *Collection) UploadSession(name string, store ResumeStore) (*UploadSession, error) {
+	if store == nil {
+		store = FileResumeStore{}
+	}
+
+	return store.Load(col.con, col.path+"/"+PartialName(name))
+}
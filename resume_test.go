@@ -0,0 +1,93 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUploadSessionChunkMap(t *testing.T) {
+	s, err := newUploadSession("/tempZone/home/rods/big.bin", "/tempZone/home/rods/.big.bin.gorods-partial", 100, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Chunks) != 10 {
+		t.Fatalf("Expected 10 chunks, got %v", len(s.Chunks))
+	}
+
+	for _, i := range []int{0, 1, 2, 3, 5, 7, 8} {
+		s.Chunks[i] = true
+	}
+
+	if m := s.ChunkMap(); m != "0-3,5,7-8" {
+		t.Errorf("Expected chunk map '0-3,5,7-8', got '%v'", m)
+	}
+
+	r, err := newUploadSession(s.Target, s.Partial, s.Size, s.ChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.setChunkMap(s.ChunkMap()); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range s.Chunks {
+		if r.Chunks[i] != s.Chunks[i] {
+			t.Errorf("Chunk %v doesn't match after parsing the chunk map", i)
+		}
+	}
+
+	if r.Complete() {
+		t.Error("Expected an incomplete session")
+	}
+}
+
+func TestUploadSessionMatches(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "big.bin")
+
+	if err := ioutil.WriteFile(name, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	sum, err := fileSHA256(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sum != "84d89877f0d4041efb6bf91a16f0248f2fd573e6af05c19f96bedb9f882f7882" {
+		t.Errorf("Unexpected checksum %v", sum)
+	}
+
+	s := &UploadSession{Size: 10, ModTime: 1500000000, SHA256: sum}
+
+	for _, c := range []struct {
+		size    int64
+		modTime int64
+		sum     string
+		match   bool
+	}{
+		{10, 1500000000, sum, true},
+		{11, 1500000000, sum, false},
+		{10, 1500000001, sum, false},
+		{10, 1500000000, "0" + sum[1:], false},
+	} {
+		if m := s.matches(c.size, c.modTime, c.sum); m != c.match {
+			t.Errorf("matches(%v, %v, %v): expected %v, got %v", c.size, c.modTime, c.sum, c.match, m)
+		}
+	}
+}
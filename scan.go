@@ -0,0 +1,190 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Scanner inspects content before it's uploaded to iRODS. Scan reads the content from r and returns a non-nil
+// error to veto the upload. name is the destination path of the data object.
+// Scanners are set with ConnectionOptions.Scanners or Connection.AddScanner, and are applied by Collection.Put,
+// Collection.PutBundle, Collection.PutResumable and DataObj.Write. DataObj.WriteBytes and DataObj.WriteAt write
+// incrementally, so the content can't be scanned before it's stored: they fail on connections with scanners, as do
+// the gateways built on them and write references minted by Connection.Presign.
+type Scanner interface {
+	Scan(name string, r io.Reader) error
+}
+
+// ScannerFunc is an adapter to allow the use of ordinary functions as a Scanner
+type ScannerFunc func(name string, r io.Reader) error
+
+// Scan calls f(name, r)
+func (f ScannerFunc) Scan(name string, r io.Reader) error {
+	return f(name, r)
+}
+
+// AddScanner adds a content scanner to the connection, in addition to those in ConnectionOptions.Scanners
+func (con *Connection) AddScanner(s Scanner) {
+	con.scanners = append(con.scanners, s)
+}
+
+func (con *Connection) allScanners() []Scanner {
+	var scanners []Scanner
+
+	if con.Options != nil {
+		scanners = append(scanners, con.Options.Scanners...)
+	}
+
+	return append(scanners, con.scanners...)
+}
+
+func (con *Connection) hasScanners() bool {
+	return len(con.allScanners()) > 0
+}
+
+// checkIncrementalWrite returns an error if writing to the data object at p bit by bit would bypass the connection's
+// scanners or transforms
+func (con *Connection) checkIncrementalWrite(p string) error {
+	if err := con.checkUntransformedWrite(p); err != nil {
+		return err
+	}
+
+	if con.hasScanners() {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Write DataObject Failed: %v, incremental writes can't be scanned, upload with Collection.Put or DataObj.Write", p))
+	}
+
+	return nil
+}
+
+func rejected(name string, err error) error {
+	return newError(Fatal, -1, fmt.Sprintf("iRODS Upload Rejected: %v, %v", name, err))
+}
+
+// scanFile runs every scanner on the local file, reopening it for each scanner
+func (con *Connection) scanFile(name string, localPath string) error {
+	for _, s := range con.allScanners() {
+		f, err := os.Open(localPath)
+		if err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Upload Scan Failed: %v", err))
+		}
+
+		err = s.Scan(name, bufio.NewReader(f))
+		f.Close()
+
+		if err != nil {
+			return rejected(name, err)
+		}
+	}
+
+	return nil
+}
+
+// scanBytes runs every scanner on data
+func (con *Connection) scanBytes(name string, data []byte) error {
+	for _, s := range con.allScanners() {
+		if err := s.Scan(name, bytes.NewReader(data)); err != nil {
+			return rejected(name, err)
+		}
+	}
+
+	return nil
+}
+
+// ExtensionAllowlist returns a Scanner that only allows data objects with one of the file extensions specified
+// (e.g. ".csv", ".txt"). Extensions are compared case insensitively. It doesn't read the content.
+func ExtensionAllowlist(exts ...string) Scanner {
+	allowed := make(map[string]bool, len(exts))
+
+	for _, ext := range exts {
+		allowed[strings.ToLower(ext)] = true
+	}
+
+	return ScannerFunc(func(name string, r io.Reader) error {
+		ext := strings.ToLower(filepath.Ext(name))
+
+		if !allowed[ext] {
+			return fmt.Errorf("file extension %q not allowed", ext)
+		}
+
+		return nil
+	})
+}
+
+// ClamdScanner returns a Scanner that streams content to a ClamAV daemon using the INSTREAM command.
+// network and address are passed to net.Dial, e.g. "tcp", "localhost:3310" or "unix", "/var/run/clamav/clamd.ctl".
+// Uploads are rejected if a virus is found, or if clamd can't be reached.
+func ClamdScanner(network string, address string, timeout time.Duration) Scanner {
+	return ScannerFunc(func(name string, r io.Reader) error {
+		conn, err := net.DialTimeout(network, address, timeout)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if timeout > 0 {
+			conn.SetDeadline(time.Now().Add(timeout))
+		}
+
+		if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+			return err
+		}
+
+		buf := make([]byte, 1024*64)
+		size := make([]byte, 4)
+
+		for {
+			n, rErr := r.Read(buf)
+
+			if n > 0 {
+				binary.BigEndian.PutUint32(size, uint32(n))
+
+				if _, err := conn.Write(size); err != nil {
+					return err
+				}
+
+				if _, err := conn.Write(buf[:n]); err != nil {
+					return err
+				}
+			}
+
+			if rErr == io.EOF {
+				break
+			} else if rErr != nil {
+				return rErr
+			}
+		}
+
+		// A zero length chunk terminates the stream
+		binary.BigEndian.PutUint32(size, 0)
+		if _, err := conn.Write(size); err != nil {
+			return err
+		}
+
+		reply, err := bufio.NewReader(conn).ReadString('\x00')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		reply = strings.TrimRight(reply, "\x00\n")
+
+		if !strings.HasSuffix(reply, "OK") {
+			return fmt.Errorf("clamd: %v", reply)
+		}
+
+		return nil
+	})
+}
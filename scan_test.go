@@ -0,0 +1,48 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtensionAllowlist(t *testing.T) {
+	s := ExtensionAllowlist(".csv", ".TXT")
+
+	for name, allowed := range map[string]bool{
+		"/tempZone/home/rods/data.csv":     true,
+		"/tempZone/home/rods/Data.CSV":     true,
+		"/tempZone/home/rods/notes.txt":    true,
+		"/tempZone/home/rods/run.sh":       false,
+		"/tempZone/home/rods/archive.csv.": false,
+		"/tempZone/home/rods/README":       false,
+	} {
+		// The content isn't read
+		err := s.Scan(name, nil)
+
+		if allowed && err != nil {
+			t.Errorf("%v: unexpected error %v", name, err)
+		} else if !allowed && err == nil {
+			t.Errorf("%v: expected the extension to be rejected", name)
+		}
+	}
+}
+
+func TestCheckIncrementalWrite(t *testing.T) {
+	con := &Connection{Options: &ConnectionOptions{}}
+
+	if err := con.checkIncrementalWrite("/tempZone/home/rods/a.csv"); err != nil {
+		t.Errorf("Unexpected error without scanners: %v", err)
+	}
+
+	con.AddScanner(ExtensionAllowlist(".csv"))
+
+	if err := con.checkIncrementalWrite("/tempZone/home/rods/a.csv"); err == nil || !strings.Contains(err.Error(), "can't be scanned") {
+		t.Errorf("Expected incremental writes to be refused with scanners, got %v", err)
+	}
+}
@@ -0,0 +1,328 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SearchOptions are used with Connection.Search. All of the criteria set must match.
+//
+// Name is a glob pattern ("*.csv", "run_??.dat") matched against the data object name, or a general query
+// like pattern ("%.csv") if Like is set. Collection restricts the search to a collection and everything below it,
+// and selects the zone searched. MaxSize and the zero times mean no limit. PageSize is the number of results
+// fetched per request (defaults to 256). Limit stops the search after that many hits (0 is unlimited).
+type SearchOptions struct {
+	Name           string
+	Like           bool
+	Collection     string
+	MinSize        int64
+	MaxSize        int64
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	Owner          string
+	Resource       string
+	PageSize       int
+	Limit          int
+}
+
+// SearchHit is a data object found by Connection.Search. Replicas of the same data object are reported once,
+// unless SearchOptions.Resource is set.
+type SearchHit struct {
+	Path       string
+	Name       string
+	Collection string
+	Size       int64
+	Owner      string
+	Resource   string
+	Checksum   string
+	ModifyTime time.Time
+}
+
+// SearchStats describes the execution of a search, see SearchResults.Stats.
+// Total is the number of matching rows reported by the catalog, which counts each replica, or -1 if it wasn't reported.
+// Elapsed is the time spent waiting for the server. Truncated is true if SearchOptions.Limit stopped the search
+// before all matches were read.
+type SearchStats struct {
+	Total     int
+	Hits      int
+	Pages     int
+	Elapsed   time.Duration
+	Truncated bool
+}
+
+// searchPage is a page of rows, with the query state after it was fetched
+type searchPage struct {
+	rows []map[string]string
+	info pageInfo
+}
+
+// SearchResults iterates over the hits of Connection.Search, fetching pages from the server as needed.
+// Always call Close when done, as the query stays open on the server until all pages are read.
+type SearchResults struct {
+	pages chan searchPage
+	errc  chan error
+	done  chan struct{}
+	once  sync.Once
+	limit int
+
+	page   []map[string]string
+	hit    SearchHit
+	err    error
+	seen   map[string]bool
+	closed bool
+	stats  SearchStats
+
+	// final is the query state once all pages were fetched, set before the error is sent on errc
+	final pageInfo
+}
+
+// errSearchClosed stops the query when the results are closed before all pages are read
+var errSearchClosed = errors.New("search closed")
+
+// Search finds data objects matching opts with a single general query, evaluated by the catalog.
+// Results are fetched lazily, page by page, as SearchResults.Next is called:
+//
+//	res, err := con.Search(gorods.SearchOptions{Collection: "/tempZone/home/rods", Name: "*.csv", MinSize: 1024})
+//	defer res.Close()
+//
+//	for res.Next() {
+//		fmt.Println(res.Hit().Path)
+//	}
+//
+//	if err := res.Err(); err != nil { ... }
+func (con *Connection) Search(opts SearchOptions) (*SearchResults, error) {
+	query, err := searchQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := con.zoneHint(opts.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.PageSize <= 0 {
+		opts.PageSize = 256
+	}
+
+	res := &SearchResults{
+		pages: make(chan searchPage),
+		errc:  make(chan error, 1),
+		done:  make(chan struct{}),
+		limit: opts.Limit,
+		seen:  make(map[string]bool),
+		stats: SearchStats{Total: -1},
+	}
+
+	go func() {
+		info := new(pageInfo)
+
+		err := con.intercept(&Event{Op: OpQuery, Query: query, noRetry: true}, func() error {
+			return con.queryPages(query, QueryOptions{PageSize: opts.PageSize, Zone: zone}, info, func(rows []map[string]string) error {
+				select {
+				case res.pages <- searchPage{rows: rows, info: *info}:
+					return nil
+				case <-res.done:
+					return errSearchClosed
+				}
+			})
+		})
+
+		res.final = *info
+		res.errc <- err
+		close(res.pages)
+	}()
+
+	return res, nil
+}
+
+// Next advances to the next hit, fetching the next page if needed. It returns false when there are no more hits,
+// the limit was reached, or the query failed (see Err).
+func (res *SearchResults) Next() bool {
+	if res.closed {
+		return false
+	}
+
+	for {
+		for len(res.page) > 0 {
+			row := res.page[0]
+
+			hit := searchHitOf(row)
+			key := hit.Path + "\x00" + hit.Resource
+
+			if res.seen[key] {
+				res.page = res.page[1:]
+				continue
+			}
+
+			if res.limit > 0 && res.stats.Hits >= res.limit {
+				res.stats.Truncated = true
+				res.Close()
+
+				return false
+			}
+
+			res.page = res.page[1:]
+			res.seen[key] = true
+			res.hit = hit
+			res.stats.Hits++
+
+			return true
+		}
+
+		page, ok := <-res.pages
+		if !ok {
+			if err := <-res.errc; err != nil && err != errSearchClosed {
+				res.err = err
+			} else if err == nil {
+				res.stats.Pages = res.final.Pages
+				res.stats.Elapsed = res.final.Elapsed
+
+				if res.final.Pages == 0 {
+					res.stats.Total = 0
+				}
+			}
+
+			res.closed = true
+
+			return false
+		}
+
+		res.page = page.rows
+		res.stats.Total = page.info.Total
+		res.stats.Pages = page.info.Pages
+		res.stats.Elapsed = page.info.Elapsed
+	}
+}
+
+// Hit returns the current hit, after Next returned true
+func (res *SearchResults) Hit() SearchHit {
+	return res.hit
+}
+
+// Stats returns the execution metadata of the search so far. Once Next returned false, it describes the whole search.
+func (res *SearchResults) Stats() SearchStats {
+	return res.stats
+}
+
+// Err returns the error that ended the iteration, if any
+func (res *SearchResults) Err() error {
+	return res.err
+}
+
+// All reads the remaining hits
+func (res *SearchResults) All() ([]SearchHit, error) {
+	hits := make([]SearchHit, 0)
+
+	for res.Next() {
+		hits = append(hits, res.Hit())
+	}
+
+	return hits, res.Err()
+}
+
+// Close stops fetching pages and closes the query on the server. It is safe to call more than once.
+func (res *SearchResults) Close() error {
+	res.once.Do(func() {
+		close(res.done)
+	})
+
+	res.page = nil
+	res.closed = true
+
+	return nil
+}
+
+// searchQuery translates the search options to a general query
+func searchQuery(opts SearchOptions) (string, error) {
+	columns := []string{"COLL_NAME", "DATA_NAME", "DATA_SIZE", "DATA_OWNER_NAME", "DATA_CHECKSUM", "DATA_MODIFY_TIME"}
+	conds := make([]string, 0)
+
+	for _, s := range []string{opts.Name, opts.Collection, opts.Owner, opts.Resource} {
+		if strings.Contains(s, "'") {
+			return "", newError(Fatal, -1, fmt.Sprintf("iRODS Search Failed: single quotes aren't supported in %v", s))
+		}
+	}
+
+	if opts.Name != "" {
+		pattern := opts.Name
+		if !opts.Like {
+			pattern = globToLike(pattern)
+		}
+
+		conds = append(conds, fmt.Sprintf("DATA_NAME like '%v'", pattern))
+	}
+
+	if opts.Collection != "" {
+		p := strings.TrimRight(opts.Collection, "/")
+		conds = append(conds, fmt.Sprintf("COLL_NAME = '%v' || like '%v/%%'", p, p))
+	}
+
+	if opts.MinSize > 0 {
+		conds = append(conds, fmt.Sprintf("DATA_SIZE >= '%v'", opts.MinSize))
+	}
+
+	if opts.MaxSize > 0 {
+		conds = append(conds, fmt.Sprintf("DATA_SIZE <= '%v'", opts.MaxSize))
+	}
+
+	if !opts.ModifiedAfter.IsZero() {
+		conds = append(conds, fmt.Sprintf("DATA_MODIFY_TIME >= '%011d'", opts.ModifiedAfter.Unix()))
+	}
+
+	if !opts.ModifiedBefore.IsZero() {
+		conds = append(conds, fmt.Sprintf("DATA_MODIFY_TIME < '%011d'", opts.ModifiedBefore.Unix()))
+	}
+
+	if opts.Owner != "" {
+		p := ParsePrincipal(opts.Owner)
+
+		conds = append(conds, fmt.Sprintf("DATA_OWNER_NAME = '%v'", p.Name))
+		if p.Zone != "" {
+			conds = append(conds, fmt.Sprintf("DATA_OWNER_ZONE = '%v'", p.Zone))
+		}
+	}
+
+	if opts.Resource != "" {
+		columns = append(columns, "DATA_RESC_NAME")
+		conds = append(conds, fmt.Sprintf("DATA_RESC_NAME = '%v'", opts.Resource))
+	}
+
+	query := "select " + strings.Join(columns, ", ")
+	if len(conds) > 0 {
+		query += " where " + strings.Join(conds, " and ")
+	}
+
+	return query, nil
+}
+
+// globToLike converts a glob pattern to a like pattern. The general query syntax has no escape, so % and _ in the
+// glob also act as wildcards.
+func globToLike(glob string) string {
+	return strings.NewReplacer("*", "%", "?", "_").Replace(glob)
+}
+
+func searchHitOf(row map[string]string) SearchHit {
+	size, _ := strconv.ParseInt(row["DATA_SIZE"], 10, 64)
+
+	return SearchHit{
+		Path:       row["COLL_NAME"] + "/" + row["DATA_NAME"],
+		Name:       row["DATA_NAME"],
+		Collection: row["COLL_NAME"],
+		Size:       size,
+		Owner:      row["DATA_OWNER_NAME"],
+		Resource:   row["DATA_RESC_NAME"],
+		Checksum:   row["DATA_CHECKSUM"],
+		ModifyTime: timeStringToTime(row["DATA_MODIFY_TIME"]),
+	}
+}
@@ -0,0 +1,136 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchQuery(t *testing.T) {
+	query, err := searchQuery(SearchOptions{
+		Name:          "run_?.*",
+		Collection:    "/tempZone/home/rods/",
+		MinSize:       1024,
+		ModifiedAfter: time.Unix(1500000000, 0),
+		Owner:         "alice#otherZone",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "select COLL_NAME, DATA_NAME, DATA_SIZE, DATA_OWNER_NAME, DATA_CHECKSUM, DATA_MODIFY_TIME where " +
+		"DATA_NAME like 'run__.%' and COLL_NAME = '/tempZone/home/rods' || like '/tempZone/home/rods/%' and " +
+		"DATA_SIZE >= '1024' and DATA_MODIFY_TIME >= '01500000000' and DATA_OWNER_NAME = 'alice' and DATA_OWNER_ZONE = 'otherZone'"
+
+	if query != expected {
+		t.Errorf("Unexpected query:\n%v\nexpected:\n%v", query, expected)
+	}
+
+	if _, err := searchQuery(SearchOptions{Name: "it's.txt"}); err == nil {
+		t.Error("Expected an error for a name with a single quote")
+	}
+}
+
+// testSearchResults returns results fed with pages of rows, as Connection.Search does, reporting total matches
+func testSearchResults(limit int, total int, pages ...[]map[string]string) *SearchResults {
+	res := &SearchResults{
+		pages: make(chan searchPage),
+		errc:  make(chan error, 1),
+		done:  make(chan struct{}),
+		limit: limit,
+		seen:  make(map[string]bool),
+		stats: SearchStats{Total: -1},
+	}
+
+	go func() {
+		var info pageInfo
+		var err error
+
+		for _, rows := range pages {
+			info.Total = total
+			info.Pages++
+			info.Elapsed += time.Millisecond
+
+			select {
+			case res.pages <- searchPage{rows: rows, info: info}:
+				continue
+			case <-res.done:
+				err = errSearchClosed
+			}
+
+			break
+		}
+
+		res.final = info
+		res.errc <- err
+		close(res.pages)
+	}()
+
+	return res
+}
+
+func testSearchRow(name string) map[string]string {
+	return map[string]string{"COLL_NAME": "/tempZone/home/rods", "DATA_NAME": name, "DATA_SIZE": "10", "DATA_RESC_NAME": "demoResc"}
+}
+
+func TestSearchStats(t *testing.T) {
+	res := testSearchResults(0, 4,
+		[]map[string]string{testSearchRow("a.txt"), testSearchRow("b.txt")},
+		[]map[string]string{testSearchRow("b.txt"), testSearchRow("c.txt")},
+	)
+
+	hits, err := res.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hits) != 3 || hits[2].Path != "/tempZone/home/rods/c.txt" {
+		t.Errorf("Expected 3 distinct hits, got %v", hits)
+	}
+
+	stats := res.Stats()
+
+	if stats.Total != 4 || stats.Hits != 3 || stats.Pages != 2 || stats.Elapsed != 2*time.Millisecond || stats.Truncated {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	if empty := testSearchResults(0, -1); empty.Next() || empty.Stats().Total != 0 || empty.Stats().Pages != 0 {
+		t.Errorf("Expected no matches for an empty search, got %+v", empty.Stats())
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	res := testSearchResults(2, 5,
+		[]map[string]string{testSearchRow("a.txt"), testSearchRow("b.txt")},
+		[]map[string]string{testSearchRow("c.txt"), testSearchRow("d.txt"), testSearchRow("e.txt")},
+	)
+
+	hits, err := res.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hits) != 2 {
+		t.Errorf("Expected the limit to stop the search after 2 hits, got %v", hits)
+	}
+
+	if stats := res.Stats(); !stats.Truncated || stats.Hits != 2 || stats.Total != 5 {
+		t.Errorf("Expected a truncated search of 5 matches, got %+v", stats)
+	}
+
+	if res.Next() {
+		t.Error("Expected no more hits once truncated")
+	}
+
+	// Exactly reaching the limit isn't a truncation
+	exact := testSearchResults(2, 2, []map[string]string{testSearchRow("a.txt"), testSearchRow("b.txt")})
+
+	if hits, err := exact.All(); err != nil || len(hits) != 2 || exact.Stats().Truncated {
+		t.Errorf("Expected 2 hits without truncation, got %v, %+v, %v", hits, exact.Stats(), err)
+	}
+}
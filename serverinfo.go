@@ -0,0 +1,312 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// ServerVersion is the release version of an iRODS server, parsed from strings like "rods4.2.0"
+type ServerVersion struct {
+	Release string
+	API     string
+	Major   int
+	Minor   int
+	Patch   int
+}
+
+// ParseServerVersion parses an iRODS release string such as "rods4.2.0" or "4.1.10"
+func ParseServerVersion(release string) (ServerVersion, error) {
+	v := ServerVersion{Release: release}
+
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(release), "rods"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return v, newError(Fatal, -1, fmt.Sprintf("Unable to parse server version %q", release))
+	}
+
+	nums := make([]int, 3)
+
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, newError(Fatal, -1, fmt.Sprintf("Unable to parse server version %q", release))
+		}
+		nums[i] = n
+	}
+
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+
+	return v, nil
+}
+
+// String returns the version in major.minor.patch form
+func (v ServerVersion) String() string {
+	return fmt.Sprintf("%v.%v.%v", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 if v is older than, the same as, or newer than major.minor.patch
+func (v ServerVersion) Compare(major int, minor int, patch int) int {
+	a := []int{v.Major, v.Minor, v.Patch}
+	b := []int{major, minor, patch}
+
+	for i := range a {
+		if a[i] < b[i] {
+			return -1
+		} else if a[i] > b[i] {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// AtLeast returns true if v is major.minor.patch or newer
+func (v ServerVersion) AtLeast(major int, minor int, patch int) bool {
+	return v.Compare(major, minor, patch) >= 0
+}
+
+// ServerVersion returns the version of the iRODS server the connection is attached to, as reported during the connection handshake
+func (con *Connection) ServerVersion() (ServerVersion, error) {
+	ccon := con.GetCcon()
+
+	if ccon.svrVersion == nil {
+		con.ReturnCcon(ccon)
+
+		info, err := con.MiscServerInfo()
+		if err != nil {
+			return ServerVersion{}, err
+		}
+
+		return info.Version, nil
+	}
+
+	release := C.GoString(&ccon.svrVersion.relVersion[0])
+	api := C.GoString(&ccon.svrVersion.apiVersion[0])
+
+	con.ReturnCcon(ccon)
+
+	v, err := ParseServerVersion(release)
+	v.API = api
+
+	return v, err
+}
+
+// MiscServerInfo holds the information returned by rcGetMiscSvrInfo (imiscsvrinfo).
+// ServerType is "RCAT_ENABLED" for an iCAT server, or "RCAT_NOT_ENABLED" for a resource server.
+type MiscServerInfo struct {
+	ServerType string
+	BootTime   time.Time
+	Version    ServerVersion
+	Zone       string
+}
+
+// ICAT returns true if the server the connection is attached to is an iCAT enabled server
+func (info *MiscServerInfo) ICAT() bool {
+	return info.ServerType == "RCAT_ENABLED"
+}
+
+// Uptime returns the time elapsed since the server was started
+func (info *MiscServerInfo) Uptime() time.Duration {
+	return time.Since(info.BootTime)
+}
+
+// MiscServerInfo returns the server type, boot time, version and zone of the iRODS server the connection is attached to
+func (con *Connection) MiscServerInfo() (*MiscServerInfo, error) {
+	var (
+		err     *C.char
+		svrInfo *C.miscSvrInfo_t
+	)
+
+	ccon := con.GetCcon()
+
+	if status := C.gorods_get_misc_svr_info(&svrInfo, ccon, &err); status < 0 {
+		con.ReturnCcon(ccon)
+		return nil, newError(Fatal, status, fmt.Sprintf("iRODS MiscServerInfo Failed: %v", C.GoString(err)))
+	}
+
+	con.ReturnCcon(ccon)
+
+	defer C.free(unsafe.Pointer(svrInfo))
+
+	info := new(MiscServerInfo)
+
+	if svrInfo.serverType == C.RCAT_ENABLED {
+		info.ServerType = "RCAT_ENABLED"
+	} else {
+		info.ServerType = "RCAT_NOT_ENABLED"
+	}
+
+	info.BootTime = time.Unix(int64(svrInfo.serverBootTime), 0)
+	info.Zone = C.GoString(&svrInfo.rodsZone[0])
+
+	v, er := ParseServerVersion(C.GoString(&svrInfo.relVersion[0]))
+	if er != nil {
+		return nil, er
+	}
+
+	v.API = C.GoString(&svrInfo.apiVersion[0])
+	info.Version = v
+
+	return info, nil
+}
+
+// ZoneReport is the decoded output of rcZoneReport (izonereport), describing every server in the zone
+type ZoneReport struct {
+	SchemaVersion string           `json:"schema_version"`
+	Zones         []ZoneReportZone `json:"zones"`
+
+	// Raw is the report JSON as returned by the server, for fields not mapped onto the structs
+	Raw json.RawMessage `json:"-"`
+}
+
+// ZoneReportZone describes the iCAT server, resource servers and coordinating resources of a single zone
+type ZoneReportZone struct {
+	ICATServer            ZoneReportServer         `json:"icat_server"`
+	Servers               []ZoneReportServer       `json:"servers"`
+	CoordinatingResources []map[string]interface{} `json:"coordinating_resources"`
+}
+
+// ZoneReportServer describes a single server in the zone report: host information, installed plugins,
+// local storage resources and the server configuration (which includes federation settings)
+type ZoneReportServer struct {
+	HostSystemInformation map[string]interface{}   `json:"host_system_information"`
+	Version               map[string]interface{}   `json:"version"`
+	Plugins               []ZoneReportPlugin       `json:"plugins"`
+	Resources             []map[string]interface{} `json:"resources"`
+	ServerConfig          map[string]interface{}   `json:"server_config"`
+}
+
+// ZoneReportPlugin describes a plugin installed on a server
+type ZoneReportPlugin struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Version  string `json:"version"`
+	Checksum string `json:"checksum_sha256"`
+}
+
+// ZoneFederation describes a remote zone that the server is federated with
+type ZoneFederation struct {
+	ZoneName       string `json:"zone_name"`
+	CatalogHost    string `json:"catalog_provider_hosts"`
+	NegotiationKey string `json:"negotiation_key"`
+}
+
+// Hostname returns the host name of the server, or an empty string if it wasn't reported
+func (s *ZoneReportServer) Hostname() string {
+	if name, ok := s.HostSystemInformation["hostname"].(string); ok {
+		return name
+	}
+
+	return ""
+}
+
+// IRODSVersion returns the parsed iRODS version of the server
+func (s *ZoneReportServer) IRODSVersion() (ServerVersion, error) {
+	release, _ := s.Version["irods_version"].(string)
+
+	return ParseServerVersion(release)
+}
+
+// ResourceNames returns the names of the storage resources hosted on the server
+func (s *ZoneReportServer) ResourceNames() []string {
+	names := make([]string, 0, len(s.Resources))
+
+	for _, resc := range s.Resources {
+		if name, ok := resc["name"].(string); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Federation returns the remote zones listed in the federation section of the server configuration
+func (s *ZoneReportServer) Federation() []ZoneFederation {
+	feds := make([]ZoneFederation, 0)
+
+	list, ok := s.ServerConfig["federation"].([]interface{})
+	if !ok {
+		return feds
+	}
+
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var fed ZoneFederation
+
+		fed.ZoneName, _ = m["zone_name"].(string)
+		fed.NegotiationKey, _ = m["negotiation_key"].(string)
+
+		switch hosts := m["catalog_provider_hosts"].(type) {
+		case string:
+			fed.CatalogHost = hosts
+		case []interface{}:
+			if len(hosts) > 0 {
+				fed.CatalogHost, _ = hosts[0].(string)
+			}
+		}
+
+		feds = append(feds, fed)
+	}
+
+	return feds
+}
+
+// ParseZoneReport decodes the JSON produced by rcZoneReport or izonereport
+func ParseZoneReport(data []byte) (*ZoneReport, error) {
+	report := new(ZoneReport)
+
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Unable to parse zone report: %v", err))
+	}
+
+	report.Raw = json.RawMessage(data)
+
+	return report, nil
+}
+
+// ZoneReport returns the zone report (izonereport) of the zone the connection is attached to.
+// You must have the proper rodsadmin privileges to use this function, and GoRODS must be built against the iRODS 4.2
+// or later client library.
+func (con *Connection) ZoneReport() (*ZoneReport, error) {
+	if er := con.requireAdmin("ZoneReport"); er != nil {
+		return nil, er
+	}
+
+	if er := con.requireCapability("ZoneReport", "4.1.0", func(c Capabilities) bool { return c.ZoneReport }); er != nil {
+		return nil, er
+	}
+
+	var (
+		err    *C.char
+		report *C.char
+	)
+
+	ccon := con.GetCcon()
+
+	if status := C.gorods_zone_report(&report, ccon, &err); status < 0 {
+		con.ReturnCcon(ccon)
+		return nil, newError(Fatal, status, fmt.Sprintf("iRODS ZoneReport Failed: %v", C.GoString(err)))
+	}
+
+	con.ReturnCcon(ccon)
+
+	defer C.free(unsafe.Pointer(report))
+
+	return ParseZoneReport([]byte(C.GoString(report)))
+}
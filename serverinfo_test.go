@@ -0,0 +1,59 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	v, err := ParseServerVersion("rods4.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Major != 4 || v.Minor != 2 || v.Patch != 0 || v.String() != "4.2.0" {
+		t.Errorf("Unexpected version %+v", v)
+	}
+
+	if !v.AtLeast(4, 1, 10) || v.AtLeast(4, 2, 1) || v.Compare(4, 2, 0) != 0 {
+		t.Errorf("Unexpected comparison result for %v", v)
+	}
+
+	if _, err := ParseServerVersion("rods4.x"); err == nil {
+		t.Error("Expected an error for an invalid version")
+	}
+}
+
+func TestParseZoneReport(t *testing.T) {
+	data := `{"schema_version": "v2", "zones": [{"icat_server": {
+		"host_system_information": {"hostname": "icat.example.org"},
+		"version": {"irods_version": "4.2.1"},
+		"plugins": [{"name": "unixfilesystem", "type": "resource", "version": "4.2.1"}],
+		"resources": [{"name": "demoResc"}],
+		"server_config": {"federation": [{"zone_name": "otherZone", "catalog_provider_hosts": ["other.example.org"]}]}
+	}}]}`
+
+	report, err := ParseZoneReport([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	icat := report.Zones[0].ICATServer
+
+	if icat.Hostname() != "icat.example.org" || len(icat.Plugins) != 1 || icat.ResourceNames()[0] != "demoResc" {
+		t.Errorf("Unexpected icat server %+v", icat)
+	}
+
+	if v, err := icat.IRODSVersion(); err != nil || !v.AtLeast(4, 2, 1) {
+		t.Errorf("Unexpected icat version %v, %v", v, err)
+	}
+
+	if feds := icat.Federation(); len(feds) != 1 || feds[0].ZoneName != "otherZone" || feds[0].CatalogHost != "other.example.org" {
+		t.Errorf("Unexpected federation %+v", feds)
+	}
+}
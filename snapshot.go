@@ -0,0 +1,374 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// SnapshotAVU is a plain copy of a metadata AVU triple, detached from any connection
+type SnapshotAVU struct {
+	Attribute string
+	Value     string
+	Units     string
+}
+
+// SnapshotEntry holds the catalog information of a single collection or data object, as recorded by an Exporter
+type SnapshotEntry struct {
+	Path       string
+	Name       string
+	Type       int
+	Size       int64
+	Checksum   string
+	OwnerName  string
+	Resource   string
+	CreateTime time.Time
+	ModifyTime time.Time
+	Metas      []SnapshotAVU
+}
+
+// Snapshot is a local, offline copy of catalog metadata for one or more collection trees, stored in a file.
+// The file holds a header followed by one JSON document per entry, so snapshots are written as the trees are walked
+// and queried by streaming the entries from disk: neither needs to hold the whole catalog in memory.
+// Snapshots are queried without a connection to the iCAT server.
+type Snapshot struct {
+	Created time.Time
+	Zone    string
+	Roots   []string
+
+	path string
+}
+
+// SnapshotWriter writes a snapshot file one entry at a time
+type SnapshotWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewSnapshotWriter writes the header of a snapshot of roots in zone to w, and returns a *SnapshotWriter for its entries.
+// Call Flush once every entry is written.
+func NewSnapshotWriter(w io.Writer, zone string, roots []string) (*SnapshotWriter, error) {
+	sw := new(SnapshotWriter)
+	sw.w = bufio.NewWriter(w)
+	sw.enc = json.NewEncoder(sw.w)
+
+	if err := sw.enc.Encode(&Snapshot{Created: time.Now(), Zone: zone, Roots: roots}); err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Save Snapshot Failed: %v", err))
+	}
+
+	return sw, nil
+}
+
+// Write appends the entry to the snapshot
+func (sw *SnapshotWriter) Write(entry *SnapshotEntry) error {
+	if err := sw.enc.Encode(entry); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Save Snapshot Failed: %v", err))
+	}
+
+	return nil
+}
+
+// Flush writes any buffered entries to the underlying io.Writer
+func (sw *SnapshotWriter) Flush() error {
+	if err := sw.w.Flush(); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Save Snapshot Failed: %v", err))
+	}
+
+	return nil
+}
+
+// ExporterOptions are used when creating an Exporter with Connection.Exporter().
+// Paths are the collection trees to export. SkipMeta disables fetching AVUs, which is much faster for large trees.
+type ExporterOptions struct {
+	Paths    []string
+	SkipMeta bool
+}
+
+// Exporter dumps catalog metadata for selected collection trees into a Snapshot
+type Exporter struct {
+	con  *Connection
+	opts ExporterOptions
+}
+
+// Exporter returns a new *Exporter that uses the connection to read the catalog
+func (con *Connection) Exporter(opts ExporterOptions) *Exporter {
+	exp := new(Exporter)
+
+	exp.con = con
+	exp.opts = opts
+
+	return exp
+}
+
+// Export walks each path in ExporterOptions.Paths recursively, and writes the snapshot to w as it goes
+func (exp *Exporter) Export(w io.Writer) error {
+	if len(exp.opts.Paths) == 0 {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Export Failed: no paths specified"))
+	}
+
+	roots := make([]string, 0, len(exp.opts.Paths))
+	for _, p := range exp.opts.Paths {
+		roots = append(roots, strings.TrimRight(p, "/"))
+	}
+
+	sw, err := NewSnapshotWriter(w, exp.con.Options.Zone, roots)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range roots {
+		col, err := exp.con.Collection(CollectionOptions{
+			Path:      p,
+			Recursive: false,
+			SkipCache: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := exp.exportCollection(col, sw); err != nil {
+			return err
+		}
+	}
+
+	return sw.Flush()
+}
+
+// ExportTo runs Export into the local file specified, and returns the resulting *Snapshot.
+// The file is removed if the export fails.
+func (exp *Exporter) ExportTo(localPath string) (*Snapshot, error) {
+	f, err := os.Create(localPath)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Save Snapshot Failed: %v", err))
+	}
+
+	err = exp.Export(f)
+
+	if cErr := f.Close(); err == nil && cErr != nil {
+		err = newError(Fatal, -1, fmt.Sprintf("Save Snapshot Failed: %v", cErr))
+	}
+
+	if err != nil {
+		os.Remove(localPath)
+		return nil, err
+	}
+
+	return LoadSnapshot(localPath)
+}
+
+func (exp *Exporter) exportCollection(col *Collection, sw *SnapshotWriter) error {
+	entry, err := exp.newEntry(col)
+	if err != nil {
+		return err
+	}
+
+	if err := sw.Write(entry); err != nil {
+		return err
+	}
+
+	objs, err := col.All()
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		if obj.Type() == CollectionType {
+			if err := exp.exportCollection(obj.(*Collection), sw); err != nil {
+				return err
+			}
+		} else {
+			entry, err := exp.newEntry(obj)
+			if err != nil {
+				return err
+			}
+
+			if err := sw.Write(entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (exp *Exporter) newEntry(obj IRodsObj) (*SnapshotEntry, error) {
+	entry := new(SnapshotEntry)
+
+	entry.Path = obj.Path()
+	entry.Name = obj.Name()
+	entry.Type = obj.Type()
+	entry.OwnerName = obj.OwnerName()
+	entry.CreateTime = obj.CreateTime()
+	entry.ModifyTime = obj.ModifyTime()
+	entry.Metas = make([]SnapshotAVU, 0)
+
+	if obj.Type() == DataObjType {
+		do := obj.(*DataObj)
+
+		entry.Size = do.Size()
+		entry.Checksum = do.Checksum()
+
+		if do.Resource() != nil {
+			entry.Resource = do.Resource().Name()
+		}
+	}
+
+	if !exp.opts.SkipMeta {
+		mc, err := obj.Meta()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range mc.Metas {
+			entry.Metas = append(entry.Metas, SnapshotAVU{
+				Attribute: m.Attribute,
+				Value:     m.Value,
+				Units:     m.Units,
+			})
+		}
+	}
+
+	return entry, nil
+}
+
+// LoadSnapshot opens a snapshot previously written with Exporter.ExportTo or a SnapshotWriter. Only the header
+// is read, entries are read from the file by each query.
+func LoadSnapshot(localPath string) (*Snapshot, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Load Snapshot Failed: %v", err))
+	}
+	defer f.Close()
+
+	snap := new(Snapshot)
+
+	if err := json.NewDecoder(f).Decode(snap); err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("Load Snapshot Failed: %v", err))
+	}
+
+	snap.path = localPath
+
+	return snap, nil
+}
+
+// Each calls fn with every entry of the snapshot, in the order they were exported, until fn returns an error.
+// That error is returned.
+func (snap *Snapshot) Each(fn func(*SnapshotEntry) error) error {
+	f, err := os.Open(snap.path)
+	if err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Load Snapshot Failed: %v", err))
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+
+	// Skip the header
+	if err := dec.Decode(new(Snapshot)); err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("Load Snapshot Failed: %v", err))
+	}
+
+	for {
+		entry := new(SnapshotEntry)
+
+		if err := dec.Decode(entry); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("Load Snapshot Failed: %v", err))
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// errEntryFound stops Each once Get found its entry
+var errEntryFound = errors.New("gorods: entry found")
+
+// Get returns the entry recorded for the absolute path specified, or nil if it isn't in the snapshot
+func (snap *Snapshot) Get(path string) (*SnapshotEntry, error) {
+	path = strings.TrimRight(path, "/")
+
+	var found *SnapshotEntry
+
+	err := snap.Each(func(entry *SnapshotEntry) error {
+		if entry.Path == path {
+			found = entry
+			return errEntryFound
+		}
+
+		return nil
+	})
+	if err != nil && err != errEntryFound {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// Query returns every entry for which filter returns true, in the order they were exported
+func (snap *Snapshot) Query(filter func(*SnapshotEntry) bool) ([]*SnapshotEntry, error) {
+	response := make([]*SnapshotEntry, 0)
+
+	err := snap.Each(func(entry *SnapshotEntry) error {
+		if filter(entry) {
+			response = append(response, entry)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Under returns every entry contained within the collection path specified, recursively
+func (snap *Snapshot) Under(path string) ([]*SnapshotEntry, error) {
+	prefix := strings.TrimRight(path, "/") + "/"
+
+	return snap.Query(func(entry *SnapshotEntry) bool {
+		return strings.HasPrefix(entry.Path, prefix)
+	})
+}
+
+// FindByMeta returns every entry that has an AVU matching attr and value. An empty value matches any value.
+func (snap *Snapshot) FindByMeta(attr string, value string) ([]*SnapshotEntry, error) {
+	return snap.Query(func(entry *SnapshotEntry) bool {
+		return entry.HasMeta(attr, value)
+	})
+}
+
+// HasMeta returns true if the entry has an AVU matching attr and value. An empty value matches any value.
+func (entry *SnapshotEntry) HasMeta(attr string, value string) bool {
+	for _, m := range entry.Metas {
+		if m.Attribute == attr && (value == "" || m.Value == value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TotalSnapshotSize returns the sum of the sizes of all data objects in the slice
+func TotalSnapshotSize(entries []*SnapshotEntry) int64 {
+	var total int64
+
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	return total
+}
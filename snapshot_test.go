@@ -0,0 +1,76 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotWriteLoadQuery(t *testing.T) {
+	entries := []*SnapshotEntry{
+		{Path: "/tempZone/home/rods", Name: "rods", Type: CollectionType},
+		{Path: "/tempZone/home/rods/hello.txt", Name: "hello.txt", Type: DataObjType, Size: 14, Metas: []SnapshotAVU{{Attribute: "test", Value: "test"}}},
+		{Path: "/tempZone/home/rods/sub/a.txt", Name: "a.txt", Type: DataObjType, Size: 6},
+	}
+
+	dir, err := ioutil.TempDir("", "gorods-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "snap.json")
+
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sw, err := NewSnapshotWriter(f, "tempZone", []string{"/tempZone/home/rods"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		if err := sw.Write(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := sw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	loaded, err := LoadSnapshot(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Zone != "tempZone" || len(loaded.Roots) != 1 {
+		t.Errorf("Unexpected header %+v", loaded)
+	}
+
+	if e, err := loaded.Get("/tempZone/home/rods/hello.txt"); err != nil || e == nil || e.Size != 14 {
+		t.Errorf("Expected hello.txt entry with size 14, got %v, %v", e, err)
+	}
+
+	if e, err := loaded.Get("/tempZone/home/rods/missing.txt"); err != nil || e != nil {
+		t.Errorf("Expected no entry for a missing path, got %v, %v", e, err)
+	}
+
+	if found, _ := loaded.FindByMeta("test", "test"); len(found) != 1 {
+		t.Errorf("Expected 1 entry with meta test=test, got %v", len(found))
+	}
+
+	if under, _ := loaded.Under("/tempZone/home/rods"); TotalSnapshotSize(under) != 20 {
+		t.Errorf("Expected total size 20, got %v", TotalSnapshotSize(under))
+	}
+}
@@ -0,0 +1,125 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"github.com/jjacquay712/GoRODS/api"
+)
+
+// ObjStat contains the system metadata of a data object or collection, see api.ObjStat
+type ObjStat = api.ObjStat
+
+// ObjStat returns the system metadata for the iRODS path specified, which can be either a data object or a collection.
+// The object doesn't need to be opened, or loaded into a *DataObj or *Collection beforehand.
+// Results are served from ConnectionOptions.Cache when set.
+func (con *Connection) ObjStat(p string) (*ObjStat, error) {
+	p = strings.TrimRight(p, "/")
+
+	if v, ok := con.cacheGet(CacheStat, p); ok {
+		stat := *(v.(*ObjStat))
+		return &stat, nil
+	}
+
+	stat, err := con.objStat(p)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := *stat
+	con.cacheSet(CacheStat, p, &cached)
+
+	return stat, nil
+}
+
+func (con *Connection) objStat(p string) (*ObjStat, error) {
+	var (
+		err        *C.char
+		statResult *C.rodsObjStat_t
+	)
+
+	path := C.CString(p)
+	defer C.free(unsafe.Pointer(path))
+
+	ccon := con.GetCcon()
+
+	if status := C.gorods_stat_dataobject(path, &statResult, ccon, &err); status != 0 {
+		con.ReturnCcon(ccon)
+		return nil, newError(Fatal, status, fmt.Sprintf("iRODS Stat Failed: %v, %v", p, C.GoString(err)))
+	}
+
+	con.ReturnCcon(ccon)
+
+	defer C.freeRodsObjStat(statResult)
+
+	stat := new(ObjStat)
+
+	stat.Path = strings.TrimRight(p, "/")
+	stat.Size = int64(statResult.objSize)
+	stat.DataMode = int(statResult.dataMode)
+	stat.DataId = C.GoString(&statResult.dataId[0])
+	stat.Checksum = C.GoString(&statResult.chksum[0])
+	stat.OwnerName = C.GoString(&statResult.ownerName[0])
+	stat.OwnerZone = C.GoString(&statResult.ownerZone[0])
+	stat.CreateTime = cTimeToTime(&statResult.createTime[0])
+	stat.ModifyTime = cTimeToTime(&statResult.modifyTime[0])
+	stat.RescHier = C.GoString(&statResult.rescHier[0])
+
+	if statResult.objType == C.DATA_OBJ_T {
+		stat.Type = DataObjType
+
+		if er := con.statReplicas(stat); er != nil {
+			return nil, er
+		}
+	} else if statResult.objType == C.COLL_OBJ_T {
+		stat.Type = CollectionType
+	} else {
+		stat.Type = UnknownType
+	}
+
+	return stat, nil
+}
+
+// statReplicas fills the DataType and ReplicaCount fields, which aren't returned by rcObjStat
+func (con *Connection) statReplicas(stat *ObjStat) error {
+	collLit, err := queryLiteral(filepath.Dir(stat.Path))
+	if err != nil {
+		return err
+	}
+
+	nameLit, err := queryLiteral(filepath.Base(stat.Path))
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("select DATA_TYPE_NAME, DATA_REPL_NUM where COLL_NAME = %v and DATA_NAME = %v", collLit, nameLit)
+
+	zone, err := con.zoneHint(stat.Path)
+	if err != nil {
+		return err
+	}
+
+	result, err := con.IQuestZone(query, false, zone)
+	if err != nil {
+		return err
+	}
+
+	stat.ReplicaCount = len(result)
+
+	if len(result) > 0 {
+		stat.DataType = result[0]["DATA_TYPE_NAME"]
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Structured file formats, the data types understood by the server's structured file API (ibun -D)
+const (
+	FormatTar      = "tar"
+	FormatGzipTar  = "gzipTar"
+	FormatBzip2Tar = "bzip2Tar"
+	FormatZip      = "zip"
+)
+
+// StructFileOptions are used with DataObj.Extract and Collection.Bundle. Format is one of the Format* constants,
+// chosen from the archive name (.tar, .tar.gz, .tgz, .tar.bz2, .tbz2 or .zip) when empty. Resource can be a string
+// or *Resource, and is where the extracted files or the archive are stored. Force overwrites existing data objects.
+type StructFileOptions struct {
+	Format   string
+	Resource interface{}
+	Force    bool
+}
+
+// Extract unpacks the tar or zip data object into the collection at colPath and registers its contents (ibun -x).
+// Files are extracted on the server, no data is transferred to the client. Returns the collection.
+func (obj *DataObj) Extract(colPath string, opts StructFileOptions) (*Collection, error) {
+	format, err := structFileFormat(obj.name, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	colPath = strings.TrimRight(colPath, "/")
+
+	if err := obj.con.extractBundle(obj.path, colPath, format, opts.Resource, opts.Force); err != nil {
+		return nil, err
+	}
+
+	obj.con.InvalidateCache(colPath)
+
+	return obj.con.Collection(CollectionOptions{
+		Path:      colPath,
+		Recursive: false,
+		SkipCache: true,
+	})
+}
+
+// Bundle packs the collection and its contents into a tar or zip data object at objPath (ibun -c), on the server.
+// A relative objPath is created next to the collection. Returns the archive.
+func (col *Collection) Bundle(objPath string, opts StructFileOptions) (*DataObj, error) {
+	if !strings.HasPrefix(objPath, "/") {
+		objPath = path.Join(path.Dir(col.path), objPath)
+	}
+
+	format, err := structFileFormat(path.Base(objPath), opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := col.con.createBundle(objPath, col.path, format, opts.Resource, opts.Force); err != nil {
+		return nil, err
+	}
+
+	col.con.InvalidateCache(objPath)
+
+	return col.con.DataObject(objPath)
+}
+
+// structFileFormat returns format if set, otherwise the format matching the extension of name
+func structFileFormat(name string, format string) (string, error) {
+	switch format {
+	case FormatTar, FormatGzipTar, FormatBzip2Tar, FormatZip:
+		return format, nil
+	case "":
+	default:
+		return "", newError(Fatal, -1, fmt.Sprintf("iRODS Structured File Failed: unknown format %v", format))
+	}
+
+	lower := strings.ToLower(name)
+
+	for _, ext := range []struct {
+		suffix string
+		format string
+	}{
+		{".tar.gz", FormatGzipTar},
+		{".tgz", FormatGzipTar},
+		{".tar.bz2", FormatBzip2Tar},
+		{".tbz2", FormatBzip2Tar},
+		{".tar", FormatTar},
+		{".zip", FormatZip},
+	} {
+		if strings.HasSuffix(lower, ext.suffix) {
+			return ext.format, nil
+		}
+	}
+
+	return "", newError(Fatal, -1, fmt.Sprintf("iRODS Structured File Failed: can't tell the format of %v, set StructFileOptions.Format", name))
+}
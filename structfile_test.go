@@ -0,0 +1,32 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestStructFileFormat(t *testing.T) {
+	for name, expected := range map[string]string{
+		"run.tar":      FormatTar,
+		"run.TAR.GZ":   FormatGzipTar,
+		"run.tgz":      FormatGzipTar,
+		"run.tar.bz2":  FormatBzip2Tar,
+		"package.zip":  FormatZip,
+		"package.data": "",
+	} {
+		format, err := structFileFormat(name, "")
+
+		if format != expected || (expected == "") != (err != nil) {
+			t.Errorf("%v: expected %v, got %v, %v", name, expected, format, err)
+		}
+	}
+
+	if format, err := structFileFormat("package.data", FormatZip); err != nil || format != FormatZip {
+		t.Errorf("Expected the format set to be used, got %v, %v", format, err)
+	}
+}
@@ -0,0 +1,234 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jjacquay712/GoRODS/api"
+)
+
+// Listing diff kinds, used in ListingDiff.Kind
+const (
+	DiffAdded = iota
+	DiffRemoved
+	DiffChanged
+)
+
+// ListingEntry is a single item of a collection listing, as seen by Collection.Subscribe, see api.ListingEntry
+type ListingEntry = api.ListingEntry
+
+// ListingDiff describes how one entry of the listing changed since the previous poll.
+// For DiffRemoved, Entry holds the last known state of the removed item.
+type ListingDiff struct {
+	Kind  int
+	Entry ListingEntry
+}
+
+// String returns a short description of the diff, like "+ report.csv"
+func (d ListingDiff) String() string {
+	prefix := map[int]string{
+		DiffAdded:   "+",
+		DiffRemoved: "-",
+		DiffChanged: "~",
+	}
+
+	return fmt.Sprintf("%v %v", prefix[d.Kind], d.Entry.Name)
+}
+
+// ListingUpdate is sent by a Subscription whenever a poll finds changes, or fails
+type ListingUpdate struct {
+	Time  time.Time
+	Diffs []ListingDiff
+	Err   error
+}
+
+// Subscription delivers incremental listing diffs of a collection, see Collection.Subscribe
+type Subscription struct {
+	C <-chan ListingUpdate
+
+	col      *Collection
+	interval time.Duration
+	prev     map[string]ListingEntry
+	polled   bool
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Subscribe polls the direct children of the collection every interval, and sends the added, removed and changed
+// entries relative to the previous poll on Subscription.C. The first update lists every entry as added, so it can be
+// used to render the initial listing. Updates are only sent when something changed or a poll failed.
+// Changes found by later polls are passed to Connection.ObserveChange, so cached entries don't go stale.
+// Call Close to stop polling.
+func (col *Collection) Subscribe(interval time.Duration) *Subscription {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	c := make(chan ListingUpdate, 1)
+
+	sub := &Subscription{
+		C:        c,
+		col:      col,
+		interval: interval,
+		prev:     make(map[string]ListingEntry),
+		done:     make(chan struct{}),
+	}
+
+	go sub.run(c)
+
+	return sub
+}
+
+// Close stops polling and closes Subscription.C. It is safe to call more than once.
+func (sub *Subscription) Close() {
+	sub.once.Do(func() {
+		close(sub.done)
+	})
+}
+
+func (sub *Subscription) run(c chan<- ListingUpdate) {
+	defer close(c)
+
+	ticker := time.NewTicker(sub.interval)
+	defer ticker.Stop()
+
+	for {
+		if update, changed := sub.poll(); changed {
+			select {
+			case c <- update:
+			case <-sub.done:
+				return
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+func (sub *Subscription) poll() (ListingUpdate, bool) {
+	update := ListingUpdate{Time: time.Now()}
+
+	current, err := sub.col.con.listEntries(sub.col.path)
+	if err != nil {
+		update.Err = err
+		return update, true
+	}
+
+	update.Diffs = diffListings(sub.prev, current)
+	sub.prev = current
+
+	// The first update lists the collection, it doesn't report changes
+	if sub.polled && len(update.Diffs) > 0 {
+		paths := make([]string, len(update.Diffs))
+		for i, d := range update.Diffs {
+			paths[i] = d.Entry.Path
+		}
+
+		sub.col.con.ObserveChange(ChangeSourceSubscription, paths...)
+	}
+
+	sub.polled = true
+
+	return update, len(update.Diffs) > 0
+}
+
+// sameListingEntry returns true if a and b describe the same state of an item. ModifyTime is compared with Equal,
+// like every time.Time: == also compares their locations and monotonic clock readings.
+func sameListingEntry(a ListingEntry, b ListingEntry) bool {
+	return a.Name == b.Name && a.Path == b.Path && a.Type == b.Type && a.Size == b.Size && a.Checksum == b.Checksum &&
+		a.ModifyTime.Equal(b.ModifyTime)
+}
+
+// diffListings returns the diffs between two listings keyed by name, sorted by name
+func diffListings(prev map[string]ListingEntry, current map[string]ListingEntry) []ListingDiff {
+	diffs := make([]ListingDiff, 0)
+
+	for name, entry := range current {
+		if old, ok := prev[name]; !ok {
+			diffs = append(diffs, ListingDiff{Kind: DiffAdded, Entry: entry})
+		} else if !sameListingEntry(old, entry) {
+			diffs = append(diffs, ListingDiff{Kind: DiffChanged, Entry: entry})
+		}
+	}
+
+	for name, entry := range prev {
+		if _, ok := current[name]; !ok {
+			diffs = append(diffs, ListingDiff{Kind: DiffRemoved, Entry: entry})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Entry.Name < diffs[j].Entry.Name
+	})
+
+	return diffs
+}
+
+// listEntries returns the direct children of the collection at p using general queries, bypassing the collection cache
+func (con *Connection) listEntries(p string) (map[string]ListingEntry, error) {
+	zone, err := con.zoneHint(p)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]ListingEntry)
+
+	cols, err := con.IQuestZone(fmt.Sprintf("select COLL_NAME, COLL_MODIFY_TIME where COLL_PARENT_NAME = '%v'", p), false, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range cols {
+		name := path.Base(row["COLL_NAME"])
+
+		entries[name] = ListingEntry{
+			Name:       name,
+			Path:       row["COLL_NAME"],
+			Type:       CollectionType,
+			ModifyTime: timeStringToTime(row["COLL_MODIFY_TIME"]),
+		}
+	}
+
+	objs, err := con.IQuestZone(fmt.Sprintf("select DATA_NAME, DATA_SIZE, DATA_CHECKSUM, DATA_MODIFY_TIME where COLL_NAME = '%v'", p), false, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	// One row per distinct replica state, keep the most recently modified
+	for _, row := range objs {
+		size, _ := strconv.ParseInt(row["DATA_SIZE"], 10, 64)
+
+		entry := ListingEntry{
+			Name:       row["DATA_NAME"],
+			Path:       p + "/" + row["DATA_NAME"],
+			Type:       DataObjType,
+			Size:       size,
+			Checksum:   row["DATA_CHECKSUM"],
+			ModifyTime: timeStringToTime(row["DATA_MODIFY_TIME"]),
+		}
+
+		if old, ok := entries[entry.Name]; ok {
+			if old.ModifyTime.After(entry.ModifyTime) || (old.ModifyTime.Equal(entry.ModifyTime) && old.Checksum >= entry.Checksum) {
+				continue
+			}
+		}
+
+		entries[entry.Name] = entry
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,52 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffListings(t *testing.T) {
+	prev := map[string]ListingEntry{
+		"a.txt": {Name: "a.txt", Type: DataObjType, Size: 1},
+		"b.txt": {Name: "b.txt", Type: DataObjType, Size: 2},
+		"sub":   {Name: "sub", Type: CollectionType},
+	}
+
+	current := map[string]ListingEntry{
+		"a.txt": {Name: "a.txt", Type: DataObjType, Size: 1},
+		"b.txt": {Name: "b.txt", Type: DataObjType, Size: 3},
+		"c.txt": {Name: "c.txt", Type: DataObjType, Size: 4},
+	}
+
+	diffs := diffListings(prev, current)
+
+	expected := []string{"~ b.txt", "+ c.txt", "- sub"}
+
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %v diffs, got %v", len(expected), diffs)
+	}
+
+	for i, d := range diffs {
+		if d.String() != expected[i] {
+			t.Errorf("Expected diff '%v', got '%v'", expected[i], d.String())
+		}
+	}
+
+	if len(diffListings(current, current)) != 0 {
+		t.Error("Expected no diffs between identical listings")
+	}
+
+	modified := time.Date(2016, 5, 4, 12, 0, 0, 0, time.UTC)
+	utc := map[string]ListingEntry{"a.txt": {Name: "a.txt", ModifyTime: modified}}
+	local := map[string]ListingEntry{"a.txt": {Name: "a.txt", ModifyTime: modified.In(time.FixedZone("CEST", 2*3600))}}
+
+	if diffs := diffListings(utc, local); len(diffs) != 0 {
+		t.Errorf("Expected the same instant in another location not to be a change, got %v", diffs)
+	}
+}
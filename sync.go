@@ -0,0 +1,538 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sync direction constants, used in SyncOptions.Direction
+const (
+	SyncUpload = iota
+	SyncDownload
+	SyncBidirectional
+)
+
+// Sync action constants, used in SyncAction.Op
+const (
+	SyncPut = iota
+	SyncGet
+	SyncMkdirRemote
+	SyncMkdirLocal
+	SyncDeleteRemote
+	SyncDeleteLocal
+)
+
+// SyncOptions are used with Connection.Sync. Direction is SyncUpload (default), SyncDownload or SyncBidirectional.
+// Checksum compares checksums of files with equal sizes, otherwise modification times are compared.
+// Delete removes files and collections from the target that don't exist in the source (ignored for SyncBidirectional).
+// DryRun only plans the actions, without transferring or deleting anything. Resource can be a string or *Resource.
+// PreserveTimes sets the modification time of uploaded data objects to the local file's (downloaded files always
+// get the data object's time).
+// NoOverwrite makes uploads of files whose data object already exists fail, like iput without -f, instead of
+// replacing the data object.
+// Verify checks every transferred file against the checksum of its data object once transferred (the server computes
+// it if the catalog has none). Mismatches fail the action, SyncReport.Verified counts the files that matched.
+type SyncOptions struct {
+	Direction     int
+	Checksum      bool
+	Delete        bool
+	DryRun        bool
+	Resource      interface{}
+	PreserveTimes bool
+	NoOverwrite   bool
+	Verify        bool
+}
+
+// SyncAction is a single step of a synchronization. Err is set if the action failed. Checksum is set for
+// transfers checked with SyncOptions.Verify.
+type SyncAction struct {
+	Op        int
+	LocalPath string
+	RodsPath  string
+	Size      int64
+	Reason    string
+	Checksum  string
+	Err       error
+}
+
+// String returns a short description of the action, like "put /local/a.txt -> /tempZone/home/rods/a.txt (missing)"
+func (a *SyncAction) String() string {
+	var s string
+
+	switch a.Op {
+	case SyncPut:
+		s = fmt.Sprintf("put %v -> %v", a.LocalPath, a.RodsPath)
+	case SyncGet:
+		s = fmt.Sprintf("get %v -> %v", a.RodsPath, a.LocalPath)
+	case SyncMkdirRemote:
+		s = fmt.Sprintf("mkdir %v", a.RodsPath)
+	case SyncMkdirLocal:
+		s = fmt.Sprintf("mkdir %v", a.LocalPath)
+	case SyncDeleteRemote:
+		s = fmt.Sprintf("delete %v", a.RodsPath)
+	case SyncDeleteLocal:
+		s = fmt.Sprintf("delete %v", a.LocalPath)
+	}
+
+	if a.Reason != "" {
+		s += " (" + a.Reason + ")"
+	}
+
+	if a.Err != nil {
+		s += ": " + a.Err.Error()
+	}
+
+	return s
+}
+
+// SyncReport is returned by Connection.Sync. Unchanged is the number of files that didn't need a transfer.
+// Bytes is the total size of transferred files. Verified is the number of transfers checked with SyncOptions.Verify.
+type SyncReport struct {
+	Actions   []*SyncAction
+	Unchanged int
+	Bytes     int64
+	Verified  int
+	DryRun    bool
+}
+
+// Failed returns the actions that returned an error
+func (r *SyncReport) Failed() []*SyncAction {
+	failed := make([]*SyncAction, 0)
+
+	for _, a := range r.Actions {
+		if a.Err != nil {
+			failed = append(failed, a)
+		}
+	}
+
+	return failed
+}
+
+type syncEntry struct {
+	isDir   bool
+	size    int64
+	modTime time.Time
+	obj     *DataObj
+}
+
+// Sync synchronizes the local directory localDir with the iRODS collection collection, like irsync.
+// Only files that are missing or changed (by size, then checksum or modification time) are transferred.
+// The returned report lists every action taken (or planned, with SyncOptions.DryRun). Failed transfers don't stop
+// the synchronization, check SyncReport.Failed().
+func (con *Connection) Sync(localDir string, collection string, opts SyncOptions) (*SyncReport, error) {
+	localDir = filepath.Clean(localDir)
+	collection = strings.TrimRight(collection, "/")
+
+	local, err := localTree(localDir)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Sync Failed: %v", err))
+	}
+
+	remote, err := con.remoteTree(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	if local == nil && opts.Direction == SyncUpload {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Sync Failed: %v doesn't exist", localDir))
+	}
+
+	if remote == nil && opts.Direction == SyncDownload {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Sync Failed: %v doesn't exist", collection))
+	}
+
+	report := planSync(localDir, collection, local, remote, opts)
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for _, a := range report.Actions {
+		if a.Err != nil {
+			continue
+		}
+
+		a.Err = con.runSyncAction(a, opts)
+
+		if a.Err == nil && (a.Op == SyncPut || a.Op == SyncGet) {
+			report.Bytes += a.Size
+
+			if opts.Verify {
+				if a.Checksum, a.Err = con.verifySyncAction(a); a.Err == nil {
+					report.Verified++
+				}
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// planSync returns the report listing the actions that synchronize the local and remote trees, as returned by localTree
+// and remoteTree (nil if missing). With SyncOptions.DryRun, Bytes is the total size of the planned transfers.
+func planSync(localDir string, collection string, local map[string]*syncEntry, remote map[string]*syncEntry, opts SyncOptions) *SyncReport {
+	report := new(SyncReport)
+	report.DryRun = opts.DryRun
+
+	if local == nil {
+		local = map[string]*syncEntry{"": {isDir: true}}
+		report.add(&SyncAction{Op: SyncMkdirLocal, LocalPath: localDir, Reason: "missing"})
+	}
+
+	if remote == nil {
+		remote = map[string]*syncEntry{"": {isDir: true}}
+		report.add(&SyncAction{Op: SyncMkdirRemote, RodsPath: collection, Reason: "missing"})
+	}
+
+	var deletes []*SyncAction
+
+	for _, rel := range sortedKeys(local, remote) {
+		if rel == "" {
+			continue
+		}
+
+		l, r := local[rel], remote[rel]
+
+		localPath := filepath.Join(localDir, filepath.FromSlash(rel))
+		rodsPath := collection + "/" + rel
+
+		switch {
+		case l != nil && r == nil:
+			if opts.Direction == SyncDownload {
+				if opts.Delete {
+					deletes = append(deletes, &SyncAction{Op: SyncDeleteLocal, LocalPath: localPath, Reason: "extraneous"})
+				}
+			} else if l.isDir {
+				report.add(&SyncAction{Op: SyncMkdirRemote, RodsPath: rodsPath, Reason: "missing"})
+			} else {
+				report.add(&SyncAction{Op: SyncPut, LocalPath: localPath, RodsPath: rodsPath, Size: l.size, Reason: "missing"})
+			}
+		case l == nil && r != nil:
+			if opts.Direction == SyncUpload {
+				if opts.Delete {
+					deletes = append(deletes, &SyncAction{Op: SyncDeleteRemote, RodsPath: rodsPath, Reason: "extraneous"})
+				}
+			} else if r.isDir {
+				report.add(&SyncAction{Op: SyncMkdirLocal, LocalPath: localPath, Reason: "missing"})
+			} else {
+				report.add(&SyncAction{Op: SyncGet, LocalPath: localPath, RodsPath: rodsPath, Size: r.size, Reason: "missing"})
+			}
+		case l.isDir && r.isDir:
+			continue
+		case l.isDir != r.isDir:
+			report.add(&SyncAction{Op: SyncPut, LocalPath: localPath, RodsPath: rodsPath, Reason: "type mismatch",
+				Err: newError(Fatal, -1, fmt.Sprintf("iRODS Sync Failed: %v and %v aren't both files or directories", localPath, rodsPath))})
+		default:
+			op, reason, err := syncCompare(localPath, l, r, opts)
+
+			if err != nil {
+				report.add(&SyncAction{Op: op, LocalPath: localPath, RodsPath: rodsPath, Reason: reason, Err: err})
+			} else if reason == "" {
+				report.Unchanged++
+			} else if op == SyncPut {
+				report.add(&SyncAction{Op: SyncPut, LocalPath: localPath, RodsPath: rodsPath, Size: l.size, Reason: reason})
+			} else {
+				report.add(&SyncAction{Op: SyncGet, LocalPath: localPath, RodsPath: rodsPath, Size: r.size, Reason: reason})
+			}
+		}
+	}
+
+	// Delete children before their parents
+	for i := len(deletes) - 1; i >= 0; i-- {
+		report.add(deletes[i])
+	}
+
+	if opts.DryRun {
+		for _, a := range report.Actions {
+			if a.Op == SyncPut || a.Op == SyncGet {
+				report.Bytes += a.Size
+			}
+		}
+	}
+
+	return report
+}
+
+func (r *SyncReport) add(a *SyncAction) {
+	r.Actions = append(r.Actions, a)
+}
+
+// syncCompare decides whether a file present on both sides needs a transfer, and in which direction.
+// An empty reason means the files are considered equal.
+func syncCompare(localPath string, l *syncEntry, r *syncEntry, opts SyncOptions) (int, string, error) {
+	op := SyncPut
+	if opts.Direction == SyncDownload || (opts.Direction == SyncBidirectional && r.modTime.After(l.modTime)) {
+		op = SyncGet
+	}
+
+	if l.size != r.size {
+		return op, "size differs", nil
+	}
+
+	if opts.Checksum {
+		remoteSum := r.obj.Checksum()
+		if remoteSum == "" {
+			var err error
+			if remoteSum, err = r.obj.Chksum(); err != nil {
+				return op, "checksum", err
+			}
+		}
+
+		localSum, err := fileChecksum(localPath, remoteSum)
+		if err != nil {
+			return op, "checksum", newError(Fatal, -1, fmt.Sprintf("iRODS Sync Failed: %v", err))
+		}
+
+		if localSum != remoteSum {
+			return op, "checksum differs", nil
+		}
+
+		return op, "", nil
+	}
+
+	// iRODS only stores modification times with one second precision
+	lt, rt := l.modTime.Truncate(time.Second), r.modTime.Truncate(time.Second)
+
+	switch opts.Direction {
+	case SyncUpload:
+		if lt.After(rt) {
+			return op, "newer", nil
+		}
+	case SyncDownload:
+		if rt.After(lt) {
+			return op, "newer", nil
+		}
+	default:
+		if !lt.Equal(rt) {
+			return op, "newer", nil
+		}
+	}
+
+	return op, "", nil
+}
+
+func (con *Connection) runSyncAction(a *SyncAction, opts SyncOptions) error {
+	switch a.Op {
+	case SyncMkdirLocal:
+		if err := os.MkdirAll(a.LocalPath, 0755); err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Sync Failed: %v", err))
+		}
+	case SyncMkdirRemote:
+		parent, err := con.Collection(CollectionOptions{Path: path.Dir(a.RodsPath), SkipCache: true})
+		if err != nil {
+			return err
+		}
+
+		if _, err := parent.CreateSubCollection(path.Base(a.RodsPath)); err != nil {
+			return err
+		}
+	case SyncPut:
+		parent, err := con.Collection(CollectionOptions{Path: path.Dir(a.RodsPath), SkipCache: true})
+		if err != nil {
+			return err
+		}
+
+		obj, err := parent.Put(a.LocalPath, DataObjOptions{
+			Name:     path.Base(a.RodsPath),
+			Size:     a.Size,
+			Force:    !opts.NoOverwrite,
+			Resource: opts.Resource,
+		})
+		if err != nil {
+			return err
+		}
+
+		if opts.PreserveTimes {
+			info, err := os.Stat(a.LocalPath)
+			if err != nil {
+				return newError(Fatal, -1, fmt.Sprintf("iRODS Sync Failed: %v", err))
+			}
+
+			if err := obj.SetModifyTime(info.ModTime()); err != nil {
+				return err
+			}
+		}
+	case SyncGet:
+		obj, err := con.DataObject(a.RodsPath)
+		if err != nil {
+			return err
+		}
+
+		if err := downloadFile(obj, a.LocalPath); err != nil {
+			return err
+		}
+	case SyncDeleteLocal:
+		if err := os.RemoveAll(a.LocalPath); err != nil {
+			return newError(Fatal, -1, fmt.Sprintf("iRODS Sync Failed: %v", err))
+		}
+	case SyncDeleteRemote:
+		typ, err := con.PathType(a.RodsPath)
+		if err != nil {
+			return err
+		}
+
+		if typ == CollectionType {
+			col, err := con.Collection(CollectionOptions{Path: a.RodsPath, SkipCache: true})
+			if err != nil {
+				return err
+			}
+
+			return col.Delete(true)
+		}
+
+		obj, err := con.DataObject(a.RodsPath)
+		if err != nil {
+			return err
+		}
+
+		return obj.Delete(false)
+	}
+
+	return nil
+}
+
+// downloadFile streams the data object to localPath and sets the file's modification time to the object's
+func downloadFile(obj *DataObj, localPath string) error {
+	f, err := os.Create(localPath)
+	if err != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Download Failed: %v", err))
+	}
+
+	var wErr error
+
+	if obj.Size() > 0 {
+		err = obj.ReadChunk(1024000, func(chunk []byte) {
+			if wErr == nil {
+				_, wErr = f.Write(chunk)
+			}
+		})
+	}
+
+	if cErr := f.Close(); wErr == nil {
+		wErr = cErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if wErr != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Download Failed: %v", wErr))
+	}
+
+	return os.Chtimes(localPath, time.Now(), obj.ModifyTime())
+}
+
+// localTree returns every file and directory below localDir, keyed by slash separated relative path ("" is localDir itself).
+// Returns nil if localDir doesn't exist.
+func localTree(localDir string) (map[string]*syncEntry, error) {
+	if _, err := os.Stat(localDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	tree := make(map[string]*syncEntry)
+
+	err := filepath.Walk(localDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+
+		if rel == "." {
+			rel = ""
+		}
+
+		tree[filepath.ToSlash(rel)] = &syncEntry{
+			isDir:   info.IsDir(),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		}
+
+		return nil
+	})
+
+	return tree, err
+}
+
+// remoteTree returns every data object and collection below collection, keyed by relative path. Returns nil if collection doesn't exist.
+func (con *Connection) remoteTree(collection string) (map[string]*syncEntry, error) {
+	if typ, err := con.PathType(collection); IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	} else if typ != CollectionType {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Sync Failed: %v isn't a collection", collection))
+	}
+
+	col, err := con.Collection(CollectionOptions{Path: collection, SkipCache: true})
+	if err != nil {
+		return nil, err
+	}
+
+	tree := map[string]*syncEntry{"": {isDir: true}}
+
+	var walk func(col *Collection) error
+	walk = func(col *Collection) error {
+		objs, err := col.All()
+		if err != nil {
+			return err
+		}
+
+		for _, obj := range objs {
+			rel := strings.TrimPrefix(obj.Path(), collection+"/")
+
+			if obj.Type() == CollectionType {
+				tree[rel] = &syncEntry{isDir: true, modTime: obj.ModifyTime()}
+
+				if err := walk(obj.(*Collection)); err != nil {
+					return err
+				}
+			} else {
+				do := obj.(*DataObj)
+				tree[rel] = &syncEntry{size: do.Size(), modTime: do.ModifyTime(), obj: do}
+			}
+		}
+
+		return nil
+	}
+
+	return tree, walk(col)
+}
+
+// sortedKeys returns the union of the keys of a and b, sorted so parents come before their children
+func sortedKeys(a map[string]*syncEntry, b map[string]*syncEntry) []string {
+	keys := make([]string, 0, len(a)+len(b))
+
+	for k := range a {
+		keys = append(keys, k)
+	}
+
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
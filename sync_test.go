@@ -0,0 +1,196 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSyncCompare(t *testing.T) {
+	now := time.Date(2016, 5, 4, 12, 0, 0, 0, time.UTC)
+
+	local := &syncEntry{size: 5, modTime: now.Add(400 * time.Millisecond)}
+	remote := &syncEntry{size: 5, modTime: now}
+
+	// Sub-second differences are lost by iRODS and must not trigger a transfer in either direction
+	for _, dir := range []int{SyncUpload, SyncDownload, SyncBidirectional} {
+		if _, reason, err := syncCompare("", local, remote, SyncOptions{Direction: dir}); err != nil || reason != "" {
+			t.Errorf("Direction %v: expected equal files, got %q, %v", dir, reason, err)
+		}
+	}
+
+	newer := &syncEntry{size: 5, modTime: now.Add(2 * time.Second)}
+
+	if op, reason, _ := syncCompare("", newer, remote, SyncOptions{Direction: SyncUpload}); op != SyncPut || reason != "newer" {
+		t.Errorf("Expected a newer local file to be uploaded, got %v %q", op, reason)
+	}
+
+	if _, reason, _ := syncCompare("", newer, remote, SyncOptions{Direction: SyncDownload}); reason != "" {
+		t.Errorf("Expected an older data object not to be downloaded, got %q", reason)
+	}
+
+	if op, reason, _ := syncCompare("", remote, newer, SyncOptions{Direction: SyncDownload}); op != SyncGet || reason != "newer" {
+		t.Errorf("Expected a newer data object to be downloaded, got %v %q", op, reason)
+	}
+
+	if op, reason, _ := syncCompare("", remote, newer, SyncOptions{Direction: SyncBidirectional}); op != SyncGet || reason != "newer" {
+		t.Errorf("Expected the newer side to win a bidirectional sync, got %v %q", op, reason)
+	}
+
+	if op, reason, _ := syncCompare("", newer, remote, SyncOptions{Direction: SyncBidirectional}); op != SyncPut || reason != "newer" {
+		t.Errorf("Expected the newer side to win a bidirectional sync, got %v %q", op, reason)
+	}
+
+	if _, reason, _ := syncCompare("", &syncEntry{size: 6, modTime: now}, remote, SyncOptions{}); reason != "size differs" {
+		t.Errorf("Expected a size mismatch, got %q", reason)
+	}
+}
+
+func TestSyncCompareChecksum(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "a.txt")
+
+	if err := ioutil.WriteFile(localPath, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	local := &syncEntry{size: 5, modTime: now}
+	opts := SyncOptions{Checksum: true}
+
+	// Equal checksums win over differing modification times
+	same := &syncEntry{size: 5, modTime: now.Add(time.Hour), obj: &DataObj{checksum: "5d41402abc4b2a76b9719d911017c592"}}
+
+	if _, reason, err := syncCompare(localPath, local, same, opts); err != nil || reason != "" {
+		t.Errorf("Expected matching checksums to be equal, got %q, %v", reason, err)
+	}
+
+	differs := &syncEntry{size: 5, modTime: now, obj: &DataObj{checksum: "sha2:LCa0a2j/xo/5m0U8HTBBNBNCLXBkg7+g+YpeiGJm564="}}
+
+	if op, reason, err := syncCompare(localPath, local, differs, opts); err != nil || op != SyncPut || reason != "checksum differs" {
+		t.Errorf("Expected a checksum mismatch, got %v %q, %v", op, reason, err)
+	}
+}
+
+func TestLocalTree(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, "sub", "deeper"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := localTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tree) != 4 || !tree[""].isDir || !tree["sub"].isDir || !tree["sub/deeper"].isDir {
+		t.Fatalf("Unexpected tree %v", tree)
+	}
+
+	if f := tree["sub/a.txt"]; f == nil || f.isDir || f.size != 5 {
+		t.Errorf("Unexpected file entry %+v", f)
+	}
+
+	if tree, err := localTree(filepath.Join(dir, "missing")); tree != nil || err != nil {
+		t.Errorf("Expected no tree for a missing directory, got %v, %v", tree, err)
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	a := map[string]*syncEntry{"": {}, "b": {}, "b/c": {}, "a-x": {}}
+	b := map[string]*syncEntry{"": {}, "b": {}, "a": {}, "a/z": {}}
+
+	expected := []string{"", "a", "a-x", "a/z", "b", "b/c"}
+
+	if keys := sortedKeys(a, b); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+}
+
+func TestPlanSync(t *testing.T) {
+	now := time.Date(2016, 5, 4, 12, 0, 0, 0, time.UTC)
+
+	local := map[string]*syncEntry{
+		"":            {isDir: true},
+		"new.txt":     {size: 3, modTime: now},
+		"same.txt":    {size: 5, modTime: now},
+		"dir":         {isDir: true},
+		"dir/new.txt": {size: 7, modTime: now},
+	}
+
+	remote := map[string]*syncEntry{
+		"":              {isDir: true},
+		"same.txt":      {size: 5, modTime: now},
+		"old":           {isDir: true},
+		"old/sub":       {isDir: true},
+		"old/sub/a.txt": {size: 1, modTime: now},
+		"remote.txt":    {size: 2, modTime: now},
+	}
+
+	up := planSync("/local", "/z/c", local, remote, SyncOptions{Delete: true, DryRun: true})
+
+	expected := []string{
+		"mkdir /z/c/dir (missing)",
+		"put /local/dir/new.txt -> /z/c/dir/new.txt (missing)",
+		"put /local/new.txt -> /z/c/new.txt (missing)",
+		"delete /z/c/remote.txt (extraneous)",
+		"delete /z/c/old/sub/a.txt (extraneous)",
+		"delete /z/c/old/sub (extraneous)",
+		"delete /z/c/old (extraneous)",
+	}
+
+	if actions := syncActionStrings(up); !reflect.DeepEqual(actions, expected) {
+		t.Errorf("Expected upload actions %v, got %v", expected, actions)
+	}
+
+	if !up.DryRun || up.Unchanged != 1 || up.Bytes != 10 {
+		t.Errorf("Expected a dry run of 10 bytes with one unchanged file, got %+v", up)
+	}
+
+	down := planSync("/local", "/z/c", local, remote, SyncOptions{Direction: SyncDownload})
+
+	expected = []string{
+		"mkdir /local/old (missing)",
+		"mkdir /local/old/sub (missing)",
+		"get /z/c/old/sub/a.txt -> /local/old/sub/a.txt (missing)",
+		"get /z/c/remote.txt -> /local/remote.txt (missing)",
+	}
+
+	if actions := syncActionStrings(down); !reflect.DeepEqual(actions, expected) {
+		t.Errorf("Expected download actions %v, got %v", expected, actions)
+	}
+
+	// Only dry runs report the planned bytes, real runs count what was transferred
+	if down.DryRun || down.Bytes != 0 {
+		t.Errorf("Expected no bytes for a planned run, got %+v", down)
+	}
+
+	missing := planSync("/local", "/z/c", local, nil, SyncOptions{DryRun: true})
+
+	if len(missing.Actions) == 0 || missing.Actions[0].String() != "mkdir /z/c (missing)" {
+		t.Errorf("Expected the missing collection to be created first, got %v", syncActionStrings(missing))
+	}
+}
+
+func syncActionStrings(r *SyncReport) []string {
+	s := make([]string, 0, len(r.Actions))
+
+	for _, a := range r.Actions {
+		s = append(s, a.String())
+	}
+
+	return s
+}
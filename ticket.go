@@ -0,0 +1,111 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+	"unsafe"
+)
+
+// Ticket types used by CreateTicket
+const (
+	TicketRead  = "read"
+	TicketWrite = "write"
+)
+
+// ticketChars are the characters used in generated ticket strings, the same alphabet iticket uses
+const ticketChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// ticketLength is the length of generated ticket strings
+const ticketLength = 15
+
+// NewTicketString returns a random ticket string suitable for CreateTicket
+func NewTicketString() (string, error) {
+	b := make([]byte, ticketLength)
+	max := big.NewInt(int64(len(ticketChars)))
+
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", newError(Fatal, -1, fmt.Sprintf("Unable to generate ticket: %v", err))
+		}
+		b[i] = ticketChars[n.Int64()]
+	}
+
+	return string(b), nil
+}
+
+// CreateTicket creates a ticket of the type specified (TicketRead or TicketWrite) for the data object or
+// collection at p, and returns the ticket string (iticket create)
+func (con *Connection) CreateTicket(p string, typ string) (string, error) {
+	if typ != TicketRead && typ != TicketWrite {
+		return "", newError(Fatal, -1, fmt.Sprintf("iRODS Create Ticket Failed: unknown ticket type %q", typ))
+	}
+
+	ticket, err := NewTicketString()
+	if err != nil {
+		return "", err
+	}
+
+	if err := con.ticketAdmin("create", ticket, typ, p, ticket, ""); err != nil {
+		return "", err
+	}
+
+	return ticket, nil
+}
+
+// ModifyTicket changes a setting of the ticket (iticket mod), for example "uses", "expire", or "add host"
+func (con *Connection) ModifyTicket(ticket string, setting string, value string) error {
+	return con.ticketAdmin("mod", ticket, setting, value, "", "")
+}
+
+// SetTicketExpiry sets the time after which the ticket can no longer be used. A zero time removes the expiry.
+func (con *Connection) SetTicketExpiry(ticket string, t time.Time) error {
+	if t.IsZero() {
+		return con.ModifyTicket(ticket, "expire", "0")
+	}
+
+	return con.ModifyTicket(ticket, "expire", RuleTime(t))
+}
+
+// SetTicketUses limits the number of times the ticket can be used. A limit of 0 removes the restriction.
+func (con *Connection) SetTicketUses(ticket string, uses int) error {
+	return con.ModifyTicket(ticket, "uses", strconv.Itoa(uses))
+}
+
+// DeleteTicket deletes the ticket (iticket delete)
+func (con *Connection) DeleteTicket(ticket string) error {
+	return con.ticketAdmin("delete", ticket, "", "", "", "")
+}
+
+func (con *Connection) ticketAdmin(args ...string) error {
+	var (
+		err   *C.char
+		cArgs [6]*C.char
+	)
+
+	for i := range cArgs {
+		cArgs[i] = C.CString(args[i])
+		defer C.free(unsafe.Pointer(cArgs[i]))
+	}
+
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	if status := C.gorods_ticket_admin(cArgs[0], cArgs[1], cArgs[2], cArgs[3], cArgs[4], cArgs[5], ccon, &err); status < 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS Ticket %v Failed: %v, %v", args[0], args[1], C.GoString(err)))
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// setSocketTimeouts applies ConnectionOptions.ReadTimeout and WriteTimeout to the socket of con.ccon
+func (con *Connection) setSocketTimeouts() error {
+	if con.Options.ReadTimeout <= 0 && con.Options.WriteTimeout <= 0 {
+		return nil
+	}
+
+	read := C.int(con.Options.ReadTimeout / time.Millisecond)
+	write := C.int(con.Options.WriteTimeout / time.Millisecond)
+
+	if status := C.gorods_set_socket_timeouts(con.ccon, read, write); status < 0 {
+		return newError(Fatal, -1, "iRODS Connect Failed: Unable to set the socket timeouts")
+	}
+
+	return nil
+}
+
+// observeDuration is called by intercept once an operation ran, slow operations are reported
+func (con *Connection) observeDuration(e *Event) {
+	if con.Options == nil {
+		return
+	}
+
+	if slow := con.Options.SlowOperation; slow > 0 && e.Duration >= slow {
+		if con.Options.SlowLog != nil {
+			con.Options.SlowLog(e)
+		} else {
+			con.logf("slow %v", describeEvent(e))
+		}
+	}
+}
+
+// observeCheckout is called by ReturnCcon with the time the handle was checked out. A call holding it as long as a
+// socket timeout may have timed out, leaving the protocol stream out of sync: the handle is marked as suspect, so it
+// is pinged, and replaced if needed, before its next use. Long transfers are marked too, which only costs a ping.
+func (con *Connection) observeCheckout(held time.Duration) {
+	if con.Options != nil && timedOut(held, con.Options.ReadTimeout, con.Options.WriteTimeout) {
+		atomic.StoreInt32(&con.suspect, 1)
+	}
+}
+
+// timedOut returns true if an operation lasting d may have hit one of the timeouts
+func timedOut(d time.Duration, timeouts ...time.Duration) bool {
+	for _, t := range timeouts {
+		if t > 0 && d >= t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// describeEvent returns a one line description of the operation, like "put /tempZone/home/rods/a.txt (1024 bytes) took 2.5s"
+func describeEvent(e *Event) string {
+	s := opName(e.Op)
+
+	if e.Path != "" {
+		s += " " + e.Path
+	}
+
+	if e.Dest != "" {
+		s += " -> " + e.Dest
+	}
+
+	if e.Query != "" {
+		s += " " + strconv.Quote(e.Query)
+	}
+
+	if e.Size > 0 {
+		s += fmt.Sprintf(" (%v bytes)", e.Size)
+	}
+
+	s += fmt.Sprintf(" took %v", e.Duration)
+
+	if e.Err != nil {
+		s += fmt.Sprintf(", failed: %v", e.Err)
+	}
+
+	return s
+}
@@ -0,0 +1,49 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObserveDuration(t *testing.T) {
+	var slow []*Event
+
+	con := &Connection{Options: &ConnectionOptions{
+		SlowOperation: time.Second,
+		ReadTimeout:   5 * time.Second,
+		SlowLog:       func(e *Event) { slow = append(slow, e) },
+	}}
+
+	con.observeDuration(&Event{Op: OpQuery, Duration: 10 * time.Millisecond})
+	con.observeDuration(&Event{Op: OpPut, Path: "/z/a.txt", Size: 1024, Duration: 2 * time.Second})
+
+	if len(slow) != 1 || slow[0].Path != "/z/a.txt" {
+		t.Fatalf("Expected the put to be reported as slow, got %v", slow)
+	}
+
+	if s := describeEvent(slow[0]); s != "put /z/a.txt (1024 bytes) took 2s" {
+		t.Errorf("Unexpected description %q", s)
+	}
+}
+
+func TestObserveCheckout(t *testing.T) {
+	con := &Connection{Options: &ConnectionOptions{ReadTimeout: 5 * time.Second}}
+
+	con.observeCheckout(10 * time.Millisecond)
+
+	if con.suspect != 0 {
+		t.Error("A short call shouldn't mark the handle as suspect")
+	}
+
+	con.observeCheckout(5 * time.Second)
+
+	if con.suspect != 1 {
+		t.Error("A call lasting the read timeout should mark the handle as suspect, whether it was hooked or not")
+	}
+}
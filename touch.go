@@ -0,0 +1,161 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+	"unsafe"
+)
+
+// TouchOptions are used with Connection.Touch. Time is the new modification time, the current time if zero.
+// NoCreate fails instead of creating an empty data object when p doesn't exist. Resource (string or *Resource) is
+// the leaf resource of the replica to update, the latest good replica otherwise.
+type TouchOptions struct {
+	Time     time.Time
+	NoCreate bool
+	Resource interface{}
+}
+
+// touchInput is the JSON input of the touch API
+type touchInput struct {
+	Path    string `json:"logical_path"`
+	Options struct {
+		NoCreate bool   `json:"no_create"`
+		Seconds  int64  `json:"seconds_since_epoch,omitempty"`
+		Resource string `json:"leaf_resource_name,omitempty"`
+	} `json:"options"`
+}
+
+// Touch sets the modification time of the data object or collection at p, like the touch command.
+// It uses the touch API of iRODS 4.2.9 and later servers. On older servers, the modification time of existing
+// data objects is set in the catalog instead, which requires a rodsadmin connection.
+func (con *Connection) Touch(p string, opts TouchOptions) error {
+	resc, err := resourceName(opts.Resource)
+	if err != nil {
+		return err
+	}
+
+	var input touchInput
+
+	input.Path = p
+	input.Options.NoCreate = opts.NoCreate
+	input.Options.Resource = resc
+
+	if !opts.Time.IsZero() {
+		input.Options.Seconds = opts.Time.Unix()
+	}
+
+	params := map[string]string{"no_create": strconv.FormatBool(opts.NoCreate)}
+	if !opts.Time.IsZero() {
+		params["time"] = strconv.FormatInt(opts.Time.Unix(), 10)
+	}
+
+	err = con.intercept(&Event{Op: OpTouch, Path: p, Params: params}, func() error {
+		return con.touch(input, opts.Time)
+	})
+
+	con.InvalidateCache(p)
+
+	return err
+}
+
+func (con *Connection) touch(input touchInput, t time.Time) error {
+	var errMsg *C.char
+
+	if con.lacks(func(c Capabilities) bool { return c.Touch }) {
+		return con.setModifyTime(input.Path, t)
+	}
+
+	js, er := json.Marshal(input)
+	if er != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Touch Failed: %v", er))
+	}
+
+	cInput := C.CString(string(js))
+	defer C.free(unsafe.Pointer(cInput))
+
+	ccon := con.GetCcon()
+	status := C.gorods_touch(cInput, ccon, &errMsg)
+	con.ReturnCcon(ccon)
+
+	if status == C.SYS_UNMATCHED_API_NUM {
+		return con.setModifyTime(input.Path, t)
+	}
+
+	if status < 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS Touch Failed: %v, %v", input.Path, C.GoString(errMsg)))
+	}
+
+	return nil
+}
+
+// setModifyTime sets the modification time of every replica of the data object at p in the catalog
+func (con *Connection) setModifyTime(p string, t time.Time) error {
+	var errMsg *C.char
+
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	if typ, err := con.PathType(p); err != nil {
+		return err
+	} else if typ != DataObjType {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Touch Failed: %v, setting the modification time of collections requires iRODS 4.2.9 or later", p))
+	}
+
+	cPath := C.CString(p)
+	cTime := C.CString(fmt.Sprintf("%011d", t.Unix()))
+	defer C.free(unsafe.Pointer(cPath))
+	defer C.free(unsafe.Pointer(cTime))
+
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	if status := C.gorods_set_modify_time(cPath, cTime, ccon, &errMsg); status < 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS Touch Failed: %v, %v", p, C.GoString(errMsg)))
+	}
+
+	return nil
+}
+
+// Touch sets the modification time of the data object to the current time
+func (obj *DataObj) Touch() error {
+	return obj.SetModifyTime(time.Now())
+}
+
+// SetModifyTime sets the modification time of the data object, for example to preserve the time of a local file
+func (obj *DataObj) SetModifyTime(t time.Time) error {
+	if err := obj.con.Touch(obj.path, TouchOptions{Time: t, NoCreate: true}); err != nil {
+		return err
+	}
+
+	obj.modifyTime = time.Unix(t.Unix(), 0)
+
+	return nil
+}
+
+// Touch sets the modification time of the collection to the current time
+func (col *Collection) Touch() error {
+	return col.SetModifyTime(time.Now())
+}
+
+// SetModifyTime sets the modification time of the collection. Requires an iRODS 4.2.9 or later server.
+func (col *Collection) SetModifyTime(t time.Time) error {
+	if err := col.con.Touch(col.path, TouchOptions{Time: t, NoCreate: true}); err != nil {
+		return err
+	}
+
+	col.modifyTime = time.Unix(t.Unix(), 0)
+
+	return nil
+}
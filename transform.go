@@ -0,0 +1,461 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jjacquay712/GoRODS/api"
+)
+
+// TransformAttr is the attribute of the AVU recording the transforms applied to a data object's contents,
+// as a comma separated list of their names in the order they were applied
+const TransformAttr = "gorods::transform"
+
+// Transform is a reversible transformation of data object contents, like compression or encryption, see api.Transform
+type Transform = api.Transform
+
+// ObjectTransform is implemented by transforms keeping per object state, like an encryption key, in AVUs.
+// Uploads use EncodeObject instead of Encode and store the AVUs it returns on the data object, downloads pass
+// the data object's AVUs to DecodeObject instead of calling Decode.
+type ObjectTransform interface {
+	Transform
+	EncodeObject(w io.Writer) (io.WriteCloser, []Meta, error)
+	DecodeObject(r io.Reader, metas Metas) (io.ReadCloser, error)
+}
+
+var (
+	transforms   = map[string]Transform{"gzip": GzipTransform{}}
+	transformsMu sync.RWMutex
+)
+
+// RegisterTransform makes t available to downloads of data objects it was applied to, replacing any transform
+// registered with the same name. GzipTransform is registered by default.
+func RegisterTransform(t Transform) {
+	transformsMu.Lock()
+	defer transformsMu.Unlock()
+
+	transforms[t.Name()] = t
+}
+
+// lookupTransform returns the registered transform called name
+func lookupTransform(name string) (Transform, error) {
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+
+	if t, ok := transforms[name]; ok {
+		return t, nil
+	}
+
+	return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Transform Failed: Unknown transform %v, register it with RegisterTransform", name))
+}
+
+// GzipTransform compresses contents with gzip. Level is a compress/gzip level, gzip.DefaultCompression if 0.
+type GzipTransform struct {
+	Level int
+}
+
+// Name returns "gzip"
+func (t GzipTransform) Name() string {
+	return "gzip"
+}
+
+// Encode returns a gzip writer
+func (t GzipTransform) Encode(w io.Writer) (io.WriteCloser, error) {
+	level := t.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	return gzip.NewWriterLevel(w, level)
+}
+
+// Decode returns a gzip reader
+func (t GzipTransform) Decode(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// transformNames returns the TransformAttr value of the pipeline
+func transformNames(pipeline []Transform) string {
+	names := make([]string, len(pipeline))
+
+	for i, t := range pipeline {
+		names[i] = t.Name()
+	}
+
+	return strings.Join(names, ",")
+}
+
+// parseTransforms returns the registered transforms listed in a TransformAttr value
+func parseTransforms(value string) ([]Transform, error) {
+	pipeline := make([]Transform, 0)
+
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		t, err := lookupTransform(name)
+		if err != nil {
+			return nil, err
+		}
+
+		pipeline = append(pipeline, t)
+	}
+
+	return pipeline, nil
+}
+
+// encodePipeline returns a writer applying the pipeline, first transform first, to the bytes written to w,
+// and the AVUs of its ObjectTransforms. Closing it flushes and closes every transform, but not w.
+func encodePipeline(w io.Writer, pipeline []Transform) (io.WriteCloser, []Meta, error) {
+	writers := make([]io.WriteCloser, len(pipeline))
+	metas := make([]Meta, 0)
+
+	for i := len(pipeline) - 1; i >= 0; i-- {
+		var (
+			enc io.WriteCloser
+			err error
+		)
+
+		if ot, ok := pipeline[i].(ObjectTransform); ok {
+			var m []Meta
+			enc, m, err = ot.EncodeObject(w)
+			metas = append(metas, m...)
+		} else {
+			enc, err = pipeline[i].Encode(w)
+		}
+
+		if err != nil {
+			return nil, nil, newError(Fatal, -1, fmt.Sprintf("iRODS Transform Failed: %v, %v", pipeline[i].Name(), err))
+		}
+
+		writers[i] = enc
+		w = enc
+	}
+
+	return &pipelineWriter{Writer: w, writers: writers}, metas, nil
+}
+
+// decodePipeline returns a reader reversing the pipeline on the bytes read from r. metas are the AVUs
+// of the data object, passed to ObjectTransforms.
+func decodePipeline(r io.Reader, pipeline []Transform, metas Metas) (io.ReadCloser, error) {
+	readers := make([]io.ReadCloser, 0, len(pipeline))
+
+	for i := len(pipeline) - 1; i >= 0; i-- {
+		var (
+			dec io.ReadCloser
+			err error
+		)
+
+		if ot, ok := pipeline[i].(ObjectTransform); ok {
+			dec, err = ot.DecodeObject(r, metas)
+		} else {
+			dec, err = pipeline[i].Decode(r)
+		}
+
+		if err != nil {
+			for _, rc := range readers {
+				rc.Close()
+			}
+
+			return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Transform Failed: %v, %v", pipeline[i].Name(), err))
+		}
+
+		readers = append(readers, dec)
+		r = dec
+	}
+
+	return &pipelineReader{Reader: r, readers: readers}, nil
+}
+
+type pipelineWriter struct {
+	io.Writer
+	writers []io.WriteCloser
+}
+
+// Close closes the transforms from the first to the last, so each flushes into the next
+func (pw *pipelineWriter) Close() error {
+	for _, w := range pw.writers {
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type pipelineReader struct {
+	io.Reader
+	readers []io.ReadCloser
+	obj     *DataObj
+}
+
+// Close closes the transforms and the data object
+func (pr *pipelineReader) Close() error {
+	var err error
+
+	for i := len(pr.readers) - 1; i >= 0; i-- {
+		if er := pr.readers[i].Close(); er != nil && err == nil {
+			err = er
+		}
+	}
+
+	if pr.obj != nil {
+		if er := pr.obj.Close(); er != nil && err == nil {
+			err = er
+		}
+	}
+
+	return err
+}
+
+// dataObjWriter writes to the data object at its offset pointer
+type dataObjWriter struct {
+	obj *DataObj
+	n   int64
+}
+
+func (w *dataObjWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if err := w.obj.writeNext(p); err != nil {
+		return 0, err
+	}
+
+	w.n += int64(len(p))
+
+	return len(p), nil
+}
+
+// transformPipeline returns the transforms Put applies with opts: opts.Transforms if not nil, the connection's otherwise
+func (con *Connection) transformPipeline(opts DataObjOptions) []Transform {
+	if opts.Transforms != nil || con.Options == nil {
+		return opts.Transforms
+	}
+
+	return con.Options.Transforms
+}
+
+// decodesTransforms returns true if the connection reverses the transforms of the data objects it downloads
+func (con *Connection) decodesTransforms() bool {
+	return con.Options != nil && (len(con.Options.Transforms) > 0 || con.Options.DecodeTransforms)
+}
+
+// putTransformed streams localPath through the pipeline into a new data object, and records the pipeline in its
+// TransformAttr AVU. The data object is written under a temporary name, and only renamed, replacing the existing
+// one with opts.Force, once its content and AVUs are complete.
+func (col *Collection) putTransformed(localPath string, opts DataObjOptions, pipeline []Transform, scan bool) (*DataObj, error) {
+	if opts.Name == "" {
+		opts.Name = filepath.Base(localPath)
+	}
+
+	if scan {
+		if err := col.con.scanFile(col.path+"/"+opts.Name, localPath); err != nil {
+			return nil, err
+		}
+	}
+
+	f, er := os.Open(localPath)
+	if er != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Put DataObject Failed: %v", er))
+	}
+	defer f.Close()
+
+	return col.replaceDataObj(opts.Name, opts.Force, func(tmpName string) (*DataObj, error) {
+		tmpOpts := opts
+		tmpOpts.Name = tmpName
+		tmpOpts.Force = false
+
+		obj, err := createDataObj(tmpOpts, col)
+		if err != nil {
+			return nil, err
+		}
+
+		out := &dataObjWriter{obj: obj}
+		buf := bufio.NewWriterSize(out, ArchiveChunkSize)
+
+		enc, metas, err := encodePipeline(buf, pipeline)
+		if err != nil {
+			obj.Close()
+			return obj, err
+		}
+
+		_, er := io.Copy(enc, f)
+		if er == nil {
+			er = enc.Close()
+		}
+		if er == nil {
+			er = buf.Flush()
+		}
+
+		if er != nil {
+			obj.Close()
+			return obj, newError(Fatal, -1, fmt.Sprintf("iRODS Put DataObject Failed: %v, %v", obj.path, er))
+		}
+
+		if err := obj.Close(); err != nil {
+			return obj, err
+		}
+
+		obj.size = out.n
+
+		// The AVUs of the transforms, like the wrapped key of an encryption, are added with the TransformAttr one in
+		// a single request
+		ops := make([]MetaOperation, 0, len(metas)+1)
+
+		for _, m := range metas {
+			ops = append(ops, MetaOperation{Operation: MetaOpAdd, Attribute: m.Attribute, Value: m.Value, Units: m.Units})
+		}
+
+		ops = append(ops, MetaOperation{Operation: MetaOpAdd, Attribute: TransformAttr, Value: transformNames(pipeline)})
+
+		return obj, col.con.ApplyMetaOperations(obj.path, DataObjType, ops)
+	})
+}
+
+// checkUntransformedWrite returns an error if content written to the data object at p would be stored as is
+// although the connection has transforms, like the encryption of a client-side encryption policy
+func (con *Connection) checkUntransformedWrite(p string) error {
+	if con.Options != nil && len(con.Options.Transforms) > 0 {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Write DataObject Failed: %v, the connection's transforms are only applied by Collection.Put", p))
+	}
+
+	return nil
+}
+
+// setTransformMeta sets the TransformAttr AVU of the data object to value, removing it if value is empty
+func setTransformMeta(obj *DataObj, value string) error {
+	return setSingleMeta(obj, TransformAttr, value, "")
+}
+
+// setSingleMeta makes value the only AVU of the data object with attribute attr, removing them all if value is empty
+func setSingleMeta(obj *DataObj, attr string, value string, units string) error {
+	mc, err := obj.Meta()
+	if err != nil {
+		return err
+	}
+
+	all, err := mc.All()
+	if err != nil {
+		return err
+	}
+
+	existing := make([]*Meta, 0)
+	for _, m := range all {
+		if m.Attribute == attr {
+			existing = append(existing, m)
+		}
+	}
+
+	if len(existing) == 0 {
+		if value != "" {
+			_, err = mc.Add(Meta{Attribute: attr, Value: value, Units: units})
+		}
+		return err
+	}
+
+	if value == "" {
+		return mc.Delete(attr)
+	}
+
+	if _, err := existing[0].SetAll(attr, value, units); err != nil {
+		return err
+	}
+
+	for _, m := range existing[1:] {
+		if _, err := m.Delete(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Transforms returns the transforms applied to the contents of the data object when it was uploaded, in the order
+// they were applied. It is empty for data objects stored as is.
+func (obj *DataObj) Transforms() ([]Transform, error) {
+	pipeline, _, err := obj.transforms()
+	return pipeline, err
+}
+
+// transforms returns the transforms of the data object along with all of its AVUs
+func (obj *DataObj) transforms() ([]Transform, Metas, error) {
+	mc, err := obj.Meta()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	all, err := mc.All()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, m := range all {
+		if m.Attribute == TransformAttr {
+			pipeline, err := parseTransforms(m.Value)
+			return pipeline, all, err
+		}
+	}
+
+	return nil, all, nil
+}
+
+// NewReader returns a reader of the data object's original contents: the transforms recorded in its TransformAttr
+// AVU are reversed as it is read. Closing the reader closes the data object.
+func (obj *DataObj) NewReader() (io.ReadCloser, error) {
+	pipeline, metas, err := obj.transforms()
+	if err != nil {
+		return nil, err
+	}
+
+	src := bufio.NewReaderSize(io.NewSectionReader(obj, 0, obj.size), ArchiveChunkSize)
+
+	rc, err := decodePipeline(src, pipeline, metas)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.(*pipelineReader).obj = obj
+
+	return rc, nil
+}
+
+// downloadDecoded streams the original contents of the data object to localPath
+func (obj *DataObj) downloadDecoded(localPath string) error {
+	r, err := obj.NewReader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f, er := os.Create(localPath)
+	if er != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Download DataObject Failed: %v, %v", obj.path, er))
+	}
+
+	_, er = io.Copy(f, r)
+
+	if cerr := f.Close(); er == nil {
+		er = cerr
+	}
+
+	if er != nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Download DataObject Failed: %v, %v", obj.path, er))
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestTransformPipeline(t *testing.T) {
+	pipeline := []Transform{GzipTransform{}, GzipTransform{Level: gzip.BestSpeed}}
+	data := strings.Repeat("ACGT", 10000)
+
+	var stored bytes.Buffer
+
+	enc, _, err := encodePipeline(&stored, pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := enc.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Len() >= len(data) {
+		t.Errorf("Expected compressed contents, got %v bytes", stored.Len())
+	}
+
+	dec, err := decodePipeline(&stored, pipeline, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ioutil.ReadAll(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != data {
+		t.Errorf("Expected the original contents back, got %v bytes", len(out))
+	}
+}
+
+func TestParseTransforms(t *testing.T) {
+	value := transformNames([]Transform{GzipTransform{}, GzipTransform{}})
+	if value != "gzip,gzip" {
+		t.Errorf("Unexpected names %v", value)
+	}
+
+	if pipeline, err := parseTransforms(value); err != nil || len(pipeline) != 2 {
+		t.Errorf("Expected two transforms, got %v, %v", pipeline, err)
+	}
+
+	if _, err := parseTransforms("gzip,rot13"); err == nil {
+		t.Error("Expected an error for an unregistered transform")
+	}
+}
+
+func TestCheckUntransformedWrite(t *testing.T) {
+	con := &Connection{Options: &ConnectionOptions{DecodeTransforms: true}}
+
+	if err := con.checkUntransformedWrite("/tempZone/home/rods/a.txt"); err != nil || !con.decodesTransforms() {
+		t.Errorf("Unexpected error without transforms: %v", err)
+	}
+
+	con.Options.Transforms = []Transform{GzipTransform{}}
+
+	if err := con.checkIncrementalWrite("/tempZone/home/rods/a.txt"); err == nil || !strings.Contains(err.Error(), "only applied by Collection.Put") {
+		t.Errorf("Expected writes to be refused with transforms, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+)
+
+// TruncateOptions are used with Connection.Truncate. Resource (string or *Resource) is the resource of the replica to
+// truncate, with the replica truncate API of iRODS 4.2.11 and later servers and client libraries. The other replicas
+// are then marked stale.
+type TruncateOptions struct {
+	Resource interface{}
+}
+
+// Truncate sets the size of the data object at p, like truncate(2): the content beyond size is discarded, and the
+// data object is extended with zero bytes if it's shorter. It uses the replica truncate API of iRODS 4.2.11 and later
+// servers, and the data object truncate API on older ones.
+func (con *Connection) Truncate(p string, size int64, opts TruncateOptions) error {
+	if size < 0 {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Truncate DataObject Failed: %v, invalid size %v", p, size))
+	}
+
+	resc, err := resourceName(opts.Resource)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{"size": strconv.FormatInt(size, 10)}
+	if resc != "" {
+		params["resource"] = resc
+	}
+
+	err = con.intercept(&Event{Op: OpTruncate, Path: p, Params: params}, func() error {
+		return con.truncate(p, size, resc)
+	})
+
+	con.InvalidateCache(p)
+
+	return err
+}
+
+func (con *Connection) truncate(p string, size int64, resc string) error {
+	var errMsg *C.char
+
+	hasReplicaTruncate := func(c Capabilities) bool { return c.ReplicaTruncate }
+
+	if resc != "" {
+		if err := con.requireCapability("Truncate Replica", "4.2.11", hasReplicaTruncate); err != nil {
+			return err
+		}
+	}
+
+	cPath := C.CString(p)
+	cResource := C.CString(resc)
+	defer C.free(unsafe.Pointer(cPath))
+	defer C.free(unsafe.Pointer(cResource))
+
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	if !con.lacks(hasReplicaTruncate) {
+		status := C.gorods_replica_truncate(cPath, C.rodsLong_t(size), cResource, ccon, &errMsg)
+
+		if status >= 0 {
+			return nil
+		}
+
+		if status != C.SYS_UNMATCHED_API_NUM || resc != "" {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Truncate DataObject Failed: %v, %v", p, C.GoString(errMsg)))
+		}
+	}
+
+	if status := C.gorods_truncate_dataobject(cPath, C.rodsLong_t(size), ccon, &errMsg); status < 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS Truncate DataObject Failed: %v, %v", p, C.GoString(errMsg)))
+	}
+
+	return nil
+}
+
+// Truncate sets the size of the data object, discarding the content beyond size. The data object should be closed
+// first if it's open for writing: iRODS 4.2.9 and later servers lock replicas while they're written.
+func (obj *DataObj) Truncate(size int64) error {
+	if err := obj.con.Truncate(obj.path, size, TruncateOptions{}); err != nil {
+		return err
+	}
+
+	obj.size = size
+
+	return nil
+}
+
+// TruncateReplica truncates the replica of the data object on its resource, marking the other replicas stale.
+// Requires an iRODS 4.2.11 or later server.
+func (obj *DataObj) TruncateReplica(size int64) error {
+	if obj.resource == nil {
+		return newError(Fatal, -1, fmt.Sprintf("iRODS Truncate Replica Failed: %v, the replica's resource is unknown", obj.path))
+	}
+
+	if err := obj.con.Truncate(obj.path, size, TruncateOptions{Resource: obj.resource}); err != nil {
+		return err
+	}
+
+	obj.size = size
+
+	return nil
+}
@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, The BioTeam, Inc.                     ***
  *** For more information please refer to the LICENSE.md file  ***/
 
@@ -175,18 +178,12 @@ func (usr *User) RefreshInfo() error {
 	// user_info:
 	// r_comment:
 
-	typeMap := map[string]int{
-		"rodsuser":   UserType,
-		"rodsadmin":  AdminType,
-		"groupadmin": GroupAdminType,
-	}
-
 	if infoMap, err := usr.FetchInfo(); err == nil {
 		usr.comment = infoMap["r_comment"]
 		usr.createTime = timeStringToTime(infoMap["create_ts"])
 		usr.modifyTime = timeStringToTime(infoMap["modify_ts"])
 		usr.id, _ = strconv.Atoi(infoMap["user_id"])
-		usr.typ = typeMap[infoMap["user_type_name"]]
+		usr.typ = ParseUserType(infoMap["user_type_name"])
 		usr.info = infoMap["user_info"]
 
 		if zones, err := usr.con.Zones(); err != nil {
@@ -453,6 +450,10 @@ func (usr *User) Meta() (*MetaCollection, error) {
 }
 
 func deleteUser(userName string, zone *Zone, con *Connection) error {
+	if er := con.requireAdmin("DeleteUser"); er != nil {
+		return er
+	}
+
 	var (
 		err *C.char
 	)
@@ -473,6 +474,10 @@ func deleteUser(userName string, zone *Zone, con *Connection) error {
 }
 
 func createUser(userName string, zoneName string, typ int, con *Connection) error {
+	if er := con.requireAdmin("CreateUser"); er != nil {
+		return er
+	}
+
 	var (
 		err   *C.char
 		cType *C.char
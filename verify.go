@@ -0,0 +1,240 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// VerifyOptions are used with Connection.VerifyReplicas and Collection.VerifyReplicas.
+// When Local is true, vault files are read directly from the local filesystem (the program must run on the resource server,
+// or have the vault mounted). VaultMap can be used to translate a vault path prefix to a local mount point.
+// Otherwise, the server is asked to verify each replica's checksum against its physical file.
+// Resource limits verification to replicas on the named resource. SkipChecksum only compares sizes.
+type VerifyOptions struct {
+	Local        bool
+	VaultMap     map[string]string
+	Resource     string
+	SkipChecksum bool
+}
+
+// ReplicaDrift describes a replica whose catalog information doesn't match its physical file
+type ReplicaDrift struct {
+	Path             string
+	ReplNum          int
+	Resource         string
+	PhyPath          string
+	CatalogSize      int64
+	PhysicalSize     int64
+	CatalogChecksum  string
+	PhysicalChecksum string
+	Missing          bool
+	Err              error
+}
+
+// String returns a short description of the drift found
+func (d *ReplicaDrift) String() string {
+	switch {
+	case d.Missing:
+		return fmt.Sprintf("%v (repl %v on %v): physical file missing: %v", d.Path, d.ReplNum, d.Resource, d.PhyPath)
+	case d.Err != nil:
+		return fmt.Sprintf("%v (repl %v on %v): %v", d.Path, d.ReplNum, d.Resource, d.Err)
+	case d.CatalogSize != d.PhysicalSize:
+		return fmt.Sprintf("%v (repl %v on %v): size mismatch: catalog %v, physical %v", d.Path, d.ReplNum, d.Resource, d.CatalogSize, d.PhysicalSize)
+	default:
+		return fmt.Sprintf("%v (repl %v on %v): checksum mismatch: catalog %v, physical %v", d.Path, d.ReplNum, d.Resource, d.CatalogChecksum, d.PhysicalChecksum)
+	}
+}
+
+// VerifyReport is returned by VerifyReplicas. Checked is the number of replicas examined.
+type VerifyReport struct {
+	Checked int
+	Drift   []*ReplicaDrift
+}
+
+// OK returns true if no drift was found
+func (r *VerifyReport) OK() bool {
+	return len(r.Drift) == 0
+}
+
+// VerifyReplicas compares the catalog size and checksum of every replica of the data object at p with its physical file.
+// Only replicas on unixfilesystem resources are checked. You must have the proper rodsadmin privileges to use this function.
+func (con *Connection) VerifyReplicas(p string, opts VerifyOptions) (*VerifyReport, error) {
+	report := new(VerifyReport)
+
+	if err := con.verifyAdmin(); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("select DATA_REPL_NUM, DATA_SIZE, DATA_CHECKSUM, DATA_PATH, RESC_NAME, RESC_TYPE_NAME where COLL_NAME = '%v' and DATA_NAME = '%v'", filepath.Dir(p), filepath.Base(p))
+
+	zone, err := con.zoneHint(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, con.verifyQuery(query, zone, p, opts, report)
+}
+
+// VerifyReplicas compares the catalog size and checksum of every replica within the collection (recursively) with its physical file.
+// Only replicas on unixfilesystem resources are checked. You must have the proper rodsadmin privileges to use this function.
+func (col *Collection) VerifyReplicas(opts VerifyOptions) (*VerifyReport, error) {
+	report := new(VerifyReport)
+
+	if err := col.con.verifyAdmin(); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("select COLL_NAME, DATA_NAME, DATA_REPL_NUM, DATA_SIZE, DATA_CHECKSUM, DATA_PATH, RESC_NAME, RESC_TYPE_NAME where COLL_NAME = '%v' || like '%v/%%'", col.path, col.path)
+
+	zone, err := col.con.zoneHint(col.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, col.con.verifyQuery(query, zone, "", opts, report)
+}
+
+func (con *Connection) verifyAdmin() error {
+	return con.requireAdmin("VerifyReplicas")
+}
+
+func (con *Connection) verifyQuery(query string, zone string, p string, opts VerifyOptions, report *VerifyReport) error {
+	result, err := con.IQuestZone(query, false, zone)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range result {
+		if !strings.EqualFold(row["RESC_TYPE_NAME"], "unixfilesystem") {
+			continue
+		}
+
+		if opts.Resource != "" && row["RESC_NAME"] != opts.Resource {
+			continue
+		}
+
+		objPath := p
+		if objPath == "" {
+			objPath = row["COLL_NAME"] + "/" + row["DATA_NAME"]
+		}
+
+		replNum, _ := strconv.Atoi(row["DATA_REPL_NUM"])
+		size, _ := strconv.ParseInt(row["DATA_SIZE"], 10, 64)
+
+		d := &ReplicaDrift{
+			Path:            objPath,
+			ReplNum:         replNum,
+			Resource:        row["RESC_NAME"],
+			PhyPath:         row["DATA_PATH"],
+			CatalogSize:     size,
+			PhysicalSize:    size,
+			CatalogChecksum: row["DATA_CHECKSUM"],
+		}
+
+		report.Checked++
+
+		var drift bool
+		if opts.Local {
+			drift = verifyLocal(d, opts)
+		} else {
+			drift = con.verifyRemote(d, opts)
+		}
+
+		if drift {
+			report.Drift = append(report.Drift, d)
+		}
+	}
+
+	return nil
+}
+
+// verifyLocal stats and digests the vault file directly. Returns true if drift was found.
+func verifyLocal(d *ReplicaDrift, opts VerifyOptions) bool {
+	localPath := d.PhyPath
+	for prefix, mount := range opts.VaultMap {
+		if strings.HasPrefix(localPath, prefix) {
+			localPath = mount + strings.TrimPrefix(localPath, prefix)
+			break
+		}
+	}
+
+	info, err := os.Stat(localPath)
+	if os.IsNotExist(err) {
+		d.Missing = true
+		return true
+	} else if err != nil {
+		d.Err = err
+		return true
+	}
+
+	d.PhysicalSize = info.Size()
+	if d.PhysicalSize != d.CatalogSize {
+		return true
+	}
+
+	if opts.SkipChecksum || d.CatalogChecksum == "" {
+		return false
+	}
+
+	d.PhysicalChecksum, err = fileChecksum(localPath, d.CatalogChecksum)
+	if err != nil {
+		d.Err = err
+		return true
+	}
+
+	return d.PhysicalChecksum != d.CatalogChecksum
+}
+
+// fileChecksum computes the checksum of a local file, in the same format (md5 or sha2) as the catalog checksum like
+func fileChecksum(localPath string, like string) (string, error) {
+	return IRODSChecksum(localPath, checksumAlgorithmOf(like))
+}
+
+// verifyRemote asks the server to verify the replica's checksum (or only its size, with SkipChecksum) against its physical file.
+// Returns true if drift was found.
+func (con *Connection) verifyRemote(d *ReplicaDrift, opts VerifyOptions) bool {
+	if err := con.verifyReplica(d.Path, d.ReplNum, opts.SkipChecksum); err != nil {
+		d.Err = err
+		return true
+	}
+
+	return false
+}
+
+// verifyReplica asks the server to compute the checksum of replica replNum of the data object at p, and compare it with the catalog's.
+// When sizeOnly is set, the server only compares the size of the physical file with the catalog's, without computing a checksum.
+func (con *Connection) verifyReplica(p string, replNum int, sizeOnly bool) error {
+	var err *C.char
+
+	path := C.CString(p)
+	cReplNum := C.CString(strconv.Itoa(replNum))
+	defer C.free(unsafe.Pointer(path))
+	defer C.free(unsafe.Pointer(cReplNum))
+
+	ccon := con.GetCcon()
+	defer con.ReturnCcon(ccon)
+
+	var cSizeOnly C.int
+	if sizeOnly {
+		cSizeOnly = 1
+	}
+
+	if status := C.gorods_verify_checksum_dataobject(path, cReplNum, cSizeOnly, ccon, &err); status < 0 {
+		return newError(Fatal, status, fmt.Sprintf("iRODS Verify Replica Failed: %v", C.GoString(err)))
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyLocal(t *testing.T) {
+	mount := t.TempDir()
+
+	if err := ioutil.WriteFile(filepath.Join(mount, "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := VerifyOptions{Local: true, VaultMap: map[string]string{"/var/lib/irods/Vault": mount}}
+
+	replica := func(size int64, checksum string, phyPath string) *ReplicaDrift {
+		return &ReplicaDrift{Path: "/tempZone/home/rods/a.txt", Resource: "demoResc", PhyPath: phyPath, CatalogSize: size, CatalogChecksum: checksum}
+	}
+
+	vault := "/var/lib/irods/Vault/a.txt"
+
+	if d := replica(5, "5d41402abc4b2a76b9719d911017c592", vault); verifyLocal(d, opts) {
+		t.Errorf("Expected a matching replica, got %v", d)
+	}
+
+	if d := replica(5, "", vault); verifyLocal(d, opts) {
+		t.Errorf("Expected replicas without a catalog checksum to only compare sizes, got %v", d)
+	}
+
+	if d := replica(6, "", vault); !verifyLocal(d, opts) || d.PhysicalSize != 5 || !strings.Contains(d.String(), "size mismatch") {
+		t.Errorf("Expected a size mismatch, got %v", d)
+	}
+
+	if d := replica(5, "sha2:LCa0a2j/xo/5m0U8HTBBNBNCLXBkg7+g+YpeiGJm564=", vault); !verifyLocal(d, opts) || !strings.Contains(d.String(), "checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch, got %v", d)
+	}
+
+	skip := opts
+	skip.SkipChecksum = true
+
+	if d := replica(5, "sha2:LCa0a2j/xo/5m0U8HTBBNBNCLXBkg7+g+YpeiGJm564=", vault); verifyLocal(d, skip) {
+		t.Errorf("Expected SkipChecksum to ignore the checksum, got %v", d)
+	}
+
+	if d := replica(5, "", "/var/lib/irods/Vault/missing.txt"); !verifyLocal(d, opts) || !d.Missing || !strings.Contains(d.String(), "physical file missing") {
+		t.Errorf("Expected a missing physical file, got %v", d)
+	}
+
+	report := &VerifyReport{Checked: 2}
+	if !report.OK() {
+		t.Error("Expected a report without drift to be OK")
+	}
+}
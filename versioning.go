@@ -0,0 +1,389 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
+ *** For more information please refer to the LICENSE.md file                                   ***/
+
+package gorods
+
+// #include "wrapper.h"
+import "C"
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// Attributes of the AVUs set on stored versions
+const (
+	VersionNumberAttr   = "gorods:version"
+	VersionSourceAttr   = "gorods:version:source"
+	VersionModifiedAttr = "gorods:version:modified"
+)
+
+// DefaultVersionsDir is the name of the sub-collection holding previous versions, see Versioning.Dir
+const DefaultVersionsDir = ".versions"
+
+// Version is a previous content of a data object, stored by Versioning.PutVersion. Time is when it was replaced.
+type Version struct {
+	Number   int
+	Path     string
+	Size     int64
+	Checksum string
+	Time     time.Time
+}
+
+// Versioning keeps the previous contents of data objects: before a data object is overwritten by PutVersion, its
+// content is copied (server-side) to <collection>/<Dir>/<name>/<number>, with AVUs recording the version number,
+// the source path and when the content was last modified. Keep limits the number of versions of each data object,
+// the oldest ones are deleted first. Like CAS, version numbering isn't atomic, so a data object shouldn't be
+// versioned concurrently by multiple clients.
+type Versioning struct {
+	con      *Connection
+	Dir      string
+	Keep     int
+	Resource interface{}
+}
+
+// Versioning returns a versioning helper storing versions in DefaultVersionsDir sub-collections
+func (con *Connection) Versioning() *Versioning {
+	return &Versioning{con: con, Dir: DefaultVersionsDir}
+}
+
+// VersionsPath returns the path of the collection holding the versions of the data object at p
+func (v *Versioning) VersionsPath(p string) string {
+	dir := v.Dir
+	if dir == "" {
+		dir = DefaultVersionsDir
+	}
+
+	p = strings.TrimRight(p, "/")
+
+	return path.Dir(p) + "/" + strings.Trim(dir, "/") + "/" + path.Base(p)
+}
+
+// PutVersion writes content to the data object at p, creating it if needed. If p exists, its current content is
+// stored as a new version first, which is returned (nil if p didn't exist).
+func (v *Versioning) PutVersion(p string, content []byte) (*Version, error) {
+	if err := v.con.checkUntransformedWrite(p); err != nil {
+		return nil, err
+	}
+
+	if err := v.con.scanBytes(p, content); err != nil {
+		return nil, err
+	}
+
+	typ, err := v.con.PathType(p)
+
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+
+	if err == nil && typ != DataObjType {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS PutVersion Failed: %v is not a data object", p))
+	}
+
+	if err != nil {
+		obj, er := v.create(p, int64(len(content)))
+		if er != nil {
+			return nil, er
+		}
+
+		return nil, v.write(obj, content)
+	}
+
+	version, err := v.save(p)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := v.con.DataObject(p)
+	if err != nil {
+		return version, err
+	}
+
+	if er := obj.OpenFlags(O_WRONLY | O_TRUNC); er != nil {
+		return version, er
+	}
+
+	if er := v.write(obj, content); er != nil {
+		return version, er
+	}
+
+	return version, v.prune(p)
+}
+
+// PutVersionFile reads the local file specified and writes its contents with PutVersion
+func (v *Versioning) PutVersionFile(p string, localPath string) (*Version, error) {
+	content, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS PutVersion Failed: %v", err))
+	}
+
+	return v.PutVersion(p, content)
+}
+
+// ListVersions returns the stored versions of the data object at p, oldest first
+func (v *Versioning) ListVersions(p string) ([]*Version, error) {
+	zone, err := v.con.zoneHint(p)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := v.VersionsPath(p)
+
+	dirLit, err := queryLiteral(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := v.con.IQuestZone(fmt.Sprintf("select DATA_NAME, DATA_SIZE, DATA_CHECKSUM, DATA_CREATE_TIME where COLL_NAME = %v", dirLit), false, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	return versionsFromRows(dir, rows), nil
+}
+
+// RestoreVersion makes version n the current content of the data object at p. The content it replaces is stored as
+// a new version, so restoring can be undone. Returns that new version.
+func (v *Versioning) RestoreVersion(p string, n int) (*Version, error) {
+	versions, err := v.ListVersions(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var restore *Version
+
+	for _, version := range versions {
+		if version.Number == n {
+			restore = version
+		}
+	}
+
+	if restore == nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS RestoreVersion Failed: %v has no version %v", p, n))
+	}
+
+	saved, err := v.save(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if er := v.con.copyDataObj(restore.Path, p, true, v.Resource); er != nil {
+		return saved, er
+	}
+
+	return saved, v.prune(p)
+}
+
+// save copies the current content of the data object at p to a new version
+func (v *Versioning) save(p string) (*Version, error) {
+	obj, err := v.con.DataObject(p)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := v.ListVersions(p)
+	if err != nil {
+		return nil, err
+	}
+
+	number := 1
+	if len(versions) > 0 {
+		number = versions[len(versions)-1].Number + 1
+	}
+
+	dir := v.VersionsPath(p)
+
+	if er := v.mkdirs(dir); er != nil {
+		return nil, er
+	}
+
+	version := &Version{
+		Number:   number,
+		Path:     dir + "/" + strconv.Itoa(number),
+		Size:     obj.Size(),
+		Checksum: obj.Checksum(),
+		Time:     time.Now(),
+	}
+
+	if er := v.con.copyDataObj(p, version.Path, false, v.Resource); er != nil {
+		return nil, er
+	}
+
+	if er := v.con.ApplyMetaOperations(version.Path, DataObjType, []MetaOperation{
+		{Operation: MetaOpAdd, Attribute: VersionNumberAttr, Value: strconv.Itoa(number)},
+		{Operation: MetaOpAdd, Attribute: VersionSourceAttr, Value: p},
+		{Operation: MetaOpAdd, Attribute: VersionModifiedAttr, Value: strconv.FormatInt(obj.ModifyTime().Unix(), 10)},
+	}); er != nil {
+		// Don't leave a copy without its AVUs, ListVersions would still return it
+		if vObj, err := v.con.DataObject(version.Path); err == nil {
+			vObj.Delete(false)
+		}
+
+		return nil, er
+	}
+
+	return version, nil
+}
+
+// prune deletes the oldest versions of the data object at p beyond Keep
+func (v *Versioning) prune(p string) error {
+	if v.Keep <= 0 {
+		return nil
+	}
+
+	versions, err := v.ListVersions(p)
+	if err != nil {
+		return err
+	}
+
+	for len(versions) > v.Keep {
+		obj, err := v.con.DataObject(versions[0].Path)
+		if err != nil {
+			return err
+		}
+
+		if er := obj.Delete(false); er != nil {
+			return er
+		}
+
+		versions = versions[1:]
+	}
+
+	return nil
+}
+
+// create creates the data object at p, in an existing collection
+func (v *Versioning) create(p string, size int64) (*DataObj, error) {
+	col, err := v.con.Collection(CollectionOptions{Path: path.Dir(p), SkipCache: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return col.CreateDataObj(DataObjOptions{
+		Name:     path.Base(p),
+		Size:     size,
+		Mode:     0750,
+		Resource: v.Resource,
+	})
+}
+
+// write writes content, already scanned, to the open data object and closes it
+func (v *Versioning) write(obj *DataObj, content []byte) error {
+	if len(content) > 0 {
+		if err := obj.writeNext(content); err != nil {
+			return err
+		}
+	}
+
+	return obj.Close()
+}
+
+// mkdirs creates the versions collection dir and its parent, if they don't exist
+func (v *Versioning) mkdirs(dir string) error {
+	for _, p := range []string{path.Dir(dir), dir} {
+		if _, err := v.con.Collection(CollectionOptions{Path: p, SkipCache: true}); err == nil {
+			continue
+		}
+
+		parent, err := v.con.Collection(CollectionOptions{Path: path.Dir(p), SkipCache: true})
+		if err != nil {
+			return err
+		}
+
+		if _, err := parent.CreateSubCollection(path.Base(p)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// versionsFromRows returns the versions listed by the rows of a query on the versions collection dir, oldest first.
+// Data objects not named by a version number are ignored, and replicas are listed once.
+func versionsFromRows(dir string, rows []map[string]string) []*Version {
+	byNumber := make(map[int]*Version)
+
+	for _, row := range rows {
+		n, err := strconv.Atoi(row["DATA_NAME"])
+		if err != nil || n <= 0 {
+			continue
+		}
+
+		if _, ok := byNumber[n]; ok {
+			continue
+		}
+
+		size, _ := strconv.ParseInt(row["DATA_SIZE"], 10, 64)
+
+		byNumber[n] = &Version{
+			Number:   n,
+			Path:     dir + "/" + row["DATA_NAME"],
+			Size:     size,
+			Checksum: row["DATA_CHECKSUM"],
+			Time:     timeStringToTime(row["DATA_CREATE_TIME"]),
+		}
+	}
+
+	versions := make([]*Version, 0, len(byNumber))
+
+	for _, version := range byNumber {
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Number < versions[j].Number
+	})
+
+	return versions
+}
+
+// copyDataObj copies the data object at source to dest server-side, overwriting dest if force is set
+func (con *Connection) copyDataObj(source string, dest string, force bool, resc interface{}) error {
+	rescName, err := con.targetResource(resc)
+	if err != nil {
+		return err
+	}
+
+	cForce := 0
+	if force {
+		cForce = 1
+	}
+
+	cSource := C.CString(source)
+	cDest := C.CString(dest)
+	cResource := C.CString(rescName)
+	defer C.free(unsafe.Pointer(cSource))
+	defer C.free(unsafe.Pointer(cDest))
+	defer C.free(unsafe.Pointer(cResource))
+
+	params := map[string]string{"force": strconv.FormatBool(force)}
+	if rescName != "" {
+		params["resource"] = rescName
+	}
+
+	err = con.intercept(&Event{Op: OpCopy, Path: source, Dest: dest, Params: params}, func() error {
+		var errMsg *C.char
+
+		ccon := con.GetCcon()
+		defer con.ReturnCcon(ccon)
+
+		if status := C.gorods_copy_dataobject(cSource, cDest, C.int(cForce), cResource, ccon, &errMsg); status != 0 {
+			return newError(Fatal, status, fmt.Sprintf("iRODS Copy DataObject Failed: %v, %v", dest, C.GoString(errMsg)))
+		}
+
+		return nil
+	})
+
+	con.InvalidateCache(dest)
+
+	return err
+}
@@ -0,0 +1,51 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+)
+
+func TestVersionsPath(t *testing.T) {
+	v := &Versioning{Dir: DefaultVersionsDir}
+
+	if p := v.VersionsPath("/tempZone/home/rods/report.csv"); p != "/tempZone/home/rods/.versions/report.csv" {
+		t.Errorf("Unexpected versions path %v", p)
+	}
+
+	v.Dir = "/history/"
+
+	if p := v.VersionsPath("/tempZone/home/rods/report.csv"); p != "/tempZone/home/rods/history/report.csv" {
+		t.Errorf("Unexpected versions path %v", p)
+	}
+}
+
+func TestVersionsFromRows(t *testing.T) {
+	dir := "/tempZone/home/rods/.versions/report.csv"
+
+	versions := versionsFromRows(dir, []map[string]string{
+		{"DATA_NAME": "10", "DATA_SIZE": "30", "DATA_CHECKSUM": "sha2:c", "DATA_CREATE_TIME": "01500000200"},
+		{"DATA_NAME": "2", "DATA_SIZE": "20", "DATA_CHECKSUM": "sha2:b", "DATA_CREATE_TIME": "01500000100"},
+		{"DATA_NAME": "2", "DATA_SIZE": "20", "DATA_CHECKSUM": "sha2:b", "DATA_CREATE_TIME": "01500000100"},
+		{"DATA_NAME": "notes.txt", "DATA_SIZE": "5"},
+		{"DATA_NAME": "1", "DATA_SIZE": "10", "DATA_CHECKSUM": "sha2:a", "DATA_CREATE_TIME": "01500000000"},
+	})
+
+	if len(versions) != 3 {
+		t.Fatalf("Expected 3 versions, got %v", len(versions))
+	}
+
+	for i, n := range []int{1, 2, 10} {
+		if versions[i].Number != n {
+			t.Errorf("Expected version %v at %v, got %v", n, i, versions[i].Number)
+		}
+	}
+
+	if versions[2].Path != dir+"/10" || versions[2].Size != 30 || versions[2].Time.Unix() != 1500000200 {
+		t.Errorf("Unexpected version %+v", versions[2])
+	}
+}
@@ -0,0 +1,374 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WatchEvent is sent by a Watcher for every entry added, removed or changed under the watched collection, or when
+// the watcher fails. Entry.Path is the full path of the entry. Source is ChangeSourceWatch for changes found by
+// polling, and ChangeSourceAudit for those read from audit plugin messages.
+type WatchEvent struct {
+	ListingDiff
+	Time   time.Time
+	Source string
+	Err    error
+}
+
+// WatchOptions configures Collection.Watch
+type WatchOptions struct {
+	// Interval between polls for entries modified since the previous one, 5 seconds if 0
+	Interval time.Duration
+
+	// RescanInterval between full listings of the tree, which find removed entries and entries moved
+	// into it (renames keep their modify time). 1 minute if 0, never if negative.
+	RescanInterval time.Duration
+
+	// Messages are the JSON messages of the iRODS audit_amqp rule engine plugin, as consumed from its queue
+	// by the caller. When set, the watcher reads them instead of polling the catalog.
+	Messages <-chan []byte
+}
+
+// Watcher delivers the changes under a collection and its sub-collections, see Collection.Watch
+type Watcher struct {
+	C <-chan WatchEvent
+
+	con       *Connection
+	root      string
+	opts      WatchOptions
+	known     map[string]ListingEntry
+	watermark time.Time
+	scanned   time.Time
+	done      chan struct{}
+	once      sync.Once
+}
+
+// Watch sends the data objects and collections added, removed or modified anywhere below the collection on
+// Watcher.C. Entries existing when it starts aren't reported. By default the catalog is polled: every Interval, a
+// general query fetches only the entries whose modify time is at least the newest one seen so far, and every
+// RescanInterval the whole tree is listed to find removals. The watcher keeps the last listing in memory.
+// If opts.Messages is set, the events come from the audit plugin messages instead, without querying the catalog.
+// Changes are passed to Connection.ObserveChange, so cached entries don't go stale. Call Close to stop watching.
+func (col *Collection) Watch(opts WatchOptions) *Watcher {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+
+	if opts.RescanInterval == 0 {
+		opts.RescanInterval = time.Minute
+	}
+
+	c := make(chan WatchEvent, 64)
+
+	w := &Watcher{
+		C:    c,
+		con:  col.con,
+		root: strings.TrimRight(col.path, "/"),
+		opts: opts,
+		done: make(chan struct{}),
+	}
+
+	if opts.Messages != nil {
+		go w.consume(c)
+	} else {
+		go w.run(c)
+	}
+
+	return w
+}
+
+// Close stops watching and closes Watcher.C. It is safe to call more than once.
+func (w *Watcher) Close() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+}
+
+func (w *Watcher) run(c chan<- WatchEvent) {
+	defer close(c)
+
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		diffs, err := w.poll()
+
+		if !w.send(c, diffs, ChangeSourceWatch, err) {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// poll returns the changes since the previous poll. The first successful poll lists the tree and returns nothing.
+func (w *Watcher) poll() ([]ListingDiff, error) {
+	now := time.Now()
+
+	if w.known == nil || (w.opts.RescanInterval > 0 && now.Sub(w.scanned) >= w.opts.RescanInterval) {
+		current, err := w.con.treeEntries(w.root, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+
+		var diffs []ListingDiff
+		if w.known != nil {
+			diffs = diffListings(w.known, current)
+			sortDiffsByPath(diffs)
+		}
+
+		w.known = current
+		w.scanned = now
+		w.watermark = newestModifyTime(current, w.watermark)
+
+		return diffs, nil
+	}
+
+	modified, err := w.con.treeEntries(w.root, w.watermark)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := mergeModified(w.known, modified)
+	w.watermark = newestModifyTime(modified, w.watermark)
+
+	return diffs, nil
+}
+
+// consume reads the audit plugin messages until their channel or the watcher is closed
+func (w *Watcher) consume(c chan<- WatchEvent) {
+	defer close(c)
+
+	for {
+		select {
+		case msg, ok := <-w.opts.Messages:
+			if !ok {
+				return
+			}
+
+			diffs, err := parseAuditMessage(msg, w.root)
+
+			if !w.send(c, diffs, ChangeSourceAudit, err) {
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// send reports the diffs to the connection and sends them, or err, on c. Returns false if the watcher was closed.
+func (w *Watcher) send(c chan<- WatchEvent, diffs []ListingDiff, source string, err error) bool {
+	now := time.Now()
+
+	events := make([]WatchEvent, 0, len(diffs)+1)
+
+	if err != nil {
+		events = append(events, WatchEvent{Time: now, Source: source, Err: err})
+	}
+
+	if len(diffs) > 0 {
+		paths := make([]string, len(diffs))
+		for i, d := range diffs {
+			paths[i] = d.Entry.Path
+			events = append(events, WatchEvent{ListingDiff: d, Time: now, Source: source})
+		}
+
+		w.con.ObserveChange(source, paths...)
+	}
+
+	for _, e := range events {
+		select {
+		case c <- e:
+		case <-w.done:
+			return false
+		}
+	}
+
+	return true
+}
+
+// mergeModified updates known with the modified entries, returning those which were added or changed
+func mergeModified(known map[string]ListingEntry, modified map[string]ListingEntry) []ListingDiff {
+	diffs := make([]ListingDiff, 0)
+
+	for p, entry := range modified {
+		if old, ok := known[p]; !ok {
+			diffs = append(diffs, ListingDiff{Kind: DiffAdded, Entry: entry})
+		} else if old != entry {
+			diffs = append(diffs, ListingDiff{Kind: DiffChanged, Entry: entry})
+		} else {
+			continue
+		}
+
+		known[p] = entry
+	}
+
+	sortDiffsByPath(diffs)
+
+	return diffs
+}
+
+func sortDiffsByPath(diffs []ListingDiff) {
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffs[i].Entry.Path < diffs[j].Entry.Path
+	})
+}
+
+// newestModifyTime returns the latest modify time of the entries, or t if it is later
+func newestModifyTime(entries map[string]ListingEntry, t time.Time) time.Time {
+	for _, entry := range entries {
+		if entry.ModifyTime.After(t) {
+			t = entry.ModifyTime
+		}
+	}
+
+	return t
+}
+
+// treeEntries returns the collections and data objects below the collection at p, keyed by path. If since
+// isn't zero, only those modified at or after it are returned. Catalog times have a one second resolution, so
+// entries modified in the same second as since are returned again.
+func (con *Connection) treeEntries(p string, since time.Time) (map[string]ListingEntry, error) {
+	zone, err := con.zoneHint(p)
+	if err != nil {
+		return nil, err
+	}
+
+	colCond := fmt.Sprintf("COLL_NAME like '%v/%%'", p)
+	objCond := fmt.Sprintf("COLL_NAME = '%v' || like '%v/%%'", p, p)
+
+	if !since.IsZero() {
+		colCond += fmt.Sprintf(" and COLL_MODIFY_TIME >= '%011d'", since.Unix())
+		objCond += fmt.Sprintf(" and DATA_MODIFY_TIME >= '%011d'", since.Unix())
+	}
+
+	entries := make(map[string]ListingEntry)
+
+	cols, err := con.IQuestZone("select COLL_NAME, COLL_MODIFY_TIME where "+colCond, false, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range cols {
+		entries[row["COLL_NAME"]] = ListingEntry{
+			Name:       path.Base(row["COLL_NAME"]),
+			Path:       row["COLL_NAME"],
+			Type:       CollectionType,
+			ModifyTime: timeStringToTime(row["COLL_MODIFY_TIME"]),
+		}
+	}
+
+	objs, err := con.IQuestZone("select COLL_NAME, DATA_NAME, DATA_SIZE, DATA_CHECKSUM, DATA_MODIFY_TIME where "+objCond, false, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	// One row per distinct replica state, keep the most recently modified like listEntries
+	for _, row := range objs {
+		size, _ := strconv.ParseInt(row["DATA_SIZE"], 10, 64)
+
+		entry := ListingEntry{
+			Name:       row["DATA_NAME"],
+			Path:       row["COLL_NAME"] + "/" + row["DATA_NAME"],
+			Type:       DataObjType,
+			Size:       size,
+			Checksum:   row["DATA_CHECKSUM"],
+			ModifyTime: timeStringToTime(row["DATA_MODIFY_TIME"]),
+		}
+
+		if old, ok := entries[entry.Path]; ok {
+			if old.ModifyTime.After(entry.ModifyTime) || (old.ModifyTime.Equal(entry.ModifyTime) && old.Checksum >= entry.Checksum) {
+				continue
+			}
+		}
+
+		entries[entry.Path] = entry
+	}
+
+	return entries, nil
+}
+
+// auditPluginPEPs maps the API policy enforcement points reported by the audit plugin to the diffs they cause,
+// and the message key holding the affected path
+var auditPluginPEPs = []struct {
+	api  string
+	kind int
+	typ  int
+	key  string
+}{
+	{"data_obj_put", DiffAdded, DataObjType, "obj_path"},
+	{"data_obj_create", DiffAdded, DataObjType, "obj_path"},
+	{"phy_path_reg", DiffAdded, DataObjType, "obj_path"},
+	{"data_obj_copy", DiffAdded, DataObjType, "dst_obj_path"},
+	{"data_obj_rename", DiffRemoved, DataObjType, "src_obj_path"},
+	{"data_obj_rename", DiffAdded, DataObjType, "dst_obj_path"},
+	{"data_obj_truncate", DiffChanged, DataObjType, "obj_path"},
+	{"data_obj_unlink", DiffRemoved, DataObjType, "obj_path"},
+	{"coll_create", DiffAdded, CollectionType, "coll_name"},
+	{"rm_coll", DiffRemoved, CollectionType, "coll_name"},
+}
+
+// parseAuditMessage returns the diffs below root described by an audit plugin message. Only the post operation
+// PEPs, called once the API succeeded, are considered. Messages of other PEPs, or about paths outside root, give no diffs.
+// Renamed collections are reported with DataObjType, as the message doesn't tell them apart from data objects.
+func parseAuditMessage(msg []byte, root string) ([]ListingDiff, error) {
+	fields := make(map[string]interface{})
+
+	if er := json.Unmarshal(msg, &fields); er != nil {
+		return nil, newError(Fatal, -1, fmt.Sprintf("iRODS Watch Failed: invalid audit message, %v", er))
+	}
+
+	rule, _ := fields["rule_name"].(string)
+
+	if !strings.HasSuffix(rule, "_post") {
+		return nil, nil
+	}
+
+	when := time.Now()
+	if ts, ok := fields["time_stamp"].(string); ok {
+		if ms, er := strconv.ParseInt(ts, 10, 64); er == nil {
+			when = time.Unix(0, ms*int64(time.Millisecond))
+		}
+	}
+
+	diffs := make([]ListingDiff, 0)
+
+	for _, pep := range auditPluginPEPs {
+		if !strings.HasSuffix(rule, "_api_"+pep.api+"_post") {
+			continue
+		}
+
+		p, _ := fields[pep.key].(string)
+		p = strings.TrimRight(p, "/")
+
+		if !strings.HasPrefix(p, root+"/") {
+			continue
+		}
+
+		diffs = append(diffs, ListingDiff{Kind: pep.kind, Entry: ListingEntry{
+			Name:       path.Base(p),
+			Path:       p,
+			Type:       pep.typ,
+			ModifyTime: when,
+		}})
+	}
+
+	return diffs, nil
+}
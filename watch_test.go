@@ -0,0 +1,75 @@
+//go:build cgo
+// +build cgo
+
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package gorods
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeModified(t *testing.T) {
+	known := map[string]ListingEntry{
+		"/z/a/x.txt": {Name: "x.txt", Path: "/z/a/x.txt", Type: DataObjType, Size: 1, ModifyTime: time.Unix(100, 0)},
+		"/z/a/y.txt": {Name: "y.txt", Path: "/z/a/y.txt", Type: DataObjType, Size: 2, ModifyTime: time.Unix(200, 0)},
+	}
+
+	modified := map[string]ListingEntry{
+		"/z/a/y.txt":     {Name: "y.txt", Path: "/z/a/y.txt", Type: DataObjType, Size: 2, ModifyTime: time.Unix(200, 0)},
+		"/z/a/b/new.txt": {Name: "new.txt", Path: "/z/a/b/new.txt", Type: DataObjType, Size: 3, ModifyTime: time.Unix(300, 0)},
+		"/z/a/x.txt":     {Name: "x.txt", Path: "/z/a/x.txt", Type: DataObjType, Size: 5, ModifyTime: time.Unix(300, 0)},
+	}
+
+	diffs := mergeModified(known, modified)
+
+	expected := []string{"+ new.txt", "~ x.txt"}
+
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %v diffs, got %v", len(expected), diffs)
+	}
+
+	for i, d := range diffs {
+		if d.String() != expected[i] {
+			t.Errorf("Diff %v: expected %q, got %q", i, expected[i], d.String())
+		}
+	}
+
+	if len(known) != 3 || known["/z/a/x.txt"].Size != 5 {
+		t.Errorf("Known entries weren't updated: %v", known)
+	}
+
+	if wm := newestModifyTime(modified, time.Unix(250, 0)); !wm.Equal(time.Unix(300, 0)) {
+		t.Errorf("Expected watermark 300, got %v", wm.Unix())
+	}
+}
+
+func TestParseAuditMessage(t *testing.T) {
+	msg := []byte(`{"rule_name": "audit_pep_api_data_obj_rename_post", "time_stamp": "1500000000000",
+		"src_obj_path": "/z/home/a/old.txt", "dst_obj_path": "/z/other/new.txt"}`)
+
+	diffs, err := parseAuditMessage(msg, "/z/home")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diffs) != 1 || diffs[0].Kind != DiffRemoved || diffs[0].Entry.Path != "/z/home/a/old.txt" {
+		t.Fatalf("Unexpected diffs %v", diffs)
+	}
+
+	if !diffs[0].Entry.ModifyTime.Equal(time.Unix(1500000000, 0)) {
+		t.Errorf("Unexpected time %v", diffs[0].Entry.ModifyTime)
+	}
+
+	pre := []byte(`{"rule_name": "audit_pep_api_data_obj_put_pre", "obj_path": "/z/home/a.txt"}`)
+
+	if diffs, _ := parseAuditMessage(pre, "/z/home"); len(diffs) != 0 {
+		t.Errorf("Expected no diffs for pre PEPs, got %v", diffs)
+	}
+
+	if _, err := parseAuditMessage([]byte("not json"), "/z/home"); err == nil {
+		t.Error("Expected an error for an invalid message")
+	}
+}
@@ -0,0 +1,42 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package webdav
+
+import (
+	"time"
+
+	"github.com/jjacquay712/GoRODS"
+	"github.com/jjacquay712/GoRODS/internal/userpool"
+)
+
+// pooledCon is the iRODS connection of a WebDAV user
+type pooledCon struct {
+	*gorods.Connection
+}
+
+// Close implements userpool.Handle
+func (pc pooledCon) Close() error {
+	return pc.Disconnect()
+}
+
+// newPool returns the pool keeping one iRODS connection per authenticated WebDAV user, disconnecting connections
+// that have been idle longer than ttl
+func newPool(opts ConnectionTemplate, ttl time.Duration) *userpool.Pool {
+	return userpool.New(func(username string, password string) (userpool.Handle, error) {
+		con, err := gorods.NewConnection(&gorods.ConnectionOptions{
+			Type:     gorods.UserDefined,
+			Host:     opts.Host,
+			Port:     opts.Port,
+			Zone:     opts.Zone,
+			FastInit: true,
+			Username: username,
+			Password: password,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return pooledCon{con}, nil
+	}, ttl)
+}
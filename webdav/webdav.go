@@ -0,0 +1,534 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+// Package webdav provides an http.Handler that serves an iRODS collection tree over WebDAV (class 1),
+// so desktop clients can mount iRODS without extra software. Each request is authenticated with HTTP basic auth,
+// and mapped to a pooled iRODS connection for that user.
+package webdav
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jjacquay712/GoRODS"
+	"github.com/jjacquay712/GoRODS/internal/userpool"
+)
+
+// ConnectionTemplate holds the iCAT server information used to open a connection for each WebDAV user
+type ConnectionTemplate struct {
+	Host string
+	Port int
+	Zone string
+}
+
+// Options are used when creating a handler with Handler(). Path is the iRODS collection served at the root of the share.
+// The string "{user}" in Path is replaced with the authenticated username, e.g. "/tempZone/home/{user}".
+// IdleTimeout is how long a user's connection is kept open between requests (defaults to 5 minutes).
+type Options struct {
+	Server      ConnectionTemplate
+	Path        string
+	StripPrefix string
+	Realm       string
+	IdleTimeout time.Duration
+}
+
+// DAVHandler serves WebDAV requests. Use Handler() to create one.
+type DAVHandler struct {
+	opts Options
+	pool *userpool.Pool
+}
+
+// Handler returns a new *DAVHandler using the options specified
+func Handler(opts Options) *DAVHandler {
+	h := new(DAVHandler)
+
+	if opts.Realm == "" {
+		opts.Realm = "iRODS"
+	}
+
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = 5 * time.Minute
+	}
+
+	h.opts = opts
+	h.pool = newPool(opts.Server, opts.IdleTimeout)
+
+	return h
+}
+
+// Close disconnects all pooled iRODS connections
+func (h *DAVHandler) Close() {
+	h.pool.Close()
+}
+
+// davRequest holds the state of a single WebDAV request
+type davRequest struct {
+	h    *DAVHandler
+	con  *gorods.Connection
+	root string
+	w    http.ResponseWriter
+	r    *http.Request
+}
+
+func (h *DAVHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	username, password, ok := r.BasicAuth()
+	if !ok || username == "" {
+		h.unauthorized(w)
+		return
+	}
+
+	pc, release, err := h.pool.Get(username, password)
+	if err != nil {
+		gorods.Log(gorods.LogWarn, "WebDAV authentication failed", "user", username, "error", err)
+		h.unauthorized(w)
+		return
+	}
+	defer release()
+
+	con := pc.(pooledCon).Connection
+
+	req := &davRequest{
+		h:    h,
+		con:  con,
+		root: strings.TrimRight(strings.Replace(h.opts.Path, "{user}", username, -1), "/"),
+		w:    w,
+		r:    r,
+	}
+
+	switch r.Method {
+	case "OPTIONS":
+		req.options()
+	case "PROPFIND":
+		req.propfind()
+	case "GET", "HEAD":
+		req.get()
+	case "PUT":
+		req.put()
+	case "MKCOL":
+		req.mkcol()
+	case "DELETE":
+		req.delete()
+	case "MOVE":
+		req.move()
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *DAVHandler) unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", h.opts.Realm))
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// irodsPath maps a URL path to the iRODS path it represents
+func (req *davRequest) irodsPath(urlPath string) string {
+	p := path.Clean("/" + strings.TrimPrefix(urlPath, req.h.opts.StripPrefix))
+
+	return strings.TrimRight(req.root+p, "/")
+}
+
+// urlPath maps an iRODS path back to the URL path it's served at
+func (req *davRequest) urlPath(irodsPath string, isCol bool) string {
+	p := req.h.opts.StripPrefix + strings.TrimPrefix(irodsPath, req.root)
+
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+
+	if isCol && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+func (req *davRequest) fail(err error, status int) {
+	gorods.Log(gorods.LogError, "WebDAV request failed", "method", req.r.Method, "url", req.r.URL.Path, "error", err)
+	http.Error(req.w, http.StatusText(status), status)
+}
+
+func (req *davRequest) options() {
+	req.w.Header().Set("DAV", "1")
+	req.w.Header().Set("Allow", "OPTIONS, PROPFIND, GET, HEAD, PUT, MKCOL, DELETE, MOVE")
+	req.w.Header().Set("MS-Author-Via", "DAV")
+	req.w.WriteHeader(http.StatusOK)
+}
+
+type multistatus struct {
+	XMLName   xml.Name      `xml:"D:multistatus"`
+	XMLNS     string        `xml:"xmlns:D,attr"`
+	Responses []davResponse `xml:"D:response"`
+}
+
+type davResponse struct {
+	Href     string      `xml:"D:href"`
+	Propstat davPropstat `xml:"D:propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"D:prop"`
+	Status string  `xml:"D:status"`
+}
+
+type davResourceType struct {
+	Collection *struct{} `xml:"D:collection,omitempty"`
+}
+
+type davProp struct {
+	DisplayName     string          `xml:"D:displayname"`
+	ResourceType    davResourceType `xml:"D:resourcetype"`
+	ContentLength   string          `xml:"D:getcontentlength,omitempty"`
+	ContentType     string          `xml:"D:getcontenttype,omitempty"`
+	ETag            string          `xml:"D:getetag,omitempty"`
+	CreationDate    string          `xml:"D:creationdate,omitempty"`
+	GetLastModified string          `xml:"D:getlastmodified,omitempty"`
+}
+
+func etag(stat *gorods.ObjStat) string {
+	if stat.Checksum != "" {
+		return strconv.Quote(stat.Checksum)
+	}
+
+	return strconv.Quote(fmt.Sprintf("%v-%v-%v", stat.DataId, stat.Size, stat.ModifyTime.Unix()))
+}
+
+func (req *davRequest) newResponse(stat *gorods.ObjStat) davResponse {
+	isCol := stat.Type == gorods.CollectionType
+
+	resp := davResponse{
+		Href: req.urlPath(stat.Path, isCol),
+		Propstat: davPropstat{
+			Status: "HTTP/1.1 200 OK",
+			Prop: davProp{
+				DisplayName:     filepath.Base(stat.Path),
+				CreationDate:    stat.CreateTime.UTC().Format(time.RFC3339),
+				GetLastModified: stat.ModifyTime.UTC().Format(http.TimeFormat),
+			},
+		},
+	}
+
+	if isCol {
+		resp.Propstat.Prop.ResourceType.Collection = &struct{}{}
+	} else {
+		resp.Propstat.Prop.ContentLength = strconv.FormatInt(stat.Size, 10)
+		resp.Propstat.Prop.ContentType = contentType(stat.Path)
+		resp.Propstat.Prop.ETag = etag(stat)
+	}
+
+	return resp
+}
+
+func contentType(p string) string {
+	if typ := mime.TypeByExtension(filepath.Ext(p)); typ != "" {
+		return typ
+	}
+
+	return "application/octet-stream"
+}
+
+// propfind answers PROPFIND with all supported properties (allprop), for Depth 0 or 1. Depth infinity is treated as 1.
+func (req *davRequest) propfind() {
+	p := req.irodsPath(req.r.URL.Path)
+
+	stat, err := req.con.ObjStat(p)
+	if err != nil {
+		req.fail(err, http.StatusNotFound)
+		return
+	}
+
+	ms := multistatus{XMLNS: "DAV:"}
+	ms.Responses = append(ms.Responses, req.newResponse(stat))
+
+	if stat.Type == gorods.CollectionType && req.r.Header.Get("Depth") != "0" {
+		col, err := req.con.Collection(gorods.CollectionOptions{
+			Path:      p,
+			Recursive: false,
+			SkipCache: true,
+		})
+		if err != nil {
+			req.fail(err, http.StatusInternalServerError)
+			return
+		}
+
+		objs, err := col.All()
+		if err != nil {
+			req.fail(err, http.StatusInternalServerError)
+			return
+		}
+
+		for _, obj := range objs {
+			ms.Responses = append(ms.Responses, req.newResponse(objStat(obj)))
+		}
+	}
+
+	req.w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	req.w.WriteHeader(207)
+
+	io.WriteString(req.w, xml.Header)
+
+	if err := xml.NewEncoder(req.w).Encode(ms); err != nil {
+		gorods.Log(gorods.LogError, "unable to write the WebDAV response", "url", req.r.URL.Path, "error", err)
+	}
+}
+
+// objStat builds an *ObjStat from the information already loaded in a collection listing, avoiding a stat call per child
+func objStat(obj gorods.IRodsObj) *gorods.ObjStat {
+	stat := &gorods.ObjStat{
+		Path:       obj.Path(),
+		Type:       obj.Type(),
+		OwnerName:  obj.OwnerName(),
+		CreateTime: obj.CreateTime(),
+		ModifyTime: obj.ModifyTime(),
+	}
+
+	if do, ok := obj.(*gorods.DataObj); ok {
+		stat.Size = do.Size()
+		stat.Checksum = do.Checksum()
+		stat.DataId = do.DataId()
+	}
+
+	return stat
+}
+
+func (req *davRequest) get() {
+	p := req.irodsPath(req.r.URL.Path)
+
+	stat, err := req.con.ObjStat(p)
+	if err != nil {
+		req.fail(err, http.StatusNotFound)
+		return
+	}
+
+	if stat.Type != gorods.DataObjType {
+		http.Error(req.w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	req.w.Header().Set("Content-Type", contentType(p))
+	req.w.Header().Set("Content-Length", strconv.FormatInt(stat.Size, 10))
+	req.w.Header().Set("Last-Modified", stat.ModifyTime.UTC().Format(http.TimeFormat))
+	req.w.Header().Set("ETag", etag(stat))
+
+	if req.r.Method == "HEAD" {
+		req.w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	obj, err := req.con.DataObject(p)
+	if err != nil {
+		req.fail(err, http.StatusInternalServerError)
+		return
+	}
+
+	req.w.WriteHeader(http.StatusOK)
+
+	if err := obj.ReadChunk(1024000, func(chunk []byte) {
+		req.w.Write(chunk)
+	}); err != nil {
+		gorods.Log(gorods.LogError, "unable to read the data object", "path", p, "error", err)
+	}
+}
+
+func (req *davRequest) parent(p string) (*gorods.Collection, error) {
+	return req.con.Collection(gorods.CollectionOptions{
+		Path:      path.Dir(p),
+		Recursive: false,
+		SkipCache: true,
+	})
+}
+
+func (req *davRequest) put() {
+	p := req.irodsPath(req.r.URL.Path)
+
+	status := http.StatusCreated
+	if stat, err := req.con.ObjStat(p); err == nil {
+		if stat.Type == gorods.CollectionType {
+			http.Error(req.w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		status = http.StatusNoContent
+	}
+
+	col, err := req.parent(p)
+	if err != nil {
+		req.fail(err, http.StatusConflict)
+		return
+	}
+
+	size := req.r.ContentLength
+	if size < 0 {
+		size = 0
+	}
+
+	obj, err := col.CreateDataObj(gorods.DataObjOptions{
+		Name:  path.Base(p),
+		Size:  size,
+		Mode:  0750,
+		Force: true,
+	})
+	if err != nil {
+		req.fail(err, http.StatusForbidden)
+		return
+	}
+
+	buf := make([]byte, 1024000)
+
+	for {
+		n, rErr := req.r.Body.Read(buf)
+
+		if n > 0 {
+			if wErr := obj.WriteBytes(buf[:n]); wErr != nil {
+				obj.Close()
+				req.fail(wErr, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if rErr == io.EOF {
+			break
+		} else if rErr != nil {
+			obj.Close()
+			req.fail(rErr, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := obj.Close(); err != nil {
+		req.fail(err, http.StatusInternalServerError)
+		return
+	}
+
+	req.w.WriteHeader(status)
+}
+
+func (req *davRequest) mkcol() {
+	p := req.irodsPath(req.r.URL.Path)
+
+	if req.r.ContentLength > 0 {
+		http.Error(req.w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if _, err := req.con.ObjStat(p); err == nil {
+		http.Error(req.w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	col, err := req.parent(p)
+	if err != nil {
+		req.fail(err, http.StatusConflict)
+		return
+	}
+
+	if _, err := col.CreateSubCollection(path.Base(p)); err != nil {
+		req.fail(err, http.StatusForbidden)
+		return
+	}
+
+	req.w.WriteHeader(http.StatusCreated)
+}
+
+// open returns the data object or collection at p
+func (req *davRequest) open(p string) (gorods.IRodsObj, error) {
+	typ, err := req.con.PathType(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ == gorods.CollectionType {
+		return req.con.Collection(gorods.CollectionOptions{
+			Path:      p,
+			Recursive: false,
+			SkipCache: true,
+		})
+	}
+
+	return req.con.DataObject(p)
+}
+
+func (req *davRequest) delete() {
+	p := req.irodsPath(req.r.URL.Path)
+
+	if p == req.root {
+		http.Error(req.w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	obj, err := req.open(p)
+	if err != nil {
+		req.fail(err, http.StatusNotFound)
+		return
+	}
+
+	if err := obj.Delete(true); err != nil {
+		req.fail(err, http.StatusForbidden)
+		return
+	}
+
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+func (req *davRequest) move() {
+	src := req.irodsPath(req.r.URL.Path)
+
+	destURL, err := url.Parse(req.r.Header.Get("Destination"))
+	if err != nil || destURL.Path == "" {
+		http.Error(req.w, "Bad Destination", http.StatusBadRequest)
+		return
+	}
+
+	dst := req.irodsPath(destURL.Path)
+
+	if src == req.root || dst == req.root || src == dst {
+		http.Error(req.w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	obj, err := req.open(src)
+	if err != nil {
+		req.fail(err, http.StatusNotFound)
+		return
+	}
+
+	status := http.StatusCreated
+
+	if existing, er := req.open(dst); er == nil {
+		if req.r.Header.Get("Overwrite") == "F" {
+			http.Error(req.w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+			return
+		}
+
+		if er := existing.Delete(true); er != nil {
+			req.fail(er, http.StatusForbidden)
+			return
+		}
+
+		status = http.StatusNoContent
+	}
+
+	// Move into the destination collection first (keeping the source name), then rename if needed
+	if path.Dir(src) != path.Dir(dst) {
+		if er := obj.MoveTo(path.Dir(dst)); er != nil {
+			req.fail(er, http.StatusConflict)
+			return
+		}
+	}
+
+	if path.Base(src) != path.Base(dst) {
+		if er := obj.Rename(path.Base(dst)); er != nil {
+			req.fail(er, http.StatusConflict)
+			return
+		}
+	}
+
+	req.w.WriteHeader(status)
+}
@@ -0,0 +1,56 @@
+/*** Copyright (c) 2016, The BioTeam, Inc.                     ***
+ *** For more information please refer to the LICENSE.md file  ***/
+
+package webdav
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeHTTPRequiresAuth(t *testing.T) {
+	h := Handler(Options{Path: "/tempZone/home/{user}", Realm: "Lab"})
+	defer h.Close()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PROPFIND", "/", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %v", w.Code)
+	}
+
+	if a := w.Header().Get("WWW-Authenticate"); a != `Basic realm="Lab"` {
+		t.Errorf("Unexpected WWW-Authenticate header %q", a)
+	}
+
+	if h.pool.Len() != 0 {
+		t.Error("Expected no connection to be pooled")
+	}
+}
+
+func TestPaths(t *testing.T) {
+	h := Handler(Options{Path: "/tempZone/home/{user}", StripPrefix: "/dav"})
+	defer h.Close()
+
+	req := &davRequest{h: h, root: "/tempZone/home/alice"}
+
+	for urlPath, irodsPath := range map[string]string{
+		"/dav":                 "/tempZone/home/alice",
+		"/dav/":                "/tempZone/home/alice",
+		"/dav/a b/c.txt":       "/tempZone/home/alice/a b/c.txt",
+		"/dav/../../bob/x.txt": "/tempZone/home/alice/bob/x.txt",
+	} {
+		if p := req.irodsPath(urlPath); p != irodsPath {
+			t.Errorf("irodsPath(%q): expected %q, got %q", urlPath, irodsPath, p)
+		}
+	}
+
+	if u := req.urlPath("/tempZone/home/alice/a b", true); u != "/dav/a%20b/" {
+		t.Errorf("Unexpected URL path %q", u)
+	}
+
+	if u := req.urlPath("/tempZone/home/alice/c.txt", false); u != "/dav/c.txt" {
+		t.Errorf("Unexpected URL path %q", u)
+	}
+}
@@ -54,6 +54,21 @@ int gorods_connect_env(rcComm_t** conn, char* host, int port, char* username, ch
     return 0;
 }
 
+int gorods_connect_proxy(rcComm_t** conn, char* host, int port, char* proxyUser, char* proxyZone, char* clientUser, char* clientZone, char** err) {
+    /*
+      Connects as proxyUser, acting on behalf of clientUser. The server only accepts it if proxyUser is a rodsadmin.
+     */
+    rErrMsg_t errMsg;
+    *conn = _rcConnect(host, port, proxyUser, proxyZone, clientUser, clientZone, &errMsg, 0, 1);
+
+    if ( !*conn ) {
+        *err = "rcConnect failed";
+        return errMsg.status < 0 ? errMsg.status : -1;
+    }
+
+    return 0;
+}
+
 void display_mallinfo(void) {
     struct mallinfo mi;
 
@@ -151,6 +166,28 @@ int gorods_set_session_ticket(rcComm_t *myConn, char *ticket, char** err) {
     return status;
 }
 
+int gorods_ticket_admin(char* arg1, char* arg2, char* arg3, char* arg4, char* arg5, char* arg6, rcComm_t *myConn, char** err) {
+    ticketAdminInp_t ticketAdminInp;
+    int status;
+
+    bzero(&ticketAdminInp, sizeof(ticketAdminInp));
+
+    ticketAdminInp.arg1 = arg1;
+    ticketAdminInp.arg2 = arg2;
+    ticketAdminInp.arg3 = arg3;
+    ticketAdminInp.arg4 = arg4;
+    ticketAdminInp.arg5 = arg5;
+    ticketAdminInp.arg6 = arg6;
+
+    status = rcTicketAdmin( myConn, &ticketAdminInp );
+
+    if ( status < 0 ) {
+        *err = "rcTicketAdmin failed";
+    }
+
+    return status;
+}
+
 int gorods_iuserinfo(rcComm_t *myConn, char *name, userInfo_t* outInfo, char** err) {
     genQueryInp_t genQueryInp;
     genQueryOut_t *genQueryOut;
@@ -284,6 +321,80 @@ int gorods_phys_path_reg(rcComm_t* ccon, char* physPath, char* rodsPath, int for
     return rcPhyPathReg(ccon, &dataObjOprInp);
 }
 
+int gorods_register_path(char* physPath, char* rodsPath, char* resourceName, char* dataType, int recursive, int force, int replica, int checksum, char* excludeFiles, rcComm_t* conn, char** err) {
+
+    dataObjInp_t dataObjOprInp;
+    bzero(&dataObjOprInp, sizeof(dataObjOprInp));
+
+    if ( dataType != NULL && dataType[0] != '\0' ) {
+        addKeyVal(&dataObjOprInp.condInput, DATA_TYPE_KW, dataType);
+    } else {
+        addKeyVal(&dataObjOprInp.condInput, DATA_TYPE_KW, "generic");
+    }
+
+    if ( force > 0 ) {
+        addKeyVal(&dataObjOprInp.condInput, FORCE_FLAG_KW, "");
+    }
+
+    if ( recursive > 0 ) {
+        addKeyVal(&dataObjOprInp.condInput, COLLECTION_KW, "");
+    }
+
+    if ( replica > 0 ) {
+        addKeyVal(&dataObjOprInp.condInput, REG_REPL_KW, "");
+    }
+
+    if ( checksum == 1 ) {
+        addKeyVal(&dataObjOprInp.condInput, REG_CHKSUM_KW, "");
+    } else if ( checksum == 2 ) {
+        addKeyVal(&dataObjOprInp.condInput, VERIFY_CHKSUM_KW, "");
+    }
+
+    if ( excludeFiles != NULL && excludeFiles[0] != '\0' ) {
+        addKeyVal(&dataObjOprInp.condInput, EXCLUDE_FILE_KW, excludeFiles);
+    }
+
+    if ( resourceName != NULL && resourceName[0] != '\0' ) {
+        addKeyVal(&dataObjOprInp.condInput, DEST_RESC_NAME_KW, resourceName);
+    }
+
+    addKeyVal(&dataObjOprInp.condInput, FILE_PATH_KW, physPath);
+    rstrcpy(dataObjOprInp.objPath, rodsPath, MAX_NAME_LEN);
+
+    int status = rcPhyPathReg(conn, &dataObjOprInp);
+
+    clearKeyVal(&dataObjOprInp.condInput);
+
+    if ( status < 0 ) {
+        *err = "rcPhyPathReg failed";
+    }
+
+    return status;
+}
+
+int gorods_unregister_replica(char* rodsPath, char* replNum, rcComm_t* conn, char** err) {
+
+    dataObjInp_t dataObjInp;
+    bzero(&dataObjInp, sizeof(dataObjInp));
+
+    rstrcpy(dataObjInp.objPath, rodsPath, MAX_NAME_LEN);
+    dataObjInp.oprType = UNREG_OPR;
+
+    if ( replNum != NULL && replNum[0] != '\0' ) {
+        addKeyVal(&dataObjInp.condInput, REPL_NUM_KW, replNum);
+    }
+
+    int status = rcDataObjUnlink(conn, &dataObjInp);
+
+    clearKeyVal(&dataObjInp.condInput);
+
+    if ( status < 0 ) {
+        *err = "rcDataObjUnlink failed";
+    }
+
+    return status;
+}
+
 
 int gorods_open_collection(char* path, int trimRepls, collHandle_t* collHandle, rcComm_t* conn, char** err) {
 
@@ -308,6 +419,54 @@ int gorods_open_collection(char* path, int trimRepls, collHandle_t* collHandle,
 }
 
 
+void gorods_add_dest_resource(keyValPair_t* condInput, char* resource) {
+    /*
+      Adds the destination resource to condInput. A resource hierarchy ("root;child;leaf") targets the leaf
+      resource: the root is passed as the destination resource, and the full hierarchy as the hierarchy string.
+     */
+    char root[NAME_LEN];
+    char* sep;
+
+    if ( resource == NULL || resource[0] == '\0' ) {
+        return;
+    }
+
+    sep = strchr(resource, ';');
+    if ( sep == NULL ) {
+        addKeyVal(condInput, DEST_RESC_NAME_KW, resource);
+        return;
+    }
+
+    rstrcpy(root, resource, NAME_LEN);
+    if ( sep - resource < NAME_LEN ) {
+        root[sep - resource] = '\0';
+    }
+
+    addKeyVal(condInput, DEST_RESC_NAME_KW, root);
+    addKeyVal(condInput, RESC_HIER_STR_KW, resource);
+}
+
+int gorods_get_default_resource(char** resource) {
+    /*
+      Sets resource to the default resource of the iRODS environment (irods_default_resource), or an empty
+      string if there isn't one. The caller frees resource.
+     */
+    rodsEnv myEnv;
+
+    int status = getRodsEnv(&myEnv);
+
+    *resource = gorods_malloc(NAME_LEN);
+
+    if ( status < 0 ) {
+        (*resource)[0] = '\0';
+        return status;
+    }
+
+    rstrcpy(*resource, myEnv.rodsDefResource, NAME_LEN);
+
+    return 0;
+}
+
 int gorods_put_dataobject(char* inPath, char* outPath, rodsLong_t size, int mode, int force, char* resource, rcComm_t* conn, char** err) {
     
     int status;
@@ -322,9 +481,7 @@ int gorods_put_dataobject(char* inPath, char* outPath, rodsLong_t size, int mode
     dataObjInp.dataSize = size;
     dataObjInp.numThreads = conn->transStat.numThreads;
 
-    if ( resource != NULL && resource[0] != '\0' ) {
-        addKeyVal(&dataObjInp.condInput, DEST_RESC_NAME_KW, resource); 
-    }
+    gorods_add_dest_resource(&dataObjInp.condInput, resource);
 
     if ( force > 0 ) {
         addKeyVal(&dataObjInp.condInput, FORCE_FLAG_KW, ""); 
@@ -338,6 +495,154 @@ int gorods_put_dataobject(char* inPath, char* outPath, rodsLong_t size, int mode
     return status;
 }
 
+int gorods_get_host_for(rcComm_t* conn, char* objPath, char* resource, int forPut, char** host, char** err) {
+    /*
+      Asks the server which host the data object should be transferred with (rcGetHostForPut or rcGetHostForGet).
+      *host is THIS_ADDRESS if it is the server conn is attached to. The caller must free *host.
+     */
+    int status;
+    dataObjInp_t dataObjInp;
+    char* outHost = NULL;
+    bzero(&dataObjInp, sizeof(dataObjInp));
+
+    rstrcpy(dataObjInp.objPath, objPath, MAX_NAME_LEN);
+
+    if ( forPut > 0 ) {
+        dataObjInp.oprType = PUT_OPR;
+        gorods_add_dest_resource(&dataObjInp.condInput, resource);
+
+        status = rcGetHostForPut(conn, &dataObjInp, &outHost);
+    } else {
+        dataObjInp.oprType = GET_OPR;
+
+        if ( resource != NULL && resource[0] != '\0' ) {
+            addKeyVal(&dataObjInp.condInput, RESC_NAME_KW, resource);
+        }
+
+        status = rcGetHostForGet(conn, &dataObjInp, &outHost);
+    }
+
+    clearKeyVal(&dataObjInp.condInput);
+
+    if ( status < 0 ) {
+        *err = forPut > 0 ? "rcGetHostForPut failed" : "rcGetHostForGet failed";
+        return status;
+    }
+
+    *host = outHost;
+
+    return 0;
+}
+
+int gorods_extract_bundle(char* objPath, char* collection, char* dataType, char* resource, int force, rcComm_t* conn, char** err) {
+
+    structFileExtAndRegInp_t structFileExtAndRegInp;
+    bzero(&structFileExtAndRegInp, sizeof(structFileExtAndRegInp)); 
+
+    rstrcpy(structFileExtAndRegInp.objPath, objPath, MAX_NAME_LEN);
+    rstrcpy(structFileExtAndRegInp.collection, collection, MAX_NAME_LEN);
+
+    if ( dataType != NULL && dataType[0] != '\0' ) {
+        addKeyVal(&structFileExtAndRegInp.condInput, DATA_TYPE_KW, dataType); 
+    }
+
+    if ( resource != NULL && resource[0] != '\0' ) {
+        addKeyVal(&structFileExtAndRegInp.condInput, DEST_RESC_NAME_KW, resource); 
+    }
+
+    if ( force > 0 ) {
+        addKeyVal(&structFileExtAndRegInp.condInput, FORCE_FLAG_KW, ""); 
+    }
+
+    int status = rcStructFileExtAndReg(conn, &structFileExtAndRegInp); 
+
+    clearKeyVal(&structFileExtAndRegInp.condInput);
+
+    if ( status < 0 ) { 
+        *err = "rcStructFileExtAndReg failed";
+    }
+
+    return status;
+}
+
+int gorods_create_bundle(char* objPath, char* collection, char* dataType, char* resource, int force, rcComm_t* conn, char** err) {
+
+    structFileExtAndRegInp_t structFileBundleInp;
+    bzero(&structFileBundleInp, sizeof(structFileBundleInp));
+
+    rstrcpy(structFileBundleInp.objPath, objPath, MAX_NAME_LEN);
+    rstrcpy(structFileBundleInp.collection, collection, MAX_NAME_LEN);
+
+    if ( dataType != NULL && dataType[0] != '\0' ) {
+        addKeyVal(&structFileBundleInp.condInput, DATA_TYPE_KW, dataType);
+    }
+
+    if ( resource != NULL && resource[0] != '\0' ) {
+        addKeyVal(&structFileBundleInp.condInput, DEST_RESC_NAME_KW, resource);
+    }
+
+    if ( force > 0 ) {
+        addKeyVal(&structFileBundleInp.condInput, FORCE_FLAG_KW, "");
+    }
+
+    int status = rcStructFileBundle(conn, &structFileBundleInp);
+
+    clearKeyVal(&structFileBundleInp.condInput);
+
+    if ( status < 0 ) {
+        *err = "rcStructFileBundle failed";
+    }
+
+    return status;
+}
+
+int gorods_lock_dataobject(rcComm_t* conn, char* path, int writeLock, int wait, char** err) {
+    /*
+      Takes an advisory read or write lock on the data object, waiting for conflicting locks to be released if
+      wait is set. Returns the lock descriptor used by gorods_unlock_dataobject, or a negative status.
+     */
+    dataObjInp_t dataObjInp;
+    bzero(&dataObjInp, sizeof(dataObjInp));
+
+    rstrcpy(dataObjInp.objPath, path, MAX_NAME_LEN);
+
+    addKeyVal(&dataObjInp.condInput, LOCK_TYPE_KW, writeLock ? WRITE_LOCK_TYPE : READ_LOCK_TYPE);
+    addKeyVal(&dataObjInp.condInput, LOCK_CMD_KW, wait ? SET_LOCK_WAIT_CMD : SET_LOCK_CMD);
+
+    int status = rcDataObjLock(conn, &dataObjInp);
+
+    clearKeyVal(&dataObjInp.condInput);
+
+    if ( status < 0 ) {
+        *err = "rcDataObjLock failed";
+    }
+
+    return status;
+}
+
+int gorods_unlock_dataobject(rcComm_t* conn, char* path, int fd, char** err) {
+    dataObjInp_t dataObjInp;
+    char fdStr[NAME_LEN];
+
+    bzero(&dataObjInp, sizeof(dataObjInp));
+
+    rstrcpy(dataObjInp.objPath, path, MAX_NAME_LEN);
+    snprintf(fdStr, NAME_LEN, "%d", fd);
+
+    addKeyVal(&dataObjInp.condInput, LOCK_TYPE_KW, UNLOCK_TYPE);
+    addKeyVal(&dataObjInp.condInput, LOCK_FD_KW, fdStr);
+
+    int status = rcDataObjUnlock(conn, &dataObjInp);
+
+    clearKeyVal(&dataObjInp.condInput);
+
+    if ( status < 0 ) {
+        *err = "rcDataObjUnlock failed";
+    }
+
+    return status;
+}
+
 int gorods_write_dataobject(int handle, void* data, int size, rcComm_t* conn, char** err) {
 	
 	openedDataObjInp_t dataObjWriteInp; 
@@ -374,9 +679,7 @@ int gorods_create_dataobject(char* path, rodsLong_t size, int mode, int force, c
 	dataObjInp.dataSize = size; 
     dataObjInp.numThreads = conn->transStat.numThreads;
 
-	if ( resource != NULL && resource[0] != '\0' ) {
-		addKeyVal(&dataObjInp.condInput, DEST_RESC_NAME_KW, resource); 
-	}
+	gorods_add_dest_resource(&dataObjInp.condInput, resource);
 
 	if ( force > 0 ) {
 		addKeyVal(&dataObjInp.condInput, FORCE_FLAG_KW, ""); 
@@ -959,6 +1262,325 @@ int gorods_delete_user(char* userName, char* zoneName, rcComm_t *conn, char** er
     return status;
 }
 
+int gorods_rule_exec_del(char* ruleExecId, rcComm_t* conn, char** err) {
+
+    ruleExecDelInp_t ruleExecDelInp;
+    bzero(&ruleExecDelInp, sizeof(ruleExecDelInp));
+
+    rstrcpy(ruleExecDelInp.ruleExecId, ruleExecId, NAME_LEN);
+
+    int status = rcRuleExecDel(conn, &ruleExecDelInp);
+
+    if ( status < 0 ) {
+        *err = "rcRuleExecDel failed";
+    }
+
+    return status;
+}
+
+int gorods_rule_exec_mod(char* ruleExecId, char* key, char* value, rcComm_t* conn, char** err) {
+
+    ruleExecModInp_t ruleExecModInp;
+    bzero(&ruleExecModInp, sizeof(ruleExecModInp));
+
+    rstrcpy(ruleExecModInp.ruleId, ruleExecId, NAME_LEN);
+
+    addKeyVal(&ruleExecModInp.condInput, key, value);
+
+    int status = rcRuleExecMod(conn, &ruleExecModInp);
+
+    clearKeyVal(&ruleExecModInp.condInput);
+
+    if ( status < 0 ) {
+        *err = "rcRuleExecMod failed";
+    }
+
+    return status;
+}
+
+int gorods_set_quota(char* ownerType, char* ownerName, char* resourceName, char* limit, rcComm_t *conn, char** err) {
+    int status;
+
+    // iadmin suq / sgq
+    status = gorods_general_admin(0, "set-quota", ownerType, ownerName, resourceName,
+        limit, "", "", "", "", "", 0, conn, err);
+
+    return status;
+}
+
+int gorods_calculate_quota_usage(rcComm_t *conn, char** err) {
+    int status;
+
+    // iadmin cu
+    status = gorods_general_admin(0, "calculate-usage", "", "", "",
+        "", "", "", "", "", "", 0, conn, err);
+
+    return status;
+}
+
+int gorods_atomic_apply_metadata_operations(char* input, char** output, rcComm_t* conn, char** err) {
+
+#if IRODS_VERSION_INTEGER < 4002008
+    *err = "rc_atomic_apply_metadata_operations requires the iRODS 4.2.8 client library";
+    return SYS_UNMATCHED_API_NUM;
+#else
+    int status = rc_atomic_apply_metadata_operations(conn, input, output);
+
+    if ( status < 0 ) {
+        *err = "rc_atomic_apply_metadata_operations failed";
+    }
+
+    return status;
+#endif
+}
+
+int gorods_touch(char* input, rcComm_t* conn, char** err) {
+
+#if IRODS_VERSION_INTEGER < 4002009
+    *err = "rc_touch requires the iRODS 4.2.9 client library";
+    return SYS_UNMATCHED_API_NUM;
+#else
+    int status = rc_touch(conn, input);
+
+    if ( status < 0 ) {
+        *err = "rc_touch failed";
+    }
+
+    return status;
+#endif
+}
+
+int gorods_set_modify_time(char* path, char* modifyTime, rcComm_t* conn, char** err) {
+
+    dataObjInfo_t dataObjInfo;
+    keyValPair_t regParam;
+    modDataObjMeta_t modDataObjMetaInp;
+
+    memset(&dataObjInfo, 0, sizeof(dataObjInfo));
+    memset(&regParam, 0, sizeof(regParam));
+    memset(&modDataObjMetaInp, 0, sizeof(modDataObjMetaInp));
+
+    rstrcpy(dataObjInfo.objPath, path, MAX_NAME_LEN);
+
+    addKeyVal(&regParam, DATA_MODIFY_KW, modifyTime);
+    addKeyVal(&regParam, ALL_KW, "");
+
+    modDataObjMetaInp.dataObjInfo = &dataObjInfo;
+    modDataObjMetaInp.regParam = &regParam;
+
+    int status = rcModDataObjMeta(conn, &modDataObjMetaInp);
+
+    clearKeyVal(&regParam);
+
+    if ( status < 0 ) {
+        *err = "rcModDataObjMeta failed";
+    }
+
+    return status;
+}
+
+int gorods_truncate_dataobject(char* path, rodsLong_t size, rcComm_t* conn, char** err) {
+
+    dataObjInp_t dataObjInp;
+    memset(&dataObjInp, 0, sizeof(dataObjInp));
+
+    rstrcpy(dataObjInp.objPath, path, MAX_NAME_LEN);
+    dataObjInp.dataSize = size;
+
+    int status = rcDataObjTruncate(conn, &dataObjInp);
+
+    if ( status < 0 ) {
+        *err = "rcDataObjTruncate failed";
+    }
+
+    return status;
+}
+
+int gorods_replica_truncate(char* path, rodsLong_t size, char* resource, rcComm_t* conn, char** err) {
+
+#if IRODS_VERSION_INTEGER < 4002011
+    *err = "rc_replica_truncate requires the iRODS 4.2.11 client library";
+    return SYS_UNMATCHED_API_NUM;
+#else
+    dataObjInp_t dataObjInp;
+    char* output = NULL;
+
+    memset(&dataObjInp, 0, sizeof(dataObjInp));
+
+    rstrcpy(dataObjInp.objPath, path, MAX_NAME_LEN);
+    dataObjInp.dataSize = size;
+
+    if ( resource != NULL && resource[0] != '\0' ) {
+        addKeyVal(&dataObjInp.condInput, RESC_NAME_KW, resource);
+    }
+
+    int status = rc_replica_truncate(conn, &dataObjInp, &output);
+
+    clearKeyVal(&dataObjInp.condInput);
+
+    if ( output != NULL ) {
+        free(output);
+    }
+
+    if ( status < 0 ) {
+        *err = "rc_replica_truncate failed";
+    }
+
+    return status;
+#endif
+}
+
+int gorods_genquery2(char* query, char* zone, char** output, rcComm_t* conn, char** err) {
+
+#if IRODS_VERSION_INTEGER < 4003002
+    *err = "rc_genquery2 requires the iRODS 4.3.2 client library";
+    return SYS_UNMATCHED_API_NUM;
+#else
+    struct GenQuery2Input input;
+    memset(&input, 0, sizeof(input));
+
+    input.query_string = query;
+
+    if ( zone != NULL && zone[0] != '\0' ) {
+        input.zone = zone;
+    }
+
+    int status = rc_genquery2(conn, &input, output);
+
+    if ( status < 0 ) {
+        *err = "rc_genquery2 failed";
+    }
+
+    return status;
+#endif
+}
+
+int gorods_auth_file_name(char** fileName, char** err) {
+
+    char buf[MAX_NAME_LEN];
+
+    int status = obfGetFilename(buf);
+
+    if ( status != 0 ) {
+        *err = "obfGetFilename failed";
+        return status;
+    }
+
+    *fileName = strcpy(gorods_malloc(strlen(buf) + 1), buf);
+
+    return 0;
+}
+
+int gorods_save_auth(char* password, char** err) {
+
+    /* no prompts, overwrite an existing file */
+    int status = obfSavePw(0, 1, 0, password);
+
+    if ( status != 0 ) {
+        *err = "obfSavePw failed";
+    }
+
+    return status;
+}
+
+int gorods_get_misc_svr_info(miscSvrInfo_t** info, rcComm_t* conn, char** err) {
+
+    int status = rcGetMiscSvrInfo(conn, info);
+
+    if ( status < 0 ) {
+        *err = "rcGetMiscSvrInfo failed";
+    }
+
+    return status;
+}
+
+int gorods_ping(rcComm_t* conn, char** err) {
+
+    miscSvrInfo_t* info = NULL;
+
+    int status = rcGetMiscSvrInfo(conn, &info);
+
+    if ( info != NULL ) {
+        free(info);
+    }
+
+    if ( status < 0 ) {
+        *err = "rcGetMiscSvrInfo failed";
+    }
+
+    return status;
+}
+
+int gorods_socket_alive(rcComm_t* conn) {
+
+    struct pollfd pfd;
+
+    pfd.fd = conn->sock;
+    pfd.events = POLLIN;
+    pfd.revents = 0;
+
+    int status = poll(&pfd, 1, 0);
+
+    if ( status < 0 ) {
+        return 0;
+    }
+
+    // Nothing should be pending on an idle connection: readable means closed by the peer, or out of sync
+    return status == 0;
+}
+
+int gorods_set_socket_timeouts(rcComm_t* conn, int readMillis, int writeMillis) {
+    /*
+      Sets SO_RCVTIMEO and SO_SNDTIMEO on the connection's socket, so a read or write blocking longer fails
+      instead of hanging. 0 means no timeout.
+     */
+    struct timeval tv;
+
+    tv.tv_sec = readMillis / 1000;
+    tv.tv_usec = (readMillis % 1000) * 1000;
+
+    if ( setsockopt(conn->sock, SOL_SOCKET, SO_RCVTIMEO, &tv, sizeof(tv)) < 0 ) {
+        return -1;
+    }
+
+    tv.tv_sec = writeMillis / 1000;
+    tv.tv_usec = (writeMillis % 1000) * 1000;
+
+    if ( setsockopt(conn->sock, SOL_SOCKET, SO_SNDTIMEO, &tv, sizeof(tv)) < 0 ) {
+        return -1;
+    }
+
+    return 0;
+}
+
+int gorods_zone_report(char** report, rcComm_t* conn, char** err) {
+
+#if IRODS_VERSION_INTEGER < 4002000
+    *err = "rcZoneReport requires the iRODS 4.2 client library";
+    return SYS_UNMATCHED_API_NUM;
+#else
+    bytesBuf_t* bbuf = NULL;
+
+    int status = rcZoneReport(conn, &bbuf);
+
+    if ( status < 0 ) {
+        *err = "rcZoneReport failed";
+        return status;
+    }
+
+    if ( bbuf == NULL || bbuf->buf == NULL ) {
+        *err = "rcZoneReport returned an empty report";
+        return -1;
+    }
+
+    *report = strndup((char*)bbuf->buf, bbuf->len);
+
+    freeBBuf(bbuf);
+
+    return 0;
+#endif
+}
+
 int gorods_create_user(char* userName, char* zoneName, char* type, rcComm_t *conn, char** err) {
     int status;
 
@@ -1575,6 +2197,74 @@ int gorods_iquest_general(rcComm_t *conn, char *selectConditionString, int noDis
 
 }
 
+int gorods_iquest_page(rcComm_t *conn, char *selectConditionString, int upperCaseFlag, char *zoneName, int rowOffset, int maxRows, int* continueInx, int* totalRowCount, goRodsHashResult_t* result, char** err) {
+    /*
+      Fetches a single page of results into result. continueInx is 0 for the first page, and is set to the
+      index of the next page (or 0 when there are no more rows). Calling with maxRows = 0 closes the query.
+      If totalRowCount isn't NULL, the total number of rows is requested with the first page and stored in it
+      when the catalog reports one. The first page starts after rowOffset rows.
+     */
+    int i;
+    genQueryInp_t genQueryInp;
+    genQueryOut_t *genQueryOut = NULL;
+
+    memset(&genQueryInp, 0, sizeof(genQueryInp_t));
+
+    i = fillGenQueryInpFromStrCond(selectConditionString, &genQueryInp);
+    if ( i < 0 ) {
+        *err = "fillGenQueryInpFromStrCond failed";
+        return i;
+    }
+
+    if ( upperCaseFlag ) {
+        genQueryInp.options = UPPER_CASE_WHERE;
+    }
+
+    if ( zoneName != 0 && zoneName[0] != '\0' ) {
+        addKeyVal(&genQueryInp.condInput, ZONE_KW, zoneName);
+    }
+
+    if ( totalRowCount != NULL && *continueInx == 0 ) {
+        genQueryInp.options |= RETURN_TOTAL_ROW_COUNT;
+    }
+
+    if ( *continueInx == 0 ) {
+        genQueryInp.rowOffset = rowOffset;
+    }
+
+    genQueryInp.maxRows = maxRows;
+    genQueryInp.continueInx = *continueInx;
+
+    i = rcGenQuery(conn, &genQueryInp, &genQueryOut);
+
+    clearGenQueryInp(&genQueryInp);
+
+    if ( i < 0 ) {
+        *continueInx = 0;
+        freeGenQueryOut(&genQueryOut);
+
+        if ( i != CAT_NO_ROWS_FOUND ) {
+            *err = "rcGenQuery failed";
+        }
+
+        return i;
+    }
+
+    *continueInx = genQueryOut->continueInx;
+
+    if ( totalRowCount != NULL && genQueryOut->totalRowCount > 0 ) {
+        *totalRowCount = genQueryOut->totalRowCount;
+    }
+
+    if ( maxRows > 0 ) {
+        i = gorods_build_iquest_result(genQueryOut, result, err);
+    }
+
+    freeGenQueryOut(&genQueryOut);
+
+    return i;
+}
+
 int gorods_build_iquest_result(genQueryOut_t * genQueryOut, goRodsHashResult_t* result, char** err) {
     int i = 0, n = 0, j = 0;
     sqlResult_t *v[MAX_SQL_ATTR];
@@ -1700,7 +2390,7 @@ int gorods_repl_dataobject(rcComm_t *conn, char* objPath, char* resourceName, in
     if ( backupMode > 0 ) {
         addKeyVal(&dataObjInp.condInput, BACKUP_RESC_NAME_KW, resourceName);
     } else {
-        addKeyVal(&dataObjInp.condInput, DEST_RESC_NAME_KW, resourceName);
+        gorods_add_dest_resource(&dataObjInp.condInput, resourceName);
     }
 
     status = rcDataObjRepl(conn, &dataObjInp); 
@@ -2041,9 +2731,7 @@ int gorods_copy_dataobject(char* source, char* destination, int force, char* res
 
 	addKeyVal(&dataObjCopyInp.destDataObjInp.condInput, REG_CHKSUM_KW, ""); 
 
-    if ( resource != NULL && resource[0] != '\0' ) {
-        addKeyVal(&dataObjCopyInp.destDataObjInp.condInput, DEST_RESC_NAME_KW, resource); 
-    }
+    gorods_add_dest_resource(&dataObjCopyInp.destDataObjInp.condInput, resource);
 
     if ( force > 0 ) {
         addKeyVal(&dataObjCopyInp.destDataObjInp.condInput, FORCE_FLAG_KW, ""); 
@@ -2121,6 +2809,47 @@ int gorods_checksum_dataobject(char* path, char** outChksum, rcComm_t* conn, cha
 	return 0;
 }
 
+#ifndef VERIFY_VAULT_SIZE_EQUALS_DATABASE_SIZE_KW
+#define VERIFY_VAULT_SIZE_EQUALS_DATABASE_SIZE_KW "verify_vault_size_equals_database_size"
+#endif
+
+#ifndef NO_COMPUTE_KW
+#define NO_COMPUTE_KW "no_compute"
+#endif
+
+int gorods_verify_checksum_dataobject(char* path, char* replNum, int sizeOnly, rcComm_t* conn, char** err) {
+
+	dataObjInp_t dataObjInp; 
+	char *chksumOut = NULL;
+
+	bzero(&dataObjInp, sizeof(dataObjInp)); 
+	rstrcpy(dataObjInp.objPath, path, MAX_NAME_LEN); 
+
+	if ( sizeOnly ) {
+		addKeyVal(&dataObjInp.condInput, VERIFY_VAULT_SIZE_EQUALS_DATABASE_SIZE_KW, ""); 
+		addKeyVal(&dataObjInp.condInput, NO_COMPUTE_KW, ""); 
+	} else {
+		addKeyVal(&dataObjInp.condInput, VERIFY_CHKSUM_KW, ""); 
+	}
+
+	if ( replNum != NULL && replNum[0] != '\0' ) {
+		addKeyVal(&dataObjInp.condInput, REPL_NUM_KW, replNum); 
+	}
+
+    dataObjInp.numThreads = conn->transStat.numThreads;
+
+	int status = rcDataObjChksum(conn, &dataObjInp, &chksumOut); 
+
+	free(chksumOut);
+
+	if ( status < 0 ) { 
+		*err = "rcDataObjChksum verify failed";
+		return status;
+	}
+
+	return 0;
+}
+
 
 const char NON_ROOT_COLL_CHECK_STR[] = "<>'/'";
 
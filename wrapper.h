@@ -1,10 +1,15 @@
 /*** Copyright (c) 2016, University of Florida Research Foundation, Inc. and The BioTeam, Inc.  ***
  *** For more information please refer to the LICENSE.md file                                   ***/
 
+#ifdef _WIN32
+#error "GoRODS: the iRODS client library isn't available for Windows, see the Platform Support section of the README"
+#endif
+
 #include "rods.h"
 #include "rodsErrorTable.h"
 #include "rodsType.h"
 #include "rodsClient.h"
+#include "rodsVersion.h"
 #include "miscUtil.h"
 #include "rodsPath.h"
 #include "rcConnect.h"
@@ -13,8 +18,49 @@
 #include "dataObjRead.h"
 #include "dataObjChksum.h"
 #include "dataObjClose.h"
+#include "getHostForGet.h"
+#include "getHostForPut.h"
 #include "lsUtil.h"
+#include "structFileExtAndReg.h"
+#include "structFileBundle.h"
+#include "dataObjLock.h"
+#include "ruleExecDel.h"
+#include "ruleExecMod.h"
+#include "getMiscSvrInfo.h"
+
+/* IRODS_VERSION_INTEGER is major * 1000000 + minor * 1000 + patch. The 4.1 client library doesn't define it, so the
+ * APIs guarded below are left out when building against it, and their wrappers return SYS_UNMATCHED_API_NUM. */
+#ifndef IRODS_VERSION_INTEGER
+#define IRODS_VERSION_INTEGER 0
+#endif
+
+#if IRODS_VERSION_INTEGER >= 4002000
+#include "zone_report.h"
+#endif
+
+#if IRODS_VERSION_INTEGER >= 4002008
+#include "atomic_apply_metadata_operations.h"
+#endif
+
+#if IRODS_VERSION_INTEGER >= 4002009
+#include "touch.h"
+#endif
+
+#if IRODS_VERSION_INTEGER >= 4002011
+#include "replica_truncate.h"
+#endif
+
+#if IRODS_VERSION_INTEGER >= 4003002
+#include "genquery2.h"
+#endif
+#include <poll.h>
+#include <sys/socket.h>
+#include <sys/time.h>
+#ifdef __APPLE__
+#include <stdlib.h>
+#else
 #include <malloc.h>
+#endif
 
 typedef struct {
 	int size;
@@ -78,11 +124,16 @@ int gorods_getNextCollMetaInfo( collHandle_t *collHandle, collEnt_t *outCollEnt
 int gorods_getNextDataObjMetaInfo( collHandle_t *collHandle, collEnt_t *outCollEnt );
 
 int gorods_phys_path_reg(rcComm_t*, char*, char*, int, int, int, char*, char*);
+int gorods_register_path(char* physPath, char* rodsPath, char* resourceName, char* dataType, int recursive, int force, int replica, int checksum, char* excludeFiles, rcComm_t* conn, char** err);
+int gorods_unregister_replica(char* rodsPath, char* replNum, rcComm_t* conn, char** err);
 
 void display_mallinfo(void);
 void* gorods_malloc(size_t size);
+void gorods_add_dest_resource(keyValPair_t* condInput, char* resource);
+int gorods_get_default_resource(char** resource);
 int gorods_connect(rcComm_t** conn, char** host, int* port, char** username, char** zone, char** err);
 int gorods_connect_env(rcComm_t** conn, char* host, int port, char* username, char* zone, char** err);
+int gorods_connect_proxy(rcComm_t** conn, char* host, int port, char* proxyUser, char* proxyZone, char* clientUser, char* clientZone, char** err);
 int gorods_clientLoginPam(rcComm_t* conn, char* password, int ttl, char** pamPass, char** err) ;
 
 int gorods_iuserinfo(rcComm_t *myConn, char *name, userInfo_t* outInfo, char** err);
@@ -95,6 +146,7 @@ int gorods_get_group(rcComm_t *conn, goRodsStringResult_t* result, char* groupNa
 
 int gorods_build_iquest_result(genQueryOut_t * genQueryOut, goRodsHashResult_t* result, char** err);
 int gorods_iquest_general(rcComm_t *conn, char *selectConditionString, int noDistinctFlag, int upperCaseFlag, char *zoneName, goRodsHashResult_t* result, char** err);
+int gorods_iquest_page(rcComm_t *conn, char *selectConditionString, int upperCaseFlag, char *zoneName, int rowOffset, int maxRows, int* continueInx, int* totalRowCount, goRodsHashResult_t* result, char** err);
 void gorods_free_map_result(goRodsHashResult_t* result);
 
 int gorods_get_users(rcComm_t* conn, goRodsStringResult_t* result, char** err);
@@ -127,6 +179,15 @@ int gorods_get_local_zone(rcComm_t* conn, char** zoneName, char** err);
 
 int gorods_create_user(char* userName, char* zoneName, char* type, rcComm_t *conn, char** err);
 int gorods_delete_user(char* userName, char* zoneName, rcComm_t *conn, char** err);
+int gorods_rule_exec_del(char* ruleExecId, rcComm_t* conn, char** err);
+int gorods_rule_exec_mod(char* ruleExecId, char* key, char* value, rcComm_t* conn, char** err);
+int gorods_set_quota(char* ownerType, char* ownerName, char* resourceName, char* limit, rcComm_t *conn, char** err);
+int gorods_calculate_quota_usage(rcComm_t *conn, char** err);
+int gorods_get_misc_svr_info(miscSvrInfo_t** info, rcComm_t* conn, char** err);
+int gorods_ping(rcComm_t* conn, char** err);
+int gorods_socket_alive(rcComm_t* conn);
+int gorods_set_socket_timeouts(rcComm_t* conn, int readMillis, int writeMillis);
+int gorods_zone_report(char** report, rcComm_t* conn, char** err);
 
 int gorods_general_admin(int userOption, char *arg0, char *arg1, char *arg2, char *arg3,
               char *arg4, char *arg5, char *arg6, char *arg7, char* arg8, char* arg9,
@@ -147,6 +208,11 @@ int gorods_trimrepls_dataobject(rcComm_t *conn, char* objPath, char* ageStr, cha
 int gorods_phymv_dataobject(rcComm_t *conn, char* objPath, char* sourceResource, char* destResource, char** err);
 int gorods_repl_dataobject(rcComm_t *conn, char* objPath, char* resourceName, int backupMode, int createMode, rodsLong_t dataSize, char** err);
 int gorods_put_dataobject(char* inPath, char* outPath, rodsLong_t size, int mode, int force, char* resource, rcComm_t* conn, char** err);
+int gorods_get_host_for(rcComm_t* conn, char* objPath, char* resource, int forPut, char** host, char** err);
+int gorods_extract_bundle(char* objPath, char* collection, char* dataType, char* resource, int force, rcComm_t* conn, char** err);
+int gorods_create_bundle(char* objPath, char* collection, char* dataType, char* resource, int force, rcComm_t* conn, char** err);
+int gorods_lock_dataobject(rcComm_t* conn, char* path, int writeLock, int wait, char** err);
+int gorods_unlock_dataobject(rcComm_t* conn, char* path, int fd, char** err);
 int gorods_open_dataobject(char* path, char* resourceName, char* replNum, int openFlag, int* handle, rcComm_t* conn, char** err);
 int gorods_read_dataobject(int handleInx, rodsLong_t length, bytesBuf_t* buffer, int* bytesRead, rcComm_t* conn, char** err);
 int gorods_lseek_dataobject(int handleInx, rodsLong_t offset, rcComm_t* conn, char** err);
@@ -158,6 +224,7 @@ int gorods_copy_dataobject(char* source, char* destination, int force, char* res
 int gorods_move_dataobject(char* source, char* destination, int objType, rcComm_t* conn, char** err);
 int gorods_unlink_dataobject(char* path, int force, rcComm_t* conn, char** err);
 int gorods_checksum_dataobject(char* path, char** outChksum, rcComm_t* conn, char** err);
+int gorods_verify_checksum_dataobject(char* path, char* replNum, int sizeOnly, rcComm_t* conn, char** err);
 int gorods_rm(char* path, int isCollection, int recursive, int force, int trash, rcComm_t* conn, char** err);
 int gorods_get_dataobject_acl(rcComm_t* conn, char* dataId, goRodsACLResult_t* result, char* zoneHint, char** err);
 void gorods_free_acl_result(goRodsACLResult_t* result);
@@ -174,8 +241,17 @@ int gorods_meta_dataobj(char *name, char *cwd, goRodsMetaResult_t* result, rcCom
 int gorods_meta_collection(char *name, char *cwd, goRodsMetaResult_t* result, rcComm_t* conn, char** err);
 int gorods_mod_meta(char* type, char* path, char* oa, char* ov, char* ou, char* na, char* nv, char* nu, rcComm_t* conn, char** err);
 int gorods_add_meta(char* type, char* path, char* na, char* nv, char* nu, rcComm_t* conn, char** err);
+int gorods_atomic_apply_metadata_operations(char* input, char** output, rcComm_t* conn, char** err);
+int gorods_touch(char* input, rcComm_t* conn, char** err);
+int gorods_set_modify_time(char* path, char* modifyTime, rcComm_t* conn, char** err);
+int gorods_truncate_dataobject(char* path, rodsLong_t size, rcComm_t* conn, char** err);
+int gorods_replica_truncate(char* path, rodsLong_t size, char* resource, rcComm_t* conn, char** err);
+int gorods_genquery2(char* query, char* zone, char** output, rcComm_t* conn, char** err);
+int gorods_auth_file_name(char** fileName, char** err);
+int gorods_save_auth(char* password, char** err);
 int gorods_rm_meta(char* type, char* path, char* oa, char* ov, char* ou, rcComm_t* conn, char** err);
 int gorods_set_session_ticket(rcComm_t *myConn, char *ticket, char** err);
+int gorods_ticket_admin(char* arg1, char* arg2, char* arg3, char* arg4, char* arg5, char* arg6, rcComm_t *myConn, char** err);
 
 int gorods_query_collection(rcComm_t* conn, char* query, goRodsPathResult_t* result, char** err);
 int gorods_query_dataobj(rcComm_t* conn, char* query, goRodsPathResult_t* result, char** err);
@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 /*** Copyright (c) 2016, The BioTeam, Inc.                     ***
  *** For more information please refer to the LICENSE.md file  ***/
 
@@ -137,6 +140,14 @@ func (zne *Zone) Type() (int, error) {
 	return zne.typ, nil
 }
 
+// IsRemote loads data from iRODS if needed, and returns true if the zone is a remote (federated) zone.
+func (zne *Zone) IsRemote() (bool, error) {
+	if err := zne.init(); err != nil {
+		return false, err
+	}
+	return zne.typ == Remote, nil
+}
+
 // ConString loads data from iRODS if needed, and returns the zone's conString attribute.
 func (zne *Zone) ConString() (string, error) {
 	if err := zne.init(); err != nil {